@@ -0,0 +1,61 @@
+// Command logfire-backfill uploads the spans written by the file exporter of the Logfire SDK to Logfire,
+// with their original timestamps, see [logfire.Backfill]:
+//
+//	go run github.com/pydantic/logfire/go/cmd/logfire-backfill [flags] file...
+//
+// The token defaults to the LOGFIRE_TOKEN environment variable, or else the credentials file
+// of the Logfire CLI, like for [logfire.Configure].
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func main() {
+	token := flag.String("token", "", "project write token")
+	baseURL := flag.String("base-url", "", "base URL of the Logfire API, defaults to the one of the token region")
+	dataDir := flag.String("data-dir", "", "directory of the credentials file, defaults to .logfire")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] file...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var opts []logfire.Option
+	if *token != "" {
+		opts = append(opts, logfire.WithToken(*token))
+	}
+	if *baseURL != "" {
+		opts = append(opts, logfire.WithBaseURL(*baseURL))
+	}
+	if *dataDir != "" {
+		opts = append(opts, logfire.WithDataDir(*dataDir))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	failed := false
+	for _, path := range flag.Args() {
+		n, err := logfire.Backfill(ctx, path, opts...)
+		if n > 0 || err == nil {
+			fmt.Printf("%s: uploaded %d spans\n", path, n)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,345 @@
+// Package genaiconv holds the span names, attributes and message formats shared by the LLM integrations,
+// so that calls to every provider look the same in Logfire, which renders the gen_ai.* attributes of the
+// OpenTelemetry semantic conventions as conversations.
+package genaiconv
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// ProviderNameKey records the provider of a call, e.g. `openai`.
+	ProviderNameKey = attribute.Key("gen_ai.provider.name")
+	// SystemKey is the former name of ProviderNameKey, which the Logfire UI still reads.
+	SystemKey = attribute.Key("gen_ai.system")
+	// OperationNameKey records the operation of a call, e.g. `chat` or `embeddings`.
+	OperationNameKey = attribute.Key("gen_ai.operation.name")
+	// RequestModelKey records the model requested.
+	RequestModelKey = attribute.Key("gen_ai.request.model")
+	// RequestMaxTokensKey records the maximum number of tokens to generate.
+	RequestMaxTokensKey = attribute.Key("gen_ai.request.max_tokens")
+	// RequestTemperatureKey records the temperature of the sampling.
+	RequestTemperatureKey = attribute.Key("gen_ai.request.temperature")
+	// RequestTopPKey records the top_p of the sampling.
+	RequestTopPKey = attribute.Key("gen_ai.request.top_p")
+	// RequestTopKKey records the top_k of the sampling.
+	RequestTopKKey = attribute.Key("gen_ai.request.top_k")
+	// RequestFrequencyPenaltyKey records the frequency penalty of the sampling.
+	RequestFrequencyPenaltyKey = attribute.Key("gen_ai.request.frequency_penalty")
+	// RequestPresencePenaltyKey records the presence penalty of the sampling.
+	RequestPresencePenaltyKey = attribute.Key("gen_ai.request.presence_penalty")
+	// RequestStopSequencesKey records the sequences stopping the generation.
+	RequestStopSequencesKey = attribute.Key("gen_ai.request.stop_sequences")
+	// RequestSeedKey records the seed of the sampling.
+	RequestSeedKey = attribute.Key("gen_ai.request.seed")
+	// RequestChoiceCountKey records the number of candidates requested.
+	RequestChoiceCountKey = attribute.Key("gen_ai.request.choice.count")
+	// RequestStreamKey records whether the response is streamed.
+	RequestStreamKey = attribute.Key("gen_ai.request.stream")
+	// EmbeddingsDimensionCountKey records the number of dimensions of the embeddings requested.
+	EmbeddingsDimensionCountKey = attribute.Key("gen_ai.embeddings.dimension.count")
+	// ResponseIDKey records the identifier of the response.
+	ResponseIDKey = attribute.Key("gen_ai.response.id")
+	// ResponseModelKey records the model which generated the response.
+	ResponseModelKey = attribute.Key("gen_ai.response.model")
+	// ResponseFinishReasonsKey records why the generation of each candidate stopped.
+	ResponseFinishReasonsKey = attribute.Key("gen_ai.response.finish_reasons")
+	// UsageInputTokensKey records the number of tokens of the prompt.
+	UsageInputTokensKey = attribute.Key("gen_ai.usage.input_tokens")
+	// UsageCacheCreationInputTokensKey records the number of tokens of the prompt written to the cache of the provider.
+	UsageCacheCreationInputTokensKey = attribute.Key("gen_ai.usage.cache_creation.input_tokens")
+	// UsageCacheReadInputTokensKey records the number of tokens of the prompt read from the cache of the provider.
+	UsageCacheReadInputTokensKey = attribute.Key("gen_ai.usage.cache_read.input_tokens")
+	// UsageOutputTokensKey records the number of tokens generated.
+	UsageOutputTokensKey = attribute.Key("gen_ai.usage.output_tokens")
+	// InputMessagesKey records the messages of the request as JSON, when message content is recorded.
+	InputMessagesKey = attribute.Key("gen_ai.input.messages")
+	// OutputMessagesKey records the messages of the response as JSON, when message content is recorded.
+	OutputMessagesKey = attribute.Key("gen_ai.output.messages")
+	// SystemInstructionsKey records the system instructions given apart from the messages, as JSON parts.
+	SystemInstructionsKey = attribute.Key("gen_ai.system_instructions")
+	// ToolDefinitionsKey records the tools the model may call, as JSON.
+	ToolDefinitionsKey = attribute.Key("gen_ai.tool.definitions")
+	// ToolNameKey records the name of the tool executed.
+	ToolNameKey = attribute.Key("gen_ai.tool.name")
+	// ToolCallIDKey records the identifier of the tool call executed.
+	ToolCallIDKey = attribute.Key("gen_ai.tool.call.id")
+	// ToolCallArgumentsKey records the arguments of the tool call executed, when message content is recorded.
+	ToolCallArgumentsKey = attribute.Key("gen_ai.tool.call.arguments")
+	// ToolCallResultKey records the result of the tool call executed, when message content is recorded.
+	ToolCallResultKey = attribute.Key("gen_ai.tool.call.result")
+	// ResponseTimeToFirstChunkKey records the seconds between the start of a streamed call and the
+	// first chunk of its output, when stream progress is recorded.
+	ResponseTimeToFirstChunkKey = attribute.Key("gen_ai.response.time_to_first_chunk")
+	// ResponseChunkCountKey records the number of chunks of the output of a streamed call, when
+	// stream progress is recorded.
+	ResponseChunkCountKey = attribute.Key("gen_ai.response.chunk_count")
+	// OperationCostKey records the cost of a call in USD, when the model has a price.
+	OperationCostKey = attribute.Key("operation.cost")
+	// TokenTypeKey records whether the tokens of the token usage metric are input or output tokens.
+	TokenTypeKey = attribute.Key("gen_ai.token.type")
+	// tagsKey records the Logfire tags, which mark LLM spans like in the Python SDK.
+	tagsKey = attribute.Key("logfire.tags")
+)
+
+// Operation names.
+const (
+	OperationChat            = "chat"
+	OperationTextCompletion  = "text_completion"
+	OperationEmbeddings      = "embeddings"
+	OperationGenerateContent = "generate_content"
+	OperationExecuteTool     = "execute_tool"
+)
+
+// SpanName returns the name of the span of a call, e.g. `chat gpt-4o`.
+func SpanName(operation, model string) string {
+	if model == "" {
+		return operation
+	}
+	return operation + " " + model
+}
+
+// Params are the parameters of a request, which are recorded when set.
+type Params struct {
+	MaxTokens        *int64
+	Temperature      *float64
+	TopP             *float64
+	TopK             *float64
+	FrequencyPenalty *float64
+	PresencePenalty  *float64
+	Seed             *int64
+	ChoiceCount      *int64
+	StopSequences    []string
+}
+
+// Attributes returns the attributes of the parameters set.
+func (p Params) Attributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, i := range []struct {
+		key   attribute.Key
+		value *int64
+	}{
+		{RequestMaxTokensKey, p.MaxTokens},
+		{RequestSeedKey, p.Seed},
+		{RequestChoiceCountKey, p.ChoiceCount},
+	} {
+		if i.value != nil {
+			attrs = append(attrs, i.key.Int64(*i.value))
+		}
+	}
+	for _, f := range []struct {
+		key   attribute.Key
+		value *float64
+	}{
+		{RequestTemperatureKey, p.Temperature},
+		{RequestTopPKey, p.TopP},
+		{RequestTopKKey, p.TopK},
+		{RequestFrequencyPenaltyKey, p.FrequencyPenalty},
+		{RequestPresencePenaltyKey, p.PresencePenalty},
+	} {
+		if f.value != nil {
+			attrs = append(attrs, f.key.Float64(*f.value))
+		}
+	}
+	if len(p.StopSequences) > 0 {
+		attrs = append(attrs, RequestStopSequencesKey.StringSlice(p.StopSequences))
+	}
+	return attrs
+}
+
+// Message is a message of a conversation, in the format of `gen_ai.input.messages` and `gen_ai.output.messages`.
+type Message struct {
+	Role         string `json:"role"`
+	Parts        []Part `json:"parts"`
+	Name         string `json:"name,omitempty"`
+	FinishReason string `json:"finish_reason,omitempty"`
+}
+
+// Part is a part of a message: text, a tool call or its response, reasoning, or another kind of content.
+type Part struct {
+	Type      string `json:"type"`
+	Content   string `json:"content,omitempty"`
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments any    `json:"arguments,omitempty"`
+	Response  any    `json:"response,omitempty"`
+	MIMEType  string `json:"mime_type,omitempty"`
+	URI       string `json:"uri,omitempty"`
+}
+
+// TextPart returns a text part.
+func TextPart(text string) Part {
+	return Part{Type: "text", Content: text}
+}
+
+// ToolCallPart returns a call of a tool, whose arguments are decoded if they're a JSON string.
+func ToolCallPart(id, name string, arguments any) Part {
+	if s, ok := arguments.(string); ok && json.Valid([]byte(s)) {
+		arguments = json.RawMessage(s)
+	}
+	return Part{Type: "tool_call", ID: id, Name: name, Arguments: arguments}
+}
+
+// ToolCallResponsePart returns the response of a tool call, which is decoded if it's a JSON string.
+func ToolCallResponsePart(id string, response any) Part {
+	if s, ok := response.(string); ok && json.Valid([]byte(s)) {
+		response = json.RawMessage(s)
+	}
+	return Part{Type: "tool_call_response", ID: id, Response: response}
+}
+
+// JSON returns v encoded as a JSON string attribute, or an invalid attribute if it can't be encoded.
+func JSON(key attribute.Key, v any) attribute.KeyValue {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return attribute.KeyValue{}
+	}
+	return key.String(string(b))
+}
+
+// Response is the outcome of a call.
+type Response struct {
+	ID            string
+	Model         string
+	FinishReasons []string
+	Usage         *Usage
+	// Output is recorded when message content is recorded.
+	Output []Message
+	// Attributes are recorded along with the response, for what's specific to the provider.
+	Attributes []attribute.KeyValue
+}
+
+// Usage is the number of tokens used by a call. InputTokens includes the cached tokens. Output tokens
+// aren't recorded when there are none, like for embeddings, nor are cached tokens.
+type Usage struct {
+	InputTokens              int64
+	OutputTokens             int64
+	CacheCreationInputTokens int64
+	CacheReadInputTokens     int64
+}
+
+// Span is the span of a call, which can be ended from the goroutine reading a streamed response
+// while the caller closes it.
+type Span struct {
+	span    trace.Span
+	content bool
+	start   time.Time
+	// provider, operation and model are the attributes of the metrics of the call.
+	provider, operation, model string
+
+	mu       sync.Mutex
+	ended    bool
+	progress *progress
+	metrics  *Metrics
+	errType  string
+}
+
+// Start starts the span of a call of operation with model of provider, which may be empty if
+// it's unknown. content is whether the messages of the call are recorded.
+func Start(ctx context.Context, tracer trace.Tracer, provider, operation, model string, content bool, attrs ...attribute.KeyValue) (context.Context, *Span) {
+	name := SpanName(operation, model)
+	attrs = append([]attribute.KeyValue{
+		logfire.MsgKey.String(name),
+		tagsKey.StringSlice([]string{"LLM"}),
+		OperationNameKey.String(operation),
+	}, attrs...)
+	if provider != "" {
+		attrs = append(attrs, ProviderNameKey.String(provider), SystemKey.String(provider))
+	}
+	if model != "" {
+		attrs = append(attrs, RequestModelKey.String(model))
+	}
+	ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, &Span{span: span, content: content, start: time.Now(), provider: provider, operation: operation, model: model}
+}
+
+// Span returns the underlying span.
+func (s *Span) Span() trace.Span {
+	return s.span
+}
+
+// Content is whether the messages of the call are recorded.
+func (s *Span) Content() bool {
+	return s.content
+}
+
+// Input records the system instructions and messages of the request, when message content is recorded.
+func (s *Span) Input(system []Part, messages []Message) {
+	if !s.content {
+		return
+	}
+	if len(system) > 0 {
+		s.span.SetAttributes(JSON(SystemInstructionsKey, system))
+	}
+	if len(messages) > 0 {
+		s.span.SetAttributes(JSON(InputMessagesKey, messages))
+	}
+}
+
+// End records the response and ends the span, once.
+func (s *Span) End(resp Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	var attrs []attribute.KeyValue
+	if resp.ID != "" {
+		attrs = append(attrs, ResponseIDKey.String(resp.ID))
+	}
+	if resp.Model != "" {
+		attrs = append(attrs, ResponseModelKey.String(resp.Model))
+	}
+	if len(resp.FinishReasons) > 0 {
+		attrs = append(attrs, ResponseFinishReasonsKey.StringSlice(resp.FinishReasons))
+	}
+	if resp.Usage != nil {
+		attrs = append(attrs, UsageInputTokensKey.Int64(resp.Usage.InputTokens))
+		if resp.Usage.OutputTokens > 0 {
+			attrs = append(attrs, UsageOutputTokensKey.Int64(resp.Usage.OutputTokens))
+		}
+		if resp.Usage.CacheCreationInputTokens > 0 {
+			attrs = append(attrs, UsageCacheCreationInputTokensKey.Int64(resp.Usage.CacheCreationInputTokens))
+		}
+		if resp.Usage.CacheReadInputTokens > 0 {
+			attrs = append(attrs, UsageCacheReadInputTokensKey.Int64(resp.Usage.CacheReadInputTokens))
+		}
+	}
+	if s.content && len(resp.Output) > 0 {
+		attrs = append(attrs, JSON(OutputMessagesKey, resp.Output))
+	}
+	if s.progress != nil {
+		attrs = append(attrs, ResponseChunkCountKey.Int(s.progress.chunks))
+	}
+	if cost, ok := s.metrics.cost(s.model, resp); ok {
+		attrs = append(attrs, OperationCostKey.Float64(cost))
+	}
+	attrs = append(attrs, resp.Attributes...)
+	s.span.SetAttributes(attrs...)
+	s.record(resp)
+	s.span.End()
+}
+
+// Fail records the error of a call and ends the span, once. The partial response of a
+// stream is still recorded.
+func (s *Span) Fail(err error, resp Response) {
+	s.mu.Lock()
+	if !s.ended {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+		s.errType = semconv.ErrorType(err).Value.AsString()
+	}
+	s.mu.Unlock()
+	s.End(resp)
+}
@@ -0,0 +1,210 @@
+package genaiconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Call is a call to an HTTP API, as parsed from its request.
+type Call struct {
+	Operation  string
+	Model      string
+	Stream     bool
+	Attributes []attribute.KeyValue
+	System     []Part
+	Messages   []Message
+}
+
+// Stream accumulates a streamed response line by line. Line returns whether the line ends the stream,
+// or the error streamed, so that the span ends even if the client stops reading before EOF. Chunks
+// returns the number of lines containing output so far, usually by embedding [ChunkCounter].
+type Stream interface {
+	Line(line []byte) (done bool, err error)
+	Response() Response
+	Chunks() int
+}
+
+// Client traces the calls of the integrations instrumenting the HTTP clients of providers.
+type Client struct {
+	Tracer     trace.Tracer
+	Provider   string
+	Content    bool
+	Attributes []attribute.KeyValue
+	// Progress configures the recording of the progress of streamed responses, which isn't recorded if it's nil.
+	Progress *Progress
+	// Metrics records the metrics of calls, which aren't recorded if it's nil.
+	Metrics *Metrics
+}
+
+// Do sends r with next in the span of call, parsing its response with parse, or with stream for streamed
+// calls, whose span ends once the body of the response is read or closed.
+func (c *Client) Do(r *http.Request, call Call, next func(*http.Request) (*http.Response, error), parse func(body []byte) Response, stream Stream) (*http.Response, error) {
+	ctx, span := Start(r.Context(), c.Tracer, c.Provider, call.Operation, call.Model, c.Content,
+		append(call.Attributes, c.Attributes...)...)
+	span.Input(call.System, call.Messages)
+	span.Metrics(c.Metrics)
+	resp, err := next(r.WithContext(ctx))
+	if err != nil {
+		span.Fail(err, Response{})
+		return resp, err
+	}
+	span.Span().SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := ReadResponseBody(resp)
+		span.Fail(HTTPError(resp, body), Response{})
+		return resp, nil
+	}
+	if call.Stream && stream != nil {
+		span.StreamProgress(c.Progress, stream.Response)
+		chunks := 0
+		onLine := func(line []byte) (bool, error) {
+			done, err := stream.Line(line)
+			for ; chunks < stream.Chunks(); chunks++ {
+				span.Chunk()
+			}
+			return done, err
+		}
+		resp.Body = StreamBody(resp.Body, onLine, func(err error) {
+			if err != nil {
+				span.Fail(err, stream.Response())
+				return
+			}
+			span.End(stream.Response())
+		})
+		return resp, nil
+	}
+	body, err := ReadResponseBody(resp)
+	if err != nil {
+		span.Fail(err, Response{})
+		return nil, err
+	}
+	span.End(parse(body))
+	return resp, nil
+}
+
+// ReadRequestBody reads the body of a request, replacing it so that it can still be sent.
+func ReadRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+// ReadResponseBody reads the body of a response, replacing it so that it can still be read by the client.
+func ReadResponseBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+// HTTPError returns the error of a failed response, with the message of its JSON body if it has one, like
+// `{"error": {"message": "..."}}` or `{"error": "..."}`.
+func HTTPError(resp *http.Response, body []byte) error {
+	var payload struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	msg := ""
+	if json.Unmarshal(body, &payload) == nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		switch {
+		case json.Unmarshal(payload.Error, &msg) == nil:
+		case json.Unmarshal(payload.Error, &nested) == nil && nested.Message != "":
+			msg = nested.Message
+		default:
+			msg = payload.Message
+		}
+	}
+	if msg == "" {
+		return errors.New(resp.Status)
+	}
+	return errors.New(resp.Status + ": " + msg)
+}
+
+// StreamBody wraps the body of a streamed response, calling onLine with each line as it's read
+// by the client until it returns true or an error, then onDone once with the error ending the
+// stream, which is nil if it's read until its end or closed by the client.
+func StreamBody(body io.ReadCloser, onLine func([]byte) (bool, error), onDone func(error)) io.ReadCloser {
+	return &streamBody{ReadCloser: body, onLine: onLine, onDone: onDone}
+}
+
+type streamBody struct {
+	io.ReadCloser
+	onLine  func([]byte) (bool, error)
+	onDone  func(error)
+	pending []byte
+	ended   bool
+	once    sync.Once
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.pending = append(b.pending, p[:n]...)
+	for !b.ended {
+		i := bytes.IndexByte(b.pending, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimSuffix(b.pending[:i], []byte("\r"))
+		b.pending = b.pending[i+1:]
+		b.line(line)
+	}
+	switch {
+	case err == io.EOF:
+		if len(b.pending) > 0 && !b.ended {
+			b.line(b.pending)
+		}
+		b.pending = nil
+		b.done(nil)
+	case err != nil:
+		b.done(err)
+	}
+	return n, err
+}
+
+func (b *streamBody) line(line []byte) {
+	done, err := b.onLine(line)
+	if done || err != nil {
+		b.ended = true
+		b.pending = nil
+		b.done(err)
+	}
+}
+
+func (b *streamBody) Close() error {
+	b.done(nil)
+	return b.ReadCloser.Close()
+}
+
+func (b *streamBody) done(err error) {
+	b.once.Do(func() { b.onDone(err) })
+}
+
+// SSEData returns the data of a line of a server-sent event stream, if it's a data line.
+func SSEData(line []byte) ([]byte, bool) {
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return nil, false
+	}
+	return bytes.TrimPrefix(data, []byte(" ")), true
+}
+
+// IsEventStream reports whether a response is a server-sent event stream.
+func IsEventStream(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
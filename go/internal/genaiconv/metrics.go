@@ -0,0 +1,143 @@
+package genaiconv
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Names of the metrics of calls.
+const (
+	tokenUsageMetric        = "gen_ai.client.token.usage"
+	operationDurationMetric = "gen_ai.client.operation.duration"
+	costMetric              = "operation.cost"
+)
+
+// Bucket boundaries advised by the semantic conventions.
+var (
+	tokenUsageBuckets = []float64{
+		1, 4, 16, 64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216, 67108864,
+	}
+	operationDurationBuckets = []float64{
+		0.01, 0.02, 0.04, 0.08, 0.16, 0.32, 0.64, 1.28, 2.56, 5.12, 10.24, 20.48, 40.96, 81.92,
+	}
+)
+
+// Price is the price of a model in USD per million tokens. The prices of cached tokens default to
+// the price of input tokens when they're zero.
+type Price struct {
+	Input      float64
+	Output     float64
+	CacheRead  float64
+	CacheWrite float64
+}
+
+// Metrics records the metrics of calls along with their spans: the token usage, the duration and,
+// for the models of Pricing, the cost.
+type Metrics struct {
+	Meter metric.Meter
+	// Pricing holds the prices of models by name. The price of a model is the one of its longest
+	// prefix, so that `gpt-4o` prices `gpt-4o-2024-08-06`.
+	Pricing map[string]Price
+}
+
+// Metrics records the metrics of the call with m, if it's not nil.
+func (s *Span) Metrics(m *Metrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = m
+}
+
+// price returns the price of model.
+func (m *Metrics) price(model string) (Price, bool) {
+	var price Price
+	found := ""
+	for name, p := range m.Pricing {
+		if strings.HasPrefix(model, name) && (found == "" || len(name) > len(found)) {
+			price, found = p, name
+		}
+	}
+	return price, found != ""
+}
+
+// cost returns the cost of a call, priced after the model of its response, or else of its request.
+func (m *Metrics) cost(model string, resp Response) (float64, bool) {
+	if m == nil || resp.Usage == nil {
+		return 0, false
+	}
+	if resp.Model != "" {
+		model = resp.Model
+	}
+	price, ok := m.price(model)
+	if !ok {
+		return 0, false
+	}
+	u := resp.Usage
+	cacheRead, cacheWrite := price.CacheRead, price.CacheWrite
+	if cacheRead == 0 {
+		cacheRead = price.Input
+	}
+	if cacheWrite == 0 {
+		cacheWrite = price.Input
+	}
+	uncached := u.InputTokens - u.CacheReadInputTokens - u.CacheCreationInputTokens
+	cost := float64(uncached)*price.Input + float64(u.CacheReadInputTokens)*cacheRead +
+		float64(u.CacheCreationInputTokens)*cacheWrite + float64(u.OutputTokens)*price.Output
+	return cost / 1e6, true
+}
+
+// record records the metrics of a call ending with resp.
+func (s *Span) record(resp Response) {
+	m := s.metrics
+	if m == nil || m.Meter == nil {
+		return
+	}
+	attrs := []attribute.KeyValue{OperationNameKey.String(s.operation)}
+	if s.provider != "" {
+		attrs = append(attrs, ProviderNameKey.String(s.provider))
+	}
+	if s.model != "" {
+		attrs = append(attrs, RequestModelKey.String(s.model))
+	}
+	if resp.Model != "" {
+		attrs = append(attrs, ResponseModelKey.String(resp.Model))
+	}
+	if s.errType != "" {
+		attrs = append(attrs, semconv.ErrorTypeKey.String(s.errType))
+	}
+	// The context of the span links the exemplars of the metrics to it.
+	ctx := trace.ContextWithSpan(context.Background(), s.span)
+	if duration, err := m.Meter.Float64Histogram(operationDurationMetric,
+		metric.WithDescription("Duration of GenAI client operations."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(operationDurationBuckets...),
+	); err == nil {
+		duration.Record(ctx, time.Since(s.start).Seconds(), metric.WithAttributes(attrs...))
+	}
+	if resp.Usage == nil {
+		return
+	}
+	if usage, err := m.Meter.Int64Histogram(tokenUsageMetric,
+		metric.WithDescription("Number of input and output tokens used."),
+		metric.WithUnit("{token}"),
+		metric.WithExplicitBucketBoundaries(tokenUsageBuckets...),
+	); err == nil {
+		usage.Record(ctx, resp.Usage.InputTokens, metric.WithAttributes(append(attrs, TokenTypeKey.String("input"))...))
+		if resp.Usage.OutputTokens > 0 {
+			usage.Record(ctx, resp.Usage.OutputTokens, metric.WithAttributes(append(attrs, TokenTypeKey.String("output"))...))
+		}
+	}
+	if c, ok := m.cost(s.model, resp); ok {
+		if counter, err := m.Meter.Float64Counter(costMetric,
+			metric.WithDescription("Cost of GenAI client operations."),
+			metric.WithUnit("USD"),
+		); err == nil {
+			counter.Add(ctx, c, metric.WithAttributes(attrs...))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package genaiconv
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// progressEvent is the name of the events recording the progress of a streamed response.
+const progressEvent = "gen_ai.stream.progress"
+
+// Progress configures the recording of the progress of streamed responses on their spans: the time
+// to the first chunk of the output, the number of chunks, and an event at most every Interval with the
+// number of chunks so far and, when message content is recorded, the partial output. The events are
+// recorded as chunks are received, so the last one of a stuck stream shows where it stopped. There
+// are no events if Interval is zero.
+type Progress struct {
+	Interval time.Duration
+}
+
+type progress struct {
+	*Progress
+	partial   func() Response
+	chunks    int
+	lastEvent time.Time
+}
+
+// StreamProgress records the progress of the streamed response of the call with p, if it's not nil.
+// partial returns the response received so far.
+func (s *Span) StreamProgress(p *Progress, partial func() Response) {
+	if p == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress = &progress{Progress: p, partial: partial, lastEvent: s.start}
+}
+
+// Chunk records the reception of a chunk of the output of a streamed response, if its progress is recorded.
+func (s *Span) Chunk() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.progress
+	if s.ended || p == nil {
+		return
+	}
+	p.chunks++
+	now := time.Now()
+	if p.chunks == 1 {
+		s.span.SetAttributes(ResponseTimeToFirstChunkKey.Float64(now.Sub(s.start).Seconds()))
+	}
+	if p.Interval <= 0 || now.Sub(p.lastEvent) < p.Interval {
+		return
+	}
+	p.lastEvent = now
+	attrs := []attribute.KeyValue{ResponseChunkCountKey.Int(p.chunks)}
+	if s.content && p.partial != nil {
+		if output := p.partial().Output; len(output) > 0 {
+			attrs = append(attrs, JSON(OutputMessagesKey, output))
+		}
+	}
+	s.span.AddEvent(progressEvent, trace.WithTimestamp(now), trace.WithAttributes(attrs...))
+}
+
+// ChunkCounter counts the chunks of the output of a streamed response. It's embedded in the
+// implementations of [Stream], which call Add for each line containing output.
+type ChunkCounter struct {
+	n int
+}
+
+// Add counts a chunk.
+func (c *ChunkCounter) Add() {
+	c.n++
+}
+
+// Chunks returns the number of chunks counted.
+func (c *ChunkCounter) Chunks() int {
+	return c.n
+}
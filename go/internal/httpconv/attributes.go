@@ -0,0 +1,105 @@
+package httpconv
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// ServerRequestAttributes returns the attributes of a server span known when the request starts.
+// route is the matched route template, if it's already known.
+func ServerRequestAttributes(r *http.Request, route string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(Message(r.Method, r.URL.Path, r.URL.RawQuery)),
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLPath(r.URL.Path),
+		semconv.URLScheme(Scheme(r)),
+		semconv.NetworkProtocolVersion(fmt.Sprintf("%d.%d", r.ProtoMajor, r.ProtoMinor)),
+	}
+	if r.URL.RawQuery != "" {
+		attrs = append(attrs, semconv.URLQuery(r.URL.RawQuery))
+	}
+	if host, port := SplitHostPort(r.Host); host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if port > 0 {
+			attrs = append(attrs, semconv.ServerPort(port))
+		}
+	}
+	if host, _ := SplitHostPort(r.RemoteAddr); host != "" {
+		attrs = append(attrs, semconv.ClientAddress(host))
+	}
+	if ua := r.UserAgent(); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
+	}
+	if route != "" {
+		attrs = append(attrs, semconv.HTTPRoute(route))
+	}
+	return attrs
+}
+
+// RouteFromPattern strips the method and host from a ServeMux pattern such as `GET example.com/users/{id}`.
+func RouteFromPattern(pattern string) string {
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+// Scheme returns the scheme of a server request, `http` or `https`.
+func Scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// SplitHostPort splits an address into its host and port, which is 0 if missing.
+func SplitHostPort(hostport string) (string, int) {
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport, 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
+
+// ClientRequestAttributes returns the attributes of a client span known before the request is sent.
+func ClientRequestAttributes(r *http.Request) []attribute.KeyValue {
+	u := *r.URL
+	u.User = nil
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(Message(r.Method, u.Host+u.Path, u.RawQuery)),
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLFull(u.String()),
+	}
+	if host, port := SplitHostPort(u.Host); host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if port == 0 {
+			port = defaultPort(u.Scheme)
+		}
+		if port > 0 {
+			attrs = append(attrs, semconv.ServerPort(port))
+		}
+	}
+	return attrs
+}
+
+func defaultPort(scheme string) int {
+	switch scheme {
+	case "http":
+		return 80
+	case "https":
+		return 443
+	}
+	return 0
+}
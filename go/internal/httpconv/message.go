@@ -0,0 +1,75 @@
+// Package httpconv holds the span names, messages and attributes shared by the HTTP integrations,
+// so that spans from every framework look the same in Logfire.
+package httpconv
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SpanName returns the low-cardinality name of a server span, e.g. `GET /users/{id}`.
+func SpanName(method, route string) string {
+	if route == "" {
+		return method
+	}
+	return method + " " + route
+}
+
+// Message returns the message of a span, e.g. `GET /users/42 ? page='2'`,
+// formatted like the messages of HTTP spans in the Python SDK.
+//
+// Query parameters are shown decoded after the path, shortest first so that they're visible
+// in the Logfire UI even if the whole message isn't, with keys and values truncated to 20 characters.
+func Message(method, path, rawQuery string) string {
+	msg := method + " " + path
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil || len(query) == 0 {
+		return msg
+	}
+	type pair struct{ key, value string }
+	var pairs []pair
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, pair{key, value})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		li := utf8.RuneCountInString(pairs[i].key) + utf8.RuneCountInString(pairs[i].value)
+		lj := utf8.RuneCountInString(pairs[j].key) + utf8.RuneCountInString(pairs[j].value)
+		if li != lj {
+			return li < lj
+		}
+		if pairs[i].key != pairs[j].key {
+			return pairs[i].key < pairs[j].key
+		}
+		return pairs[i].value < pairs[j].value
+	})
+	parts := make([]string, len(pairs))
+	for i, p := range pairs {
+		parts[i] = truncate(p.key) + "=" + quote(truncate(p.value))
+	}
+	return msg + " ? " + strings.Join(parts, " & ")
+}
+
+// truncate keeps the start and end of strings longer than 20 characters.
+func truncate(s string) string {
+	const maxLength = 20
+	runes := []rune(s)
+	if len(runes) <= maxLength {
+		return s
+	}
+	half := (maxLength - 1) / 2
+	return string(runes[:half]) + "…" + string(runes[len(runes)-half:])
+}
+
+// quote quotes s like Python's repr, which the Python SDK uses for query parameter values.
+func quote(s string) string {
+	q := "'"
+	if strings.Contains(s, "'") && !strings.Contains(s, `"`) {
+		q = `"`
+	}
+	r := strings.NewReplacer(`\`, `\\`, q, `\`+q, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return q + r.Replace(s) + q
+}
@@ -0,0 +1,32 @@
+package httpconv
+
+import "testing"
+
+func TestMessage(t *testing.T) {
+	for _, tc := range []struct {
+		query, want string
+	}{
+		{"", "GET /path"},
+		{"foo=1&bar=2%203", "GET /path ? foo='1' & bar='2 3'"},
+		{"long=" + "abcdefghijklmnopqrstuvwxyz", "GET /path ? long='abcdefghi…rstuvwxyz'"},
+		{"q=it's", `GET /path ? q="it's"`},
+	} {
+		if got := Message("GET", "/path", tc.query); got != tc.want {
+			t.Errorf("Message(%q) = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestRouteFromPattern(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"":                           "",
+		"/users/{id}":                "/users/{id}",
+		"GET /users/{id}":            "/users/{id}",
+		"GET example.com/users/{id}": "/users/{id}",
+		"example.com/":               "/",
+	} {
+		if got := RouteFromPattern(pattern); got != want {
+			t.Errorf("RouteFromPattern(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package httpconv
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// Names of the metrics of servers.
+const (
+	serverDurationMetric       = "http.server.request.duration"
+	serverActiveRequestsMetric = "http.server.active_requests"
+)
+
+// serverDurationBuckets are the bucket boundaries advised by the semantic conventions.
+var serverDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// knownMethods are the methods recorded in metrics, the others being recorded as `_OTHER`
+// so that clients can't create any number of series.
+var knownMethods = map[string]bool{
+	http.MethodConnect: true, http.MethodDelete: true, http.MethodGet: true, http.MethodHead: true,
+	http.MethodOptions: true, http.MethodPatch: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodTrace: true,
+}
+
+// ServerMetrics records the metrics of a server request along with its span: its duration and,
+// while it's handled, the number of active requests.
+type ServerMetrics struct {
+	meter metric.Meter
+	start time.Time
+	attrs []attribute.KeyValue
+}
+
+// StartServerMetrics starts recording the metrics of a request with method and scheme, e.g. from [Scheme].
+// [ServerMetrics.End] must be called once the request is handled.
+func StartServerMetrics(ctx context.Context, meter metric.Meter, method, scheme string) *ServerMetrics {
+	if !knownMethods[method] {
+		method = "_OTHER"
+	}
+	m := &ServerMetrics{
+		meter: meter,
+		start: time.Now(),
+		attrs: []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(method), semconv.URLScheme(scheme)},
+	}
+	if active, err := m.activeRequests(); err == nil {
+		active.Add(ctx, 1, metric.WithAttributes(m.attrs...))
+	}
+	return m
+}
+
+func (m *ServerMetrics) activeRequests() (metric.Int64UpDownCounter, error) {
+	return m.meter.Int64UpDownCounter(serverActiveRequestsMetric,
+		metric.WithDescription("Number of active HTTP server requests."),
+		metric.WithUnit("{request}"),
+	)
+}
+
+// End records the duration of the request with its route, if it's known, and the status of its response,
+// which should be 500 if the handler panicked. ctx should contain the span of the request, to link it
+// to the exemplars of the metrics.
+func (m *ServerMetrics) End(ctx context.Context, route string, status int) {
+	if active, err := m.activeRequests(); err == nil {
+		active.Add(ctx, -1, metric.WithAttributes(m.attrs...))
+	}
+	attrs := append(m.attrs, semconv.HTTPResponseStatusCode(status))
+	if route != "" {
+		attrs = append(attrs, semconv.HTTPRoute(route))
+	}
+	if status >= http.StatusInternalServerError {
+		attrs = append(attrs, semconv.ErrorTypeKey.String(strconv.Itoa(status)))
+	}
+	if duration, err := m.meter.Float64Histogram(serverDurationMetric,
+		metric.WithDescription("Duration of HTTP server requests."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(serverDurationBuckets...),
+	); err == nil {
+		duration.Record(ctx, time.Since(m.start).Seconds(), metric.WithAttributes(attrs...))
+	}
+}
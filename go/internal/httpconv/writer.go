@@ -0,0 +1,47 @@
+package httpconv
+
+import "net/http"
+
+// StatusRecorder records the status code written by a handler.
+type StatusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// NewStatusRecorder wraps w. The status defaults to 200, like for handlers which only call Write.
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status returns the status code of the response.
+func (w *StatusRecorder) Status() int {
+	return w.status
+}
+
+func (w *StatusRecorder) WriteHeader(status int) {
+	// Informational responses are followed by the final status.
+	if !w.wroteHeader && status >= http.StatusOK {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *StatusRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements [http.Flusher] for streaming handlers.
+func (w *StatusRecorder) Flush() {
+	w.wroteHeader = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets [http.ResponseController] access the underlying response writer.
+func (w *StatusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
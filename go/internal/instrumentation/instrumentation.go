@@ -0,0 +1,42 @@
+// Package instrumentation holds the helpers shared by the Logfire integrations.
+package instrumentation
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// Tracer returns the tracer of an integration, from the global tracer provider if provider is nil.
+//
+// Integrations should call it for every operation rather than once, so that code instrumented
+// before [logfire.Configure] is called still sends its spans to Logfire.
+//
+// The spans of operations running user code are ended with a deferred
+// span.End(trace.WithStackTrace(true)), with which the SDK records panics as exception events.
+func Tracer(provider trace.TracerProvider, name string) trace.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(name, trace.WithInstrumentationVersion(logfire.Version))
+}
+
+// Meter returns the meter of an integration, from the global meter provider if provider is nil.
+// Like [Tracer], it should be called for every operation.
+func Meter(provider metric.MeterProvider, name string) metric.Meter {
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+	return provider.Meter(name, metric.WithInstrumentationVersion(logfire.Version))
+}
+
+// Propagator returns propagators, or the global propagators if it's nil.
+func Propagator(propagators propagation.TextMapPropagator) propagation.TextMapPropagator {
+	if propagators != nil {
+		return propagators
+	}
+	return otel.GetTextMapPropagator()
+}
@@ -0,0 +1,170 @@
+// Package logconv converts the records of logging libraries into Logfire logs,
+// so that logs from every bridge look like those emitted by [logfire.Log].
+package logconv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// Code location attributes, using the names expected by the Logfire UI, like the logfire package.
+const (
+	codeFilepathKey = attribute.Key("code.filepath")
+	codeLinenoKey   = attribute.Key("code.lineno")
+	codeFunctionKey = attribute.Key("code.function")
+)
+
+// spanTypeLog is the [logfire.SpanTypeKey] value of logs.
+const spanTypeLog = "log"
+
+// Record is a log record of a logging library.
+type Record struct {
+	// Time is when the record was logged. Defaults to now.
+	Time    time.Time
+	Level   logfire.Level
+	Message string
+	Attrs   []attribute.KeyValue
+	// Err is recorded as an exception, with Stack as its stack trace if set.
+	// Without Err, Stack is recorded as the `code.stacktrace` attribute.
+	Err   error
+	Stack string
+	// File, Line and Function are where the record was logged, if known.
+	File     string
+	Line     int
+	Function string
+}
+
+// Emit emits r as a Logfire log, a span of zero duration which is a child of the span in ctx, if any.
+//
+// If asEvent is set and the span in ctx is recording, r is added to it as an event instead,
+// named after the message, with the exception attributes of its error if any. The status of the
+// span is left to its owner.
+func Emit(ctx context.Context, tracer trace.Tracer, r Record, asEvent bool) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if span := trace.SpanFromContext(ctx); asEvent && span.IsRecording() {
+		attrs := append(attributes(r, 5), r.Attrs...)
+		if r.Err != nil {
+			attrs = append(attrs,
+				semconv.ExceptionType(reflect.TypeOf(r.Err).String()),
+				semconv.ExceptionMessage(r.Err.Error()),
+			)
+			if r.Stack != "" {
+				attrs = append(attrs, semconv.ExceptionStacktrace(r.Stack))
+			}
+		}
+		span.AddEvent(r.Message, trace.WithAttributes(attrs...), trace.WithTimestamp(r.Time))
+		return
+	}
+
+	attrs := append(attributes(r, 3),
+		logfire.SpanTypeKey.String(spanTypeLog),
+		logfire.MsgTemplateKey.String(r.Message),
+		logfire.MsgKey.String(r.Message),
+	)
+	attrs = append(attrs, r.Attrs...)
+	_, span := tracer.Start(ctx, r.Message,
+		trace.WithAttributes(attrs...),
+		trace.WithTimestamp(r.Time),
+	)
+	if r.Err != nil {
+		opts := []trace.EventOption{trace.WithTimestamp(r.Time)}
+		if r.Stack != "" {
+			opts = append(opts, trace.WithAttributes(semconv.ExceptionStacktrace(r.Stack)))
+		}
+		span.RecordError(r.Err, opts...)
+	}
+	if r.Level >= logfire.LevelError {
+		span.SetStatus(codes.Error, "")
+	}
+	span.End(trace.WithTimestamp(r.Time))
+}
+
+// attributes returns the level and code location attributes of r,
+// with room for n more attributes besides those of r.
+func attributes(r Record, n int) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(r.Attrs)+n+5)
+	attrs = append(attrs, logfire.LevelNumKey.Int(int(r.Level)))
+	if r.File != "" {
+		attrs = append(attrs, codeFilepathKey.String(r.File), codeLinenoKey.Int(r.Line))
+	}
+	if r.Function != "" {
+		attrs = append(attrs, codeFunctionKey.String(r.Function))
+	}
+	if r.Err == nil && r.Stack != "" {
+		attrs = append(attrs, semconv.CodeStacktrace(r.Stack))
+	}
+	return attrs
+}
+
+// Value converts a field of a logging library to an attribute. Values without an attribute type,
+// like structs and maps, are recorded as JSON, so that the Logfire UI can render them.
+func Value(key string, v any) attribute.KeyValue {
+	switch v := v.(type) {
+	case nil:
+		return attribute.String(key, "<nil>")
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int8:
+		return attribute.Int(key, int(v))
+	case int16:
+		return attribute.Int(key, int(v))
+	case int32:
+		return attribute.Int(key, int(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint8:
+		return attribute.Int(key, int(v))
+	case uint16:
+		return attribute.Int(key, int(v))
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case uint:
+		return Uint64(key, uint64(v))
+	case uint64:
+		return Uint64(key, v)
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case time.Duration:
+		return attribute.String(key, v.String())
+	case time.Time:
+		return attribute.String(key, v.Format(time.RFC3339Nano))
+	case []string:
+		return attribute.StringSlice(key, v)
+	case error:
+		return attribute.String(key, v.Error())
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	case []byte:
+		return attribute.String(key, string(v))
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return attribute.String(key, string(b))
+	}
+	return attribute.String(key, fmt.Sprint(v))
+}
+
+// Uint64 returns an int64 attribute, or a string one if v overflows int64.
+func Uint64(key string, v uint64) attribute.KeyValue {
+	if v > 1<<63-1 {
+		return attribute.String(key, fmt.Sprint(v))
+	}
+	return attribute.Int64(key, int64(v))
+}
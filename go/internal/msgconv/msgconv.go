@@ -0,0 +1,44 @@
+// Package msgconv holds the span names, messages and attributes shared by the messaging integrations,
+// so that spans from every broker look the same in Logfire.
+package msgconv
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// SpanName returns the name of a messaging span, e.g. `send orders`.
+func SpanName(operation, destination string) string {
+	if destination == "" {
+		return operation
+	}
+	return operation + " " + destination
+}
+
+// Attributes returns the attributes shared by messaging spans, with the span name as the message.
+// operationType is one of the `messaging.operation.type` values, e.g. [semconv.MessagingOperationTypeSend].
+func Attributes(system, operationType attribute.KeyValue, operation, destination string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(SpanName(operation, destination)),
+		system,
+		operationType,
+		semconv.MessagingOperationName(operation),
+	}
+	if destination != "" {
+		attrs = append(attrs, semconv.MessagingDestinationName(destination))
+	}
+	return attrs
+}
+
+// RecordError records an error on a span and sets its status, if err isn't nil.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
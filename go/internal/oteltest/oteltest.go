@@ -0,0 +1,28 @@
+// Package oteltest holds the test helpers shared by the tests of the logfire package and the integrations.
+package oteltest
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Attributes returns attrs as a map, e.g. the attributes of a span or of an event.
+func Attributes(attrs []attribute.KeyValue) map[attribute.Key]any {
+	m := make(map[attribute.Key]any, len(attrs))
+	for _, kv := range attrs {
+		m[kv.Key] = kv.Value.AsInterface()
+	}
+	return m
+}
+
+// CheckAttributes reports the attributes of want which attrs doesn't have with the same value.
+func CheckAttributes(t testing.TB, attrs []attribute.KeyValue, want map[attribute.Key]any) {
+	t.Helper()
+	got := Attributes(attrs)
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("attribute %s = %v, want %v", key, got[key], value)
+		}
+	}
+}
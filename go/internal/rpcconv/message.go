@@ -0,0 +1,72 @@
+package rpcconv
+
+import (
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// The attributes of the `message` events, as named by earlier versions of the semantic conventions,
+// which are still understood by most backends.
+const (
+	MessageTypeKey    = attribute.Key("rpc.message.type")
+	MessageIDKey      = attribute.Key("rpc.message.id")
+	MessageSizeKey    = attribute.Key("rpc.message.uncompressed_size")
+	MessagePayloadKey = attribute.Key("rpc.message.payload")
+)
+
+var (
+	messageSent     = MessageTypeKey.String("SENT")
+	messageReceived = MessageTypeKey.String("RECEIVED")
+)
+
+// MessageOptions configures the `message` events of RPC spans.
+type MessageOptions struct {
+	// Events records a `message` event for each message sent and received.
+	Events bool
+	// Payloads records the protobuf messages as JSON in the events.
+	Payloads bool
+	// MaxPayloadSize truncates longer payloads, 0 means no limit.
+	MaxPayloadSize int
+}
+
+// MessageSent records a message sent if [MessageOptions.Events] is set.
+// id is the sequence number of the message in its direction, starting at 1.
+func (o MessageOptions) MessageSent(span trace.Span, id int, msg any) {
+	o.event(span, messageSent, id, msg)
+}
+
+// MessageReceived records a message received if [MessageOptions.Events] is set.
+func (o MessageOptions) MessageReceived(span trace.Span, id int, msg any) {
+	o.event(span, messageReceived, id, msg)
+}
+
+func (o MessageOptions) event(span trace.Span, typ attribute.KeyValue, id int, msg any) {
+	if !o.Events {
+		return
+	}
+	attrs := []attribute.KeyValue{typ, MessageIDKey.Int(id)}
+	if m, ok := msg.(proto.Message); ok {
+		attrs = append(attrs, MessageSizeKey.Int(proto.Size(m)))
+		if o.Payloads {
+			if payload, err := protojson.Marshal(m); err == nil {
+				attrs = append(attrs, MessagePayloadKey.String(truncate(string(payload), o.MaxPayloadSize)))
+			}
+		}
+	}
+	span.AddEvent("message", trace.WithAttributes(attrs...))
+}
+
+// truncate cuts s to at most maxSize bytes without splitting a UTF-8 character.
+func truncate(s string, maxSize int) string {
+	if maxSize <= 0 || len(s) <= maxSize {
+		return s
+	}
+	for maxSize > 0 && !utf8.RuneStart(s[maxSize]) {
+		maxSize--
+	}
+	return s[:maxSize]
+}
@@ -0,0 +1,147 @@
+// Package rpcconv holds the span conventions shared by the RPC integrations.
+//
+// gRPC and Connect use the same status codes, which are passed as numbers so that
+// this package doesn't depend on either.
+package rpcconv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// Code is a gRPC status code.
+type Code uint32
+
+// CodeOK is the status code of successful RPCs.
+const CodeOK Code = 0
+
+// codeNames are the values of `rpc.response.status_code`, as spelled by the gRPC specification.
+var codeNames = [...]string{
+	"OK",
+	"CANCELLED",
+	"UNKNOWN",
+	"INVALID_ARGUMENT",
+	"DEADLINE_EXCEEDED",
+	"NOT_FOUND",
+	"ALREADY_EXISTS",
+	"PERMISSION_DENIED",
+	"RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION",
+	"ABORTED",
+	"OUT_OF_RANGE",
+	"UNIMPLEMENTED",
+	"INTERNAL",
+	"UNAVAILABLE",
+	"DATA_LOSS",
+	"UNAUTHENTICATED",
+}
+
+// String returns the name of the code, e.g. "NOT_FOUND", or its number if it's unknown.
+func (c Code) String() string {
+	if int(c) < len(codeNames) {
+		return codeNames[c]
+	}
+	return strconv.FormatUint(uint64(c), 10)
+}
+
+// ServerError is whether the code means that the server failed to handle the RPC,
+// as opposed to the client sending an invalid one. Only these give server spans an error status,
+// following the semantic conventions.
+func (c Code) ServerError() bool {
+	switch c {
+	case 2, 4, 12, 13, 14, 15: // UNKNOWN, DEADLINE_EXCEEDED, UNIMPLEMENTED, INTERNAL, UNAVAILABLE, DATA_LOSS
+		return true
+	}
+	return int(c) >= len(codeNames)
+}
+
+// Level returns the Logfire level of spans with the code: info for OK, error for server errors
+// and warn for the others.
+func (c Code) Level() logfire.Level {
+	switch {
+	case c == CodeOK:
+		return logfire.LevelInfo
+	case c.ServerError():
+		return logfire.LevelError
+	}
+	return logfire.LevelWarn
+}
+
+// SpanName returns the span name of an RPC, the full method without the leading slash,
+// e.g. `helloworld.Greeter/SayHello`.
+func SpanName(fullMethod string) string {
+	if name := strings.TrimPrefix(fullMethod, "/"); name != "" {
+		return name
+	}
+	return "rpc"
+}
+
+// Attributes returns the attributes of an RPC span known when it starts.
+func Attributes(system attribute.KeyValue, fullMethod string) []attribute.KeyValue {
+	return []attribute.KeyValue{system, semconv.RPCMethod(strings.TrimPrefix(fullMethod, "/"))}
+}
+
+// ServerAddress returns the `server.address` and `server.port` attributes of a client
+// connecting to a target like "dns:///example.com:443" or "localhost:50051".
+func ServerAddress(target string) []attribute.KeyValue {
+	if path, ok := strings.CutPrefix(target, "unix://"); ok {
+		return []attribute.KeyValue{semconv.ServerAddress(path)}
+	}
+	if i := strings.Index(target, "://"); i >= 0 {
+		target = target[i+len("://"):]
+		// Drop the authority of targets like "dns://8.8.8.8/example.com:443".
+		if j := strings.Index(target, "/"); j >= 0 {
+			target = target[j+1:]
+		}
+	}
+	if target == "" {
+		return nil
+	}
+	host, port := httpconv.SplitHostPort(target)
+	attrs := []attribute.KeyValue{semconv.ServerAddress(host)}
+	if port != 0 {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+	return attrs
+}
+
+// CodeInternal is the status code of RPCs whose handler panicked.
+const CodeInternal Code = 13
+
+// SetStatus records the status code of an RPC, as spelled by the RPC system, and the Logfire level it maps to.
+// name is empty for RPC systems which don't have a name for CodeOK.
+//
+// Following the semantic conventions, server spans only get an error status for server errors,
+// e.g. not for NOT_FOUND, while client spans get one for any code but OK.
+func SetStatus(span trace.Span, kind trace.SpanKind, code Code, name string, err error, message string) {
+	if name != "" {
+		span.SetAttributes(semconv.RPCResponseStatusCode(name))
+	}
+	span.SetAttributes(logfire.LevelNumKey.Int(int(code.Level())))
+	if code == CodeOK {
+		return
+	}
+	if kind == trace.SpanKindClient || code.ServerError() {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, message)
+	}
+}
+
+// RecordPanic gives the span the status of a panicking handler, then panics again.
+// name is the name of [CodeInternal] in the RPC system.
+func RecordPanic(span trace.Span, name string) {
+	if p := recover(); p != nil {
+		span.SetAttributes(semconv.RPCResponseStatusCode(name), logfire.LevelNumKey.Int(int(logfire.LevelError)))
+		span.SetStatus(codes.Error, fmt.Sprint(p))
+		panic(p)
+	}
+}
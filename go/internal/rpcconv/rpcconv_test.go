@@ -0,0 +1,72 @@
+package rpcconv
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func TestCode(t *testing.T) {
+	for _, tt := range []struct {
+		code  Code
+		name  string
+		level logfire.Level
+	}{
+		{0, "OK", logfire.LevelInfo},
+		{1, "CANCELLED", logfire.LevelWarn},
+		{5, "NOT_FOUND", logfire.LevelWarn},
+		{13, "INTERNAL", logfire.LevelError},
+		{16, "UNAUTHENTICATED", logfire.LevelWarn},
+		{42, "42", logfire.LevelError},
+	} {
+		if got := tt.code.String(); got != tt.name {
+			t.Errorf("Code(%d).String() = %q, want %q", tt.code, got, tt.name)
+		}
+		if got := tt.code.Level(); got != tt.level {
+			t.Errorf("Code(%d).Level() = %v, want %v", tt.code, got, tt.level)
+		}
+	}
+}
+
+func TestServerAddress(t *testing.T) {
+	for target, want := range map[string]map[attribute.Key]any{
+		"localhost:50051":           {"server.address": "localhost", "server.port": int64(50051)},
+		"dns:///example.com:443":    {"server.address": "example.com", "server.port": int64(443)},
+		"dns://8.8.8.8/example.com": {"server.address": "example.com"},
+		"passthrough:///bufnet":     {"server.address": "bufnet"},
+		"unix:///var/run/grpc.sock": {"server.address": "/var/run/grpc.sock"},
+	} {
+		got := map[attribute.Key]any{}
+		for _, kv := range ServerAddress(target) {
+			got[kv.Key] = kv.Value.AsInterface()
+		}
+		if len(got) != len(want) {
+			t.Errorf("ServerAddress(%q) = %v, want %v", target, got, want)
+			continue
+		}
+		for key, value := range want {
+			if got[key] != value {
+				t.Errorf("ServerAddress(%q) = %v, want %v", target, got, want)
+			}
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tt := range []struct {
+		s       string
+		maxSize int
+		want    string
+	}{
+		{"hello", 0, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "h"},
+		{"héllo", 3, "hé"},
+	} {
+		if got := truncate(tt.s, tt.maxSize); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.maxSize, got, tt.want)
+		}
+	}
+}
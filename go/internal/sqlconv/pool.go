@@ -0,0 +1,59 @@
+package sqlconv
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// PoolStats are the statistics of a connection pool at the time they're observed.
+type PoolStats struct {
+	// Idle and Used are the number of connections which are idle and in use.
+	Idle, Used int64
+	// Max is the maximum number of open connections, or 0 if it's unlimited.
+	Max int64
+	// WaitCount is the number of times a connection was waited for since the pool was opened,
+	// and WaitDuration the total time waited.
+	WaitCount    int64
+	WaitDuration time.Duration
+}
+
+// RegisterPoolMetrics registers observable metrics of a pool named name, whose stats are read at each collection:
+// `db.client.connection.count` by state and `db.client.connection.max` of the semantic conventions,
+// and the cumulative `db.client.connection.wait_count` and `db.client.connection.wait_duration`.
+func RegisterPoolMetrics(meter metric.Meter, name string, stats func() PoolStats) (metric.Registration, error) {
+	count, err1 := meter.Int64ObservableUpDownCounter("db.client.connection.count",
+		metric.WithDescription("The number of connections that are currently in state described by the state attribute."),
+		metric.WithUnit("{connection}"))
+	maximum, err2 := meter.Int64ObservableUpDownCounter("db.client.connection.max",
+		metric.WithDescription("The maximum number of open connections allowed."),
+		metric.WithUnit("{connection}"))
+	waitCount, err3 := meter.Int64ObservableCounter("db.client.connection.wait_count",
+		metric.WithDescription("The number of times a connection was waited for."),
+		metric.WithUnit("{wait}"))
+	waitDuration, err4 := meter.Float64ObservableCounter("db.client.connection.wait_duration",
+		metric.WithDescription("The total time spent waiting for a connection."),
+		metric.WithUnit("s"))
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return nil, err
+	}
+
+	pool := semconv.DBClientConnectionPoolName(name)
+	idle := metric.WithAttributes(pool, semconv.DBClientConnectionStateIdle)
+	used := metric.WithAttributes(pool, semconv.DBClientConnectionStateUsed)
+	attrs := metric.WithAttributes(pool)
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		s := stats()
+		o.ObserveInt64(count, s.Idle, idle)
+		o.ObserveInt64(count, s.Used, used)
+		if s.Max > 0 {
+			o.ObserveInt64(maximum, s.Max, attrs)
+		}
+		o.ObserveInt64(waitCount, s.WaitCount, attrs)
+		o.ObserveFloat64(waitDuration, s.WaitDuration.Seconds(), attrs)
+		return nil
+	}, count, maximum, waitCount, waitDuration)
+}
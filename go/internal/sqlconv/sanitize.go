@@ -0,0 +1,185 @@
+// Package sqlconv holds the span names, messages and attributes shared by the SQL integrations.
+package sqlconv
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sanitize replaces the string and numeric literals of a query with `?`, so that values
+// such as emails or passwords embedded in queries aren't recorded. Placeholders like `$1`,
+// `?`, `:name` and `@p1`, quoted identifiers and comments are kept.
+//
+// Strings are assumed to allow backslash escapes like in MySQL: for other dialects
+// this can only sanitize more than needed.
+func Sanitize(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'':
+			i = skipString(query, i+1)
+			b.WriteByte('?')
+		case c == '"' || c == '`':
+			end := skipQuoted(query, i+1, c)
+			b.WriteString(query[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			b.WriteString(query[i : i+end])
+			i += end
+		case c == '$':
+			if end, ok := skipDollarQuoted(query, i); ok {
+				b.WriteByte('?')
+				i = end
+				continue
+			}
+			// A positional parameter like $1.
+			end := i + 1
+			for end < len(query) && isDigit(query[end]) {
+				end++
+			}
+			b.WriteString(query[i:end])
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			i = skipNumber(query, i)
+			b.WriteByte('?')
+		case isWordByte(c) || c == ':' || c == '@':
+			// Identifiers, keywords and named parameters, which may contain digits.
+			end := i + 1
+			for end < len(query) && isWordByte(query[end]) {
+				end++
+			}
+			b.WriteString(query[i:end])
+			i = end
+		default:
+			r, size := utf8.DecodeRuneInString(query[i:])
+			if unicode.IsLetter(r) {
+				// Identifiers with non-ASCII letters.
+				end := i + size
+				for end < len(query) {
+					r, size := utf8.DecodeRuneInString(query[end:])
+					if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+						break
+					}
+					end += size
+				}
+				b.WriteString(query[i:end])
+				i = end
+				continue
+			}
+			b.WriteString(query[i : i+size])
+			i += size
+		}
+	}
+	return b.String()
+}
+
+// skipString returns the index after the string starting before i.
+func skipString(query string, i int) int {
+	for i < len(query) {
+		switch query[i] {
+		case '\\':
+			i += 2
+			continue
+		case '\'':
+			// Quotes are escaped by doubling them.
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(query)
+}
+
+// skipQuoted returns the index after the identifier quoted with q starting before i.
+func skipQuoted(query string, i int, q byte) int {
+	for i < len(query) {
+		if query[i] == q {
+			if i+1 < len(query) && query[i+1] == q {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(query)
+}
+
+// skipDollarQuoted returns the index after a PostgreSQL dollar-quoted string like
+// `$$text$$` or `$tag$text$tag$` starting at i.
+func skipDollarQuoted(query string, i int) (int, bool) {
+	end := i + 1
+	if end < len(query) && isDigit(query[end]) {
+		return 0, false
+	}
+	for end < len(query) && isWordByte(query[end]) {
+		end++
+	}
+	if end >= len(query) || query[end] != '$' {
+		return 0, false
+	}
+	tag := query[i : end+1]
+	closing := strings.Index(query[end+1:], tag)
+	if closing < 0 {
+		return len(query), true
+	}
+	return end + 1 + closing + len(tag), true
+}
+
+// skipNumber returns the index after the numeric literal starting at i.
+func skipNumber(query string, i int) int {
+	if strings.HasPrefix(query[i:], "0x") || strings.HasPrefix(query[i:], "0X") {
+		i += 2
+		for i < len(query) && isHexDigit(query[i]) {
+			i++
+		}
+		return i
+	}
+	for i < len(query) && (isDigit(query[i]) || query[i] == '.') {
+		i++
+	}
+	if i < len(query) && (query[i] == 'e' || query[i] == 'E') {
+		j := i + 1
+		if j < len(query) && (query[j] == '+' || query[j] == '-') {
+			j++
+		}
+		if j < len(query) && isDigit(query[j]) {
+			i = j
+			for i < len(query) && isDigit(query[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || isDigit(c) || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
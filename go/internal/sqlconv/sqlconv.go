@@ -0,0 +1,272 @@
+package sqlconv
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// RowsAffectedKey records the number of rows affected by a statement.
+// The semantic conventions only define `db.response.returned_rows`.
+const RowsAffectedKey = attribute.Key("db.response.affected_rows")
+
+// maxMessageLength is the number of characters of a query kept in the message of its span.
+const maxMessageLength = 200
+
+// Query describes a query for its span.
+type Query struct {
+	// Text is the query, sanitized unless sanitization is disabled.
+	Text string
+	// Operation is the SQL command, e.g. "SELECT".
+	Operation string
+	// Collection is the table the query operates on, if there's a single obvious one.
+	Collection string
+	// Summary is the low-cardinality name of the query, e.g. "SELECT users" or "CREATE TABLE users".
+	Summary string
+	// Prefix is prepended to the span name and message of operations other than executing the query,
+	// e.g. "prepare".
+	Prefix string
+}
+
+// Parse sanitizes and summarizes a query.
+func Parse(query string, sanitize bool) Query {
+	sanitized := Sanitize(query)
+	q := summarize(tokenize(sanitized))
+	q.Text = query
+	if sanitize {
+		q.Text = sanitized
+	}
+	return q
+}
+
+// SpanName returns the name of the span of the query: its summary, or the database system if it can't be summarized.
+func (q Query) SpanName(system string) string {
+	name := q.Summary
+	switch {
+	case name != "":
+	case system != "":
+		name = system
+	default:
+		name = "query"
+	}
+	if q.Prefix != "" {
+		name = q.Prefix + " " + name
+	}
+	return name
+}
+
+// Attributes returns the attributes of the span of the query, with the query as the message.
+func (q Query) Attributes() []attribute.KeyValue {
+	msg := Message(q.Text)
+	if q.Prefix != "" {
+		msg = q.Prefix + " " + msg
+	}
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(msg),
+		semconv.DBQueryText(q.Text),
+	}
+	if q.Operation != "" {
+		attrs = append(attrs, semconv.DBOperationName(q.Operation))
+	}
+	if q.Collection != "" {
+		attrs = append(attrs, semconv.DBCollectionName(q.Collection))
+	}
+	if q.Summary != "" {
+		attrs = append(attrs, semconv.DBQuerySummary(q.Summary))
+	}
+	return attrs
+}
+
+// systems maps the names of common drivers and GORM dialects to their `db.system.name`.
+var systems = map[string]string{
+	"postgres":   "postgresql",
+	"pgx":        "postgresql",
+	"mysql":      "mysql",
+	"sqlite":     "sqlite",
+	"sqlite3":    "sqlite",
+	"sqlserver":  "microsoft.sql_server",
+	"mssql":      "microsoft.sql_server",
+	"oracle":     "oracle.db",
+	"godror":     "oracle.db",
+	"clickhouse": "clickhouse",
+}
+
+// System returns the `db.system.name` of a driver or dialect name, or "" if it isn't known.
+func System(name string) string {
+	return systems[name]
+}
+
+// Message returns the query on a single line, truncated to keep messages readable.
+func Message(query string) string {
+	msg := strings.Join(strings.Fields(query), " ")
+	if runes := []rune(msg); len(runes) > maxMessageLength {
+		msg = string(runes[:maxMessageLength-1]) + "…"
+	}
+	return msg
+}
+
+// tokenize splits a sanitized query into words, with quotes removed from identifiers,
+// and punctuation. Comments are skipped.
+func tokenize(query string) []string {
+	var tokens []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '"' || c == '`' || c == '[' || isWordByte(c) || c >= utf8.RuneSelf:
+			end := i
+			for end < len(query) {
+				switch q := query[end]; {
+				case q == '"' || q == '`':
+					next := skipQuoted(query, end+1, q)
+					end = next
+				case q == '[':
+					next := strings.IndexByte(query[end:], ']')
+					if next < 0 {
+						end = len(query)
+					} else {
+						end += next + 1
+					}
+				case isWordByte(q) || q == '.' || q == '$' || q >= utf8.RuneSelf:
+					end++
+				default:
+					goto word
+				}
+			}
+		word:
+			tokens = append(tokens, unquote(query[i:end]))
+			i = end
+		case unicode.IsSpace(rune(c)):
+			i++
+		default:
+			tokens = append(tokens, query[i:i+1])
+			i++
+		}
+	}
+	return tokens
+}
+
+// unquote removes the quotes of the parts of an identifier like `"public"."users"`.
+func unquote(identifier string) string {
+	return strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "").Replace(identifier)
+}
+
+// tableKeywords introduce the part of a statement naming its table.
+var tableKeywords = map[string]string{
+	"SELECT":  "FROM",
+	"DELETE":  "FROM",
+	"INSERT":  "INTO",
+	"REPLACE": "INTO",
+	"MERGE":   "INTO",
+}
+
+// ddlCommands are followed by the type and name of the object they operate on.
+var ddlCommands = map[string]bool{"CREATE": true, "DROP": true, "ALTER": true, "TRUNCATE": true}
+
+// summarize extracts the operation and table of a statement, e.g. `SELECT users` for
+// `SELECT * FROM users WHERE id = ?`. Only the main statement of queries using CTEs is summarized.
+func summarize(tokens []string) Query {
+	if len(tokens) == 0 {
+		return Query{}
+	}
+	op := strings.ToUpper(tokens[0])
+	rest := tokens[1:]
+	if op == "WITH" {
+		// Skip the common table expressions to the main statement.
+		depth := 0
+		for i, token := range rest {
+			switch token {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			default:
+				upper := strings.ToUpper(token)
+				if _, ok := tableKeywords[upper]; depth == 0 && (ok || upper == "UPDATE") {
+					op, rest = upper, rest[i+1:]
+					goto main
+				}
+			}
+		}
+		return Query{Operation: op, Summary: op}
+	}
+main:
+	q := Query{Operation: op, Summary: op}
+	switch {
+	case op == "UPDATE":
+		q.Collection = identifier(rest, 0)
+	case ddlCommands[op]:
+		i := skipWords(rest, 0, "OR", "REPLACE", "TEMP", "TEMPORARY", "UNIQUE")
+		// TRUNCATE is the only command whose object type is optional.
+		if op != "TRUNCATE" || (i < len(rest) && strings.EqualFold(rest[i], "TABLE")) {
+			if objectType := identifier(rest, i); objectType != "" {
+				q.Summary += " " + strings.ToUpper(objectType)
+				i++
+			}
+		}
+		q.Collection = identifier(rest, skipWords(rest, i, "IF", "NOT", "EXISTS"))
+	default:
+		if keyword, ok := tableKeywords[op]; ok {
+			q.Collection = tableAfter(rest, keyword)
+		}
+	}
+	if q.Collection != "" {
+		q.Summary += " " + q.Collection
+	}
+	return q
+}
+
+// skipWords returns the index of the first token from i which isn't one of the words.
+func skipWords(tokens []string, i int, words ...string) int {
+	for i < len(tokens) && slices.ContainsFunc(words, func(word string) bool { return strings.EqualFold(tokens[i], word) }) {
+		i++
+	}
+	return i
+}
+
+// tableAfter returns the table following the keyword outside of subqueries.
+func tableAfter(tokens []string, keyword string) string {
+	depth := 0
+	for i, token := range tokens {
+		switch token {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		default:
+			if depth == 0 && strings.EqualFold(token, keyword) {
+				return identifier(tokens, i+1)
+			}
+		}
+	}
+	return ""
+}
+
+// identifier returns tokens[i] if it's an identifier, e.g. not a subquery.
+func identifier(tokens []string, i int) string {
+	if i < len(tokens) && isIdentifier(tokens[i]) {
+		return tokens[i]
+	}
+	return ""
+}
+
+func isIdentifier(token string) bool {
+	return token != "" && token != "?" && !strings.ContainsAny(token[:1], "()[],;=*")
+}
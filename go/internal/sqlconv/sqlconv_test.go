@@ -0,0 +1,60 @@
+package sqlconv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	for query, want := range map[string]string{
+		"SELECT * FROM users WHERE email = 'a@b.c' AND age > 42": "SELECT * FROM users WHERE email = ? AND age > ?",
+		"SELECT * FROM t2 WHERE id = $1 AND name = :name":        "SELECT * FROM t2 WHERE id = $1 AND name = :name",
+		`SELECT "col1" FROM "t" WHERE x = 'it''s' OR y = 'a\'b'`: `SELECT "col1" FROM "t" WHERE x = ? OR y = ?`,
+		"INSERT INTO t VALUES (1.5, -2, 3e10, 0xFF, .5)":         "INSERT INTO t VALUES (?, -?, ?, ?, ?)",
+		"SELECT $$secret$$, $tag$also secret$tag$":               "SELECT ?, ?",
+		"SELECT 1 -- keep 'comments'\nFROM dual":                 "SELECT ? -- keep 'comments'\nFROM dual",
+		"SELECT * FROM café WHERE n = 'é' AND m = @p1":           "SELECT * FROM café WHERE n = ? AND m = @p1",
+		"SELECT * FROM t WHERE password = 'unterminated":         "SELECT * FROM t WHERE password = ?",
+	} {
+		if got := Sanitize(query); got != want {
+			t.Errorf("Sanitize(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for query, want := range map[string]Query{
+		"SELECT id FROM users WHERE id = 1":                              {Operation: "SELECT", Collection: "users", Summary: "SELECT users"},
+		"select * from public.users u join orders o on o.user_id = u.id": {Operation: "SELECT", Collection: "public.users", Summary: "SELECT public.users"},
+		`SELECT * FROM "Users"`:                                          {Operation: "SELECT", Collection: "Users", Summary: "SELECT Users"},
+		"SELECT * FROM (SELECT * FROM users) AS u":                       {Operation: "SELECT", Summary: "SELECT"},
+		"SELECT (SELECT max(id) FROM orders) FROM users":                 {Operation: "SELECT", Collection: "users", Summary: "SELECT users"},
+		"INSERT INTO orders (id) VALUES ($1)":                            {Operation: "INSERT", Collection: "orders", Summary: "INSERT orders"},
+		"UPDATE users SET name = 'x'":                                    {Operation: "UPDATE", Collection: "users", Summary: "UPDATE users"},
+		"DELETE FROM sessions WHERE expires < now()":                     {Operation: "DELETE", Collection: "sessions", Summary: "DELETE sessions"},
+		"WITH recent AS (SELECT * FROM orders) SELECT * FROM recent":     {Operation: "SELECT", Collection: "recent", Summary: "SELECT recent"},
+		"CREATE TABLE IF NOT EXISTS users (id int)":                      {Operation: "CREATE", Collection: "users", Summary: "CREATE TABLE users"},
+		"create or replace view active_users as select 1":                {Operation: "CREATE", Collection: "active_users", Summary: "CREATE VIEW active_users"},
+		"DROP INDEX users_email":                                         {Operation: "DROP", Collection: "users_email", Summary: "DROP INDEX users_email"},
+		"TRUNCATE users":                                                 {Operation: "TRUNCATE", Collection: "users", Summary: "TRUNCATE users"},
+		"BEGIN":                                                          {Operation: "BEGIN", Summary: "BEGIN"},
+		"/* app=web */ SELECT 1":                                         {Operation: "SELECT", Summary: "SELECT"},
+		"":                                                               {},
+	} {
+		got := Parse(query, true)
+		got.Text = ""
+		if got != want {
+			t.Errorf("Parse(%q) = %+v, want %+v", query, got, want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if got := Message("SELECT *\n\tFROM users\n  WHERE id = ?"); got != "SELECT * FROM users WHERE id = ?" {
+		t.Errorf("unexpected message %q", got)
+	}
+	long := "SELECT " + strings.Repeat("a, ", 300)
+	if got := []rune(Message(long)); len(got) > maxMessageLength {
+		t.Errorf("expected the message to be truncated, got %d characters", len(got))
+	}
+}
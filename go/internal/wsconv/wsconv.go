@@ -0,0 +1,246 @@
+// Package wsconv holds the span names, attributes and events shared by the WebSocket integrations,
+// so that connections from every library look the same in Logfire.
+//
+// A connection is a span lasting from the handshake until it's closed, with an event for each message.
+package wsconv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// SubprotocolKey records the subprotocol negotiated by a connection.
+	SubprotocolKey = attribute.Key("websocket.subprotocol")
+	// directionKey records whether a message was `sent` or `received`.
+	directionKey = attribute.Key("websocket.message.direction")
+	// opcodeKey records the type of a message, e.g. `text` or `close`.
+	opcodeKey = attribute.Key("websocket.message.opcode")
+	// sizeKey records the size of the payload of a message in bytes.
+	sizeKey = attribute.Key("websocket.message.size")
+	// closeCodeKey records the status code of the close frame ending a connection.
+	closeCodeKey = attribute.Key("websocket.close.code")
+	// closeReasonKey records the reason of the close frame ending a connection.
+	closeReasonKey = attribute.Key("websocket.close.reason")
+	// closeDirectionKey records whether the close frame ending a connection was `sent` or `received`.
+	closeDirectionKey = attribute.Key("websocket.close.direction")
+	// messagesSentKey records the number of data messages sent on a connection.
+	messagesSentKey = attribute.Key("websocket.messages.sent")
+	// messagesReceivedKey records the number of data messages received on a connection.
+	messagesReceivedKey = attribute.Key("websocket.messages.received")
+	// bytesSentKey records the size of the data messages sent on a connection.
+	bytesSentKey = attribute.Key("websocket.bytes.sent")
+	// bytesReceivedKey records the size of the data messages received on a connection.
+	bytesReceivedKey = attribute.Key("websocket.bytes.received")
+)
+
+// Opcode is the type of a WebSocket frame, as numbered by RFC 6455.
+type Opcode int
+
+const (
+	OpText   Opcode = 1
+	OpBinary Opcode = 2
+	OpClose  Opcode = 8
+	OpPing   Opcode = 9
+	OpPong   Opcode = 10
+)
+
+var opcodeNames = map[Opcode]string{
+	OpText:   "text",
+	OpBinary: "binary",
+	OpClose:  "close",
+	OpPing:   "ping",
+	OpPong:   "pong",
+}
+
+func (o Opcode) String() string {
+	if name, ok := opcodeNames[o]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// data is whether frames of the opcode are data messages rather than control frames.
+func (o Opcode) data() bool {
+	return o == OpText || o == OpBinary
+}
+
+// Direction is whether a message was sent or received.
+type Direction string
+
+const (
+	Sent     Direction = "sent"
+	Received Direction = "received"
+)
+
+// SpanName returns the name of the span of a connection, e.g. `WebSocket /chat`.
+func SpanName(path string) string {
+	if path == "" {
+		path = "/"
+	}
+	return "WebSocket " + path
+}
+
+// ServerAttributes returns the attributes of the span of a connection accepted by a server.
+func ServerAttributes(r *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(SpanName(r.URL.Path)),
+		semconv.NetworkProtocolName("websocket"),
+		semconv.URLPath(r.URL.Path),
+	}
+	if host, _ := httpconv.SplitHostPort(r.Host); host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+	}
+	if host, _ := httpconv.SplitHostPort(r.RemoteAddr); host != "" {
+		attrs = append(attrs, semconv.ClientAddress(host))
+	}
+	if ua := r.UserAgent(); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
+	}
+	return attrs
+}
+
+// ClientAttributes returns the attributes of the span of a connection dialed by a client.
+func ClientAttributes(u *url.URL) []attribute.KeyValue {
+	redacted := *u
+	redacted.User = nil
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(SpanName(u.Host + u.Path)),
+		semconv.NetworkProtocolName("websocket"),
+		semconv.URLFull(redacted.String()),
+	}
+	if host, port := httpconv.SplitHostPort(u.Host); host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if port > 0 {
+			attrs = append(attrs, semconv.ServerPort(port))
+		}
+	}
+	return attrs
+}
+
+// CloseLevel returns the Logfire level of a connection closed with the status code: info for
+// normal closures, error for abnormal closures and server errors, and warn for the others,
+// which are mostly protocol violations of the peer.
+func CloseLevel(code int) logfire.Level {
+	switch code {
+	case 1000, 1001, 1005: // Normal Closure, Going Away, No Status Received
+		return logfire.LevelInfo
+	case 1006, 1011, 1012, 1013, 1014, 1015: // Abnormal Closure, Internal Error, Service Restart, Try Again Later, Bad Gateway, TLS Handshake
+		return logfire.LevelError
+	}
+	return logfire.LevelWarn
+}
+
+// Conn records the messages of a connection on its span.
+type Conn struct {
+	span trace.Span
+
+	mu               sync.Mutex
+	messagesSent     int
+	messagesReceived int
+	bytesSent        int
+	bytesReceived    int
+	closed           bool
+	ended            bool
+}
+
+// Start starts the span of a connection.
+func Start(ctx context.Context, tracer trace.Tracer, kind trace.SpanKind, name string, attrs ...attribute.KeyValue) (context.Context, *Conn) {
+	ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+	return ctx, &Conn{span: span}
+}
+
+// Span returns the span of the connection.
+func (c *Conn) Span() trace.Span {
+	return c.span
+}
+
+// Message records a message sent or received, with the size of its payload in bytes.
+func (c *Conn) Message(direction Direction, opcode Opcode, size int) {
+	c.span.AddEvent("message", trace.WithAttributes(
+		directionKey.String(string(direction)),
+		opcodeKey.String(opcode.String()),
+		sizeKey.Int(size),
+	))
+	if !opcode.data() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if direction == Sent {
+		c.messagesSent++
+		c.bytesSent += size
+	} else {
+		c.messagesReceived++
+		c.bytesReceived += size
+	}
+}
+
+// Close records the close frame sent or received first, which ends the connection.
+// Its status code sets the level of the span, and an error status for abnormal closures.
+func (c *Conn) Close(direction Direction, code int, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	attrs := []attribute.KeyValue{
+		closeCodeKey.Int(code),
+		closeDirectionKey.String(string(direction)),
+		logfire.LevelNumKey.Int(int(CloseLevel(code))),
+	}
+	if reason != "" {
+		attrs = append(attrs, closeReasonKey.String(reason))
+	}
+	c.span.SetAttributes(attrs...)
+	if CloseLevel(code) == logfire.LevelError {
+		msg := reason
+		if msg == "" {
+			msg = fmt.Sprintf("websocket closed with status %d", code)
+		}
+		c.span.SetStatus(codes.Error, msg)
+	}
+}
+
+// Error records an error of the connection other than a close frame, unless it's closed already,
+// in which case the error is the consequence of closing it.
+func (c *Conn) Error(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.ended {
+		return
+	}
+	c.closed = true
+	c.span.RecordError(err)
+	c.span.SetAttributes(logfire.LevelNumKey.Int(int(logfire.LevelError)))
+	c.span.SetStatus(codes.Error, err.Error())
+}
+
+// End ends the span of the connection, once.
+func (c *Conn) End() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ended {
+		return
+	}
+	c.ended = true
+	c.span.SetAttributes(
+		messagesSentKey.Int(c.messagesSent),
+		messagesReceivedKey.Int(c.messagesReceived),
+		bytesSentKey.Int(c.bytesSent),
+		bytesReceivedKey.Int(c.bytesReceived),
+	)
+	c.span.End()
+}
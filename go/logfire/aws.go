@@ -0,0 +1,132 @@
+package logfire
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// awsIMDSURL is the URL of the instance metadata service of EC2.
+const awsIMDSURL = "http://169.254.169.254"
+
+// awsDetector detects Lambda functions and ECS tasks from their environment variables, and EC2 instances,
+// including the nodes of EKS clusters, from their DMI information. Only ECS and EC2 need requests,
+// to their metadata services.
+type awsDetector struct {
+	imdsURL string
+	dmiDir  string
+}
+
+func (d awsDetector) detect(ctx context.Context) []attribute.KeyValue {
+	if name := os.Getenv("AWS_LAMBDA_FUNCTION_NAME"); name != "" {
+		return detectLambda(name)
+	}
+	if uri := os.Getenv("ECS_CONTAINER_METADATA_URI_V4"); uri != "" {
+		return detectECS(ctx, uri)
+	}
+	if readDMI(d.dmiDir, "sys_vendor") == "Amazon EC2" || strings.Contains(readDMI(d.dmiDir, "bios_version"), "amazon") {
+		return d.detectEC2(ctx)
+	}
+	return nil
+}
+
+func detectLambda(name string) []attribute.KeyValue {
+	attrs := appendNonEmpty([]attribute.KeyValue{
+		semconv.CloudProviderAWS,
+		semconv.CloudPlatformAWSLambda,
+		semconv.FaaSName(name),
+	},
+		semconv.CloudRegion(os.Getenv("AWS_REGION")),
+		semconv.FaaSVersion(os.Getenv("AWS_LAMBDA_FUNCTION_VERSION")),
+		semconv.FaaSInstance(os.Getenv("AWS_LAMBDA_LOG_STREAM_NAME")),
+	)
+	if memory, err := strconv.Atoi(os.Getenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE")); err == nil {
+		attrs = append(attrs, semconv.FaaSMaxMemory(memory<<20))
+	}
+	return attrs
+}
+
+// detectECS reads the task metadata endpoint version 4 of the container.
+func detectECS(ctx context.Context, uri string) []attribute.KeyValue {
+	var task struct {
+		Cluster          string
+		TaskARN          string
+		Family           string
+		Revision         string
+		AvailabilityZone string
+		LaunchType       string
+	}
+	var container struct {
+		Name     string
+		DockerID string `json:"DockerId"`
+	}
+	if err := getMetadataJSON(ctx, uri+"/task", nil, &task); err != nil {
+		return nil
+	}
+	// Errors are ignored, the attributes of the task being the most useful.
+	_ = getMetadataJSON(ctx, uri, nil, &container)
+
+	attrs := []attribute.KeyValue{semconv.CloudProviderAWS, semconv.CloudPlatformAWSECS}
+	// The task ARN is arn:aws:ecs:<region>:<account>:task/<cluster>/<id>.
+	arn := strings.Split(task.TaskARN, ":")
+	if len(arn) == 6 {
+		attrs = appendNonEmpty(attrs, semconv.CloudRegion(arn[3]), semconv.CloudAccountID(arn[4]))
+		if !strings.HasPrefix(task.Cluster, "arn:") && task.Cluster != "" {
+			task.Cluster = strings.Join(arn[:5], ":") + ":cluster/" + task.Cluster
+		}
+		attrs = append(attrs, semconv.AWSECSTaskID(arn[5][strings.LastIndex(arn[5], "/")+1:]))
+	}
+	attrs = appendNonEmpty(attrs,
+		semconv.CloudAvailabilityZone(task.AvailabilityZone),
+		semconv.AWSECSClusterARN(task.Cluster),
+		semconv.AWSECSTaskARN(task.TaskARN),
+		semconv.AWSECSTaskFamily(task.Family),
+		semconv.AWSECSTaskRevision(task.Revision),
+		semconv.AWSECSLaunchtypeKey.String(strings.ToLower(task.LaunchType)),
+		semconv.ContainerName(container.Name),
+		semconv.ContainerID(container.DockerID),
+	)
+	return attrs
+}
+
+// detectEC2 reads the identity document of the instance with IMDSv2, which requires a session token.
+func (d awsDetector) detectEC2(ctx context.Context) []attribute.KeyValue {
+	token, err := getMetadata(ctx, http.MethodPut, d.imdsURL+"/latest/api/token",
+		http.Header{"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"60"}})
+	if err != nil {
+		return nil
+	}
+	header := http.Header{"X-Aws-Ec2-Metadata-Token": {string(token)}}
+	var doc struct {
+		AccountID        string `json:"accountId"`
+		AvailabilityZone string `json:"availabilityZone"`
+		Region           string `json:"region"`
+		InstanceID       string `json:"instanceId"`
+		InstanceType     string `json:"instanceType"`
+		ImageID          string `json:"imageId"`
+	}
+	if err := getMetadataJSON(ctx, d.imdsURL+"/latest/dynamic/instance-identity/document", header, &doc); err != nil {
+		return nil
+	}
+	platform := semconv.CloudPlatformAWSEC2
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		platform = semconv.CloudPlatformAWSEKS
+	}
+	attrs := appendNonEmpty([]attribute.KeyValue{semconv.CloudProviderAWS, platform},
+		semconv.CloudRegion(doc.Region),
+		semconv.CloudAvailabilityZone(doc.AvailabilityZone),
+		semconv.CloudAccountID(doc.AccountID),
+		semconv.HostID(doc.InstanceID),
+		semconv.HostType(doc.InstanceType),
+		semconv.HostImageID(doc.ImageID),
+	)
+	if hostname, err := getMetadata(ctx, http.MethodGet, d.imdsURL+"/latest/meta-data/hostname", header); err == nil {
+		attrs = appendNonEmpty(attrs, semconv.HostName(string(hostname)))
+	}
+	return attrs
+}
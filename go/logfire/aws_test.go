@@ -0,0 +1,124 @@
+package logfire
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// checkDetected checks the detected attributes with the values in want, emitted as strings.
+func checkDetected(t *testing.T, attrs []attribute.KeyValue, want map[string]string) {
+	t.Helper()
+	got := map[string]string{}
+	for _, kv := range attrs {
+		got[string(kv.Key)] = kv.Value.Emit()
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func clearAWSEnv(t *testing.T) {
+	for _, name := range []string{"AWS_LAMBDA_FUNCTION_NAME", "ECS_CONTAINER_METADATA_URI_V4", "KUBERNETES_SERVICE_HOST"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestAWSDetectorLambda(t *testing.T) {
+	clearAWSEnv(t)
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "resize")
+	t.Setenv("AWS_LAMBDA_FUNCTION_VERSION", "$LATEST")
+	t.Setenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", "128")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	attrs := awsDetector{dmiDir: t.TempDir()}.detect(context.Background())
+	checkDetected(t, attrs, map[string]string{
+		"cloud.provider":  "aws",
+		"cloud.platform":  "aws_lambda",
+		"cloud.region":    "eu-west-1",
+		"faas.name":       "resize",
+		"faas.version":    "$LATEST",
+		"faas.max_memory": "134217728",
+	})
+}
+
+func TestAWSDetectorECS(t *testing.T) {
+	clearAWSEnv(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/task":
+			w.Write([]byte(`{"Cluster": "default", "TaskARN": "arn:aws:ecs:us-west-2:111122223333:task/default/158d1c8083dd49d6",
+				"Family": "web", "Revision": "26", "AvailabilityZone": "us-west-2d", "LaunchType": "FARGATE"}`))
+		case "/v4":
+			w.Write([]byte(`{"Name": "app", "DockerId": "cd189a933e5849daa93386466019ab50"}`))
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", srv.URL+"/v4")
+	attrs := awsDetector{dmiDir: t.TempDir()}.detect(context.Background())
+	checkDetected(t, attrs, map[string]string{
+		"cloud.platform":          "aws_ecs",
+		"cloud.region":            "us-west-2",
+		"cloud.account.id":        "111122223333",
+		"cloud.availability_zone": "us-west-2d",
+		"aws.ecs.cluster.arn":     "arn:aws:ecs:us-west-2:111122223333:cluster/default",
+		"aws.ecs.task.id":         "158d1c8083dd49d6",
+		"aws.ecs.task.family":     "web",
+		"aws.ecs.task.revision":   "26",
+		"aws.ecs.launchtype":      "fargate",
+		"container.name":          "app",
+		"container.id":            "cd189a933e5849daa93386466019ab50",
+	})
+}
+
+func TestAWSDetectorEC2(t *testing.T) {
+	clearAWSEnv(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != http.MethodPut {
+				t.Errorf("token method = %s", r.Method)
+			}
+			w.Write([]byte("token"))
+			return
+		}
+		if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/dynamic/instance-identity/document":
+			w.Write([]byte(`{"accountId": "111122223333", "availabilityZone": "us-east-1a", "region": "us-east-1",
+				"instanceId": "i-0123456789abcdef0", "instanceType": "t3.micro", "imageId": "ami-0abcdef"}`))
+		case "/latest/meta-data/hostname":
+			w.Write([]byte("ip-10-0-0-1.ec2.internal"))
+		}
+	}))
+	defer srv.Close()
+
+	d := awsDetector{imdsURL: srv.URL, dmiDir: t.TempDir()}
+	if attrs := d.detect(context.Background()); attrs != nil {
+		t.Errorf("attributes of another machine = %v", attrs)
+	}
+	if err := os.WriteFile(filepath.Join(d.dmiDir, "sys_vendor"), []byte("Amazon EC2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	checkDetected(t, d.detect(context.Background()), map[string]string{
+		"cloud.platform":          "aws_ec2",
+		"cloud.region":            "us-east-1",
+		"cloud.availability_zone": "us-east-1a",
+		"cloud.account.id":        "111122223333",
+		"host.id":                 "i-0123456789abcdef0",
+		"host.type":               "t3.micro",
+		"host.image.id":           "ami-0abcdef",
+		"host.name":               "ip-10-0-0-1.ec2.internal",
+	})
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	checkDetected(t, d.detect(context.Background()), map[string]string{"cloud.platform": "aws_eks"})
+}
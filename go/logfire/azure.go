@@ -0,0 +1,86 @@
+package logfire
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// azureIMDSURL is the URL of the instance metadata service of Azure.
+const azureIMDSURL = "http://169.254.169.254"
+
+// azureChassisAssetTag is the DMI chassis asset tag of Azure virtual machines.
+const azureChassisAssetTag = "7783-7084-3265-9085-8269-3286-77"
+
+// azureDetector detects App Service apps and Container Apps from their environment variables,
+// and virtual machines, including the nodes of AKS clusters, from their DMI information.
+// Only virtual machines need a request, to the instance metadata service.
+type azureDetector struct {
+	imdsURL string
+	dmiDir  string
+}
+
+func (d azureDetector) detect(ctx context.Context) []attribute.KeyValue {
+	if site := os.Getenv("WEBSITE_SITE_NAME"); site != "" {
+		return detectAppService(site)
+	}
+	if os.Getenv("CONTAINER_APP_NAME") != "" {
+		return []attribute.KeyValue{semconv.CloudProviderAzure, semconv.CloudPlatformAzureContainerApps}
+	}
+	if readDMI(d.dmiDir, "chassis_asset_tag") == azureChassisAssetTag {
+		return d.detectVM(ctx)
+	}
+	return nil
+}
+
+func detectAppService(site string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.CloudProviderAzure, semconv.CloudPlatformAzureAppService}
+	if os.Getenv("FUNCTIONS_WORKER_RUNTIME") != "" {
+		// Function apps run on App Service too.
+		attrs = []attribute.KeyValue{semconv.CloudProviderAzure, semconv.CloudPlatformAzureFunctions, semconv.FaaSName(site)}
+	}
+	attrs = appendNonEmpty(attrs,
+		semconv.CloudRegion(os.Getenv("REGION_NAME")),
+		semconv.HostID(os.Getenv("WEBSITE_HOSTNAME")),
+	)
+	// WEBSITE_OWNER_NAME is <subscription>+<resource group>-<region>webspace.
+	subscription, _, _ := strings.Cut(os.Getenv("WEBSITE_OWNER_NAME"), "+")
+	if group := os.Getenv("WEBSITE_RESOURCE_GROUP"); subscription != "" && group != "" {
+		attrs = append(attrs, semconv.CloudAccountID(subscription), semconv.CloudResourceID(
+			"/subscriptions/"+subscription+"/resourceGroups/"+group+"/providers/Microsoft.Web/sites/"+site))
+	}
+	return attrs
+}
+
+func (d azureDetector) detectVM(ctx context.Context) []attribute.KeyValue {
+	var compute struct {
+		Location       string `json:"location"`
+		Name           string `json:"name"`
+		ResourceID     string `json:"resourceId"`
+		SubscriptionID string `json:"subscriptionId"`
+		VMID           string `json:"vmId"`
+		VMSize         string `json:"vmSize"`
+		Zone           string `json:"zone"`
+	}
+	if err := getMetadataJSON(ctx, d.imdsURL+"/metadata/instance/compute?api-version=2021-12-13&format=json",
+		http.Header{"Metadata": {"true"}}, &compute); err != nil {
+		return nil
+	}
+	platform := semconv.CloudPlatformAzureVM
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		platform = semconv.CloudPlatformAzureAKS
+	}
+	return appendNonEmpty([]attribute.KeyValue{semconv.CloudProviderAzure, platform},
+		semconv.CloudRegion(compute.Location),
+		semconv.CloudAvailabilityZone(compute.Zone),
+		semconv.CloudAccountID(compute.SubscriptionID),
+		semconv.CloudResourceID(compute.ResourceID),
+		semconv.HostID(compute.VMID),
+		semconv.HostName(compute.Name),
+		semconv.HostType(compute.VMSize),
+	)
+}
@@ -0,0 +1,79 @@
+package logfire
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func clearAzureEnv(t *testing.T) {
+	for _, name := range []string{"WEBSITE_SITE_NAME", "FUNCTIONS_WORKER_RUNTIME", "CONTAINER_APP_NAME", "KUBERNETES_SERVICE_HOST"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestAzureDetectorAppService(t *testing.T) {
+	clearAzureEnv(t)
+	t.Setenv("WEBSITE_SITE_NAME", "shop")
+	t.Setenv("WEBSITE_HOSTNAME", "shop.azurewebsites.net")
+	t.Setenv("WEBSITE_OWNER_NAME", "0000-1111+shop-rg-WestEuropewebspace")
+	t.Setenv("WEBSITE_RESOURCE_GROUP", "shop-rg")
+	t.Setenv("REGION_NAME", "West Europe")
+	checkDetected(t, azureDetector{dmiDir: t.TempDir()}.detect(context.Background()), map[string]string{
+		"cloud.provider":    "azure",
+		"cloud.platform":    "azure.app_service",
+		"cloud.region":      "West Europe",
+		"cloud.account.id":  "0000-1111",
+		"cloud.resource_id": "/subscriptions/0000-1111/resourceGroups/shop-rg/providers/Microsoft.Web/sites/shop",
+		"host.id":           "shop.azurewebsites.net",
+	})
+
+	t.Setenv("FUNCTIONS_WORKER_RUNTIME", "custom")
+	checkDetected(t, azureDetector{dmiDir: t.TempDir()}.detect(context.Background()), map[string]string{
+		"cloud.platform": "azure.functions",
+		"faas.name":      "shop",
+	})
+
+	clearAzureEnv(t)
+	t.Setenv("CONTAINER_APP_NAME", "shop")
+	checkDetected(t, azureDetector{dmiDir: t.TempDir()}.detect(context.Background()), map[string]string{
+		"cloud.platform": "azure.container_apps",
+	})
+}
+
+func TestAzureDetectorVM(t *testing.T) {
+	clearAzureEnv(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metadata/instance/compute" || r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"location": "westeurope", "name": "web-1", "subscriptionId": "0000-1111",
+			"resourceId": "/subscriptions/0000-1111/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/web-1",
+			"vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6", "vmSize": "Standard_D2s_v3", "zone": "1"}`))
+	}))
+	defer srv.Close()
+
+	d := azureDetector{imdsURL: srv.URL, dmiDir: t.TempDir()}
+	if attrs := d.detect(context.Background()); attrs != nil {
+		t.Errorf("attributes of another machine = %v", attrs)
+	}
+	if err := os.WriteFile(filepath.Join(d.dmiDir, "chassis_asset_tag"), []byte(azureChassisAssetTag+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	checkDetected(t, d.detect(context.Background()), map[string]string{
+		"cloud.platform":          "azure.vm",
+		"cloud.region":            "westeurope",
+		"cloud.availability_zone": "1",
+		"cloud.account.id":        "0000-1111",
+		"host.id":                 "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+		"host.name":               "web-1",
+		"host.type":               "Standard_D2s_v3",
+	})
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	checkDetected(t, d.detect(context.Background()), map[string]string{"cloud.platform": "azure.aks"})
+}
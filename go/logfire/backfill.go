@@ -0,0 +1,150 @@
+package logfire
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxFileRecordSize bounds the length prefixes read from files, so that a corrupted one fails cleanly.
+const maxFileRecordSize = 1 << 30
+
+// Backfill uploads the spans of a file written by [WithFileExport] or [NewFileSpanExporter] to Logfire,
+// e.g. once an air-gapped job is back online, in either format. Spans keep their original timestamps
+// and IDs, and the resources of the processes which wrote them.
+//
+// The options are those of [Configure], e.g. [WithToken], [WithBaseURL] and the exporter settings.
+// Failed uploads are retried like exports, see [WithRetry]. It returns the number of spans uploaded,
+// which are those before the error if any, so that a truncated file is uploaded up to its last batch.
+func Backfill(ctx context.Context, path string, opts ...Option) (int, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return 0, err
+	}
+	if !*c.sendToLogfire {
+		return 0, errors.New("logfire: backfill with sending to Logfire disabled")
+	}
+	if err := c.loadToken(); err != nil {
+		return 0, err
+	}
+	if err := c.loadTLSConfig(); err != nil {
+		return 0, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("logfire: opening backfill: %w", err)
+	}
+	defer f.Close()
+
+	sender := newOTLPSender(c)
+	defer sender.close()
+	uploaded := 0
+	err = readFileExport(bufio.NewReader(f), func(req *collectortrace.ExportTraceServiceRequest) error {
+		body, err := proto.Marshal(req)
+		if err != nil {
+			return err
+		}
+		if err := c.sendRetrying(ctx, sender, signalTraces, body); err != nil {
+			return err
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				uploaded += len(ss.Spans)
+			}
+		}
+		return nil
+	})
+	return uploaded, err
+}
+
+// readFileExport calls fn with each batch of a file export, telling the formats apart by their first byte,
+// since JSON lines start with `{` and the length prefixes of protobuf messages with a zero byte.
+func readFileExport(r *bufio.Reader, fn func(*collectortrace.ExportTraceServiceRequest) error) error {
+	first, err := r.Peek(1)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("logfire: reading backfill: %w", err)
+	}
+	for batch := 1; ; batch++ {
+		var req *collectortrace.ExportTraceServiceRequest
+		if first[0] == '{' {
+			line, err := r.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) == 0 {
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("logfire: reading backfill: %w", err)
+				}
+				continue
+			}
+			if req, err = otlpProtobuf(line); err != nil {
+				return fmt.Errorf("logfire: invalid batch %d of backfill: %w", batch, err)
+			}
+		} else {
+			var size [4]byte
+			if _, err := io.ReadFull(r, size[:]); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("logfire: truncated batch %d of backfill: %w", batch, err)
+			}
+			n := binary.BigEndian.Uint32(size[:])
+			if n > maxFileRecordSize {
+				return fmt.Errorf("logfire: invalid batch %d of backfill: size of %d bytes", batch, n)
+			}
+			body := make([]byte, n)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return fmt.Errorf("logfire: truncated batch %d of backfill: %w", batch, err)
+			}
+			req = &collectortrace.ExportTraceServiceRequest{}
+			if err := proto.Unmarshal(body, req); err != nil {
+				return fmt.Errorf("logfire: invalid batch %d of backfill: %w", batch, err)
+			}
+		}
+		if err := fn(req); err != nil {
+			return err
+		}
+	}
+}
+
+// sendRetrying sends an encoded request, retrying it like the exporters do.
+func (c *config) sendRetrying(ctx context.Context, sender *otlpSender, signal string, body []byte) error {
+	deadline := time.Now().Add(c.retryOptions.MaxElapsedTime)
+	interval := c.retryOptions.InitialInterval
+	for {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := sender.send(sendCtx, signal, body)
+		cancel()
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) || c.retryOptions.Disabled || ctx.Err() != nil {
+			return err
+		}
+		wait := jitter(interval)
+		var throttled *throttledError
+		if errors.As(err, &throttled) {
+			wait = throttled.delay
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		interval = min(2*interval, c.retryOptions.MaxInterval)
+	}
+}
@@ -0,0 +1,99 @@
+package logfire
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestBackfill(t *testing.T) {
+	type span struct {
+		name  string
+		start uint64
+	}
+	spans := make(chan span, 4)
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "test-token" {
+			t.Errorf("request to %s with authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		// Throttled uploads are retried.
+		if attempts++; attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		var req collectortrace.ExportTraceServiceRequest
+		if err := proto.Unmarshal(b, &req); err != nil {
+			t.Errorf("unmarshal: %v", err)
+		}
+		for _, s := range req.ResourceSpans[0].ScopeSpans[0].Spans {
+			spans <- span{s.Name, s.StartTimeUnixNano}
+		}
+	}))
+	defer srv.Close()
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, format := range []FileFormat{FileFormatProtobuf, FileFormatJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			attempts = 0
+			path := filepath.Join(t.TempDir(), "spans")
+			exporter, err := NewFileSpanExporter(FileExportOptions{Path: path, Format: format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"first", "second"} {
+				stubs := tracetest.SpanStubs{{Name: name, StartTime: start, EndTime: start.Add(time.Second)}}
+				if err := exporter.ExportSpans(context.Background(), stubs.Snapshots()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			exporter.Shutdown(context.Background())
+
+			n, err := Backfill(context.Background(), path, WithToken("test-token"), WithBaseURL(srv.URL),
+				WithRetry(RetryOptions{InitialInterval: time.Millisecond}), WithCompression(CompressionNone))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 2 {
+				t.Errorf("uploaded %d spans, want 2", n)
+			}
+			for _, want := range []string{"first", "second"} {
+				if got := <-spans; got.name != want || got.start != uint64(start.UnixNano()) {
+					t.Errorf("span = %+v, want %s with its original start time", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBackfillTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "spans.pb")
+	exporter, err := NewFileSpanExporter(FileExportOptions{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter.ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "hello"}}.Snapshots())
+	exporter.Shutdown(context.Background())
+	// A process which crashed while writing a batch leaves it incomplete.
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.Write([]byte{0, 0, 1, 0, 42})
+	f.Close()
+
+	n, err := Backfill(context.Background(), path, WithToken("test-token"), WithBaseURL(srv.URL))
+	if err == nil || n != 1 {
+		t.Errorf("Backfill = %d, %v, want the first batch uploaded and an error", n, err)
+	}
+}
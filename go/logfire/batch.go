@@ -0,0 +1,65 @@
+package logfire
+
+import (
+	"errors"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// BatchOptions configures the batch span processors exporting to Logfire, the additional endpoints
+// and files, see [WithBatchOptions].
+type BatchOptions struct {
+	// MaxQueueSize is the number of spans buffered before new ones are dropped. Defaults to 2048,
+	// or the OTEL_BSP_MAX_QUEUE_SIZE environment variable.
+	MaxQueueSize int
+	// MaxExportBatchSize is the maximum number of spans in an export. Defaults to 512,
+	// or OTEL_BSP_MAX_EXPORT_BATCH_SIZE.
+	MaxExportBatchSize int
+	// ScheduleDelay is the maximum time spans wait in the queue before being exported. Defaults to 500ms
+	// like the Python SDK, so that spans show up in Logfire quickly, or OTEL_BSP_SCHEDULE_DELAY in milliseconds.
+	ScheduleDelay time.Duration
+	// ExportTimeout is the maximum duration of an export, retries included. Defaults to
+	// [RetryOptions.MaxElapsedTime], or OTEL_BSP_EXPORT_TIMEOUT in milliseconds.
+	ExportTimeout time.Duration
+}
+
+// HighThroughputBatchOptions returns the batch options recommended for services producing thousands of spans
+// per second: the larger queue absorbs bursts and the larger exports keep the number of requests down,
+// while staying well below the 5 MB Logfire accepts in a request.
+func HighThroughputBatchOptions() BatchOptions {
+	return BatchOptions{
+		MaxQueueSize:       16384,
+		MaxExportBatchSize: 2048,
+		ScheduleDelay:      time.Second,
+	}
+}
+
+// WithBatchOptions tunes how spans are batched before being exported. Unset fields keep their defaults.
+func WithBatchOptions(opts BatchOptions) Option {
+	return func(c *config) {
+		c.batchOptions = opts
+	}
+}
+
+// loadBatchParams fills in the batch options that weren't set with the OTEL_BSP_* environment variables.
+func (c *config) loadBatchParams(params *paramManager) error {
+	opts := &c.batchOptions
+	var err error
+	if opts.MaxQueueSize, err = params.int("batch_max_queue_size", opts.MaxQueueSize, sdktrace.DefaultMaxQueueSize); err != nil {
+		return err
+	}
+	if opts.MaxExportBatchSize, err = params.int("batch_max_export_batch_size", opts.MaxExportBatchSize, sdktrace.DefaultMaxExportBatchSize); err != nil {
+		return err
+	}
+	if opts.ScheduleDelay, err = params.millis("batch_schedule_delay", opts.ScheduleDelay, defaultScheduleDelayMillis*time.Millisecond); err != nil {
+		return err
+	}
+	if opts.ExportTimeout, err = params.millis("batch_export_timeout", opts.ExportTimeout, c.exportTimeout()); err != nil {
+		return err
+	}
+	if opts.MaxExportBatchSize > opts.MaxQueueSize {
+		return errors.New("logfire: the maximum export batch size is larger than the maximum queue size")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package logfire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatchParams(t *testing.T) {
+	c, err := newConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := BatchOptions{MaxQueueSize: 2048, MaxExportBatchSize: 512, ScheduleDelay: 500 * time.Millisecond, ExportTimeout: time.Minute}
+	if c.batchOptions != want {
+		t.Errorf("default batch options = %+v, want %+v", c.batchOptions, want)
+	}
+
+	t.Setenv("OTEL_BSP_MAX_QUEUE_SIZE", "4096")
+	t.Setenv("OTEL_BSP_SCHEDULE_DELAY", "2000")
+	c, err = newConfig([]Option{WithBatchOptions(BatchOptions{MaxExportBatchSize: 1024})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = BatchOptions{MaxQueueSize: 4096, MaxExportBatchSize: 1024, ScheduleDelay: 2 * time.Second, ExportTimeout: time.Minute}
+	if c.batchOptions != want {
+		t.Errorf("batch options = %+v, want %+v", c.batchOptions, want)
+	}
+	// Options take precedence over the environment.
+	c, err = newConfig([]Option{WithBatchOptions(HighThroughputBatchOptions())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.batchOptions.MaxQueueSize != HighThroughputBatchOptions().MaxQueueSize || c.batchOptions.ScheduleDelay != time.Second {
+		t.Errorf("batch options = %+v, want the high throughput ones", c.batchOptions)
+	}
+}
+
+func TestBatchParamsErrors(t *testing.T) {
+	t.Setenv("OTEL_BSP_MAX_QUEUE_SIZE", "lots")
+	if _, err := newConfig(nil); err == nil {
+		t.Error("no error for an invalid queue size")
+	}
+	t.Setenv("OTEL_BSP_MAX_QUEUE_SIZE", "100")
+	if _, err := newConfig(nil); err == nil {
+		t.Error("no error for a batch size larger than the queue")
+	}
+}
@@ -0,0 +1,97 @@
+package logfire
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// bucketAdvice records the boundaries advised with [metric.WithExplicitBucketBoundaries], e.g. by
+// [MetricOptions.Buckets], on the histograms created with an [adviceMeterProvider]. The SDK only honors them
+// when the reader aggregates histograms with explicit buckets, not with the exponential ones exported to
+// Logfire, so the views returned by views apply them.
+type bucketAdvice struct {
+	boundaries sync.Map // adviceKey -> []float64
+}
+
+type adviceKey struct {
+	scope, name string
+}
+
+func (a *bucketAdvice) record(scope, name string, boundaries []float64) {
+	if len(boundaries) > 0 {
+		a.boundaries.Store(adviceKey{scope: scope, name: name}, boundaries)
+	}
+}
+
+// aggregation returns the explicit bucket aggregation of a histogram with advised boundaries.
+func (a *bucketAdvice) aggregation(inst sdkmetric.Instrument) (sdkmetric.Aggregation, bool) {
+	if inst.Kind != sdkmetric.InstrumentKindHistogram {
+		return nil, false
+	}
+	boundaries, ok := a.boundaries.Load(adviceKey{scope: inst.Scope.Name, name: inst.Name})
+	if !ok {
+		return nil, false
+	}
+	return sdkmetric.AggregationExplicitBucketHistogram{Boundaries: boundaries.([]float64)}, true
+}
+
+// views returns the views of [WithMetricViews], which keep precedence over the advised boundaries when they
+// set the aggregation, followed by a view of the histograms with advised boundaries matched by none of them.
+func (a *bucketAdvice) views(views []sdkmetric.View) []sdkmetric.View {
+	advised := make([]sdkmetric.View, 0, len(views)+1)
+	for _, view := range views {
+		advised = append(advised, func(inst sdkmetric.Instrument) (sdkmetric.Stream, bool) {
+			stream, ok := view(inst)
+			if ok && stream.Aggregation == nil {
+				stream.Aggregation, _ = a.aggregation(inst)
+			}
+			return stream, ok
+		})
+	}
+	return append(advised, func(inst sdkmetric.Instrument) (sdkmetric.Stream, bool) {
+		aggregation, ok := a.aggregation(inst)
+		if !ok {
+			return sdkmetric.Stream{}, false
+		}
+		for _, view := range views {
+			if _, matched := view(inst); matched {
+				return sdkmetric.Stream{}, false
+			}
+		}
+		return sdkmetric.Stream{
+			Name:        inst.Name,
+			Description: inst.Description,
+			Unit:        inst.Unit,
+			Aggregation: aggregation,
+		}, true
+	})
+}
+
+// adviceMeterProvider is the meter provider installed by [Configure], which records the advised boundaries
+// of the histograms before the SDK applies the views to them.
+type adviceMeterProvider struct {
+	*sdkmetric.MeterProvider
+	advice *bucketAdvice
+}
+
+func (p adviceMeterProvider) Meter(name string, opts ...metric.MeterOption) metric.Meter {
+	return adviceMeter{Meter: p.MeterProvider.Meter(name, opts...), scope: name, advice: p.advice}
+}
+
+type adviceMeter struct {
+	metric.Meter
+	scope  string
+	advice *bucketAdvice
+}
+
+func (m adviceMeter) Float64Histogram(name string, opts ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	m.advice.record(m.scope, name, metric.NewFloat64HistogramConfig(opts...).ExplicitBucketBoundaries())
+	return m.Meter.Float64Histogram(name, opts...)
+}
+
+func (m adviceMeter) Int64Histogram(name string, opts ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	m.advice.record(m.scope, name, metric.NewInt64HistogramConfig(opts...).ExplicitBucketBoundaries())
+	return m.Meter.Int64Histogram(name, opts...)
+}
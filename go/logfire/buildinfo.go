@@ -0,0 +1,66 @@
+package logfire
+
+import (
+	"cmp"
+	"path"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// serviceName and serviceVersion are the default name and version of the service, which can be set
+// when building the program:
+//
+//	go build -ldflags "-X github.com/pydantic/logfire/go/logfire.serviceVersion=$(git describe --tags)"
+var serviceName, serviceVersion string
+
+// readBuildInfo is [debug.ReadBuildInfo], replaced in tests.
+var readBuildInfo = debug.ReadBuildInfo
+
+var majorVersionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// buildInfoAttributes returns the service name and version of the program, if the options and
+// the OpenTelemetry environment variables didn't set them in res: the variables set with -ldflags,
+// or else the name of the main package and the version of the main module.
+func (c *config) buildInfoAttributes(res *resource.Resource) []attribute.KeyValue {
+	info, ok := readBuildInfo()
+	if !ok {
+		info = &debug.BuildInfo{}
+	}
+	var attrs []attribute.KeyValue
+	// Without a name, the SDK sets unknown_service:<executable>.
+	if name, _ := res.Set().Value(semconv.ServiceNameKey); c.serviceName == "" && strings.HasPrefix(name.AsString(), "unknown_service:") {
+		if name := cmp.Or(serviceName, mainPackageName(info.Path)); name != "" {
+			attrs = append(attrs, semconv.ServiceName(name))
+		}
+	}
+	if _, ok := res.Set().Value(semconv.ServiceVersionKey); c.serviceVersion == "" && !ok {
+		version := info.Main.Version
+		if version == "(devel)" {
+			// Built from a checkout without VCS information.
+			version = ""
+		}
+		if version := cmp.Or(serviceVersion, version); version != "" {
+			attrs = append(attrs, semconv.ServiceVersion(version))
+		}
+	}
+	return attrs
+}
+
+// mainPackageName returns the last element of the path of the main package, e.g. `checkout` for
+// `github.com/acme/checkout/cmd/checkout` or `github.com/acme/checkout/v2`.
+func mainPackageName(pkg string) string {
+	if pkg == "" || pkg == "command-line-arguments" {
+		// Built with `go run main.go`, which doesn't have a package path.
+		return ""
+	}
+	name := path.Base(pkg)
+	if majorVersionSuffix.MatchString(name) && path.Dir(pkg) != "." {
+		name = path.Base(path.Dir(pkg))
+	}
+	return name
+}
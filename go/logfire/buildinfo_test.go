@@ -0,0 +1,58 @@
+package logfire
+
+import (
+	"context"
+	"runtime/debug"
+	"testing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+func TestServiceFromBuildInfo(t *testing.T) {
+	defer func(f func() (*debug.BuildInfo, bool)) { readBuildInfo = f }(readBuildInfo)
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{Path: "github.com/acme/checkout/cmd/api", Main: debug.Module{Version: "v1.2.3"}}, true
+	}
+	service := func(opts ...Option) (string, string) {
+		t.Helper()
+		c, err := newConfig(append(opts, WithResourceDetection(false)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.resource(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, _ := res.Set().Value(semconv.ServiceNameKey)
+		version, _ := res.Set().Value(semconv.ServiceVersionKey)
+		return name.AsString(), version.AsString()
+	}
+
+	if name, version := service(); name != "api" || version != "v1.2.3" {
+		t.Errorf("service = %s %s, want the build info", name, version)
+	}
+	if name, version := service(WithServiceName("checkout"), WithServiceVersion("1.0.0")); name != "checkout" || version != "1.0.0" {
+		t.Errorf("service = %s %s, want the options", name, version)
+	}
+
+	defer func() { serviceName, serviceVersion = "", "" }()
+	serviceName, serviceVersion = "from-ldflags", "2.0.0"
+	if name, version := service(); name != "from-ldflags" || version != "2.0.0" {
+		t.Errorf("service = %s %s, want the -ldflags variables", name, version)
+	}
+}
+
+func TestMainPackageName(t *testing.T) {
+	for pkg, want := range map[string]string{
+		"github.com/acme/checkout":         "checkout",
+		"github.com/acme/checkout/cmd/api": "api",
+		"github.com/acme/checkout/v2":      "checkout",
+		"command-line-arguments":           "",
+		"":                                 "",
+		"v2":                               "v2",
+	} {
+		if got := mainPackageName(pkg); got != want {
+			t.Errorf("mainPackageName(%q) = %q, want %q", pkg, got, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package logfire
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// codeFunctionKey is the function which created a span, like `code.function` in the Python SDK.
+const codeFunctionKey = attribute.Key("code.function")
+
+// CodeLocationOptions configures the code location attributes of the spans and logs created by [Span],
+// [Log] and the level functions, see [WithCodeLocationOptions].
+type CodeLocationOptions struct {
+	// Disabled disables the code location attributes, e.g. for programs creating many spans in hot paths,
+	// since getting the caller takes about a microsecond.
+	Disabled bool
+	// Skip is the number of additional stack frames to skip, e.g. 1 for programs which create all
+	// their spans with a helper function, so that the location is that of the caller of the helper.
+	Skip int
+}
+
+// WithCodeLocationOptions configures the `code.filepath`, `code.lineno` and `code.function` attributes,
+// which by default are set to the location of the caller of [Span], [Log] and the level functions,
+// so that the Logfire UI shows where each span was created, like in the Python SDK.
+//
+// The file path is relative to the root of the main module when the file is in it, so that it matches
+// the files of the repository of [WithCodeSource].
+func WithCodeLocationOptions(opts CodeLocationOptions) Option {
+	return func(c *config) {
+		c.codeLocationOptions = opts
+	}
+}
+
+// codeLocations caches the attributes of the program counters of callers, which are the same at each call.
+var codeLocations sync.Map
+
+// codeLocationAttributes appends the code location attributes of the caller, skip frames above the caller
+// of codeLocationAttributes, to attrs.
+func codeLocationAttributes(attrs []attribute.KeyValue, skip int) []attribute.KeyValue {
+	opts := global.codeLocation.Load()
+	if opts == nil {
+		opts = &CodeLocationOptions{}
+	}
+	if opts.Disabled {
+		return attrs
+	}
+	var pcs [1]uintptr
+	// Skip runtime.Callers, this function and its caller.
+	if runtime.Callers(skip+opts.Skip+3, pcs[:]) == 0 {
+		return attrs
+	}
+	if location, ok := codeLocations.Load(pcs[0]); ok {
+		return append(attrs, location.([]attribute.KeyValue)...)
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	location := []attribute.KeyValue{
+		codeFilepathKey.String(relativeFilepath(frame.File, frame.Function)),
+		codeLinenoKey.Int(frame.Line),
+	}
+	if function := functionName(frame.Function); function != "" {
+		location = append(location, codeFunctionKey.String(function))
+	}
+	codeLocations.Store(pcs[0], location)
+	return append(attrs, location...)
+}
+
+// mainModulePath returns the path of the main module, or "" if the build info isn't available.
+var mainModulePath = sync.OnceValue(func() string {
+	if info, ok := readBuildInfo(); ok {
+		return info.Main.Path
+	}
+	return ""
+})
+
+// relativeFilepath returns the path of file relative to the root of the main module, if it's in it:
+// files of builds with -trimpath start with the path of the module, and otherwise the directory
+// of the package of function ends with the path of the package in the module. The files of the main
+// package are relative to the working directory, and the others are kept absolute.
+func relativeFilepath(file, function string) string {
+	module := mainModulePath()
+	if module != "" && strings.HasPrefix(file, module+"/") {
+		return file[len(module)+1:]
+	}
+	pkg := packagePath(function)
+	if module != "" && (pkg == module || strings.HasPrefix(pkg, module+"/")) {
+		dir, rel := path.Dir(file), pkg[len(module):]
+		if strings.HasSuffix(dir, rel) {
+			return file[len(dir)-len(rel)+1:]
+		}
+	}
+	if pkg == "main" {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, filepath.FromSlash(file)); err == nil && !strings.HasPrefix(rel, "..") {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return file
+}
+
+// packagePath returns the package of a function name like `github.com/acme/checkout/api.(*Server).handle`.
+func packagePath(function string) string {
+	slash := strings.LastIndex(function, "/") + 1
+	if dot := strings.Index(function[slash:], "."); dot >= 0 {
+		return function[:slash+dot]
+	}
+	return function
+}
+
+// functionName returns a function name without its package, e.g. `(*Server).handle`.
+func functionName(function string) string {
+	pkg := packagePath(function)
+	return strings.TrimPrefix(function[len(pkg):], ".")
+}
@@ -0,0 +1,65 @@
+package logfire
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+// logHelper is a helper creating logs, as with [CodeLocationOptions.Skip].
+func logHelper(ctx context.Context) {
+	Info(ctx, "from helper")
+}
+
+func TestCodeLocation(t *testing.T) {
+	recorder := configureForTest(t)
+	ctx := context.Background()
+	_, _, line, _ := runtime.Caller(0)
+	_, span := Span(ctx, "span")
+	span.End()
+	Warn(ctx, "log")
+
+	spans := recorder.Ended()
+	for i, want := range []int64{int64(line) + 1, int64(line) + 3} {
+		attrs := oteltest.Attributes(spans[i].Attributes())
+		// The main module of the test binary is github.com/pydantic/logfire/go.
+		if attrs[codeFilepathKey] != "logfire/codelocation_test.go" || attrs[codeLinenoKey] != want ||
+			attrs[codeFunctionKey] != "TestCodeLocation" {
+			t.Errorf("%s location = %v:%v %v, want line %d", spans[i].Name(),
+				attrs[codeFilepathKey], attrs[codeLinenoKey], attrs[codeFunctionKey], want)
+		}
+	}
+
+	recorder = configureForTest(t, WithCodeLocationOptions(CodeLocationOptions{Skip: 1}))
+	logHelper(ctx)
+	if attrs := oteltest.Attributes(recorder.Ended()[0].Attributes()); attrs[codeFunctionKey] != "TestCodeLocation" {
+		t.Errorf("location with skip = %v", attrs[codeFunctionKey])
+	}
+
+	recorder = configureForTest(t, WithCodeLocationOptions(CodeLocationOptions{Disabled: true}))
+	Info(ctx, "hot path")
+	if _, ok := oteltest.Attributes(recorder.Ended()[0].Attributes())[codeFilepathKey]; ok {
+		t.Error("code location set when disabled")
+	}
+}
+
+func TestRelativeFilepath(t *testing.T) {
+	module := mainModulePath()
+	for _, tt := range []struct {
+		file, function, want string
+	}{
+		{module + "/api/server.go", module + "/api.(*Server).handle", "api/server.go"},
+		{"/app/api/server.go", module + "/api.(*Server).handle", "api/server.go"},
+		{"/app/server.go", module + ".Serve", "server.go"},
+		{"/go/pkg/mod/example.com/lib@v1.0.0/lib.go", "example.com/lib.Do", "/go/pkg/mod/example.com/lib@v1.0.0/lib.go"},
+	} {
+		if got := relativeFilepath(tt.file, tt.function); got != tt.want {
+			t.Errorf("relativeFilepath(%q, %q) = %q, want %q", tt.file, tt.function, got, tt.want)
+		}
+	}
+	if got := functionName(module + "/api.(*Server).handle.func1"); got != "(*Server).handle.func1" {
+		t.Errorf("functionName = %q", got)
+	}
+}
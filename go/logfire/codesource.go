@@ -0,0 +1,87 @@
+package logfire
+
+import (
+	"cmp"
+	"runtime/debug"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CodeSource is the repository of the source of the program, which Logfire uses to link spans to the code
+// that created them, like `CodeSource` in the Python SDK, see [WithCodeSource].
+type CodeSource struct {
+	// Repository is the URL of the repository, e.g. `https://github.com/acme/checkout`. Defaults to the
+	// repository of the main module if it's on GitHub, e.g. for `github.com/acme/checkout/api`.
+	Repository string
+	// Revision is the commit, branch or tag of the source. Defaults to the `vcs.revision` that `go build`
+	// records when building in a repository, unless the build had uncommitted changes (`vcs.modified`),
+	// since the lines of the spans might then not match the revision.
+	Revision string
+	// RootPath is the path of the main module in the repository, if it isn't at its root, e.g. `api`.
+	// Defaults to the rest of the path of a module on GitHub.
+	RootPath string
+}
+
+// WithCodeSource sets the repository of the source of the program. Unset fields are taken from the build info,
+// so that the fields only need to be set for modules which aren't on GitHub or built without VCS information.
+func WithCodeSource(source CodeSource) Option {
+	return func(c *config) {
+		c.codeSource = source
+	}
+}
+
+// codeSourceAttributes returns the resource attributes of the code source, or none if the repository
+// or the revision are unknown.
+func (c *config) codeSourceAttributes() []attribute.KeyValue {
+	var built CodeSource
+	if info, ok := readBuildInfo(); ok {
+		built = buildCodeSource(info)
+	}
+	source := c.codeSource
+	// The root path of the main module only applies to its repository.
+	if source.Repository == "" || source.Repository == built.Repository {
+		source.Repository = built.Repository
+		source.RootPath = cmp.Or(source.RootPath, built.RootPath)
+	}
+	source.Revision = cmp.Or(source.Revision, built.Revision)
+	if source.Repository == "" || source.Revision == "" {
+		return nil
+	}
+	attrs := []attribute.KeyValue{
+		vcsRepositoryURLKey.String(strings.TrimSuffix(source.Repository, "/")),
+		vcsRevisionKey.String(source.Revision),
+	}
+	if source.RootPath != "" {
+		attrs = append(attrs, codeRootPathKey.String(strings.Trim(source.RootPath, "/")))
+	}
+	return attrs
+}
+
+// buildCodeSource returns the code source recorded in the build info.
+func buildCodeSource(info *debug.BuildInfo) CodeSource {
+	var source CodeSource
+	modified := false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			source.Revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if modified {
+		source.Revision = ""
+	}
+	// github.com/<owner>/<repository>/<root path>, the major version suffix not being a directory.
+	parts := strings.Split(info.Main.Path, "/")
+	if len(parts) >= 3 && parts[0] == "github.com" {
+		source.Repository = "https://" + strings.Join(parts[:3], "/")
+		rest := parts[3:]
+		if len(rest) > 0 && majorVersionSuffix.MatchString(rest[len(rest)-1]) {
+			rest = rest[:len(rest)-1]
+		}
+		source.RootPath = strings.Join(rest, "/")
+	}
+	return source
+}
@@ -0,0 +1,53 @@
+package logfire
+
+import (
+	"context"
+	"runtime/debug"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestCodeSource(t *testing.T) {
+	defer func(f func() (*debug.BuildInfo, bool)) { readBuildInfo = f }(readBuildInfo)
+	info := &debug.BuildInfo{
+		Main:     debug.Module{Path: "github.com/acme/shop/checkout/v2"},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "3f2a1b"}, {Key: "vcs.modified", Value: "false"}},
+	}
+	readBuildInfo = func() (*debug.BuildInfo, bool) { return info, true }
+	codeSource := func(source CodeSource) map[string]string {
+		t.Helper()
+		c, err := newConfig([]Option{WithCodeSource(source), WithResourceDetection(false)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.resource(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := map[string]string{}
+		for _, key := range []string{"vcs.repository.url.full", "vcs.repository.ref.revision", "logfire.code.root_path"} {
+			value, _ := res.Set().Value(attribute.Key(key))
+			m[key] = value.AsString()
+		}
+		return m
+	}
+	check := func(got map[string]string, repository, revision, rootPath string) {
+		t.Helper()
+		if got["vcs.repository.url.full"] != repository || got["vcs.repository.ref.revision"] != revision ||
+			got["logfire.code.root_path"] != rootPath {
+			t.Errorf("code source = %v, want %s %s %s", got, repository, revision, rootPath)
+		}
+	}
+
+	check(codeSource(CodeSource{}), "https://github.com/acme/shop", "3f2a1b", "checkout")
+	check(codeSource(CodeSource{Revision: "main"}), "https://github.com/acme/shop", "main", "checkout")
+	check(codeSource(CodeSource{Repository: "https://gitlab.com/acme/shop/", RootPath: "/src/"}),
+		"https://gitlab.com/acme/shop", "3f2a1b", "src")
+
+	// The revision of a build with uncommitted changes isn't used.
+	info.Settings[1].Value = "true"
+	check(codeSource(CodeSource{}), "", "", "")
+	info.Main.Path = "example.com/shop"
+	check(codeSource(CodeSource{Revision: "main"}), "", "", "")
+}
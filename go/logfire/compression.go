@@ -0,0 +1,86 @@
+package logfire
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+)
+
+// Compression is the compression of exported data, see [WithCompression].
+type Compression string
+
+// Compressions of exported data.
+const (
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses better than gzip for a similar CPU cost, but must be supported
+	// by the receiving endpoint, e.g. an OpenTelemetry Collector.
+	CompressionZstd Compression = "zstd"
+	CompressionNone Compression = "none"
+)
+
+func (c Compression) valid() bool {
+	switch c {
+	case CompressionGzip, CompressionZstd, CompressionNone:
+		return true
+	}
+	return false
+}
+
+// zstdEncoder is shared by all exporters, since EncodeAll is safe for concurrent use.
+var zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+	enc, _ := zstd.NewWriter(nil)
+	return enc
+})
+
+// zstdTransport compresses the bodies of requests with zstd, since the OTLP HTTP exporters only support gzip.
+type zstdTransport struct {
+	base http.RoundTripper
+}
+
+func (t zstdTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	compressed := zstdEncoder().EncodeAll(body, nil)
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Content-Encoding", string(CompressionZstd))
+	req.ContentLength = int64(len(compressed))
+	req.Body = io.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	return t.base.RoundTrip(req)
+}
+
+func init() {
+	// Compressors must be registered at initialization, unless another package registered one already.
+	if encoding.GetCompressor(string(CompressionZstd)) == nil {
+		encoding.RegisterCompressor(zstdCompressor{})
+	}
+}
+
+// zstdCompressor is a gRPC compressor using zstd.
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string {
+	return string(CompressionZstd)
+}
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	// Decoding synchronously doesn't start goroutines, which would leak since gRPC doesn't close readers.
+	return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+}
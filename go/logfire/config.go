@@ -0,0 +1,673 @@
+package logfire
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+)
+
+// ErrNoToken is returned by [Configure] when sending to Logfire is enabled but no token was provided.
+var ErrNoToken = errors.New("logfire: no token provided, use WithToken, " +
+	"run `logfire auth` and `logfire projects use` to create a credentials file, " +
+	"or disable sending with WithSendToLogfire(false)")
+
+// Option configures the Logfire SDK, see [Configure].
+type Option func(*config)
+
+// WithToken sets the project write token used to authenticate with the Logfire API.
+func WithToken(token string) Option {
+	return func(c *config) {
+		c.token = token
+	}
+}
+
+// WithSendToLogfire sets whether spans and metrics are exported to Logfire. Defaults to true.
+//
+// When false, no Logfire exporter is created, so spans are only seen by additional span processors,
+// additional endpoints and the console.
+func WithSendToLogfire(send bool) Option {
+	return func(c *config) {
+		c.sendToLogfire = &send
+	}
+}
+
+// WithDataDir sets the directory containing the project credentials file. Defaults to `.logfire`.
+func WithDataDir(dir string) Option {
+	return func(c *config) {
+		c.dataDir = dir
+	}
+}
+
+// WithConfigDir sets the directory containing the `pyproject.toml` file whose `[tool.logfire]` table
+// is used for configuration not set with options. Defaults to the working directory.
+func WithConfigDir(dir string) Option {
+	return func(c *config) {
+		c.configDir = dir
+	}
+}
+
+// WithConsole sets whether spans and logs are printed to the console. Defaults to true.
+func WithConsole(enabled bool) Option {
+	return func(c *config) {
+		c.console = &enabled
+	}
+}
+
+// WithConsoleColors sets whether the console output is colored. Defaults to [ConsoleColorsAuto].
+func WithConsoleColors(colors ConsoleColors) Option {
+	return func(c *config) {
+		c.consoleOptions.Colors = colors
+	}
+}
+
+// WithConsoleSpanStyle sets how spans are laid out in the console. Defaults to [ConsoleSpanStyleShowParents].
+func WithConsoleSpanStyle(style ConsoleSpanStyle) Option {
+	return func(c *config) {
+		c.consoleOptions.SpanStyle = style
+	}
+}
+
+// WithConsoleIncludeTimestamps sets whether console lines start with a timestamp. Defaults to true.
+func WithConsoleIncludeTimestamps(include bool) Option {
+	return func(c *config) {
+		c.consoleIncludeTimestamps = &include
+	}
+}
+
+// WithConsoleVerbose sets whether the console shows the code location, level and attributes of spans.
+// Defaults to false.
+func WithConsoleVerbose(verbose bool) Option {
+	return func(c *config) {
+		c.consoleVerbose = &verbose
+	}
+}
+
+// WithConsoleMinLogLevel sets the minimum level of spans and logs printed to the console. Defaults to [LevelInfo].
+func WithConsoleMinLogLevel(level Level) Option {
+	return func(c *config) {
+		c.consoleOptions.MinLogLevel = level
+	}
+}
+
+// WithConsoleOutput sets where the console output is written. Defaults to os.Stdout.
+func WithConsoleOutput(w io.Writer) Option {
+	return func(c *config) {
+		c.consoleOptions.Output = w
+	}
+}
+
+// WithServiceName sets the `service.name` resource attribute. Defaults to the LOGFIRE_SERVICE_NAME
+// or OTEL_SERVICE_NAME environment variables, or else the name of the main package of the program,
+// e.g. `api` for `github.com/acme/checkout/cmd/api`.
+func WithServiceName(name string) Option {
+	return func(c *config) {
+		c.serviceName = name
+	}
+}
+
+// WithServiceVersion sets the `service.version` resource attribute. Defaults to the LOGFIRE_SERVICE_VERSION
+// or OTEL_SERVICE_VERSION environment variables, or else the version of the main module, which `go install`
+// records, and `go build` derives from the tags of the repository.
+func WithServiceVersion(version string) Option {
+	return func(c *config) {
+		c.serviceVersion = version
+	}
+}
+
+// WithEnvironment sets the `deployment.environment.name` resource attribute, e.g. `staging`, which the
+// environment selector of the Logfire UI filters on. Defaults to the LOGFIRE_ENVIRONMENT environment variable.
+func WithEnvironment(environment string) Option {
+	return func(c *config) {
+		c.environment = environment
+	}
+}
+
+// WithResourceAttributes adds extra attributes to the resource describing this process.
+func WithResourceAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.resourceAttributes = append(c.resourceAttributes, attrs...)
+	}
+}
+
+// WithAdditionalSpanProcessors registers span processors in addition to the ones created by Logfire,
+// e.g. to export spans to a second backend.
+func WithAdditionalSpanProcessors(processors ...sdktrace.SpanProcessor) Option {
+	return func(c *config) {
+		c.additionalSpanProcessors = append(c.additionalSpanProcessors, processors...)
+	}
+}
+
+// WithAdditionalMetricReaders registers metric readers in addition to the one created by Logfire.
+func WithAdditionalMetricReaders(readers ...sdkmetric.Reader) Option {
+	return func(c *config) {
+		c.additionalMetricReaders = append(c.additionalMetricReaders, readers...)
+	}
+}
+
+// WithMetricViews registers views of the metrics, e.g. to rename them, drop attributes, or record a histogram
+// with explicit buckets instead of the exponential ones exported to Logfire by default:
+//
+//	logfire.WithMetricViews(sdkmetric.NewView(
+//		sdkmetric.Instrument{Name: "queue.latency"},
+//		sdkmetric.Stream{Aggregation: sdkmetric.AggregationExplicitBucketHistogram{
+//			Boundaries: []float64{0.1, 1, 10},
+//		}},
+//	))
+func WithMetricViews(views ...sdkmetric.View) Option {
+	return func(c *config) {
+		c.metricViews = append(c.metricViews, views...)
+	}
+}
+
+// WithProtocol sets the protocol of the OTLP exporters, [ProtocolHTTPProtobuf] or [ProtocolGRPC].
+// Defaults to the OTEL_EXPORTER_OTLP_PROTOCOL environment variable, or else [ProtocolHTTPProtobuf].
+//
+// With gRPC, the connection uses TLS unless the base URL is `http://`.
+func WithProtocol(protocol string) Option {
+	return func(c *config) {
+		c.protocol = protocol
+	}
+}
+
+// WithGRPCDialOptions adds options to the gRPC connections of the exporters, e.g. to tune keepalives
+// with [google.golang.org/grpc.WithKeepaliveParams]. Only used with [ProtocolGRPC].
+func WithGRPCDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *config) {
+		c.grpcDialOptions = append(c.grpcDialOptions, opts...)
+	}
+}
+
+// WithCompression sets the compression of exported data. Defaults to the OTEL_EXPORTER_OTLP_COMPRESSION
+// environment variable, or else [CompressionGzip].
+func WithCompression(compression Compression) Option {
+	return func(c *config) {
+		c.compression = compression
+	}
+}
+
+// WithHTTPClient sets the client of the HTTP exporters, e.g. with a transport sending through a proxy.
+// By default, the exporters honor the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithDialer sets the function opening the network connections of the exporters, for both protocols.
+// With [WithHTTPClient], it's only used if the transport of the client is an [http.Transport].
+func WithDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(c *config) {
+		c.dialer = dial
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the connections of the exporters, for both protocols.
+// [WithCACertificate] and [WithClientCertificate] are set on a copy of it.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *config) {
+		c.tlsConfig = cfg
+	}
+}
+
+// WithCACertificate sets the PEM file of the certificate authorities trusted to verify the certificate of
+// the endpoint, instead of the system ones, e.g. for a private PKI or TLS interception.
+// Defaults to the OTEL_EXPORTER_OTLP_CERTIFICATE environment variable.
+func WithCACertificate(path string) Option {
+	return func(c *config) {
+		c.caCertificate = path
+	}
+}
+
+// WithClientCertificate sets the PEM files of the certificate and key of the exporters, for mutual TLS.
+// Defaults to the OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE and OTEL_EXPORTER_OTLP_CLIENT_KEY environment variables.
+func WithClientCertificate(certPath, keyPath string) Option {
+	return func(c *config) {
+		c.clientCertificate = certPath
+		c.clientKey = keyPath
+	}
+}
+
+type config struct {
+	token                    string
+	sendToLogfire            *bool
+	baseURL                  string
+	region                   string
+	dataDir                  string
+	configDir                string
+	serviceName              string
+	serviceVersion           string
+	environment              string
+	resourceAttributes       []attribute.KeyValue
+	detectionDisabled        bool
+	codeSource               CodeSource
+	codeLocationOptions      CodeLocationOptions
+	textMapPropagator        propagation.TextMapPropagator
+	propagators              []string
+	noPropagators            bool
+	console                  *bool
+	consoleOptions           ConsoleOptions
+	consoleIncludeTimestamps *bool
+	consoleVerbose           *bool
+	additionalSpanProcessors []sdktrace.SpanProcessor
+	additionalMetricReaders  []sdkmetric.Reader
+	metricViews              []sdkmetric.View
+	scrubbingOptions         ScrubbingOptions
+	scrubbingDisabled        bool
+	protocol                 string
+	grpcDialOptions          []grpc.DialOption
+	compression              Compression
+	client                   *http.Client
+	dialer                   func(ctx context.Context, network, addr string) (net.Conn, error)
+	tlsConfig                *tls.Config
+	caCertificate            string
+	clientCertificate        string
+	clientKey                string
+	diskQueueOptions         *DiskQueueOptions
+	retryOptions             RetryOptions
+	additionalEndpoints      []OTLPEndpoint
+	fileExports              []FileExportOptions
+	diagnosticsOptions       DiagnosticsOptions
+	diagnostics              *diagnostics
+	tokenCheck               TokenCheck
+	shutdownTimeout          time.Duration
+	batchOptions             BatchOptions
+	metricReaderOptions      MetricReaderOptions
+	traceSampleRate          *float64
+	tailSampling             *TailSamplingOptions
+	samplingRules            []SamplingRule
+	compiledSamplingRules    []samplingRule
+	rootSpanRateLimit        float64
+	baggageForceSampling     bool
+	remoteSamplingOptions    *RemoteSamplingOptions
+	// remoteSampler is nil unless enabled with WithRemoteSampling.
+	remoteSampler *remoteSampler
+	// endpointHeaders are the headers of an additional endpoint, see endpointConfig.
+	endpointHeaders map[string]string
+	// diskQueue is the transport of the exporters when the disk queue is enabled.
+	diskQueue *diskQueue
+}
+
+func newConfig(opts []Option) (*config, error) {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if err := c.loadParams(); err != nil {
+		return nil, err
+	}
+	c.diagnostics = newDiagnostics(c.diagnosticsOptions)
+	return c, nil
+}
+
+// loadParams fills in the configuration that wasn't set with an option.
+func (c *config) loadParams() error {
+	params, err := newParamManager(c.configDir)
+	if err != nil {
+		return err
+	}
+	send, err := params.bool("send_to_logfire", c.sendToLogfire, true)
+	if err != nil {
+		return err
+	}
+	c.sendToLogfire = &send
+	// The base URL may also come from the credentials file, so its default is applied later.
+	c.baseURL = params.string("base_url", c.baseURL, "")
+	c.dataDir = params.string("data_dir", c.dataDir, ".logfire")
+	c.serviceName = params.string("service_name", c.serviceName, "")
+	c.serviceVersion = params.string("service_version", c.serviceVersion, "")
+	c.environment = params.string("environment", c.environment, "")
+	c.token = params.string("token", c.token, "")
+	c.protocol = params.string("protocol", c.protocol, ProtocolHTTPProtobuf)
+	if c.protocol != ProtocolHTTPProtobuf && c.protocol != ProtocolGRPC {
+		return fmt.Errorf("logfire: invalid protocol %q, expected %q or %q", c.protocol, ProtocolHTTPProtobuf, ProtocolGRPC)
+	}
+	for _, e := range c.additionalEndpoints {
+		if e.URL == "" {
+			return errors.New("logfire: additional endpoint without URL")
+		}
+		if e.Protocol != "" && e.Protocol != ProtocolHTTPProtobuf && e.Protocol != ProtocolGRPC {
+			return fmt.Errorf("logfire: invalid protocol %q of endpoint %s, expected %q or %q", e.Protocol, e.URL, ProtocolHTTPProtobuf, ProtocolGRPC)
+		}
+	}
+	c.caCertificate = params.string("certificate", c.caCertificate, "")
+	c.clientCertificate = params.string("client_certificate", c.clientCertificate, "")
+	c.clientKey = params.string("client_key", c.clientKey, "")
+	c.compression = Compression(params.string("compression", string(c.compression), string(CompressionGzip)))
+	if !c.compression.valid() {
+		return fmt.Errorf("logfire: invalid compression %q, expected %q, %q or %q", c.compression, CompressionGzip, CompressionZstd, CompressionNone)
+	}
+	c.retryOptions = c.retryOptions.withDefaults()
+	if c.shutdownTimeout <= 0 {
+		c.shutdownTimeout = DefaultShutdownTimeout
+	}
+	if c.tokenCheck == "" {
+		c.tokenCheck = TokenCheckOff
+	}
+	if !c.tokenCheck.valid() {
+		return fmt.Errorf("logfire: invalid token check %q, expected %q, %q or %q", c.tokenCheck, TokenCheckOff, TokenCheckWarn, TokenCheckFail)
+	}
+	if err := c.loadBatchParams(params); err != nil {
+		return err
+	}
+	if err := c.loadMetricReaderParams(params); err != nil {
+		return err
+	}
+	if err := c.loadPropagatorsParams(params); err != nil {
+		return err
+	}
+	if err := c.loadSamplingParams(params); err != nil {
+		return err
+	}
+	return c.loadConsoleParams(params)
+}
+
+func (c *config) loadConsoleParams(params *paramManager) error {
+	console, err := params.bool("console", c.console, true)
+	if err != nil {
+		return err
+	}
+	c.console = &console
+
+	opts := &c.consoleOptions
+	opts.Colors = ConsoleColors(params.string("console_colors", string(opts.Colors), string(ConsoleColorsAuto)))
+	if !opts.Colors.valid() {
+		return invalidConsoleParam("console_colors", string(opts.Colors))
+	}
+	opts.SpanStyle = ConsoleSpanStyle(params.string("console_span_style", string(opts.SpanStyle), string(ConsoleSpanStyleShowParents)))
+	if !opts.SpanStyle.valid() {
+		return invalidConsoleParam("console_span_style", string(opts.SpanStyle))
+	}
+	if opts.IncludeTimestamps, err = params.bool("console_include_timestamp", c.consoleIncludeTimestamps, true); err != nil {
+		return err
+	}
+	if opts.Verbose, err = params.bool("console_verbose", c.consoleVerbose, false); err != nil {
+		return err
+	}
+	if opts.MinLogLevel, err = params.level("console_min_log_level", opts.MinLogLevel, LevelInfo); err != nil {
+		return err
+	}
+	return nil
+}
+
+// loadToken makes sure a token is available when sending to Logfire,
+// falling back to the credentials file written by the Logfire CLI.
+func (c *config) loadToken() error {
+	if c.token == "" && *c.sendToLogfire {
+		creds, err := LoadCredentials(c.dataDir)
+		if err != nil {
+			return err
+		}
+		if creds == nil {
+			if isLoggedIn(c.baseURLOrDefault()) {
+				return fmt.Errorf("logfire: no project credentials found in %s, "+
+					"run `logfire projects use <project>` or `logfire projects new` to create them: %w", c.dataDir, ErrNoToken)
+			}
+			return ErrNoToken
+		}
+		c.token = creds.Token
+		if c.baseURL == "" && c.region == "" {
+			c.baseURL = creds.LogfireAPIURL
+		}
+	}
+	if !*c.sendToLogfire {
+		c.baseURL = c.baseURLOrDefault()
+		return nil
+	}
+	return c.resolveBaseURL()
+}
+
+func (c *config) baseURLOrDefault() string {
+	if c.baseURL != "" {
+		return c.baseURL
+	}
+	return DefaultBaseURL
+}
+
+// providers holds everything created by a single call to [Configure].
+type providers struct {
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  adviceMeterProvider
+	// scrubber is nil when scrubbing is disabled.
+	scrubber *scrubber
+	// diskQueue is nil unless enabled with WithDiskQueue.
+	diskQueue *diskQueue
+	// remoteSampler is nil unless enabled with WithRemoteSampling.
+	remoteSampler   *remoteSampler
+	diagnostics     *diagnostics
+	shutdownTimeout time.Duration
+}
+
+// forceFlush exports the pending spans and metrics, of which logs are a part.
+func (p *providers) forceFlush(ctx context.Context) error {
+	err := errors.Join(
+		p.tracerProvider.ForceFlush(ctx),
+		p.meterProvider.ForceFlush(ctx),
+	)
+	if p.diskQueue != nil && err == nil {
+		// The exports are only queued so far. Those which can't be sent now stay in the queue.
+		if queueErr := p.diskQueue.sendAll(ctx); queueErr != nil {
+			err = fmt.Errorf("logfire: sending disk queue: %w", queueErr)
+		}
+	}
+	return err
+}
+
+func (p *providers) shutdown(ctx context.Context) error {
+	if p.remoteSampler != nil {
+		p.remoteSampler.shutdown()
+	}
+	err := errors.Join(
+		p.tracerProvider.Shutdown(ctx),
+		p.meterProvider.Shutdown(ctx),
+	)
+	if p.diskQueue != nil {
+		// After the providers, which flush their last exports to the queue.
+		err = errors.Join(err, p.diskQueue.shutdown(ctx))
+	}
+	return err
+}
+
+var global struct {
+	mu        sync.Mutex
+	providers *providers
+	// scrubber is read when formatting every message, so it doesn't need the lock.
+	scrubber atomic.Pointer[scrubber]
+	// codeLocation is read when creating every span and log, and nil before Configure.
+	codeLocation atomic.Pointer[CodeLocationOptions]
+}
+
+func globalProviders() *providers {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	return global.providers
+}
+
+// Configure sets up the OpenTelemetry SDK to export traces and metrics to Logfire
+// and installs the resulting providers as the OpenTelemetry globals, along with the W3C trace context
+// and baggage propagators, see [WithTextMapPropagator].
+//
+// The returned function flushes any pending telemetry and shuts the providers down;
+// it should be called before the program exits.
+// Calling Configure again shuts down the providers created by the previous call.
+func Configure(ctx context.Context, opts ...Option) (shutdown func(context.Context) error, err error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	p, err := c.initialize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	global.mu.Lock()
+	previous := global.providers
+	global.providers = p
+	global.scrubber.Store(p.scrubber)
+	global.codeLocation.Store(&c.codeLocationOptions)
+	global.mu.Unlock()
+	if previous != nil {
+		// Avoid leaking the exporters of the previous configuration.
+		_ = previous.shutdown(ctx)
+	}
+
+	otel.SetTracerProvider(p.tracerProvider)
+	otel.SetMeterProvider(p.meterProvider)
+	c.setTextMapPropagator()
+	return p.shutdown, nil
+}
+
+// ForceFlush exports the spans, logs and metrics recorded so far with the providers of the last [Configure],
+// e.g. before an AWS Lambda function is frozen, a cron job exits or at the end of a test.
+//
+// It returns when they are exported or ctx is done. It does nothing if Configure wasn't called.
+func ForceFlush(ctx context.Context) error {
+	p := globalProviders()
+	if p == nil {
+		return nil
+	}
+	return p.forceFlush(ctx)
+}
+
+func (c *config) initialize(ctx context.Context) (*providers, error) {
+	if err := c.loadToken(); err != nil {
+		return nil, err
+	}
+
+	res, err := c.resource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var scrubber *scrubber
+	if !c.scrubbingDisabled {
+		if scrubber, err = newScrubber(c.scrubbingOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	processors := append([]sdktrace.SpanProcessor{}, c.additionalSpanProcessors...)
+	if *c.console {
+		processors = append(processors, NewConsoleSpanProcessor(c.consoleOptions))
+	}
+	advice := &bucketAdvice{}
+	meterOpts := []sdkmetric.Option{sdkmetric.WithResource(res), sdkmetric.WithView(advice.views(c.metricViews)...)}
+	for _, reader := range c.additionalMetricReaders {
+		meterOpts = append(meterOpts, sdkmetric.WithReader(reader))
+	}
+
+	if *c.sendToLogfire {
+		if err := c.loadTLSConfig(); err != nil {
+			return nil, err
+		}
+		if c.tokenCheck != TokenCheckOff && c.protocol != ProtocolGRPC {
+			checker := c.tokenChecker()
+			if c.tokenCheck == TokenCheckFail {
+				if err := checker.check(ctx); err != nil {
+					return nil, err
+				}
+			} else {
+				diagnostics := c.diagnostics
+				go func() {
+					if err := checker.check(context.WithoutCancel(ctx)); err != nil {
+						diagnostics.error(err)
+					}
+				}()
+			}
+		}
+		if c.diskQueueOptions != nil {
+			if c.diskQueue, err = newDiskQueue(c); err != nil {
+				return nil, err
+			}
+		}
+		processor, reader, err := c.exporters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, processor)
+		meterOpts = append(meterOpts, sdkmetric.WithReader(reader))
+	}
+	for _, e := range c.additionalEndpoints {
+		// Separate batch processors and readers isolate the endpoints from each other.
+		processor, reader, err := c.endpointConfig(e).exporters(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w (endpoint %s)", err, e.URL)
+		}
+		processors = append(processors, processor)
+		meterOpts = append(meterOpts, sdkmetric.WithReader(reader))
+	}
+	for _, opts := range c.fileExports {
+		exporter, err := NewFileSpanExporter(opts)
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, newBatchSpanProcessor(exporter, c.batchOptions, c.diagnostics))
+	}
+
+	if c.tailSampling != nil {
+		// The tail sampling processor also makes the random decisions of the head sampler.
+		processors = []sdktrace.SpanProcessor{newTailSamplingSpanProcessor(processors, *c.tailSampling, c.tailSamplingRandomRate(), c.baggageForceSampling)}
+	}
+
+	tracerOpts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	if c.remoteSamplingOptions != nil {
+		c.remoteSampler = newRemoteSampler(c, res)
+	}
+	if sampler := c.sampler(); sampler != nil {
+		tracerOpts = append(tracerOpts, sdktrace.WithSampler(sampler))
+	}
+	if scrubber != nil {
+		// Scrubbing applies to every processor, including the additional ones, like in the Python SDK.
+		tracerOpts = append(tracerOpts, sdktrace.WithSpanProcessor(&scrubbingSpanProcessor{
+			scrubber:   scrubber,
+			processors: processors,
+		}))
+	} else {
+		for _, processor := range processors {
+			tracerOpts = append(tracerOpts, sdktrace.WithSpanProcessor(processor))
+		}
+	}
+
+	meterProvider := adviceMeterProvider{MeterProvider: sdkmetric.NewMeterProvider(meterOpts...), advice: advice}
+	c.diagnostics.setMeterProvider(meterProvider)
+	return &providers{
+		tracerProvider:  sdktrace.NewTracerProvider(tracerOpts...),
+		meterProvider:   meterProvider,
+		scrubber:        scrubber,
+		diskQueue:       c.diskQueue,
+		remoteSampler:   c.remoteSampler,
+		diagnostics:     c.diagnostics,
+		shutdownTimeout: c.shutdownTimeout,
+	}, nil
+}
+
+// exporters returns the span processor and metric reader exporting with the configuration.
+func (c *config) exporters(ctx context.Context) (sdktrace.SpanProcessor, sdkmetric.Reader, error) {
+	spanExporter, err := c.spanExporter(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("logfire: creating span exporter: %w", err)
+	}
+	metricExporter, err := c.metricExporter(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("logfire: creating metric exporter: %w", err)
+	}
+	reader := sdkmetric.NewPeriodicReader(&diagnosticMetricExporter{Exporter: metricExporter, d: c.diagnostics},
+		sdkmetric.WithProducer(newRuntimeProducer()),
+		sdkmetric.WithInterval(c.metricReaderOptions.Interval),
+		sdkmetric.WithTimeout(c.metricReaderOptions.Timeout),
+	)
+	return newBatchSpanProcessor(spanExporter, c.batchOptions, c.diagnostics), reader, nil
+}
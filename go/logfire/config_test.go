@@ -0,0 +1,125 @@
+package logfire
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+func TestConfigureRequiresToken(t *testing.T) {
+	_, err := Configure(context.Background())
+	if !errors.Is(err, ErrNoToken) {
+		t.Fatalf("expected ErrNoToken, got %v", err)
+	}
+}
+
+func TestConfigureInstallsGlobalProviders(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	shutdown, err := Configure(context.Background(),
+		WithSendToLogfire(false),
+		WithConsole(false),
+		WithServiceName("my-service"),
+		WithServiceVersion("1.2.3"),
+		WithResourceAttributes(attribute.String("team", "platform")),
+		WithAdditionalSpanProcessors(recorder),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	_, span := otel.Tracer("test").Start(context.Background(), "hello")
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	res := spans[0].Resource()
+	for _, want := range []attribute.KeyValue{
+		semconv.ServiceName("my-service"),
+		semconv.ServiceVersion("1.2.3"),
+		attribute.String("team", "platform"),
+	} {
+		if got, ok := res.Set().Value(want.Key); !ok || got != want.Value {
+			t.Errorf("resource %s = %v, want %v", want.Key, got.Emit(), want.Value.Emit())
+		}
+	}
+	if _, ok := res.Set().Value(semconv.ServiceInstanceIDKey); !ok {
+		t.Error("resource is missing service.instance.id")
+	}
+}
+
+func TestConfigureExportsToLogfire(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests = map[string]string{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path] = r.Header.Get("Authorization")
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c, err := newConfig([]Option{WithToken("test-token"), WithConsole(false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = srv.URL
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := p.tracerProvider.Tracer("test").Start(context.Background(), "hello")
+	span.End()
+	if err := p.shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := requests["/v1/traces"]; got != "test-token" {
+		t.Errorf("expected traces to be exported with the token, got requests %v", requests)
+	}
+}
+
+func TestForceFlush(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	shutdown, err := Configure(context.Background(), WithToken("test-token"), WithBaseURL(srv.URL), WithConsole(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	Info(context.Background(), "hello")
+	counter, _ := otel.Meter("test").Int64Counter("requests")
+	counter.Add(context.Background(), 1)
+	if err := ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/v1/traces"] != 1 || requests["/v1/metrics"] != 1 {
+		t.Errorf("requests = %v, want the log and the metric exported before shutdown", requests)
+	}
+}
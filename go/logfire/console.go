@@ -0,0 +1,394 @@
+package logfire
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ConsoleColors controls whether the console output is colored.
+type ConsoleColors string
+
+// Possible values of [ConsoleColors].
+const (
+	// ConsoleColorsAuto uses colors when writing to a terminal.
+	ConsoleColorsAuto   ConsoleColors = "auto"
+	ConsoleColorsAlways ConsoleColors = "always"
+	ConsoleColorsNever  ConsoleColors = "never"
+)
+
+// ConsoleSpanStyle controls how spans are laid out in the console.
+type ConsoleSpanStyle string
+
+// Possible values of [ConsoleSpanStyle].
+const (
+	// ConsoleSpanStyleSimple shows spans as a flat list, not indented.
+	ConsoleSpanStyleSimple ConsoleSpanStyle = "simple"
+	// ConsoleSpanStyleIndented shows spans as a tree, indented based on how many parents they have.
+	ConsoleSpanStyleIndented ConsoleSpanStyle = "indented"
+	// ConsoleSpanStyleShowParents indents spans like ConsoleSpanStyleIndented, and when spans are interleaved
+	// the parents of a span are printed again to give the best context.
+	ConsoleSpanStyleShowParents ConsoleSpanStyle = "show-parents"
+)
+
+// ConsoleOptions configures a [ConsoleSpanProcessor].
+type ConsoleOptions struct {
+	// Output is where spans are printed, defaults to os.Stdout.
+	Output io.Writer
+	// Colors defaults to ConsoleColorsAuto.
+	Colors ConsoleColors
+	// SpanStyle defaults to ConsoleSpanStyleShowParents.
+	SpanStyle ConsoleSpanStyle
+	// IncludeTimestamps prefixes each line with the start time of the span.
+	IncludeTimestamps bool
+	// Verbose adds the code location, level and attributes of each span.
+	Verbose bool
+	// MinLogLevel is the minimum level of spans and logs to print, defaults to LevelInfo.
+	MinLogLevel Level
+}
+
+// ConsoleSpanProcessor prints spans and logs to the console in the same indented, colorized tree format
+// as the Python SDK.
+//
+// It's a span processor rather than an exporter so that spans can be printed when they start,
+// which is what keeps the output readable as a tree. Logs are printed when they end.
+type ConsoleSpanProcessor struct {
+	opts            ConsoleOptions
+	colors          bool
+	timestampIndent int
+
+	mu sync.Mutex
+	// indentLevels maps span IDs to the indent of their children, for ConsoleSpanStyleIndented.
+	indentLevels map[trace.SpanID]int
+	// spanHistory and spanStack track open spans for ConsoleSpanStyleShowParents.
+	spanHistory map[trace.SpanID]consoleSpanEntry
+	spanStack   []trace.SpanID
+}
+
+type consoleSpanEntry struct {
+	indent int
+	msg    string
+	parent trace.SpanID
+}
+
+var _ sdktrace.SpanProcessor = (*ConsoleSpanProcessor)(nil)
+
+// NewConsoleSpanProcessor returns a [ConsoleSpanProcessor]. [Configure] creates one by default,
+// see [WithConsole].
+func NewConsoleSpanProcessor(opts ConsoleOptions) *ConsoleSpanProcessor {
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+	if opts.SpanStyle == "" {
+		opts.SpanStyle = ConsoleSpanStyleShowParents
+	}
+	if opts.MinLogLevel == 0 {
+		opts.MinLogLevel = LevelInfo
+	}
+	p := &ConsoleSpanProcessor{
+		opts:         opts,
+		colors:       useColors(opts.Colors, opts.Output),
+		indentLevels: map[trace.SpanID]int{},
+		spanHistory:  map[trace.SpanID]consoleSpanEntry{},
+	}
+	if opts.IncludeTimestamps {
+		// len("12:34:56.789") + a space
+		p.timestampIndent = 13
+	}
+	return p
+}
+
+func useColors(colors ConsoleColors, output io.Writer) bool {
+	switch colors {
+	case ConsoleColorsAlways:
+		return true
+	case ConsoleColorsNever:
+		return false
+	}
+	f, ok := output.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// OnStart prints spans as they start.
+func (p *ConsoleSpanProcessor) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	attrs := s.Attributes()
+	if spanType(attrs) == spanTypeLog {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.shouldPrint(attrs) {
+		return
+	}
+	parent := parentSpanID(s)
+	var parts []textPart
+	indent := 0
+	switch p.opts.SpanStyle {
+	case ConsoleSpanStyleIndented:
+		indent = p.indentLevels[parent]
+	case ConsoleSpanStyleShowParents:
+		parts = p.parentStackParts(parent)
+		indent = len(p.spanStack)
+	}
+	msg := p.print(s, indent, parts)
+
+	switch p.opts.SpanStyle {
+	case ConsoleSpanStyleIndented:
+		p.indentLevels[s.SpanContext().SpanID()] = indent + 1
+	case ConsoleSpanStyleShowParents:
+		id := s.SpanContext().SpanID()
+		p.spanHistory[id] = consoleSpanEntry{indent: indent, msg: msg, parent: parent}
+		p.spanStack = append(p.spanStack, id)
+	}
+}
+
+// OnEnd prints logs, and forgets spans that have ended.
+func (p *ConsoleSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	attrs := s.Attributes()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if spanType(attrs) != spanTypeLog {
+		id := s.SpanContext().SpanID()
+		delete(p.indentLevels, id)
+		delete(p.spanHistory, id)
+		if n := len(p.spanStack); n > 0 && p.spanStack[n-1] == id {
+			p.spanStack = p.spanStack[:n-1]
+		}
+		return
+	}
+	if !p.shouldPrint(attrs) {
+		return
+	}
+	parent := parentSpanID(s)
+	var parts []textPart
+	indent := 0
+	switch p.opts.SpanStyle {
+	case ConsoleSpanStyleIndented:
+		indent = p.indentLevels[parent]
+	case ConsoleSpanStyleShowParents:
+		parts = p.parentStackParts(parent)
+		indent = len(p.spanStack)
+	}
+	p.print(s, indent, parts)
+}
+
+// Shutdown does nothing.
+func (p *ConsoleSpanProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush does nothing, spans are printed synchronously.
+func (p *ConsoleSpanProcessor) ForceFlush(context.Context) error { return nil }
+
+func (p *ConsoleSpanProcessor) shouldPrint(attrs []attribute.KeyValue) bool {
+	level := LevelInfo
+	if v, ok := lookupAttribute(attrs, string(LevelNumKey)); ok {
+		level = Level(v.AsInt64())
+	}
+	return level >= p.opts.MinLogLevel
+}
+
+// parentStackParts returns dimmed lines for the parents of a span which aren't in the current stack
+// of displayed spans, and updates the stack to represent the path to the span.
+func (p *ConsoleSpanProcessor) parentStackParts(parent trace.SpanID) []textPart {
+	type missingParent struct {
+		id    trace.SpanID
+		entry consoleSpanEntry
+	}
+	var parents []missingParent
+	clearStack := true
+	for parent.IsValid() {
+		entry, ok := p.spanHistory[parent]
+		if !ok {
+			break
+		}
+		if idx := slices.Index(p.spanStack, parent); idx >= 0 {
+			p.spanStack = p.spanStack[:idx+1]
+			clearStack = false
+			break
+		}
+		parents = append(parents, missingParent{id: parent, entry: entry})
+		parent = entry.parent
+	}
+	if clearStack {
+		p.spanStack = p.spanStack[:0]
+	}
+
+	var parts []textPart
+	// parents were collected from the innermost one.
+	for i := len(parents) - 1; i >= 0; i-- {
+		entry := parents[i].entry
+		parts = append(parts, textPart{strings.Repeat(" ", p.timestampIndent+entry.indent*2) + entry.msg + "\n", styleDim})
+		p.spanStack = append(p.spanStack, parents[i].id)
+	}
+	return parts
+}
+
+// print writes the span to the output and returns its message.
+func (p *ConsoleSpanProcessor) print(s sdktrace.ReadOnlySpan, indent int, parts []textPart) string {
+	attrs := s.Attributes()
+	if p.opts.IncludeTimestamps {
+		parts = append(parts, textPart{s.StartTime().UTC().Format("15:04:05.000"), styleGreen}, textPart{" ", ""})
+	}
+	if indent > 0 {
+		parts = append(parts, textPart{strings.Repeat("  ", indent), ""})
+	}
+	msg := s.Name()
+	if v, ok := lookupAttribute(attrs, string(MsgKey)); ok && v.AsString() != "" {
+		msg = v.AsString()
+	}
+	var level Level
+	if v, ok := lookupAttribute(attrs, string(LevelNumKey)); ok {
+		level = Level(v.AsInt64())
+	}
+	switch {
+	case level >= LevelError:
+		parts = append(parts, textPart{msg, styleRed})
+	case level >= LevelWarn:
+		parts = append(parts, textPart{msg, styleYellow})
+	default:
+		parts = append(parts, textPart{msg, ""})
+	}
+
+	indentStr := strings.Repeat(" ", p.timestampIndent+indent*2)
+	if p.opts.Verbose {
+		parts = append(parts, p.detailsParts(attrs, level, indentStr)...)
+		parts = append(parts, p.attributesParts(attrs, indentStr)...)
+	}
+	for _, event := range s.Events() {
+		if event.Name == semconv.ExceptionEventName {
+			parts = append(parts, p.exceptionParts(event.Attributes, indentStr)...)
+		}
+	}
+
+	var b strings.Builder
+	for _, part := range parts {
+		if p.colors && part.style != "" {
+			b.WriteString(part.style + part.text + styleReset)
+		} else {
+			b.WriteString(part.text)
+		}
+	}
+	b.WriteByte('\n')
+	_, _ = io.WriteString(p.opts.Output, b.String())
+	return msg
+}
+
+// detailsParts returns the code location and level of a span.
+func (p *ConsoleSpanProcessor) detailsParts(attrs []attribute.KeyValue, level Level, indentStr string) []textPart {
+	location := ""
+	if v, ok := lookupAttribute(attrs, string(codeFilepathKey)); ok {
+		location = v.AsString()
+		if line, ok := lookupAttribute(attrs, string(codeLinenoKey)); ok {
+			location += ":" + strconv.FormatInt(line.AsInt64(), 10)
+		}
+	}
+	levelName := ""
+	if _, ok := levelNames[level]; ok {
+		levelName = level.String()
+	}
+	if location == "" && levelName == "" {
+		return nil
+	}
+	return []textPart{
+		{"\n" + indentStr, ""},
+		{"│", styleBlue},
+		{" ", ""},
+		{location, styleCyan},
+		{" " + levelName, ""},
+	}
+}
+
+// attributesParts returns the user attributes of a span, i.e. those that aren't set by Logfire itself.
+func (p *ConsoleSpanProcessor) attributesParts(attrs []attribute.KeyValue, indentStr string) []textPart {
+	var parts []textPart
+	for _, kv := range attrs {
+		key := string(kv.Key)
+		if strings.HasPrefix(key, "logfire.") || strings.HasPrefix(key, "code.") {
+			continue
+		}
+		value := formatValue(kv.Value)
+		if kv.Value.Type() == attribute.STRING {
+			value = strconv.Quote(value)
+		}
+		parts = append(parts,
+			textPart{"\n" + indentStr, ""},
+			textPart{"│ ", styleBlue},
+			textPart{key + "=", styleBlue},
+			textPart{value, ""},
+		)
+	}
+	return parts
+}
+
+func (p *ConsoleSpanProcessor) exceptionParts(attrs []attribute.KeyValue, indentStr string) []textPart {
+	excType, _ := lookupAttribute(attrs, string(semconv.ExceptionTypeKey))
+	excMsg, _ := lookupAttribute(attrs, string(semconv.ExceptionMessageKey))
+	parts := []textPart{
+		{"\n" + indentStr, ""},
+		{"│ ", styleBlue},
+		{excType.AsString() + ": ", styleBoldRed},
+		{excMsg.AsString(), ""},
+	}
+	if stacktrace, ok := lookupAttribute(attrs, string(semconv.ExceptionStacktraceKey)); ok {
+		for _, line := range strings.Split(strings.TrimRight(stacktrace.AsString(), "\n"), "\n") {
+			parts = append(parts, textPart{"\n" + indentStr, ""}, textPart{"│ ", styleBlue}, textPart{line, ""})
+		}
+	}
+	return parts
+}
+
+func spanType(attrs []attribute.KeyValue) string {
+	if v, ok := lookupAttribute(attrs, string(SpanTypeKey)); ok {
+		return v.AsString()
+	}
+	return spanTypeSpan
+}
+
+func parentSpanID(s sdktrace.ReadOnlySpan) trace.SpanID {
+	if parent := s.Parent(); parent.IsValid() {
+		return parent.SpanID()
+	}
+	return trace.SpanID{}
+}
+
+// textPart is a piece of console output with an ANSI style, which is only used when colors are enabled.
+type textPart struct {
+	text  string
+	style string
+}
+
+const (
+	styleReset   = "\x1b[0m"
+	styleDim     = "\x1b[2m"
+	styleRed     = "\x1b[31m"
+	styleBoldRed = "\x1b[1;31m"
+	styleGreen   = "\x1b[32m"
+	styleYellow  = "\x1b[33m"
+	styleBlue    = "\x1b[34m"
+	styleCyan    = "\x1b[36m"
+)
+
+func (c ConsoleColors) valid() bool {
+	return c == ConsoleColorsAuto || c == ConsoleColorsAlways || c == ConsoleColorsNever
+}
+
+func (s ConsoleSpanStyle) valid() bool {
+	return s == ConsoleSpanStyleSimple || s == ConsoleSpanStyleIndented || s == ConsoleSpanStyleShowParents
+}
+
+func invalidConsoleParam(name, value string) error {
+	return fmt.Errorf("logfire: invalid %s %q", name, value)
+}
@@ -0,0 +1,189 @@
+package logfire
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newConsoleTracer(t *testing.T, opts ConsoleOptions) (trace.Tracer, *bytes.Buffer) {
+	t.Helper()
+	var out bytes.Buffer
+	opts.Output = &out
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(NewConsoleSpanProcessor(opts)))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return tp.Tracer("test"), &out
+}
+
+func consoleLog(ctx context.Context, tracer trace.Tracer, level Level, msg string, attrs ...attribute.KeyValue) {
+	ts := time.Date(2024, 1, 1, 0, 0, 3, 0, time.UTC)
+	attrs = append([]attribute.KeyValue{SpanTypeKey.String(spanTypeLog), LevelNumKey.Int(int(level)), MsgKey.String(msg)}, attrs...)
+	_, span := tracer.Start(ctx, msg, trace.WithAttributes(attrs...), trace.WithTimestamp(ts))
+	span.End(trace.WithTimestamp(ts))
+}
+
+func TestConsoleSimple(t *testing.T) {
+	tracer, out := newConsoleTracer(t, ConsoleOptions{Colors: ConsoleColorsNever, SpanStyle: ConsoleSpanStyleSimple, IncludeTimestamps: true})
+
+	ctx, span := tracer.Start(context.Background(), "rootSpan",
+		trace.WithTimestamp(time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC)))
+	_, child := tracer.Start(ctx, "childSpan {a=}",
+		trace.WithAttributes(MsgKey.String("childSpan a=1")),
+		trace.WithTimestamp(time.Date(2024, 1, 1, 0, 0, 2, 0, time.UTC)))
+	consoleLog(ctx, tracer, LevelInfo, "a log")
+	child.End()
+	span.End()
+
+	want := "00:00:01.000 rootSpan\n00:00:02.000 childSpan a=1\n00:00:03.000 a log\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestConsoleIndented(t *testing.T) {
+	tracer, out := newConsoleTracer(t, ConsoleOptions{Colors: ConsoleColorsNever, SpanStyle: ConsoleSpanStyleIndented})
+
+	ctx, span := tracer.Start(context.Background(), "root")
+	childCtx, child := tracer.Start(ctx, "child")
+	consoleLog(childCtx, tracer, LevelInfo, "in child")
+	child.End()
+	consoleLog(ctx, tracer, LevelInfo, "in root")
+	span.End()
+
+	want := "root\n  child\n    in child\n  in root\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestConsoleShowParents(t *testing.T) {
+	tracer, out := newConsoleTracer(t, ConsoleOptions{Colors: ConsoleColorsNever})
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	ctx1, span1 := tracer.Start(ctx, "span 1")
+	ctx2, span2 := tracer.Start(ctx, "span 2")
+	// Interleaved: logging in span 1 after span 2 started prints span 1 again.
+	consoleLog(ctx1, tracer, LevelInfo, "log in 1")
+	consoleLog(ctx2, tracer, LevelInfo, "log in 2")
+	span2.End()
+	span1.End()
+	root.End()
+
+	want := strings.Join([]string{
+		"root",
+		"  span 1",
+		"  span 2",
+		"  span 1",
+		"    log in 1",
+		"  span 2",
+		"    log in 2",
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestConsoleColorsAndLevels(t *testing.T) {
+	tracer, out := newConsoleTracer(t, ConsoleOptions{Colors: ConsoleColorsAlways, IncludeTimestamps: true, MinLogLevel: LevelInfo})
+
+	ctx := context.Background()
+	consoleLog(ctx, tracer, LevelDebug, "hidden")
+	consoleLog(ctx, tracer, LevelWarn, "warning")
+	consoleLog(ctx, tracer, LevelError, "error")
+
+	want := "\x1b[32m00:00:03.000\x1b[0m \x1b[33mwarning\x1b[0m\n" +
+		"\x1b[32m00:00:03.000\x1b[0m \x1b[31merror\x1b[0m\n"
+	if out.String() != want {
+		t.Errorf("got:\n%q\nwant:\n%q", out.String(), want)
+	}
+}
+
+func TestConsoleVerbose(t *testing.T) {
+	tracer, out := newConsoleTracer(t, ConsoleOptions{Colors: ConsoleColorsNever, Verbose: true})
+
+	consoleLog(context.Background(), tracer, LevelInfo, "with attributes",
+		codeFilepathKey.String("main.go"),
+		codeLinenoKey.Int(42),
+		attribute.String("name", "value"),
+		attribute.Int("n", 1),
+	)
+	ts := time.Date(2024, 1, 1, 0, 0, 3, 0, time.UTC)
+	_, span := tracer.Start(context.Background(), "failed",
+		trace.WithAttributes(SpanTypeKey.String(spanTypeLog), LevelNumKey.Int(int(LevelError))), trace.WithTimestamp(ts))
+	span.RecordError(errors.New("boom"))
+	span.End(trace.WithTimestamp(ts))
+
+	want := strings.Join([]string{
+		"with attributes",
+		"│ main.go:42 info",
+		`│ name="value"`,
+		"│ n=1",
+		"failed",
+		"│  error",
+		"│ *errors.errorString: boom",
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestConsoleConfigFromEnv(t *testing.T) {
+	t.Setenv("LOGFIRE_CONSOLE_COLORS", "never")
+	t.Setenv("LOGFIRE_CONSOLE_SPAN_STYLE", "indented")
+	t.Setenv("LOGFIRE_CONSOLE_INCLUDE_TIMESTAMP", "false")
+	t.Setenv("LOGFIRE_CONSOLE_VERBOSE", "true")
+	t.Setenv("LOGFIRE_CONSOLE_MIN_LOG_LEVEL", "warn")
+
+	c, err := newConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ConsoleOptions{
+		Colors:      ConsoleColorsNever,
+		SpanStyle:   ConsoleSpanStyleIndented,
+		Verbose:     true,
+		MinLogLevel: LevelWarn,
+	}
+	if !*c.console || c.consoleOptions != want {
+		t.Errorf("got %+v, want %+v", c.consoleOptions, want)
+	}
+
+	t.Setenv("LOGFIRE_CONSOLE_SPAN_STYLE", "fancy")
+	if _, err := newConfig(nil); err == nil {
+		t.Error("expected an error for an invalid span style")
+	}
+}
+
+func TestConfigureConsole(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := Configure(context.Background(),
+		WithSendToLogfire(false),
+		WithConfigDir(filepath.Join(t.TempDir(), "missing")),
+		WithConsoleOutput(&out),
+		WithConsoleColors(ConsoleColorsNever),
+		WithConsoleIncludeTimestamps(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	ctx, span := Span(context.Background(), "hello {name}", attribute.String("name", "world"))
+	Info(ctx, "inside")
+	Debug(ctx, "not shown")
+	span.End()
+
+	if want := "hello world\n  inside\n"; out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,40 @@
+package logfire
+
+import "go.opentelemetry.io/otel/attribute"
+
+// DefaultBaseURL is the base URL of the Logfire API.
+const DefaultBaseURL = "https://logfire-api.pydantic.dev"
+
+// defaultScheduleDelayMillis is the delay between batch span exports, matching the Python SDK.
+const defaultScheduleDelayMillis = 500
+
+// Attribute keys used by Logfire to render spans and logs in the UI.
+const (
+	// SpanTypeKey differentiates logs from regular spans. Absence should be interpreted as a regular span.
+	SpanTypeKey = attribute.Key("logfire.span_type")
+	// MsgTemplateKey is the message template of a span or log, used to group similar records.
+	MsgTemplateKey = attribute.Key("logfire.msg_template")
+	// MsgKey is the formatted message of a span or log.
+	MsgKey = attribute.Key("logfire.msg")
+	// LevelNumKey is the numeric log level of a span or log.
+	LevelNumKey = attribute.Key("logfire.level_num")
+)
+
+const (
+	spanTypeLog  = "log"
+	spanTypeSpan = "span"
+)
+
+// Code location attributes, using the names expected by the Logfire UI and emitted by the Python SDK,
+// which predate the `code.file.path` and `code.line.number` semantic conventions.
+const (
+	codeFilepathKey = attribute.Key("code.filepath")
+	codeLinenoKey   = attribute.Key("code.lineno")
+)
+
+// Code source resource attributes, with the names of the Python SDK, see [CodeSource].
+const (
+	vcsRepositoryURLKey = attribute.Key("vcs.repository.url.full")
+	vcsRevisionKey      = attribute.Key("vcs.repository.ref.revision")
+	codeRootPathKey     = attribute.Key("logfire.code.root_path")
+)
@@ -0,0 +1,95 @@
+package logfire
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// credentialsFilename is the name of the file written by `logfire projects use` in the data directory.
+const credentialsFilename = "logfire_credentials.json"
+
+// Credentials are the project credentials stored by the Logfire CLI in `.logfire/logfire_credentials.json`.
+type Credentials struct {
+	// Token is the project write token.
+	Token string `json:"token"`
+	// ProjectName is the name of the project.
+	ProjectName string `json:"project_name"`
+	// ProjectURL is the URL of the project in the Logfire UI.
+	ProjectURL string `json:"project_url"`
+	// LogfireAPIURL is the base URL of the Logfire API the project belongs to.
+	LogfireAPIURL string `json:"logfire_api_url"`
+}
+
+// LoadCredentials reads the credentials file in dir, as written by the Python SDK and CLI.
+//
+// It returns nil and no error if the file doesn't exist.
+func LoadCredentials(dir string) (*Credentials, error) {
+	path := filepath.Join(dir, credentialsFilename)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("logfire: invalid credentials file %s: %w", path, err)
+	}
+
+	var raw struct {
+		Credentials
+		// DashboardURL is the legacy name of ProjectURL.
+		DashboardURL string `json:"dashboard_url"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("logfire: invalid credentials file %s: %w", path, err)
+	}
+	creds := raw.Credentials
+	if creds.ProjectURL == "" {
+		creds.ProjectURL = raw.DashboardURL
+	}
+	if creds.Token == "" {
+		return nil, fmt.Errorf("logfire: invalid credentials file %s: missing token", path)
+	}
+	return &creds, nil
+}
+
+// userTokensFile is the file where `logfire auth` stores user tokens, keyed by Logfire API URL.
+func userTokensFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".logfire", "default.toml"), nil
+}
+
+// isLoggedIn reports whether `logfire auth` has stored a user token for baseURL that hasn't expired.
+//
+// User tokens can't be used to send data, but knowing that the user is logged in
+// lets us point them to `logfire projects use` instead of `logfire auth`.
+func isLoggedIn(baseURL string) bool {
+	path, err := userTokensFile()
+	if err != nil {
+		return false
+	}
+	var data struct {
+		Tokens map[string]struct {
+			Token      string `toml:"token"`
+			Expiration string `toml:"expiration"`
+		} `toml:"tokens"`
+	}
+	if _, err := toml.DecodeFile(path, &data); err != nil {
+		return false
+	}
+	info, ok := data.Tokens[baseURL]
+	if !ok {
+		return false
+	}
+	// Expirations are stored in UTC without a timezone, e.g. 2030-01-01T00:00:00.000000.
+	expiration, err := time.Parse("2006-01-02T15:04:05.999999999", strings.TrimSuffix(info.Expiration, "Z"))
+	return err == nil && time.Now().UTC().Before(expiration)
+}
@@ -0,0 +1,110 @@
+package logfire
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadCredentials(t *testing.T) {
+	dir := t.TempDir()
+	if creds, err := LoadCredentials(dir); creds != nil || err != nil {
+		t.Fatalf("expected no credentials, got %v, %v", creds, err)
+	}
+
+	writeFile(t, filepath.Join(dir, credentialsFilename), `{
+  "token": "pylf_v1_us_abc",
+  "project_name": "my-project",
+  "project_url": "https://logfire.pydantic.dev/me/my-project",
+  "logfire_api_url": "https://logfire-api.pydantic.dev"
+}`)
+	creds, err := LoadCredentials(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Credentials{
+		Token:         "pylf_v1_us_abc",
+		ProjectName:   "my-project",
+		ProjectURL:    "https://logfire.pydantic.dev/me/my-project",
+		LogfireAPIURL: "https://logfire-api.pydantic.dev",
+	}
+	if *creds != want {
+		t.Errorf("got %+v, want %+v", *creds, want)
+	}
+}
+
+func TestLoadCredentialsLegacyDashboardURL(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, credentialsFilename), `{"token": "abc", "project_name": "p", "dashboard_url": "https://example.com/p", "logfire_api_url": "https://example.com"}`)
+	creds, err := LoadCredentials(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.ProjectURL != "https://example.com/p" {
+		t.Errorf("got %q", creds.ProjectURL)
+	}
+}
+
+func TestLoadCredentialsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, credentialsFilename), `not json`)
+	if _, err := LoadCredentials(dir); err == nil || !strings.Contains(err.Error(), "invalid credentials file") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestConfigUsesCredentialsFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, credentialsFilename), `{"token": "from-file", "project_name": "p", "project_url": "", "logfire_api_url": "https://logfire-api-eu.pydantic.dev"}`)
+
+	c, err := newConfig([]Option{WithDataDir(dir)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadToken(); err != nil {
+		t.Fatal(err)
+	}
+	if c.token != "from-file" || c.baseURL != "https://logfire-api-eu.pydantic.dev" {
+		t.Errorf("unexpected token %q and base URL %q", c.token, c.baseURL)
+	}
+
+	// An explicit token takes precedence over the file.
+	c, err = newConfig([]Option{WithDataDir(dir), WithToken("explicit")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadToken(); err != nil {
+		t.Fatal(err)
+	}
+	if c.token != "explicit" || c.baseURL != DefaultBaseURL {
+		t.Errorf("unexpected token %q and base URL %q", c.token, c.baseURL)
+	}
+}
+
+func TestConfigureLoggedInWithoutProject(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeFile(t, filepath.Join(home, ".logfire", "default.toml"), `
+[tokens."https://logfire-api.pydantic.dev"]
+token = "pylf_v1_us_user"
+expiration = "2999-01-01T00:00:00.000000"
+`)
+
+	_, err := Configure(context.Background(), WithDataDir(t.TempDir()))
+	if !errors.Is(err, ErrNoToken) || !strings.Contains(err.Error(), "logfire projects use") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
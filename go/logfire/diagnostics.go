@@ -0,0 +1,262 @@
+package logfire
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// DiagnosticKind is the kind of a [Diagnostic].
+type DiagnosticKind string
+
+// Possible values of [DiagnosticKind].
+const (
+	// DiagnosticSpansDropped is reported when spans are dropped because the queue of a batch span processor is full.
+	DiagnosticSpansDropped DiagnosticKind = "spans_dropped"
+	// DiagnosticExportFailed is reported when a batch of spans or metrics is lost because exporting it failed,
+	// after retries if the error was temporary.
+	DiagnosticExportFailed DiagnosticKind = "export_failed"
+	// DiagnosticQueueFull is reported when exports are dropped because the disk queue is full, see [WithDiskQueue].
+	DiagnosticQueueFull DiagnosticKind = "queue_full"
+	// DiagnosticError is reported for other errors, which don't lose telemetry by themselves,
+	// e.g. when the disk queue fails to send and will retry.
+	DiagnosticError DiagnosticKind = "error"
+)
+
+// Diagnostic describes a problem of the SDK, usually telemetry that was lost.
+type Diagnostic struct {
+	Kind DiagnosticKind
+	// Signal is `traces` or `metrics`, if the diagnostic is about one of them.
+	Signal string
+	// Count is the number of spans lost, or of exports for metrics and DiagnosticQueueFull.
+	Count int
+	Err   error
+}
+
+// DiagnosticsOptions configures how the SDK reports its problems, see [WithDiagnostics].
+type DiagnosticsOptions struct {
+	// Callback is called for every diagnostic, e.g. to alert about lost telemetry. It must not block.
+	Callback func(Diagnostic)
+	// ErrorOutput is where the errors of diagnostics are logged. Defaults to os.Stderr.
+	ErrorOutput io.Writer
+	// ErrorInterval is the minimum time between two errors of the same kind logged to ErrorOutput,
+	// the number of errors not logged in between being added to the next one. Defaults to one minute.
+	ErrorInterval time.Duration
+}
+
+// WithDiagnostics sets how the SDK reports its problems, e.g. telemetry lost because Logfire can't be reached.
+//
+// Besides being logged, lost telemetry is counted by the `logfire.sdk.spans.dropped`, `logfire.sdk.exports.failed`
+// and `logfire.sdk.disk_queue.dropped` metrics, exported with the others.
+func WithDiagnostics(opts DiagnosticsOptions) Option {
+	return func(c *config) {
+		c.diagnosticsOptions = opts
+	}
+}
+
+// diagnostics reports the problems of the exporters of a configuration, which would otherwise go to the
+// OpenTelemetry error handler, logging every failed export without rate limiting.
+type diagnostics struct {
+	opts        DiagnosticsOptions
+	instruments atomic.Pointer[diagnosticInstruments]
+
+	mu         sync.Mutex
+	lastLogged map[DiagnosticKind]time.Time
+	suppressed map[DiagnosticKind]int
+}
+
+type diagnosticInstruments struct {
+	spansDropped  metric.Int64Counter
+	exportsFailed metric.Int64Counter
+	queueDropped  metric.Int64Counter
+}
+
+func newDiagnostics(opts DiagnosticsOptions) *diagnostics {
+	if opts.ErrorOutput == nil {
+		opts.ErrorOutput = os.Stderr
+	}
+	if opts.ErrorInterval <= 0 {
+		opts.ErrorInterval = time.Minute
+	}
+	return &diagnostics{
+		opts:       opts,
+		lastLogged: map[DiagnosticKind]time.Time{},
+		suppressed: map[DiagnosticKind]int{},
+	}
+}
+
+// setMeterProvider creates the counters of the diagnostics, which are recorded from then on.
+func (d *diagnostics) setMeterProvider(provider metric.MeterProvider) {
+	meter := provider.Meter(instrumentationName, metric.WithInstrumentationVersion(Version))
+	var instruments diagnosticInstruments
+	instruments.spansDropped, _ = meter.Int64Counter("logfire.sdk.spans.dropped",
+		metric.WithUnit("{span}"), metric.WithDescription("Spans lost by the SDK, by reason"))
+	instruments.exportsFailed, _ = meter.Int64Counter("logfire.sdk.exports.failed",
+		metric.WithUnit("{export}"), metric.WithDescription("Exports which failed, by signal"))
+	instruments.queueDropped, _ = meter.Int64Counter("logfire.sdk.disk_queue.dropped",
+		metric.WithUnit("{export}"), metric.WithDescription("Exports dropped because the disk queue is full"))
+	d.instruments.Store(&instruments)
+}
+
+func (d *diagnostics) report(diag Diagnostic) {
+	if instruments := d.instruments.Load(); instruments != nil {
+		ctx := context.Background()
+		switch diag.Kind {
+		case DiagnosticSpansDropped:
+			instruments.spansDropped.Add(ctx, int64(diag.Count), metric.WithAttributes(attribute.String("reason", "queue_full")))
+		case DiagnosticExportFailed:
+			instruments.exportsFailed.Add(ctx, 1, metric.WithAttributes(attribute.String("signal", diag.Signal)))
+			if diag.Signal == signalTraces {
+				instruments.spansDropped.Add(ctx, int64(diag.Count), metric.WithAttributes(attribute.String("reason", "export_failed")))
+			}
+		case DiagnosticQueueFull:
+			instruments.queueDropped.Add(ctx, int64(diag.Count))
+		}
+	}
+	if d.opts.Callback != nil {
+		d.opts.Callback(diag)
+	}
+
+	d.mu.Lock()
+	now := time.Now()
+	if now.Sub(d.lastLogged[diag.Kind]) < d.opts.ErrorInterval {
+		d.suppressed[diag.Kind]++
+		d.mu.Unlock()
+		return
+	}
+	suppressed := d.suppressed[diag.Kind]
+	d.lastLogged[diag.Kind] = now
+	d.suppressed[diag.Kind] = 0
+	d.mu.Unlock()
+
+	msg := diag.Err.Error()
+	if suppressed > 0 {
+		msg += fmt.Sprintf(" (%d similar errors not logged)", suppressed)
+	}
+	fmt.Fprintf(d.opts.ErrorOutput, "%s %s\n", now.Format("2006/01/02 15:04:05"), msg)
+}
+
+func (d *diagnostics) error(err error) {
+	d.report(Diagnostic{Kind: DiagnosticError, Err: err})
+}
+
+// batchCounter detects the spans dropped by a batch span processor, which doesn't report them,
+// from the difference between the spans it received and those it exported.
+type batchCounter struct {
+	d *diagnostics
+	// capacity is the maximum number of spans in the processor, queued or being exported.
+	capacity int64
+	ended    atomic.Int64
+	exported atomic.Int64
+
+	mu       sync.Mutex
+	reported int64
+}
+
+// check reports the spans known to be dropped since the last check. Until the processor is shut down,
+// the spans not exported yet may be in its queue, so only those beyond its capacity are known to be dropped.
+func (b *batchCounter) check(shutdown bool) {
+	dropped := b.ended.Load() - b.exported.Load()
+	if !shutdown {
+		dropped -= b.capacity
+	}
+	b.mu.Lock()
+	n := dropped - b.reported
+	if n > 0 {
+		b.reported = dropped
+	}
+	b.mu.Unlock()
+	if n > 0 {
+		b.d.report(Diagnostic{
+			Kind:   DiagnosticSpansDropped,
+			Signal: signalTraces,
+			Count:  int(n),
+			Err:    fmt.Errorf("logfire: dropped %d spans, the batch span processor queue is full", n),
+		})
+	}
+}
+
+// countingSpanProcessor counts the spans ended into a batch span processor.
+type countingSpanProcessor struct {
+	sdktrace.SpanProcessor
+	counter *batchCounter
+}
+
+func (p *countingSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	// Like the batch span processor, which ignores the others.
+	if s.SpanContext().IsSampled() {
+		p.counter.ended.Add(1)
+	}
+	p.SpanProcessor.OnEnd(s)
+}
+
+func (p *countingSpanProcessor) Shutdown(ctx context.Context) error {
+	err := p.SpanProcessor.Shutdown(ctx)
+	if err == nil {
+		p.counter.check(true)
+	}
+	return err
+}
+
+// diagnosticSpanExporter reports failed exports to the diagnostics instead of the batch span processor,
+// which would pass them to the OpenTelemetry error handler.
+type diagnosticSpanExporter struct {
+	sdktrace.SpanExporter
+	counter *batchCounter
+}
+
+func (e *diagnosticSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	e.counter.exported.Add(int64(len(spans)))
+	if err != nil {
+		e.counter.d.report(Diagnostic{
+			Kind:   DiagnosticExportFailed,
+			Signal: signalTraces,
+			Count:  len(spans),
+			Err:    fmt.Errorf("logfire: failed to export %d spans: %w", len(spans), err),
+		})
+	}
+	e.counter.check(false)
+	return nil
+}
+
+// diagnosticMetricExporter reports failed exports to the diagnostics instead of the periodic reader.
+type diagnosticMetricExporter struct {
+	sdkmetric.Exporter
+	d *diagnostics
+}
+
+func (e *diagnosticMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	if err := e.Exporter.Export(ctx, rm); err != nil {
+		e.d.report(Diagnostic{
+			Kind:   DiagnosticExportFailed,
+			Signal: signalMetrics,
+			Count:  1,
+			Err:    fmt.Errorf("logfire: failed to export metrics: %w", err),
+		})
+	}
+	return nil
+}
+
+// newBatchSpanProcessor returns a batch span processor whose dropped spans and failed exports are
+// reported to d.
+func newBatchSpanProcessor(exporter sdktrace.SpanExporter, opts BatchOptions, d *diagnostics) sdktrace.SpanProcessor {
+	counter := &batchCounter{d: d, capacity: int64(opts.MaxQueueSize + opts.MaxExportBatchSize)}
+	processor := sdktrace.NewBatchSpanProcessor(&diagnosticSpanExporter{SpanExporter: exporter, counter: counter},
+		sdktrace.WithMaxQueueSize(opts.MaxQueueSize),
+		sdktrace.WithMaxExportBatchSize(opts.MaxExportBatchSize),
+		sdktrace.WithBatchTimeout(opts.ScheduleDelay),
+		sdktrace.WithExportTimeout(opts.ExportTimeout),
+	)
+	return &countingSpanProcessor{SpanProcessor: processor, counter: counter}
+}
@@ -0,0 +1,113 @@
+package logfire
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestDiagnosticsRateLimit(t *testing.T) {
+	var out bytes.Buffer
+	var reported []DiagnosticKind
+	d := newDiagnostics(DiagnosticsOptions{
+		ErrorOutput:   &out,
+		ErrorInterval: time.Hour,
+		Callback:      func(diag Diagnostic) { reported = append(reported, diag.Kind) },
+	})
+	for range 3 {
+		d.error(errors.New("send failed"))
+	}
+	d.report(Diagnostic{Kind: DiagnosticQueueFull, Count: 1, Err: errors.New("queue full")})
+	if len(reported) != 4 {
+		t.Errorf("callback called for %v, want every diagnostic", reported)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 {
+		t.Errorf("logged %q, want one error of each kind", lines)
+	}
+
+	out.Reset()
+	d.lastLogged[DiagnosticError] = time.Now().Add(-2 * time.Hour)
+	d.error(errors.New("send failed"))
+	if got := out.String(); !strings.HasSuffix(got, " send failed (2 similar errors not logged)\n") {
+		t.Errorf("logged %q, want the number of errors not logged", got)
+	}
+}
+
+func TestBatchCounter(t *testing.T) {
+	var dropped []int
+	d := newDiagnostics(DiagnosticsOptions{ErrorOutput: &bytes.Buffer{}, Callback: func(diag Diagnostic) {
+		if diag.Kind == DiagnosticSpansDropped {
+			dropped = append(dropped, diag.Count)
+		}
+	}})
+	counter := &batchCounter{d: d, capacity: 10}
+	counter.ended.Store(15)
+	counter.exported.Store(2)
+	// The 10 spans which may be queued aren't known to be dropped yet.
+	counter.check(false)
+	counter.check(false)
+	counter.check(true)
+	if len(dropped) != 2 || dropped[0] != 3 || dropped[1] != 10 {
+		t.Errorf("dropped = %v, want 3 then the 10 left at shutdown", dropped)
+	}
+}
+
+func TestDiagnosticsMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	reader := sdkmetric.NewManualReader()
+	var out bytes.Buffer
+	c, err := newConfig([]Option{WithToken("test-token"), WithConsole(false), WithRetry(RetryOptions{Disabled: true}),
+		WithAdditionalMetricReaders(reader), WithDiagnostics(DiagnosticsOptions{ErrorOutput: &out})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = srv.URL
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.shutdown(context.Background())
+	_, span := p.tracerProvider.Tracer("test").Start(context.Background(), "hello")
+	span.End()
+	if err := p.tracerProvider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				for _, kv := range dp.Attributes.ToSlice() {
+					got[m.Name+" "+string(kv.Key)+"="+kv.Value.Emit()] = dp.Value
+				}
+			}
+		}
+	}
+	want := map[string]int64{
+		"logfire.sdk.exports.failed signal=traces":       1,
+		"logfire.sdk.spans.dropped reason=export_failed": 1,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %d, want %d (got %v)", k, got[k], v, got)
+		}
+	}
+	if !strings.Contains(out.String(), "logfire: failed to export 1 spans") {
+		t.Errorf("logged %q", out.String())
+	}
+}
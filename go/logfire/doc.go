@@ -0,0 +1,13 @@
+// Package logfire is the Go SDK for Pydantic Logfire.
+//
+// Logfire is an opinionated wrapper around OpenTelemetry: [Configure] sets up
+// the OpenTelemetry SDK so that traces and metrics are exported to Logfire,
+// and installs the resulting providers globally so that any OpenTelemetry
+// instrumentation picks them up.
+//
+//	shutdown, err := logfire.Configure(ctx, logfire.WithToken(token))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer shutdown(context.Background())
+package logfire
@@ -0,0 +1,299 @@
+package logfire
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Protocols of the OTLP exporters, see [WithProtocol].
+const (
+	// ProtocolHTTPProtobuf exports protobuf messages over HTTP, to the `/v1/traces` and `/v1/metrics` paths of the base URL.
+	ProtocolHTTPProtobuf = "http/protobuf"
+	// ProtocolGRPC exports over gRPC, to the host and port of the base URL.
+	ProtocolGRPC = "grpc"
+)
+
+// OTLPEndpoint is an OpenTelemetry endpoint receiving the same spans and metrics as Logfire,
+// see [WithAdditionalEndpoints].
+type OTLPEndpoint struct {
+	// URL is the base URL of the endpoint, e.g. `http://collector:4318` with [ProtocolHTTPProtobuf].
+	URL string
+	// Protocol defaults to the protocol of the Logfire exporters, see [WithProtocol].
+	Protocol string
+	// Headers are sent with every export, e.g. to authenticate. The Logfire token isn't.
+	Headers map[string]string
+	// TLSConfig is the TLS configuration of the connections to the endpoint.
+	// Those of the Logfire exporters aren't used, so that they can only be trusted by Logfire.
+	TLSConfig *tls.Config
+}
+
+// WithAdditionalEndpoints also exports spans and metrics to OTLP endpoints, e.g. to an internal
+// OpenTelemetry Collector, even when sending to Logfire is disabled.
+//
+// Each endpoint is exported to on its own, so that an endpoint that is down or slow doesn't delay the
+// others. The settings of the Logfire exporters are used, except for their TLS configuration and disk queue.
+func WithAdditionalEndpoints(endpoints ...OTLPEndpoint) Option {
+	return func(c *config) {
+		c.additionalEndpoints = append(c.additionalEndpoints, endpoints...)
+	}
+}
+
+// endpointConfig returns the configuration of the exporters of an additional endpoint.
+func (c *config) endpointConfig(e OTLPEndpoint) *config {
+	ec := *c
+	ec.baseURL = e.URL
+	if e.Protocol != "" {
+		ec.protocol = e.Protocol
+	}
+	ec.token = ""
+	ec.endpointHeaders = e.Headers
+	ec.tlsConfig = e.TLSConfig
+	ec.diskQueue = nil
+	return &ec
+}
+
+func (c *config) spanExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	if c.diskQueue != nil {
+		// The queue sends with the configured protocol, the exporter only encodes the spans.
+		return otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpointURL(c.endpoint("/v1/"+signalTraces)),
+			otlptracehttp.WithHTTPClient(&http.Client{Transport: c.diskQueue}),
+			otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}),
+		)
+	}
+	if c.protocol == ProtocolGRPC {
+		opts := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpointURL(c.baseURL),
+			otlptracegrpc.WithHeaders(c.headers()),
+			otlptracegrpc.WithDialOption(c.grpcDialOptions...),
+			otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{
+				Enabled:         !c.retryOptions.Disabled,
+				InitialInterval: c.retryOptions.InitialInterval,
+				MaxInterval:     c.retryOptions.MaxInterval,
+				MaxElapsedTime:  c.retryOptions.MaxElapsedTime,
+			}),
+			otlptracegrpc.WithTimeout(c.exportTimeout()),
+		}
+		if c.dialer != nil {
+			opts = append(opts, otlptracegrpc.WithDialOption(c.grpcDialer()))
+		}
+		if c.tlsConfig != nil {
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(c.tlsConfig)))
+		}
+		switch c.compression {
+		case CompressionGzip:
+			opts = append(opts, otlptracegrpc.WithCompressor(string(c.compression)))
+		case CompressionZstd:
+			// The exporter only knows of gzip, so zstd is set on the connection.
+			opts = append(opts, otlptracegrpc.WithDialOption(grpc.WithDefaultCallOptions(grpc.UseCompressor(string(c.compression)))))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	}
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpointURL(c.endpoint("/v1/traces")),
+		otlptracehttp.WithHeaders(c.headers()),
+		// Retries are made by the client.
+		otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}),
+		otlptracehttp.WithHTTPClient(c.exporterHTTPClient()),
+		otlptracehttp.WithTimeout(c.exportTimeout()),
+	}
+	if c.compression == CompressionGzip {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
+
+func (c *config) metricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	if c.diskQueue != nil {
+		return otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpointURL(c.endpoint("/v1/"+signalMetrics)),
+			otlpmetrichttp.WithHTTPClient(&http.Client{Transport: c.diskQueue}),
+			otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{Enabled: false}),
+			otlpmetrichttp.WithTemporalitySelector(deltaTemporality),
+			otlpmetrichttp.WithAggregationSelector(exponentialHistograms),
+		)
+	}
+	if c.protocol == ProtocolGRPC {
+		opts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpointURL(c.baseURL),
+			otlpmetricgrpc.WithHeaders(c.headers()),
+			otlpmetricgrpc.WithDialOption(c.grpcDialOptions...),
+			otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{
+				Enabled:         !c.retryOptions.Disabled,
+				InitialInterval: c.retryOptions.InitialInterval,
+				MaxInterval:     c.retryOptions.MaxInterval,
+				MaxElapsedTime:  c.retryOptions.MaxElapsedTime,
+			}),
+			otlpmetricgrpc.WithTimeout(c.exportTimeout()),
+			otlpmetricgrpc.WithTemporalitySelector(deltaTemporality),
+			otlpmetricgrpc.WithAggregationSelector(exponentialHistograms),
+		}
+		if c.dialer != nil {
+			opts = append(opts, otlpmetricgrpc.WithDialOption(c.grpcDialer()))
+		}
+		if c.tlsConfig != nil {
+			opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(c.tlsConfig)))
+		}
+		switch c.compression {
+		case CompressionGzip:
+			opts = append(opts, otlpmetricgrpc.WithCompressor(string(c.compression)))
+		case CompressionZstd:
+			// The exporter only knows of gzip, so zstd is set on the connection.
+			opts = append(opts, otlpmetricgrpc.WithDialOption(grpc.WithDefaultCallOptions(grpc.UseCompressor(string(c.compression)))))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	}
+	opts := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithEndpointURL(c.endpoint("/v1/metrics")),
+		otlpmetrichttp.WithHeaders(c.headers()),
+		// Retries are made by the client.
+		otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{Enabled: false}),
+		otlpmetrichttp.WithHTTPClient(c.exporterHTTPClient()),
+		otlpmetrichttp.WithTimeout(c.exportTimeout()),
+		otlpmetrichttp.WithTemporalitySelector(deltaTemporality),
+		otlpmetrichttp.WithAggregationSelector(exponentialHistograms),
+	}
+	if c.compression == CompressionGzip {
+		opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+	}
+	return otlpmetrichttp.New(ctx, opts...)
+}
+
+// httpClient returns a client with the transport settings of the configuration, or nil if there are none,
+// in which case the default transport honors the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func (c *config) httpClient() *http.Client {
+	if c.client == nil && c.dialer == nil && c.tlsConfig == nil && c.compression != CompressionZstd {
+		return nil
+	}
+	client := &http.Client{}
+	if c.client != nil {
+		*client = *c.client
+	}
+	if client.Transport == nil {
+		client.Transport = http.DefaultTransport
+	}
+	if t, ok := client.Transport.(*http.Transport); ok && (c.dialer != nil || c.tlsConfig != nil) {
+		t = t.Clone()
+		if c.dialer != nil {
+			t.DialContext = c.dialer
+		}
+		if c.tlsConfig != nil {
+			t.TLSClientConfig = c.tlsConfig
+		}
+		client.Transport = t
+	}
+	if c.compression == CompressionZstd {
+		client.Transport = zstdTransport{base: client.Transport}
+	}
+	return client
+}
+
+// exportTimeout returns the timeout of the exporters, which includes their retries.
+func (c *config) exportTimeout() time.Duration {
+	if c.retryOptions.Disabled {
+		return 10 * time.Second
+	}
+	return c.retryOptions.MaxElapsedTime
+}
+
+// exporterHTTPClient returns the client of the HTTP exporters, retrying failed requests unless disabled.
+func (c *config) exporterHTTPClient() *http.Client {
+	client := c.httpClient()
+	if client == nil {
+		client = &http.Client{Transport: http.DefaultTransport}
+	}
+	if !c.retryOptions.Disabled {
+		client.Transport = &retryTransport{base: client.Transport, opts: c.retryOptions}
+	}
+	return client
+}
+
+// loadTLSConfig adds the certificates set with [WithCACertificate] and [WithClientCertificate]
+// to a copy of the TLS configuration set with [WithTLSConfig], if any.
+func (c *config) loadTLSConfig() error {
+	if c.caCertificate == "" && c.clientCertificate == "" {
+		return nil
+	}
+	cfg := &tls.Config{}
+	if c.tlsConfig != nil {
+		cfg = c.tlsConfig.Clone()
+	}
+	if c.caCertificate != "" {
+		pem, err := os.ReadFile(c.caCertificate)
+		if err != nil {
+			return fmt.Errorf("logfire: reading CA certificate: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("logfire: no certificate found in %s", c.caCertificate)
+		}
+	}
+	if c.clientCertificate != "" {
+		cert, err := tls.LoadX509KeyPair(c.clientCertificate, c.clientKey)
+		if err != nil {
+			return fmt.Errorf("logfire: loading client certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	c.tlsConfig = cfg
+	return nil
+}
+
+// grpcDialer returns the dial option of the gRPC exporters using the dialer set with [WithDialer].
+func (c *config) grpcDialer() grpc.DialOption {
+	return grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return c.dialer(ctx, "tcp", addr)
+	})
+}
+
+func (c *config) endpoint(path string) string {
+	u, err := url.JoinPath(c.baseURL, path)
+	if err != nil {
+		return c.baseURL + path
+	}
+	return u
+}
+
+func (c *config) headers() map[string]string {
+	headers := map[string]string{"User-Agent": "logfire-go/" + Version}
+	for k, v := range c.endpointHeaders {
+		headers[k] = v
+	}
+	if c.token != "" {
+		headers["Authorization"] = c.token
+	}
+	return headers
+}
+
+// deltaTemporality matches the temporality preferred by the Logfire backend.
+func deltaTemporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	return metricdata.DeltaTemporality
+}
+
+// exponentialHistograms aggregates histograms, e.g. of durations, with base 2 exponential buckets like the
+// Python SDK, which keep their precision whatever the range of values and are handled best by the Logfire
+// backend. Histograms with advised bucket boundaries keep them, see [bucketAdvice], and views can set the
+// aggregation of any histogram, see [WithMetricViews].
+func exponentialHistograms(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	if kind == sdkmetric.InstrumentKindHistogram {
+		return sdkmetric.AggregationBase2ExponentialHistogram{MaxSize: 160, MaxScale: 20}
+	}
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
@@ -0,0 +1,528 @@
+package logfire
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"google.golang.org/protobuf/proto"
+
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+type traceService struct {
+	collectortrace.UnimplementedTraceServiceServer
+	spans         chan string
+	authorization chan string
+}
+
+func (s *traceService) Export(ctx context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.authorization <- md.Get("authorization")[0]
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				s.spans <- span.Name
+			}
+		}
+	}
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+type metricsService struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+}
+
+func (metricsService) Export(context.Context, *collectormetrics.ExportMetricsServiceRequest) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	return &collectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+func TestConfigureExportsOverGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traces := &traceService{spans: make(chan string, 1), authorization: make(chan string, 1)}
+	srv := grpc.NewServer()
+	collectortrace.RegisterTraceServiceServer(srv, traces)
+	collectormetrics.RegisterMetricsServiceServer(srv, metricsService{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	c, err := newConfig([]Option{WithToken("test-token"), WithConsole(false), WithProtocol(ProtocolGRPC), WithCompression(CompressionZstd)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = "http://" + lis.Addr().String()
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := p.tracerProvider.Tracer("test").Start(context.Background(), "hello")
+	span.End()
+	if err := p.shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := <-traces.spans; got != "hello" {
+		t.Errorf("span = %s, want hello", got)
+	}
+	if got := <-traces.authorization; got != "test-token" {
+		t.Errorf("authorization = %s, want the token", got)
+	}
+}
+
+func TestProtocolParam(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	c, err := newConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.protocol != ProtocolGRPC {
+		t.Errorf("protocol = %s, want grpc", c.protocol)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/json")
+	if _, err := newConfig(nil); err == nil {
+		t.Error("no error for an unsupported protocol")
+	}
+}
+
+func TestCompression(t *testing.T) {
+	for _, compression := range []Compression{CompressionGzip, CompressionZstd, CompressionNone} {
+		t.Run(string(compression), func(t *testing.T) {
+			encodings := make(chan string, 1)
+			spans := make(chan string, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/traces" {
+					return
+				}
+				encodings <- r.Header.Get("Content-Encoding")
+				var body io.Reader = r.Body
+				switch r.Header.Get("Content-Encoding") {
+				case "gzip":
+					body, _ = gzip.NewReader(r.Body)
+				case "zstd":
+					dec, _ := zstd.NewReader(r.Body)
+					defer dec.Close()
+					body = dec
+				}
+				b, _ := io.ReadAll(body)
+				var req collectortrace.ExportTraceServiceRequest
+				if err := proto.Unmarshal(b, &req); err != nil {
+					t.Errorf("unmarshal: %v", err)
+				}
+				spans <- req.ResourceSpans[0].ScopeSpans[0].Spans[0].Name
+			}))
+			defer srv.Close()
+
+			c, err := newConfig([]Option{WithToken("test-token"), WithConsole(false), WithCompression(compression)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.baseURL = srv.URL
+			p, err := c.initialize(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, span := p.tracerProvider.Tracer("test").Start(context.Background(), "hello")
+			span.End()
+			if err := p.shutdown(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			want := string(compression)
+			if compression == CompressionNone {
+				want = ""
+			}
+			if got := <-encodings; got != want {
+				t.Errorf("Content-Encoding = %q, want %q", got, want)
+			}
+			if got := <-spans; got != "hello" {
+				t.Errorf("span = %s, want hello", got)
+			}
+		})
+	}
+}
+
+func TestExponentialHistograms(t *testing.T) {
+	exports := make(chan *collectormetrics.ExportMetricsServiceRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" {
+			return
+		}
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			body, _ = gzip.NewReader(r.Body)
+		}
+		b, _ := io.ReadAll(body)
+		var req collectormetrics.ExportMetricsServiceRequest
+		if err := proto.Unmarshal(b, &req); err != nil {
+			t.Errorf("unmarshal: %v", err)
+		}
+		exports <- &req
+	}))
+	defer srv.Close()
+
+	c, err := newConfig([]Option{WithToken("test-token"), WithConsole(false), WithMetricViews(sdkmetric.NewView(
+		sdkmetric.Instrument{Name: "explicit"},
+		sdkmetric.Stream{Aggregation: sdkmetric.AggregationExplicitBucketHistogram{Boundaries: []float64{1, 10}}},
+	))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = srv.URL
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	meter := p.meterProvider.Meter("test")
+	for _, name := range []string{"duration", "explicit"} {
+		histogram, _ := meter.Float64Histogram(name)
+		histogram.Record(context.Background(), 0.5)
+	}
+	advised, _ := meter.Int64Histogram("advised", metric.WithExplicitBucketBoundaries(1, 2, 5))
+	advised.Record(context.Background(), 3)
+	defer otel.SetMeterProvider(otel.GetMeterProvider())
+	otel.SetMeterProvider(p.meterProvider)
+	MetricHistogram("buckets", MetricOptions{Buckets: []float64{0.1, 1, 10, 100}}).Record(context.Background(), 0.5)
+	if err := p.shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-exports
+	seen := 0
+	for _, sm := range req.ResourceMetrics[0].ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch m.Name {
+			case "duration":
+				seen++
+				if m.GetExponentialHistogram() == nil {
+					t.Errorf("duration = %v, want an exponential histogram", m.Data)
+				}
+			case "explicit":
+				seen++
+				if h := m.GetHistogram(); h == nil || len(h.DataPoints[0].ExplicitBounds) != 2 {
+					t.Errorf("explicit = %v, want the histogram of the view", m.Data)
+				}
+			case "advised", "buckets":
+				seen++
+				want := map[string]int{"advised": 3, "buckets": 4}[m.Name]
+				if h := m.GetHistogram(); h == nil || len(h.DataPoints[0].ExplicitBounds) != want {
+					t.Errorf("%s = %v, want a histogram with %d boundaries", m.Name, m.Data, want)
+				}
+			}
+		}
+	}
+	if seen != 4 {
+		t.Errorf("exported %d of the histograms, want 4", seen)
+	}
+}
+
+func exportSpan(t *testing.T, baseURL string, opts ...Option) {
+	t.Helper()
+	c, err := newConfig(append([]Option{WithToken("test-token"), WithConsole(false)}, opts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = baseURL
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := p.tracerProvider.Tracer("test").Start(context.Background(), "hello")
+	span.End()
+	if err := p.shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	hosts := make(chan string, 2)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts <- r.URL.Host
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	exportSpan(t, "http://logfire.invalid", WithHTTPClient(&http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+	}), WithCompression(CompressionZstd))
+	if got := <-hosts; got != "logfire.invalid" {
+		t.Errorf("proxied host = %s", got)
+	}
+}
+
+func TestDialer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcSrv := grpc.NewServer()
+	traces := &traceService{spans: make(chan string, 1), authorization: make(chan string, 1)}
+	collectortrace.RegisterTraceServiceServer(grpcSrv, traces)
+	collectormetrics.RegisterMetricsServiceServer(grpcSrv, metricsService{})
+	go grpcSrv.Serve(lis)
+	defer grpcSrv.Stop()
+
+	for _, tt := range []struct {
+		protocol string
+		addr     string
+	}{
+		{ProtocolHTTPProtobuf, srv.Listener.Addr().String()},
+		{ProtocolGRPC, lis.Addr().String()},
+	} {
+		var dialed []string
+		var mu sync.Mutex
+		exportSpan(t, "http://192.0.2.1:4318", WithProtocol(tt.protocol),
+			WithDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+				mu.Lock()
+				dialed = append(dialed, addr)
+				mu.Unlock()
+				return (&net.Dialer{}).DialContext(ctx, network, tt.addr)
+			}))
+		if len(dialed) == 0 || dialed[0] != "192.0.2.1:4318" {
+			t.Errorf("%s: dialed %v", tt.protocol, dialed)
+		}
+	}
+	if got := <-traces.spans; got != "hello" {
+		t.Errorf("gRPC span = %s, want hello", got)
+	}
+}
+
+// writeClientCertificate writes a self-signed client certificate and its key to dir.
+func writeClientCertificate(t *testing.T, dir string) (certPath, keyPath string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "exporter"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "exporter"}}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ = x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certPath, keyPath = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certPath, keyPath, cert
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath, clientCert := writeClientCertificate(t, dir)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	subjects := make(chan string, 2)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			subjects <- r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	caPath := filepath.Join(dir, "ca.pem")
+	os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600)
+
+	exportSpan(t, srv.URL, WithCACertificate(caPath), WithClientCertificate(certPath, keyPath))
+	if got := <-subjects; got != "exporter" {
+		t.Errorf("client certificate = %s", got)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_CERTIFICATE", caPath)
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE", certPath)
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_KEY", keyPath)
+	exportSpan(t, srv.URL, WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS13}))
+	if got := <-subjects; got != "exporter" {
+		t.Errorf("client certificate = %s", got)
+	}
+}
+
+func TestMutualTLSOverGRPC(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath, clientCert := writeClientCertificate(t, dir)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	// The server uses the certificate of an httptest server, which is valid for 127.0.0.1.
+	certSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	certSrv.Close()
+	caPath := filepath.Join(dir, "ca.pem")
+	os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certSrv.Certificate().Raw}), 0o600)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: certSrv.TLS.Certificates,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	})))
+	traces := &traceService{spans: make(chan string, 1), authorization: make(chan string, 1)}
+	collectortrace.RegisterTraceServiceServer(srv, traces)
+	collectormetrics.RegisterMetricsServiceServer(srv, metricsService{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	exportSpan(t, "https://"+lis.Addr().String(), WithProtocol(ProtocolGRPC),
+		WithCACertificate(caPath), WithClientCertificate(certPath, keyPath))
+	if got := <-traces.spans; got != "hello" {
+		t.Errorf("span = %s, want hello", got)
+	}
+}
+
+func TestCACertificateErrors(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, nil, 0o600)
+	for _, opt := range []Option{
+		WithCACertificate(filepath.Join(t.TempDir(), "missing.pem")),
+		WithCACertificate(empty),
+		WithClientCertificate(empty, empty),
+	} {
+		c, err := newConfig([]Option{WithToken("test-token"), WithConsole(false), opt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.baseURL = "https://127.0.0.1"
+		if _, err := c.initialize(context.Background()); err == nil {
+			t.Error("no error for invalid certificates")
+		}
+	}
+}
+
+func TestAdditionalEndpoints(t *testing.T) {
+	type request struct{ authorization, apiKey string }
+	newServer := func(status int) (*httptest.Server, chan request) {
+		requests := make(chan request, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v1/traces" {
+				requests <- request{r.Header.Get("Authorization"), r.Header.Get("X-Api-Key")}
+			}
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(srv.Close)
+		return srv, requests
+	}
+	logfireSrv, logfireRequests := newServer(http.StatusOK)
+	collector, collectorRequests := newServer(http.StatusOK)
+	// A failing endpoint doesn't prevent the others from receiving the spans.
+	failing, _ := newServer(http.StatusBadRequest)
+
+	var mu sync.Mutex
+	var failed []string
+	c, err := newConfig([]Option{WithToken("test-token"), WithConsole(false), WithAdditionalEndpoints(
+		OTLPEndpoint{URL: failing.URL},
+		OTLPEndpoint{URL: collector.URL, Headers: map[string]string{"X-Api-Key": "collector-key"}},
+	), WithDiagnostics(DiagnosticsOptions{ErrorOutput: io.Discard, Callback: func(d Diagnostic) {
+		mu.Lock()
+		defer mu.Unlock()
+		if d.Kind == DiagnosticExportFailed {
+			failed = append(failed, d.Signal)
+		}
+	}})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = logfireSrv.URL
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := p.tracerProvider.Tracer("test").Start(context.Background(), "hello")
+	span.End()
+	if err := p.shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 {
+		t.Errorf("failed exports = %v, want the spans and metrics of the failing endpoint", failed)
+	}
+	if got := <-logfireRequests; got.authorization != "test-token" || got.apiKey != "" {
+		t.Errorf("Logfire request = %+v", got)
+	}
+	if got := <-collectorRequests; got.authorization != "" || got.apiKey != "collector-key" {
+		t.Errorf("collector request = %+v, want its headers only", got)
+	}
+}
+
+func TestAdditionalEndpointsWithoutLogfire(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traces := &traceService{spans: make(chan string, 1), authorization: make(chan string, 1)}
+	srv := grpc.NewServer()
+	collectortrace.RegisterTraceServiceServer(srv, traces)
+	collectormetrics.RegisterMetricsServiceServer(srv, metricsService{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	// No token is needed.
+	c, err := newConfig([]Option{WithSendToLogfire(false), WithConsole(false), WithAdditionalEndpoints(OTLPEndpoint{
+		URL:      "http://" + lis.Addr().String(),
+		Protocol: ProtocolGRPC,
+		Headers:  map[string]string{"authorization": "Bearer collector"},
+	})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := p.tracerProvider.Tracer("test").Start(context.Background(), "hello")
+	span.End()
+	if err := p.shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-traces.spans; got != "hello" {
+		t.Errorf("span = %s, want hello", got)
+	}
+	if got := <-traces.authorization; got != "Bearer collector" {
+		t.Errorf("authorization = %s", got)
+	}
+}
+
+func TestAdditionalEndpointsValidation(t *testing.T) {
+	for _, e := range []OTLPEndpoint{{}, {URL: "http://collector:4318", Protocol: "http/json"}} {
+		if _, err := newConfig([]Option{WithAdditionalEndpoints(e)}); err == nil {
+			t.Errorf("no error for endpoint %+v", e)
+		}
+	}
+}
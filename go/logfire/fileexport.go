@@ -0,0 +1,298 @@
+package logfire
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// FileFormat is the format of the files written by [NewFileSpanExporter].
+type FileFormat string
+
+// Possible values of [FileFormat], which are those of the file exporter of the OpenTelemetry Collector,
+// so that its `otlpjsonfile` receiver or an OTLP library can read them back.
+const (
+	// FileFormatProtobuf writes each batch as an OTLP `ExportTraceServiceRequest` protobuf message,
+	// preceded by its length as a 4 bytes big-endian integer.
+	FileFormatProtobuf FileFormat = "protobuf"
+	// FileFormatJSON writes each batch as an OTLP/JSON `ExportTraceServiceRequest` on its own line.
+	FileFormatJSON FileFormat = "json"
+)
+
+// DefaultFileMaxSize is the default [FileExportOptions.MaxSize].
+const DefaultFileMaxSize = 100 << 20
+
+// FileExportOptions configures a file exporter, see [NewFileSpanExporter].
+type FileExportOptions struct {
+	// Path is the file spans are appended to.
+	Path string
+	// Format defaults to FileFormatProtobuf.
+	Format FileFormat
+	// MaxSize is the size in bytes above which the file is rotated: it's renamed with its rotation time
+	// added to its name, e.g. `spans-20261014T081940.123456789Z.pb` for `spans.pb`, and a new one is started.
+	// Defaults to [DefaultFileMaxSize].
+	MaxSize int64
+	// MaxBackups is the number of rotated files kept, the oldest being deleted. Defaults to keeping them all.
+	MaxBackups int
+}
+
+// WithFileExport also writes spans to local files, e.g. to capture the telemetry of air-gapped jobs
+// for a later upload. With [WithSendToLogfire] set to false, no token is needed.
+func WithFileExport(opts FileExportOptions) Option {
+	return func(c *config) {
+		c.fileExports = append(c.fileExports, opts)
+	}
+}
+
+// NewFileSpanExporter returns an exporter writing spans to files in the OTLP format, with size-based rotation.
+func NewFileSpanExporter(opts FileExportOptions) (sdktrace.SpanExporter, error) {
+	if opts.Path == "" {
+		return nil, errors.New("logfire: file export without path")
+	}
+	if opts.Format == "" {
+		opts.Format = FileFormatProtobuf
+	}
+	if opts.Format != FileFormatProtobuf && opts.Format != FileFormatJSON {
+		return nil, fmt.Errorf("logfire: invalid file format %q, expected %q or %q", opts.Format, FileFormatProtobuf, FileFormatJSON)
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultFileMaxSize
+	}
+	w := &fileWriter{opts: opts}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	// The OTLP exporter encodes the spans, and its transport writes them to the file.
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL("http://localhost/v1/traces"),
+		otlptracehttp.WithHTTPClient(&http.Client{Transport: w}),
+		otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}),
+	)
+	if err != nil {
+		w.close()
+		return nil, err
+	}
+	return &fileSpanExporter{SpanExporter: exporter, w: w}, nil
+}
+
+type fileSpanExporter struct {
+	sdktrace.SpanExporter
+	w *fileWriter
+}
+
+func (e *fileSpanExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if closeErr := e.w.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+type fileWriter struct {
+	opts FileExportOptions
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func (w *fileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.opts.Path), 0o755); err != nil {
+		return fmt.Errorf("logfire: creating file export directory: %w", err)
+	}
+	f, err := os.OpenFile(w.opts.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("logfire: opening file export: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("logfire: opening file export: %w", err)
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+func (w *fileWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// RoundTrip writes the request of the exporter to the file, acknowledging it as if it had been sent.
+func (w *fileWriter) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.write(body); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/x-protobuf"}},
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+func (w *fileWriter) write(body []byte) error {
+	var record []byte
+	if w.opts.Format == FileFormatJSON {
+		line, err := otlpJSON(body)
+		if err != nil {
+			return fmt.Errorf("logfire: encoding file export: %w", err)
+		}
+		record = append(line, '\n')
+	} else {
+		record = binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(body)), uint32(len(body)))
+		record = append(record, body...)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return errors.New("logfire: file export is shut down")
+	}
+	if w.size > 0 && w.size+int64(len(record)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := w.file.Write(record)
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("logfire: writing file export: %w", err)
+	}
+	return nil
+}
+
+// rotate renames the file with the current time and opens a new one, deleting the oldest backups.
+func (w *fileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("logfire: rotating file export: %w", err)
+	}
+	w.file = nil
+	ext := filepath.Ext(w.opts.Path)
+	base := strings.TrimSuffix(w.opts.Path, ext)
+	backup := base + "-" + time.Now().UTC().Format(backupTimeLayout) + ext
+	if err := os.Rename(w.opts.Path, backup); err != nil {
+		return fmt.Errorf("logfire: rotating file export: %w", err)
+	}
+	if w.opts.MaxBackups > 0 {
+		backups := rotatedBackups(base, ext)
+		for len(backups) > w.opts.MaxBackups {
+			os.Remove(backups[0])
+			backups = backups[1:]
+		}
+	}
+	return w.open()
+}
+
+// backupTimeLayout is the layout of the time in the names of the files renamed by [fileWriter.rotate].
+const backupTimeLayout = "20060102T150405.000000000Z"
+
+// rotatedBackups returns the files renamed by [fileWriter.rotate], oldest first, skipping the files which
+// only share their prefix, e.g. spans-old.pb next to spans.pb.
+func rotatedBackups(base, ext string) []string {
+	entries, _ := os.ReadDir(filepath.Dir(base))
+	prefix := filepath.Base(base) + "-"
+	var backups []string
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeLayout, strings.TrimSuffix(stamp, ext)); err == nil {
+			backups = append(backups, filepath.Join(filepath.Dir(base), entry.Name()))
+		}
+	}
+	// The timestamps sort chronologically, like the entries returned by ReadDir.
+	return backups
+}
+
+// otlpJSON converts an OTLP protobuf message to OTLP/JSON, whose trace and span IDs are hex strings
+// instead of the base64 of the protobuf JSON mapping.
+func otlpJSON(body []byte) ([]byte, error) {
+	var req collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	b, err := protojson.Marshal(&req)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	mapIDs(v, base64.StdEncoding.DecodeString, hex.EncodeToString)
+	return json.Marshal(v)
+}
+
+// otlpProtobuf converts a line written by otlpJSON back to an OTLP protobuf message.
+func otlpProtobuf(line []byte) (*collectortrace.ExportTraceServiceRequest, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	mapIDs(v, hex.DecodeString, base64.StdEncoding.EncodeToString)
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var req collectortrace.ExportTraceServiceRequest
+	if err := protojson.Unmarshal(b, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// mapIDs re-encodes the trace and span IDs of a decoded JSON message.
+func mapIDs(v any, decode func(string) ([]byte, error), encode func([]byte) string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if s, ok := field.(string); ok && (k == "traceId" || k == "spanId" || k == "parentSpanId") {
+				if id, err := decode(s); err == nil {
+					v[k] = encode(id)
+				}
+				continue
+			}
+			mapIDs(field, decode, encode)
+		}
+	case []any:
+		for _, item := range v {
+			mapIDs(item, decode, encode)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package logfire
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFileExport(t *testing.T) {
+	dir := t.TempDir()
+	pbPath, jsonPath := filepath.Join(dir, "spans.pb"), filepath.Join(dir, "json", "spans.jsonl")
+	c, err := newConfig([]Option{WithSendToLogfire(false), WithConsole(false),
+		WithFileExport(FileExportOptions{Path: pbPath}),
+		WithFileExport(FileExportOptions{Path: jsonPath, Format: FileFormatJSON}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := p.tracerProvider.Tracer("test").Start(context.Background(), "hello")
+	span.End()
+	if err := p.shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(pbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := binary.BigEndian.Uint32(b); int(n) != len(b)-4 {
+		t.Fatalf("length = %d, want the %d bytes of the message", n, len(b)-4)
+	}
+	var req collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(b[4:], &req); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.ResourceSpans[0].ScopeSpans[0].Spans[0].Name; got != "hello" {
+		t.Errorf("protobuf span = %s, want hello", got)
+	}
+
+	b, err = os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					Name    string `json:"name"`
+					TraceID string `json:"traceId"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if !strings.HasSuffix(string(b), "}\n") || strings.Count(string(b), "\n") != 1 {
+		t.Errorf("JSON file = %q, want one line", b)
+	}
+	if err := json.Unmarshal(b, &line); err != nil {
+		t.Fatal(err)
+	}
+	got := line.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if got.Name != "hello" || got.TraceID != span.SpanContext().TraceID().String() {
+		t.Errorf("JSON span = %+v, want hello with a hex trace ID", got)
+	}
+}
+
+func TestFileExportRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "spans.pb")
+	unrelated := filepath.Join(dir, "spans-old.pb")
+	if err := os.WriteFile(unrelated, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	exporter, err := NewFileSpanExporter(FileExportOptions{Path: path, MaxSize: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := tracetest.SpanStubs{{Name: "hello"}}.Snapshots()
+	for range 4 {
+		if err := exporter.ExportSpans(context.Background(), spans); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.ExportSpans(context.Background(), spans); err == nil {
+		t.Error("no error after shutdown")
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "spans-2*.pb"))
+	if len(backups) != 2 {
+		t.Errorf("backups = %v, want the 2 most recent", backups)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("unrelated file: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("current file: %v", err)
+	}
+}
+
+func TestFileExportOptions(t *testing.T) {
+	for _, opts := range []FileExportOptions{{}, {Path: "spans.csv", Format: "csv"}} {
+		if _, err := NewFileSpanExporter(opts); err == nil {
+			t.Errorf("no error for %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package logfire
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// messageFormattedValueLengthLimit is the maximum number of characters for a value interpolated into a message.
+const messageFormattedValueLengthLimit = 128
+
+// formatMessage renders a Python-style message template, replacing `{name}` placeholders
+// with the value of the attribute with the same key.
+//
+// `{{` and `}}` render literal braces and `{name=}` renders as `name=value`.
+// Placeholders without a matching attribute are left as they are.
+// Format specs (`{name:spec}`) are accepted for compatibility with templates shared with
+// the Python SDK, but ignored.
+//
+// Sensitive values are redacted by s unless it's nil. Unlike attributes, placeholders are not
+// redacted because of their name, since the template explicitly asks for the value to be logged.
+func formatMessage(template string, attrs []attribute.KeyValue, s *scrubber) string {
+	if !strings.ContainsAny(template, "{}") {
+		return template
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		ch := template[i]
+		switch {
+		case ch == '{' && i+1 < len(template) && template[i+1] == '{':
+			b.WriteByte('{')
+			i++
+		case ch == '}' && i+1 < len(template) && template[i+1] == '}':
+			b.WriteByte('}')
+			i++
+		case ch == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				b.WriteString(template[i:])
+				return b.String()
+			}
+			field := template[i+1 : i+end]
+			b.WriteString(formatField(field, attrs, s))
+			i += end
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+func formatField(field string, attrs []attribute.KeyValue, s *scrubber) string {
+	name := field
+	if idx := strings.IndexByte(name, ':'); idx >= 0 {
+		name = name[:idx]
+	}
+	prefix := ""
+	if strings.HasSuffix(name, "=") {
+		name = strings.TrimSuffix(name, "=")
+		prefix = name + "="
+	}
+	value, ok := lookupAttribute(attrs, name)
+	if !ok {
+		return "{" + field + "}"
+	}
+	if s != nil && !safeScrubbingKeys[attribute.Key(name)] {
+		value, _ = s.scrubValue([]any{"message", name}, value)
+	}
+	return prefix + truncateString(formatValue(value), messageFormattedValueLengthLimit)
+}
+
+// lookupAttribute returns the value of the last attribute with the given key,
+// matching the last-value-wins semantics of span attributes.
+func lookupAttribute(attrs []attribute.KeyValue, key string) (attribute.Value, bool) {
+	for i := len(attrs) - 1; i >= 0; i-- {
+		if string(attrs[i].Key) == key {
+			return attrs[i].Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func formatValue(v attribute.Value) string {
+	if v.Type() == attribute.STRING {
+		return v.AsString()
+	}
+	return v.Emit()
+}
+
+// truncateString returns s if it has at most maxLength characters,
+// otherwise it keeps the start and end of s with "..." in the middle.
+func truncateString(s string, maxLength int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLength {
+		return s
+	}
+	const middle = "..."
+	half := (maxLength - len(middle)) / 2
+	return string(runes[:half]) + middle + string(runes[len(runes)-half:])
+}
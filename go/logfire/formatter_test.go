@@ -0,0 +1,81 @@
+package logfire
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func TestFormatMessage(t *testing.T) {
+	attrs := []attribute.KeyValue{
+		attribute.String("name", "world"),
+		attribute.Int("n", 3),
+		attribute.Float64("f", 1.5),
+		attribute.Bool("ok", true),
+		attribute.StringSlice("tags", []string{"a", "b"}),
+	}
+	for _, tc := range []struct {
+		template string
+		want     string
+	}{
+		{"hello", "hello"},
+		{"hello {name}", "hello world"},
+		{"{n} items at {f} ok={ok}", "3 items at 1.5 ok=true"},
+		{"{name=}", "name=world"},
+		{"{f:.2f}", "1.5"},
+		{"{tags}", `["a","b"]`},
+		{"{{name}} {name}", "{name} world"},
+		{"missing {other}", "missing {other}"},
+		{"unclosed {name", "unclosed {name"},
+	} {
+		if got := formatMessage(tc.template, attrs, nil); got != tc.want {
+			t.Errorf("formatMessage(%q) = %q, want %q", tc.template, got, tc.want)
+		}
+	}
+}
+
+func TestFormatMessageLastValueWins(t *testing.T) {
+	got := formatMessage("{a}", []attribute.KeyValue{attribute.String("a", "1"), attribute.String("a", "2")}, nil)
+	if got != "2" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFormatMessageTruncatesValues(t *testing.T) {
+	got := formatMessage("{long}", []attribute.KeyValue{attribute.String("long", strings.Repeat("a", 100)+strings.Repeat("b", 100))}, nil)
+	want := strings.Repeat("a", 62) + "..." + strings.Repeat("b", 62)
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLogMessageTemplate(t *testing.T) {
+	recorder := configureForTest(t)
+
+	Info(context.Background(), "user {user_id} placed order {order_id}",
+		attribute.Int("user_id", 1),
+		attribute.String("order_id", "abc"),
+	)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	attrs := oteltest.Attributes(spans[0].Attributes())
+	if spans[0].Name() != "user {user_id} placed order {order_id}" {
+		t.Errorf("unexpected span name %q", spans[0].Name())
+	}
+	if attrs[MsgTemplateKey] != "user {user_id} placed order {order_id}" {
+		t.Errorf("unexpected template %v", attrs[MsgTemplateKey])
+	}
+	if attrs[MsgKey] != "user 1 placed order abc" {
+		t.Errorf("unexpected message %v", attrs[MsgKey])
+	}
+	if attrs["user_id"] != int64(1) || attrs["order_id"] != "abc" {
+		t.Errorf("expected interpolated values to be recorded as attributes, got %v", attrs)
+	}
+}
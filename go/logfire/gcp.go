@@ -0,0 +1,81 @@
+package logfire
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// gcpMetadataURL is the URL of the metadata server of GCP.
+const gcpMetadataURL = "http://metadata.google.internal/computeMetadata/v1"
+
+// gcpDetector detects Cloud Run services and Cloud Functions from their environment variables,
+// and Compute Engine instances, including the nodes of GKE clusters, from their DMI information.
+// Their attributes are read from the metadata server.
+type gcpDetector struct {
+	metadataURL string
+	dmiDir      string
+}
+
+func (d gcpDetector) detect(ctx context.Context) []attribute.KeyValue {
+	service := os.Getenv("K_SERVICE")
+	if service == "" && !strings.HasPrefix(readDMI(d.dmiDir, "product_name"), "Google Compute Engine") {
+		return nil
+	}
+	project := d.metadata(ctx, "project/project-id")
+	if project == "" {
+		// Not on GCP, or the metadata server isn't reachable.
+		return nil
+	}
+	attrs := []attribute.KeyValue{semconv.CloudProviderGCP, semconv.CloudAccountID(project)}
+
+	if service != "" {
+		// Cloud Functions of the 2nd generation are Cloud Run services, which also have their entry point.
+		platform := semconv.CloudPlatformGCPCloudRun
+		if os.Getenv("FUNCTION_TARGET") != "" {
+			platform = semconv.CloudPlatformGCPCloudFunctions
+		}
+		// The region is projects/<number>/regions/<region>.
+		region := d.metadata(ctx, "instance/region")
+		return appendNonEmpty(append(attrs, platform, semconv.FaaSName(service)),
+			semconv.FaaSVersion(os.Getenv("K_REVISION")),
+			semconv.FaaSInstance(d.metadata(ctx, "instance/id")),
+			semconv.CloudRegion(region[strings.LastIndex(region, "/")+1:]),
+		)
+	}
+
+	// The zone is projects/<number>/zones/<zone>, and the region the zone without its last part.
+	zone := d.metadata(ctx, "instance/zone")
+	zone = zone[strings.LastIndex(zone, "/")+1:]
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i >= 0 {
+		region = zone[:i]
+	}
+	machineType := d.metadata(ctx, "instance/machine-type")
+	attrs = appendNonEmpty(attrs,
+		semconv.CloudAvailabilityZone(zone),
+		semconv.CloudRegion(region),
+		semconv.HostID(d.metadata(ctx, "instance/id")),
+		semconv.HostName(d.metadata(ctx, "instance/name")),
+		semconv.HostType(machineType[strings.LastIndex(machineType, "/")+1:]),
+	)
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return append(attrs, semconv.CloudPlatformGCPComputeEngine)
+	}
+	return appendNonEmpty(append(attrs, semconv.CloudPlatformGCPKubernetesEngine),
+		semconv.K8SClusterName(d.metadata(ctx, "instance/attributes/cluster-name")),
+	)
+}
+
+// metadata returns the value at path in the metadata server, or "" if it can't be read.
+func (d gcpDetector) metadata(ctx context.Context, path string) string {
+	b, err := getMetadata(ctx, http.MethodGet, d.metadataURL+"/"+path, http.Header{"Metadata-Flavor": {"Google"}})
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
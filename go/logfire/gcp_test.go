@@ -0,0 +1,98 @@
+package logfire
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func gcpMetadataServer(t *testing.T) *httptest.Server {
+	values := map[string]string{
+		"/project/project-id":               "my-project",
+		"/instance/id":                      "4520031799277581759",
+		"/instance/name":                    "web-1",
+		"/instance/zone":                    "projects/123/zones/europe-west1-b",
+		"/instance/region":                  "projects/123/regions/europe-west1",
+		"/instance/machine-type":            "projects/123/machineTypes/e2-medium",
+		"/instance/attributes/cluster-name": "prod",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value, ok := values[r.URL.Path]
+		if !ok || r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(value))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGCPDetectorComputeEngine(t *testing.T) {
+	for _, name := range []string{"K_SERVICE", "FUNCTION_TARGET", "KUBERNETES_SERVICE_HOST"} {
+		t.Setenv(name, "")
+	}
+	d := gcpDetector{metadataURL: gcpMetadataServer(t).URL, dmiDir: t.TempDir()}
+	if attrs := d.detect(context.Background()); attrs != nil {
+		t.Errorf("attributes of another machine = %v", attrs)
+	}
+	if err := os.WriteFile(filepath.Join(d.dmiDir, "product_name"), []byte("Google Compute Engine\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	checkDetected(t, d.detect(context.Background()), map[string]string{
+		"cloud.provider":          "gcp",
+		"cloud.platform":          "gcp_compute_engine",
+		"cloud.account.id":        "my-project",
+		"cloud.availability_zone": "europe-west1-b",
+		"cloud.region":            "europe-west1",
+		"host.id":                 "4520031799277581759",
+		"host.name":               "web-1",
+		"host.type":               "e2-medium",
+	})
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	checkDetected(t, d.detect(context.Background()), map[string]string{
+		"cloud.platform":   "gcp_kubernetes_engine",
+		"k8s.cluster.name": "prod",
+	})
+}
+
+func TestGCPDetectorServerless(t *testing.T) {
+	t.Setenv("K_SERVICE", "checkout")
+	t.Setenv("K_REVISION", "checkout-00042-abc")
+	t.Setenv("FUNCTION_TARGET", "")
+	d := gcpDetector{metadataURL: gcpMetadataServer(t).URL, dmiDir: t.TempDir()}
+	checkDetected(t, d.detect(context.Background()), map[string]string{
+		"cloud.platform":   "gcp_cloud_run",
+		"cloud.account.id": "my-project",
+		"cloud.region":     "europe-west1",
+		"faas.name":        "checkout",
+		"faas.version":     "checkout-00042-abc",
+		"faas.instance":    "4520031799277581759",
+	})
+
+	t.Setenv("FUNCTION_TARGET", "HandleEvent")
+	checkDetected(t, d.detect(context.Background()), map[string]string{"cloud.platform": "gcp_cloud_functions"})
+}
+
+func TestWithResourceDetection(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("K8S_POD_NAME", "web-0")
+	for _, enabled := range []bool{true, false} {
+		c, err := newConfig([]Option{WithResourceDetection(enabled)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.resource(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if detected := strings.Contains(res.String(), "k8s.pod.name=web-0"); detected != enabled {
+			t.Errorf("WithResourceDetection(%v): resource %s", enabled, res)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package logfire
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// kubernetesServiceAccountDir is where Kubernetes mounts the service account of pods, with their namespace.
+const kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubernetesDetector detects the pod the program runs in. Kubernetes only exposes the pod name,
+// as the hostname, and namespace by default, so the other attributes come from environment variables
+// set with the downward API:
+//
+//	env:
+//	  - name: K8S_NODE_NAME
+//	    valueFrom:
+//	      fieldRef:
+//	        fieldPath: spec.nodeName
+//
+// K8S_POD_NAME, K8S_NAMESPACE_NAME, K8S_NODE_NAME, K8S_DEPLOYMENT_NAME and K8S_CONTAINER_NAME
+// set the attribute of the same name.
+type kubernetesDetector struct {
+	serviceAccountDir string
+}
+
+func (d kubernetesDetector) detect(context.Context) []attribute.KeyValue {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return nil
+	}
+	var attrs []attribute.KeyValue
+	pod := os.Getenv("K8S_POD_NAME")
+	if pod == "" {
+		pod, _ = os.Hostname()
+	}
+	if pod != "" {
+		attrs = append(attrs, semconv.K8SPodName(pod))
+	}
+	namespace := os.Getenv("K8S_NAMESPACE_NAME")
+	if namespace == "" {
+		b, _ := os.ReadFile(filepath.Join(d.serviceAccountDir, "namespace"))
+		namespace = strings.TrimSpace(string(b))
+	}
+	if namespace != "" {
+		attrs = append(attrs, semconv.K8SNamespaceName(namespace))
+	}
+	if node := os.Getenv("K8S_NODE_NAME"); node != "" {
+		attrs = append(attrs, semconv.K8SNodeName(node))
+	}
+	deployment := os.Getenv("K8S_DEPLOYMENT_NAME")
+	if deployment == "" {
+		deployment = deploymentOfPod(pod)
+	}
+	if deployment != "" {
+		attrs = append(attrs, semconv.K8SDeploymentName(deployment))
+	}
+	if container := os.Getenv("K8S_CONTAINER_NAME"); container != "" {
+		attrs = append(attrs, semconv.K8SContainerName(container))
+	}
+	return attrs
+}
+
+// deploymentOfPod returns the deployment of a pod from its name, `<deployment>-<replica set hash>-<pod suffix>`,
+// or "" if the name doesn't have this form.
+func deploymentOfPod(pod string) string {
+	rest, suffix, ok := cutLast(pod, "-")
+	if !ok || len(suffix) != 5 || !isPodHash(suffix) {
+		return ""
+	}
+	deployment, hash, ok := cutLast(rest, "-")
+	if !ok || deployment == "" || len(hash) > 10 || !isPodHash(hash) {
+		return ""
+	}
+	return deployment
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// isPodHash reports whether s only has the characters of the names Kubernetes generates, without vowels
+// so that they don't spell words.
+func isPodHash(s string) bool {
+	return s != "" && strings.Trim(s, "bcdfghjklmnpqrstvwxz2456789") == ""
+}
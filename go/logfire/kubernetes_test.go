@@ -0,0 +1,75 @@
+package logfire
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+func TestKubernetesDetector(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "namespace"), []byte("payments\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	d := kubernetesDetector{serviceAccountDir: dir}
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	if attrs := d.detect(context.Background()); attrs != nil {
+		t.Errorf("attributes outside Kubernetes = %v", attrs)
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("K8S_POD_NAME", "checkout-7d4b9c8f6-x2k9p")
+	t.Setenv("K8S_NODE_NAME", "node-1")
+	t.Setenv("K8S_CONTAINER_NAME", "app")
+	got := d.detect(context.Background())
+	want := []string{
+		"k8s.pod.name=checkout-7d4b9c8f6-x2k9p",
+		"k8s.namespace.name=payments",
+		"k8s.node.name=node-1",
+		"k8s.deployment.name=checkout",
+		"k8s.container.name=app",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("attributes = %v, want %v", got, want)
+	}
+	for i, attr := range got {
+		if s := string(attr.Key) + "=" + attr.Value.Emit(); s != want[i] {
+			t.Errorf("attribute %d = %s, want %s", i, s, want[i])
+		}
+	}
+
+	// The options take precedence over the detected attributes.
+	c, err := newConfig([]Option{WithResourceAttributes(semconv.K8SContainerName("from-option"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.resource(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container, _ := res.Set().Value(semconv.K8SContainerNameKey); container.AsString() != "from-option" {
+		t.Errorf("container = %s, want from-option", container.Emit())
+	}
+	if pod, _ := res.Set().Value(semconv.K8SPodNameKey); pod.AsString() != "checkout-7d4b9c8f6-x2k9p" {
+		t.Errorf("pod = %s", pod.Emit())
+	}
+}
+
+func TestDeploymentOfPod(t *testing.T) {
+	for pod, want := range map[string]string{
+		"checkout-7d4b9c8f6-x2k9p":     "checkout",
+		"api-gateway-5f6d7b8c9d-abcde": "",
+		"api-gateway-5f6d7b8c9d-bcdfg": "api-gateway",
+		"web-0":                        "",
+		"fluent-bit-x2k9p":             "",
+		"x2k9p":                        "",
+		"-7d4b9c8f6-x2k9p":             "",
+	} {
+		if got := deploymentOfPod(pod); got != want {
+			t.Errorf("deploymentOfPod(%q) = %q, want %q", pod, got, want)
+		}
+	}
+}
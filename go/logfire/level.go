@@ -0,0 +1,56 @@
+package logfire
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Level is the severity of a log or span, stored in the `logfire.level_num` attribute.
+//
+// The numeric values match the Python SDK and OpenTelemetry severity numbers.
+type Level int
+
+// Logfire levels, from least to most severe.
+const (
+	LevelTrace  Level = 1
+	LevelDebug  Level = 5
+	LevelInfo   Level = 9
+	LevelNotice Level = 10
+	LevelWarn   Level = 13
+	LevelError  Level = 17
+	LevelFatal  Level = 21
+)
+
+var levelNames = map[Level]string{
+	LevelTrace:  "trace",
+	LevelDebug:  "debug",
+	LevelInfo:   "info",
+	LevelNotice: "notice",
+	LevelWarn:   "warn",
+	LevelError:  "error",
+	LevelFatal:  "fatal",
+}
+
+// String returns the name of the level, or its number if it isn't one of the predefined levels.
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return strconv.Itoa(int(l))
+}
+
+// ParseLevel returns the level with the given name, case-insensitively.
+// "warning" is accepted as an alias of "warn", as in the standard library logging of Python.
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "warning" {
+		return LevelWarn, nil
+	}
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("logfire: invalid level %q", name)
+}
@@ -0,0 +1,63 @@
+package logfire
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]Level{
+		"trace":   LevelTrace,
+		"debug":   LevelDebug,
+		"info":    LevelInfo,
+		"notice":  LevelNotice,
+		"warn":    LevelWarn,
+		"warning": LevelWarn,
+		"ERROR":   LevelError,
+		" fatal":  LevelFatal,
+	} {
+		got, err := ParseLevel(name)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestLevelString(t *testing.T) {
+	if LevelWarn.String() != "warn" {
+		t.Errorf("got %q", LevelWarn.String())
+	}
+	if Level(3).String() != "3" {
+		t.Errorf("got %q", Level(3).String())
+	}
+}
+
+func TestLevelFunctions(t *testing.T) {
+	recorder := configureForTest(t)
+
+	ctx := context.Background()
+	Trace(ctx, "trace")
+	Debug(ctx, "debug")
+	Info(ctx, "info")
+	Notice(ctx, "notice")
+	Warn(ctx, "warn")
+	Error(ctx, "error")
+	Fatal(ctx, "fatal")
+	Log(ctx, Level(11), "custom")
+
+	want := []int64{1, 5, 9, 10, 13, 17, 21, 11}
+	spans := recorder.Ended()
+	if len(spans) != len(want) {
+		t.Fatalf("expected %d spans, got %d", len(want), len(spans))
+	}
+	for i, span := range spans {
+		if got := oteltest.Attributes(span.Attributes())[LevelNumKey]; got != want[i] {
+			t.Errorf("%s: level_num = %v, want %d", span.Name(), got, want[i])
+		}
+	}
+}
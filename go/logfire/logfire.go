@@ -0,0 +1,110 @@
+package logfire
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "logfire"
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName, trace.WithInstrumentationVersion(Version))
+}
+
+// Span starts a span named after msgTemplate, with the Logfire message attributes already set.
+//
+// Placeholders in msgTemplate such as `{order_id}` are replaced by the value of the attribute
+// with the same key to render the message, while the template itself is kept as the span name
+// so that the Logfire UI can group similar spans.
+//
+// The span must be ended by the caller:
+//
+//	ctx, span := logfire.Span(ctx, "processing order {order_id}", attribute.Int("order_id", id))
+//	defer span.End()
+func Span(ctx context.Context, msgTemplate string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	spanAttrs := make([]attribute.KeyValue, 0, len(attrs)+6)
+	spanAttrs = append(spanAttrs,
+		SpanTypeKey.String(spanTypeSpan),
+		MsgTemplateKey.String(msgTemplate),
+		MsgKey.String(formatMessage(msgTemplate, attrs, global.scrubber.Load())),
+	)
+	spanAttrs = codeLocationAttributes(spanAttrs, 0)
+	spanAttrs = append(spanAttrs, attrs...)
+	return tracer().Start(ctx, msgTemplate, trace.WithAttributes(spanAttrs...))
+}
+
+// Log emits a log with the given level.
+//
+// Like [Span], placeholders in msgTemplate are filled in from attrs:
+//
+//	logfire.Log(ctx, logfire.LevelInfo, "user {user_id} placed order {order_id}",
+//		attribute.Int("user_id", userID),
+//		attribute.Int("order_id", orderID),
+//	)
+func Log(ctx context.Context, level Level, msgTemplate string, attrs ...attribute.KeyValue) {
+	log(ctx, level, msgTemplate, attrs)
+}
+
+// Trace emits a log with the trace level.
+func Trace(ctx context.Context, msgTemplate string, attrs ...attribute.KeyValue) {
+	log(ctx, LevelTrace, msgTemplate, attrs)
+}
+
+// Debug emits a log with the debug level.
+func Debug(ctx context.Context, msgTemplate string, attrs ...attribute.KeyValue) {
+	log(ctx, LevelDebug, msgTemplate, attrs)
+}
+
+// Info emits a log with the info level.
+func Info(ctx context.Context, msgTemplate string, attrs ...attribute.KeyValue) {
+	log(ctx, LevelInfo, msgTemplate, attrs)
+}
+
+// Notice emits a log with the notice level.
+func Notice(ctx context.Context, msgTemplate string, attrs ...attribute.KeyValue) {
+	log(ctx, LevelNotice, msgTemplate, attrs)
+}
+
+// Warn emits a log with the warn level.
+func Warn(ctx context.Context, msgTemplate string, attrs ...attribute.KeyValue) {
+	log(ctx, LevelWarn, msgTemplate, attrs)
+}
+
+// Error emits a log with the error level. The span representing the log has an error status.
+func Error(ctx context.Context, msgTemplate string, attrs ...attribute.KeyValue) {
+	log(ctx, LevelError, msgTemplate, attrs)
+}
+
+// Fatal emits a log with the fatal level. Unlike the standard library log package, it doesn't exit the program.
+func Fatal(ctx context.Context, msgTemplate string, attrs ...attribute.KeyValue) {
+	log(ctx, LevelFatal, msgTemplate, attrs)
+}
+
+// log emits a zero-duration span, which is how Logfire represents logs.
+func log(ctx context.Context, level Level, msgTemplate string, attrs []attribute.KeyValue) {
+	logAttrs := make([]attribute.KeyValue, 0, len(attrs)+7)
+	logAttrs = append(logAttrs,
+		SpanTypeKey.String(spanTypeLog),
+		LevelNumKey.Int(int(level)),
+		MsgTemplateKey.String(msgTemplate),
+		MsgKey.String(formatMessage(msgTemplate, attrs, global.scrubber.Load())),
+	)
+	// Skip the function calling log.
+	logAttrs = codeLocationAttributes(logAttrs, 1)
+	logAttrs = append(logAttrs, attrs...)
+
+	now := time.Now()
+	_, span := tracer().Start(ctx, msgTemplate,
+		trace.WithAttributes(logAttrs...),
+		trace.WithTimestamp(now),
+	)
+	if level >= LevelError {
+		span.SetStatus(codes.Error, "")
+	}
+	span.End(trace.WithTimestamp(now))
+}
@@ -0,0 +1,96 @@
+package logfire
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+// configureForTest configures Logfire without exporting and returns a recorder of all ended spans.
+func configureForTest(t *testing.T, opts ...Option) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	opts = append([]Option{WithSendToLogfire(false), WithConsole(false), WithAdditionalSpanProcessors(recorder)}, opts...)
+	shutdown, err := Configure(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = shutdown(context.Background()) })
+	return recorder
+}
+
+func TestSpan(t *testing.T) {
+	recorder := configureForTest(t)
+
+	ctx, span := Span(context.Background(), "outer", attribute.String("a", "b"))
+	Info(ctx, "inside")
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	log, outer := spans[0], spans[1]
+	if outer.Name() != "outer" {
+		t.Errorf("unexpected span name %q", outer.Name())
+	}
+	attrs := oteltest.Attributes(outer.Attributes())
+	for key, want := range map[attribute.Key]any{
+		SpanTypeKey:    "span",
+		MsgTemplateKey: "outer",
+		MsgKey:         "outer",
+		"a":            "b",
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+	if log.Parent().SpanID() != outer.SpanContext().SpanID() {
+		t.Error("expected the log to be a child of the span")
+	}
+}
+
+func TestLogs(t *testing.T) {
+	recorder := configureForTest(t)
+
+	Info(context.Background(), "info message", attribute.Int("n", 1))
+	Warn(context.Background(), "warn message")
+	Error(context.Background(), "error message")
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	for i, want := range []struct {
+		msg    string
+		level  int64
+		status codes.Code
+	}{
+		{"info message", 9, codes.Unset},
+		{"warn message", 13, codes.Unset},
+		{"error message", 17, codes.Error},
+	} {
+		span := spans[i]
+		attrs := oteltest.Attributes(span.Attributes())
+		if attrs[SpanTypeKey] != "log" || attrs[MsgKey] != want.msg || attrs[MsgTemplateKey] != want.msg {
+			t.Errorf("unexpected attributes %v", attrs)
+		}
+		if attrs[LevelNumKey] != want.level {
+			t.Errorf("level = %v, want %d", attrs[LevelNumKey], want.level)
+		}
+		if span.Status().Code != want.status {
+			t.Errorf("status = %v, want %v", span.Status().Code, want.status)
+		}
+		if !span.StartTime().Equal(span.EndTime()) {
+			t.Error("expected a zero-duration span")
+		}
+	}
+	if oteltest.Attributes(spans[0].Attributes())["n"] != int64(1) {
+		t.Error("expected user attributes to be recorded")
+	}
+}
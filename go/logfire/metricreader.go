@@ -0,0 +1,48 @@
+package logfire
+
+import (
+	"errors"
+	"time"
+)
+
+// defaultMetricExportInterval is the interval between metric exports of the OpenTelemetry SDKs.
+const defaultMetricExportInterval = time.Minute
+
+// MetricReaderOptions configures how often metrics are collected and exported to Logfire,
+// see [WithMetricReaderOptions].
+//
+// Whatever the interval, the shutdown function returned by [Configure] collects and exports the metrics
+// recorded since the last export, so short-lived jobs don't lose their last window of metrics, and
+// [ForceFlush] exports them immediately.
+type MetricReaderOptions struct {
+	// Interval is the time between exports. Defaults to 60s, or the OTEL_METRIC_EXPORT_INTERVAL
+	// environment variable in milliseconds.
+	Interval time.Duration
+	// Timeout is the maximum duration of an export, retries included. Defaults to
+	// [RetryOptions.MaxElapsedTime], or OTEL_METRIC_EXPORT_TIMEOUT in milliseconds.
+	Timeout time.Duration
+}
+
+// WithMetricReaderOptions tunes the periodic export of metrics. Unset fields keep their defaults.
+func WithMetricReaderOptions(opts MetricReaderOptions) Option {
+	return func(c *config) {
+		c.metricReaderOptions = opts
+	}
+}
+
+// loadMetricReaderParams fills in the metric reader options that weren't set with the OTEL_METRIC_EXPORT_*
+// environment variables.
+func (c *config) loadMetricReaderParams(params *paramManager) error {
+	opts := &c.metricReaderOptions
+	var err error
+	if opts.Interval, err = params.millis("metric_export_interval", opts.Interval, defaultMetricExportInterval); err != nil {
+		return err
+	}
+	if opts.Timeout, err = params.millis("metric_export_timeout", opts.Timeout, c.exportTimeout()); err != nil {
+		return err
+	}
+	if opts.Interval <= 0 || opts.Timeout <= 0 {
+		return errors.New("logfire: the metric export interval and timeout must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package logfire
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMetricReaderParams(t *testing.T) {
+	c, err := newConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MetricReaderOptions{Interval: time.Minute, Timeout: time.Minute}
+	if c.metricReaderOptions != want {
+		t.Errorf("default metric reader options = %+v, want %+v", c.metricReaderOptions, want)
+	}
+
+	t.Setenv("OTEL_METRIC_EXPORT_INTERVAL", "5000")
+	t.Setenv("OTEL_METRIC_EXPORT_TIMEOUT", "2000")
+	c, err = newConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = MetricReaderOptions{Interval: 5 * time.Second, Timeout: 2 * time.Second}
+	if c.metricReaderOptions != want {
+		t.Errorf("metric reader options = %+v, want %+v", c.metricReaderOptions, want)
+	}
+	// Options take precedence over the environment.
+	c, err = newConfig([]Option{WithMetricReaderOptions(MetricReaderOptions{Interval: time.Second})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = MetricReaderOptions{Interval: time.Second, Timeout: 2 * time.Second}
+	if c.metricReaderOptions != want {
+		t.Errorf("metric reader options = %+v, want %+v", c.metricReaderOptions, want)
+	}
+
+	for _, value := range []string{"often", "-1"} {
+		t.Setenv("OTEL_METRIC_EXPORT_INTERVAL", value)
+		if _, err := newConfig(nil); err == nil {
+			t.Errorf("no error for the interval %q", value)
+		}
+	}
+}
+
+func TestMetricExportInterval(t *testing.T) {
+	exports := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" {
+			return
+		}
+		select {
+		case exports <- struct{}{}:
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	c, err := newConfig([]Option{WithToken("test-token"), WithConsole(false),
+		WithMetricReaderOptions(MetricReaderOptions{Interval: 10 * time.Millisecond})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = srv.URL
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.shutdown(context.Background())
+
+	// The runtime metrics are exported without any flush.
+	select {
+	case <-exports:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no metrics exported after the interval")
+	}
+}
@@ -0,0 +1,172 @@
+package logfire
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func meter() metric.Meter {
+	return otel.Meter(instrumentationName, metric.WithInstrumentationVersion(Version))
+}
+
+// MetricOptions configures the instruments created by [MetricCounter], [MetricHistogram], [MetricGauge],
+// [MetricUpDownCounter] and [MetricGaugeCallback].
+type MetricOptions struct {
+	// Unit is the unit of the values in UCUM, e.g. `ms`, `By` or `{request}`.
+	Unit string
+	// Description describes the metric in the Logfire UI.
+	Description string
+	// Buckets are the boundaries of the explicit buckets of a histogram. Without them, the histogram is
+	// exported to Logfire with base 2 exponential buckets. A view of [WithMetricViews] setting the aggregation
+	// of the histogram takes precedence.
+	Buckets []float64
+}
+
+// instrumentOptions returns the unit and description options, as the options O of an instrument.
+func instrumentOptions[O any](opts MetricOptions) []O {
+	var options []O
+	if opts.Unit != "" {
+		options = append(options, any(metric.WithUnit(opts.Unit)).(O))
+	}
+	if opts.Description != "" {
+		options = append(options, any(metric.WithDescription(opts.Description)).(O))
+	}
+	return options
+}
+
+// Counter is a metric whose value only increases, e.g. the number of requests, created by [MetricCounter].
+type Counter struct {
+	inst metric.Int64Counter
+}
+
+// MetricCounter returns a counter exported to Logfire, like `logfire.metric_counter` in the Python SDK:
+//
+//	var orders = logfire.MetricCounter("orders", logfire.MetricOptions{Unit: "{order}"})
+//
+//	orders.Add(ctx, 1, attribute.String("country", country))
+//
+// Like the other metric helpers, it can be called before [Configure], e.g. to initialize a package variable,
+// the values being exported once Configure is called. An invalid name is reported to the OpenTelemetry
+// error handler, and the metric records nothing.
+func MetricCounter(name string, opts MetricOptions) Counter {
+	inst, err := meter().Int64Counter(name, instrumentOptions[metric.Int64CounterOption](opts)...)
+	handleMetricError(err)
+	return Counter{inst: inst}
+}
+
+// Add increments the counter by incr, which must not be negative.
+func (c Counter) Add(ctx context.Context, incr int64, attrs ...attribute.KeyValue) {
+	c.inst.Add(ctx, incr, metric.WithAttributes(attrs...))
+}
+
+// UpDownCounter is a metric which can be incremented and decremented, e.g. the number of active requests,
+// created by [MetricUpDownCounter].
+type UpDownCounter struct {
+	inst metric.Int64UpDownCounter
+}
+
+// MetricUpDownCounter returns an up-down counter exported to Logfire, like `logfire.metric_up_down_counter`
+// in the Python SDK, see [MetricCounter].
+func MetricUpDownCounter(name string, opts MetricOptions) UpDownCounter {
+	inst, err := meter().Int64UpDownCounter(name, instrumentOptions[metric.Int64UpDownCounterOption](opts)...)
+	handleMetricError(err)
+	return UpDownCounter{inst: inst}
+}
+
+// Add adds incr to the counter, which decreases it if incr is negative.
+func (c UpDownCounter) Add(ctx context.Context, incr int64, attrs ...attribute.KeyValue) {
+	c.inst.Add(ctx, incr, metric.WithAttributes(attrs...))
+}
+
+// Histogram is a metric of the distribution of values, e.g. the duration of requests, created by [MetricHistogram].
+type Histogram struct {
+	inst metric.Float64Histogram
+}
+
+// MetricHistogram returns a histogram exported to Logfire, like `logfire.metric_histogram` in the Python SDK,
+// see [MetricCounter].
+func MetricHistogram(name string, opts MetricOptions) Histogram {
+	hopts := instrumentOptions[metric.Float64HistogramOption](opts)
+	if opts.Buckets != nil {
+		hopts = append(hopts, metric.WithExplicitBucketBoundaries(opts.Buckets...))
+	}
+	inst, err := meter().Float64Histogram(name, hopts...)
+	handleMetricError(err)
+	return Histogram{inst: inst}
+}
+
+// Record records a value in the histogram.
+func (h Histogram) Record(ctx context.Context, value float64, attrs ...attribute.KeyValue) {
+	h.inst.Record(ctx, value, metric.WithAttributes(attrs...))
+}
+
+// Gauge is a metric whose value is set, e.g. the temperature, created by [MetricGauge].
+type Gauge struct {
+	inst metric.Float64Gauge
+}
+
+// MetricGauge returns a gauge exported to Logfire, like `logfire.metric_gauge` in the Python SDK,
+// see [MetricCounter]. Only the last value set for each set of attributes is exported.
+func MetricGauge(name string, opts MetricOptions) Gauge {
+	inst, err := meter().Float64Gauge(name, instrumentOptions[metric.Float64GaugeOption](opts)...)
+	handleMetricError(err)
+	return Gauge{inst: inst}
+}
+
+// Set sets the value of the gauge.
+func (g Gauge) Set(ctx context.Context, value float64, attrs ...attribute.KeyValue) {
+	g.inst.Record(ctx, value, metric.WithAttributes(attrs...))
+}
+
+// GaugeCallback is a gauge whose value is observed by a callback at each collection,
+// registered by [MetricGaugeCallback].
+type GaugeCallback struct {
+	registration metric.Registration
+}
+
+// MetricGaugeCallback registers a gauge exported to Logfire whose value and attributes are returned by callback
+// when metrics are collected, like `logfire.metric_gauge_callback` in the Python SDK:
+//
+//	logfire.MetricGaugeCallback("queue.size", func(context.Context) (float64, []attribute.KeyValue) {
+//		return float64(queue.Len()), []attribute.KeyValue{attribute.String("queue", "emails")}
+//	}, logfire.MetricOptions{Unit: "{message}"})
+//
+// The options are optional, unlike those of the other metric helpers, and only the first one is used.
+// callback is called concurrently with the rest of the program, so it must be safe to do so. It's called
+// until [GaugeCallback.Unregister], so that a gauge registered by a short-lived component should be
+// unregistered when the component is done. Like the other metric helpers, it can be called before [Configure].
+func MetricGaugeCallback(name string, callback func(context.Context) (float64, []attribute.KeyValue), opts ...MetricOptions) GaugeCallback {
+	var options MetricOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	m := meter()
+	inst, err := m.Float64ObservableGauge(name, instrumentOptions[metric.Float64ObservableGaugeOption](options)...)
+	if err != nil {
+		handleMetricError(err)
+		return GaugeCallback{}
+	}
+	registration, err := m.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		value, attrs := callback(ctx)
+		o.ObserveFloat64(inst, value, metric.WithAttributes(attrs...))
+		return nil
+	}, inst)
+	handleMetricError(err)
+	return GaugeCallback{registration: registration}
+}
+
+// Unregister stops calling the callback of the gauge, which isn't exported anymore.
+func (g GaugeCallback) Unregister() {
+	if g.registration != nil {
+		handleMetricError(g.registration.Unregister())
+	}
+}
+
+func handleMetricError(err error) {
+	if err != nil {
+		otel.Handle(err)
+	}
+}
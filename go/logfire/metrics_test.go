@@ -0,0 +1,80 @@
+package logfire
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricHelpers(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer otel.SetMeterProvider(otel.GetMeterProvider())
+	otel.SetMeterProvider(provider)
+
+	ctx := context.Background()
+	country := attribute.String("country", "fr")
+	MetricCounter("orders", MetricOptions{Unit: "{order}", Description: "Orders placed"}).Add(ctx, 2, country)
+	requests := MetricUpDownCounter("active_requests", MetricOptions{})
+	requests.Add(ctx, 3)
+	requests.Add(ctx, -1)
+	MetricHistogram("latency", MetricOptions{Unit: "ms", Buckets: []float64{10, 100}}).Record(ctx, 42)
+	temperature := MetricGauge("temperature", MetricOptions{})
+	temperature.Set(ctx, 20)
+	temperature.Set(ctx, 21.5)
+	queue := MetricGaugeCallback("queue.size", func(context.Context) (float64, []attribute.KeyValue) {
+		return 7, []attribute.KeyValue{attribute.String("queue", "emails")}
+	}, MetricOptions{Unit: "{message}"})
+	unregistered := MetricGaugeCallback("unregistered", func(context.Context) (float64, []attribute.KeyValue) {
+		return 1, nil
+	})
+	unregistered.Unregister()
+	defer queue.Unregister()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Metrics{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+	if scope := rm.ScopeMetrics[0].Scope; scope.Name != instrumentationName || scope.Version != Version {
+		t.Errorf("scope = %+v", scope)
+	}
+
+	orders := metrics["orders"]
+	if orders.Unit != "{order}" || orders.Description != "Orders placed" {
+		t.Errorf("orders = %+v", orders)
+	}
+	sum := orders.Data.(metricdata.Sum[int64])
+	if !sum.IsMonotonic || sum.DataPoints[0].Value != 2 {
+		t.Errorf("orders = %+v", sum)
+	}
+	if value, _ := sum.DataPoints[0].Attributes.Value("country"); value.AsString() != "fr" {
+		t.Errorf("country = %v", value)
+	}
+	if active := metrics["active_requests"].Data.(metricdata.Sum[int64]); active.IsMonotonic || active.DataPoints[0].Value != 2 {
+		t.Errorf("active requests = %+v", active)
+	}
+	latency := metrics["latency"].Data.(metricdata.Histogram[float64]).DataPoints[0]
+	if len(latency.Bounds) != 2 || latency.BucketCounts[1] != 1 {
+		t.Errorf("latency = %+v", latency)
+	}
+	if gauge := metrics["temperature"].Data.(metricdata.Gauge[float64]); gauge.DataPoints[0].Value != 21.5 {
+		t.Errorf("temperature = %+v", gauge)
+	}
+	size := metrics["queue.size"]
+	if point := size.Data.(metricdata.Gauge[float64]).DataPoints[0]; size.Unit != "{message}" || point.Value != 7 {
+		t.Errorf("queue size = %+v", size)
+	} else if value, _ := point.Attributes.Value("queue"); value.AsString() != "emails" {
+		t.Errorf("queue = %v", value)
+	}
+	if _, ok := metrics["unregistered"]; ok {
+		t.Error("unregistered gauge exported")
+	}
+}
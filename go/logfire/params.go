@@ -0,0 +1,199 @@
+package logfire
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configParam describes where a parameter can be set besides an [Option].
+type configParam struct {
+	// envVars are checked in order, the first one that is set and non-empty wins.
+	envVars []string
+	// allowFileConfig is whether the parameter can be set in `pyproject.toml`.
+	allowFileConfig bool
+}
+
+// configParams uses the same names and environment variables as the Python SDK.
+var configParams = map[string]configParam{
+	"base_url":          {envVars: []string{"LOGFIRE_BASE_URL", "OTEL_EXPORTER_OTLP_ENDPOINT"}, allowFileConfig: true},
+	"send_to_logfire":   {envVars: []string{"LOGFIRE_SEND_TO_LOGFIRE"}, allowFileConfig: true},
+	"token":             {envVars: []string{"LOGFIRE_TOKEN"}},
+	"service_name":      {envVars: []string{"LOGFIRE_SERVICE_NAME", "OTEL_SERVICE_NAME"}, allowFileConfig: true},
+	"service_version":   {envVars: []string{"LOGFIRE_SERVICE_VERSION", "OTEL_SERVICE_VERSION"}, allowFileConfig: true},
+	"environment":       {envVars: []string{"LOGFIRE_ENVIRONMENT"}, allowFileConfig: true},
+	"data_dir":          {envVars: []string{"LOGFIRE_CREDENTIALS_DIR"}, allowFileConfig: true},
+	"trace_sample_rate": {envVars: []string{"LOGFIRE_TRACE_SAMPLE_RATE", "OTEL_TRACES_SAMPLER_ARG"}, allowFileConfig: true},
+	// The Python SDK doesn't have these exporter settings, so they come from the OpenTelemetry variables only.
+	"protocol":           {envVars: []string{"OTEL_EXPORTER_OTLP_PROTOCOL"}},
+	"compression":        {envVars: []string{"OTEL_EXPORTER_OTLP_COMPRESSION"}},
+	"certificate":        {envVars: []string{"OTEL_EXPORTER_OTLP_CERTIFICATE"}},
+	"client_certificate": {envVars: []string{"OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE"}},
+	"client_key":         {envVars: []string{"OTEL_EXPORTER_OTLP_CLIENT_KEY"}},
+
+	"batch_max_queue_size":        {envVars: []string{"OTEL_BSP_MAX_QUEUE_SIZE"}},
+	"batch_max_export_batch_size": {envVars: []string{"OTEL_BSP_MAX_EXPORT_BATCH_SIZE"}},
+	"batch_schedule_delay":        {envVars: []string{"OTEL_BSP_SCHEDULE_DELAY"}},
+	"batch_export_timeout":        {envVars: []string{"OTEL_BSP_EXPORT_TIMEOUT"}},
+	"metric_export_interval":      {envVars: []string{"OTEL_METRIC_EXPORT_INTERVAL"}},
+	"metric_export_timeout":       {envVars: []string{"OTEL_METRIC_EXPORT_TIMEOUT"}},
+	"propagators":                 {envVars: []string{"OTEL_PROPAGATORS"}},
+
+	"console":                   {envVars: []string{"LOGFIRE_CONSOLE"}, allowFileConfig: true},
+	"console_colors":            {envVars: []string{"LOGFIRE_CONSOLE_COLORS"}, allowFileConfig: true},
+	"console_span_style":        {envVars: []string{"LOGFIRE_CONSOLE_SPAN_STYLE"}, allowFileConfig: true},
+	"console_include_timestamp": {envVars: []string{"LOGFIRE_CONSOLE_INCLUDE_TIMESTAMP"}, allowFileConfig: true},
+	"console_verbose":           {envVars: []string{"LOGFIRE_CONSOLE_VERBOSE"}, allowFileConfig: true},
+	"console_min_log_level":     {envVars: []string{"LOGFIRE_CONSOLE_MIN_LOG_LEVEL"}, allowFileConfig: true},
+}
+
+// paramManager resolves configuration that wasn't set with an [Option],
+// reading the LOGFIRE_* environment variables and then the `[tool.logfire]` table of `pyproject.toml`
+// like the Python SDK, so that deployment configuration is identical across languages.
+type paramManager struct {
+	fileConfig map[string]any
+}
+
+func newParamManager(configDir string) (*paramManager, error) {
+	if configDir == "" {
+		configDir = os.Getenv("LOGFIRE_CONFIG_DIR")
+	}
+	if configDir == "" {
+		configDir = "."
+	}
+	fileConfig, err := loadConfigFile(configDir)
+	if err != nil {
+		return nil, err
+	}
+	return &paramManager{fileConfig: fileConfig}, nil
+}
+
+func loadConfigFile(configDir string) (map[string]any, error) {
+	path := filepath.Join(configDir, "pyproject.toml")
+	var data struct {
+		Tool struct {
+			Logfire map[string]any `toml:"logfire"`
+		} `toml:"tool"`
+	}
+	if _, err := toml.DecodeFile(path, &data); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("logfire: invalid config file %s: %w", path, err)
+	}
+	return data.Tool.Logfire, nil
+}
+
+// lookup returns the configured value of the parameter, if any.
+func (m *paramManager) lookup(name string) (string, bool) {
+	param := configParams[name]
+	for _, envVar := range param.envVars {
+		// Unset and empty environment variables are considered the same.
+		if value := os.Getenv(envVar); value != "" {
+			return value, true
+		}
+	}
+	if !param.allowFileConfig {
+		return "", false
+	}
+	value, ok := m.fileConfig[name]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+// string returns runtime if it's set, otherwise the configured value or def.
+func (m *paramManager) string(name, runtime, def string) string {
+	if runtime != "" {
+		return runtime
+	}
+	if value, ok := m.lookup(name); ok && value != "" {
+		return value
+	}
+	return def
+}
+
+// bool returns runtime if it's set, otherwise the configured value or def.
+func (m *paramManager) bool(name string, runtime *bool, def bool) (bool, error) {
+	if runtime != nil {
+		return *runtime, nil
+	}
+	value, ok := m.lookup(name)
+	if !ok || value == "" {
+		return def, nil
+	}
+	switch strings.ToLower(value) {
+	case "1", "true", "t":
+		return true, nil
+	case "0", "false", "f":
+		return false, nil
+	}
+	return false, fmt.Errorf("logfire: expected %s to be a boolean, got %q", name, value)
+}
+
+// int returns runtime if it's set, otherwise the configured positive integer or def.
+func (m *paramManager) int(name string, runtime, def int) (int, error) {
+	if runtime > 0 {
+		return runtime, nil
+	}
+	value, ok := m.lookup(name)
+	if !ok || value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("logfire: expected %s to be a positive integer, got %q", name, value)
+	}
+	return n, nil
+}
+
+// float returns runtime if it's set, otherwise the configured number or def.
+func (m *paramManager) float(name string, runtime *float64, def float64) (float64, error) {
+	if runtime != nil {
+		return *runtime, nil
+	}
+	value, ok := m.lookup(name)
+	if !ok || value == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("logfire: expected %s to be a number, got %q", name, value)
+	}
+	return f, nil
+}
+
+// millis returns runtime if it's set, otherwise the configured number of milliseconds or def.
+func (m *paramManager) millis(name string, runtime, def time.Duration) (time.Duration, error) {
+	if runtime > 0 {
+		return runtime, nil
+	}
+	n, err := m.int(name, 0, 0)
+	if err != nil || n == 0 {
+		return def, err
+	}
+	return time.Duration(n) * time.Millisecond, nil
+}
+
+// level returns runtime if it's set, otherwise the configured level or def.
+func (m *paramManager) level(name string, runtime, def Level) (Level, error) {
+	if runtime != 0 {
+		return runtime, nil
+	}
+	value, ok := m.lookup(name)
+	if !ok || value == "" {
+		return def, nil
+	}
+	level, err := ParseLevel(value)
+	if err != nil {
+		return 0, fmt.Errorf("logfire: invalid %s: %w", name, err)
+	}
+	return level, nil
+}
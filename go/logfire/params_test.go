@@ -0,0 +1,115 @@
+package logfire
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+func TestConfigFromPyproject(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pyproject.toml"), `
+[project]
+name = "example"
+
+[tool.logfire]
+send_to_logfire = false
+service_name = "from-pyproject"
+data_dir = "custom-dir"
+`)
+
+	c, err := newConfig([]Option{WithConfigDir(dir)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *c.sendToLogfire || c.serviceName != "from-pyproject" || c.dataDir != "custom-dir" {
+		t.Errorf("unexpected config %+v", c)
+	}
+
+	// Options take precedence over the file.
+	c, err = newConfig([]Option{WithConfigDir(dir), WithServiceName("explicit"), WithSendToLogfire(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*c.sendToLogfire || c.serviceName != "explicit" {
+		t.Errorf("unexpected config %+v", c)
+	}
+}
+
+func TestConfigFromInvalidPyproject(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pyproject.toml"), "[tool.logfire\n")
+	if _, err := newConfig([]Option{WithConfigDir(dir)}); err == nil {
+		t.Error("expected an error")
+	}
+
+	writeFile(t, filepath.Join(dir, "pyproject.toml"), "[tool.logfire]\nsend_to_logfire = 'maybe'\n")
+	if _, err := newConfig([]Option{WithConfigDir(dir)}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pyproject.toml"), "[tool.logfire]\nservice_name = \"from-pyproject\"\n")
+	t.Setenv("LOGFIRE_CONFIG_DIR", dir)
+	t.Setenv("LOGFIRE_TOKEN", "env-token")
+	t.Setenv("LOGFIRE_SEND_TO_LOGFIRE", "FALSE")
+	t.Setenv("LOGFIRE_SERVICE_NAME", "from-env")
+	t.Setenv("OTEL_SERVICE_VERSION", "2.0")
+	t.Setenv("LOGFIRE_ENVIRONMENT", "staging")
+	t.Setenv("LOGFIRE_CREDENTIALS_DIR", "/tmp/creds")
+	t.Setenv("LOGFIRE_BASE_URL", "https://example.com")
+
+	c, err := newConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, pair := range map[string][2]string{
+		"token":           {c.token, "env-token"},
+		"service_name":    {c.serviceName, "from-env"},
+		"service_version": {c.serviceVersion, "2.0"},
+		"environment":     {c.environment, "staging"},
+		"data_dir":        {c.dataDir, "/tmp/creds"},
+		"base_url":        {c.baseURL, "https://example.com"},
+	} {
+		if pair[0] != pair[1] {
+			t.Errorf("%s = %q, want %q", name, pair[0], pair[1])
+		}
+	}
+	if *c.sendToLogfire {
+		t.Error("expected LOGFIRE_SEND_TO_LOGFIRE=false to disable sending")
+	}
+
+	// Options take precedence over the environment.
+	c, err = newConfig([]Option{WithToken("explicit"), WithSendToLogfire(true), WithEnvironment("production")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.token != "explicit" || !*c.sendToLogfire || c.environment != "production" {
+		t.Errorf("unexpected config %+v", c)
+	}
+}
+
+func TestConfigureSendToLogfireFalseFromEnv(t *testing.T) {
+	t.Setenv("LOGFIRE_SEND_TO_LOGFIRE", "0")
+	t.Setenv("LOGFIRE_ENVIRONMENT", "staging")
+	recorder := tracetest.NewSpanRecorder()
+	shutdown, err := Configure(context.Background(), WithConsole(false), WithAdditionalSpanProcessors(recorder))
+	if err != nil {
+		t.Fatalf("expected no token to be needed, got %v", err)
+	}
+	defer shutdown(context.Background())
+
+	Info(context.Background(), "hi")
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if got, _ := spans[0].Resource().Set().Value(semconv.DeploymentEnvironmentNameKey); got.AsString() != "staging" {
+		t.Errorf("deployment.environment.name = %q", got.AsString())
+	}
+}
@@ -0,0 +1,87 @@
+package logfire
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// WithTextMapPropagator sets the propagator [Configure] installs as the global propagator, which the
+// integrations use to propagate the trace context across services. Defaults to the W3C trace context
+// and baggage propagators, like the other OpenTelemetry SDKs.
+//
+// To keep a propagator installed before Configure, pass it [otel.GetTextMapPropagator].
+func WithTextMapPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.textMapPropagator = propagator
+	}
+}
+
+// WithPropagators sets the propagators [Configure] installs by name, like the OTEL_PROPAGATORS environment
+// variable which it defaults to: `tracecontext` and `baggage` for the W3C propagators, `b3` for the single
+// `b3` header and `b3multi` for the `X-B3-*` headers of Zipkin, e.g. to interoperate with Istio,
+// or `none` to not install a propagator. Both B3 propagators extract either format.
+//
+//	logfire.Configure(ctx, logfire.WithPropagators("b3", "tracecontext"))
+//
+// [WithTextMapPropagator] takes precedence.
+func WithPropagators(names ...string) Option {
+	return func(c *config) {
+		c.propagators = names
+	}
+}
+
+// loadPropagatorsParams parses the names of the propagators, from the options or OTEL_PROPAGATORS.
+func (c *config) loadPropagatorsParams(params *paramManager) error {
+	names := c.propagators
+	if names == nil {
+		if value := params.string("propagators", "", ""); value != "" {
+			names = strings.Split(value, ",")
+		}
+	}
+	if c.textMapPropagator != nil || names == nil {
+		return nil
+	}
+	var propagators []propagation.TextMapPropagator
+	for _, name := range names {
+		switch name = strings.TrimSpace(name); name {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "none":
+			c.noPropagators = true
+		default:
+			return fmt.Errorf("logfire: unknown propagator %q, expected tracecontext, baggage, b3, b3multi or none", name)
+		}
+	}
+	if !c.noPropagators {
+		c.textMapPropagator = propagation.NewCompositeTextMapPropagator(propagators...)
+	}
+	return nil
+}
+
+// setTextMapPropagator installs the propagator of the configuration as the global propagator.
+func (c *config) setTextMapPropagator() {
+	if c.noPropagators {
+		return
+	}
+	propagator := c.textMapPropagator
+	if propagator == nil {
+		propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	// Setting the current propagator again would be reported as an error by the SDK. Composite propagators
+	// are slices, which can't be compared.
+	if reflect.TypeOf(propagator).Comparable() && propagator == otel.GetTextMapPropagator() {
+		return
+	}
+	otel.SetTextMapPropagator(propagator)
+}
@@ -0,0 +1,87 @@
+package logfire
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTextMapPropagator(t *testing.T) {
+	defer otel.SetTextMapPropagator(otel.GetTextMapPropagator())
+
+	// Configure can be called again with the default propagators, which can't be compared.
+	configureForTest(t)
+	configureForTest(t)
+	fields := otel.GetTextMapPropagator().Fields()
+	for _, field := range []string{"traceparent", "tracestate", "baggage"} {
+		if !slices.Contains(fields, field) {
+			t.Errorf("propagator fields = %v, missing %s", fields, field)
+		}
+	}
+
+	ctx, span := Span(context.Background(), "outgoing")
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	span.End()
+	if carrier.Get("traceparent") == "" {
+		t.Errorf("injected headers = %v, missing traceparent", carrier)
+	}
+
+	configureForTest(t, WithTextMapPropagator(propagation.Baggage{}))
+	if fields := otel.GetTextMapPropagator().Fields(); !slices.Equal(fields, []string{"baggage"}) {
+		t.Errorf("propagator fields = %v, want those of the option", fields)
+	}
+
+	// The current propagator can be kept.
+	configureForTest(t, WithTextMapPropagator(otel.GetTextMapPropagator()))
+	if fields := otel.GetTextMapPropagator().Fields(); !slices.Equal(fields, []string{"baggage"}) {
+		t.Errorf("propagator fields = %v, want the current ones", fields)
+	}
+}
+
+func TestWithPropagators(t *testing.T) {
+	defer otel.SetTextMapPropagator(otel.GetTextMapPropagator())
+	ctx, span := configureForTestSpan(t, WithPropagators("b3", "tracecontext"))
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	span.End()
+	if carrier.Get("b3") == "" || carrier.Get("traceparent") == "" || carrier.Get("x-b3-traceid") != "" {
+		t.Errorf("headers = %v, want b3 and traceparent", carrier)
+	}
+	// The trace context is extracted from the B3 headers of other services.
+	extracted := trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(context.Background(),
+		propagation.MapCarrier{"b3": carrier.Get("b3")}))
+	if extracted.TraceID() != span.SpanContext().TraceID() {
+		t.Errorf("extracted trace %s, want %s", extracted.TraceID(), span.SpanContext().TraceID())
+	}
+
+	t.Setenv("OTEL_PROPAGATORS", "b3multi")
+	ctx, span = configureForTestSpan(t)
+	carrier = propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	span.End()
+	if carrier.Get("x-b3-traceid") == "" || carrier.Get("traceparent") != "" {
+		t.Errorf("headers = %v, want the B3 headers of OTEL_PROPAGATORS", carrier)
+	}
+
+	// none keeps the current propagator.
+	configureForTest(t, WithPropagators("none"))
+	if fields := otel.GetTextMapPropagator().Fields(); !slices.Contains(fields, "x-b3-traceid") {
+		t.Errorf("propagator fields = %v, want the previous ones", fields)
+	}
+
+	if _, err := newConfig([]Option{WithPropagators("jaeger")}); err == nil {
+		t.Error("no error for an unknown propagator")
+	}
+}
+
+// configureForTestSpan configures the SDK and starts a span.
+func configureForTestSpan(t *testing.T, opts ...Option) (context.Context, trace.Span) {
+	t.Helper()
+	configureForTest(t, opts...)
+	return Span(context.Background(), "outgoing")
+}
@@ -0,0 +1,312 @@
+package logfire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDiskQueueMaxSize is the default [DiskQueueOptions.MaxSize].
+const DefaultDiskQueueMaxSize = 64 << 20
+
+// DiskQueueOptions configures the disk queue of the exporters, see [WithDiskQueue].
+type DiskQueueOptions struct {
+	// Dir is the directory of the queue, which must not be shared by concurrent processes.
+	// Defaults to the `queue` subdirectory of the data directory, see [WithDataDir].
+	Dir string
+	// MaxSize is the maximum size of the queue in bytes. When it's full, the oldest exports are dropped.
+	// Defaults to [DefaultDiskQueueMaxSize].
+	MaxSize int64
+}
+
+// WithDiskQueue writes exported spans and metrics to files in a directory, which are sent to Logfire
+// in the background and deleted once received, so that telemetry survives network outages and restarts.
+//
+// While Logfire can't be reached, sending is retried with an exponential backoff, oldest exports first.
+// Exports still queued when the process exits are sent by the next process configured with the same directory.
+// Shutting down doesn't wait for Logfire to be reachable, making it fast for short-lived jobs and
+// devices with intermittent connectivity.
+func WithDiskQueue(opts DiskQueueOptions) Option {
+	return func(c *config) {
+		c.diskQueueOptions = &opts
+	}
+}
+
+const (
+	queueMinBackoff = time.Second
+	queueMaxBackoff = time.Minute
+)
+
+// queueSendMu makes sure that a file is sent once when Configure is called again,
+// since the queue of the previous call drains while the new one starts.
+var queueSendMu sync.Mutex
+
+// diskQueue stores the requests of the OTLP HTTP exporters in files, acting as their transport,
+// and sends them with the protocol of the configuration.
+type diskQueue struct {
+	c       *config
+	dir     string
+	maxSize int64
+
+	mu   sync.Mutex
+	size int64
+	seq  atomic.Uint64
+
+	sender *otlpSender
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func newDiskQueue(c *config) (*diskQueue, error) {
+	opts := *c.diskQueueOptions
+	if opts.Dir == "" {
+		opts.Dir = filepath.Join(c.dataDir, "queue")
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultDiskQueueMaxSize
+	}
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("logfire: creating disk queue: %w", err)
+	}
+	q := &diskQueue{
+		c:       c,
+		sender:  newOTLPSender(c),
+		dir:     opts.Dir,
+		maxSize: opts.MaxSize,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, fmt.Errorf("logfire: reading disk queue: %w", err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			// Left by a process which exited while writing.
+			os.Remove(filepath.Join(q.dir, entry.Name()))
+		} else if info, err := entry.Info(); err == nil && queueSignal(entry.Name()) != "" {
+			q.size += info.Size()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	go q.run(ctx)
+	return q, nil
+}
+
+// queueSignal returns the signal of a file of the queue, its extension, or "" if it isn't one.
+func queueSignal(name string) string {
+	switch ext := filepath.Ext(name); ext {
+	case "." + signalTraces, "." + signalMetrics:
+		return ext[1:]
+	}
+	return ""
+}
+
+// RoundTrip queues the request of an exporter, acknowledging it as if Logfire had received it.
+func (q *diskQueue) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := q.push(path.Base(req.URL.Path), body); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/x-protobuf"}},
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+// push writes an export to a new file, dropping the oldest ones if the queue is full.
+func (q *diskQueue) push(signal string, body []byte) error {
+	size := int64(len(body))
+	if size > q.maxSize {
+		return fmt.Errorf("logfire: export of %d bytes exceeds the disk queue size", size)
+	}
+	name := fmt.Sprintf("%020d-%06d.%s", time.Now().UnixNano(), q.seq.Add(1)%1e6, signal)
+	file := filepath.Join(q.dir, name)
+	if err := os.WriteFile(file+".tmp", body, 0o600); err != nil {
+		return fmt.Errorf("logfire: writing to disk queue: %w", err)
+	}
+
+	q.mu.Lock()
+	dropped := 0
+	if q.size+size > q.maxSize {
+		names, _ := q.files()
+		for _, old := range names {
+			if q.size+size <= q.maxSize {
+				break
+			}
+			if q.removeLocked(old) {
+				dropped++
+			}
+		}
+	}
+	err := os.Rename(file+".tmp", file)
+	if err == nil {
+		q.size += size
+	}
+	q.mu.Unlock()
+
+	if err != nil {
+		os.Remove(file + ".tmp")
+		return fmt.Errorf("logfire: writing to disk queue: %w", err)
+	}
+	if dropped > 0 {
+		q.c.diagnostics.report(Diagnostic{
+			Kind:  DiagnosticQueueFull,
+			Count: dropped,
+			Err:   fmt.Errorf("logfire: disk queue full, dropped the %d oldest exports", dropped),
+		})
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// files returns the names of the files of the queue, oldest first.
+func (q *diskQueue) files() ([]string, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if queueSignal(entry.Name()) != "" {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func (q *diskQueue) remove(name string) {
+	q.mu.Lock()
+	q.removeLocked(name)
+	q.mu.Unlock()
+}
+
+func (q *diskQueue) removeLocked(name string) bool {
+	file := filepath.Join(q.dir, name)
+	info, err := os.Stat(file)
+	if err != nil || os.Remove(file) != nil {
+		return false
+	}
+	q.size -= info.Size()
+	return true
+}
+
+// run sends the queue whenever an export is pushed, retrying with an exponential backoff while it fails.
+func (q *diskQueue) run(ctx context.Context) {
+	defer close(q.done)
+	var backoff time.Duration
+	for {
+		err := q.sendAll(ctx)
+		if err == nil {
+			backoff = 0
+			select {
+			case <-q.wake:
+			case <-q.stop:
+				return
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		q.c.diagnostics.error(err)
+		backoff = min(max(2*backoff, queueMinBackoff), queueMaxBackoff)
+		wait := jitter(backoff)
+		var throttled *throttledError
+		if errors.As(err, &throttled) {
+			wait = throttled.delay
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-q.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// sendAll sends the files of the queue, oldest first, stopping at the first error which may be
+// resolved by retrying. Files rejected for other reasons are dropped.
+func (q *diskQueue) sendAll(ctx context.Context) error {
+	names, err := q.files()
+	if err != nil {
+		return fmt.Errorf("logfire: reading disk queue: %w", err)
+	}
+	for _, name := range names {
+		if err := q.sendFile(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendFile sends a file of the queue and removes it once it's received or rejected. The file is read, sent
+// and removed with queueSendMu held, so that the background sending, ForceFlush and the queue of another
+// configuration can't send it again.
+func (q *diskQueue) sendFile(ctx context.Context, name string) error {
+	queueSendMu.Lock()
+	defer queueSendMu.Unlock()
+	body, err := os.ReadFile(filepath.Join(q.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		// Sent by the queue of another configuration, or dropped.
+		return nil
+	}
+	if err != nil {
+		err = &permanentError{fmt.Errorf("logfire: reading disk queue: %w", err)}
+	} else {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err = q.sender.send(sendCtx, queueSignal(name), body)
+		cancel()
+	}
+	if err != nil {
+		var permanent *permanentError
+		if !errors.As(err, &permanent) {
+			return err
+		}
+		q.c.diagnostics.report(Diagnostic{Kind: DiagnosticExportFailed, Signal: queueSignal(name), Count: 1, Err: err})
+	}
+	q.remove(name)
+	return nil
+}
+
+// shutdown stops sending in the background, then sends what's left until ctx is done or sending fails,
+// e.g. the exports flushed when shutting the providers down. The rest is left for the next process.
+func (q *diskQueue) shutdown(ctx context.Context) error {
+	q.stopOnce.Do(func() {
+		close(q.stop)
+		q.cancel()
+		<-q.done
+		if err := q.sendAll(ctx); err != nil && ctx.Err() == nil {
+			q.c.diagnostics.error(err)
+		}
+		q.sender.close()
+	})
+	return nil
+}
@@ -0,0 +1,208 @@
+package logfire
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func queuedFiles(t *testing.T, dir, signal string) []string {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, "*."+signal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestDiskQueue(t *testing.T) {
+	var up atomic.Bool
+	spans := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/v1/traces" {
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "test-token" {
+			t.Errorf("authorization = %s, want the token", got)
+		}
+		b, _ := io.ReadAll(r.Body)
+		var req collectortrace.ExportTraceServiceRequest
+		if err := proto.Unmarshal(b, &req); err != nil {
+			t.Errorf("unmarshal: %v", err)
+		}
+		spans <- req.ResourceSpans[0].ScopeSpans[0].Spans[0].Name
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	// Logfire is down, so the span stays in the queue when the process exits.
+	exportSpan(t, srv.URL, WithDiskQueue(DiskQueueOptions{Dir: dir}), WithCompression(CompressionNone))
+	if files := queuedFiles(t, dir, signalTraces); len(files) != 1 {
+		t.Fatalf("queued spans = %v, want 1 file", files)
+	}
+
+	// The next process sends it.
+	up.Store(true)
+	c, err := newConfig([]Option{WithToken("test-token"), WithConsole(false), WithCompression(CompressionNone),
+		WithDiskQueue(DiskQueueOptions{Dir: dir})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = srv.URL
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := <-spans; got != "hello" {
+		t.Errorf("span = %s, want hello", got)
+	}
+	if err := p.shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if files := queuedFiles(t, dir, signalTraces); len(files) != 0 {
+		t.Errorf("queued spans = %v after sending", files)
+	}
+}
+
+func TestDiskQueueOverGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traces := &traceService{spans: make(chan string, 1), authorization: make(chan string, 1)}
+	srv := grpc.NewServer()
+	collectortrace.RegisterTraceServiceServer(srv, traces)
+	collectormetrics.RegisterMetricsServiceServer(srv, metricsService{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	dir := t.TempDir()
+	exportSpan(t, "http://"+lis.Addr().String(), WithProtocol(ProtocolGRPC), WithDiskQueue(DiskQueueOptions{Dir: dir}))
+	if got := <-traces.spans; got != "hello" {
+		t.Errorf("span = %s, want hello", got)
+	}
+	if got := <-traces.authorization; got != "test-token" {
+		t.Errorf("authorization = %s, want the token", got)
+	}
+	if files := queuedFiles(t, dir, signalTraces); len(files) != 0 {
+		t.Errorf("queued spans = %v after sending", files)
+	}
+}
+
+func TestDiskQueueMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	// A file left by a previous process counts towards the size.
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000001-000001.traces"), make([]byte, 40), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "00000000000000000002-000001.traces.tmp"), make([]byte, 40), 0o600)
+
+	// The unreachable base URL keeps the files in the queue.
+	c, err := newConfig([]Option{WithToken("test-token"), WithDiskQueue(DiskQueueOptions{Dir: dir, MaxSize: 100})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = "http://127.0.0.1:1"
+	q, err := newDiskQueue(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.shutdown(context.Background())
+	if q.size != 40 {
+		t.Errorf("size = %d, want the size of the previous file", q.size)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "00000000000000000002-000001.traces.tmp")); err == nil {
+		t.Error("partially written file not removed")
+	}
+
+	for range 2 {
+		if err := q.push(signalTraces, make([]byte, 40)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := queuedFiles(t, dir, signalTraces)
+	if len(files) != 2 || filepath.Base(files[0]) == "00000000000000000001-000001.traces" {
+		t.Errorf("queued spans = %v, want the oldest dropped", files)
+	}
+	if q.size != 80 {
+		t.Errorf("size = %d, want 80", q.size)
+	}
+	if err := q.push(signalMetrics, make([]byte, 101)); err == nil {
+		t.Error("no error for an export larger than the queue")
+	}
+}
+
+func TestDiskQueueSendsOnce(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received[string(b)]++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	// Two queues sharing a directory, like those of two Configure calls.
+	dir := t.TempDir()
+	var queues []*diskQueue
+	for range 2 {
+		c, err := newConfig([]Option{WithToken("test-token"), WithCompression(CompressionNone),
+			WithDiskQueue(DiskQueueOptions{Dir: dir})})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.baseURL = srv.URL
+		q, err := newDiskQueue(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		queues = append(queues, q)
+	}
+	const exports = 50
+	for i := range exports {
+		if err := queues[0].push(signalTraces, []byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Flushes race with the background sending of both queues.
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			if err := queues[i%2].sendAll(context.Background()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	for _, q := range queues {
+		if err := q.shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := range exports {
+		if n := received[strconv.Itoa(i)]; n != 1 {
+			t.Errorf("export %d sent %d times, want once", i, n)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package logfire
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// regionBaseURLs are the ingestion endpoints of the Logfire data regions.
+var regionBaseURLs = map[string]string{
+	"us": "https://logfire-us.pydantic.dev",
+	"eu": "https://logfire-eu.pydantic.dev",
+}
+
+// tokenPattern matches write tokens which encode their data region, e.g. `pylf_v1_eu_...`.
+// Older tokens don't, and are sent to [DefaultBaseURL].
+var tokenPattern = regexp.MustCompile(`^pylf_v[0-9]+_([a-z]+)_[a-zA-Z0-9]+$`)
+
+// ErrRegionMismatch is returned by [Configure] when the token belongs to a different data region
+// than the one selected with [WithRegion] or [WithBaseURL].
+var ErrRegionMismatch = errors.New("logfire: the token belongs to a different data region")
+
+// WithRegion selects the Logfire data region to send data to, "us" or "eu".
+//
+// By default the region is inferred from the token.
+func WithRegion(region string) Option {
+	return func(c *config) {
+		c.region = region
+	}
+}
+
+// WithBaseURL sets the base URL of the Logfire API, e.g. to send data through a proxy or a collector.
+// It takes precedence over [WithRegion].
+func WithBaseURL(baseURL string) Option {
+	return func(c *config) {
+		c.baseURL = baseURL
+	}
+}
+
+// tokenRegion returns the data region encoded in the token, if any.
+func tokenRegion(token string) string {
+	if m := tokenPattern.FindStringSubmatch(token); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// baseURLRegion returns the data region of one of the Logfire ingestion endpoints.
+func baseURLRegion(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	for region, url := range regionBaseURLs {
+		if url == baseURL {
+			return region
+		}
+	}
+	return ""
+}
+
+// resolveBaseURL picks the base URL from the region when it wasn't set explicitly,
+// and checks that the token belongs to that region.
+func (c *config) resolveBaseURL() error {
+	if c.region != "" {
+		if _, ok := regionBaseURLs[c.region]; !ok {
+			regions := make([]string, 0, len(regionBaseURLs))
+			for region := range regionBaseURLs {
+				regions = append(regions, region)
+			}
+			sort.Strings(regions)
+			return fmt.Errorf("logfire: unknown region %q, expected one of %s", c.region, strings.Join(regions, ", "))
+		}
+	}
+
+	tokenRegion := tokenRegion(c.token)
+	if c.baseURL == "" {
+		region := c.region
+		if region == "" {
+			region = tokenRegion
+		}
+		if url, ok := regionBaseURLs[region]; ok {
+			c.baseURL = url
+		} else {
+			c.baseURL = DefaultBaseURL
+		}
+	}
+
+	// Only check tokens for regions we know about, so that new regions keep working.
+	if _, known := regionBaseURLs[tokenRegion]; known {
+		if region := baseURLRegion(c.baseURL); region != "" && region != tokenRegion {
+			return fmt.Errorf("%w: the token is for the %q region but data would be sent to %s (%q region)",
+				ErrRegionMismatch, tokenRegion, c.baseURL, region)
+		}
+		if c.region != "" && c.region != tokenRegion {
+			return fmt.Errorf("%w: the token is for the %q region but the %q region was selected",
+				ErrRegionMismatch, tokenRegion, c.region)
+		}
+	}
+	return nil
+}
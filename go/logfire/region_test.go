@@ -0,0 +1,61 @@
+package logfire
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveBaseURL(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"legacy token", []Option{WithToken("abc123")}, DefaultBaseURL},
+		{"us token", []Option{WithToken("pylf_v1_us_abc123")}, "https://logfire-us.pydantic.dev"},
+		{"eu token", []Option{WithToken("pylf_v1_eu_abc123")}, "https://logfire-eu.pydantic.dev"},
+		{"unknown region token", []Option{WithToken("pylf_v1_mars_abc123")}, DefaultBaseURL},
+		{"region", []Option{WithToken("abc123"), WithRegion("eu")}, "https://logfire-eu.pydantic.dev"},
+		{"matching region", []Option{WithToken("pylf_v1_eu_abc123"), WithRegion("eu")}, "https://logfire-eu.pydantic.dev"},
+		{"explicit base URL", []Option{WithToken("pylf_v1_eu_abc123"), WithBaseURL("https://proxy.internal")}, "https://proxy.internal"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := newConfig(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.loadToken(); err != nil {
+				t.Fatal(err)
+			}
+			if c.baseURL != tc.want {
+				t.Errorf("base URL = %q, want %q", c.baseURL, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveBaseURLErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		opts     []Option
+		mismatch bool
+	}{
+		{"unknown region", []Option{WithToken("abc123"), WithRegion("mars")}, false},
+		{"region mismatch", []Option{WithToken("pylf_v1_us_abc123"), WithRegion("eu")}, true},
+		{"base URL mismatch", []Option{WithToken("pylf_v1_us_abc123"), WithBaseURL("https://logfire-eu.pydantic.dev/")}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := newConfig(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.loadToken()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, ErrRegionMismatch) != tc.mismatch {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package logfire
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// RemoteSamplingOptions configures where sampling configuration is fetched from, see [WithRemoteSampling].
+type RemoteSamplingOptions struct {
+	// URL returns the sampling configuration as JSON, e.g. a file in object storage or an internal service.
+	URL string
+	// Headers are sent with every request, e.g. to authenticate.
+	Headers map[string]string
+	// Interval is the time between two fetches. Defaults to one minute.
+	Interval time.Duration
+}
+
+// RemoteSamplingConfig is the sampling configuration returned by the URL of [RemoteSamplingOptions], e.g.
+//
+//	{"rate": 0.1, "rules": [{"name": "GET /healthz", "rate": 0}, {"name": "POST *", "rate": 1, "service": "checkout"}]}
+type RemoteSamplingConfig struct {
+	// Rate replaces the rate set with [WithTraceSampleRate], if set.
+	Rate *float64 `json:"rate"`
+	// Rules are evaluated before those set with [WithSamplingRules].
+	Rules []RemoteSamplingRule `json:"rules"`
+}
+
+// RemoteSamplingRule is a [SamplingRule] which may only apply to one service.
+type RemoteSamplingRule struct {
+	Name string  `json:"name"`
+	Rate float64 `json:"rate"`
+	// Service restricts the rule to the service whose resource has this service.name, e.g. set with
+	// [WithServiceName] or OTEL_RESOURCE_ATTRIBUTES.
+	Service string `json:"service"`
+}
+
+// WithRemoteSampling periodically fetches the sampling configuration from a URL and applies it without
+// restarting the process, e.g. to sample a service more while investigating an incident.
+//
+// Until the first fetch succeeds, and when the configuration is invalid, the sampling configured
+// with options applies. Fetch errors are reported as diagnostics, see [WithDiagnostics].
+// The configuration doesn't change the ratio of traces kept by [WithTailSampling].
+func WithRemoteSampling(opts RemoteSamplingOptions) Option {
+	return func(c *config) {
+		c.remoteSamplingOptions = &opts
+	}
+}
+
+// remoteSampler delegates to the sampler built from the last configuration fetched.
+type remoteSampler struct {
+	c    *config
+	opts RemoteSamplingOptions
+	// service is the service.name of the resource, which the rules of the configuration can be scoped to.
+	service string
+	current atomic.Pointer[sdktrace.Sampler]
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newRemoteSampler(c *config, res *resource.Resource) *remoteSampler {
+	service, _ := res.Set().Value(semconv.ServiceNameKey)
+	s := &remoteSampler{c: c, opts: *c.remoteSamplingOptions, service: service.AsString(), done: make(chan struct{})}
+	if s.opts.Interval <= 0 {
+		s.opts.Interval = time.Minute
+	}
+	sampler, _ := c.buildSampler(*c.traceSampleRate, c.compiledSamplingRules)
+	s.current.Store(&sampler)
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run(ctx)
+	return s
+}
+
+func (s *remoteSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return (*s.current.Load()).ShouldSample(p)
+}
+
+func (s *remoteSampler) Description() string {
+	return fmt.Sprintf("LogfireRemoteSampler{%s}", (*s.current.Load()).Description())
+}
+
+func (s *remoteSampler) run(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.refresh(ctx); err != nil && ctx.Err() == nil {
+			s.c.diagnostics.error(err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh fetches the configuration and applies it.
+func (s *remoteSampler) refresh(ctx context.Context) error {
+	cfg, err := s.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("logfire: fetching sampling configuration from %s: %w", s.opts.URL, err)
+	}
+	rate := *s.c.traceSampleRate
+	if cfg.Rate != nil {
+		rate = *cfg.Rate
+	}
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("logfire: invalid sampling configuration from %s: rate %v isn't between 0 and 1", s.opts.URL, rate)
+	}
+	var rules []samplingRule
+	for _, rule := range cfg.Rules {
+		if rule.Service != "" && rule.Service != s.service {
+			continue
+		}
+		compiled, err := compileSamplingRule(SamplingRule{Name: rule.Name, Rate: rule.Rate})
+		if err != nil {
+			return fmt.Errorf("logfire: invalid sampling configuration from %s: %w", s.opts.URL, err)
+		}
+		rules = append(rules, compiled)
+	}
+	sampler, _ := s.c.buildSampler(rate, append(rules, s.c.compiledSamplingRules...))
+	s.current.Store(&sampler)
+	return nil
+}
+
+func (s *remoteSampler) fetch(ctx context.Context) (*RemoteSamplingConfig, error) {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.opts.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var cfg RemoteSamplingConfig
+	if err := json.NewDecoder(resp.Body).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+func (s *remoteSampler) shutdown() {
+	s.cancel()
+	<-s.done
+}
@@ -0,0 +1,123 @@
+package logfire
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRemoteSampling(t *testing.T) {
+	var body atomic.Value
+	body.Store(`{"rate": 0}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer srv.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	c, err := newConfig([]Option{WithSendToLogfire(false), WithConsole(false), WithAdditionalSpanProcessors(recorder),
+		WithServiceName("checkout"), WithSamplingRules(SamplingRule{Name: "local", Rate: 0}),
+		WithRemoteSampling(RemoteSamplingOptions{URL: srv.URL, Headers: map[string]string{"Authorization": "secret"}, Interval: time.Hour})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.shutdown(context.Background())
+	// Wait for the first fetch, made in the background when configuring.
+	initial := c.remoteSampler.current.Load()
+	for deadline := time.Now().Add(5 * time.Second); c.remoteSampler.current.Load() == initial; {
+		if time.Now().After(deadline) {
+			t.Fatal("sampling configuration not fetched")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	refresh := func() {
+		t.Helper()
+		if err := c.remoteSampler.refresh(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record := func(names ...string) []string {
+		recorder.Reset()
+		for _, name := range names {
+			_, span := p.tracerProvider.Tracer("test").Start(context.Background(), name)
+			span.End()
+		}
+		var recorded []string
+		for _, s := range recorder.Ended() {
+			recorded = append(recorded, s.Name())
+		}
+		return recorded
+	}
+
+	refresh()
+	if got := record("a", "b"); len(got) != 0 {
+		t.Errorf("recorded %v with a remote rate of 0", got)
+	}
+	body.Store(`{"rules": [{"name": "b", "rate": 0}, {"name": "a", "rate": 0, "service": "other"}]}`)
+	refresh()
+	if got := record("a", "b", "local"); len(got) != 1 || got[0] != "a" {
+		t.Errorf("recorded %v, want the rules of the service and the local ones applied", got)
+	}
+
+	// Invalid configurations are ignored.
+	body.Store(`{"rate": 2}`)
+	if err := c.remoteSampler.refresh(context.Background()); err == nil {
+		t.Error("no error for an invalid rate")
+	}
+	if got := record("a"); len(got) != 1 {
+		t.Errorf("recorded %v, want the last valid configuration applied", got)
+	}
+}
+
+func TestRemoteSamplingResourceService(t *testing.T) {
+	// The SDK reads OTEL_RESOURCE_ATTRIBUTES once per process, so the test runs in a new one.
+	if os.Getenv("LOGFIRE_TEST_RESOURCE_SERVICE") == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestRemoteSamplingResourceService$")
+		cmd.Env = append(os.Environ(), "LOGFIRE_TEST_RESOURCE_SERVICE=1", "OTEL_RESOURCE_ATTRIBUTES=service.name=checkout")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		return
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"rules": [{"name": "a", "rate": 0, "service": "checkout"}]}`))
+	}))
+	defer srv.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	c, err := newConfig([]Option{WithSendToLogfire(false), WithConsole(false), WithAdditionalSpanProcessors(recorder),
+		WithRemoteSampling(RemoteSamplingOptions{URL: srv.URL, Interval: time.Hour})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.shutdown(context.Background())
+	if err := c.remoteSampler.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		_, span := p.tracerProvider.Tracer("test").Start(context.Background(), name)
+		span.End()
+	}
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Name() != "b" {
+		t.Errorf("recorded %d spans, want b only, sampled by the rule of the service of the resource", len(spans))
+	}
+}
@@ -0,0 +1,162 @@
+package logfire
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// detector returns the resource attributes of the environment the program runs in,
+// or none if it doesn't run there.
+type detector interface {
+	detect(ctx context.Context) []attribute.KeyValue
+}
+
+// WithResourceDetection sets whether [Configure] detects the environment the program runs in, e.g. a Kubernetes
+// pod or a cloud instance, and adds its attributes to the resource. Defaults to true.
+//
+// Cloud instances are recognized from their DMI information, or the environment variables of serverless
+// platforms, so that requests are only sent to the metadata services of the cloud the program runs in.
+func WithResourceDetection(enabled bool) Option {
+	return func(c *config) {
+		c.detectionDisabled = !enabled
+	}
+}
+
+// detectTimeout bounds the requests of the detectors to metadata services, so that [Configure] stays fast
+// when they don't respond.
+const detectTimeout = time.Second
+
+// dmiDir has the DMI information of the machine, which tells the cloud of a virtual machine
+// without any request.
+const dmiDir = "/sys/class/dmi/id"
+
+// defaultDetectors are the detectors run by [Configure].
+func defaultDetectors() []detector {
+	return []detector{
+		kubernetesDetector{serviceAccountDir: kubernetesServiceAccountDir},
+		awsDetector{imdsURL: awsIMDSURL, dmiDir: dmiDir},
+		gcpDetector{metadataURL: gcpMetadataURL, dmiDir: dmiDir},
+		azureDetector{imdsURL: azureIMDSURL, dmiDir: dmiDir},
+	}
+}
+
+// detect runs the detectors concurrently, and returns their attributes in order.
+func detect(ctx context.Context, detectors []detector) []attribute.KeyValue {
+	ctx, cancel := context.WithTimeout(ctx, detectTimeout)
+	defer cancel()
+	results := make([][]attribute.KeyValue, len(detectors))
+	var wg sync.WaitGroup
+	for i, d := range detectors {
+		wg.Go(func() {
+			results[i] = d.detect(ctx)
+		})
+	}
+	wg.Wait()
+	return slices.Concat(results...)
+}
+
+// metadataClient is the client of the requests to metadata services, which are local so proxies are bypassed.
+var metadataClient = &http.Client{Transport: &http.Transport{}}
+
+// getMetadata sends a request to a metadata service and returns the body of its response.
+func getMetadata(ctx context.Context, method, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata request to %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// getMetadataJSON decodes the JSON response of a metadata service into v.
+func getMetadataJSON(ctx context.Context, url string, header http.Header, v any) error {
+	b, err := getMetadata(ctx, http.MethodGet, url, header)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// readDMI returns the DMI field name, or "" if the machine doesn't have it.
+func readDMI(dir, name string) string {
+	b, _ := os.ReadFile(filepath.Join(dir, name))
+	return strings.TrimSpace(string(b))
+}
+
+// appendNonEmpty appends the attributes whose value isn't empty.
+func appendNonEmpty(attrs []attribute.KeyValue, kvs ...attribute.KeyValue) []attribute.KeyValue {
+	for _, kv := range kvs {
+		if kv.Value.Emit() != "" {
+			attrs = append(attrs, kv)
+		}
+	}
+	return attrs
+}
+
+func (c *config) resource(ctx context.Context) (*resource.Resource, error) {
+	var detected []attribute.KeyValue
+	if !c.detectionDisabled {
+		detected = detect(ctx, defaultDetectors())
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.ServiceInstanceID(newInstanceID()),
+		semconv.ProcessPID(os.Getpid()),
+	}
+	if c.serviceName != "" {
+		attrs = append(attrs, semconv.ServiceName(c.serviceName))
+	}
+	if c.serviceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(c.serviceVersion))
+	}
+	if c.environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironmentNameKey.String(c.environment))
+	}
+	attrs = append(attrs, c.codeSourceAttributes()...)
+	attrs = append(attrs, c.resourceAttributes...)
+
+	// resource.Default includes the SDK attributes and OTEL_RESOURCE_ATTRIBUTES, which take precedence
+	// over the detected attributes, while the options take precedence over both. The service name and
+	// version of the build info are only used if neither set them.
+	res, err := resource.Merge(resource.NewWithAttributes(semconv.SchemaURL, detected...), resource.Default())
+	if err == nil {
+		attrs = append(c.buildInfoAttributes(res), attrs...)
+		res, err = resource.Merge(res, resource.NewWithAttributes(semconv.SchemaURL, attrs...))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("logfire: creating resource: %w", err)
+	}
+	return res, nil
+}
+
+// newInstanceID returns a random `service.instance.id`, as recommended by the semantic conventions.
+func newInstanceID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,127 @@
+package logfire
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryOptions configures how exports are retried when they fail, see [WithRetry].
+type RetryOptions struct {
+	// Disabled disables retries, so that failed exports are dropped at once.
+	Disabled bool
+	// InitialInterval is the time to wait after the first failure. Defaults to 5 seconds.
+	InitialInterval time.Duration
+	// MaxInterval is the maximum time between attempts, which doubles after each failure. Defaults to 30 seconds.
+	MaxInterval time.Duration
+	// MaxElapsedTime is the maximum time spent trying to export a batch. Defaults to one minute.
+	MaxElapsedTime time.Duration
+}
+
+// WithRetry sets how exports are retried when Logfire is unavailable, returns a server error or throttles them.
+//
+// Waits are randomized by up to half of their duration, so that restarted processes don't retry together,
+// unless Logfire requests a delay with the Retry-After header. Exports which still fail, or are rejected
+// for other reasons, are reported as diagnostics, see [WithDiagnostics].
+func WithRetry(opts RetryOptions) Option {
+	return func(c *config) {
+		c.retryOptions = opts
+	}
+}
+
+func (o RetryOptions) withDefaults() RetryOptions {
+	if o.InitialInterval <= 0 {
+		o.InitialInterval = 5 * time.Second
+	}
+	if o.MaxInterval <= 0 {
+		o.MaxInterval = 30 * time.Second
+	}
+	if o.MaxElapsedTime <= 0 {
+		o.MaxElapsedTime = time.Minute
+	}
+	return o
+}
+
+// retryTransport retries the requests of the HTTP exporters, whose own retries don't include
+// every server error or randomize their waits.
+type retryTransport struct {
+	base http.RoundTripper
+	opts RetryOptions
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	deadline := time.Now().Add(t.opts.MaxElapsedTime)
+	interval := t.opts.InitialInterval
+	for attempt := req; ; {
+		resp, err := t.base.RoundTrip(attempt)
+		if (err == nil && !retryableStatus(resp.StatusCode)) || ctx.Err() != nil || req.GetBody == nil {
+			return resp, err
+		}
+		wait := jitter(interval)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				wait = after
+			}
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+		if d, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(d) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		interval = min(2*interval, t.opts.MaxInterval)
+
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		attempt = req.Clone(ctx)
+		attempt.Body = body
+	}
+}
+
+// retryableStatus reports whether a response status may change when the request is retried.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusRequestTimeout:
+		return true
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return code >= 500
+}
+
+// retryAfter returns the delay requested by the Retry-After header of a response, in seconds or as a date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// jitter randomizes d by up to half of it in either direction.
+func jitter(d time.Duration) time.Duration {
+	return d/2 + rand.N(d+1)
+}
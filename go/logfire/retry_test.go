@@ -0,0 +1,108 @@
+package logfire
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		statuses []int
+		header   string
+		want     int
+		attempts int32
+	}{
+		{"server errors", []int{http.StatusInternalServerError, http.StatusBadGateway}, "", http.StatusOK, 3},
+		{"throttled", []int{http.StatusTooManyRequests}, "0", http.StatusOK, 2},
+		{"rejected", []int{http.StatusBadRequest}, "", http.StatusBadRequest, 1},
+		{"not implemented", []int{http.StatusNotImplemented}, "", http.StatusNotImplemented, 1},
+		// Waiting longer than the maximum elapsed time would be pointless.
+		{"throttled for too long", []int{http.StatusTooManyRequests}, "3600", http.StatusTooManyRequests, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if b, _ := io.ReadAll(r.Body); string(b) != "spans" {
+					t.Errorf("body = %q", b)
+				}
+				n := int(attempts.Add(1))
+				if n > len(tt.statuses) {
+					return
+				}
+				if tt.header != "" {
+					w.Header().Set("Retry-After", tt.header)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			client := &http.Client{Transport: &retryTransport{
+				base: http.DefaultTransport,
+				opts: RetryOptions{InitialInterval: time.Millisecond, MaxElapsedTime: time.Minute}.withDefaults(),
+			}}
+			resp, err := client.Post(srv.URL, "application/x-protobuf", strings.NewReader("spans"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if got := attempts.Load(); got != tt.attempts {
+				t.Errorf("attempts = %d, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for header, want := range map[string]time.Duration{
+		"120": 2 * time.Minute,
+		time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat): 0,
+	} {
+		got, ok := retryAfter(&http.Response{Header: http.Header{"Retry-After": {header}}})
+		if !ok || got != want {
+			t.Errorf("retryAfter(%s) = %v, %t, want %v", header, got, ok, want)
+		}
+	}
+	if _, ok := retryAfter(&http.Response{Header: http.Header{"Retry-After": {"soon"}}}); ok {
+		t.Error("invalid Retry-After accepted")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for range 100 {
+		if d := jitter(time.Second); d < time.Second/2 || d > 3*time.Second/2 {
+			t.Fatalf("jitter(1s) = %v", d)
+		}
+	}
+}
+
+func TestExportRetries(t *testing.T) {
+	var attempts atomic.Int32
+	spans := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			return
+		}
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		spans <- struct{}{}
+	}))
+	defer srv.Close()
+
+	exportSpan(t, srv.URL, WithRetry(RetryOptions{InitialInterval: time.Millisecond}))
+	select {
+	case <-spans:
+	default:
+		t.Errorf("span not retried after a server error")
+	}
+}
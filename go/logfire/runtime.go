@@ -0,0 +1,228 @@
+package logfire
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/semconv/v1.43.0/goconv"
+)
+
+// runtimeHistograms is whether the GC pause and scheduling latency histograms are produced,
+// see [InstrumentRuntimeMetrics].
+var runtimeHistograms atomic.Bool
+
+// Names of the runtime/metrics samples read by [InstrumentRuntimeMetrics].
+const (
+	runtimeGoroutines  = "/sched/goroutines:goroutines"
+	runtimeMemoryTotal = "/memory/classes/total:bytes"
+	runtimeReleased    = "/memory/classes/heap/released:bytes"
+	runtimeHeapStacks  = "/memory/classes/heap/stacks:bytes"
+	runtimeOSStacks    = "/memory/classes/os-stacks:bytes"
+	runtimeMemoryLimit = "/gc/gomemlimit:bytes"
+	runtimeAllocated   = "/gc/heap/allocs:bytes"
+	runtimeAllocations = "/gc/heap/allocs:objects"
+	runtimeHeapGoal    = "/gc/heap/goal:bytes"
+	runtimeGOGC        = "/gc/gogc:percent"
+	runtimeGOMAXPROCS  = "/sched/gomaxprocs:threads"
+	runtimeGCPauses    = "/sched/pauses/total/gc:seconds"
+	runtimeLatencies   = "/sched/latencies:seconds"
+)
+
+// InstrumentRuntimeMetrics exports metrics of the Go runtime with the names of the OpenTelemetry semantic
+// conventions: the number of goroutines, the memory used by stacks and the rest of the runtime, the heap
+// allocations and GC goal, GOMAXPROCS, GOGC and the memory limit, and histograms of the GC pauses
+// and of the time goroutines wait to be scheduled.
+//
+// It should be called once, after [Configure]. The histograms are only exported by the readers of Configure,
+// not those set with [WithAdditionalMetricReaders], since the runtime only provides them pre-aggregated.
+func InstrumentRuntimeMetrics() error {
+	meter := meter()
+	goroutines, err := goconv.NewGoroutineCount(meter)
+	if err != nil {
+		return err
+	}
+	memoryUsed, err := goconv.NewMemoryUsed(meter)
+	if err != nil {
+		return err
+	}
+	memoryLimit, err := goconv.NewMemoryLimit(meter)
+	if err != nil {
+		return err
+	}
+	allocated, err := goconv.NewMemoryAllocated(meter)
+	if err != nil {
+		return err
+	}
+	allocations, err := goconv.NewMemoryAllocations(meter)
+	if err != nil {
+		return err
+	}
+	gcGoal, err := goconv.NewMemoryGCGoal(meter)
+	if err != nil {
+		return err
+	}
+	processorLimit, err := goconv.NewProcessorLimit(meter)
+	if err != nil {
+		return err
+	}
+	gogc, err := goconv.NewConfigGogc(meter)
+	if err != nil {
+		return err
+	}
+
+	samples := newRuntimeSamples(runtimeGoroutines, runtimeMemoryTotal, runtimeReleased, runtimeHeapStacks,
+		runtimeOSStacks, runtimeMemoryLimit, runtimeAllocated, runtimeAllocations, runtimeHeapGoal, runtimeGOGC,
+		runtimeGOMAXPROCS)
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		v := samples.read()
+		stacks := v[runtimeHeapStacks] + v[runtimeOSStacks]
+		o.ObserveInt64(goroutines.Inst(), v[runtimeGoroutines])
+		o.ObserveInt64(memoryUsed.Inst(), stacks, metric.WithAttributes(memoryUsed.AttrMemoryType(goconv.MemoryTypeStack)))
+		o.ObserveInt64(memoryUsed.Inst(), v[runtimeMemoryTotal]-v[runtimeReleased]-stacks,
+			metric.WithAttributes(memoryUsed.AttrMemoryType(goconv.MemoryTypeOther)))
+		// The limit is math.MaxInt64 unless set.
+		if limit := v[runtimeMemoryLimit]; limit != math.MaxInt64 {
+			o.ObserveInt64(memoryLimit.Inst(), limit)
+		}
+		o.ObserveInt64(allocated.Inst(), v[runtimeAllocated])
+		o.ObserveInt64(allocations.Inst(), v[runtimeAllocations])
+		o.ObserveInt64(gcGoal.Inst(), v[runtimeHeapGoal])
+		o.ObserveInt64(processorLimit.Inst(), v[runtimeGOMAXPROCS])
+		// GOGC is negative when the GC is off.
+		if percent := v[runtimeGOGC]; percent >= 0 {
+			o.ObserveInt64(gogc.Inst(), percent)
+		}
+		return nil
+	}, goroutines.Inst(), memoryUsed.Inst(), memoryLimit.Inst(), allocated.Inst(), allocations.Inst(),
+		gcGoal.Inst(), processorLimit.Inst(), gogc.Inst())
+	if err != nil {
+		return err
+	}
+	runtimeHistograms.Store(true)
+	return nil
+}
+
+// runtimeSamples reads integer runtime metrics.
+type runtimeSamples struct {
+	mu      sync.Mutex
+	samples []metrics.Sample
+}
+
+func newRuntimeSamples(names ...string) *runtimeSamples {
+	s := &runtimeSamples{samples: make([]metrics.Sample, len(names))}
+	for i, name := range names {
+		s.samples[i].Name = name
+	}
+	return s
+}
+
+// read returns the values of the samples, zero for those unsupported by this Go version.
+func (s *runtimeSamples) read() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metrics.Read(s.samples)
+	values := make(map[string]int64, len(s.samples))
+	for _, sample := range s.samples {
+		if sample.Value.Kind() == metrics.KindUint64 {
+			values[sample.Name] = int64(min(sample.Value.Uint64(), math.MaxInt64))
+		}
+	}
+	return values
+}
+
+// runtimeProducer produces the histograms of the runtime, which it only provides as cumulative bucket counts,
+// so they can't be recorded with histogram instruments. Each reader has its own producer, since the
+// histograms are exported as the deltas since the previous export of the reader.
+type runtimeProducer struct {
+	mu       sync.Mutex
+	samples  []metrics.Sample
+	previous map[string][]uint64
+	start    time.Time
+}
+
+var _ sdkmetric.Producer = (*runtimeProducer)(nil)
+
+func newRuntimeProducer() *runtimeProducer {
+	return &runtimeProducer{
+		samples:  []metrics.Sample{{Name: runtimeGCPauses}, {Name: runtimeLatencies}},
+		previous: map[string][]uint64{},
+		start:    time.Now(),
+	}
+}
+
+func (p *runtimeProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	if !runtimeHistograms.Load() {
+		return nil, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	metrics.Read(p.samples)
+	now := time.Now()
+	var pauses goconv.MemoryGCPauseDuration
+	var latencies goconv.ScheduleDuration
+	scope := metricdata.ScopeMetrics{Scope: instrumentation.Scope{Name: instrumentationName, Version: Version}}
+	for _, sample := range p.samples {
+		if sample.Value.Kind() != metrics.KindFloat64Histogram {
+			continue
+		}
+		name, description := pauses.Name(), pauses.Description()
+		if sample.Name == runtimeLatencies {
+			name, description = latencies.Name(), latencies.Description()
+		}
+		scope.Metrics = append(scope.Metrics, metricdata.Metrics{
+			Name:        name,
+			Description: description,
+			Unit:        "s",
+			Data: metricdata.Histogram[float64]{
+				Temporality: metricdata.DeltaTemporality,
+				DataPoints:  []metricdata.HistogramDataPoint[float64]{p.delta(sample.Name, sample.Value.Float64Histogram(), now)},
+			},
+		})
+	}
+	p.start = now
+	return []metricdata.ScopeMetrics{scope}, nil
+}
+
+// delta converts the counts of a runtime histogram since the previous export to a data point.
+func (p *runtimeProducer) delta(name string, h *metrics.Float64Histogram, now time.Time) metricdata.HistogramDataPoint[float64] {
+	previous := p.previous[name]
+	counts := make([]uint64, len(h.Counts))
+	var count uint64
+	var sum float64
+	for i, c := range h.Counts {
+		if i < len(previous) {
+			c -= previous[i]
+		}
+		counts[i] = c
+		count += c
+		// The runtime doesn't provide the sum, so it's estimated from the bucket boundaries.
+		lower, upper := h.Buckets[i], h.Buckets[i+1]
+		switch {
+		case math.IsInf(lower, -1):
+			sum += float64(c) * upper
+		case math.IsInf(upper, 1):
+			sum += float64(c) * lower
+		default:
+			sum += float64(c) * (lower + upper) / 2
+		}
+	}
+	p.previous[name] = append(previous[:0], h.Counts...)
+	// The first and last boundaries are those of the overflow buckets of OpenTelemetry histograms.
+	return metricdata.HistogramDataPoint[float64]{
+		StartTime:    p.start,
+		Time:         now,
+		Count:        count,
+		Bounds:       slices.Clone(h.Buckets[1 : len(h.Buckets)-1]),
+		BucketCounts: counts,
+		Sum:          sum,
+	}
+}
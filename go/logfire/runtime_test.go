@@ -0,0 +1,56 @@
+package logfire
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRuntimeMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader(sdkmetric.WithProducer(newRuntimeProducer()))
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer otel.SetMeterProvider(otel.GetMeterProvider())
+	otel.SetMeterProvider(provider)
+	defer runtimeHistograms.Store(false)
+	if err := InstrumentRuntimeMetrics(); err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func() map[string]metricdata.Aggregation {
+		t.Helper()
+		var rm metricdata.ResourceMetrics
+		if err := reader.Collect(context.Background(), &rm); err != nil {
+			t.Fatal(err)
+		}
+		data := map[string]metricdata.Aggregation{}
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				data[m.Name] = m.Data
+			}
+		}
+		return data
+	}
+	collect()
+	runtime.GC()
+	time.Sleep(time.Millisecond)
+	data := collect()
+	for _, name := range []string{"go.goroutine.count", "go.memory.used", "go.memory.allocated", "go.memory.gc.goal", "go.processor.limit", "go.schedule.duration"} {
+		if data[name] == nil {
+			t.Errorf("missing %s", name)
+		}
+	}
+	pauses, ok := data["go.memory.gc.pause.duration"].(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("GC pauses = %T, want a histogram", data["go.memory.gc.pause.duration"])
+	}
+	point := pauses.DataPoints[0]
+	// runtime.GC stops the world twice, and the previous pauses were exported by the first collection.
+	if point.Count == 0 || point.Count > 10 || len(point.BucketCounts) != len(point.Bounds)+1 {
+		t.Errorf("GC pauses = %+v, want those of runtime.GC", point)
+	}
+}
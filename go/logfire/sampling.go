@@ -0,0 +1,271 @@
+package logfire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTraceSampleRate sets the ratio of traces recorded, between 0 and 1, like `SamplingOptions(head=...)`
+// in the Python SDK. Defaults to 1, recording every trace.
+//
+// The decision is made when a trace starts and inherited by its spans, including those of downstream
+// services, so that sampled traces are complete.
+func WithTraceSampleRate(rate float64) Option {
+	return func(c *config) {
+		c.traceSampleRate = &rate
+	}
+}
+
+// SamplingRule sets the sample rate of the spans whose name matches a pattern, see [WithSamplingRules].
+type SamplingRule struct {
+	// Name is the span name pattern, where `*` and `%` match any characters,
+	// e.g. `GET /healthz` or `SELECT pg_catalog%`.
+	Name string
+	// Rate is the ratio of the matching spans recorded, between 0 and 1.
+	Rate float64
+}
+
+// WithSamplingRules sets the sample rate of spans by name, e.g. to downsample health checks or
+// noisy queries without dropping everything. The first matching rule applies, and spans matching
+// none follow [WithTraceSampleRate].
+//
+// Rules are evaluated when spans start, and also apply to spans within a trace: the spans started
+// below a dropped one are dropped too. The decision depends on the trace ID, so that the matching
+// spans of a trace are either all recorded or all dropped.
+func WithSamplingRules(rules ...SamplingRule) Option {
+	return func(c *config) {
+		c.samplingRules = append(c.samplingRules, rules...)
+	}
+}
+
+// samplingRule is a compiled [SamplingRule].
+type samplingRule struct {
+	pattern *regexp.Regexp
+	sampler sdktrace.Sampler
+}
+
+func compileSamplingRule(rule SamplingRule) (samplingRule, error) {
+	if rule.Rate < 0 || rule.Rate > 1 {
+		return samplingRule{}, fmt.Errorf("logfire: invalid sample rate %v of rule %q, expected a number between 0 and 1", rule.Rate, rule.Name)
+	}
+	pattern := regexp.QuoteMeta(rule.Name)
+	pattern = strings.NewReplacer(`\*`, ".*", "%", ".*").Replace(pattern)
+	return samplingRule{
+		pattern: regexp.MustCompile("^" + pattern + "$"),
+		sampler: sdktrace.TraceIDRatioBased(rule.Rate),
+	}, nil
+}
+
+// ruleSampler applies the first matching sampling rule, and next to the spans matching none.
+type ruleSampler struct {
+	rules []samplingRule
+	next  sdktrace.Sampler
+}
+
+func (s *ruleSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, rule := range s.rules {
+		if !rule.pattern.MatchString(p.Name) {
+			continue
+		}
+		if parent := trace.SpanContextFromContext(p.ParentContext); parent.IsValid() && !parent.IsSampled() {
+			// A rule can't bring back a span of a dropped trace.
+			return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: parent.TraceState()}
+		}
+		return rule.sampler.ShouldSample(p)
+	}
+	return s.next.ShouldSample(p)
+}
+
+func (s *ruleSampler) Description() string {
+	return fmt.Sprintf("LogfireRuleSampler{rules:%d,next:%s}", len(s.rules), s.next.Description())
+}
+
+// The baggage entry forcing the sampling of a request, see [WithBaggageForceSampling].
+const (
+	// SampleBaggageKey is the key of the entry.
+	SampleBaggageKey = "logfire.sample"
+	// SampleBaggageAlways is the value of the entry forcing sampling.
+	SampleBaggageAlways = "always"
+)
+
+// WithBaggageForceSampling records every span started in the context of a `logfire.sample=always` baggage
+// entry, regardless of the sampling configuration, which also bypasses tail sampling. Upstream callers can set
+// it to trace a specific request through every service, e.g. to debug the requests of a customer.
+//
+// The entry is received with the W3C baggage propagator, which Configure installs unless another propagator
+// is set with [WithTextMapPropagator].
+// Since any client can set it, services receiving requests from the internet should remove the incoming baggage.
+func WithBaggageForceSampling(enabled bool) Option {
+	return func(c *config) {
+		c.baggageForceSampling = enabled
+	}
+}
+
+// forcedSampling reports whether the baggage of ctx forces sampling.
+func forcedSampling(ctx context.Context) bool {
+	return baggage.FromContext(ctx).Member(SampleBaggageKey).Value() == SampleBaggageAlways
+}
+
+// forceSampler records the spans whose sampling is forced by baggage, and delegates the others to next.
+type forceSampler struct {
+	next sdktrace.Sampler
+}
+
+func (s *forceSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if forcedSampling(p.ParentContext) {
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.RecordAndSample,
+			Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+	return s.next.ShouldSample(p)
+}
+
+func (s *forceSampler) Description() string {
+	return fmt.Sprintf("LogfireForceSampler{next:%s}", s.next.Description())
+}
+
+// WithRootSpanRateLimit caps the number of traces started per second for each root span name,
+// e.g. to protect the bill and the exporters during traffic spikes or retry storms. See [NewRateLimitSampler].
+func WithRootSpanRateLimit(perSecond float64) Option {
+	return func(c *config) {
+		c.rootSpanRateLimit = perSecond
+	}
+}
+
+// maxRateLimitNames bounds the memory of a rate limit sampler when span names have a high cardinality,
+// e.g. because they contain IDs.
+const maxRateLimitNames = 1000
+
+// NewRateLimitSampler returns a sampler recording at most perSecond root spans per second for each span name,
+// with bursts of up to perSecond. The decisions of the other spans, and those of next for the root spans
+// within the limit, are made by next.
+func NewRateLimitSampler(perSecond float64, next sdktrace.Sampler) sdktrace.Sampler {
+	return &rateLimitSampler{perSecond: perSecond, next: next, buckets: map[string]*tokenBucket{}}
+}
+
+type rateLimitSampler struct {
+	perSecond float64
+	next      sdktrace.Sampler
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (s *rateLimitSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.next.ShouldSample(p)
+	if trace.SpanContextFromContext(p.ParentContext).IsValid() || result.Decision == sdktrace.Drop {
+		return result
+	}
+	if !s.take(p.Name, time.Now()) {
+		return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: result.Tracestate}
+	}
+	return result
+}
+
+// take takes a token from the bucket of a span name, reporting whether one was available.
+func (s *rateLimitSampler) take(name string, now time.Time) bool {
+	burst := max(s.perSecond, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.buckets[name]
+	if !ok {
+		if len(s.buckets) >= maxRateLimitNames {
+			// Starting over only lets a burst through, which is better than growing without bound.
+			clear(s.buckets)
+		}
+		b = &tokenBucket{tokens: burst, last: now}
+		s.buckets[name] = b
+	}
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*s.perSecond, burst)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (s *rateLimitSampler) Description() string {
+	return fmt.Sprintf("LogfireRateLimitSampler{perSecond:%v,next:%s}", s.perSecond, s.next.Description())
+}
+
+// loadSamplingParams fills in the sampling configuration that wasn't set with an option.
+func (c *config) loadSamplingParams(params *paramManager) error {
+	rate, err := params.float("trace_sample_rate", c.traceSampleRate, 1)
+	if err != nil {
+		return err
+	}
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("logfire: invalid trace sample rate %v, expected a number between 0 and 1", rate)
+	}
+	c.traceSampleRate = &rate
+	if c.remoteSamplingOptions != nil && c.remoteSamplingOptions.URL == "" {
+		return errors.New("logfire: remote sampling without URL")
+	}
+	c.compiledSamplingRules = nil
+	for _, rule := range c.samplingRules {
+		compiled, err := compileSamplingRule(rule)
+		if err != nil {
+			return err
+		}
+		c.compiledSamplingRules = append(c.compiledSamplingRules, compiled)
+	}
+	return nil
+}
+
+// sampler returns the sampler of the tracer provider, or nil to keep the default one, which records every span.
+func (c *config) sampler() sdktrace.Sampler {
+	if c.remoteSampler != nil {
+		return c.remoteSampler
+	}
+	sampler, custom := c.buildSampler(*c.traceSampleRate, c.compiledSamplingRules)
+	if !custom {
+		return nil
+	}
+	return sampler
+}
+
+// buildSampler returns the sampler applying a sample rate and rules, and whether it differs from the default one.
+func (c *config) buildSampler(rate float64, rules []samplingRule) (sdktrace.Sampler, bool) {
+	root, custom := sdktrace.AlwaysSample(), false
+	if rate < 1 && c.tailSampling == nil {
+		root, custom = sdktrace.TraceIDRatioBased(rate), true
+	}
+	sampler := sdktrace.ParentBased(root)
+	if len(rules) > 0 {
+		sampler, custom = &ruleSampler{rules: rules, next: sampler}, true
+	}
+	if c.rootSpanRateLimit > 0 {
+		// After the others, so that the limit applies to the spans they keep.
+		sampler, custom = NewRateLimitSampler(c.rootSpanRateLimit, sampler), true
+	}
+	if c.baggageForceSampling {
+		// Before the rate limit, so that forced requests are recorded during spikes.
+		sampler, custom = &forceSampler{next: sampler}, true
+	}
+	return sampler, custom
+}
+
+// tailSamplingRandomRate returns the ratio of traces kept by tail sampling regardless of its criteria.
+// Without a sample rate, only the traces meeting them are kept.
+func (c *config) tailSamplingRandomRate() float64 {
+	if *c.traceSampleRate >= 1 {
+		return 0
+	}
+	return *c.traceSampleRate
+}
@@ -0,0 +1,152 @@
+package logfire
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// sampledSpans starts n root spans, each with a child, and returns the spans recorded.
+func sampledSpans(t *testing.T, n int, opts ...Option) []string {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	c, err := newConfig(append([]Option{WithSendToLogfire(false), WithConsole(false), WithAdditionalSpanProcessors(recorder)}, opts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.shutdown(context.Background())
+	tracer := p.tracerProvider.Tracer("test")
+	for range n {
+		ctx, root := tracer.Start(context.Background(), "root")
+		_, child := tracer.Start(ctx, "child")
+		child.End()
+		root.End()
+	}
+	var names []string
+	for _, s := range recorder.Ended() {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+func TestTraceSampleRate(t *testing.T) {
+	if got := sampledSpans(t, 10); len(got) != 20 {
+		t.Errorf("recorded %d spans by default, want all 20", len(got))
+	}
+	if got := sampledSpans(t, 10, WithTraceSampleRate(0)); len(got) != 0 {
+		t.Errorf("recorded %v with a sample rate of 0", got)
+	}
+	got := sampledSpans(t, 1000, WithTraceSampleRate(0.5))
+	if len(got) < 800 || len(got) > 1200 || len(got)%2 != 0 {
+		t.Errorf("recorded %d spans with a sample rate of 0.5, want about 1000 in complete traces", len(got))
+	}
+
+	t.Setenv("LOGFIRE_TRACE_SAMPLE_RATE", "0")
+	if got := sampledSpans(t, 10); len(got) != 0 {
+		t.Errorf("recorded %v with LOGFIRE_TRACE_SAMPLE_RATE=0", got)
+	}
+	t.Setenv("LOGFIRE_TRACE_SAMPLE_RATE", "2")
+	if _, err := newConfig(nil); err == nil {
+		t.Error("no error for a sample rate above 1")
+	}
+}
+
+func TestSamplingRules(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	c, err := newConfig([]Option{WithSendToLogfire(false), WithConsole(false), WithAdditionalSpanProcessors(recorder),
+		WithSamplingRules(
+			SamplingRule{Name: "GET /healthz", Rate: 0},
+			SamplingRule{Name: "SELECT pg_catalog%", Rate: 0},
+			SamplingRule{Name: "GET *", Rate: 1},
+		), WithTraceSampleRate(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.shutdown(context.Background())
+	tracer := p.tracerProvider.Tracer("test")
+	for _, name := range []string{"GET /healthz", "GET /users", "POST /users"} {
+		ctx, root := tracer.Start(context.Background(), name)
+		_, query := tracer.Start(ctx, "SELECT pg_catalog.pg_type")
+		query.End()
+		_, other := tracer.Start(ctx, "SELECT * FROM users")
+		other.End()
+		root.End()
+	}
+	var names []string
+	for _, s := range recorder.Ended() {
+		names = append(names, s.Name())
+	}
+	// The first rule wins, and the spans matching none follow the trace.
+	if len(names) != 2 || names[0] != "SELECT * FROM users" || names[1] != "GET /users" {
+		t.Errorf("recorded %v, want GET /users without its pg_catalog query", names)
+	}
+
+	if _, err := newConfig([]Option{WithSamplingRules(SamplingRule{Name: "*", Rate: 1.5})}); err == nil {
+		t.Error("no error for a rule rate above 1")
+	}
+}
+
+func TestRateLimitSampler(t *testing.T) {
+	s := NewRateLimitSampler(2, sdktrace.AlwaysSample()).(*rateLimitSampler)
+	now := time.Now()
+	for i, want := range []bool{true, true, false} {
+		if got := s.take("GET /", now); got != want {
+			t.Errorf("take %d = %t, want %t", i, got, want)
+		}
+	}
+	if !s.take("POST /", now) {
+		t.Error("names don't have their own limit")
+	}
+	if !s.take("GET /", now.Add(500*time.Millisecond)) || s.take("GET /", now.Add(500*time.Millisecond)) {
+		t.Error("want a single token after half a second")
+	}
+
+	// Child spans follow their root.
+	if got := sampledSpans(t, 100, WithRootSpanRateLimit(5)); len(got) < 10 || len(got) > 12 || len(got)%2 != 0 {
+		t.Errorf("recorded %d spans, want the 5 first traces", len(got))
+	}
+}
+
+func TestBaggageForceSampling(t *testing.T) {
+	member, _ := baggage.NewMember(SampleBaggageKey, SampleBaggageAlways)
+	bag, _ := baggage.New(member)
+	forced := baggage.ContextWithBaggage(context.Background(), bag)
+	for _, opts := range [][]Option{
+		{WithTraceSampleRate(0)},
+		{WithTailSampling(TailSamplingOptions{})},
+	} {
+		recorder := tracetest.NewSpanRecorder()
+		c, err := newConfig(append([]Option{WithSendToLogfire(false), WithConsole(false), WithAdditionalSpanProcessors(recorder),
+			WithBaggageForceSampling(true)}, opts...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := c.initialize(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracer := p.tracerProvider.Tracer("test")
+		for _, ctx := range []context.Context{context.Background(), forced} {
+			ctx, root := tracer.Start(ctx, "root")
+			_, child := tracer.Start(ctx, "child")
+			child.End()
+			root.End()
+		}
+		p.shutdown(context.Background())
+		if got := len(recorder.Ended()); got != 2 {
+			t.Errorf("recorded %d spans, want the forced trace only", got)
+		}
+	}
+}
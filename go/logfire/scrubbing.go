@@ -0,0 +1,473 @@
+package logfire
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// defaultScrubbingPatterns are the same patterns as the Python SDK.
+//
+// The Python SDK matches `auth(?!ors?\b)` so that "author" and "authors" aren't redacted.
+// RE2 doesn't support lookaheads, so `auth` is matched here and those words are skipped by [scrubber.search].
+var defaultScrubbingPatterns = []string{
+	`password`,
+	`passwd`,
+	`mysql_pwd`,
+	`secret`,
+	`auth`,
+	`credential`,
+	`private[._ -]?key`,
+	`api[._ -]?key`,
+	`session`,
+	`cookie`,
+	`csrf`,
+	`xsrf`,
+	`jwt`,
+	`ssn`,
+	`social[._ -]?security`,
+	`credit[._ -]?card`,
+}
+
+// safeScrubbingKeys are attribute keys whose values are kept even if they match a scrubbing pattern.
+var safeScrubbingKeys = map[attribute.Key]bool{
+	MsgKey:                         true, // Formatted values are scrubbed separately.
+	MsgTemplateKey:                 true,
+	"logfire.json_schema":          true,
+	"logfire.tags":                 true,
+	"logfire.level_name":           true,
+	LevelNumKey:                    true,
+	SpanTypeKey:                    true,
+	"logfire.pending_parent_id":    true,
+	"logfire.sample_rate":          true,
+	"logfire.null_args":            true,
+	"logfire.package_versions":     true,
+	codeFilepathKey:                true,
+	codeLinenoKey:                  true,
+	"code.function":                true,
+	semconv.ExceptionStacktraceKey: true, // See scrubEventAttributes.
+	semconv.ExceptionTypeKey:       true,
+	"schema_url":                   true,
+	"http.method":                  true,
+	"http.status_code":             true,
+	"http.scheme":                  true,
+	"http.url":                     true,
+	"http.target":                  true,
+	"http.route":                   true,
+	"db.statement":                 true,
+	"db.plan":                      true,
+	// The current semantic conventions for the same attributes.
+	semconv.HTTPRequestMethodKey:      true,
+	semconv.HTTPResponseStatusCodeKey: true,
+	semconv.URLSchemeKey:              true,
+	semconv.URLFullKey:                true,
+	semconv.URLPathKey:                true,
+	semconv.DBQueryTextKey:            true,
+}
+
+// ScrubbingOptions configures the redaction of sensitive data, see [WithScrubbing].
+type ScrubbingOptions struct {
+	// ExtraPatterns are regular expressions detecting sensitive data, in addition to the defaults
+	// such as `password`, `secret` and `api[._ -]?key`. They are matched case-insensitively.
+	ExtraPatterns []string
+
+	// Callback is called for each value about to be redacted. If it returns nil, the value is redacted,
+	// otherwise the returned value is used instead, e.g. the original match.Value to keep it.
+	// It may be called more than once for the same value, e.g. when a span starts and when it ends,
+	// and from multiple goroutines.
+	//
+	// For attributes, the returned value must be a string, bool, int, int64, float64,
+	// a slice of one of those or an [attribute.Value], otherwise the value is redacted.
+	Callback func(match ScrubMatch) any
+}
+
+// ScrubMatch describes a value about to be redacted, see [ScrubbingOptions.Callback].
+type ScrubMatch struct {
+	// Path is the location of the value in the span, made of string keys and int indices,
+	// e.g. `["attributes", "password"]` or `["otel_events", 0, "attributes", "exception.message"]`.
+	// It uses the same names as the Python SDK.
+	Path []any
+	// Value is the value about to be redacted, e.g. "my_password".
+	Value any
+	// PatternMatch is the text that matched a scrubbing pattern, e.g. "password".
+	PatternMatch string
+}
+
+// WithScrubbing configures the redaction of sensitive data from spans and logs before they are exported.
+//
+// Scrubbing is enabled by default: attributes whose key or string value matches one of the patterns
+// are replaced by a message such as `[Scrubbed due to 'password']`.
+func WithScrubbing(opts ScrubbingOptions) Option {
+	return func(c *config) {
+		c.scrubbingOptions = opts
+		c.scrubbingDisabled = false
+	}
+}
+
+// WithoutScrubbing disables the redaction of sensitive data.
+func WithoutScrubbing() Option {
+	return func(c *config) {
+		c.scrubbingDisabled = true
+	}
+}
+
+// scrubber redacts potentially sensitive data.
+type scrubber struct {
+	pattern  *regexp.Regexp
+	callback func(ScrubMatch) any
+}
+
+func newScrubber(opts ScrubbingOptions) (*scrubber, error) {
+	for _, p := range opts.ExtraPatterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("logfire: invalid scrubbing pattern %q: %w", p, err)
+		}
+	}
+	patterns := append(append([]string{}, defaultScrubbingPatterns...), opts.ExtraPatterns...)
+	pattern, err := regexp.Compile(`(?is)` + strings.Join(patterns, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("logfire: invalid scrubbing patterns: %w", err)
+	}
+	return &scrubber{pattern: pattern, callback: opts.Callback}, nil
+}
+
+// search returns the location of the first match of the scrubbing patterns in str, or nil.
+func (s *scrubber) search(str string) []int {
+	for offset := 0; offset < len(str); {
+		loc := s.pattern.FindStringIndex(str[offset:])
+		if loc == nil {
+			return nil
+		}
+		loc[0] += offset
+		loc[1] += offset
+		if !isAuthorWord(str, loc) {
+			return loc
+		}
+		offset = loc[1]
+	}
+	return nil
+}
+
+// isAuthorWord reports whether the match at loc is the `auth` of "author" or "authors".
+func isAuthorWord(str string, loc []int) bool {
+	if !strings.EqualFold(str[loc[0]:loc[1]], "auth") {
+		return false
+	}
+	rest := str[loc[1]:]
+	if len(rest) < 2 || !strings.EqualFold(rest[:2], "or") {
+		return false
+	}
+	rest = rest[2:]
+	if rest != "" && (rest[0] == 's' || rest[0] == 'S') {
+		rest = rest[1:]
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return rest == "" || !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func redacted(patternMatch string) string {
+	return fmt.Sprintf("[Scrubbed due to '%s']", patternMatch)
+}
+
+// redact returns the replacement for a match, from the callback if it returns a value.
+func (s *scrubber) redact(match ScrubMatch) any {
+	if s.callback != nil {
+		if result := s.callback(match); result != nil {
+			return result
+		}
+	}
+	return redacted(match.PatternMatch)
+}
+
+// redactAttribute is like redact, for a replacement that must be an attribute value.
+func (s *scrubber) redactAttribute(match ScrubMatch) attribute.Value {
+	if value, ok := attributeValue(s.redact(match)); ok {
+		return value
+	}
+	// Don't risk exporting the original value when the callback returns something unusable.
+	return attribute.StringValue(redacted(match.PatternMatch))
+}
+
+// attributeValue converts a value returned by a scrubbing callback to an attribute value.
+func attributeValue(v any) (attribute.Value, bool) {
+	switch v := v.(type) {
+	case attribute.Value:
+		return v, true
+	case string:
+		return attribute.StringValue(v), true
+	case bool:
+		return attribute.BoolValue(v), true
+	case int:
+		return attribute.IntValue(v), true
+	case int64:
+		return attribute.Int64Value(v), true
+	case float64:
+		return attribute.Float64Value(v), true
+	case []string:
+		return attribute.StringSliceValue(v), true
+	case []bool:
+		return attribute.BoolSliceValue(v), true
+	case []int:
+		return attribute.IntSliceValue(v), true
+	case []int64:
+		return attribute.Int64SliceValue(v), true
+	case []float64:
+		return attribute.Float64SliceValue(v), true
+	}
+	return attribute.Value{}, false
+}
+
+// appendPath returns a copy of path with elem appended, so that paths passed to the callback aren't shared.
+func appendPath(path []any, elem ...any) []any {
+	return append(append(make([]any, 0, len(path)+len(elem)), path...), elem...)
+}
+
+// scrubAttributes returns attrs with sensitive values redacted, and whether anything changed.
+func (s *scrubber) scrubAttributes(path []any, attrs []attribute.KeyValue) ([]attribute.KeyValue, bool) {
+	var result []attribute.KeyValue
+	for i, kv := range attrs {
+		scrubbed, changed := s.scrubAttribute(path, kv)
+		if changed && result == nil {
+			result = append(make([]attribute.KeyValue, 0, len(attrs)), attrs[:i]...)
+		}
+		if result != nil {
+			result = append(result, scrubbed)
+		}
+	}
+	if result == nil {
+		return attrs, false
+	}
+	return result, true
+}
+
+func (s *scrubber) scrubAttribute(path []any, kv attribute.KeyValue) (attribute.KeyValue, bool) {
+	if safeScrubbingKeys[kv.Key] {
+		return kv, false
+	}
+	key := string(kv.Key)
+	path = appendPath(path, key)
+	if loc := s.search(key); loc != nil {
+		value := s.redactAttribute(ScrubMatch{Path: path, Value: kv.Value.AsInterface(), PatternMatch: key[loc[0]:loc[1]]})
+		if value.Type() == attribute.STRING {
+			switch kv.Value.Type() {
+			case attribute.BOOLSLICE, attribute.INT64SLICE, attribute.FLOAT64SLICE, attribute.STRINGSLICE, attribute.SLICE:
+				value = attribute.StringSliceValue([]string{value.AsString()})
+			}
+		}
+		return attribute.KeyValue{Key: kv.Key, Value: value}, true
+	}
+	value, changed := s.scrubValue(path, kv.Value)
+	return attribute.KeyValue{Key: kv.Key, Value: value}, changed
+}
+
+// scrubValue redacts string values matching the patterns, recursing into slices and maps.
+func (s *scrubber) scrubValue(path []any, v attribute.Value) (attribute.Value, bool) {
+	switch v.Type() {
+	case attribute.STRING:
+		loc := s.search(v.AsString())
+		if loc == nil {
+			return v, false
+		}
+		scrubbed, changed := s.scrubString(path, v.AsString(), loc)
+		if !changed {
+			return v, false
+		}
+		if value, ok := attributeValue(scrubbed); ok {
+			return value, true
+		}
+		return attribute.StringValue(redacted(v.AsString()[loc[0]:loc[1]])), true
+	case attribute.STRINGSLICE:
+		values := v.AsStringSlice()
+		changed := false
+		for i, value := range values {
+			if loc := s.search(value); loc != nil {
+				scrubbed, c := s.scrubString(appendPath(path, i), value, loc)
+				if !c {
+					continue
+				}
+				if str, ok := scrubbed.(string); ok {
+					values[i] = str
+				} else {
+					values[i] = redacted(value[loc[0]:loc[1]])
+				}
+				changed = true
+			}
+		}
+		if !changed {
+			return v, false
+		}
+		return attribute.StringSliceValue(values), true
+	case attribute.SLICE:
+		values := v.AsSlice()
+		changed := false
+		for i, value := range values {
+			var c bool
+			values[i], c = s.scrubValue(appendPath(path, i), value)
+			changed = changed || c
+		}
+		if !changed {
+			return v, false
+		}
+		return attribute.SliceValue(values...), true
+	case attribute.MAP:
+		if kvs, changed := s.scrubAttributes(path, v.AsMap()); changed {
+			return attribute.MapValue(kvs...), true
+		}
+	}
+	return v, false
+}
+
+// scrubString redacts str, in which the patterns matched at loc.
+// The result is usually a string, but may be any value returned by the callback.
+func (s *scrubber) scrubString(path []any, str string, loc []int) (any, bool) {
+	if loc[0] == 0 && loc[1] == len(str) {
+		// If the whole string matches, e.g. the value is literally "password", it's considered safe.
+		return str, false
+	}
+	// JSON is scrubbed structurally so that only the sensitive parts are redacted.
+	var data any
+	if err := json.Unmarshal([]byte(str), &data); err == nil {
+		if encoded, err := json.Marshal(s.scrubJSON(path, data)); err == nil {
+			return string(encoded), true
+		}
+	}
+	return s.redact(ScrubMatch{Path: path, Value: str, PatternMatch: str[loc[0]:loc[1]]}), true
+}
+
+func (s *scrubber) scrubJSON(path []any, data any) any {
+	switch data := data.(type) {
+	case string:
+		if loc := s.search(data); loc != nil {
+			scrubbed, _ := s.scrubString(path, data, loc)
+			return scrubbed
+		}
+	case []any:
+		for i, value := range data {
+			data[i] = s.scrubJSON(appendPath(path, i), value)
+		}
+	case map[string]any:
+		for key, value := range data {
+			if safeScrubbingKeys[attribute.Key(key)] {
+				continue
+			}
+			if loc := s.search(key); loc != nil {
+				redacted := s.redact(ScrubMatch{Path: appendPath(path, key), Value: value, PatternMatch: key[loc[0]:loc[1]]})
+				if _, isSlice := value.([]any); isSlice {
+					if _, isString := redacted.(string); isString {
+						redacted = []any{redacted}
+					}
+				}
+				data[key] = redacted
+				continue
+			}
+			data[key] = s.scrubJSON(appendPath(path, key), value)
+		}
+	}
+	return data
+}
+
+// scrubEventAttributes scrubs the attributes of an event,
+// keeping exception stack traces except for the exception message they may end with.
+func (s *scrubber) scrubEventAttributes(path []any, attrs []attribute.KeyValue) ([]attribute.KeyValue, bool) {
+	scrubbed, changed := s.scrubAttributes(path, attrs)
+	if !changed {
+		return attrs, false
+	}
+	oldMessage, _ := lookupAttribute(attrs, string(semconv.ExceptionMessageKey))
+	newMessage, _ := lookupAttribute(scrubbed, string(semconv.ExceptionMessageKey))
+	stacktrace, ok := lookupAttribute(attrs, string(semconv.ExceptionStacktraceKey))
+	if !ok || stacktrace.Type() != attribute.STRING || oldMessage.Type() != attribute.STRING ||
+		newMessage.Type() != attribute.STRING || oldMessage.AsString() == newMessage.AsString() {
+		return scrubbed, true
+	}
+	trace := strings.TrimRightFunc(stacktrace.AsString(), unicode.IsSpace)
+	message := strings.TrimRightFunc(oldMessage.AsString(), unicode.IsSpace)
+	var newTrace attribute.Value
+	if strings.HasSuffix(trace, message) {
+		newTrace = attribute.StringValue(strings.TrimSuffix(trace, message) + newMessage.AsString())
+	} else {
+		// The stack trace doesn't look like we expect, so scrub the whole thing.
+		newTrace, _ = s.scrubValue(appendPath(path, string(semconv.ExceptionStacktraceKey)), attribute.StringValue(trace))
+	}
+	for i, kv := range scrubbed {
+		if kv.Key == semconv.ExceptionStacktraceKey {
+			scrubbed[i] = attribute.KeyValue{Key: kv.Key, Value: newTrace}
+		}
+	}
+	return scrubbed, true
+}
+
+// scrubbingSpanProcessor redacts sensitive data before spans reach the wrapped processors,
+// like the MainSpanProcessorWrapper of the Python SDK.
+type scrubbingSpanProcessor struct {
+	scrubber   *scrubber
+	processors []sdktrace.SpanProcessor
+}
+
+var _ sdktrace.SpanProcessor = (*scrubbingSpanProcessor)(nil)
+
+// OnStart scrubs the attributes the span was started with in place,
+// since processors such as the console print spans when they start.
+func (p *scrubbingSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if scrubbed, changed := p.scrubber.scrubAttributes([]any{"attributes"}, s.Attributes()); changed {
+		s.SetAttributes(scrubbed...)
+	}
+	for _, processor := range p.processors {
+		processor.OnStart(parent, s)
+	}
+}
+
+// OnEnd passes a scrubbed view of the span to the wrapped processors,
+// since ended spans can't be modified.
+func (p *scrubbingSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	scrubbed := &scrubbedSpan{ReadOnlySpan: s}
+	scrubbed.attributes, _ = p.scrubber.scrubAttributes([]any{"attributes"}, s.Attributes())
+	scrubbed.events = s.Events()
+	for i, event := range scrubbed.events {
+		scrubbed.events[i].Attributes, _ = p.scrubber.scrubEventAttributes([]any{"otel_events", i, "attributes"}, event.Attributes)
+	}
+	scrubbed.links = s.Links()
+	for i, link := range scrubbed.links {
+		scrubbed.links[i].Attributes, _ = p.scrubber.scrubAttributes([]any{"links", i, "attributes"}, link.Attributes)
+	}
+	for _, processor := range p.processors {
+		processor.OnEnd(scrubbed)
+	}
+}
+
+func (p *scrubbingSpanProcessor) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, processor := range p.processors {
+		errs = append(errs, processor.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *scrubbingSpanProcessor) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, processor := range p.processors {
+		errs = append(errs, processor.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// scrubbedSpan is an ended span with scrubbed attributes, events and links.
+type scrubbedSpan struct {
+	sdktrace.ReadOnlySpan
+	attributes []attribute.KeyValue
+	events     []sdktrace.Event
+	links      []sdktrace.Link
+}
+
+func (s *scrubbedSpan) Attributes() []attribute.KeyValue { return s.attributes }
+func (s *scrubbedSpan) Events() []sdktrace.Event         { return s.events }
+func (s *scrubbedSpan) Links() []sdktrace.Link           { return s.links }
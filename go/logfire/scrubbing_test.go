@@ -0,0 +1,198 @@
+package logfire
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func TestScrubAttributes(t *testing.T) {
+	recorder := configureForTest(t)
+
+	Info(context.Background(), "Password: {user_password}",
+		attribute.StringSlice("user_password", []string{"hunter2"}),
+		attribute.String("mode", "password"),
+		attribute.String("modes", "passwords"),
+		attribute.String("Author", "Alice1"),
+		attribute.String("authors", "Alice2"),
+		attribute.String("authr", "Alice3"),
+		attribute.String("authorization", "Alice4"),
+		attribute.String("query", `{"user": "alice", "api_key": "abc"}`),
+		attribute.String("note", "my secret is 42"),
+	)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	attrs := oteltest.Attributes(spans[0].Attributes())
+	for key, want := range map[attribute.Key]any{
+		MsgTemplateKey:  "Password: {user_password}",
+		MsgKey:          `Password: ["hunter2"]`,
+		"user_password": []string{"[Scrubbed due to 'password']"},
+		"mode":          "password",
+		"modes":         "[Scrubbed due to 'password']",
+		"Author":        "Alice1",
+		"authors":       "Alice2",
+		"authr":         "[Scrubbed due to 'auth']",
+		"authorization": "[Scrubbed due to 'auth']",
+		"query":         `{"api_key":"[Scrubbed due to 'api_key']","user":"alice"}`,
+		"note":          "[Scrubbed due to 'secret']",
+	} {
+		if got := attrs[key]; !equalValues(got, want) {
+			t.Errorf("attribute %s = %#v, want %#v", key, got, want)
+		}
+	}
+}
+
+func equalValues(got, want any) bool {
+	if want, ok := want.([]string); ok {
+		got, ok := got.([]string)
+		if !ok || len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return got == want
+}
+
+func TestScrubMessage(t *testing.T) {
+	recorder := configureForTest(t)
+
+	Info(context.Background(), "User: {user}", attribute.String("user", "alice's password is hunter2"))
+
+	if msg := oteltest.Attributes(recorder.Ended()[0].Attributes())[MsgKey]; msg != "User: [Scrubbed due to 'password']" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestScrubAttributesSetAfterStart(t *testing.T) {
+	recorder := configureForTest(t)
+
+	_, span := Span(context.Background(), "request")
+	span.SetAttributes(attribute.String("session_id", "abc"))
+	span.RecordError(errors.New("invalid secret: hunter2"), trace.WithAttributes(
+		semconv.ExceptionStacktrace("goroutine 1 [running]:\nmain.main()\ninvalid secret: hunter2"),
+	))
+	span.AddLink(trace.Link{Attributes: []attribute.KeyValue{attribute.String("cookie", "abc")}})
+	span.End()
+
+	ended := recorder.Ended()[0]
+	if got := oteltest.Attributes(ended.Attributes())["session_id"]; got != "[Scrubbed due to 'session']" {
+		t.Errorf("session_id = %v", got)
+	}
+	event := ended.Events()[0]
+	for _, kv := range event.Attributes {
+		switch kv.Key {
+		case semconv.ExceptionMessageKey:
+			if got := kv.Value.AsString(); got != "[Scrubbed due to 'secret']" {
+				t.Errorf("exception message = %q", got)
+			}
+		case semconv.ExceptionStacktraceKey:
+			if got, want := kv.Value.AsString(), "goroutine 1 [running]:\nmain.main()\n[Scrubbed due to 'secret']"; got != want {
+				t.Errorf("stacktrace = %q, want %q", got, want)
+			}
+		}
+	}
+	if got := ended.Links()[0].Attributes[0].Value.AsString(); got != "[Scrubbed due to 'cookie']" {
+		t.Errorf("link attribute = %q", got)
+	}
+}
+
+func TestScrubbingExtraPatterns(t *testing.T) {
+	recorder := configureForTest(t, WithScrubbing(ScrubbingOptions{ExtraPatterns: []string{"my_pattern"}}))
+
+	Info(context.Background(), "hi", attribute.String("my_pattern_field", "x"), attribute.String("password", "y"))
+
+	attrs := oteltest.Attributes(recorder.Ended()[0].Attributes())
+	if attrs["my_pattern_field"] != "[Scrubbed due to 'my_pattern']" || attrs["password"] != "[Scrubbed due to 'password']" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
+
+func TestScrubbingDisabled(t *testing.T) {
+	recorder := configureForTest(t, WithoutScrubbing())
+
+	Info(context.Background(), "{password}", attribute.String("password", "my password"))
+
+	attrs := oteltest.Attributes(recorder.Ended()[0].Attributes())
+	if attrs["password"] != "my password" || attrs[MsgKey] != "my password" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
+
+func TestInvalidScrubbingPattern(t *testing.T) {
+	_, err := Configure(context.Background(), WithSendToLogfire(false), WithConsole(false),
+		WithScrubbing(ScrubbingOptions{ExtraPatterns: []string{"("}}))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestScrubbingCallback(t *testing.T) {
+	var paths [][]any
+	recorder := configureForTest(t, WithScrubbing(ScrubbingOptions{
+		Callback: func(match ScrubMatch) any {
+			paths = append(paths, match.Path)
+			switch {
+			case match.Path[len(match.Path)-1] == "token_count":
+				return match.Value
+			case match.PatternMatch == "password":
+				return "***"
+			}
+			return nil
+		},
+		ExtraPatterns: []string{"token"},
+	}))
+
+	Info(context.Background(), "hi",
+		attribute.Int("token_count", 12),
+		attribute.String("password", "hunter2"),
+		attribute.String("secret", "hunter2"),
+		attribute.StringSlice("notes", []string{"ok", "the password is hunter2"}),
+	)
+
+	attrs := oteltest.Attributes(recorder.Ended()[0].Attributes())
+	for key, want := range map[attribute.Key]any{
+		"token_count": int64(12),
+		"password":    "***",
+		"secret":      "[Scrubbed due to 'secret']",
+		"notes":       []string{"ok", "***"},
+	} {
+		if got := attrs[key]; !equalValues(got, want) {
+			t.Errorf("attribute %s = %#v, want %#v", key, got, want)
+		}
+	}
+	found := false
+	for _, path := range paths {
+		if len(path) == 3 && path[0] == "attributes" && path[1] == "notes" && path[2] == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("unexpected paths %v", paths)
+	}
+}
+
+func TestScrubbingCallbackInvalidValue(t *testing.T) {
+	recorder := configureForTest(t, WithScrubbing(ScrubbingOptions{
+		Callback: func(ScrubMatch) any { return struct{}{} },
+	}))
+
+	Info(context.Background(), "hi", attribute.String("password", "hunter2"))
+
+	if got := oteltest.Attributes(recorder.Ended()[0].Attributes())["password"]; got != "[Scrubbed due to 'password']" {
+		t.Errorf("password = %v", got)
+	}
+}
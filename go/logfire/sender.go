@@ -0,0 +1,177 @@
+package logfire
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Signals of encoded OTLP requests, which are the paths of their HTTP endpoints after `/v1/`.
+const (
+	signalTraces  = "traces"
+	signalMetrics = "metrics"
+)
+
+// sendTimeout is the timeout of each attempt to send a request, like the default one of the exporters.
+const sendTimeout = 10 * time.Second
+
+// otlpSender sends encoded OTLP requests with the protocol of a configuration,
+// for the disk queue and backfills, which can't go through the OTLP exporters.
+type otlpSender struct {
+	c      *config
+	client *http.Client
+	conn   *grpc.ClientConn
+}
+
+func newOTLPSender(c *config) *otlpSender {
+	client := c.httpClient()
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &otlpSender{c: c, client: client}
+}
+
+// send sends an encoded `ExportTraceServiceRequest` or `ExportMetricsServiceRequest`, depending on signal.
+// Errors are a *permanentError if retrying wouldn't help, or a *throttledError if Logfire requested a delay.
+func (s *otlpSender) send(ctx context.Context, signal string, body []byte) error {
+	if s.c.protocol == ProtocolGRPC {
+		return s.sendGRPC(ctx, signal, body)
+	}
+	return s.sendHTTP(ctx, signal, body)
+}
+
+func (s *otlpSender) close() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// permanentError is an error of an export that fails the same way when retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// throttledError is an error of an export which Logfire asked to retry after a delay.
+type throttledError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *throttledError) Error() string { return e.err.Error() }
+
+func (e *throttledError) Unwrap() error { return e.err }
+
+func (s *otlpSender) sendHTTP(ctx context.Context, signal string, body []byte) error {
+	encoding := ""
+	if s.c.compression == CompressionGzip {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(body)
+		w.Close()
+		body, encoding = buf.Bytes(), "gzip"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.c.endpoint("/v1/"+signal), bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{fmt.Errorf("logfire: sending %s: %w", signal, err)}
+	}
+	for k, v := range s.c.headers() {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("logfire: sending %s: %w", signal, err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("logfire: sending %s: %s", signal, resp.Status)
+	if !retryableStatus(resp.StatusCode) {
+		return &permanentError{err}
+	}
+	if delay, ok := retryAfter(resp); ok {
+		return &throttledError{err, delay}
+	}
+	return err
+}
+
+func (s *otlpSender) sendGRPC(ctx context.Context, signal string, body []byte) error {
+	conn, err := s.grpcConn()
+	if err != nil {
+		return &permanentError{fmt.Errorf("logfire: sending %s: %w", signal, err)}
+	}
+	ctx = metadata.NewOutgoingContext(ctx, metadata.New(s.c.headers()))
+	var opts []grpc.CallOption
+	if s.c.compression != CompressionNone {
+		opts = append(opts, grpc.UseCompressor(string(s.c.compression)))
+	}
+	switch signal {
+	case signalTraces:
+		var req collectortrace.ExportTraceServiceRequest
+		if err = proto.Unmarshal(body, &req); err == nil {
+			_, err = collectortrace.NewTraceServiceClient(conn).Export(ctx, &req, opts...)
+		}
+	case signalMetrics:
+		var req collectormetrics.ExportMetricsServiceRequest
+		if err = proto.Unmarshal(body, &req); err == nil {
+			_, err = collectormetrics.NewMetricsServiceClient(conn).Export(ctx, &req, opts...)
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	code := status.Code(err)
+	err = fmt.Errorf("logfire: sending %s: %w", signal, err)
+	switch code {
+	case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Unavailable:
+		return err
+	}
+	return &permanentError{err}
+}
+
+// grpcConn returns the connection of the sender, created on first use with the settings of the gRPC exporters.
+func (s *otlpSender) grpcConn() (*grpc.ClientConn, error) {
+	if s.conn != nil {
+		return s.conn, nil
+	}
+	u, err := url.Parse(s.c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	creds := credentials.NewTLS(s.c.tlsConfig)
+	if u.Scheme == "http" {
+		creds = insecure.NewCredentials()
+	}
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, s.c.grpcDialOptions...)
+	if s.c.dialer != nil {
+		opts = append(opts, s.c.grpcDialer())
+	}
+	if s.conn, err = grpc.NewClient(u.Host, opts...); err != nil {
+		return nil, err
+	}
+	return s.conn, nil
+}
@@ -0,0 +1,61 @@
+package logfire
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout is the default time [ShutdownOnSignal] waits for telemetry to be flushed,
+// well within the 30 seconds Kubernetes waits before killing a pod.
+const DefaultShutdownTimeout = 5 * time.Second
+
+// WithShutdownTimeout sets how long [ShutdownOnSignal] waits for the pending spans and metrics to be exported.
+// Defaults to [DefaultShutdownTimeout].
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.shutdownTimeout = timeout
+	}
+}
+
+// ShutdownOnSignal flushes the telemetry of the last [Configure] when the process receives SIGINT or SIGTERM,
+// e.g. when Kubernetes stops a pod, so that the spans of its last seconds aren't lost.
+//
+// The returned context is canceled once they are flushed, or after the timeout set with [WithShutdownTimeout],
+// so that the program can proceed with its own shutdown, calling the shutdown function returned by Configure
+// last. Like with [signal.NotifyContext], the program is then in charge of exiting, and a second signal
+// kills it at once.
+func ShutdownOnSignal(ctx context.Context) context.Context {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		flushOnSignal(ctx, signals, func() { signal.Stop(signals) })
+	}()
+	return ctx
+}
+
+// flushOnSignal flushes the global providers when a signal is received, unless ctx is done first. It calls
+// stop in both cases, before flushing so that a second signal kills the program if the flush hangs.
+func flushOnSignal(ctx context.Context, signals <-chan os.Signal, stop func()) {
+	select {
+	case <-signals:
+		stop()
+	case <-ctx.Done():
+		stop()
+		return
+	}
+	p := globalProviders()
+	if p == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.shutdownTimeout)
+	defer cancel()
+	if err := p.forceFlush(ctx); err != nil {
+		p.diagnostics.error(fmt.Errorf("logfire: flushing on signal: %w", err))
+	}
+}
@@ -0,0 +1,58 @@
+package logfire
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+func TestFlushOnSignal(t *testing.T) {
+	var exported, stopped, stoppedFirst atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			exported.Store(true)
+			stoppedFirst.Store(stopped.Load())
+		}
+	}))
+	defer srv.Close()
+	c, err := newConfig([]Option{WithToken("test-token"), WithBaseURL(srv.URL), WithConsole(false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.shutdown(context.Background())
+	global.mu.Lock()
+	global.providers = p
+	global.mu.Unlock()
+	defer func() {
+		global.mu.Lock()
+		global.providers = nil
+		global.mu.Unlock()
+	}()
+
+	_, span := p.tracerProvider.Tracer("test").Start(context.Background(), "hello")
+	span.End()
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGTERM
+	flushOnSignal(context.Background(), signals, func() { stopped.Store(true) })
+	if !exported.Load() {
+		t.Error("span not exported after the signal")
+	}
+	if !stoppedFirst.Load() {
+		t.Error("signals not stopped before flushing, a second signal wouldn't kill the program")
+	}
+}
+
+func TestShutdownOnSignalCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signalCtx := ShutdownOnSignal(ctx)
+	cancel()
+	<-signalCtx.Done()
+}
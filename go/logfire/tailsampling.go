@@ -0,0 +1,205 @@
+package logfire
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TailSamplingOptions configures which traces are kept by tail sampling, see [WithTailSampling].
+type TailSamplingOptions struct {
+	// Level keeps the traces containing a span or log of this level or above. Defaults to LevelNotice.
+	// A negative level keeps traces based on their duration only.
+	Level Level
+	// Duration keeps the traces lasting longer than this. Defaults to one second.
+	// A negative duration keeps traces based on their level only.
+	Duration time.Duration
+	// Errors also keeps the traces containing a span which ended with an error status,
+	// e.g. set by an instrumentation which doesn't record levels.
+	Errors bool
+}
+
+// WithTailSampling only exports the traces which contain a notable span or log or take long,
+// like `SamplingOptions.level_or_duration` in the Python SDK, discarding the others.
+//
+// Spans are buffered in memory until their trace meets one of the criteria, and discarded when its root
+// span ends otherwise. Traces continued from another service aren't buffered, since their root
+// isn't in this process. With [WithTraceSampleRate], that ratio of the other traces is kept too.
+func WithTailSampling(opts TailSamplingOptions) Option {
+	return func(c *config) {
+		if opts.Level == 0 {
+			opts.Level = LevelNotice
+		}
+		if opts.Duration == 0 {
+			opts.Duration = time.Second
+		}
+		c.tailSampling = &opts
+	}
+}
+
+// WithErrorSampling keeps every trace containing an error, and the given ratio of the others,
+// deciding once their root span ends. It's a shorthand for tail sampling on error status and level only:
+//
+//	WithTailSampling(TailSamplingOptions{Level: LevelError, Duration: -1, Errors: true}), WithTraceSampleRate(rate)
+func WithErrorSampling(rate float64) Option {
+	return func(c *config) {
+		if rate < 1 {
+			// Otherwise every trace is kept, so there's no need to buffer them.
+			WithTailSampling(TailSamplingOptions{Level: LevelError, Duration: -1, Errors: true})(c)
+		}
+		WithTraceSampleRate(rate)(c)
+	}
+}
+
+// traceBuffer holds the spans of a trace which hasn't met the tail sampling criteria yet.
+type traceBuffer struct {
+	start   time.Time
+	started []startedSpan
+	ended   []sdktrace.ReadOnlySpan
+}
+
+type startedSpan struct {
+	parent context.Context
+	span   sdktrace.ReadWriteSpan
+}
+
+// tailSamplingSpanProcessor passes the spans of a trace to the wrapped processors once any of them
+// meets the sampling criteria, like the TailSamplingProcessor of the Python SDK.
+type tailSamplingSpanProcessor struct {
+	processors []sdktrace.SpanProcessor
+	level      Level
+	duration   time.Duration
+	errors     bool
+	// randomRate is the ratio of traces included regardless of the criteria.
+	randomRate float64
+	// baggageForce passes through the traces whose sampling is forced by baggage.
+	baggageForce bool
+
+	mu sync.Mutex
+	// traces are the buffers of the traces which haven't met the criteria. Spans of other traces
+	// are passed through at once.
+	traces map[trace.TraceID]*traceBuffer
+}
+
+var _ sdktrace.SpanProcessor = (*tailSamplingSpanProcessor)(nil)
+
+func newTailSamplingSpanProcessor(processors []sdktrace.SpanProcessor, opts TailSamplingOptions, randomRate float64, baggageForce bool) *tailSamplingSpanProcessor {
+	p := &tailSamplingSpanProcessor{
+		processors:   processors,
+		level:        opts.Level,
+		duration:     opts.Duration,
+		errors:       opts.Errors,
+		randomRate:   randomRate,
+		baggageForce: baggageForce,
+		traces:       map[trace.TraceID]*traceBuffer{},
+	}
+	if p.level < 0 {
+		p.level = math.MaxInt
+	}
+	if p.duration < 0 {
+		p.duration = math.MaxInt64
+	}
+	return p
+}
+
+func (p *tailSamplingSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	traceID := s.SpanContext().TraceID()
+	p.mu.Lock()
+	if !s.Parent().IsValid() && rand.Float64() >= p.randomRate && !(p.baggageForce && forcedSampling(parent)) {
+		p.traces[traceID] = &traceBuffer{start: s.StartTime()}
+	}
+	buffer := p.traces[traceID]
+	var met bool
+	if buffer != nil {
+		buffer.started = append(buffer.started, startedSpan{parent, s})
+		met = p.meets(s, s.StartTime(), buffer)
+	}
+	p.mu.Unlock()
+
+	// The wrapped processors are called without the lock, since they may take time.
+	if buffer == nil {
+		for _, processor := range p.processors {
+			processor.OnStart(parent, s)
+		}
+	} else if met {
+		p.push(buffer)
+	}
+}
+
+func (p *tailSamplingSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	traceID := s.SpanContext().TraceID()
+	p.mu.Lock()
+	buffer := p.traces[traceID]
+	var met bool
+	if buffer != nil {
+		buffer.ended = append(buffer.ended, s)
+		met = p.meets(s, s.EndTime(), buffer)
+		if !s.Parent().IsValid() {
+			// The trace is complete, and is discarded unless it has just met the criteria.
+			delete(p.traces, traceID)
+		}
+	}
+	p.mu.Unlock()
+
+	if buffer == nil {
+		for _, processor := range p.processors {
+			processor.OnEnd(s)
+		}
+	} else if met {
+		p.push(buffer)
+	}
+}
+
+// meets reports whether a span of a buffered trace meets the criteria, in which case the buffer is removed
+// so that the following spans pass through. It's called with the lock held.
+func (p *tailSamplingSpanProcessor) meets(s sdktrace.ReadOnlySpan, t time.Time, buffer *traceBuffer) bool {
+	level := LevelInfo
+	for _, kv := range s.Attributes() {
+		if kv.Key == LevelNumKey {
+			level = Level(kv.Value.AsInt64())
+		}
+	}
+	failed := p.errors && s.Status().Code == codes.Error
+	if t.Sub(buffer.start) <= p.duration && level < p.level && !failed {
+		return false
+	}
+	delete(p.traces, s.SpanContext().TraceID())
+	return true
+}
+
+// push passes the buffered spans of a trace which met the criteria to the wrapped processors.
+func (p *tailSamplingSpanProcessor) push(buffer *traceBuffer) {
+	for _, started := range buffer.started {
+		for _, processor := range p.processors {
+			processor.OnStart(started.parent, started.span)
+		}
+	}
+	for _, s := range buffer.ended {
+		for _, processor := range p.processors {
+			processor.OnEnd(s)
+		}
+	}
+}
+
+func (p *tailSamplingSpanProcessor) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, processor := range p.processors {
+		errs = append(errs, processor.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *tailSamplingSpanProcessor) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, processor := range p.processors {
+		errs = append(errs, processor.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
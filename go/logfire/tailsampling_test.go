@@ -0,0 +1,118 @@
+package logfire
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTailSampling(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	c, err := newConfig([]Option{WithSendToLogfire(false), WithConsole(false), WithAdditionalSpanProcessors(recorder),
+		WithTailSampling(TailSamplingOptions{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.shutdown(context.Background())
+	tracer := p.tracerProvider.Tracer("test")
+	ended := func() map[string]int {
+		names := map[string]int{}
+		for _, s := range recorder.Ended() {
+			names[s.Name()]++
+		}
+		return names
+	}
+
+	// A boring trace is discarded.
+	ctx, root := tracer.Start(context.Background(), "boring")
+	_, child := tracer.Start(ctx, "boring child")
+	child.End()
+	root.End()
+	if got := ended(); len(got) != 0 {
+		t.Errorf("recorded %v for a boring trace", got)
+	}
+
+	// A warning keeps the whole trace, including the spans before it.
+	ctx, root = tracer.Start(context.Background(), "warned")
+	_, child = tracer.Start(ctx, "warned child")
+	child.End()
+	_, warning := tracer.Start(ctx, "warning", trace.WithAttributes(LevelNumKey.Int(int(LevelWarn))))
+	warning.End()
+	_, after := tracer.Start(ctx, "after")
+	after.End()
+	root.End()
+	if got := ended(); got["warned"] != 1 || got["warned child"] != 1 || got["warning"] != 1 || got["after"] != 1 {
+		t.Errorf("recorded %v, want the whole warned trace", got)
+	}
+
+	// So does a long duration.
+	start := time.Now()
+	_, root = tracer.Start(context.Background(), "slow", trace.WithTimestamp(start))
+	root.End(trace.WithTimestamp(start.Add(2 * time.Second)))
+	if got := ended(); got["slow"] != 1 {
+		t.Errorf("recorded %v, want the slow trace", got)
+	}
+
+	// A trace continued from another service isn't buffered.
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled, Remote: true,
+	})
+	_, root = tracer.Start(trace.ContextWithRemoteSpanContext(context.Background(), remote), "remote")
+	root.End()
+	if got := ended(); got["remote"] != 1 || got["boring"] != 0 {
+		t.Errorf("recorded %v, want the remote trace", got)
+	}
+}
+
+func TestTailSamplingRandomRate(t *testing.T) {
+	if got := sampledSpans(t, 10, WithTailSampling(TailSamplingOptions{}), WithTraceSampleRate(1)); len(got) != 0 {
+		t.Errorf("recorded %v, want only the traces meeting the criteria", got)
+	}
+	got := sampledSpans(t, 1000, WithTailSampling(TailSamplingOptions{}), WithTraceSampleRate(0.5))
+	if len(got) < 800 || len(got) > 1200 || len(got)%2 != 0 {
+		t.Errorf("recorded %d spans with a sample rate of 0.5, want about 1000 in complete traces", len(got))
+	}
+}
+
+func TestErrorSampling(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	c, err := newConfig([]Option{WithSendToLogfire(false), WithConsole(false), WithAdditionalSpanProcessors(recorder),
+		WithErrorSampling(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.shutdown(context.Background())
+	tracer := p.tracerProvider.Tracer("test")
+	for _, name := range []string{"ok", "failed"} {
+		ctx, root := tracer.Start(context.Background(), name)
+		_, child := tracer.Start(ctx, name+" child")
+		if name == "failed" {
+			child.SetStatus(codes.Error, "boom")
+		}
+		child.End()
+		// Slow traces aren't kept.
+		root.End(trace.WithTimestamp(time.Now().Add(time.Hour)))
+	}
+	var names []string
+	for _, s := range recorder.Ended() {
+		names = append(names, s.Name())
+	}
+	if len(names) != 2 || names[0] != "failed child" || names[1] != "failed" {
+		t.Errorf("recorded %v, want the failed trace only", names)
+	}
+	if got := sampledSpans(t, 10, WithErrorSampling(1)); len(got) != 20 {
+		t.Errorf("recorded %d spans with a rate of 1, want all 20", len(got))
+	}
+}
@@ -0,0 +1,141 @@
+package logfire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// TokenCheck is whether [Configure] checks the token with the Logfire API, see [WithTokenCheck].
+type TokenCheck string
+
+// Possible values of [TokenCheck].
+const (
+	// TokenCheckOff doesn't check the token, which is the default.
+	TokenCheckOff TokenCheck = "off"
+	// TokenCheckWarn checks the token in the background and reports problems as diagnostics,
+	// see [WithDiagnostics], without delaying Configure.
+	TokenCheckWarn TokenCheck = "warn"
+	// TokenCheckFail checks the token before Configure returns, which fails if the check does.
+	TokenCheckFail TokenCheck = "fail"
+)
+
+// ErrInvalidToken is returned by [Configure] when the token check finds that Logfire rejects the token.
+var ErrInvalidToken = errors.New("logfire: invalid token, check that it's a write token of the project, " +
+	"which can be created in the project settings or with `logfire projects use`")
+
+// ErrLogfireUnreachable is returned by [Configure] when the token check can't reach the Logfire API,
+// or gets an unexpected response from it.
+var ErrLogfireUnreachable = errors.New("logfire: the Logfire API is unreachable")
+
+// tokenCheckTimeout is the timeout of each request of the token check.
+const tokenCheckTimeout = 10 * time.Second
+
+// WithTokenCheck makes [Configure] check the token with the Logfire API, like the Python SDK does,
+// so that a wrong token fails or is reported at startup instead of silently dropping telemetry.
+//
+// The errors distinguish an invalid token ([ErrInvalidToken]), a token of another data region
+// ([ErrRegionMismatch]) and network problems ([ErrLogfireUnreachable]). The check is made over HTTP,
+// so it's skipped with [ProtocolGRPC].
+func WithTokenCheck(check TokenCheck) Option {
+	return func(c *config) {
+		c.tokenCheck = check
+	}
+}
+
+func (c TokenCheck) valid() bool {
+	switch c {
+	case TokenCheckOff, TokenCheckWarn, TokenCheckFail:
+		return true
+	}
+	return false
+}
+
+// tokenChecker holds what the token check needs, copied from the config before the check runs in the
+// background, while [Configure] goes on initializing the config.
+type tokenChecker struct {
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (c *config) tokenChecker() tokenChecker {
+	client := c.httpClient()
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return tokenChecker{baseURL: c.baseURL, headers: c.headers(), client: client}
+}
+
+// check checks the token with the Logfire API, returning an error explaining why it can't be used.
+func (t tokenChecker) check(ctx context.Context) error {
+	status, err := t.infoStatus(ctx, t.baseURL)
+	if err != nil {
+		return fmt.Errorf("%w at %s, you may have trouble sending data: %w", ErrLogfireUnreachable, t.baseURL, err)
+	}
+	switch status {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		if region := t.otherTokenRegion(ctx); region != "" {
+			return fmt.Errorf("%w: the token is for the %q region but data would be sent to %s, use WithRegion(%q)",
+				ErrRegionMismatch, region, t.baseURL, region)
+		}
+		return fmt.Errorf("%w (sending to %s)", ErrInvalidToken, t.baseURL)
+	}
+	return fmt.Errorf("%w at %s, you may have trouble sending data: unexpected status %d", ErrLogfireUnreachable, t.baseURL, status)
+}
+
+// otherTokenRegion returns the data region whose API accepts the token, when it was rejected by the
+// one of the base URL. Old tokens don't encode their region, so a token of another region is only
+// detected this way.
+func (t tokenChecker) otherTokenRegion(ctx context.Context) string {
+	current := baseURLRegion(t.baseURL)
+	if current == "" {
+		// A proxy or a self-hosted deployment, whose tokens aren't those of the regions.
+		return ""
+	}
+	regions := make([]string, 0, len(regionBaseURLs))
+	for region := range regionBaseURLs {
+		if region != current {
+			regions = append(regions, region)
+		}
+	}
+	sort.Strings(regions)
+	for _, region := range regions {
+		if status, err := t.infoStatus(ctx, regionBaseURLs[region]); err == nil && status == http.StatusOK {
+			return region
+		}
+	}
+	return ""
+}
+
+// infoStatus returns the status of the `/v1/info` endpoint of the Logfire API at baseURL,
+// which returns the project of the token.
+func (t tokenChecker) infoStatus(ctx context.Context, baseURL string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, tokenCheckTimeout)
+	defer cancel()
+	endpoint, err := url.JoinPath(baseURL, "/v1/info")
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,107 @@
+package logfire
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// infoServer returns a Logfire API accepting only the given token.
+func infoServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/info" {
+			return
+		}
+		if r.Header.Get("Authorization") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"project_name": "test", "project_url": "https://logfire.pydantic.dev/test/test"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTokenCheck(t *testing.T) {
+	us := infoServer(t, "us-token")
+	eu := infoServer(t, "eu-token")
+	defer func(urls map[string]string) { regionBaseURLs = urls }(regionBaseURLs)
+	regionBaseURLs = map[string]string{"us": us.URL, "eu": eu.URL}
+
+	for _, tc := range []struct {
+		name    string
+		token   string
+		baseURL string
+		want    error
+	}{
+		{"valid", "us-token", us.URL, nil},
+		{"invalid", "wrong-token", us.URL, ErrInvalidToken},
+		{"other region", "eu-token", us.URL, ErrRegionMismatch},
+		{"unreachable", "us-token", "http://127.0.0.1:1", ErrLogfireUnreachable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := newConfig([]Option{WithToken(tc.token), WithBaseURL(tc.baseURL), WithConsole(false),
+				WithTokenCheck(TokenCheckFail)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := c.initialize(context.Background())
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("error = %v, want %v", err, tc.want)
+			}
+			if p != nil {
+				p.shutdown(context.Background())
+			}
+		})
+	}
+}
+
+func TestTokenCheckWarn(t *testing.T) {
+	srv := infoServer(t, "test-token")
+	errs := make(chan error, 10)
+	c, err := newConfig([]Option{WithToken("wrong-token"), WithBaseURL(srv.URL), WithConsole(false),
+		WithTokenCheck(TokenCheckWarn), WithDiagnostics(DiagnosticsOptions{Callback: func(d Diagnostic) {
+			errs <- d.Err
+		}})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatalf("configuring failed with a warning: %v", err)
+	}
+	defer p.shutdown(context.Background())
+	if err := <-errs; !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("diagnostic = %v, want an invalid token", err)
+	}
+}
+
+// TestTokenCheckWarnInitializing checks, with -race, that the background check doesn't read the config
+// while Configure initializes the disk queue and the remote sampler.
+func TestTokenCheckWarnInitializing(t *testing.T) {
+	srv := infoServer(t, "test-token")
+	errs := make(chan error, 10)
+	c, err := newConfig([]Option{WithToken("wrong-token"), WithBaseURL(srv.URL), WithConsole(false),
+		WithTokenCheck(TokenCheckWarn), WithDiskQueue(DiskQueueOptions{Dir: t.TempDir()}),
+		WithRemoteSampling(RemoteSamplingOptions{URL: srv.URL, Interval: time.Hour}),
+		WithDiagnostics(DiagnosticsOptions{Callback: func(d Diagnostic) {
+			errs <- d.Err
+		}})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.initialize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.shutdown(context.Background())
+	for err := range errs {
+		if errors.Is(err, ErrInvalidToken) {
+			break
+		}
+	}
+}
@@ -0,0 +1,4 @@
+package logfire
+
+// Version is the version of the Logfire Go SDK.
+const Version = "0.1.0"
@@ -0,0 +1,34 @@
+package logfireamqp
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TableCarrier carries the trace context in the headers of a message.
+type TableCarrier amqp.Table
+
+var _ propagation.TextMapCarrier = TableCarrier{}
+
+// Get returns the value of a header, which is empty if it isn't a string.
+func (c TableCarrier) Get(key string) string {
+	switch v := c[key].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+func (c TableCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c TableCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,168 @@
+package logfireamqp
+
+import (
+	"context"
+	"maps"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/msgconv"
+)
+
+// Channel is a RabbitMQ channel creating spans for the messages it publishes and consumes.
+type Channel struct {
+	*amqp.Channel
+	config *config
+}
+
+// WrapChannel instruments a RabbitMQ channel.
+func WrapChannel(ch *amqp.Channel, opts ...Option) *Channel {
+	return &Channel{Channel: ch, config: newConfig(opts)}
+}
+
+// Publish publishes a message, in a new trace. Prefer [Channel.PublishWithContext].
+func (ch *Channel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	return ch.PublishWithContext(context.Background(), exchange, key, mandatory, immediate, msg)
+}
+
+// PublishWithContext publishes a message, as a child of ctx.
+func (ch *Channel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	return ch.config.publish(ctx, exchange, key, msg, func(ctx context.Context, msg amqp.Publishing) error {
+		return ch.Channel.PublishWithContext(ctx, exchange, key, mandatory, immediate, msg)
+	})
+}
+
+// PublishWithDeferredConfirmWithContext publishes a message, as a child of ctx, returning its confirmation.
+// The span ends once the message is sent rather than confirmed.
+func (ch *Channel) PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (*amqp.DeferredConfirmation, error) {
+	var confirmation *amqp.DeferredConfirmation
+	err := ch.config.publish(ctx, exchange, key, msg, func(ctx context.Context, msg amqp.Publishing) error {
+		var err error
+		confirmation, err = ch.Channel.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+		return err
+	})
+	return confirmation, err
+}
+
+// Consume starts delivering the messages of a queue, creating a span for each delivery.
+func (ch *Channel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	deliveries, err := ch.Channel.Consume(queue, consumer, autoAck, exclusive, noLocal, noWait, args)
+	if err != nil {
+		return nil, err
+	}
+	return ch.config.deliveries(queue, deliveries), nil
+}
+
+// ConsumeWithContext starts delivering the messages of a queue until ctx is done, creating a span for each delivery.
+func (ch *Channel) ConsumeWithContext(ctx context.Context, queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	deliveries, err := ch.Channel.ConsumeWithContext(ctx, queue, consumer, autoAck, exclusive, noLocal, noWait, args)
+	if err != nil {
+		return nil, err
+	}
+	return ch.config.deliveries(queue, deliveries), nil
+}
+
+// publish creates the span of a message and injects its context in a copy of the headers of the message,
+// so that the table of the caller isn't modified.
+func (c *config) publish(ctx context.Context, exchange, key string, msg amqp.Publishing, send func(context.Context, amqp.Publishing) error) error {
+	destination := publishDestination(exchange, key)
+	attrs := msgconv.Attributes(semconv.MessagingSystemRabbitMQ, semconv.MessagingOperationTypeSend, "publish", destination)
+	attrs = append(attrs, semconv.MessagingMessageBodySize(len(msg.Body)))
+	if key != "" {
+		attrs = append(attrs, semconv.MessagingRabbitMQDestinationRoutingKey(key))
+	}
+	attrs = append(attrs, messageAttributes(msg.MessageId, msg.CorrelationId)...)
+	ctx, span := c.tracer().Start(ctx, msgconv.SpanName("publish", destination),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(c.attrs...),
+	)
+	defer span.End()
+	headers := make(amqp.Table, len(msg.Headers)+1)
+	maps.Copy(headers, msg.Headers)
+	c.propagator().Inject(ctx, TableCarrier(headers))
+	msg.Headers = headers
+	err := send(ctx, msg)
+	msgconv.RecordError(span, err)
+	return err
+}
+
+// publishDestination names the destination of a message after its exchange and routing key,
+// as recommended by the semantic conventions for RabbitMQ.
+func publishDestination(exchange, key string) string {
+	switch {
+	case exchange == "" && key == "":
+		return "amq.default"
+	case exchange == "":
+		return key
+	case key == "":
+		return exchange
+	}
+	return exchange + ":" + key
+}
+
+func messageAttributes(messageID, correlationID string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if messageID != "" {
+		attrs = append(attrs, semconv.MessagingMessageID(messageID))
+	}
+	if correlationID != "" {
+		attrs = append(attrs, semconv.MessagingMessageConversationID(correlationID))
+	}
+	return attrs
+}
+
+// deliveries forwards the deliveries of a queue, creating a span for each of them which ends once it's received.
+// The acknowledger of each delivery is replaced to carry the context of its span and record its acknowledgement.
+func (c *config) deliveries(queue string, in <-chan amqp.Delivery) <-chan amqp.Delivery {
+	out := make(chan amqp.Delivery)
+	go func() {
+		defer close(out)
+		for d := range in {
+			ctx, span := c.startReceive(queue, d)
+			d.Acknowledger = &acknowledger{Acknowledger: d.Acknowledger, ctx: ctx, config: c, destination: queue}
+			out <- d
+			span.End()
+		}
+	}()
+	return out
+}
+
+func (c *config) startReceive(queue string, d amqp.Delivery) (context.Context, trace.Span) {
+	ctx := c.propagator().Extract(context.Background(), TableCarrier(d.Headers))
+	attrs := msgconv.Attributes(semconv.MessagingSystemRabbitMQ, semconv.MessagingOperationTypeReceive, "receive", queue)
+	attrs = append(attrs,
+		semconv.MessagingMessageBodySize(len(d.Body)),
+		semconv.MessagingRabbitMQMessageDeliveryTag(int(d.DeliveryTag)),
+	)
+	if d.RoutingKey != "" {
+		attrs = append(attrs, semconv.MessagingRabbitMQDestinationRoutingKey(d.RoutingKey))
+	}
+	if d.Exchange != "" {
+		attrs = append(attrs, exchangeKey.String(d.Exchange))
+	}
+	if d.ConsumerTag != "" {
+		attrs = append(attrs, semconv.MessagingConsumerGroupName(d.ConsumerTag))
+	}
+	if d.Redelivered {
+		attrs = append(attrs, redeliveredKey.Bool(true))
+	}
+	attrs = append(attrs, messageAttributes(d.MessageId, d.CorrelationId)...)
+	return c.tracer().Start(ctx, msgconv.SpanName("receive", queue),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(c.attrs...),
+	)
+}
+
+const (
+	// exchangeKey records the exchange a delivered message was published to.
+	exchangeKey = attribute.Key("messaging.rabbitmq.exchange")
+	// redeliveredKey records that a message was delivered before without being acknowledged.
+	redeliveredKey = attribute.Key("messaging.rabbitmq.redelivered")
+	// requeueKey records whether a message rejected by its consumer is requeued.
+	requeueKey = attribute.Key("messaging.rabbitmq.requeue")
+)
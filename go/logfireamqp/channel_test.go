@@ -0,0 +1,191 @@
+package logfireamqp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestConfig(t *testing.T) (*config, *sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	c := newConfig([]Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})})
+	return c, provider, recorder
+}
+
+func TestPublish(t *testing.T) {
+	c, provider, recorder := newTestConfig(t)
+
+	parent, parentSpan := provider.Tracer("test").Start(t.Context(), "parent")
+	headers := amqp.Table{"app": "billing"}
+	var sent amqp.Publishing
+	err := c.publish(parent, "orders", "created", amqp.Publishing{Headers: headers, Body: []byte("hello"), MessageId: "m1"},
+		func(ctx context.Context, msg amqp.Publishing) error {
+			sent = msg
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentSpan.End()
+
+	if _, ok := headers["traceparent"]; ok {
+		t.Error("expected the headers of the caller not to be modified")
+	}
+	if sent.Headers["app"] != "billing" || sent.Headers["traceparent"] == nil {
+		t.Errorf("unexpected headers %v", sent.Headers)
+	}
+	span := recorder.Ended()[0]
+	if span.Name() != "publish orders:created" || span.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	if span.Parent().SpanID() != parentSpan.SpanContext().SpanID() {
+		t.Error("expected the span to be a child of the context")
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:                               "publish orders:created",
+		"messaging.system":                           "rabbitmq",
+		"messaging.operation.type":                   "send",
+		"messaging.destination.name":                 "orders:created",
+		"messaging.rabbitmq.destination.routing_key": "created",
+		"messaging.message.body.size":                int64(5),
+		"messaging.message.id":                       "m1",
+	})
+}
+
+func TestPublishDestination(t *testing.T) {
+	for _, tt := range []struct{ exchange, key, want string }{
+		{"", "", "amq.default"},
+		{"", "tasks", "tasks"},
+		{"logs", "", "logs"},
+		{"orders", "created", "orders:created"},
+	} {
+		if got := publishDestination(tt.exchange, tt.key); got != tt.want {
+			t.Errorf("publishDestination(%q, %q) = %q, want %q", tt.exchange, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestPublishError(t *testing.T) {
+	c, _, recorder := newTestConfig(t)
+
+	err := c.publish(t.Context(), "", "tasks", amqp.Publishing{}, func(context.Context, amqp.Publishing) error {
+		return amqp.ErrClosed
+	})
+	if !errors.Is(err, amqp.ErrClosed) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+}
+
+// fakeAcknowledger records the acknowledgements of deliveries, failing rejections.
+type fakeAcknowledger struct {
+	acks []string
+}
+
+func (a *fakeAcknowledger) Ack(uint64, bool) error {
+	a.acks = append(a.acks, "ack")
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(uint64, bool, bool) error {
+	a.acks = append(a.acks, "nack")
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(uint64, bool) error {
+	return errors.New("channel closed")
+}
+
+func TestConsume(t *testing.T) {
+	c, provider, recorder := newTestConfig(t)
+
+	producer, producerSpan := provider.Tracer("test").Start(t.Context(), "producer")
+	producerSpan.End()
+	headers := amqp.Table{}
+	propagation.TraceContext{}.Inject(producer, TableCarrier(headers))
+	ack := &fakeAcknowledger{}
+	in := make(chan amqp.Delivery, 2)
+	in <- amqp.Delivery{Acknowledger: ack, Headers: headers, Body: []byte("hi"), DeliveryTag: 1, Exchange: "orders", RoutingKey: "created", ConsumerTag: "worker-1", Redelivered: true}
+	in <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 2}
+	close(in)
+
+	var deliveries []amqp.Delivery
+	for d := range c.deliveries("billing", in) {
+		deliveries = append(deliveries, d)
+	}
+	if err := deliveries[0].Ack(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := deliveries[1].Nack(false, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := deliveries[1].Reject(false); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if len(ack.acks) != 2 {
+		t.Errorf("expected the acknowledgements to be forwarded, got %v", ack.acks)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 6 {
+		t.Fatalf("expected 6 spans, got %d", len(spans))
+	}
+	receive := spans[1]
+	if receive.Name() != "receive billing" || receive.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("unexpected span %q of kind %v", receive.Name(), receive.SpanKind())
+	}
+	if receive.Parent().SpanID() != producerSpan.SpanContext().SpanID() {
+		t.Error("expected the span to continue the trace of the producer")
+	}
+	if trace.SpanContextFromContext(DeliveryContext(deliveries[0])).SpanID() != receive.SpanContext().SpanID() {
+		t.Error("expected the context of the delivery to contain its span")
+	}
+	oteltest.CheckAttributes(t, receive.Attributes(), map[attribute.Key]any{
+		"messaging.operation.type":                   "receive",
+		"messaging.destination.name":                 "billing",
+		"messaging.rabbitmq.destination.routing_key": "created",
+		"messaging.rabbitmq.message.delivery_tag":    int64(1),
+		"messaging.rabbitmq.exchange":                "orders",
+		"messaging.rabbitmq.redelivered":             true,
+		"messaging.consumer.group.name":              "worker-1",
+	})
+
+	ackSpan := spans[3]
+	if ackSpan.Name() != "ack billing" || ackSpan.Parent().SpanID() != receive.SpanContext().SpanID() {
+		t.Errorf("unexpected span %q", ackSpan.Name())
+	}
+	oteltest.CheckAttributes(t, ackSpan.Attributes(), map[attribute.Key]any{
+		"messaging.operation.type": "settle",
+	})
+	oteltest.CheckAttributes(t, spans[4].Attributes(), map[attribute.Key]any{
+		"messaging.operation.name":   "nack",
+		"messaging.rabbitmq.requeue": true,
+	})
+	if spans[5].Name() != "reject billing" || spans[5].Status().Code != codes.Error {
+		t.Errorf("unexpected span %q with status %v", spans[5].Name(), spans[5].Status())
+	}
+}
+
+func TestDeliveryContextWithoutSpan(t *testing.T) {
+	if trace.SpanContextFromContext(DeliveryContext(amqp.Delivery{})).IsValid() {
+		t.Error("expected no span")
+	}
+}
@@ -0,0 +1,92 @@
+// Package logfireamqp instruments RabbitMQ channels of rabbitmq/amqp091-go for Pydantic Logfire.
+//
+// [WrapChannel] returns a channel creating a producer span for each message published, named like
+// `publish orders:created` after the exchange and routing key, and a consumer span for each message
+// delivered to its consumers, named like `receive billing` after the queue, with the trace context
+// propagated in the headers of messages:
+//
+//	ch := logfireamqp.WrapChannel(channel, logfireamqp.WithConnection(conn))
+//	err := ch.PublishWithContext(ctx, "orders", "created", false, false, amqp.Publishing{Body: body})
+//	deliveries, err := ch.ConsumeWithContext(ctx, "billing", "", false, false, false, false, nil)
+//	for d := range deliveries {
+//		ctx := logfireamqp.DeliveryContext(d)
+//		// ...
+//		d.Ack(false)
+//	}
+package logfireamqp
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireamqp"
+
+// Option configures [WrapChannel].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject and extract the trace context
+// in the headers of messages. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithConnection records the address of the server of the connection of the channel on all spans.
+func WithConnection(conn *amqp.Connection) Option {
+	return func(c *config) {
+		addr := conn.RemoteAddr()
+		if addr == nil {
+			return
+		}
+		host, port := httpconv.SplitHostPort(addr.String())
+		c.attrs = append(c.attrs, semconv.ServerAddress(host))
+		if port > 0 {
+			c.attrs = append(c.attrs, semconv.ServerPort(port))
+		}
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	attrs          []attribute.KeyValue
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
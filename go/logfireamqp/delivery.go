@@ -0,0 +1,58 @@
+package logfireamqp
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/msgconv"
+)
+
+// DeliveryContext returns the context of the span of a delivery from [Channel.Consume],
+// continuing the trace of the publisher, to use as the parent of the work done for it.
+// It returns [context.Background] for deliveries which aren't instrumented.
+func DeliveryContext(d amqp.Delivery) context.Context {
+	if a, ok := d.Acknowledger.(*acknowledger); ok {
+		return a.ctx
+	}
+	return context.Background()
+}
+
+// acknowledger creates a span for each acknowledgement of a delivery, named like `ack billing`.
+type acknowledger struct {
+	amqp.Acknowledger
+	ctx         context.Context
+	config      *config
+	destination string
+}
+
+func (a *acknowledger) Ack(tag uint64, multiple bool) error {
+	return a.settle("ack", tag, nil, func() error { return a.Acknowledger.Ack(tag, multiple) })
+}
+
+func (a *acknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	return a.settle("nack", tag, &requeue, func() error { return a.Acknowledger.Nack(tag, multiple, requeue) })
+}
+
+func (a *acknowledger) Reject(tag uint64, requeue bool) error {
+	return a.settle("reject", tag, &requeue, func() error { return a.Acknowledger.Reject(tag, requeue) })
+}
+
+func (a *acknowledger) settle(operation string, tag uint64, requeue *bool, ack func() error) error {
+	attrs := msgconv.Attributes(semconv.MessagingSystemRabbitMQ, semconv.MessagingOperationTypeSettle, operation, a.destination)
+	attrs = append(attrs, semconv.MessagingRabbitMQMessageDeliveryTag(int(tag)))
+	if requeue != nil {
+		attrs = append(attrs, requeueKey.Bool(*requeue))
+	}
+	_, span := a.config.tracer().Start(a.ctx, msgconv.SpanName(operation, a.destination),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(a.config.attrs...),
+	)
+	defer span.End()
+	err := ack()
+	msgconv.RecordError(span, err)
+	return err
+}
@@ -0,0 +1,299 @@
+package logfireanthropic
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+)
+
+// provider is the `gen_ai.provider.name` of the spans.
+const provider = "anthropic"
+
+// Middleware returns a middleware of the Anthropic client creating a span for each call of the
+// messages endpoint. Other calls aren't traced.
+func Middleware(opts ...Option) option.Middleware {
+	c := newConfig(opts)
+	client := &genaiconv.Client{
+		Tracer:     c.tracer(),
+		Provider:   provider,
+		Content:    c.content,
+		Attributes: c.attrs,
+		Progress:   c.progress,
+		Metrics:    c.metrics(),
+	}
+	return func(r *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/v1/messages") {
+			return next(r)
+		}
+		body, err := genaiconv.ReadRequestBody(r)
+		if err != nil {
+			return next(r)
+		}
+		call, err := messagesCall(body, c.content)
+		if err != nil {
+			return next(r)
+		}
+		return client.Do(r, call, next, messagesResponse, &messageStream{})
+	}
+}
+
+type block struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	Thinking  string          `json:"thinking"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	Source    struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+		URL       string `json:"url"`
+	} `json:"source"`
+}
+
+// blocks decodes content which is either a string or an array of blocks.
+func blocks(raw json.RawMessage) []block {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		if text == "" {
+			return nil
+		}
+		return []block{{Type: "text", Text: text}}
+	}
+	var bs []block
+	json.Unmarshal(raw, &bs)
+	return bs
+}
+
+func (b block) part() genaiconv.Part {
+	switch b.Type {
+	case "text":
+		return genaiconv.TextPart(b.Text)
+	case "thinking":
+		return genaiconv.Part{Type: "reasoning", Content: b.Thinking}
+	case "tool_use", "server_tool_use":
+		return genaiconv.ToolCallPart(b.ID, b.Name, b.Input)
+	case "tool_result":
+		var text strings.Builder
+		for _, c := range blocks(b.Content) {
+			text.WriteString(c.Text)
+		}
+		return genaiconv.ToolCallResponsePart(b.ToolUseID, text.String())
+	case "image", "document":
+		if b.Source.Type == "url" {
+			return genaiconv.Part{Type: "uri", URI: b.Source.URL}
+		}
+		return genaiconv.Part{Type: "blob", MIMEType: b.Source.MediaType}
+	}
+	return genaiconv.Part{Type: b.Type}
+}
+
+func parts(bs []block) []genaiconv.Part {
+	parts := []genaiconv.Part{}
+	for _, b := range bs {
+		parts = append(parts, b.part())
+	}
+	return parts
+}
+
+func messagesCall(body []byte, content bool) (genaiconv.Call, error) {
+	var req struct {
+		Model         string          `json:"model"`
+		MaxTokens     *int64          `json:"max_tokens"`
+		Temperature   *float64        `json:"temperature"`
+		TopP          *float64        `json:"top_p"`
+		TopK          *float64        `json:"top_k"`
+		StopSequences []string        `json:"stop_sequences"`
+		Stream        bool            `json:"stream"`
+		System        json.RawMessage `json:"system"`
+		Messages      []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Tools []any `json:"tools"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return genaiconv.Call{}, err
+	}
+	params := genaiconv.Params{
+		MaxTokens:     req.MaxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		TopK:          req.TopK,
+		StopSequences: req.StopSequences,
+	}
+	call := genaiconv.Call{
+		Operation:  genaiconv.OperationChat,
+		Model:      req.Model,
+		Stream:     req.Stream,
+		Attributes: append(params.Attributes(), genaiconv.RequestStreamKey.Bool(req.Stream)),
+	}
+	if content {
+		if system := blocks(req.System); len(system) > 0 {
+			call.System = parts(system)
+		}
+		for _, m := range req.Messages {
+			call.Messages = append(call.Messages, genaiconv.Message{Role: m.Role, Parts: parts(blocks(m.Content))})
+		}
+		if len(req.Tools) > 0 {
+			call.Attributes = append(call.Attributes, genaiconv.JSON(genaiconv.ToolDefinitionsKey, req.Tools))
+		}
+	}
+	return call, nil
+}
+
+type usage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+}
+
+// usage returns the usage of a message, whose input tokens don't include the cached tokens.
+func (u usage) usage() *genaiconv.Usage {
+	return &genaiconv.Usage{
+		InputTokens:              u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		OutputTokens:             u.OutputTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens,
+	}
+}
+
+type message struct {
+	ID         string  `json:"id"`
+	Model      string  `json:"model"`
+	Role       string  `json:"role"`
+	Content    []block `json:"content"`
+	StopReason string  `json:"stop_reason"`
+	Usage      *usage  `json:"usage"`
+}
+
+func (m message) response() genaiconv.Response {
+	resp := genaiconv.Response{ID: m.ID, Model: m.Model}
+	if m.Usage != nil {
+		resp.Usage = m.Usage.usage()
+	}
+	if m.StopReason != "" {
+		resp.FinishReasons = []string{m.StopReason}
+	}
+	role := m.Role
+	if role == "" {
+		role = "assistant"
+	}
+	resp.Output = []genaiconv.Message{{Role: role, Parts: parts(m.Content), FinishReason: m.StopReason}}
+	return resp
+}
+
+func messagesResponse(body []byte) genaiconv.Response {
+	var m message
+	if json.Unmarshal(body, &m) != nil {
+		return genaiconv.Response{}
+	}
+	return m.response()
+}
+
+// messageStream accumulates the events of a streamed message.
+type messageStream struct {
+	genaiconv.ChunkCounter
+	message message
+	// inputs are the partial JSON inputs of the tool use blocks, by index.
+	inputs map[int]*strings.Builder
+}
+
+func (s *messageStream) Line(line []byte) (bool, error) {
+	data, ok := genaiconv.SSEData(line)
+	if !ok {
+		return false, nil
+	}
+	var event struct {
+		Type         string  `json:"type"`
+		Message      message `json:"message"`
+		Index        int     `json:"index"`
+		ContentBlock block   `json:"content_block"`
+		Delta        struct {
+			Type        string `json:"type"`
+			Text        string `json:"text"`
+			Thinking    string `json:"thinking"`
+			PartialJSON string `json:"partial_json"`
+			StopReason  string `json:"stop_reason"`
+		} `json:"delta"`
+		Usage *usage `json:"usage"`
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &event) != nil {
+		return false, nil
+	}
+	switch event.Type {
+	case "message_start":
+		s.message = event.Message
+	case "content_block_start":
+		for len(s.message.Content) <= event.Index {
+			s.message.Content = append(s.message.Content, block{})
+		}
+		s.message.Content[event.Index] = event.ContentBlock
+	case "content_block_delta":
+		if event.Index >= len(s.message.Content) {
+			return false, nil
+		}
+		s.Add()
+		b := &s.message.Content[event.Index]
+		switch event.Delta.Type {
+		case "text_delta":
+			b.Text += event.Delta.Text
+		case "thinking_delta":
+			b.Thinking += event.Delta.Thinking
+		case "input_json_delta":
+			if s.inputs == nil {
+				s.inputs = map[int]*strings.Builder{}
+			}
+			if s.inputs[event.Index] == nil {
+				s.inputs[event.Index] = &strings.Builder{}
+			}
+			s.inputs[event.Index].WriteString(event.Delta.PartialJSON)
+		}
+	case "message_delta":
+		if event.Delta.StopReason != "" {
+			s.message.StopReason = event.Delta.StopReason
+		}
+		// The usage of message_delta is cumulative.
+		if event.Usage != nil {
+			if s.message.Usage == nil {
+				s.message.Usage = &usage{}
+			}
+			s.message.Usage.OutputTokens = event.Usage.OutputTokens
+			if event.Usage.InputTokens > 0 {
+				s.message.Usage.InputTokens = event.Usage.InputTokens
+			}
+		}
+	case "message_stop":
+		return true, nil
+	case "error":
+		return true, errors.New(event.Error.Type + ": " + event.Error.Message)
+	}
+	return false, nil
+}
+
+func (s *messageStream) Response() genaiconv.Response {
+	for i, input := range s.inputs {
+		if i >= len(s.message.Content) {
+			continue
+		}
+		// The input of a stream ending early isn't valid JSON, and is kept as a string.
+		if json.Valid([]byte(input.String())) {
+			s.message.Content[i].Input = json.RawMessage(input.String())
+		} else {
+			s.message.Content[i].Input, _ = json.Marshal(input.String())
+		}
+	}
+	return s.message.response()
+}
@@ -0,0 +1,248 @@
+package logfireanthropic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func checkJSON(t *testing.T, attrs []attribute.KeyValue, key attribute.Key, want string) {
+	t.Helper()
+	got, _ := oteltest.Attributes(attrs)[key].(string)
+	var g, w any
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Errorf("%s = %q, not JSON", key, got)
+		return
+	}
+	json.Unmarshal([]byte(want), &w)
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("%s = %s, want %s", key, gb, wb)
+	}
+}
+
+const messageJSON = `{
+	"id": "msg_1",
+	"type": "message",
+	"role": "assistant",
+	"model": "claude-sonnet-4-5-20250929",
+	"content": [
+		{"type": "text", "text": "Let me check."},
+		{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Paris"}}
+	],
+	"stop_reason": "tool_use",
+	"usage": {"input_tokens": 10, "output_tokens": 20, "cache_read_input_tokens": 90}
+}`
+
+// messageSSE is a streamed message. The server keeps the connection open after it, like
+// proxies sometimes do, so the span must end with the message_stop event.
+const messageSSE = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_2","type":"message","role":"assistant","model":"claude-sonnet-4-5-20250929","content":[],"usage":{"input_tokens":8,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_2","name":"weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":15}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+func newClient(t *testing.T, opts ...Option) (anthropic.Client, *tracetest.SpanRecorder) {
+	t.Helper()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Stream bool   `json:"stream"`
+			Model  string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case req.Model == "overloaded":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(529)
+			w.Write([]byte(`{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`))
+		case req.Stream:
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(messageSSE))
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(messageJSON))
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := anthropic.NewClient(
+		option.WithBaseURL(server.URL),
+		option.WithAPIKey("test"),
+		option.WithMaxRetries(0),
+		option.WithMiddleware(Middleware(append(opts, WithTracerProvider(provider))...)),
+	)
+	return client, recorder
+}
+
+func TestMessage(t *testing.T) {
+	client, recorder := newClient(t, WithMessageContent())
+	_, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeSonnet4_5,
+		MaxTokens: 1024,
+		System:    []anthropic.TextBlockParam{{Text: "Be brief."}},
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock("What's the weather in Paris?")),
+			anthropic.NewAssistantMessage(anthropic.NewToolUseBlock("toolu_0", map[string]string{"city": "Paris"}, "weather")),
+			anthropic.NewUserMessage(anthropic.NewToolResultBlock("toolu_0", "Sunny", false)),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "chat claude-sonnet-4-5" {
+		t.Errorf("name = %s", span.Name())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"gen_ai.system":                        "anthropic",
+		"gen_ai.request.max_tokens":            int64(1024),
+		"gen_ai.response.id":                   "msg_1",
+		"gen_ai.response.model":                "claude-sonnet-4-5-20250929",
+		"gen_ai.usage.input_tokens":            int64(100),
+		"gen_ai.usage.cache_read.input_tokens": int64(90),
+		"gen_ai.usage.output_tokens":           int64(20),
+	})
+	checkJSON(t, span.Attributes(), "gen_ai.system_instructions", `[{"type": "text", "content": "Be brief."}]`)
+	checkJSON(t, span.Attributes(), "gen_ai.input.messages", `[
+		{"role": "user", "parts": [{"type": "text", "content": "What's the weather in Paris?"}]},
+		{"role": "assistant", "parts": [{"type": "tool_call", "id": "toolu_0", "name": "weather", "arguments": {"city": "Paris"}}]},
+		{"role": "user", "parts": [{"type": "tool_call_response", "id": "toolu_0", "response": "Sunny"}]}
+	]`)
+	checkJSON(t, span.Attributes(), "gen_ai.output.messages", `[{
+		"role": "assistant",
+		"finish_reason": "tool_use",
+		"parts": [
+			{"type": "text", "content": "Let me check."},
+			{"type": "tool_call", "id": "toolu_1", "name": "weather", "arguments": {"city": "Paris"}}
+		]
+	}]`)
+}
+
+func TestMessageStreaming(t *testing.T) {
+	client, recorder := newClient(t, WithMessageContent(), WithStreamProgress(0))
+	stream := client.Messages.NewStreaming(context.Background(), anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeSonnet4_5,
+		MaxTokens: 1024,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("Hi"))},
+	})
+	defer stream.Close()
+	for stream.Next() {
+		if stream.Current().Type == "message_stop" {
+			break
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want the span ended by message_stop", len(spans))
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		"gen_ai.request.stream":       true,
+		"gen_ai.response.id":          "msg_2",
+		"gen_ai.usage.input_tokens":   int64(8),
+		"gen_ai.usage.output_tokens":  int64(15),
+		"gen_ai.response.chunk_count": int64(4),
+	})
+	checkJSON(t, spans[0].Attributes(), "gen_ai.output.messages", `[{
+		"role": "assistant",
+		"finish_reason": "tool_use",
+		"parts": [
+			{"type": "text", "content": "Hello"},
+			{"type": "tool_call", "id": "toolu_2", "name": "weather", "arguments": {"city": "Paris"}}
+		]
+	}]`)
+}
+
+func TestCost(t *testing.T) {
+	client, recorder := newClient(t, WithPricing(map[string]Price{
+		"claude-sonnet-4-5": {Input: 3, Output: 15, CacheRead: 0.3},
+	}))
+	_, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeSonnet4_5,
+		MaxTokens: 1024,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("Hi"))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The 90 cached tokens are priced apart from the 10 other input tokens.
+	const cost = (10*3 + 90*0.3 + 20*15) / 1e6
+	if got := oteltest.Attributes(recorder.Ended()[0].Attributes())["operation.cost"]; got != cost {
+		t.Errorf("operation.cost = %v, want %v", got, cost)
+	}
+}
+
+func TestError(t *testing.T) {
+	client, recorder := newClient(t)
+	_, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+		Model:     "overloaded",
+		MaxTokens: 1024,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("Hi"))},
+	})
+	if err == nil {
+		t.Fatal("no error for an overloaded model")
+	}
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || !strings.HasSuffix(span.Status().Description, ": Overloaded") {
+		t.Errorf("status = %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{"gen_ai.input.messages": nil})
+}
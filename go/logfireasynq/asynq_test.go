@@ -0,0 +1,143 @@
+package logfireasynq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func waitForSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, span := range recorder.Ended() {
+			if span.Name() == name {
+				return span
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no %s span", name)
+	return nil
+}
+
+// newTestServer processes the tasks enqueued by the returned client with handler.
+func newTestServer(t *testing.T, handler asynq.HandlerFunc) (*Client, *tracetest.SpanRecorder) {
+	t.Helper()
+	redis := asynq.RedisClientOpt{Addr: miniredis.RunT(t).Addr()}
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opts := []Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}
+
+	mux := asynq.NewServeMux()
+	mux.Use(Middleware(opts...))
+	mux.Handle("email:deliver", handler)
+	server := asynq.NewServer(redis, asynq.Config{
+		Concurrency:              1,
+		LogLevel:                 asynq.FatalLevel,
+		RetryDelayFunc:           func(int, error, *asynq.Task) time.Duration { return 0 },
+		DelayedTaskCheckInterval: 10 * time.Millisecond,
+		TaskCheckInterval:        10 * time.Millisecond,
+	})
+	if err := server.Start(mux); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Shutdown)
+
+	client := WrapClient(asynq.NewClient(redis), opts...)
+	t.Cleanup(func() { client.Close() })
+	return client, recorder
+}
+
+func TestEnqueueProcess(t *testing.T) {
+	client, recorder := newTestServer(t, func(ctx context.Context, task *asynq.Task) error {
+		return nil
+	})
+
+	info, err := client.EnqueueContext(context.Background(), NewTask("email:deliver", []byte("hello")), asynq.MaxRetry(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue := waitForSpan(t, recorder, "enqueue email:deliver")
+	if enqueue.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("kind = %v, want producer", enqueue.SpanKind())
+	}
+	oteltest.CheckAttributes(t, enqueue.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:                "enqueue email:deliver",
+		"messaging.system":            "asynq",
+		"messaging.destination.name":  "email:deliver",
+		"messaging.message.id":        info.ID,
+		"messaging.message.body.size": int64(5),
+		"messaging.asynq.queue":       "default",
+		"messaging.asynq.task.state":  "pending",
+		"messaging.asynq.max_retry":   int64(3),
+		"messaging.operation.type":    "send",
+	})
+
+	process := waitForSpan(t, recorder, "process email:deliver")
+	if process.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("kind = %v, want consumer", process.SpanKind())
+	}
+	if process.Parent().SpanID() != enqueue.SpanContext().SpanID() {
+		t.Errorf("parent = %v, want the enqueue span %v", process.Parent().SpanID(), enqueue.SpanContext().SpanID())
+	}
+	oteltest.CheckAttributes(t, process.Attributes(), map[attribute.Key]any{
+		"messaging.message.id":        info.ID,
+		"messaging.asynq.queue":       "default",
+		"messaging.asynq.retry_count": int64(0),
+		"messaging.asynq.max_retry":   int64(3),
+		"messaging.operation.type":    "process",
+	})
+	if process.Status().Code == codes.Error {
+		t.Errorf("status = %v", process.Status())
+	}
+}
+
+func TestProcessRetry(t *testing.T) {
+	attempts := 0
+	client, recorder := newTestServer(t, func(ctx context.Context, task *asynq.Task) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("mail server unavailable")
+		}
+		return nil
+	})
+
+	if _, err := client.Enqueue(NewTask("email:deliver", nil), asynq.MaxRetry(1)); err != nil {
+		t.Fatal(err)
+	}
+	var spans []sdktrace.ReadOnlySpan
+	deadline := time.Now().Add(10 * time.Second)
+	for len(spans) < 2 && time.Now().Before(deadline) {
+		spans = spans[:0]
+		for _, span := range recorder.Ended() {
+			if span.Name() == "process email:deliver" {
+				spans = append(spans, span)
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("got %d process spans, want 2", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "mail server unavailable" {
+		t.Errorf("status = %v, want the error", spans[0].Status())
+	}
+	oteltest.CheckAttributes(t, spans[1].Attributes(), map[attribute.Key]any{"messaging.asynq.retry_count": int64(1)})
+	if spans[0].SpanContext().TraceID() != spans[1].SpanContext().TraceID() {
+		t.Error("retry in another trace")
+	}
+}
@@ -0,0 +1,74 @@
+package logfireasynq
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/msgconv"
+)
+
+const (
+	// queueKey records the queue of a task.
+	queueKey = attribute.Key("messaging.asynq.queue")
+	// stateKey records the state of a task once enqueued, e.g. `pending` or `scheduled`.
+	stateKey = attribute.Key("messaging.asynq.task.state")
+	// retryCountKey records the number of times a task was retried before the current attempt.
+	retryCountKey = attribute.Key("messaging.asynq.retry_count")
+	// maxRetryKey records the maximum number of times a task is retried.
+	maxRetryKey = attribute.Key("messaging.asynq.max_retry")
+)
+
+// NewTask returns a task with headers, which carry the trace context of the span enqueuing it.
+func NewTask(typename string, payload []byte, opts ...asynq.Option) *asynq.Task {
+	return asynq.NewTaskWithHeaders(typename, payload, map[string]string{}, opts...)
+}
+
+// Client is an asynq client creating spans for the tasks it enqueues.
+type Client struct {
+	*asynq.Client
+	config *config
+}
+
+// WrapClient instruments an asynq client.
+func WrapClient(client *asynq.Client, opts ...Option) *Client {
+	return &Client{Client: client, config: newConfig(opts)}
+}
+
+// Enqueue enqueues a task, in a new trace. Prefer [Client.EnqueueContext].
+func (c *Client) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	return c.EnqueueContext(context.Background(), task, opts...)
+}
+
+// EnqueueContext enqueues a task, as a child of ctx. The trace context is injected in the headers
+// of the task, if it has any: see [NewTask].
+func (c *Client) EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	attrs := msgconv.Attributes(system, semconv.MessagingOperationTypeSend, "enqueue", task.Type())
+	attrs = append(attrs, semconv.MessagingMessageBodySize(len(task.Payload())))
+	ctx, span := c.config.tracer().Start(ctx, msgconv.SpanName("enqueue", task.Type()),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(c.config.attrs...),
+	)
+	defer span.End()
+	// The headers can't be replaced without losing the options of the task, so they're set in place.
+	if headers := task.Headers(); headers != nil {
+		c.config.propagator().Inject(ctx, propagation.MapCarrier(headers))
+	}
+	info, err := c.Client.EnqueueContext(ctx, task, opts...)
+	if err != nil {
+		msgconv.RecordError(span, err)
+		return info, err
+	}
+	span.SetAttributes(
+		semconv.MessagingMessageID(info.ID),
+		queueKey.String(info.Queue),
+		stateKey.String(info.State.String()),
+		maxRetryKey.Int(info.MaxRetry),
+	)
+	return info, nil
+}
@@ -0,0 +1,80 @@
+// Package logfireasynq instruments hibiken/asynq task queues for Pydantic Logfire.
+//
+// [WrapClient] returns a client creating a producer span for each task enqueued, named like
+// `enqueue email:deliver` after the type of the task, and injecting its trace context in the headers
+// of the task. [Middleware] creates a consumer span for each task processed, named like
+// `process email:deliver`, continuing the trace of the producer, with the queue, the retry count
+// and the maximum number of retries of the task:
+//
+//	client := logfireasynq.WrapClient(asynq.NewClient(redis))
+//	info, err := client.EnqueueContext(ctx, logfireasynq.NewTask("email:deliver", payload))
+//
+//	mux := asynq.NewServeMux()
+//	mux.Use(logfireasynq.Middleware())
+//	mux.HandleFunc("email:deliver", handleEmailDelivery)
+//
+// The trace context is carried by the headers of tasks, which [asynq.NewTask] doesn't create:
+// create tasks with [NewTask] or [asynq.NewTaskWithHeaders] so that they're part of the trace enqueuing them.
+package logfireasynq
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireasynq"
+
+// system is the `messaging.system` of the spans, which has no well-known value for asynq.
+var system = semconv.MessagingSystemKey.String("asynq")
+
+// Option configures [WrapClient] and [Middleware].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject and extract the trace context
+// in the headers of tasks. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	attrs          []attribute.KeyValue
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
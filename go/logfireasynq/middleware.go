@@ -0,0 +1,62 @@
+package logfireasynq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/msgconv"
+)
+
+// Middleware returns a middleware for [asynq.ServeMux] creating a span for each task processed,
+// which is the parent of the context passed to the handler. Each attempt to process a task is a span
+// of the trace enqueuing it.
+func Middleware(opts ...Option) asynq.MiddlewareFunc {
+	c := newConfig(opts)
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			return c.process(ctx, task, next)
+		})
+	}
+}
+
+func (c *config) process(ctx context.Context, task *asynq.Task, next asynq.Handler) error {
+	if headers := task.Headers(); headers != nil {
+		ctx = c.propagator().Extract(ctx, propagation.MapCarrier(headers))
+	}
+	attrs := msgconv.Attributes(system, semconv.MessagingOperationTypeProcess, "process", task.Type())
+	attrs = append(attrs, semconv.MessagingMessageBodySize(len(task.Payload())))
+	if id, ok := asynq.GetTaskID(ctx); ok {
+		attrs = append(attrs, semconv.MessagingMessageID(id))
+	}
+	if queue, ok := asynq.GetQueueName(ctx); ok {
+		attrs = append(attrs, queueKey.String(queue))
+	}
+	if n, ok := asynq.GetRetryCount(ctx); ok {
+		attrs = append(attrs, retryCountKey.Int(n))
+	}
+	if n, ok := asynq.GetMaxRetry(ctx); ok {
+		attrs = append(attrs, maxRetryKey.Int(n))
+	}
+	ctx, span := c.tracer().Start(ctx, msgconv.SpanName("process", task.Type()),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(c.attrs...),
+	)
+	// The server recovers them and retries the task.
+	defer span.End(trace.WithStackTrace(true))
+	defer func() {
+		if p := recover(); p != nil {
+			span.SetStatus(codes.Error, fmt.Sprint(p))
+			panic(p)
+		}
+	}()
+	err := next.ProcessTask(ctx, task)
+	msgconv.RecordError(span, err)
+	return err
+}
@@ -0,0 +1,53 @@
+// Package logfireaws instruments the clients of the AWS SDK for Go v2 for Pydantic Logfire.
+//
+// [AppendMiddlewares] creates a span for each call to an AWS service, named like `S3.GetObject`,
+// recording the service, operation, region, request ID and number of retries:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	logfireaws.AppendMiddlewares(&cfg.APIOptions)
+//	client := s3.NewFromConfig(cfg)
+package logfireaws
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireaws"
+
+// Option configures [AppendMiddlewares].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
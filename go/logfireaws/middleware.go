@@ -0,0 +1,81 @@
+package logfireaws
+
+import (
+	"context"
+	"errors"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// middlewareID identifies the middleware in the stacks of the clients.
+const middlewareID = "logfire"
+
+// system is the `rpc.system.name` of AWS calls, as named by the semantic conventions for the AWS SDKs.
+var system = semconv.RPCSystemNameKey.String("aws-api")
+
+// AppendMiddlewares adds the middleware creating the spans of AWS calls to the API options of a config,
+// usually `&cfg.APIOptions`, so that it's used by all clients created from the config.
+func AppendMiddlewares(apiOptions *[]func(*middleware.Stack) error, opts ...Option) {
+	c := newConfig(opts)
+	*apiOptions = append(*apiOptions, func(stack *middleware.Stack) error {
+		// After the metadata of the service and operation is added to the context, and before the retries.
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc(middlewareID, c.handleInitialize), middleware.After)
+	})
+}
+
+// handleInitialize creates the span of a call, which includes all of its attempts.
+func (c *config) handleInitialize(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+	name := service + "." + operation
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(name),
+		system,
+		semconv.RPCMethod(service + "/" + operation),
+	}
+	if region := awsmiddleware.GetRegion(ctx); region != "" {
+		attrs = append(attrs, semconv.CloudRegion(region))
+	}
+	ctx, span := c.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(c.attrs...),
+	)
+	defer span.End()
+
+	out, metadata, err := next.HandleInitialize(ctx, in)
+	span.SetAttributes(responseAttributes(metadata)...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			span.SetAttributes(semconv.ErrorTypeKey.String(apiErr.ErrorCode()))
+		}
+	}
+	return out, metadata, err
+}
+
+// responseAttributes returns the attributes of the last attempt of a call.
+func responseAttributes(metadata middleware.Metadata) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if requestID, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok && requestID != "" {
+		attrs = append(attrs, semconv.AWSRequestID(requestID))
+	}
+	if results, ok := retry.GetAttemptResults(metadata); ok && len(results.Results) > 1 {
+		attrs = append(attrs, semconv.HTTPRequestResendCount(len(results.Results)-1))
+	}
+	if resp, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok {
+		attrs = append(attrs, semconv.HTTPResponseStatusCode(resp.StatusCode))
+	}
+	return attrs
+}
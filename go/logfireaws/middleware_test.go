@@ -0,0 +1,96 @@
+package logfireaws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// newTestClient returns an SQS client sending its requests to handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*sqs.Client, *tracetest.SpanRecorder) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	cfg := aws.Config{
+		Region:       "eu-west-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	AppendMiddlewares(&cfg.APIOptions, WithTracerProvider(provider))
+	return sqs.NewFromConfig(cfg), recorder
+}
+
+func TestCall(t *testing.T) {
+	var attempts atomic.Int32
+	client, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-amzn-RequestId", "req-1")
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"QueueUrl": "https://sqs.eu-west-1.amazonaws.com/123/orders"}`))
+	})
+
+	if _, err := client.GetQueueUrl(t.Context(), &sqs.GetQueueUrlInput{QueueName: aws.String("orders")}); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "SQS.GetQueueUrl" || span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:              "SQS.GetQueueUrl",
+		"rpc.system.name":           "aws-api",
+		"rpc.method":                "SQS/GetQueueUrl",
+		"cloud.region":              "eu-west-1",
+		"aws.request_id":            "req-1",
+		"http.request.resend_count": int64(1),
+		"http.response.status_code": int64(http.StatusOK),
+	})
+}
+
+func TestCallError(t *testing.T) {
+	client, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-amzn-RequestId", "req-2")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"__type": "com.amazonaws.sqs#QueueDoesNotExist", "message": "The specified queue does not exist."}`))
+	})
+
+	if _, err := client.GetQueueUrl(t.Context(), &sqs.GetQueueUrlInput{QueueName: aws.String("missing")}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"error.type":                "QueueDoesNotExist",
+		"aws.request_id":            "req-2",
+		"http.response.status_code": int64(http.StatusBadRequest),
+	})
+	if _, ok := oteltest.Attributes(span.Attributes())["http.request.resend_count"]; ok {
+		t.Error("expected no retries")
+	}
+}
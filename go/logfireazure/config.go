@@ -0,0 +1,69 @@
+// Package logfireazure instruments the clients of the Azure SDK for Go for Pydantic Logfire.
+//
+// [NewPolicy] returns a pipeline policy creating a span for each HTTP request sent by a client,
+// recording the client and service request IDs used by Azure to correlate requests.
+// [NewTracingProvider] creates the spans of the operations of the clients, named like
+// `BlobClient.DownloadStream`, which the spans of their requests are children of.
+// [ClientOptions] sets both:
+//
+//	client, err := azblob.NewClient(url, cred, &azblob.ClientOptions{ClientOptions: logfireazure.ClientOptions()})
+package logfireazure
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireazure"
+
+// Option configures [NewPolicy], [NewTracingProvider] and [ClientOptions].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject the trace context in requests.
+// Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
+
+// ClientOptions returns the options of a client creating the spans of its operations and requests,
+// to embed in the options of the client of a service.
+func ClientOptions(opts ...Option) policy.ClientOptions {
+	return policy.ClientOptions{
+		TracingProvider:  NewTracingProvider(opts...),
+		PerRetryPolicies: []policy.Policy{NewPolicy(opts...)},
+	}
+}
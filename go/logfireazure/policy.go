@@ -0,0 +1,95 @@
+package logfireazure
+
+import (
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+)
+
+// The attributes correlating requests with the logs of Azure, as named by the Azure SDKs.
+const (
+	clientRequestIDKey  = attribute.Key("az.client_request_id")
+	serviceRequestIDKey = attribute.Key("az.service_request_id")
+	// operationNameKey records the operation of the client sending a request, e.g. `BlobClient.DownloadStream`.
+	operationNameKey = attribute.Key("az.operation")
+)
+
+// NewPolicy returns a pipeline policy creating a span for each HTTP request sent by a client, including retries.
+// Add it to the PerRetryPolicies of the options of the client, or use [ClientOptions].
+func NewPolicy(opts ...Option) policy.Policy {
+	return &tracingPolicy{config: newConfig(opts)}
+}
+
+type tracingPolicy struct {
+	config *config
+}
+
+// attempts counts the attempts of a request, and is shared by the retries as an operation value.
+type attempts struct {
+	count int
+}
+
+func (p *tracingPolicy) Do(req *policy.Request) (*http.Response, error) {
+	var a *attempts
+	if !req.OperationValue(&a) {
+		a = &attempts{}
+		req.SetOperationValue(a)
+	}
+	a.count++
+
+	r := req.Raw()
+	ctx, span := p.config.tracer().Start(r.Context(), r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(requestAttributes(r)...),
+	)
+	defer span.End()
+	if a.count > 1 {
+		span.SetAttributes(semconv.HTTPRequestResendCount(a.count - 1))
+	}
+	if op, ok := ctx.Value(operationKey{}).(operation); ok {
+		span.SetAttributes(operationNameKey.String(op.name))
+		if op.namespace != "" {
+			span.SetAttributes(namespaceKey.String(op.namespace))
+		}
+	}
+	req = req.WithContext(ctx)
+	p.config.propagator().Inject(ctx, propagation.HeaderCarrier(req.Raw().Header))
+
+	resp, err := req.Next()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if id := resp.Header.Get("x-ms-request-id"); id != "" {
+		span.SetAttributes(serviceRequestIDKey.String(id))
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, "")
+		if code := resp.Header.Get("x-ms-error-code"); code != "" {
+			span.SetAttributes(semconv.ErrorTypeKey.String(code))
+		}
+	}
+	return resp, nil
+}
+
+// requestAttributes returns the attributes of a request without its query, which can hold SAS tokens.
+func requestAttributes(r *http.Request) []attribute.KeyValue {
+	sanitized := *r
+	u := *r.URL
+	u.RawQuery = ""
+	sanitized.URL = &u
+	attrs := httpconv.ClientRequestAttributes(&sanitized)
+	if id := r.Header.Get("x-ms-client-request-id"); id != "" {
+		attrs = append(attrs, clientRequestIDKey.String(id))
+	}
+	return attrs
+}
@@ -0,0 +1,137 @@
+package logfireazure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// newTestClient returns a client sending its requests to handler, like the clients of the Azure services.
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*azcore.Client, string, *tracetest.SpanRecorder) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+
+	options := ClientOptions(WithTracerProvider(provider), WithPropagators(propagation.TraceContext{}))
+	options.Transport = server.Client()
+	options.Retry = policy.RetryOptions{RetryDelay: time.Millisecond}
+	client, err := azcore.NewClient("azblob.Client", "v1.0.0", runtime.PipelineOptions{
+		Tracing: runtime.TracingOptions{Namespace: "Microsoft.Storage"},
+	}, &options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, server.URL, recorder
+}
+
+// download sends a request like an operation of a client.
+func download(ctx context.Context, client *azcore.Client, url string) (err error) {
+	ctx, endSpan := runtime.StartSpan(ctx, "BlobClient.DownloadStream", client.Tracer(), nil)
+	defer func() { endSpan(err) }()
+	req, err := runtime.NewRequest(ctx, http.MethodGet, url+"/container/blob?sig=secret")
+	if err != nil {
+		return err
+	}
+	req.Raw().Header.Set("x-ms-client-request-id", "client-1")
+	resp, err := client.Pipeline().Do(req)
+	if err != nil {
+		return err
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return runtime.NewResponseError(resp)
+	}
+	return nil
+}
+
+func TestPolicy(t *testing.T) {
+	var calls atomic.Int32
+	var traceparent atomic.Value
+	client, url, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparent.Store(r.Header.Get("traceparent"))
+		w.Header().Set("x-ms-request-id", "service-1")
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	if err := download(t.Context(), client, url); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	first, retry, op := spans[0], spans[1], spans[2]
+	if op.Name() != "BlobClient.DownloadStream" {
+		t.Errorf("unexpected span name %q", op.Name())
+	}
+	oteltest.CheckAttributes(t, op.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey: "BlobClient.DownloadStream",
+		"az.namespace": "Microsoft.Storage",
+	})
+	for _, span := range []sdktrace.ReadOnlySpan{first, retry} {
+		if span.Name() != "GET" || span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+		}
+		if span.Parent().SpanID() != op.SpanContext().SpanID() {
+			t.Error("expected the request span to be a child of the operation span")
+		}
+		oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+			"az.operation":          "BlobClient.DownloadStream",
+			"az.namespace":          "Microsoft.Storage",
+			"az.client_request_id":  "client-1",
+			"az.service_request_id": "service-1",
+			"url.full":              url + "/container/blob",
+		})
+	}
+	if first.Status().Code != codes.Error {
+		t.Errorf("unexpected status %v", first.Status())
+	}
+	oteltest.CheckAttributes(t, retry.Attributes(), map[attribute.Key]any{
+		"http.request.resend_count": int64(1),
+		"http.response.status_code": int64(http.StatusOK),
+	})
+	if got := traceparent.Load(); got == "" {
+		t.Error("expected the trace context to be injected")
+	}
+}
+
+func TestPolicyError(t *testing.T) {
+	client, url, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-error-code", "BlobNotFound")
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	if err := download(t.Context(), client, url); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	spans := recorder.Ended()
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		"error.type":                "BlobNotFound",
+		"http.response.status_code": int64(http.StatusNotFound),
+	})
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("expected the operation span to fail, got %v", spans[1].Status())
+	}
+}
@@ -0,0 +1,119 @@
+package logfireazure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// namespaceKey is the attribute of the Azure resource provider of an operation, e.g. `Microsoft.Storage`,
+// which the clients set on their tracers.
+const namespaceKey = attribute.Key("az.namespace")
+
+// operationKey is the context key of the operation of a client, which the policy records on its requests.
+type operationKey struct{}
+
+type operation struct {
+	name      string
+	namespace string
+}
+
+// NewTracingProvider returns the tracing provider of the clients, creating a span for each of their operations.
+//
+// The spans of their HTTP requests are left to [NewPolicy], so use both, e.g. with [ClientOptions].
+func NewTracingProvider(opts ...Option) tracing.Provider {
+	c := newConfig(opts)
+	return tracing.NewProvider(func(string, string) tracing.Tracer {
+		return tracing.NewTracer(c.startSpan, &tracing.TracerOptions{
+			SpanFromContext: func(ctx context.Context) tracing.Span {
+				return newSpan(trace.SpanFromContext(ctx))
+			},
+		})
+	}, nil)
+}
+
+func (c *config) startSpan(ctx context.Context, name string, options *tracing.SpanOptions) (context.Context, tracing.Span) {
+	// azcore creates a span like `HTTP GET` for each request of an operation, which the policy replaces.
+	if options.Kind == tracing.SpanKindClient && strings.HasPrefix(name, "HTTP ") {
+		return ctx, tracing.Span{}
+	}
+	attrs := []attribute.KeyValue{logfire.MsgKey.String(name)}
+	op := operation{name: name}
+	for _, a := range options.Attributes {
+		attrs = append(attrs, convertAttribute(a))
+		if a.Key == string(namespaceKey) {
+			op.namespace = fmt.Sprint(a.Value)
+		}
+	}
+	ctx, span := c.tracer().Start(ctx, name,
+		trace.WithSpanKind(spanKind(options.Kind)),
+		trace.WithAttributes(attrs...),
+	)
+	return context.WithValue(ctx, operationKey{}, op), newSpan(span)
+}
+
+func newSpan(span trace.Span) tracing.Span {
+	return tracing.NewSpan(tracing.SpanImpl{
+		End: func() { span.End() },
+		SetAttributes: func(attrs ...tracing.Attribute) {
+			for _, a := range attrs {
+				span.SetAttributes(convertAttribute(a))
+			}
+		},
+		AddEvent: func(name string, attrs ...tracing.Attribute) {
+			kvs := make([]attribute.KeyValue, len(attrs))
+			for i, a := range attrs {
+				kvs[i] = convertAttribute(a)
+			}
+			span.AddEvent(name, trace.WithAttributes(kvs...))
+		},
+		SetStatus: func(status tracing.SpanStatus, description string) {
+			switch status {
+			case tracing.SpanStatusError:
+				span.SetStatus(codes.Error, description)
+			case tracing.SpanStatusOK:
+				span.SetStatus(codes.Ok, description)
+			}
+		},
+	})
+}
+
+func spanKind(kind tracing.SpanKind) trace.SpanKind {
+	switch kind {
+	case tracing.SpanKindServer:
+		return trace.SpanKindServer
+	case tracing.SpanKindClient:
+		return trace.SpanKindClient
+	case tracing.SpanKindProducer:
+		return trace.SpanKindProducer
+	case tracing.SpanKindConsumer:
+		return trace.SpanKindConsumer
+	}
+	return trace.SpanKindInternal
+}
+
+func convertAttribute(a tracing.Attribute) attribute.KeyValue {
+	key := attribute.Key(a.Key)
+	switch v := a.Value.(type) {
+	case string:
+		return key.String(v)
+	case bool:
+		return key.Bool(v)
+	case int:
+		return key.Int(v)
+	case int64:
+		return key.Int64(v)
+	case float64:
+		return key.Float64(v)
+	case []string:
+		return key.StringSlice(v)
+	}
+	return key.String(fmt.Sprint(a.Value))
+}
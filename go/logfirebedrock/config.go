@@ -0,0 +1,116 @@
+// Package logfirebedrock instruments the Amazon Bedrock Runtime client of the AWS SDK for Go v2
+// for Pydantic Logfire.
+//
+// [AppendMiddlewares] creates a span for each call of the Converse, ConverseStream and InvokeModel
+// operations, named after the operation and model like `chat anthropic.claude-sonnet-4-5-20250929-v1:0`,
+// with the gen_ai.* semantic convention attributes: the inference parameters, the stop reason and the
+// token usage. The bodies of InvokeModel are parsed for the formats of the Anthropic, Amazon Nova,
+// Amazon Titan, Meta Llama and Mistral models. Along with [logfireaws.AppendMiddlewares], its spans
+// are children of the spans of the AWS calls:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	logfireaws.AppendMiddlewares(&cfg.APIOptions)
+//	logfirebedrock.AppendMiddlewares(&cfg.APIOptions)
+//	client := bedrockruntime.NewFromConfig(cfg)
+//
+// The messages of requests and responses are only recorded with [WithMessageContent].
+//
+// [logfireaws.AppendMiddlewares]: https://pkg.go.dev/github.com/pydantic/logfire/go/logfireaws#AppendMiddlewares
+package logfirebedrock
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirebedrock"
+
+// Option configures [AppendMiddlewares].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithMessageContent records the messages of requests and responses, and the definitions of tools,
+// as the `gen_ai.input.messages`, `gen_ai.output.messages` and `gen_ai.tool.definitions` attributes.
+// They still go through the scrubbing of Logfire.
+func WithMessageContent() Option {
+	return func(c *config) {
+		c.content = true
+	}
+}
+
+// WithStreamProgress records the progress of streamed responses on their spans: the time to the first
+// chunk of the output as the `gen_ai.response.time_to_first_chunk` attribute, the number of chunks as
+// `gen_ai.response.chunk_count`, and a `gen_ai.stream.progress` event at most every interval with the
+// number of chunks so far and, with [WithMessageContent], the partial output. There are no events if
+// interval is zero.
+func WithStreamProgress(interval time.Duration) Option {
+	return func(c *config) {
+		c.progress = &genaiconv.Progress{Interval: interval}
+	}
+}
+
+// WithMeterProvider sets the meter provider used to record the `gen_ai.client.token.usage`,
+// `gen_ai.client.operation.duration` and `operation.cost` metrics. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// Price is the price of a model in USD per million tokens. The prices of cached tokens default to
+// the price of input tokens when they're zero.
+type Price = genaiconv.Price
+
+// WithPricing sets the prices of models by name, to record the cost of calls as the `operation.cost`
+// attribute and metric. The price of a model is the one of its longest prefix, so that `anthropic.claude-sonnet-4-5`
+// prices `anthropic.claude-sonnet-4-5-20250929-v1:0`.
+func WithPricing(prices map[string]Price) Option {
+	return func(c *config) {
+		c.pricing = prices
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	content        bool
+	progress       *genaiconv.Progress
+	meterProvider  metric.MeterProvider
+	pricing        map[string]Price
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) metrics() *genaiconv.Metrics {
+	return &genaiconv.Metrics{Meter: instrumentation.Meter(c.meterProvider, instrumentationName), Pricing: c.pricing}
+}
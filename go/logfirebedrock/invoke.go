@@ -0,0 +1,308 @@
+package logfirebedrock
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+)
+
+// invokeRequest is the body of an InvokeModel request, in any of the formats of the Anthropic
+// (messages with typed blocks), Amazon Nova (messages with untyped blocks), Amazon Titan
+// (inputText), Meta Llama and Mistral (prompt) models. Each format sets its own fields.
+type invokeRequest struct {
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	System    json.RawMessage `json:"system"`
+	Prompt    string          `json:"prompt"`
+	InputText string          `json:"inputText"`
+	Tools     []any           `json:"tools"`
+
+	MaxTokens     *int64   `json:"max_tokens"`
+	MaxGenLen     *int64   `json:"max_gen_len"`
+	Temperature   *float64 `json:"temperature"`
+	TopP          *float64 `json:"top_p"`
+	TopK          *float64 `json:"top_k"`
+	StopSequences []string `json:"stop_sequences"`
+	Stop          []string `json:"stop"`
+	Dimensions    *int64   `json:"dimensions"`
+
+	InferenceConfig *struct {
+		MaxTokens     *int64   `json:"maxTokens"`
+		MaxNewTokens  *int64   `json:"max_new_tokens"`
+		Temperature   *float64 `json:"temperature"`
+		TopP          *float64 `json:"topP"`
+		TopK          *float64 `json:"topK"`
+		StopSequences []string `json:"stopSequences"`
+	} `json:"inferenceConfig"`
+	TextGenerationConfig *struct {
+		MaxTokenCount *int64   `json:"maxTokenCount"`
+		Temperature   *float64 `json:"temperature"`
+		TopP          *float64 `json:"topP"`
+		StopSequences []string `json:"stopSequences"`
+	} `json:"textGenerationConfig"`
+}
+
+func (r invokeRequest) params() genaiconv.Params {
+	p := genaiconv.Params{
+		MaxTokens:     r.MaxTokens,
+		Temperature:   r.Temperature,
+		TopP:          r.TopP,
+		TopK:          r.TopK,
+		StopSequences: r.StopSequences,
+	}
+	if r.MaxGenLen != nil {
+		p.MaxTokens = r.MaxGenLen
+	}
+	if r.Stop != nil {
+		p.StopSequences = r.Stop
+	}
+	if c := r.InferenceConfig; c != nil {
+		p.MaxTokens, p.Temperature, p.TopP, p.TopK, p.StopSequences = c.MaxTokens, c.Temperature, c.TopP, c.TopK, c.StopSequences
+		if c.MaxNewTokens != nil {
+			p.MaxTokens = c.MaxNewTokens
+		}
+	}
+	if c := r.TextGenerationConfig; c != nil {
+		p.MaxTokens, p.Temperature, p.TopP, p.StopSequences = c.MaxTokenCount, c.Temperature, c.TopP, c.StopSequences
+	}
+	return p
+}
+
+// block is a content block of the Anthropic or Amazon Nova formats.
+type block struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	Thinking  string          `json:"thinking"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	Source    *struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+	} `json:"source"`
+
+	ToolUse *struct {
+		ToolUseID string          `json:"toolUseId"`
+		Name      string          `json:"name"`
+		Input     json.RawMessage `json:"input"`
+	} `json:"toolUse"`
+	ToolResult *struct {
+		ToolUseID string          `json:"toolUseId"`
+		Content   json.RawMessage `json:"content"`
+	} `json:"toolResult"`
+	Image *struct {
+		Format string `json:"format"`
+	} `json:"image"`
+}
+
+// blocks decodes content which is either a string or an array of blocks.
+func blocks(raw json.RawMessage) []block {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		if text == "" {
+			return nil
+		}
+		return []block{{Type: "text", Text: text}}
+	}
+	var bs []block
+	json.Unmarshal(raw, &bs)
+	return bs
+}
+
+func (b block) part() genaiconv.Part {
+	switch {
+	case b.ToolUse != nil:
+		return genaiconv.ToolCallPart(b.ToolUse.ToolUseID, b.ToolUse.Name, b.ToolUse.Input)
+	case b.ToolResult != nil:
+		return genaiconv.ToolCallResponsePart(b.ToolResult.ToolUseID, b.ToolResult.Content)
+	case b.Image != nil:
+		return genaiconv.Part{Type: "blob", MIMEType: "image/" + b.Image.Format}
+	}
+	switch b.Type {
+	case "", "text":
+		return genaiconv.TextPart(b.Text)
+	case "thinking":
+		return genaiconv.Part{Type: "reasoning", Content: b.Thinking}
+	case "tool_use":
+		return genaiconv.ToolCallPart(b.ID, b.Name, b.Input)
+	case "tool_result":
+		var text strings.Builder
+		for _, c := range blocks(b.Content) {
+			text.WriteString(c.Text)
+		}
+		return genaiconv.ToolCallResponsePart(b.ToolUseID, text.String())
+	case "image", "document":
+		if b.Source != nil {
+			return genaiconv.Part{Type: "blob", MIMEType: b.Source.MediaType}
+		}
+	}
+	return genaiconv.Part{Type: b.Type}
+}
+
+func parts(bs []block) []genaiconv.Part {
+	parts := []genaiconv.Part{}
+	for _, b := range bs {
+		parts = append(parts, b.part())
+	}
+	return parts
+}
+
+// invokeCall parses the body of an InvokeModel request. Models whose format isn't known are
+// still traced, with their usage from the headers of the response.
+func invokeCall(modelID string, body []byte, content bool) genaiconv.Call {
+	call := genaiconv.Call{Operation: genaiconv.OperationTextCompletion, Model: modelID}
+	if strings.Contains(modelID, "embed") {
+		call.Operation = genaiconv.OperationEmbeddings
+	}
+	var req invokeRequest
+	if json.Unmarshal(body, &req) != nil {
+		return call
+	}
+	if len(req.Messages) > 0 {
+		call.Operation = genaiconv.OperationChat
+	}
+	if call.Operation == genaiconv.OperationEmbeddings {
+		if req.Dimensions != nil {
+			call.Attributes = append(call.Attributes, genaiconv.EmbeddingsDimensionCountKey.Int64(*req.Dimensions))
+		}
+		return call
+	}
+	call.Attributes = req.params().Attributes()
+	if !content {
+		return call
+	}
+	if system := blocks(req.System); len(system) > 0 {
+		call.System = parts(system)
+	}
+	for _, m := range req.Messages {
+		call.Messages = append(call.Messages, genaiconv.Message{Role: m.Role, Parts: parts(blocks(m.Content))})
+	}
+	if prompt := req.Prompt + req.InputText; prompt != "" {
+		call.Messages = append(call.Messages, genaiconv.Message{Role: "user", Parts: []genaiconv.Part{genaiconv.TextPart(prompt)}})
+	}
+	if len(req.Tools) > 0 {
+		call.Attributes = append(call.Attributes, genaiconv.JSON(genaiconv.ToolDefinitionsKey, req.Tools))
+	}
+	return call
+}
+
+// invokeBody is the body of an InvokeModel response, in any of the formats of invokeRequest.
+type invokeBody struct {
+	// Anthropic
+	ID         string  `json:"id"`
+	Model      string  `json:"model"`
+	Content    []block `json:"content"`
+	StopReason string  `json:"stop_reason"`
+	// Usage is in the format of the Anthropic models, or the camel case of the Amazon Nova models.
+	Usage *struct {
+		InputTokens              int64 `json:"input_tokens"`
+		OutputTokens             int64 `json:"output_tokens"`
+		CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+		NovaInputTokens          int64 `json:"inputTokens"`
+		NovaOutputTokens         int64 `json:"outputTokens"`
+		NovaCacheWriteTokens     int64 `json:"cacheWriteInputTokenCount"`
+		NovaCacheReadTokens      int64 `json:"cacheReadInputTokenCount"`
+	} `json:"usage"`
+	// Amazon Nova
+	Output *struct {
+		Message struct {
+			Role    string  `json:"role"`
+			Content []block `json:"content"`
+		} `json:"message"`
+	} `json:"output"`
+	NovaStopReason string `json:"stopReason"`
+	// Amazon Titan
+	Results []struct {
+		OutputText       string `json:"outputText"`
+		CompletionReason string `json:"completionReason"`
+	} `json:"results"`
+	// Meta Llama
+	Generation string `json:"generation"`
+	// Mistral
+	Outputs []struct {
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"outputs"`
+}
+
+// output returns the messages of the response and why the generation stopped.
+func (b invokeBody) output() []genaiconv.Message {
+	text := func(text, reason string) genaiconv.Message {
+		return genaiconv.Message{Role: "assistant", Parts: []genaiconv.Part{genaiconv.TextPart(text)}, FinishReason: reason}
+	}
+	switch {
+	case len(b.Content) > 0:
+		return []genaiconv.Message{{Role: "assistant", Parts: parts(b.Content), FinishReason: b.StopReason}}
+	case b.Output != nil:
+		return []genaiconv.Message{{Role: b.Output.Message.Role, Parts: parts(b.Output.Message.Content), FinishReason: b.NovaStopReason}}
+	case len(b.Results) > 0:
+		var msgs []genaiconv.Message
+		for _, r := range b.Results {
+			msgs = append(msgs, text(r.OutputText, r.CompletionReason))
+		}
+		return msgs
+	case len(b.Outputs) > 0:
+		var msgs []genaiconv.Message
+		for _, o := range b.Outputs {
+			msgs = append(msgs, text(o.Text, o.StopReason))
+		}
+		return msgs
+	case b.Generation != "":
+		return []genaiconv.Message{text(b.Generation, b.StopReason)}
+	}
+	return nil
+}
+
+// headerCount returns a token count reported in the headers of a response.
+func headerCount(resp *smithyhttp.Response, name string) (int64, bool) {
+	n, err := strconv.ParseInt(resp.Header.Get(name), 10, 64)
+	return n, err == nil
+}
+
+func invokeResponse(out middleware.InitializeOutput, metadata middleware.Metadata, span *genaiconv.Span) bool {
+	result, ok := out.Result.(*bedrockruntime.InvokeModelOutput)
+	if !ok {
+		return false
+	}
+	var resp genaiconv.Response
+	var body invokeBody
+	if json.Unmarshal(result.Body, &body) == nil {
+		resp.ID, resp.Model, resp.Output = body.ID, body.Model, body.output()
+		for _, msg := range resp.Output {
+			if msg.FinishReason != "" {
+				resp.FinishReasons = append(resp.FinishReasons, msg.FinishReason)
+			}
+		}
+		if u := body.Usage; u != nil {
+			cacheCreation := u.CacheCreationInputTokens + u.NovaCacheWriteTokens
+			cacheRead := u.CacheReadInputTokens + u.NovaCacheReadTokens
+			resp.Usage = &genaiconv.Usage{
+				InputTokens:              u.InputTokens + u.NovaInputTokens + cacheCreation + cacheRead,
+				OutputTokens:             u.OutputTokens + u.NovaOutputTokens,
+				CacheCreationInputTokens: cacheCreation,
+				CacheReadInputTokens:     cacheRead,
+			}
+		}
+	}
+	// Bedrock reports the usage of all models in the headers, which the bodies of some formats lack.
+	if raw, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok && resp.Usage == nil {
+		if input, ok := headerCount(raw, "X-Amzn-Bedrock-Input-Token-Count"); ok {
+			resp.Usage = &genaiconv.Usage{InputTokens: input}
+			resp.Usage.OutputTokens, _ = headerCount(raw, "X-Amzn-Bedrock-Output-Token-Count")
+		}
+	}
+	span.End(resp)
+	return true
+}
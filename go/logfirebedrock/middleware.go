@@ -0,0 +1,289 @@
+package logfirebedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+)
+
+// middlewareID identifies the middleware in the stacks of the clients.
+const middlewareID = "logfire.bedrock"
+
+// provider is the `gen_ai.provider.name` of the spans.
+const provider = "aws.bedrock"
+
+// AppendMiddlewares adds the middleware creating the spans of Bedrock Runtime calls to the API options
+// of a config, usually `&cfg.APIOptions`. Calls of other operations and services aren't traced.
+func AppendMiddlewares(apiOptions *[]func(*middleware.Stack) error, opts ...Option) {
+	c := newConfig(opts)
+	*apiOptions = append(*apiOptions, func(stack *middleware.Stack) error {
+		// After the middleware of logfireaws, if any, so that the spans are children of its spans.
+		if err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc(middlewareID, c.handleInitialize), middleware.After); err != nil {
+			return err
+		}
+		if _, ok := stack.Deserialize.Get(eventStreamDeserializerID); !ok {
+			return nil
+		}
+		// Between the deserializer of the event stream and the transport, to read the events as they're decoded.
+		return stack.Deserialize.Insert(middleware.DeserializeMiddlewareFunc(middlewareID, handleDeserialize), eventStreamDeserializerID, middleware.After)
+	})
+}
+
+// handleInitialize creates the span of a call, which includes all of its attempts.
+func (c *config) handleInitialize(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	var call genaiconv.Call
+	var response func(out middleware.InitializeOutput, metadata middleware.Metadata, span *genaiconv.Span) bool
+	var stream *eventStream
+	switch params := in.Parameters.(type) {
+	case *bedrockruntime.ConverseInput:
+		call = converseCall(params.ModelId, params.Messages, params.System, params.InferenceConfig, params.ToolConfig, c.content)
+		if params.GuardrailConfig != nil && params.GuardrailConfig.GuardrailIdentifier != nil {
+			call.Attributes = append(call.Attributes, semconv.AWSBedrockGuardrailID(*params.GuardrailConfig.GuardrailIdentifier))
+		}
+		response = converseResponse
+	case *bedrockruntime.ConverseStreamInput:
+		call = converseCall(params.ModelId, params.Messages, params.System, params.InferenceConfig, params.ToolConfig, c.content)
+		if params.GuardrailConfig != nil && params.GuardrailConfig.GuardrailIdentifier != nil {
+			call.Attributes = append(call.Attributes, semconv.AWSBedrockGuardrailID(*params.GuardrailConfig.GuardrailIdentifier))
+		}
+		call.Stream = true
+		stream = &eventStream{}
+	case *bedrockruntime.InvokeModelInput:
+		var modelID string
+		if params.ModelId != nil {
+			modelID = *params.ModelId
+		}
+		call = invokeCall(modelID, params.Body, c.content)
+		if params.GuardrailIdentifier != nil {
+			call.Attributes = append(call.Attributes, semconv.AWSBedrockGuardrailID(*params.GuardrailIdentifier))
+		}
+		response = invokeResponse
+	default:
+		return next.HandleInitialize(ctx, in)
+	}
+	call.Attributes = append(call.Attributes, genaiconv.RequestStreamKey.Bool(call.Stream))
+
+	ctx, span := genaiconv.Start(ctx, c.tracer(), provider, call.Operation, call.Model, c.content,
+		append(call.Attributes, c.attrs...)...)
+	span.Input(call.System, call.Messages)
+	span.Metrics(c.metrics())
+	if stream != nil {
+		stream.span = span
+		span.StreamProgress(c.progress, stream.response)
+		ctx = context.WithValue(ctx, eventStreamKey{}, stream)
+	}
+	out, metadata, err := next.HandleInitialize(ctx, in)
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			span.Span().SetAttributes(semconv.ErrorTypeKey.String(apiErr.ErrorCode()))
+		}
+		span.Fail(err, genaiconv.Response{})
+		return out, metadata, err
+	}
+	switch {
+	case stream != nil:
+		// The span ends once the events are read, unless the body of the response isn't an event stream.
+		if !stream.started {
+			span.End(genaiconv.Response{})
+		}
+	case !response(out, metadata, span):
+		span.End(genaiconv.Response{})
+	}
+	return out, metadata, nil
+}
+
+func converseCall(modelID *string, messages []types.Message, system []types.SystemContentBlock, inference *types.InferenceConfiguration, tools *types.ToolConfiguration, content bool) genaiconv.Call {
+	call := genaiconv.Call{Operation: genaiconv.OperationChat}
+	if modelID != nil {
+		call.Model = *modelID
+	}
+	if inference != nil {
+		params := genaiconv.Params{
+			MaxTokens:     int32Ptr(inference.MaxTokens),
+			Temperature:   float32Ptr(inference.Temperature),
+			TopP:          float32Ptr(inference.TopP),
+			StopSequences: inference.StopSequences,
+		}
+		call.Attributes = params.Attributes()
+	}
+	if !content {
+		return call
+	}
+	for _, block := range system {
+		if text, ok := block.(*types.SystemContentBlockMemberText); ok {
+			call.System = append(call.System, genaiconv.TextPart(text.Value))
+		}
+	}
+	for _, m := range messages {
+		call.Messages = append(call.Messages, message(m))
+	}
+	if tools != nil && len(tools.Tools) > 0 {
+		call.Attributes = append(call.Attributes, genaiconv.JSON(genaiconv.ToolDefinitionsKey, toolDefinitions(tools.Tools)))
+	}
+	return call
+}
+
+func int32Ptr(i *int32) *int64 {
+	if i == nil {
+		return nil
+	}
+	v := int64(*i)
+	return &v
+}
+
+func float32Ptr(f *float32) *float64 {
+	if f == nil {
+		return nil
+	}
+	v := float64(*f)
+	return &v
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// documentJSON returns a document as raw JSON, or nil if it can't be marshaled.
+func documentJSON(d document.Interface) any {
+	if d == nil {
+		return nil
+	}
+	b, err := d.MarshalSmithyDocument()
+	if err != nil {
+		return nil
+	}
+	return json.RawMessage(b)
+}
+
+type toolDefinition struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+func toolDefinitions(tools []types.Tool) []toolDefinition {
+	var defs []toolDefinition
+	for _, tool := range tools {
+		spec, ok := tool.(*types.ToolMemberToolSpec)
+		if !ok {
+			continue
+		}
+		def := toolDefinition{Type: "function", Name: str(spec.Value.Name), Description: str(spec.Value.Description)}
+		if schema, ok := spec.Value.InputSchema.(*types.ToolInputSchemaMemberJson); ok {
+			def.Parameters = documentJSON(schema.Value)
+		}
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+func message(m types.Message) genaiconv.Message {
+	msg := genaiconv.Message{Role: string(m.Role), Parts: []genaiconv.Part{}}
+	for _, block := range m.Content {
+		msg.Parts = append(msg.Parts, part(block))
+	}
+	return msg
+}
+
+func part(block types.ContentBlock) genaiconv.Part {
+	switch b := block.(type) {
+	case *types.ContentBlockMemberText:
+		return genaiconv.TextPart(b.Value)
+	case *types.ContentBlockMemberReasoningContent:
+		if text, ok := b.Value.(*types.ReasoningContentBlockMemberReasoningText); ok {
+			return genaiconv.Part{Type: "reasoning", Content: str(text.Value.Text)}
+		}
+		return genaiconv.Part{Type: "reasoning"}
+	case *types.ContentBlockMemberToolUse:
+		return genaiconv.ToolCallPart(str(b.Value.ToolUseId), str(b.Value.Name), documentJSON(b.Value.Input))
+	case *types.ContentBlockMemberToolResult:
+		var results []any
+		for _, c := range b.Value.Content {
+			switch r := c.(type) {
+			case *types.ToolResultContentBlockMemberText:
+				results = append(results, r.Value)
+			case *types.ToolResultContentBlockMemberJson:
+				results = append(results, documentJSON(r.Value))
+			}
+		}
+		var response any = results
+		if len(results) == 1 {
+			response = results[0]
+		}
+		return genaiconv.ToolCallResponsePart(str(b.Value.ToolUseId), response)
+	case *types.ContentBlockMemberImage:
+		return mediaPart("image/"+string(b.Value.Format), b.Value.Source)
+	case *types.ContentBlockMemberDocument:
+		return mediaPart("", b.Value.Source)
+	case *types.ContentBlockMemberVideo:
+		return mediaPart("video/"+string(b.Value.Format), b.Value.Source)
+	}
+	return genaiconv.Part{Type: "unknown"}
+}
+
+// mediaPart returns a part for an image, document or video, which is a URI if it's stored in S3.
+func mediaPart(mimeType string, source any) genaiconv.Part {
+	switch s := source.(type) {
+	case *types.ImageSourceMemberS3Location:
+		return genaiconv.Part{Type: "uri", MIMEType: mimeType, URI: str(s.Value.Uri)}
+	case *types.DocumentSourceMemberS3Location:
+		return genaiconv.Part{Type: "uri", MIMEType: mimeType, URI: str(s.Value.Uri)}
+	case *types.VideoSourceMemberS3Location:
+		return genaiconv.Part{Type: "uri", MIMEType: mimeType, URI: str(s.Value.Uri)}
+	}
+	return genaiconv.Part{Type: "blob", MIMEType: mimeType}
+}
+
+// usage returns the usage of a call, whose input tokens don't include the cached tokens.
+func usage(u *types.TokenUsage) *genaiconv.Usage {
+	if u == nil {
+		return nil
+	}
+	usage := &genaiconv.Usage{}
+	if u.InputTokens != nil {
+		usage.InputTokens = int64(*u.InputTokens)
+	}
+	if u.OutputTokens != nil {
+		usage.OutputTokens = int64(*u.OutputTokens)
+	}
+	if u.CacheReadInputTokens != nil {
+		usage.CacheReadInputTokens = int64(*u.CacheReadInputTokens)
+	}
+	if u.CacheWriteInputTokens != nil {
+		usage.CacheCreationInputTokens = int64(*u.CacheWriteInputTokens)
+	}
+	usage.InputTokens += usage.CacheReadInputTokens + usage.CacheCreationInputTokens
+	return usage
+}
+
+func converseResponse(out middleware.InitializeOutput, _ middleware.Metadata, span *genaiconv.Span) bool {
+	result, ok := out.Result.(*bedrockruntime.ConverseOutput)
+	if !ok {
+		return false
+	}
+	resp := genaiconv.Response{Usage: usage(result.Usage)}
+	if result.StopReason != "" {
+		resp.FinishReasons = []string{string(result.StopReason)}
+	}
+	if output, ok := result.Output.(*types.ConverseOutputMemberMessage); ok {
+		msg := message(output.Value)
+		msg.FinishReason = string(result.StopReason)
+		resp.Output = []genaiconv.Message{msg}
+	}
+	span.End(resp)
+	return true
+}
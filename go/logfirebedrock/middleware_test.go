@@ -0,0 +1,321 @@
+package logfirebedrock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func checkJSON(t *testing.T, attrs []attribute.KeyValue, key attribute.Key, want string) {
+	t.Helper()
+	got, _ := oteltest.Attributes(attrs)[key].(string)
+	var g, w any
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Errorf("%s = %q, not JSON", key, got)
+		return
+	}
+	json.Unmarshal([]byte(want), &w)
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("%s = %s, want %s", key, gb, wb)
+	}
+}
+
+// newTestClient returns a Bedrock Runtime client sending its requests to handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) (*bedrockruntime.Client, *tracetest.SpanRecorder) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	cfg := aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	AppendMiddlewares(&cfg.APIOptions, append(opts, WithTracerProvider(provider))...)
+	return bedrockruntime.NewFromConfig(cfg), recorder
+}
+
+const model = "anthropic.claude-sonnet-4-5-20250929-v1:0"
+
+func TestConverse(t *testing.T) {
+	client, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{
+			"output": {"message": {"role": "assistant", "content": [
+				{"text": "Let me check."},
+				{"toolUse": {"toolUseId": "t1", "name": "weather", "input": {"city": "Paris"}}}
+			]}},
+			"stopReason": "tool_use",
+			"usage": {"inputTokens": 10, "outputTokens": 5, "totalTokens": 15, "cacheReadInputTokens": 90},
+			"metrics": {"latencyMs": 100}
+		}`)
+	}, WithMessageContent())
+
+	_, err := client.Converse(t.Context(), &bedrockruntime.ConverseInput{
+		ModelId: aws.String(model),
+		System:  []types.SystemContentBlock{&types.SystemContentBlockMemberText{Value: "Be brief."}},
+		Messages: []types.Message{{
+			Role:    types.ConversationRoleUser,
+			Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "Weather in Paris?"}},
+		}},
+		InferenceConfig: &types.InferenceConfiguration{MaxTokens: aws.Int32(100), Temperature: aws.Float32(0.5)},
+		ToolConfig: &types.ToolConfiguration{Tools: []types.Tool{&types.ToolMemberToolSpec{Value: types.ToolSpecification{
+			Name:        aws.String("weather"),
+			InputSchema: &types.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(map[string]any{"type": "object"})},
+		}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "chat "+model {
+		t.Errorf("name = %s", span.Name())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"gen_ai.provider.name":                 "aws.bedrock",
+		"gen_ai.request.model":                 model,
+		"gen_ai.request.max_tokens":            int64(100),
+		"gen_ai.request.temperature":           0.5,
+		"gen_ai.request.stream":                false,
+		"gen_ai.usage.input_tokens":            int64(100),
+		"gen_ai.usage.output_tokens":           int64(5),
+		"gen_ai.usage.cache_read.input_tokens": int64(90),
+	})
+	checkJSON(t, span.Attributes(), "gen_ai.system_instructions", `[{"type":"text","content":"Be brief."}]`)
+	checkJSON(t, span.Attributes(), "gen_ai.input.messages", `[{"role":"user","parts":[{"type":"text","content":"Weather in Paris?"}]}]`)
+	checkJSON(t, span.Attributes(), "gen_ai.output.messages", `[{"role":"assistant","parts":[
+		{"type":"text","content":"Let me check."},
+		{"type":"tool_call","id":"t1","name":"weather","arguments":{"city":"Paris"}}
+	],"finish_reason":"tool_use"}]`)
+	checkJSON(t, span.Attributes(), "gen_ai.tool.definitions", `[{"type":"function","name":"weather","parameters":{"type":"object"}}]`)
+}
+
+func TestConverseError(t *testing.T) {
+	client, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amzn-ErrorType", "ValidationException")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"message": "The provided model identifier is invalid."}`)
+	})
+	_, err := client.Converse(t.Context(), &bedrockruntime.ConverseInput{ModelId: aws.String("nope")})
+	if err == nil {
+		t.Fatal("no error")
+	}
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{"error.type": "ValidationException"})
+}
+
+// encodeEvents encodes events of a streamed response.
+func encodeEvents(t *testing.T, events ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	encoder := eventstream.NewEncoder()
+	for _, e := range events {
+		err := encoder.Encode(&buf, eventstream.Message{
+			Headers: eventstream.Headers{
+				{Name: ":message-type", Value: eventstream.StringValue("event")},
+				{Name: ":event-type", Value: eventstream.StringValue(e[0])},
+				{Name: ":content-type", Value: eventstream.StringValue("application/json")},
+			},
+			Payload: []byte(e[1]),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestConverseStream(t *testing.T) {
+	body := encodeEvents(t,
+		[2]string{"messageStart", `{"role":"assistant"}`},
+		[2]string{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Hel"}}`},
+		[2]string{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"lo"}}`},
+		[2]string{"contentBlockStop", `{"contentBlockIndex":0}`},
+		[2]string{"messageStop", `{"stopReason":"end_turn"}`},
+		[2]string{"metadata", `{"usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5},"metrics":{"latencyMs":10}}`},
+	)
+	client, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.Write(body)
+	}, WithMessageContent(), WithStreamProgress(0))
+
+	out, err := client.ConverseStream(t.Context(), &bedrockruntime.ConverseStreamInput{
+		ModelId: aws.String(model),
+		Messages: []types.Message{{
+			Role:    types.ConversationRoleUser,
+			Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "Hi"}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := out.GetStream()
+	var text strings.Builder
+	for event := range stream.Events() {
+		if delta, ok := event.(*types.ConverseStreamOutputMemberContentBlockDelta); ok {
+			text.WriteString(delta.Value.Delta.(*types.ContentBlockDeltaMemberText).Value)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "Hello" {
+		t.Errorf("text = %q", text.String())
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		"gen_ai.request.stream":       true,
+		"gen_ai.usage.input_tokens":   int64(3),
+		"gen_ai.usage.output_tokens":  int64(2),
+		"gen_ai.response.chunk_count": int64(2),
+	})
+	checkJSON(t, spans[0].Attributes(), "gen_ai.output.messages",
+		`[{"role":"assistant","parts":[{"type":"text","content":"Hello"}],"finish_reason":"end_turn"}]`)
+}
+
+func TestConverseStreamClosed(t *testing.T) {
+	body := encodeEvents(t, [2]string{"messageStart", `{"role":"assistant"}`})
+	client, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.Write(body)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	out, err := client.ConverseStream(t.Context(), &bedrockruntime.ConverseStreamInput{ModelId: aws.String(model)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := out.GetStream()
+	<-stream.Events()
+	stream.Close()
+	for range stream.Events() {
+	}
+	if spans := recorder.Ended(); len(spans) != 1 {
+		t.Fatalf("spans = %d, want the span ended by Close", len(spans))
+	}
+}
+
+func TestInvokeModel(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		model   string
+		request string
+		headers map[string]string
+		body    string
+		op      string
+		want    map[attribute.Key]any
+		output  string
+	}{
+		{
+			name:    "anthropic",
+			model:   model,
+			request: `{"anthropic_version":"bedrock-2023-05-31","max_tokens":100,"system":"Be brief.","messages":[{"role":"user","content":"Hi"}]}`,
+			body:    `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5-20250929","content":[{"type":"text","text":"Hello"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":2}}`,
+			op:      "chat",
+			want: map[attribute.Key]any{
+				"gen_ai.request.max_tokens":  int64(100),
+				"gen_ai.response.id":         "msg_1",
+				"gen_ai.usage.input_tokens":  int64(3),
+				"gen_ai.usage.output_tokens": int64(2),
+			},
+			output: `[{"role":"assistant","parts":[{"type":"text","content":"Hello"}],"finish_reason":"end_turn"}]`,
+		},
+		{
+			name:    "nova",
+			model:   "amazon.nova-lite-v1:0",
+			request: `{"schemaVersion":"messages-v1","messages":[{"role":"user","content":[{"text":"Hi"}]}],"inferenceConfig":{"maxTokens":50,"topP":0.9}}`,
+			headers: map[string]string{"X-Amzn-Bedrock-Input-Token-Count": "4", "X-Amzn-Bedrock-Output-Token-Count": "6"},
+			body:    `{"output":{"message":{"role":"assistant","content":[{"text":"Hello"}]}},"stopReason":"end_turn","usage":{"inputTokens":4,"outputTokens":6}}`,
+			op:      "chat",
+			want: map[attribute.Key]any{
+				"gen_ai.request.max_tokens":  int64(50),
+				"gen_ai.request.top_p":       0.9,
+				"gen_ai.usage.input_tokens":  int64(4),
+				"gen_ai.usage.output_tokens": int64(6),
+			},
+			output: `[{"role":"assistant","parts":[{"type":"text","content":"Hello"}],"finish_reason":"end_turn"}]`,
+		},
+		{
+			name:    "llama",
+			model:   "meta.llama3-8b-instruct-v1:0",
+			request: `{"prompt":"Hi","max_gen_len":64,"temperature":0.1}`,
+			headers: map[string]string{"X-Amzn-Bedrock-Input-Token-Count": "2", "X-Amzn-Bedrock-Output-Token-Count": "1"},
+			body:    `{"generation":"Hello","prompt_token_count":2,"generation_token_count":1,"stop_reason":"stop"}`,
+			op:      "text_completion",
+			want: map[attribute.Key]any{
+				"gen_ai.request.max_tokens":  int64(64),
+				"gen_ai.usage.input_tokens":  int64(2),
+				"gen_ai.usage.output_tokens": int64(1),
+			},
+			output: `[{"role":"assistant","parts":[{"type":"text","content":"Hello"}],"finish_reason":"stop"}]`,
+		},
+		{
+			name:    "titan embeddings",
+			model:   "amazon.titan-embed-text-v2:0",
+			request: `{"inputText":"Hi","dimensions":256}`,
+			headers: map[string]string{"X-Amzn-Bedrock-Input-Token-Count": "2"},
+			body:    `{"embedding":[0.1,0.2],"inputTextTokenCount":2}`,
+			op:      "embeddings",
+			want: map[attribute.Key]any{
+				"gen_ai.embeddings.dimension.count": int64(256),
+				"gen_ai.usage.input_tokens":         int64(2),
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			client, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range test.headers {
+					w.Header().Set(k, v)
+				}
+				io.WriteString(w, test.body)
+			}, WithMessageContent())
+			_, err := client.InvokeModel(t.Context(), &bedrockruntime.InvokeModelInput{
+				ModelId:     aws.String(test.model),
+				Body:        []byte(test.request),
+				ContentType: aws.String("application/json"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			span := recorder.Ended()[0]
+			if span.Name() != test.op+" "+test.model {
+				t.Errorf("name = %s", span.Name())
+			}
+			oteltest.CheckAttributes(t, span.Attributes(), test.want)
+			if test.output != "" {
+				checkJSON(t, span.Attributes(), "gen_ai.output.messages", test.output)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package logfirebedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+)
+
+// eventStreamDeserializerID identifies the middleware of the clients decoding event streams.
+const eventStreamDeserializerID = "OperationEventStreamDeserializer"
+
+type eventStreamKey struct{}
+
+// handleDeserialize wraps the body of the response of a ConverseStream call, whose events are
+// decoded by the SDK in its own goroutine, to record them as they're read.
+func handleDeserialize(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+	out, metadata, err := next.HandleDeserialize(ctx, in)
+	stream, _ := ctx.Value(eventStreamKey{}).(*eventStream)
+	if resp, ok := out.RawResponse.(*smithyhttp.Response); ok && err == nil && stream != nil && !stream.started {
+		stream.started = true
+		stream.body = resp.Body
+		resp.Body = stream
+	}
+	return out, metadata, err
+}
+
+// eventStream is the body of a streamed response, whose events it accumulates. The span ends with
+// the metadata event, which is the last one, or once the body is read or closed.
+type eventStream struct {
+	span    *genaiconv.Span
+	body    io.ReadCloser
+	started bool
+	pending []byte
+
+	// mu guards the events accumulated, as the stream may be closed while it's read.
+	mu    sync.Mutex
+	ended bool
+
+	role    string
+	blocks  map[int]*streamBlock
+	indices []int
+	resp    genaiconv.Response
+}
+
+type streamBlock struct {
+	text      strings.Builder
+	reasoning strings.Builder
+	toolUse   *toolUseStart
+	input     strings.Builder
+}
+
+type toolUseStart struct {
+	ToolUseID string `json:"toolUseId"`
+	Name      string `json:"name"`
+}
+
+func (s *eventStream) Read(p []byte) (int, error) {
+	n, err := s.body.Read(p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, p[:n]...)
+	// A message starts with its total length, including the prelude and checksums.
+	for len(s.pending) >= 4 {
+		size := int(binary.BigEndian.Uint32(s.pending))
+		if len(s.pending) < size {
+			break
+		}
+		msg, decodeErr := eventstream.NewDecoder().Decode(bytes.NewReader(s.pending[:size]), nil)
+		s.pending = s.pending[size:]
+		if decodeErr != nil {
+			s.end(decodeErr)
+			break
+		}
+		if done, eventErr := s.event(msg); done || eventErr != nil {
+			s.end(eventErr)
+		}
+	}
+	switch {
+	case err == io.EOF:
+		s.end(nil)
+	case err != nil:
+		s.end(err)
+	}
+	return n, err
+}
+
+func (s *eventStream) Close() error {
+	s.mu.Lock()
+	s.end(nil)
+	s.mu.Unlock()
+	return s.body.Close()
+}
+
+func header(msg eventstream.Message, name string) string {
+	if v := msg.Headers.Get(name); v != nil {
+		return v.String()
+	}
+	return ""
+}
+
+func (s *eventStream) block(index int) *streamBlock {
+	if s.blocks == nil {
+		s.blocks = map[int]*streamBlock{}
+	}
+	b, ok := s.blocks[index]
+	if !ok {
+		b = &streamBlock{}
+		s.blocks[index] = b
+		s.indices = append(s.indices, index)
+	}
+	return b
+}
+
+// event records an event, returning whether it's the last one or the exception streamed.
+func (s *eventStream) event(msg eventstream.Message) (bool, error) {
+	if header(msg, ":message-type") == "exception" {
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(msg.Payload, &e)
+		if e.Message == "" {
+			e.Message = header(msg, ":exception-type")
+		}
+		return true, errors.New(e.Message)
+	}
+	var e struct {
+		Role              string `json:"role"`
+		ContentBlockIndex int    `json:"contentBlockIndex"`
+		Start             struct {
+			ToolUse *toolUseStart `json:"toolUse"`
+		} `json:"start"`
+		Delta struct {
+			Text             string `json:"text"`
+			ReasoningContent struct {
+				Text string `json:"text"`
+			} `json:"reasoningContent"`
+			ToolUse struct {
+				Input string `json:"input"`
+			} `json:"toolUse"`
+		} `json:"delta"`
+		StopReason string `json:"stopReason"`
+		Usage      *struct {
+			InputTokens           int64 `json:"inputTokens"`
+			OutputTokens          int64 `json:"outputTokens"`
+			CacheReadInputTokens  int64 `json:"cacheReadInputTokens"`
+			CacheWriteInputTokens int64 `json:"cacheWriteInputTokens"`
+		} `json:"usage"`
+	}
+	if json.Unmarshal(msg.Payload, &e) != nil {
+		return false, nil
+	}
+	switch header(msg, ":event-type") {
+	case "messageStart":
+		s.role = e.Role
+	case "contentBlockStart":
+		if e.Start.ToolUse != nil {
+			s.block(e.ContentBlockIndex).toolUse = e.Start.ToolUse
+		}
+	case "contentBlockDelta":
+		b := s.block(e.ContentBlockIndex)
+		b.text.WriteString(e.Delta.Text)
+		b.reasoning.WriteString(e.Delta.ReasoningContent.Text)
+		b.input.WriteString(e.Delta.ToolUse.Input)
+		s.span.Chunk()
+	case "messageStop":
+		if e.StopReason != "" {
+			s.resp.FinishReasons = []string{e.StopReason}
+		}
+	case "metadata":
+		if u := e.Usage; u != nil {
+			s.resp.Usage = &genaiconv.Usage{
+				InputTokens:              u.InputTokens + u.CacheReadInputTokens + u.CacheWriteInputTokens,
+				OutputTokens:             u.OutputTokens,
+				CacheReadInputTokens:     u.CacheReadInputTokens,
+				CacheCreationInputTokens: u.CacheWriteInputTokens,
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// end ends the span with the message accumulated, once.
+func (s *eventStream) end(err error) {
+	if s.ended {
+		return
+	}
+	s.ended = true
+	resp := s.response()
+	if err != nil {
+		s.span.Fail(err, resp)
+		return
+	}
+	s.span.End(resp)
+}
+
+// response returns the message accumulated so far.
+func (s *eventStream) response() genaiconv.Response {
+	resp := s.resp
+	role := s.role
+	if role == "" {
+		role = "assistant"
+	}
+	msg := genaiconv.Message{Role: role, Parts: []genaiconv.Part{}}
+	for _, i := range s.indices {
+		b := s.blocks[i]
+		switch {
+		case b.toolUse != nil:
+			msg.Parts = append(msg.Parts, genaiconv.ToolCallPart(b.toolUse.ToolUseID, b.toolUse.Name, b.input.String()))
+		case b.reasoning.Len() > 0:
+			msg.Parts = append(msg.Parts, genaiconv.Part{Type: "reasoning", Content: b.reasoning.String()})
+		default:
+			msg.Parts = append(msg.Parts, genaiconv.TextPart(b.text.String()))
+		}
+	}
+	if len(resp.FinishReasons) > 0 {
+		msg.FinishReason = resp.FinishReasons[0]
+	}
+	resp.Output = []genaiconv.Message{msg}
+	return resp
+}
@@ -0,0 +1,158 @@
+// Package logfirechi instruments chi routers for Pydantic Logfire.
+//
+// [Middleware] creates a server span for each request, named after the matched route pattern
+// like the spans of the Python SDK's web framework integrations:
+//
+//	r := chi.NewRouter()
+//	r.Use(logfirechi.Middleware(logfirechi.WithExcludedPaths("/healthz")))
+//	r.Get("/users/{id}", getUser) // spans named `GET /users/{id}`
+package logfirechi
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirechi"
+
+// Option configures [Middleware].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider used to record the `http.server.request.duration`
+// and `http.server.active_requests` metrics. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to extract the trace context. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithFilter adds a filter for requests: a request is only traced if all filters return true.
+func WithFilter(filter func(*http.Request) bool) Option {
+	return func(c *config) {
+		c.filters = append(c.filters, filter)
+	}
+}
+
+// WithExcludedPaths skips tracing the requests for the given paths, e.g. "/healthz".
+//
+// Paths are used rather than route patterns because the middleware runs before chi
+// matches the route.
+func WithExcludedPaths(paths ...string) Option {
+	return func(c *config) {
+		for _, path := range paths {
+			c.excludedPaths[path] = true
+		}
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagators    propagation.TextMapPropagator
+	filters        []func(*http.Request) bool
+	excludedPaths  map[string]bool
+}
+
+func (c *config) traced(r *http.Request) bool {
+	if c.excludedPaths[r.URL.Path] {
+		return false
+	}
+	for _, filter := range c.filters {
+		if !filter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware returns a chi middleware creating a server span for each request, and recording
+// the `http.server.request.duration` and `http.server.active_requests` metrics.
+//
+// The span is renamed after the route pattern once chi has matched it, e.g. `GET /users/{id}`,
+// so requests which don't match a route keep the name of their method.
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	cfg := &config{excludedPaths: map[string]bool{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cfg.traced(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := instrumentation.Propagator(cfg.propagators).Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := instrumentation.Tracer(cfg.tracerProvider, instrumentationName).Start(ctx,
+				httpconv.SpanName(r.Method, ""),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(httpconv.ServerRequestAttributes(r, "")...),
+			)
+			defer span.End(trace.WithStackTrace(true))
+			metrics := httpconv.StartServerMetrics(ctx, instrumentation.Meter(cfg.meterProvider, instrumentationName),
+				r.Method, httpconv.Scheme(r))
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			r = r.WithContext(ctx)
+			defer func() {
+				if p := recover(); p != nil {
+					span.SetAttributes(semconv.HTTPResponseStatusCode(http.StatusInternalServerError))
+					span.SetStatus(codes.Error, fmt.Sprint(p))
+					metrics.End(ctx, routePattern(r), http.StatusInternalServerError)
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(ww, r)
+
+			route := routePattern(r)
+			if route != "" {
+				span.SetName(httpconv.SpanName(r.Method, route))
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			metrics.End(ctx, route, status)
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, "")
+			}
+		})
+	}
+}
+
+// routePattern returns the route pattern matched by chi, if any. The route context is shared with the router,
+// which fills it in when it matches the route.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
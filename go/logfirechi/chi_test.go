@@ -0,0 +1,133 @@
+package logfirechi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestRouter(t *testing.T, opts ...Option) (*chi.Mux, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	r := chi.NewRouter()
+	r.Use(Middleware(append([]Option{WithTracerProvider(provider)}, opts...)...))
+	return r, recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	r, recorder := newTestRouter(t)
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			if !trace.SpanContextFromContext(r.Context()).IsValid() {
+				t.Error("expected the request context to contain the span")
+			}
+			w.WriteHeader(http.StatusCreated)
+		})
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users/42", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/users/{id}" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		logfire.MsgKey:              "GET /api/users/42",
+		"http.route":                "/api/users/{id}",
+		"http.response.status_code": int64(http.StatusCreated),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+}
+
+func TestMiddlewareNotFound(t *testing.T) {
+	r, recorder := newTestRouter(t)
+	r.Get("/items", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+	span := recorder.Ended()[0]
+	if span.Name() != "GET" || span.Status().Code != codes.Unset {
+		t.Errorf("unexpected span %q with status %v", span.Name(), span.Status())
+	}
+	if got := oteltest.Attributes(span.Attributes())["http.response.status_code"]; got != int64(http.StatusNotFound) {
+		t.Errorf("status code = %v", got)
+	}
+}
+
+func TestMiddlewareServerError(t *testing.T) {
+	r, recorder := newTestRouter(t)
+	r.Post("/items", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusBadGateway)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/items", nil))
+
+	if span := recorder.Ended()[0]; span.Status().Code != codes.Error {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+}
+
+func TestMiddlewareExcludedPaths(t *testing.T) {
+	r, recorder := newTestRouter(t, WithExcludedPaths("/healthz"))
+	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	r, _ := newTestRouter(t, WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+	duration := metrics["http.server.request.duration"].(metricdata.Histogram[float64]).DataPoints[0]
+	want := attribute.NewSet(
+		attribute.String("http.request.method", "GET"),
+		attribute.String("url.scheme", "http"),
+		attribute.String("http.route", "/users/{id}"),
+		attribute.Int("http.response.status_code", http.StatusCreated),
+	)
+	if duration.Count != 1 || !duration.Attributes.Equals(&want) {
+		t.Errorf("unexpected duration %d with attributes %v", duration.Count, duration.Attributes.ToSlice())
+	}
+	if active := metrics["http.server.active_requests"].(metricdata.Sum[int64]).DataPoints[0]; active.Value != 0 {
+		t.Errorf("expected no active requests, got %d", active.Value)
+	}
+}
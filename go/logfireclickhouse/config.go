@@ -0,0 +1,73 @@
+// Package logfireclickhouse instruments clickhouse-go v2 connections for Pydantic Logfire.
+//
+// [Open] and [Wrap] return a connection creating a client span for each query, batch and async insert,
+// named after the operation and table like `SELECT events`, with the sanitized query as message and
+// the blocks, rows and bytes read or written reported by the server:
+//
+//	conn, err := logfireclickhouse.Open(&clickhouse.Options{Addr: []string{"127.0.0.1:9000"}})
+//	ctx = logfireclickhouse.ContextWithQueryID(ctx, "monthly-report")
+//	rows, err := conn.Query(ctx, "SELECT id FROM events WHERE day = ?", day)
+package logfireclickhouse
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireclickhouse"
+
+// Option configures [Open] and [Wrap].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithoutSanitization records queries as is, rather than with their literals replaced by `?`.
+// Only use it if queries never embed sensitive values, e.g. when all values are passed as arguments.
+func WithoutSanitization() Option {
+	return func(c *config) {
+		c.noSanitization = true
+	}
+}
+
+// WithoutProgress stops recording the progress and profile info reported by the server. The callbacks
+// recording them replace those set with [clickhouse.WithProgress] and [clickhouse.WithProfileInfo],
+// so use this option to keep your own.
+func WithoutProgress() Option {
+	return func(c *config) {
+		c.noProgress = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	noSanitization bool
+	noProgress     bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
@@ -0,0 +1,191 @@
+package logfireclickhouse
+
+import (
+	"context"
+	"io"
+	"reflect"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+)
+
+// system is the `db.system.name` of all spans.
+const system = "clickhouse"
+
+const (
+	// queryIDAttrKey records the ID of a query set with [ContextWithQueryID].
+	queryIDAttrKey = attribute.Key("db.clickhouse.query_id")
+	// asyncInsertKey records whether an insert is an async insert.
+	asyncInsertKey = attribute.Key("db.clickhouse.async_insert")
+	// asyncInsertWaitKey records whether an async insert waits for the data to be flushed.
+	asyncInsertWaitKey = attribute.Key("db.clickhouse.async_insert.wait")
+	// abortedKey records that a batch was aborted rather than sent.
+	abortedKey = attribute.Key("db.clickhouse.batch.aborted")
+)
+
+// Open opens a connection like [clickhouse.Open] and wraps it with [Wrap]. The spans also
+// record the database and, if there's a single one, the address of the server.
+func Open(opt *clickhouse.Options, opts ...Option) (driver.Conn, error) {
+	c, err := clickhouse.Open(opt)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(c, newConfig(opts), serverAttributes(opt)), nil
+}
+
+// Wrap returns a connection creating spans for the queries, batches and inserts of c.
+func Wrap(c driver.Conn, opts ...Option) driver.Conn {
+	return wrap(c, newConfig(opts), nil)
+}
+
+func wrap(c driver.Conn, cfg *config, attrs []attribute.KeyValue) driver.Conn {
+	return &conn{Conn: c, config: cfg, attrs: attrs}
+}
+
+// conn embeds the wrapped connection so that the methods added by later versions of clickhouse-go
+// are forwarded, without spans.
+type conn struct {
+	driver.Conn
+	config *config
+	attrs  []attribute.KeyValue
+}
+
+func (c *conn) Select(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, op := c.startQuery(ctx, query)
+	err := c.Conn.Select(ctx, dest, query, args...)
+	if err == nil {
+		if v := reflect.ValueOf(dest); v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Slice {
+			op.span.SetAttributes(semconv.DBResponseReturnedRows(v.Elem().Len()))
+		}
+	}
+	op.end(err)
+	return err
+}
+
+// Query ends the span of the query when its rows are closed, so that it includes reading them.
+func (c *conn) Query(ctx context.Context, query string, args ...any) (driver.Rows, error) {
+	ctx, op := c.startQuery(ctx, query)
+	r, err := c.Conn.Query(ctx, query, args...)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+	return &rows{Rows: r, op: op}, nil
+}
+
+// QueryRow ends the span of the query when its row is scanned.
+func (c *conn) QueryRow(ctx context.Context, query string, args ...any) driver.Row {
+	ctx, op := c.startQuery(ctx, query)
+	r := c.Conn.QueryRow(ctx, query, args...)
+	if err := r.Err(); err != nil {
+		op.end(err)
+		return r
+	}
+	return &row{Row: r, op: op}
+}
+
+// PrepareBatch starts the span of a batch, which ends when it's sent, aborted or closed.
+func (c *conn) PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (driver.Batch, error) {
+	ctx, op := c.startQuery(ctx, query)
+	b, err := c.Conn.PrepareBatch(ctx, query, opts...)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+	return &batch{Batch: b, op: op}, nil
+}
+
+func (c *conn) Exec(ctx context.Context, query string, args ...any) error {
+	ctx, op := c.startQuery(ctx, query)
+	err := c.Conn.Exec(ctx, query, args...)
+	op.end(err)
+	return err
+}
+
+// QueryFormat ends the span of the query when its stream is closed.
+func (c *conn) QueryFormat(ctx context.Context, format string, query string, args ...any) (io.ReadCloser, error) {
+	ctx, op := c.startQuery(ctx, query)
+	r, err := c.Conn.QueryFormat(ctx, format, query, args...)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+	return &stream{ReadCloser: r, op: op}, nil
+}
+
+func (c *conn) InsertFormat(ctx context.Context, format string, query string, data io.Reader) error {
+	ctx, op := c.startQuery(ctx, query)
+	err := c.Conn.InsertFormat(ctx, format, query, data)
+	op.end(err)
+	return err
+}
+
+// AsyncInsert is deprecated in favor of [ContextWithAsync], but still instrumented.
+func (c *conn) AsyncInsert(ctx context.Context, query string, wait bool, args ...any) error {
+	ctx, op := c.startQuery(ctx, query, asyncInsertKey.Bool(true), asyncInsertWaitKey.Bool(wait))
+	err := c.Conn.AsyncInsert(ctx, query, wait, args...)
+	op.end(err)
+	return err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	ctx, op := c.start(ctx, "ping")
+	err := c.Conn.Ping(ctx)
+	op.end(err)
+	return err
+}
+
+// start creates the span of an operation, passing it to the server as the parent of its own spans.
+func (c *conn) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *operation) {
+	if queryID, ok := ctx.Value(queryIDKey{}).(string); ok {
+		attrs = append(attrs, queryIDAttrKey.String(queryID))
+	}
+	if wait, ok := ctx.Value(asyncKey{}).(bool); ok {
+		attrs = append(attrs, asyncInsertKey.Bool(true), asyncInsertWaitKey.Bool(wait))
+	}
+	ctx, span := c.config.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNameClickHouse),
+		trace.WithAttributes(c.attrs...),
+		trace.WithAttributes(c.config.attrs...),
+		trace.WithAttributes(attrs...),
+	)
+	op := &operation{span: span}
+	options := []clickhouse.QueryOption{clickhouse.WithSpan(span.SpanContext())}
+	if !c.config.noProgress {
+		options = append(options, clickhouse.WithProgress(op.progress), clickhouse.WithProfileInfo(op.profileInfo))
+	}
+	return clickhouse.Context(ctx, options...), op
+}
+
+// startQuery creates the span of a query, named after its summary like `SELECT events`.
+func (c *conn) startQuery(ctx context.Context, query string, attrs ...attribute.KeyValue) (context.Context, *operation) {
+	q := sqlconv.Parse(query, !c.config.noSanitization)
+	return c.start(ctx, q.SpanName(system), append(q.Attributes(), attrs...)...)
+}
+
+// serverAttributes returns the attributes of the database and server of a connection.
+func serverAttributes(opt *clickhouse.Options) []attribute.KeyValue {
+	if opt == nil {
+		return nil
+	}
+	var attrs []attribute.KeyValue
+	if len(opt.Addr) == 1 {
+		if host, port := httpconv.SplitHostPort(opt.Addr[0]); host != "" {
+			attrs = append(attrs, semconv.ServerAddress(host))
+			if port > 0 {
+				attrs = append(attrs, semconv.ServerPort(port))
+			}
+		}
+	}
+	if opt.Auth.Database != "" {
+		attrs = append(attrs, semconv.DBNamespace(opt.Auth.Database))
+	}
+	return attrs
+}
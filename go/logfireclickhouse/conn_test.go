@@ -0,0 +1,371 @@
+package logfireclickhouse
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	chproto "github.com/ClickHouse/ch-go/proto"
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// fakeConn returns two rows for every query. Queries containing "missing" fail like an unknown table.
+type fakeConn struct {
+	driver.Conn
+}
+
+var errUnknownTable = &clickhouse.Exception{Code: 60, Name: "DB::Exception", Message: "Table default.missing does not exist"}
+
+func (fakeConn) Query(ctx context.Context, query string, args ...any) (driver.Rows, error) {
+	if strings.Contains(query, "missing") {
+		return nil, errUnknownTable
+	}
+	return &fakeRows{remaining: 2}, nil
+}
+
+func (c fakeConn) QueryRow(ctx context.Context, query string, args ...any) driver.Row {
+	r, err := c.Query(ctx, query, args...)
+	return &fakeRow{rows: r, err: err}
+}
+
+func (fakeConn) Select(ctx context.Context, dest any, query string, args ...any) error {
+	*dest.(*[]int) = []int{1, 2}
+	return nil
+}
+
+func (fakeConn) Exec(ctx context.Context, query string, args ...any) error {
+	if strings.Contains(query, "missing") {
+		return errUnknownTable
+	}
+	return nil
+}
+
+func (c fakeConn) AsyncInsert(ctx context.Context, query string, wait bool, args ...any) error {
+	return c.Exec(ctx, query, args...)
+}
+
+func (fakeConn) PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (driver.Batch, error) {
+	return &fakeBatch{}, nil
+}
+
+func (fakeConn) Ping(context.Context) error { return nil }
+
+type fakeRows struct {
+	driver.Rows
+	remaining int
+}
+
+func (r *fakeRows) Next() bool {
+	if r.remaining == 0 {
+		return false
+	}
+	r.remaining--
+	return true
+}
+
+func (*fakeRows) Scan(dest ...any) error { return nil }
+func (*fakeRows) Close() error           { return nil }
+func (*fakeRows) Err() error             { return nil }
+
+type fakeRow struct {
+	driver.Row
+	rows driver.Rows
+	err  error
+}
+
+func (r *fakeRow) Err() error { return r.err }
+
+func (r *fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if !r.rows.Next() {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// fakeBatch buffers rows until they're flushed or sent, like the native protocol.
+type fakeBatch struct {
+	driver.Batch
+	buffered int
+	sent     bool
+}
+
+func (b *fakeBatch) Append(v ...any) error { b.buffered++; return nil }
+func (b *fakeBatch) Rows() int             { return b.buffered }
+func (b *fakeBatch) IsSent() bool          { return b.sent }
+func (b *fakeBatch) Flush() error          { b.buffered = 0; return nil }
+func (b *fakeBatch) Send() error           { b.buffered, b.sent = 0, true; return nil }
+func (b *fakeBatch) Abort() error          { b.sent = true; return nil }
+func (b *fakeBatch) Close() error          { b.sent = true; return nil }
+
+func newTestConn(t *testing.T, opts ...Option) (driver.Conn, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	return Wrap(fakeConn{}, append([]Option{WithTracerProvider(provider)}, opts...)...), recorder
+}
+
+func TestQuery(t *testing.T) {
+	conn, recorder := newTestConn(t)
+
+	ctx := ContextWithQueryID(t.Context(), "report-1")
+	rows, err := conn.Query(ctx, "SELECT id FROM events WHERE user = 'a@example.com'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if len(recorder.Ended()) != 0 {
+		t.Fatal("expected the span to end when the rows are closed")
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "SELECT events" || span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:              "SELECT id FROM events WHERE user = ?",
+		"db.system.name":            "clickhouse",
+		"db.query.text":             "SELECT id FROM events WHERE user = ?",
+		"db.response.returned_rows": int64(2),
+		"db.clickhouse.query_id":    "report-1",
+	})
+}
+
+func TestProgress(t *testing.T) {
+	wrapped, recorder := newTestConn(t)
+	c := wrapped.(*conn)
+
+	_, op := c.startQuery(t.Context(), "SELECT count() FROM events")
+	op.progress(&clickhouse.Progress{Rows: 100, Bytes: 800})
+	op.progress(&clickhouse.Progress{Rows: 50, Bytes: 400})
+	op.profileInfo(&clickhouse.ProfileInfo{Rows: 1, Blocks: 1})
+	op.end(nil)
+	op.end(nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected the span to end once, got %d spans", len(spans))
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		"db.clickhouse.read_rows":  int64(150),
+		"db.clickhouse.read_bytes": int64(1200),
+		"db.clickhouse.blocks":     int64(1),
+	})
+	if _, ok := oteltest.Attributes(spans[0].Attributes())["db.clickhouse.written_rows"]; ok {
+		t.Error("expected no written rows")
+	}
+}
+
+func TestQueryRow(t *testing.T) {
+	conn, recorder := newTestConn(t)
+
+	if err := conn.QueryRow(t.Context(), "SELECT id FROM events LIMIT 1").Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.QueryRow(t.Context(), "SELECT id FROM missing").Scan(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{"db.response.returned_rows": int64(1)})
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("unexpected status %v", spans[1].Status())
+	}
+	oteltest.CheckAttributes(t, spans[1].Attributes(), map[attribute.Key]any{"db.response.status_code": "60"})
+}
+
+func TestSelect(t *testing.T) {
+	conn, recorder := newTestConn(t)
+
+	var ids []int
+	if err := conn.Select(t.Context(), &ids, "SELECT id FROM events"); err != nil {
+		t.Fatal(err)
+	}
+
+	oteltest.CheckAttributes(t, recorder.Ended()[0].Attributes(), map[attribute.Key]any{"db.response.returned_rows": int64(2)})
+}
+
+func TestExecError(t *testing.T) {
+	conn, recorder := newTestConn(t)
+
+	if err := conn.Exec(t.Context(), "ALTER TABLE missing DELETE WHERE 1"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || span.Status().Description != errUnknownTable.Error() {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	if len(span.Events()) != 1 || span.Events()[0].Name != "exception" {
+		t.Errorf("expected an exception event, got %v", span.Events())
+	}
+}
+
+func TestBatch(t *testing.T) {
+	conn, recorder := newTestConn(t)
+
+	b, err := conn.PrepareBatch(t.Context(), "INSERT INTO events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	for i := range 5 {
+		if err := b.Append(i); err != nil {
+			t.Fatal(err)
+		}
+		if i == 2 {
+			if err := b.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := b.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != "INSERT events" {
+		t.Errorf("unexpected span name %q", spans[0].Name())
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		"db.response.affected_rows": int64(5),
+		"db.clickhouse.blocks":      int64(2),
+	})
+}
+
+func TestBatchAbort(t *testing.T) {
+	conn, recorder := newTestConn(t)
+
+	b, err := conn.PrepareBatch(t.Context(), "INSERT INTO events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = b.Append(1)
+	if err := b.Abort(); err != nil {
+		t.Fatal(err)
+	}
+
+	oteltest.CheckAttributes(t, recorder.Ended()[0].Attributes(), map[attribute.Key]any{
+		"db.clickhouse.batch.aborted": true,
+		"db.response.affected_rows":   int64(0),
+	})
+}
+
+func TestAsyncInsert(t *testing.T) {
+	conn, recorder := newTestConn(t)
+
+	if err := conn.Exec(ContextWithAsync(t.Context(), true), "INSERT INTO events VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	//lint:ignore SA1019 The deprecated method is still instrumented.
+	if err := conn.AsyncInsert(t.Context(), "INSERT INTO events VALUES (2)", false); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		"db.clickhouse.async_insert":      true,
+		"db.clickhouse.async_insert.wait": true,
+	})
+	oteltest.CheckAttributes(t, spans[1].Attributes(), map[attribute.Key]any{
+		"db.clickhouse.async_insert":      true,
+		"db.clickhouse.async_insert.wait": false,
+	})
+}
+
+// writeHello answers the query made by HTTP connections to get the version of the server.
+func writeHello(t *testing.T, w io.Writer) {
+	block := proto.NewBlock()
+	for _, col := range []struct{ name, typ string }{
+		{"displayName()", "String"}, {"version()", "String"}, {"revision()", "UInt32"}, {"timezone()", "String"},
+	} {
+		if err := block.AddColumn(col.name, column.Type(col.typ)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := block.Append("test", "25.8.1.1", uint32(54479), "UTC"); err != nil {
+		t.Fatal(err)
+	}
+	var buf chproto.Buffer
+	if err := block.Encode(&buf, clickhouse.ClientTCPProtocolVersion); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write(buf.Buf)
+}
+
+func TestOpen(t *testing.T) {
+	var queryID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "version()") {
+			writeHello(t, w)
+			return
+		}
+		queryID = r.URL.Query().Get("query_id")
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	conn, err := Open(&clickhouse.Options{
+		Addr:     []string{strings.TrimPrefix(server.URL, "http://")},
+		Protocol: clickhouse.HTTP,
+		Auth:     clickhouse.Auth{Database: "analytics"},
+	}, WithTracerProvider(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.Exec(ContextWithQueryID(t.Context(), "cleanup-1"), "DELETE FROM sessions WHERE expired"); err != nil {
+		t.Fatal(err)
+	}
+
+	if queryID != "cleanup-1" {
+		t.Errorf("query_id = %q, want cleanup-1", queryID)
+	}
+	span := recorder.Ended()[0]
+	if span.Name() != "DELETE sessions" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"db.namespace":           "analytics",
+		"server.address":         "127.0.0.1",
+		"db.clickhouse.query_id": "cleanup-1",
+	})
+}
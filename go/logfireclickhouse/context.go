@@ -0,0 +1,29 @@
+package logfireclickhouse
+
+import (
+	"context"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+type (
+	queryIDKey struct{}
+	asyncKey   struct{}
+)
+
+// ContextWithQueryID returns a context running queries with the given query ID, like
+// [clickhouse.WithQueryID], which is also recorded as the `db.clickhouse.query_id` attribute.
+// The options of a clickhouse context can't be read back, so IDs set with [clickhouse.WithQueryID]
+// aren't recorded.
+func ContextWithQueryID(ctx context.Context, queryID string) context.Context {
+	ctx = context.WithValue(ctx, queryIDKey{}, queryID)
+	return clickhouse.Context(ctx, clickhouse.WithQueryID(queryID))
+}
+
+// ContextWithAsync returns a context running inserts as async inserts, like [clickhouse.WithAsync],
+// which is also recorded by the `db.clickhouse.async_insert` attributes. With wait, inserts return
+// once the server has flushed the data rather than once it has buffered it.
+func ContextWithAsync(ctx context.Context, wait bool) context.Context {
+	ctx = context.WithValue(ctx, asyncKey{}, wait)
+	return clickhouse.Context(ctx, clickhouse.WithAsync(wait))
+}
@@ -0,0 +1,232 @@
+package logfireclickhouse
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+)
+
+const (
+	// blocksKey records the number of blocks read by a query or sent by a batch.
+	blocksKey = attribute.Key("db.clickhouse.blocks")
+	// readRowsKey records the number of rows read by the server.
+	readRowsKey = attribute.Key("db.clickhouse.read_rows")
+	// readBytesKey records the number of bytes read by the server.
+	readBytesKey = attribute.Key("db.clickhouse.read_bytes")
+	// writtenRowsKey records the number of rows written by the server.
+	writtenRowsKey = attribute.Key("db.clickhouse.written_rows")
+	// writtenBytesKey records the number of bytes written by the server.
+	writtenBytesKey = attribute.Key("db.clickhouse.written_bytes")
+)
+
+// operation is the span of a query with the progress reported by the server, which is received
+// concurrently with reading the results.
+type operation struct {
+	span trace.Span
+
+	mu                        sync.Mutex
+	ended                     bool
+	blocks                    uint64
+	readRows, readBytes       uint64
+	writtenRows, writtenBytes uint64
+}
+
+func (op *operation) progress(p *clickhouse.Progress) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.readRows += p.Rows
+	op.readBytes += p.Bytes
+	op.writtenRows += p.WroteRows
+	op.writtenBytes += p.WroteBytes
+}
+
+func (op *operation) profileInfo(p *clickhouse.ProfileInfo) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.blocks += p.Blocks
+}
+
+// end ends the span once, recording the progress and err. [sql.ErrNoRows] isn't an error of the query.
+func (op *operation) end(err error, attrs ...attribute.KeyValue) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if op.ended {
+		return
+	}
+	op.ended = true
+	for _, counter := range []struct {
+		key   attribute.Key
+		value uint64
+	}{
+		{blocksKey, op.blocks},
+		{readRowsKey, op.readRows},
+		{readBytesKey, op.readBytes},
+		{writtenRowsKey, op.writtenRows},
+		{writtenBytesKey, op.writtenBytes},
+	} {
+		if counter.value > 0 {
+			attrs = append(attrs, counter.key.Int64(int64(counter.value)))
+		}
+	}
+	op.span.SetAttributes(attrs...)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		recordError(op.span, err)
+	}
+	op.span.End()
+}
+
+// rows ends the span of their query when they're closed.
+type rows struct {
+	driver.Rows
+	op    *operation
+	count int
+}
+
+func (r *rows) Next() bool {
+	if !r.Rows.Next() {
+		return false
+	}
+	r.count++
+	return true
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	if err == nil {
+		err = r.Rows.Err()
+	}
+	r.op.end(err, semconv.DBResponseReturnedRows(r.count))
+	return err
+}
+
+// row ends the span of its query when it's scanned.
+type row struct {
+	driver.Row
+	op *operation
+}
+
+func (r *row) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	r.end(err)
+	return err
+}
+
+func (r *row) ScanStruct(dest any) error {
+	err := r.Row.ScanStruct(dest)
+	r.end(err)
+	return err
+}
+
+func (r *row) end(err error) {
+	returned := 1
+	if errors.Is(err, sql.ErrNoRows) {
+		returned = 0
+	}
+	r.op.end(err, semconv.DBResponseReturnedRows(returned))
+}
+
+// batch ends the span of its INSERT when it's sent, aborted or closed, recording the number
+// of rows and blocks sent.
+type batch struct {
+	driver.Batch
+	op           *operation
+	rows, blocks int
+}
+
+func (b *batch) Flush() error {
+	buffered := b.Batch.Rows()
+	err := b.Batch.Flush()
+	if err != nil {
+		recordError(b.op.span, err)
+		return err
+	}
+	// Flushing is a no-op over HTTP, where the rows stay buffered until the batch is sent.
+	if sent := buffered - b.Batch.Rows(); sent > 0 {
+		b.rows += sent
+		b.blocks++
+	}
+	return nil
+}
+
+func (b *batch) Send() error {
+	if buffered := b.Batch.Rows(); buffered > 0 && !b.Batch.IsSent() {
+		b.rows += buffered
+		b.blocks++
+	}
+	err := b.Batch.Send()
+	b.end(err)
+	return err
+}
+
+func (b *batch) Abort() error {
+	err := b.Batch.Abort()
+	b.end(err, abortedKey.Bool(true))
+	return err
+}
+
+func (b *batch) Close() error {
+	err := b.Batch.Close()
+	b.end(err)
+	return err
+}
+
+func (b *batch) end(err error, attrs ...attribute.KeyValue) {
+	attrs = append(attrs, sqlconv.RowsAffectedKey.Int(b.rows))
+	if b.blocks > 0 {
+		b.op.mu.Lock()
+		b.op.blocks = uint64(b.blocks)
+		b.op.mu.Unlock()
+	}
+	b.op.end(err, attrs...)
+}
+
+// stream ends the span of its query when it's closed.
+type stream struct {
+	io.ReadCloser
+	op  *operation
+	err error
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		s.err = err
+	}
+	return n, err
+}
+
+func (s *stream) Close() error {
+	err := s.ReadCloser.Close()
+	if s.err != nil {
+		s.op.end(s.err)
+	} else {
+		s.op.end(err)
+	}
+	return err
+}
+
+// errorAttributes returns the code of ClickHouse exceptions.
+func errorAttributes(err error) []attribute.KeyValue {
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) {
+		return []attribute.KeyValue{semconv.DBResponseStatusCode(strconv.Itoa(int(exception.Code)))}
+	}
+	return nil
+}
+
+func recordError(span trace.Span, err error) {
+	span.SetAttributes(errorAttributes(err)...)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
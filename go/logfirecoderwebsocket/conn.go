@@ -0,0 +1,229 @@
+package logfirecoderwebsocket
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+
+	"github.com/coder/websocket"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/wsconv"
+)
+
+// Conn is a WebSocket connection recording its messages on its span.
+//
+// Messages read or written through the embedded [websocket.Conn], e.g. by wsjson or
+// [websocket.NetConn], aren't recorded, nor are the pings sent, but the pongs answering them are.
+type Conn struct {
+	*websocket.Conn
+	ctx  context.Context
+	conn *wsconv.Conn
+}
+
+// Accept accepts a WebSocket handshake from a client, starting the span of the connection as a child
+// of the context of the request, or of the trace context in its headers if the request isn't traced.
+func Accept(w http.ResponseWriter, r *http.Request, acceptOptions *websocket.AcceptOptions, opts ...Option) (*Conn, error) {
+	c := newConfig(opts)
+	ctx := r.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = c.propagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+	}
+	ctx, conn := wsconv.Start(ctx, c.tracer(), trace.SpanKindServer, wsconv.SpanName(r.URL.Path),
+		append(wsconv.ServerAttributes(r), c.attrs...)...)
+	o := websocket.AcceptOptions{}
+	if acceptOptions != nil {
+		o = *acceptOptions
+	}
+	o.OnPingReceived, o.OnPongReceived = hooks(conn, o.OnPingReceived, o.OnPongReceived)
+	ws, err := websocket.Accept(w, r, &o)
+	if err != nil {
+		conn.Error(err)
+		conn.End()
+		return nil, err
+	}
+	return wrap(ctx, ws, conn), nil
+}
+
+// Dial dials a WebSocket server, starting the span of the connection as a child of ctx.
+// The trace context is injected in a copy of the headers of the handshake.
+func Dial(ctx context.Context, urlStr string, dialOptions *websocket.DialOptions, opts ...Option) (*Conn, *http.Response, error) {
+	c := newConfig(opts)
+	name := wsconv.SpanName("")
+	attrs := c.attrs
+	if u, err := url.Parse(urlStr); err == nil {
+		name = wsconv.SpanName(u.Host + u.Path)
+		attrs = append(wsconv.ClientAttributes(u), attrs...)
+	}
+	ctx, conn := wsconv.Start(ctx, c.tracer(), trace.SpanKindClient, name, attrs...)
+	o := websocket.DialOptions{}
+	if dialOptions != nil {
+		o = *dialOptions
+	}
+	header := make(http.Header, len(o.HTTPHeader)+1)
+	maps.Copy(header, o.HTTPHeader)
+	c.propagator().Inject(ctx, propagation.HeaderCarrier(header))
+	o.HTTPHeader = header
+	o.OnPingReceived, o.OnPongReceived = hooks(conn, o.OnPingReceived, o.OnPongReceived)
+	ws, resp, err := websocket.Dial(ctx, urlStr, &o)
+	if err != nil {
+		conn.Error(err)
+		conn.End()
+		return nil, resp, err
+	}
+	return wrap(ctx, ws, conn), resp, nil
+}
+
+// hooks wraps the callbacks of the pings and pongs received to record them first.
+func hooks(conn *wsconv.Conn, onPing func(context.Context, []byte) bool, onPong func(context.Context, []byte)) (func(context.Context, []byte) bool, func(context.Context, []byte)) {
+	return func(ctx context.Context, payload []byte) bool {
+			conn.Message(wsconv.Received, wsconv.OpPing, len(payload))
+			if onPing != nil {
+				return onPing(ctx, payload)
+			}
+			return true
+		}, func(ctx context.Context, payload []byte) {
+			conn.Message(wsconv.Received, wsconv.OpPong, len(payload))
+			if onPong != nil {
+				onPong(ctx, payload)
+			}
+		}
+}
+
+func wrap(ctx context.Context, ws *websocket.Conn, conn *wsconv.Conn) *Conn {
+	if protocol := ws.Subprotocol(); protocol != "" {
+		conn.Span().SetAttributes(wsconv.SubprotocolKey.String(protocol))
+	}
+	return &Conn{Conn: ws, ctx: ctx, conn: conn}
+}
+
+// Context returns the context of the span of the connection, to use as the parent of the work
+// done for its messages.
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Close performs the close handshake with the status code and reason, recording the close frame sent,
+// then ends the span of the connection.
+func (c *Conn) Close(code websocket.StatusCode, reason string) error {
+	err := c.Conn.Close(code, reason)
+	if err != nil {
+		c.conn.Error(err)
+	} else {
+		c.conn.Message(wsconv.Sent, wsconv.OpClose, 2+len(reason))
+		c.conn.Close(wsconv.Sent, int(code), reason)
+	}
+	c.conn.End()
+	return err
+}
+
+// CloseNow ends the span of the connection and closes it, without a close handshake.
+func (c *Conn) CloseNow() error {
+	c.conn.End()
+	return c.Conn.CloseNow()
+}
+
+// Read reads the next data message.
+func (c *Conn) Read(ctx context.Context) (websocket.MessageType, []byte, error) {
+	typ, p, err := c.Conn.Read(ctx)
+	if err != nil {
+		c.readError(err)
+		return typ, p, err
+	}
+	c.conn.Message(wsconv.Received, wsconv.Opcode(typ), len(p))
+	return typ, p, nil
+}
+
+// Reader returns a reader of the next data message, which is recorded once it's read until EOF.
+func (c *Conn) Reader(ctx context.Context) (websocket.MessageType, io.Reader, error) {
+	typ, r, err := c.Conn.Reader(ctx)
+	if err != nil {
+		c.readError(err)
+		return typ, r, err
+	}
+	return typ, &reader{Reader: r, conn: c, opcode: wsconv.Opcode(typ)}, nil
+}
+
+// Write writes a data message.
+func (c *Conn) Write(ctx context.Context, typ websocket.MessageType, p []byte) error {
+	err := c.Conn.Write(ctx, typ, p)
+	if err != nil {
+		c.conn.Error(err)
+		return err
+	}
+	c.conn.Message(wsconv.Sent, wsconv.Opcode(typ), len(p))
+	return nil
+}
+
+// Writer returns a writer of the next data message, which is recorded once the writer is closed.
+func (c *Conn) Writer(ctx context.Context, typ websocket.MessageType) (io.WriteCloser, error) {
+	w, err := c.Conn.Writer(ctx, typ)
+	if err != nil {
+		c.conn.Error(err)
+		return nil, err
+	}
+	return &writer{WriteCloser: w, conn: c, opcode: wsconv.Opcode(typ)}, nil
+}
+
+// readError records the close frame ending a connection, or the error reading from it.
+func (c *Conn) readError(err error) {
+	var closeErr websocket.CloseError
+	if errors.As(err, &closeErr) {
+		size := 0
+		if closeErr.Code != websocket.StatusNoStatusRcvd {
+			size = 2 + len(closeErr.Reason)
+		}
+		c.conn.Message(wsconv.Received, wsconv.OpClose, size)
+		c.conn.Close(wsconv.Received, int(closeErr.Code), closeErr.Reason)
+		return
+	}
+	c.conn.Error(err)
+}
+
+type reader struct {
+	io.Reader
+	conn   *Conn
+	opcode wsconv.Opcode
+	n      int
+	done   bool
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	switch {
+	case err == io.EOF && !r.done:
+		r.done = true
+		r.conn.conn.Message(wsconv.Received, r.opcode, r.n)
+	case err != nil && err != io.EOF:
+		r.conn.readError(err)
+	}
+	return n, err
+}
+
+type writer struct {
+	io.WriteCloser
+	conn   *Conn
+	opcode wsconv.Opcode
+	n      int
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.n += n
+	return n, err
+}
+
+func (w *writer) Close() error {
+	err := w.WriteCloser.Close()
+	if err != nil {
+		w.conn.conn.Error(err)
+		return err
+	}
+	w.conn.conn.Message(wsconv.Sent, w.opcode, w.n)
+	return nil
+}
@@ -0,0 +1,173 @@
+package logfirecoderwebsocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func waitForSpan(t *testing.T, recorder *tracetest.SpanRecorder, kind trace.SpanKind) sdktrace.ReadOnlySpan {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, span := range recorder.Ended() {
+			if span.SpanKind() == kind {
+				return span
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no %v span", kind)
+	return nil
+}
+
+// newEchoServer echoes the messages it receives until the connection is closed, or closes
+// the connection without a close handshake when it receives "drop".
+func newEchoServer(t *testing.T) (string, []Option, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opts := []Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Accept(w, r, &websocket.AcceptOptions{Subprotocols: []string{"chat"}}, opts...)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		for {
+			typ, p, err := conn.Read(conn.Context())
+			if err != nil || string(p) == "drop" {
+				return
+			}
+			if err := conn.Write(conn.Context(), typ, p); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/chat", opts, recorder
+}
+
+func messageEvents(span sdktrace.ReadOnlySpan) []map[attribute.Key]any {
+	var events []map[attribute.Key]any
+	for _, event := range span.Events() {
+		if event.Name == "message" {
+			events = append(events, oteltest.Attributes(event.Attributes))
+		}
+	}
+	return events
+}
+
+func TestConn(t *testing.T) {
+	url, opts, recorder := newEchoServer(t)
+	ctx := context.Background()
+	conn, _, err := Dial(ctx, url, &websocket.DialOptions{Subprotocols: []string{"chat"}}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Write(ctx, websocket.MessageText, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := conn.Read(ctx); err != nil || string(p) != "hello" {
+		t.Fatalf("read %q, %v", p, err)
+	}
+	w, err := conn.Writer(ctx, websocket.MessageBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("abc"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := conn.Read(ctx); err != nil || string(p) != "abc" {
+		t.Fatalf("read %q, %v", p, err)
+	}
+	if err := conn.Close(websocket.StatusNormalClosure, "bye"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := waitForSpan(t, recorder, trace.SpanKindClient)
+	server := waitForSpan(t, recorder, trace.SpanKindServer)
+	if server.Parent().SpanID() != client.SpanContext().SpanID() {
+		t.Error("server span isn't a child of the client span")
+	}
+	if client.Name() != "WebSocket "+strings.TrimPrefix(url, "ws://") || server.Name() != "WebSocket /chat" {
+		t.Errorf("names = %s, %s", client.Name(), server.Name())
+	}
+	oteltest.CheckAttributes(t, client.Attributes(), map[attribute.Key]any{
+		"websocket.subprotocol":       "chat",
+		"websocket.close.code":        int64(1000),
+		"websocket.close.reason":      "bye",
+		"websocket.close.direction":   "sent",
+		"websocket.messages.sent":     int64(2),
+		"websocket.messages.received": int64(2),
+		"websocket.bytes.sent":        int64(8),
+		logfire.LevelNumKey:           int64(logfire.LevelInfo),
+	})
+	oteltest.CheckAttributes(t, server.Attributes(), map[attribute.Key]any{
+		"url.path":                  "/chat",
+		"websocket.close.code":      int64(1000),
+		"websocket.close.reason":    "bye",
+		"websocket.close.direction": "received",
+	})
+	events := messageEvents(client)
+	if len(events) != 5 {
+		t.Fatalf("events = %v, want 4 data messages and the close frame", events)
+	}
+	want := map[attribute.Key]any{
+		"websocket.message.direction": "sent",
+		"websocket.message.opcode":    "binary",
+		"websocket.message.size":      int64(3),
+	}
+	for k, v := range want {
+		if events[2][k] != v {
+			t.Errorf("third event %s = %v, want %v", k, events[2][k], v)
+		}
+	}
+	if events[4]["websocket.message.opcode"] != "close" {
+		t.Errorf("last event = %v, want the close frame", events[4])
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{client, server} {
+		if span.Status().Code == codes.Error {
+			t.Errorf("%v status = %v", span.SpanKind(), span.Status())
+		}
+	}
+}
+
+func TestAbnormalClosure(t *testing.T) {
+	url, opts, recorder := newEchoServer(t)
+	ctx := context.Background()
+	conn, _, err := Dial(ctx, url, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Write(ctx, websocket.MessageText, []byte("drop")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := conn.Read(ctx); err == nil {
+		t.Fatal("read succeeded on a dropped connection")
+	}
+	conn.CloseNow()
+
+	client := waitForSpan(t, recorder, trace.SpanKindClient)
+	oteltest.CheckAttributes(t, client.Attributes(), map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelError),
+	})
+	if client.Status().Code != codes.Error {
+		t.Errorf("status = %v, want an error", client.Status())
+	}
+}
@@ -0,0 +1,97 @@
+// Package logfireconnect instruments Connect handlers and clients for Pydantic Logfire.
+//
+// [NewInterceptor] creates a span for each RPC, named after the procedure like
+// `connect.ping.v1.PingService/Ping`, with the rpc.* semantic convention attributes.
+// The Connect error code sets the Logfire level of the span:
+//
+//	interceptors := connect.WithInterceptors(logfireconnect.NewInterceptor())
+//	path, handler := pingv1connect.NewPingServiceHandler(&pingServer{}, interceptors)
+//	client := pingv1connect.NewPingServiceClient(http.DefaultClient, url, interceptors)
+package logfireconnect
+
+import (
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+	"github.com/pydantic/logfire/go/internal/rpcconv"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireconnect"
+
+// Option configures [NewInterceptor].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to extract and inject the trace context
+// in the request headers. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithFilter adds a filter for RPCs by procedure, e.g. "/connect.ping.v1.PingService/Ping":
+// an RPC is only traced if all filters return true.
+func WithFilter(filter func(procedure string) bool) Option {
+	return func(c *config) {
+		c.filters = append(c.filters, filter)
+	}
+}
+
+// WithMessageEvents records a `message` event for each message sent and received,
+// with its type, sequence number and size.
+func WithMessageEvents() Option {
+	return func(c *config) {
+		c.messages.Events = true
+	}
+}
+
+// WithMessagePayloads records the messages as JSON in the `message` events, and implies [WithMessageEvents].
+// Payloads longer than maxSize bytes are truncated, 0 means no limit.
+//
+// Payloads often contain personal data: they go through scrubbing, but only record them when needed.
+func WithMessagePayloads(maxSize int) Option {
+	return func(c *config) {
+		c.messages = rpcconv.MessageOptions{Events: true, Payloads: true, MaxPayloadSize: maxSize}
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	filters        []func(string) bool
+	messages       rpcconv.MessageOptions
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) traced(procedure string) bool {
+	for _, filter := range c.filters {
+		if !filter(procedure) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
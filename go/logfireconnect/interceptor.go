@@ -0,0 +1,284 @@
+package logfireconnect
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"connectrpc.com/connect"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/rpcconv"
+)
+
+// protocolKey records the protocol of an RPC: "connect", "grpc" or "grpcweb".
+const protocolKey = attribute.Key("rpc.connect_rpc.protocol")
+
+// errorDetailsKey records the details of Connect errors as JSON, e.g.
+// `{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"1s"}`.
+const errorDetailsKey = attribute.Key("rpc.connect_rpc.error.details")
+
+// NewInterceptor returns an interceptor creating a server span for each RPC handled
+// and a client span for each RPC sent, for both unary and streaming RPCs.
+//
+// Streaming client spans end when Receive returns an error, including io.EOF,
+// when CloseResponse is called, or when the context is canceled.
+func NewInterceptor(opts ...Option) connect.Interceptor {
+	return &interceptor{config: newConfig(opts)}
+}
+
+type interceptor struct {
+	config *config
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		spec := req.Spec()
+		if !i.config.traced(spec.Procedure) {
+			return next(ctx, req)
+		}
+		ctx, span := i.config.start(ctx, spec, req.Peer(), req.Header())
+		if spec.IsClient {
+			defer span.End()
+			i.config.messages.MessageSent(span, 1, req.Any())
+		} else {
+			defer span.End(trace.WithStackTrace(true))
+			defer rpcconv.RecordPanic(span, connect.CodeInternal.String())
+			i.config.messages.MessageReceived(span, 1, req.Any())
+		}
+
+		resp, err := next(ctx, req)
+		if err == nil {
+			if spec.IsClient {
+				i.config.messages.MessageReceived(span, 1, resp.Any())
+			} else {
+				i.config.messages.MessageSent(span, 1, resp.Any())
+			}
+		}
+		setStatus(span, spec, err)
+		return resp, err
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		if !i.config.traced(spec.Procedure) {
+			return next(ctx, spec)
+		}
+		ctx, span := i.config.tracer().Start(ctx, rpcconv.SpanName(spec.Procedure),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(rpcconv.Attributes(semconv.RPCSystemNameConnectrpc, spec.Procedure)...),
+		)
+		conn := next(ctx, spec)
+		span.SetAttributes(peerAttributes(spec, conn.Peer())...)
+		// The headers are sent with the first message.
+		i.config.propagator().Inject(ctx, propagation.HeaderCarrier(conn.RequestHeader()))
+
+		c := &clientConn{StreamingClientConn: conn, config: i.config, span: span}
+		c.stop = context.AfterFunc(ctx, func() { c.end(ctx.Err()) })
+		return c
+	}
+}
+
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		spec := conn.Spec()
+		if !i.config.traced(spec.Procedure) {
+			return next(ctx, conn)
+		}
+		ctx, span := i.config.start(ctx, spec, conn.Peer(), conn.RequestHeader())
+		defer span.End(trace.WithStackTrace(true))
+		defer rpcconv.RecordPanic(span, connect.CodeInternal.String())
+
+		err := next(ctx, &handlerConn{StreamingHandlerConn: conn, config: i.config, span: span})
+		setStatus(span, spec, err)
+		return err
+	}
+}
+
+// start creates the span of a unary RPC or a streaming handler, extracting the trace context
+// from the request headers on the server and injecting it on the client.
+func (c *config) start(ctx context.Context, spec connect.Spec, peer connect.Peer, header http.Header) (context.Context, trace.Span) {
+	kind := trace.SpanKindClient
+	if !spec.IsClient {
+		kind = trace.SpanKindServer
+		ctx = c.propagator().Extract(ctx, propagation.HeaderCarrier(header))
+	}
+	attrs := rpcconv.Attributes(semconv.RPCSystemNameConnectrpc, spec.Procedure)
+	ctx, span := c.tracer().Start(ctx, rpcconv.SpanName(spec.Procedure),
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(append(attrs, peerAttributes(spec, peer)...)...),
+	)
+	if spec.IsClient {
+		c.propagator().Inject(ctx, propagation.HeaderCarrier(header))
+	}
+	return ctx, span
+}
+
+// peerAttributes returns the address of the server on the client, and of the client on the server.
+func peerAttributes(spec connect.Spec, peer connect.Peer) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if peer.Protocol != "" {
+		attrs = append(attrs, protocolKey.String(peer.Protocol))
+	}
+	if peer.Addr == "" {
+		return attrs
+	}
+	host, port := httpconv.SplitHostPort(peer.Addr)
+	if spec.IsClient {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if port != 0 {
+			attrs = append(attrs, semconv.ServerPort(port))
+		}
+	} else {
+		attrs = append(attrs, semconv.ClientAddress(host))
+		if port != 0 {
+			attrs = append(attrs, semconv.ClientPort(port))
+		}
+	}
+	return attrs
+}
+
+// setStatus records the Connect error code of the RPC and the details of the error,
+// see [rpcconv.SetStatus].
+func setStatus(span trace.Span, spec connect.Spec, err error) {
+	kind := trace.SpanKindServer
+	if spec.IsClient {
+		kind = trace.SpanKindClient
+	}
+	if err == nil {
+		// Connect has no name for successful RPCs.
+		rpcconv.SetStatus(span, kind, rpcconv.CodeOK, "", nil, "")
+		return
+	}
+
+	var code connect.Code
+	message := err.Error()
+	var connectErr *connect.Error
+	switch {
+	case errors.As(err, &connectErr):
+		code = connectErr.Code()
+		message = connectErr.Message()
+		if details := errorDetails(connectErr); len(details) > 0 {
+			span.SetAttributes(errorDetailsKey.StringSlice(details))
+		}
+	// Connect converts context errors returned by handlers to the matching codes.
+	case errors.Is(err, context.Canceled):
+		code = connect.CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = connect.CodeDeadlineExceeded
+	default:
+		code = connect.CodeUnknown
+	}
+	rpcconv.SetStatus(span, kind, rpcconv.Code(code), code.String(), err, message)
+}
+
+// errorDetails returns the details of the error as JSON. Details whose type isn't linked
+// in the binary are only recorded with their type.
+func errorDetails(err *connect.Error) []string {
+	var details []string
+	for _, detail := range err.Details() {
+		if value, err := detail.Value(); err == nil {
+			if a, err := anypb.New(value); err == nil {
+				if b, err := protojson.Marshal(a); err == nil {
+					details = append(details, string(b))
+					continue
+				}
+			}
+		}
+		b, _ := protojson.Marshal(&anypb.Any{TypeUrl: "type.googleapis.com/" + detail.Type()})
+		details = append(details, string(b))
+	}
+	return details
+}
+
+// handlerConn records the messages of a streaming handler.
+type handlerConn struct {
+	connect.StreamingHandlerConn
+	config   *config
+	span     trace.Span
+	sent     int
+	received int
+}
+
+func (c *handlerConn) Send(msg any) error {
+	err := c.StreamingHandlerConn.Send(msg)
+	if err == nil {
+		c.sent++
+		c.config.messages.MessageSent(c.span, c.sent, msg)
+	}
+	return err
+}
+
+func (c *handlerConn) Receive(msg any) error {
+	err := c.StreamingHandlerConn.Receive(msg)
+	if err == nil {
+		c.received++
+		c.config.messages.MessageReceived(c.span, c.received, msg)
+	}
+	return err
+}
+
+// clientConn records the messages of a streaming client and ends its span when the stream ends.
+type clientConn struct {
+	connect.StreamingClientConn
+	config *config
+	span   trace.Span
+	// sent and received are only accessed by Send and Receive respectively,
+	// which Connect allows to call concurrently.
+	sent     int
+	received int
+	stop     func() bool
+	once     sync.Once
+}
+
+func (c *clientConn) Send(msg any) error {
+	// Errors are returned again by Receive with the status of the stream.
+	err := c.StreamingClientConn.Send(msg)
+	if err == nil {
+		c.sent++
+		c.config.messages.MessageSent(c.span, c.sent, msg)
+	}
+	return err
+}
+
+func (c *clientConn) Receive(msg any) error {
+	err := c.StreamingClientConn.Receive(msg)
+	switch {
+	case err == nil:
+		c.received++
+		c.config.messages.MessageReceived(c.span, c.received, msg)
+	case errors.Is(err, io.EOF):
+		c.finish(nil)
+	default:
+		c.finish(err)
+	}
+	return err
+}
+
+func (c *clientConn) CloseResponse() error {
+	err := c.StreamingClientConn.CloseResponse()
+	c.finish(nil)
+	return err
+}
+
+// finish ends the span when the stream ends before its context is canceled.
+func (c *clientConn) finish(err error) {
+	c.stop()
+	c.end(err)
+}
+
+func (c *clientConn) end(err error) {
+	c.once.Do(func() {
+		setStatus(c.span, c.Spec(), err)
+		c.span.End()
+	})
+}
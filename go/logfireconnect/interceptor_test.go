@@ -0,0 +1,262 @@
+package logfireconnect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/internal/rpcconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	echoProcedure   = "/test.v1.TestService/Echo"
+	repeatProcedure = "/test.v1.TestService/Repeat"
+)
+
+type testClients struct {
+	echo   *connect.Client[wrapperspb.StringValue, wrapperspb.StringValue]
+	repeat *connect.Client[wrapperspb.StringValue, wrapperspb.StringValue]
+}
+
+// newTestClients starts a server with an Echo unary procedure and a Repeat server streaming one,
+// and returns clients for them, all instrumented with opts.
+func newTestClients(t *testing.T, opts ...Option) (testClients, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	opts = append([]Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}, opts...)
+	interceptors := connect.WithInterceptors(NewInterceptor(opts...))
+
+	mux := http.NewServeMux()
+	mux.Handle(echoProcedure, connect.NewUnaryHandler(echoProcedure,
+		func(ctx context.Context, req *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+			switch req.Msg.Value {
+			case "missing":
+				return nil, connect.NewError(connect.CodeNotFound, errors.New("no such thing"))
+			case "retry":
+				err := connect.NewError(connect.CodeUnavailable, errors.New("overloaded"))
+				detail, _ := connect.NewErrorDetail(durationpb.New(time.Second))
+				err.AddDetail(detail)
+				return nil, err
+			}
+			return connect.NewResponse(req.Msg), nil
+		}, interceptors))
+	mux.Handle(repeatProcedure, connect.NewServerStreamHandler(repeatProcedure,
+		func(ctx context.Context, req *connect.Request[wrapperspb.StringValue], stream *connect.ServerStream[wrapperspb.StringValue]) error {
+			for range 3 {
+				if err := stream.Send(req.Msg); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, interceptors))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return testClients{
+		echo:   connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](srv.Client(), srv.URL+echoProcedure, interceptors),
+		repeat: connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](srv.Client(), srv.URL+repeatProcedure, interceptors),
+	}, recorder
+}
+
+// spansByKind returns the server and client spans, which end in an unspecified order.
+func spansByKind(t *testing.T, recorder *tracetest.SpanRecorder) (server, client sdktrace.ReadOnlySpan) {
+	t.Helper()
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for _, span := range spans {
+		switch span.SpanKind() {
+		case trace.SpanKindServer:
+			server = span
+		case trace.SpanKindClient:
+			client = span
+		}
+	}
+	if server == nil || client == nil {
+		t.Fatal("expected a server and a client span")
+	}
+	return server, client
+}
+
+func TestUnary(t *testing.T) {
+	clients, recorder := newTestClients(t)
+
+	if _, err := clients.echo.CallUnary(t.Context(), connect.NewRequest(wrapperspb.String("hello"))); err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := spansByKind(t, recorder)
+	if server.Parent().SpanID() != client.SpanContext().SpanID() {
+		t.Error("expected the server span to be a child of the client span")
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{server, client} {
+		if span.Name() != "test.v1.TestService/Echo" {
+			t.Errorf("unexpected span name %q", span.Name())
+		}
+		attrs := oteltest.Attributes(span.Attributes())
+		for key, want := range map[attribute.Key]any{
+			"rpc.system.name":   "connectrpc",
+			"rpc.method":        "test.v1.TestService/Echo",
+			protocolKey:         connect.ProtocolConnect,
+			logfire.LevelNumKey: int64(logfire.LevelInfo),
+		} {
+			if attrs[key] != want {
+				t.Errorf("%v span attribute %s = %v, want %v", span.SpanKind(), key, attrs[key], want)
+			}
+		}
+		if _, ok := attrs["rpc.response.status_code"]; ok {
+			t.Errorf("expected no status code for a successful RPC")
+		}
+	}
+	if got := oteltest.Attributes(client.Attributes())["server.address"]; got != "127.0.0.1" {
+		t.Errorf("server.address = %v", got)
+	}
+	if got := oteltest.Attributes(server.Attributes())["client.address"]; got != "127.0.0.1" {
+		t.Errorf("client.address = %v", got)
+	}
+}
+
+func TestUnaryClientError(t *testing.T) {
+	clients, recorder := newTestClients(t)
+
+	_, err := clients.echo.CallUnary(t.Context(), connect.NewRequest(wrapperspb.String("missing")))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	server, client := spansByKind(t, recorder)
+	for _, span := range []sdktrace.ReadOnlySpan{server, client} {
+		attrs := oteltest.Attributes(span.Attributes())
+		if attrs["rpc.response.status_code"] != "not_found" || attrs[logfire.LevelNumKey] != int64(logfire.LevelWarn) {
+			t.Errorf("unexpected %v span attributes %v", span.SpanKind(), attrs)
+		}
+	}
+	if server.Status().Code != codes.Unset {
+		t.Errorf("expected no error status for the server span, got %v", server.Status())
+	}
+	if client.Status().Code != codes.Error || client.Status().Description != "no such thing" {
+		t.Errorf("unexpected client status %v", client.Status())
+	}
+}
+
+func TestUnaryErrorDetails(t *testing.T) {
+	clients, recorder := newTestClients(t)
+
+	_, err := clients.echo.CallUnary(t.Context(), connect.NewRequest(wrapperspb.String("retry")))
+	if connect.CodeOf(err) != connect.CodeUnavailable {
+		t.Fatalf("expected Unavailable, got %v", err)
+	}
+
+	server, client := spansByKind(t, recorder)
+	for _, span := range []sdktrace.ReadOnlySpan{server, client} {
+		if span.Status().Code != codes.Error {
+			t.Errorf("expected an error status for the %v span, got %v", span.SpanKind(), span.Status())
+		}
+		details, _ := oteltest.Attributes(span.Attributes())[errorDetailsKey].([]string)
+		want := `{"@type":"type.googleapis.com/google.protobuf.Duration","value":"1s"}`
+		if len(details) != 1 || strings.ReplaceAll(details[0], " ", "") != want {
+			t.Errorf("%v span error details = %v, want [%s]", span.SpanKind(), details, want)
+		}
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	clients, recorder := newTestClients(t, WithMessageEvents())
+
+	stream, err := clients.repeat.CallServerStream(t.Context(), connect.NewRequest(wrapperspb.String("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := 0
+	for stream.Receive() {
+		received++
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if received != 3 {
+		t.Fatalf("expected 3 messages, got %d", received)
+	}
+
+	server, client := spansByKind(t, recorder)
+	if server.Name() != "test.v1.TestService/Repeat" {
+		t.Errorf("unexpected span name %q", server.Name())
+	}
+	for span, want := range map[sdktrace.ReadOnlySpan][]string{
+		server: {"RECEIVED", "SENT", "SENT", "SENT"},
+		client: {"SENT", "RECEIVED", "RECEIVED", "RECEIVED"},
+	} {
+		var got []string
+		for _, event := range span.Events() {
+			got = append(got, oteltest.Attributes(event.Attributes)[rpcconv.MessageTypeKey].(string))
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%v span messages = %v, want %v", span.SpanKind(), got, want)
+		}
+		if span.Status().Code != codes.Unset {
+			t.Errorf("unexpected status %v", span.Status())
+		}
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	clients, recorder := newTestClients(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	stream, err := clients.repeat.CallServerStream(ctx, connect.NewRequest(wrapperspb.String("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	for stream.Receive() {
+	}
+
+	var client sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanKind() == trace.SpanKindClient {
+			client = span
+		}
+	}
+	if client == nil {
+		t.Fatal("expected the client span to end when the context is canceled")
+	}
+	if got := oteltest.Attributes(client.Attributes())["rpc.response.status_code"]; got != "canceled" {
+		t.Errorf("rpc.response.status_code = %v", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	clients, recorder := newTestClients(t, WithFilter(func(procedure string) bool {
+		return procedure != echoProcedure
+	}))
+
+	if _, err := clients.echo.CallUnary(t.Context(), connect.NewRequest(wrapperspb.String("hello"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
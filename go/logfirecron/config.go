@@ -0,0 +1,79 @@
+// Package logfirecron traces the jobs run by robfig/cron for Pydantic Logfire.
+//
+// [JobWrapper] returns a wrapper creating a root span for each run of a job, named after its schedule
+// like `cron @every 1h`, with the drift between the time the run was scheduled at and the time it started,
+// and recording panics. A wrapper is for the jobs of one schedule, so add them with a chain:
+//
+//	c := cron.New()
+//	c.AddJob("@every 1h", cron.NewChain(logfirecron.JobWrapper("@every 1h")).Then(job))
+//
+// A wrapper without a schedule can be added to all the jobs of a cron, but it doesn't record their drift:
+//
+//	c := cron.New(cron.WithChain(logfirecron.JobWrapper("")))
+package logfirecron
+
+import (
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirecron"
+
+// Option configures [JobWrapper].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithName names the spans of the job, e.g. `cleanup sessions`, rather than naming them after its schedule.
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// WithParser sets the parser of the schedule, which must be the parser of the cron running the job
+// for the drift to be right, e.g. one accepting seconds for a cron created with [cron.WithSeconds].
+// Defaults to the standard parser of [cron.ParseStandard].
+func WithParser(parser cron.ScheduleParser) Option {
+	return func(c *config) {
+		c.parser = parser
+	}
+}
+
+// standardParser parses the schedules accepted by [cron.New] by default.
+var standardParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	name           string
+	parser         cron.ScheduleParser
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{parser: standardParser}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
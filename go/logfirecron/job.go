@@ -0,0 +1,114 @@
+package logfirecron
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// specKey records the schedule of a job, e.g. `*/5 * * * *` or `@every 1h`.
+	specKey = attribute.Key("cron.job.spec")
+	// scheduledTimeKey records the time a run was scheduled at, in RFC 3339 format.
+	scheduledTimeKey = attribute.Key("cron.job.scheduled_time")
+	// driftKey records the delay in seconds between the time a run was scheduled at and the time it started.
+	driftKey = attribute.Key("cron.job.drift")
+)
+
+// JobWrapper returns a wrapper for jobs run on the schedule spec, creating a root span for each run.
+// The duration of the span is the duration of the run. A panicking run is recorded as an error
+// and panics again, to be recovered by a wrapper like [cron.Recover] added before this one.
+//
+// The drift of runs is computed from spec, so it's only recorded if spec is the schedule the job
+// was added with. An empty spec records neither.
+func JobWrapper(spec string, opts ...Option) cron.JobWrapper {
+	c := newConfig(opts)
+	return func(job cron.Job) cron.Job {
+		j := &wrappedJob{job: job, config: c, spec: spec}
+		if spec != "" {
+			if schedule, err := c.parser.Parse(spec); err == nil {
+				j.schedule = schedule
+				j.prev = time.Now()
+			}
+		}
+		return j
+	}
+}
+
+type wrappedJob struct {
+	job    cron.Job
+	config *config
+	spec   string
+
+	// The schedule and the time of the previous run scheduled, which is the time the job
+	// was wrapped before its first run. The schedule is nil if it can't be parsed.
+	mu       sync.Mutex
+	schedule cron.Schedule
+	prev     time.Time
+}
+
+func (j *wrappedJob) Run() {
+	start := time.Now()
+	name := j.spanName()
+	attrs := []attribute.KeyValue{logfire.MsgKey.String(name)}
+	if j.spec != "" {
+		attrs = append(attrs, specKey.String(j.spec))
+	}
+	if scheduled, ok := j.scheduled(start); ok {
+		attrs = append(attrs,
+			scheduledTimeKey.String(scheduled.Format(time.RFC3339Nano)),
+			driftKey.Float64(start.Sub(scheduled).Seconds()),
+		)
+	}
+	_, span := j.config.tracer().Start(context.Background(), name,
+		trace.WithNewRoot(),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(j.config.attrs...),
+	)
+	defer span.End(trace.WithStackTrace(true))
+	defer func() {
+		if p := recover(); p != nil {
+			span.SetStatus(codes.Error, fmt.Sprint(p))
+			panic(p)
+		}
+	}()
+	j.job.Run()
+}
+
+func (j *wrappedJob) spanName() string {
+	switch {
+	case j.config.name != "":
+		return j.config.name
+	case j.spec != "":
+		return "cron " + j.spec
+	}
+	return "cron job"
+}
+
+// scheduled returns the time of the latest activation of the schedule before now, which is the run
+// started now. Runs which took longer than the interval of the schedule may overlap.
+func (j *wrappedJob) scheduled(now time.Time) (time.Time, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.schedule == nil {
+		return time.Time{}, false
+	}
+	var scheduled time.Time
+	for next := j.schedule.Next(j.prev); !next.IsZero() && !next.After(now); next = j.schedule.Next(next) {
+		scheduled = next
+	}
+	if scheduled.IsZero() {
+		return time.Time{}, false
+	}
+	j.prev = scheduled
+	return scheduled, true
+}
@@ -0,0 +1,112 @@
+package logfirecron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newRecorder() (*tracetest.SpanRecorder, Option) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return recorder, WithTracerProvider(provider)
+}
+
+func TestDrift(t *testing.T) {
+	recorder, opt := newRecorder()
+	ran := false
+	job := JobWrapper("@every 1m", opt)(cron.FuncJob(func() { ran = true }))
+	// The previous run was scheduled 90 seconds ago, so this run was scheduled 30 seconds ago.
+	job.(*wrappedJob).prev = time.Now().Add(-90 * time.Second)
+	job.Run()
+	if !ran {
+		t.Fatal("job didn't run")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Name() != "cron @every 1m" || spans[0].Parent().IsValid() {
+		t.Errorf("span = %s with parent %v, want a root span named after the schedule", spans[0].Name(), spans[0].Parent())
+	}
+	attrs := oteltest.Attributes(spans[0].Attributes())
+	if attrs[specKey] != "@every 1m" || attrs[logfire.MsgKey] != "cron @every 1m" {
+		t.Errorf("attributes = %v", attrs)
+	}
+	if drift, _ := attrs[driftKey].(float64); drift < 30 || drift > 31 {
+		t.Errorf("drift = %v, want 30s", attrs[driftKey])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, attrs[scheduledTimeKey].(string)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	recorder, opt := newRecorder()
+	job := JobWrapper("", opt, WithName("cleanup sessions"))(cron.FuncJob(func() { panic("no database") }))
+	func() {
+		defer func() {
+			if p := recover(); p != "no database" {
+				t.Errorf("recovered %v, want the panic of the job", p)
+			}
+		}()
+		job.Run()
+	}()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "cleanup sessions" {
+		t.Errorf("name = %s", span.Name())
+	}
+	if span.Status().Code != codes.Error || span.Status().Description != "no database" {
+		t.Errorf("status = %v, want the panic", span.Status())
+	}
+	if events := span.Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("events = %v, want the exception", events)
+	}
+	if _, ok := oteltest.Attributes(span.Attributes())[driftKey]; ok {
+		t.Error("drift recorded without a schedule")
+	}
+}
+
+func TestCron(t *testing.T) {
+	recorder, opt := newRecorder()
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	c := cron.New(cron.WithParser(parser))
+	runs := make(chan struct{}, 1)
+	job := cron.FuncJob(func() {
+		select {
+		case runs <- struct{}{}:
+		default:
+		}
+	})
+	if _, err := c.AddJob("* * * * * *", cron.NewChain(JobWrapper("* * * * * *", opt, WithParser(parser))).Then(job)); err != nil {
+		t.Fatal(err)
+	}
+	c.Start()
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job didn't run")
+	}
+	<-c.Stop().Done()
+
+	spans := recorder.Ended()
+	if len(spans) == 0 {
+		t.Fatal("no span")
+	}
+	if drift, ok := oteltest.Attributes(spans[0].Attributes())[driftKey].(float64); !ok || drift < 0 || drift > 1 {
+		t.Errorf("drift = %v, want less than a second", drift)
+	}
+}
@@ -0,0 +1,159 @@
+// Package logfireecho instruments Echo applications for Pydantic Logfire.
+//
+// [Middleware] creates a server span for each request, named after the route template
+// like the spans of the Python SDK's web framework integrations:
+//
+//	e := echo.New()
+//	e.Use(logfireecho.Middleware())
+//	e.GET("/users/:id", getUser) // spans named `GET /users/:id`
+//
+// Handlers should pass `c.Request().Context()` to Logfire and other instrumented code,
+// so that their spans are children of the request span.
+package logfireecho
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireecho"
+
+// Option configures [Middleware].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider used to record the `http.server.request.duration`
+// and `http.server.active_requests` metrics. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to extract the trace context. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithSkipper skips tracing the requests for which skipper returns true.
+func WithSkipper(skipper middleware.Skipper) Option {
+	return func(c *config) {
+		c.skippers = append(c.skippers, skipper)
+	}
+}
+
+// WithExcludedRoutes skips tracing the requests matching the given route templates, e.g. "/healthz".
+func WithExcludedRoutes(routes ...string) Option {
+	return func(c *config) {
+		for _, route := range routes {
+			c.excludedRoutes[route] = true
+		}
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagators    propagation.TextMapPropagator
+	skippers       []middleware.Skipper
+	excludedRoutes map[string]bool
+}
+
+func (cfg *config) skip(c echo.Context) bool {
+	if cfg.excludedRoutes[c.Path()] {
+		return true
+	}
+	for _, skipper := range cfg.skippers {
+		if skipper(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware returns an Echo middleware creating a server span for each request, and recording
+// the `http.server.request.duration` and `http.server.active_requests` metrics.
+//
+// Errors returned by handlers are recorded as exceptions and passed to the HTTP error handler,
+// so that the span has the status code of the error response, e.g. the code of an [echo.HTTPError].
+// Only server errors give the span an error status.
+func Middleware(opts ...Option) echo.MiddlewareFunc {
+	cfg := &config{excludedRoutes: map[string]bool{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			if cfg.skip(c) {
+				return next(c)
+			}
+
+			r := c.Request()
+			ctx := instrumentation.Propagator(cfg.propagators).Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			route := c.Path()
+			ctx, span := instrumentation.Tracer(cfg.tracerProvider, instrumentationName).Start(ctx,
+				httpconv.SpanName(r.Method, route),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(httpconv.ServerRequestAttributes(r, route)...),
+			)
+			defer span.End(trace.WithStackTrace(true))
+			metrics := httpconv.StartServerMetrics(ctx, instrumentation.Meter(cfg.meterProvider, instrumentationName),
+				r.Method, httpconv.Scheme(r))
+
+			c.SetRequest(r.WithContext(ctx))
+			defer func() {
+				c.SetRequest(r)
+				if p := recover(); p != nil {
+					span.SetAttributes(semconv.HTTPResponseStatusCode(http.StatusInternalServerError))
+					span.SetStatus(codes.Error, fmt.Sprint(p))
+					metrics.End(ctx, route, http.StatusInternalServerError)
+					panic(p)
+				}
+			}()
+
+			err = next(c)
+			if err != nil {
+				span.RecordError(err)
+				// Invoke the error handler now so that the response status is known.
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			var httpErr *echo.HTTPError
+			if err != nil && errors.As(err, &httpErr) && !c.Response().Committed {
+				status = httpErr.Code
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			metrics.End(ctx, route, status)
+			if status >= http.StatusInternalServerError {
+				description := ""
+				if err != nil {
+					description = err.Error()
+				}
+				span.SetStatus(codes.Error, description)
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package logfireecho
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestServer(t *testing.T, opts ...Option) (*echo.Echo, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	e := echo.New()
+	e.Use(Middleware(append([]Option{WithTracerProvider(provider)}, opts...)...))
+	return e, recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	e, recorder := newTestServer(t)
+	e.GET("/users/:id", func(c echo.Context) error {
+		if !trace.SpanContextFromContext(c.Request().Context()).IsValid() {
+			t.Error("expected the request context to contain the span")
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /users/:id" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		logfire.MsgKey:              "GET /users/42",
+		"http.route":                "/users/:id",
+		"http.response.status_code": int64(http.StatusOK),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+}
+
+func TestMiddlewareHTTPError(t *testing.T) {
+	e, recorder := newTestServer(t)
+	e.GET("/missing", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "no such item")
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return errors.New("database unavailable")
+	})
+
+	for _, path := range []string{"/missing", "/fail"} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for i, want := range []struct {
+		status int64
+		code   codes.Code
+	}{
+		{http.StatusNotFound, codes.Unset},
+		{http.StatusInternalServerError, codes.Error},
+	} {
+		span := spans[i]
+		if got := oteltest.Attributes(span.Attributes())["http.response.status_code"]; got != want.status {
+			t.Errorf("%s: status code = %v, want %d", span.Name(), got, want.status)
+		}
+		if span.Status().Code != want.code {
+			t.Errorf("%s: status = %v, want %v", span.Name(), span.Status().Code, want.code)
+		}
+		if len(span.Events()) != 1 || span.Events()[0].Name != "exception" {
+			t.Errorf("%s: expected the error to be recorded, got %v", span.Name(), span.Events())
+		}
+	}
+}
+
+func TestMiddlewareExcludedRoutes(t *testing.T) {
+	e, recorder := newTestServer(t, WithExcludedRoutes("/healthz"), WithSkipper(func(c echo.Context) bool {
+		return c.Request().Header.Get("X-Skip") != ""
+	}))
+	e.GET("/healthz", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.GET("/items", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	skipped := httptest.NewRequest("GET", "/items", nil)
+	skipped.Header.Set("X-Skip", "1")
+	e.ServeHTTP(httptest.NewRecorder(), skipped)
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	e, _ := newTestServer(t, WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusCreated)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+	duration := metrics["http.server.request.duration"].(metricdata.Histogram[float64]).DataPoints[0]
+	want := attribute.NewSet(
+		attribute.String("http.request.method", "GET"),
+		attribute.String("url.scheme", "http"),
+		attribute.String("http.route", "/users/:id"),
+		attribute.Int("http.response.status_code", http.StatusCreated),
+	)
+	if duration.Count != 1 || !duration.Attributes.Equals(&want) {
+		t.Errorf("unexpected duration %d with attributes %v", duration.Count, duration.Attributes.ToSlice())
+	}
+	if active := metrics["http.server.active_requests"].(metricdata.Sum[int64]).DataPoints[0]; active.Value != 0 {
+		t.Errorf("expected no active requests, got %d", active.Value)
+	}
+}
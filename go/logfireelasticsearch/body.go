@@ -0,0 +1,227 @@
+package logfireelasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// maxBodySize is the maximum number of bytes of the bodies kept to record queries and response stats.
+const maxBodySize = 8 << 10
+
+// bodyBuffer keeps the start of a body, reporting whether it was truncated.
+// It may be written and read from different goroutines, e.g. when a transport is still
+// sending the request body after receiving the response.
+type bodyBuffer struct {
+	mu        sync.Mutex
+	data      []byte
+	truncated bool
+}
+
+func (b *bodyBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	room := maxBodySize - len(b.data)
+	if len(p) > room {
+		b.truncated = true
+		p = p[:max(room, 0)]
+	}
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+// bytes returns the body, or nil if it was truncated.
+func (b *bodyBuffer) bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.truncated {
+		return nil
+	}
+	return b.data
+}
+
+// prefix returns the start of the body, even if it was truncated.
+func (b *bodyBuffer) prefix() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data
+}
+
+// capturingBody records a body as it's read, calling done once when it's read to the end or closed.
+type capturingBody struct {
+	io.ReadCloser
+	buf  *bodyBuffer
+	once sync.Once
+	done func()
+}
+
+func (b *capturingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	_, _ = b.buf.Write(p[:n])
+	if err == io.EOF && b.done != nil {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *capturingBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.done != nil {
+		b.once.Do(b.done)
+	}
+	return err
+}
+
+// queryText returns the query of a request body, either a JSON document or newline-delimited documents
+// like the bodies of bulk requests, with the values replaced by `?` if sanitize is set.
+func queryText(body []byte, sanitize bool) (string, bool) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return "", false
+	}
+	if !sanitize {
+		return string(body), true
+	}
+	lines := strings.Split(string(body), "\n")
+	for i, line := range lines {
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return "", false
+		}
+		sanitized, err := json.Marshal(sanitizeValue(v))
+		if err != nil {
+			return "", false
+		}
+		lines[i] = string(sanitized)
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// sanitizeValue replaces the scalar values of a document by `?`, keeping its structure.
+// Arrays of scalars collapse to a single `?` so that queries only differing by the number of values look the same.
+func sanitizeValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = sanitizeValue(value)
+		}
+		return v
+	case []any:
+		sanitized := make([]any, 0, len(v))
+		for _, value := range v {
+			switch value.(type) {
+			case map[string]any, []any:
+				sanitized = append(sanitized, sanitizeValue(value))
+			}
+		}
+		if len(sanitized) == 0 && len(v) > 0 {
+			return "?"
+		}
+		return sanitized
+	}
+	return "?"
+}
+
+// The attributes of the stats reported by the cluster in responses.
+const (
+	tookKey             = attribute.Key("db.elasticsearch.took")
+	shardsTotalKey      = attribute.Key("db.elasticsearch.shards.total")
+	shardsSuccessfulKey = attribute.Key("db.elasticsearch.shards.successful")
+	shardsSkippedKey    = attribute.Key("db.elasticsearch.shards.skipped")
+	shardsFailedKey     = attribute.Key("db.elasticsearch.shards.failed")
+	timedOutKey         = attribute.Key("db.elasticsearch.timed_out")
+)
+
+// responseAttributes parses the start of a response body for the time taken, the shards, the number of hits
+// and the type of the error, which come before the hits in responses. Parsing stops at the first value
+// which isn't entirely in the start of the body.
+func responseAttributes(body []byte) []attribute.KeyValue {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	var attrs []attribute.KeyValue
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return attrs
+		}
+		switch tok {
+		case "took":
+			var took int64
+			if dec.Decode(&took) != nil {
+				return attrs
+			}
+			attrs = append(attrs, tookKey.Int64(took))
+		case "timed_out":
+			var timedOut bool
+			if dec.Decode(&timedOut) != nil {
+				return attrs
+			}
+			if timedOut {
+				attrs = append(attrs, timedOutKey.Bool(true))
+			}
+		case "_shards":
+			var shards struct{ Total, Successful, Skipped, Failed int }
+			if dec.Decode(&shards) != nil {
+				return attrs
+			}
+			attrs = append(attrs,
+				shardsTotalKey.Int(shards.Total),
+				shardsSuccessfulKey.Int(shards.Successful),
+				shardsSkippedKey.Int(shards.Skipped),
+				shardsFailedKey.Int(shards.Failed),
+			)
+		case "hits":
+			if total, ok := hitsTotal(dec); ok {
+				attrs = append(attrs, semconv.DBResponseReturnedRows(total))
+			}
+			return attrs
+		case "error":
+			var e struct{ Type string }
+			if dec.Decode(&e) != nil {
+				return attrs
+			}
+			if e.Type != "" {
+				attrs = append(attrs, semconv.ErrorTypeKey.String(e.Type))
+			}
+		default:
+			var skipped json.RawMessage
+			if dec.Decode(&skipped) != nil {
+				return attrs
+			}
+		}
+	}
+	return attrs
+}
+
+// hitsTotal reads the total number of hits, which comes first in the hits object, as an object
+// like `{"value": 42, "relation": "eq"}` or a number before Elasticsearch 7.
+func hitsTotal(dec *json.Decoder) (int, bool) {
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return 0, false
+	}
+	if tok, err := dec.Token(); err != nil || tok != "total" {
+		return 0, false
+	}
+	var raw json.RawMessage
+	if dec.Decode(&raw) != nil {
+		return 0, false
+	}
+	var total struct{ Value int }
+	if json.Unmarshal(raw, &total) == nil {
+		return total.Value, true
+	}
+	var n int
+	if json.Unmarshal(raw, &n) == nil {
+		return n, true
+	}
+	return 0, false
+}
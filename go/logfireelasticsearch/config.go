@@ -0,0 +1,88 @@
+// Package logfireelasticsearch instruments the Elasticsearch and OpenSearch clients for Pydantic Logfire.
+//
+// [NewTransport] creates a span for each request, named after the endpoint and target index like
+// `search products`, with the db.* semantic convention attributes, and the time taken and the
+// shards queried as reported by the cluster. Pass it as the transport of the client:
+//
+//	es, err := elasticsearch.NewClient(elasticsearch.Config{Transport: logfireelasticsearch.NewTransport(nil)})
+//	os, err := opensearch.NewClient(opensearch.Config{
+//		Transport: logfireelasticsearch.NewTransport(nil, logfireelasticsearch.WithSystem("opensearch")),
+//	})
+package logfireelasticsearch
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireelasticsearch"
+
+// Option configures [NewTransport].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject the trace context in requests.
+// Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithSystem sets the `db.system.name` attribute, e.g. `opensearch`. Defaults to `elasticsearch`.
+func WithSystem(system string) Option {
+	return func(c *config) {
+		c.system = semconv.DBSystemNameKey.String(system)
+	}
+}
+
+// WithQueryBody records the body of requests as the `db.query.text` attribute, with values replaced by `?`
+// unless [WithoutSanitization] is set. Bodies larger than 8 KiB aren't recorded.
+func WithQueryBody() Option {
+	return func(c *config) {
+		c.queryBody = true
+	}
+}
+
+// WithoutSanitization records the values of request bodies recorded with [WithQueryBody].
+// They still go through the scrubbing of Logfire.
+func WithoutSanitization() Option {
+	return func(c *config) {
+		c.noSanitization = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	system         attribute.KeyValue
+	queryBody      bool
+	noSanitization bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{system: semconv.DBSystemNameElasticsearch}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
@@ -0,0 +1,69 @@
+package logfireelasticsearch
+
+import (
+	"net/http"
+	"strings"
+)
+
+// endpoint returns the name of the API called by a request, such as `search` or `cat.indices`,
+// approximating the endpoint identifiers of the Elasticsearch specification, and its target index.
+func endpoint(method, path string) (operation, index string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if segments[0] == "" {
+		segments = nil
+	}
+	i := 0
+	for i < len(segments) && !strings.HasPrefix(segments[i], "_") {
+		i++
+	}
+	if i > 0 {
+		index = segments[0]
+	}
+	if i == len(segments) {
+		return indexOperation(method, index), index
+	}
+	api := strings.TrimPrefix(segments[i], "_")
+	switch api {
+	case "doc":
+		return documentOperation(method), index
+	case "source":
+		return "get_source", index
+	case "cat", "cluster", "nodes", "snapshot", "ingest", "security", "ml", "tasks", "ilm", "transform", "license":
+		// Namespaced APIs, e.g. `/_cat/indices` or `/_cluster/health`.
+		if i+1 < len(segments) {
+			return api + "." + strings.TrimPrefix(segments[i+1], "_"), index
+		}
+	}
+	return api, index
+}
+
+// indexOperation names the requests to an index itself, or to the cluster when there's no index.
+func indexOperation(method, index string) string {
+	if index == "" {
+		if method == http.MethodHead {
+			return "ping"
+		}
+		return "info"
+	}
+	switch method {
+	case http.MethodPut:
+		return "indices.create"
+	case http.MethodDelete:
+		return "indices.delete"
+	case http.MethodHead:
+		return "indices.exists"
+	}
+	return "indices.get"
+}
+
+func documentOperation(method string) string {
+	switch method {
+	case http.MethodGet:
+		return "get"
+	case http.MethodDelete:
+		return "delete"
+	case http.MethodHead:
+		return "exists"
+	}
+	return "index"
+}
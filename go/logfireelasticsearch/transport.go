@@ -0,0 +1,103 @@
+package logfireelasticsearch
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// NewTransport wraps base so that a span is created for each request to the cluster.
+// If base is nil, [http.DefaultTransport] is used.
+//
+// The span ends when the response body is read to the end or closed, once the stats of the response are known.
+func NewTransport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, config: newConfig(opts)}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	config *config
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	operation, index := endpoint(r.Method, r.URL.Path)
+	name := operation
+	if index != "" {
+		name += " " + index
+	}
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(name),
+		t.config.system,
+		semconv.DBOperationName(operation),
+		semconv.HTTPRequestMethodKey.String(r.Method),
+	}
+	if index != "" {
+		attrs = append(attrs, semconv.DBCollectionName(index))
+	}
+	if host, port := httpconv.SplitHostPort(r.URL.Host); host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if port > 0 {
+			attrs = append(attrs, semconv.ServerPort(port))
+		}
+	}
+	ctx, span := t.config.tracer().Start(r.Context(), name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	// RoundTrippers must not modify the request.
+	r = r.Clone(ctx)
+	t.config.propagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+	var query *bodyBuffer
+	if t.config.queryBody && r.Body != nil && r.Body != http.NoBody {
+		query = &bodyBuffer{}
+		r.Body = &capturingBody{ReadCloser: r.Body, buf: query}
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if query != nil {
+		if text, ok := queryText(query.bytes(), !t.config.noSanitization); ok {
+			span.SetAttributes(semconv.DBQueryText(text))
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return resp, err
+	}
+	span.SetAttributes(semconv.DBResponseStatusCode(strconv.Itoa(resp.StatusCode)))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, "")
+	}
+
+	if resp.Body == nil || resp.Body == http.NoBody || !isJSON(resp.Header.Get("Content-Type")) {
+		span.End()
+		return resp, nil
+	}
+	buf := &bodyBuffer{}
+	resp.Body = &capturingBody{ReadCloser: resp.Body, buf: buf, done: func() {
+		span.SetAttributes(responseAttributes(buf.prefix())...)
+		span.End()
+	}}
+	return resp, nil
+}
+
+// isJSON reports whether a response is JSON, including the `application/vnd.elasticsearch+json` of Elasticsearch 8.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
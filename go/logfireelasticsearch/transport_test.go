@@ -0,0 +1,163 @@
+package logfireelasticsearch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) (*http.Client, string, *tracetest.SpanRecorder) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	opts = append([]Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}, opts...)
+	return &http.Client{Transport: NewTransport(nil, opts...)}, server.URL, recorder
+}
+
+// send sends a request and reads its response like the clients do.
+func send(t *testing.T, client *http.Client, method, url, body string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
+
+func TestSearch(t *testing.T) {
+	var traceparent string
+	client, url, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/vnd.elasticsearch+json; compatible-with=8")
+		_, _ = io.WriteString(w, `{"took": 12, "timed_out": false, "_shards": {"total": 3, "successful": 2, "skipped": 0, "failed": 1},
+			"hits": {"total": {"value": 42, "relation": "eq"}, "hits": [{"_id": "1"}]}}`)
+	}, WithQueryBody())
+
+	send(t, client, http.MethodPost, url+"/products/_search", `{"query": {"terms": {"tags": ["a", "b"]}}, "size": 10}`)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "search products" || span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:                       "search products",
+		"db.system.name":                     "elasticsearch",
+		"db.operation.name":                  "search",
+		"db.collection.name":                 "products",
+		"db.query.text":                      `{"query":{"terms":{"tags":"?"}},"size":"?"}`,
+		"db.response.status_code":            "200",
+		"db.response.returned_rows":          int64(42),
+		"db.elasticsearch.took":              int64(12),
+		"db.elasticsearch.shards.total":      int64(3),
+		"db.elasticsearch.shards.successful": int64(2),
+		"db.elasticsearch.shards.failed":     int64(1),
+		"server.address":                     "127.0.0.1",
+	})
+	if traceparent == "" {
+		t.Error("expected the trace context to be injected")
+	}
+}
+
+func TestBulkWithoutSanitization(t *testing.T) {
+	client, url, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"took": 3, "errors": false, "items": []}`)
+	}, WithQueryBody(), WithoutSanitization(), WithSystem("opensearch"))
+
+	body := "{\"index\":{\"_index\":\"logs\"}}\n{\"message\":\"hello\"}\n"
+	send(t, client, http.MethodPost, url+"/_bulk", body)
+
+	span := recorder.Ended()[0]
+	if span.Name() != "bulk" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"db.system.name":        "opensearch",
+		"db.query.text":         strings.TrimSpace(body),
+		"db.elasticsearch.took": int64(3),
+	})
+}
+
+func TestError(t *testing.T) {
+	client, url, recorder := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error": {"root_cause": [], "type": "index_not_found_exception", "reason": "no such index [missing]"}, "status": 404}`)
+	})
+
+	send(t, client, http.MethodGet, url+"/missing/_doc/1", "")
+
+	span := recorder.Ended()[0]
+	if span.Name() != "get missing" || span.Status().Code != codes.Error {
+		t.Errorf("unexpected span %q with status %v", span.Name(), span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"db.response.status_code": "404",
+		"error.type":              "index_not_found_exception",
+	})
+	if _, ok := oteltest.Attributes(span.Attributes())["db.query.text"]; ok {
+		t.Error("expected the query not to be recorded by default")
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	for _, tt := range []struct {
+		method, path, operation, index string
+	}{
+		{"GET", "/", "info", ""},
+		{"HEAD", "/", "ping", ""},
+		{"PUT", "/products", "indices.create", "products"},
+		{"DELETE", "/products", "indices.delete", "products"},
+		{"PUT", "/products/_doc/1", "index", "products"},
+		{"POST", "/products/_doc", "index", "products"},
+		{"HEAD", "/products/_doc/1", "exists", "products"},
+		{"POST", "/products/_update/1", "update", "products"},
+		{"GET", "/products/_source/1", "get_source", "products"},
+		{"POST", "/products,orders/_count", "count", "products,orders"},
+		{"POST", "/_msearch", "msearch", ""},
+		{"GET", "/_cat/indices", "cat.indices", ""},
+		{"GET", "/_cluster/health", "cluster.health", ""},
+		{"GET", "/logs-*/_mapping", "mapping", "logs-*"},
+	} {
+		operation, index := endpoint(tt.method, tt.path)
+		if operation != tt.operation || index != tt.index {
+			t.Errorf("endpoint(%s %s) = %q, %q, want %q, %q", tt.method, tt.path, operation, index, tt.operation, tt.index)
+		}
+	}
+}
+
+func TestResponseAttributesTruncated(t *testing.T) {
+	body := `{"took": 5, "_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0}, "hits": {"total": 7, "hits": [{"_source": {"a"`
+	attrs := map[attribute.Key]any{}
+	for _, kv := range responseAttributes([]byte(body)) {
+		attrs[kv.Key] = kv.Value.AsInterface()
+	}
+	if attrs["db.elasticsearch.took"] != int64(5) || attrs["db.response.returned_rows"] != int64(7) {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
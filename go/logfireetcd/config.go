@@ -0,0 +1,72 @@
+// Package logfireetcd instruments etcd v3 clients for Pydantic Logfire.
+//
+// [DialOptions] returns the gRPC interceptors to add to the dial options of a client. They create
+// a client span for each request, named after the operation like `GET` or `TXN`, with the key as
+// message. Each watch gets a span, lasting until it's canceled, with an event for each notification.
+// Each lease gets a span lasting until it's revoked or expires, with an event for each keepalive:
+//
+//	client, err := clientv3.New(clientv3.Config{
+//		Endpoints:   []string{"127.0.0.1:2379"},
+//		DialOptions: logfireetcd.DialOptions(),
+//	})
+package logfireetcd
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireetcd"
+
+// Option configures [DialOptions] and the interceptors.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject the trace context in the gRPC metadata,
+// for etcd servers with tracing enabled. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	attrs          []attribute.KeyValue
+	leases         *leases
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.leases = &leases{config: c, spans: map[int64]*lease{}}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
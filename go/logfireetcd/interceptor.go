@@ -0,0 +1,135 @@
+package logfireetcd
+
+import (
+	"context"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pydantic/logfire/go/internal/rpcconv"
+)
+
+// system is the `db.system.name` of all spans.
+var system = semconv.DBSystemNameKey.String("etcd")
+
+const (
+	watchMethod     = "/etcdserverpb.Watch/Watch"
+	keepAliveMethod = "/etcdserverpb.Lease/LeaseKeepAlive"
+)
+
+// DialOptions returns the dial options adding the interceptors of this package to a client,
+// to append to the DialOptions of a [clientv3.Config]. The interceptors share the spans of leases,
+// so they're only available together.
+func DialOptions(opts ...Option) []grpc.DialOption {
+	cfg := newConfig(opts)
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(cfg.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(cfg.streamInterceptor),
+	}
+}
+
+// unaryInterceptor creates a span for each request of a client. Granting a lease also starts
+// the span of the lease, and revoking it ends the span.
+func (c *config) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+	name, attrs := requestAttributes(method, req)
+	spanCtx, span := c.start(ctx, name, cc, attrs...)
+	defer span.End()
+
+	err := invoker(spanCtx, method, req, reply, cc, callOpts...)
+	setStatus(span, err)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(responseAttributes(reply)...)
+	switch reply := reply.(type) {
+	case *pb.LeaseGrantResponse:
+		c.leases.grant(ctx, cc, reply.GetID(), reply.GetTTL())
+	case *pb.LeaseRevokeResponse:
+		c.leases.end(req.(*pb.LeaseRevokeRequest).GetID(), leaseRevokedKey.Bool(true))
+	}
+	return nil
+}
+
+// streamInterceptor traces the watches and lease keepalives of a client. Many watches and leases
+// share a stream, so the streams themselves don't get spans, and neither do other streams such as snapshots.
+func (c *config) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+	switch method {
+	case watchMethod:
+		cs, err := streamer(c.inject(ctx), desc, cc, method, callOpts...)
+		if err != nil {
+			return cs, err
+		}
+		return &watchStream{ClientStream: cs, ctx: ctx, cc: cc, config: c, watches: map[int64]*watch{}}, nil
+	case keepAliveMethod:
+		cs, err := streamer(c.inject(ctx), desc, cc, method, callOpts...)
+		if err != nil {
+			return cs, err
+		}
+		return &keepAliveStream{ClientStream: cs, ctx: ctx, cc: cc, config: c}, nil
+	}
+	return streamer(ctx, desc, cc, method, callOpts...)
+}
+
+// start creates the span of an operation, injecting its context in the outgoing metadata.
+func (c *config) start(ctx context.Context, name string, cc *grpc.ClientConn, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx, span := c.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(system),
+		trace.WithAttributes(rpcconv.ServerAddress(cc.Target())...),
+		trace.WithAttributes(c.attrs...),
+		trace.WithAttributes(attrs...),
+	)
+	return c.inject(ctx), span
+}
+
+func (c *config) inject(ctx context.Context) context.Context {
+	// The metadata of the context must not be modified.
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	c.propagator().Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// setStatus records the gRPC status code of a request, see [rpcconv.SetStatus].
+func setStatus(span trace.Span, err error) {
+	s, ok := status.FromError(err)
+	if !ok {
+		s = status.FromContextError(err)
+	}
+	code := rpcconv.Code(s.Code())
+	rpcconv.SetStatus(span, trace.SpanKindClient, code, code.String(), err, s.Message())
+}
+
+// canceled is whether a stream ended because its context was canceled, which is how
+// clients close watches and keepalives.
+func canceled(err error) bool {
+	return status.Code(err) == grpccodes.Canceled || err == context.Canceled
+}
+
+// metadataCarrier adapts gRPC metadata to the propagators.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
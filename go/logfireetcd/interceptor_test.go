@@ -0,0 +1,329 @@
+package logfireetcd
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// fakeKV answers every GET with one of three keys at revision 5. Keys starting with "fail" fail.
+type fakeKV struct {
+	pb.UnimplementedKVServer
+	traceparents chan string
+}
+
+func header() *pb.ResponseHeader { return &pb.ResponseHeader{Revision: 5} }
+
+func (kv *fakeKV) Range(ctx context.Context, req *pb.RangeRequest) (*pb.RangeResponse, error) {
+	if strings.HasPrefix(string(req.Key), "fail") {
+		return nil, status.Error(grpccodes.FailedPrecondition, "etcdserver: mvcc: required revision is a future revision")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("traceparent"); len(values) > 0 {
+		kv.traceparents <- values[0]
+	}
+	return &pb.RangeResponse{Header: header(), Kvs: []*mvccpb.KeyValue{{Key: req.Key, Value: []byte("v")}}, Count: 3}, nil
+}
+
+func (*fakeKV) Put(context.Context, *pb.PutRequest) (*pb.PutResponse, error) {
+	return &pb.PutResponse{Header: header()}, nil
+}
+
+func (*fakeKV) DeleteRange(context.Context, *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	return &pb.DeleteRangeResponse{Header: header(), Deleted: 2}, nil
+}
+
+func (*fakeKV) Txn(context.Context, *pb.TxnRequest) (*pb.TxnResponse, error) {
+	return &pb.TxnResponse{Header: header(), Succeeded: true}, nil
+}
+
+// fakeWatch sends a notification with a PUT and a DELETE event after creating a watch.
+type fakeWatch struct {
+	pb.UnimplementedWatchServer
+}
+
+func (fakeWatch) Watch(stream pb.Watch_WatchServer) error {
+	var id int64
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		switch {
+		case req.GetCreateRequest() != nil:
+			id++
+			if err := stream.Send(&pb.WatchResponse{Header: header(), WatchId: id, Created: true}); err != nil {
+				return err
+			}
+			key := req.GetCreateRequest().Key
+			if err := stream.Send(&pb.WatchResponse{Header: &pb.ResponseHeader{Revision: 6}, WatchId: id, Events: []*mvccpb.Event{
+				{Type: mvccpb.Event_PUT, Kv: &mvccpb.KeyValue{Key: key}},
+				{Type: mvccpb.Event_DELETE, Kv: &mvccpb.KeyValue{Key: key}},
+			}}); err != nil {
+				return err
+			}
+		case req.GetCancelRequest() != nil:
+			if err := stream.Send(&pb.WatchResponse{Header: header(), WatchId: req.GetCancelRequest().WatchId, Canceled: true}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// fakeLease grants leases with a TTL of 60 seconds, which expire once expired is set.
+type fakeLease struct {
+	pb.UnimplementedLeaseServer
+	expired atomic.Bool
+}
+
+func (*fakeLease) LeaseGrant(context.Context, *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	return &pb.LeaseGrantResponse{Header: header(), ID: 0x1234, TTL: 60}, nil
+}
+
+func (*fakeLease) LeaseRevoke(context.Context, *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	return &pb.LeaseRevokeResponse{Header: header()}, nil
+}
+
+func (l *fakeLease) LeaseKeepAlive(stream pb.Lease_LeaseKeepAliveServer) error {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		ttl := int64(60)
+		if l.expired.Load() {
+			ttl = 0
+		}
+		if err := stream.Send(&pb.LeaseKeepAliveResponse{Header: header(), ID: req.ID, TTL: ttl}); err != nil {
+			return err
+		}
+	}
+}
+
+type fakeServer struct {
+	kv    *fakeKV
+	lease *fakeLease
+}
+
+func newTestClient(t *testing.T) (*clientv3.Client, fakeServer, *tracetest.SpanRecorder) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	fake := fakeServer{kv: &fakeKV{traceparents: make(chan string, 10)}, lease: &fakeLease{}}
+	pb.RegisterKVServer(server, fake.kv)
+	pb.RegisterWatchServer(server, fakeWatch{})
+	pb.RegisterLeaseServer(server, fake.lease)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{lis.Addr().String()},
+		DialTimeout: 5 * time.Second,
+		DialOptions: DialOptions(WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client, fake, recorder
+}
+
+// waitForSpan waits for a span named name to end, as streams are handled in the background.
+func waitForSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for range 100 {
+		for _, span := range recorder.Ended() {
+			if span.Name() == name {
+				return span
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no %s span", name)
+	return nil
+}
+
+func TestGet(t *testing.T) {
+	client, fake, recorder := newTestClient(t)
+
+	if _, err := client.Get(t.Context(), "/config/", clientv3.WithPrefix()); err != nil {
+		t.Fatal(err)
+	}
+
+	span := waitForSpan(t, recorder, "GET")
+	if span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span kind %v", span.SpanKind())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:              "GET /config/*",
+		"db.system.name":            "etcd",
+		"db.operation.name":         "GET",
+		"db.etcd.key":               "/config/",
+		"db.etcd.range_end":         "/config0",
+		"db.etcd.revision":          int64(5),
+		"db.etcd.count":             int64(3),
+		"db.response.returned_rows": int64(1),
+		"server.address":            "127.0.0.1",
+	})
+	if got := <-fake.kv.traceparents; !strings.Contains(got, span.SpanContext().SpanID().String()) {
+		t.Errorf("traceparent %q doesn't contain the span ID", got)
+	}
+}
+
+func TestGetError(t *testing.T) {
+	client, _, recorder := newTestClient(t)
+
+	if _, err := client.Get(t.Context(), "failing"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	span := waitForSpan(t, recorder, "GET")
+	if span.Status().Code != codes.Error {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{"rpc.response.status_code": "FAILED_PRECONDITION"})
+}
+
+func TestPutDeleteTxn(t *testing.T) {
+	client, _, recorder := newTestClient(t)
+
+	if _, err := client.Put(t.Context(), "/config/a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Delete(t.Context(), "/config/", clientv3.WithPrefix()); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Txn(t.Context()).
+		If(clientv3.Compare(clientv3.CreateRevision("/lock"), "=", 0)).
+		Then(clientv3.OpPut("/lock", "me")).
+		Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oteltest.CheckAttributes(t, waitForSpan(t, recorder, "PUT").Attributes(), map[attribute.Key]any{
+		logfire.MsgKey: "PUT /config/a",
+	})
+	oteltest.CheckAttributes(t, waitForSpan(t, recorder, "DELETE").Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:              "DELETE /config/*",
+		"db.response.affected_rows": int64(2),
+	})
+	oteltest.CheckAttributes(t, waitForSpan(t, recorder, "TXN").Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:            "TXN /lock",
+		"db.etcd.txn.compares":    int64(1),
+		"db.etcd.txn.success_ops": int64(1),
+		"db.etcd.txn.failure_ops": int64(0),
+		"db.etcd.txn.succeeded":   true,
+	})
+}
+
+func TestWatch(t *testing.T) {
+	client, _, recorder := newTestClient(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	ch := client.Watch(ctx, "/jobs/", clientv3.WithPrefix())
+	resp := <-ch
+	if len(resp.Events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(resp.Events))
+	}
+	for _, span := range recorder.Ended() {
+		if span.Name() == "WATCH" {
+			t.Fatal("expected the span of the watch to last until it's canceled")
+		}
+	}
+	cancel()
+
+	span := waitForSpan(t, recorder, "WATCH")
+	if span.Status().Code == codes.Error {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:                "WATCH /jobs/*",
+		"db.etcd.watch_id":            int64(1),
+		"db.etcd.watch.notifications": int64(1),
+		"db.etcd.events":              int64(2),
+	})
+	events := span.Events()
+	if len(events) != 1 || events[0].Name != "watch notification" {
+		t.Fatalf("expected a watch notification event, got %v", events)
+	}
+	oteltest.CheckAttributes(t, events[0].Attributes, map[attribute.Key]any{
+		"db.etcd.revision":      int64(6),
+		"db.etcd.events.put":    int64(1),
+		"db.etcd.events.delete": int64(1),
+	})
+}
+
+func TestLease(t *testing.T) {
+	client, _, recorder := newTestClient(t)
+
+	grant, err := client.Grant(t.Context(), 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.KeepAliveOnce(t.Context(), grant.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Revoke(t.Context(), grant.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	span := waitForSpan(t, recorder, "LEASE")
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:             "LEASE 1234",
+		"db.etcd.lease_id":         int64(0x1234),
+		"db.etcd.lease.ttl":        int64(60),
+		"db.etcd.lease.keepalives": int64(1),
+		"db.etcd.lease.revoked":    true,
+	})
+	if events := span.Events(); len(events) != 1 || events[0].Name != "keepalive" {
+		t.Errorf("expected a keepalive event, got %v", events)
+	}
+	grantSpan := waitForSpan(t, recorder, "LEASE GRANT")
+	if span.Parent().SpanID() == grantSpan.SpanContext().SpanID() {
+		t.Error("expected the lease not to be a child of the span granting it")
+	}
+}
+
+func TestLeaseExpired(t *testing.T) {
+	client, fake, recorder := newTestClient(t)
+
+	grant, err := client.Grant(t.Context(), 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.lease.expired.Store(true)
+	if _, err := client.KeepAliveOnce(t.Context(), grant.ID); err == nil {
+		t.Fatal("expected an error for an expired lease")
+	}
+
+	oteltest.CheckAttributes(t, waitForSpan(t, recorder, "LEASE").Attributes(), map[attribute.Key]any{
+		"db.etcd.lease.expired": true,
+	})
+}
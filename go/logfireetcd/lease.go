@@ -0,0 +1,104 @@
+package logfireetcd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const (
+	// leaseRevokedKey records that a lease was revoked by the client.
+	leaseRevokedKey = attribute.Key("db.etcd.lease.revoked")
+	// leaseExpiredKey records that a lease expired: it wasn't kept alive within its time to live.
+	leaseExpiredKey = attribute.Key("db.etcd.lease.expired")
+	// keepalivesKey records the number of keepalives of a lease.
+	keepalivesKey = attribute.Key("db.etcd.lease.keepalives")
+)
+
+// leases holds the spans of the leases of a client, which last from when they're granted,
+// or first kept alive by this client, until they're revoked or expire.
+type leases struct {
+	config *config
+
+	mu    sync.Mutex
+	spans map[int64]*lease
+}
+
+type lease struct {
+	span       trace.Span
+	expiry     *time.Timer
+	keepalives int
+}
+
+// grant starts the span of a lease, as a sibling of the span of the request granting it.
+func (l *leases) grant(ctx context.Context, cc *grpc.ClientConn, id, ttl int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.start(ctx, cc, id, ttl)
+}
+
+// keepAlive records a keepalive of a lease, whose TTL is 0 if the lease already expired.
+func (l *leases) keepAlive(ctx context.Context, cc *grpc.ClientConn, id, ttl int64) {
+	if ttl <= 0 {
+		l.end(id, leaseExpiredKey.Bool(true))
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lease, ok := l.spans[id]
+	if !ok {
+		lease = l.start(ctx, cc, id, ttl)
+	}
+	lease.keepalives++
+	lease.span.AddEvent("keepalive", trace.WithAttributes(leaseTTLKey.Int64(ttl)))
+	lease.expiry.Reset(time.Duration(ttl) * time.Second)
+}
+
+// start starts the span of a lease, which ends when its TTL elapses unless it's kept alive.
+// The server may expire it slightly later.
+func (l *leases) start(ctx context.Context, cc *grpc.ClientConn, id, ttl int64) *lease {
+	attrs := append(operationAttributes("LEASE", leaseMessage(id)), leaseIDKey.Int64(id), leaseTTLKey.Int64(ttl))
+	_, span := l.config.start(ctx, "LEASE", cc, attrs...)
+	lease := &lease{span: span}
+	lease.expiry = time.AfterFunc(time.Duration(ttl)*time.Second, func() {
+		l.end(id, leaseExpiredKey.Bool(true))
+	})
+	l.spans[id] = lease
+	return lease
+}
+
+func (l *leases) end(id int64, attrs ...attribute.KeyValue) {
+	l.mu.Lock()
+	lease, ok := l.spans[id]
+	delete(l.spans, id)
+	l.mu.Unlock()
+	if !ok {
+		return
+	}
+	lease.expiry.Stop()
+	lease.span.SetAttributes(keepalivesKey.Int(lease.keepalives))
+	lease.span.SetAttributes(attrs...)
+	lease.span.End()
+}
+
+// keepAliveStream records the keepalives received on a stream. Leases outlive the streams
+// keeping them alive, so their spans don't end with them.
+type keepAliveStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	cc     *grpc.ClientConn
+	config *config
+}
+
+func (s *keepAliveStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if resp, ok := m.(*pb.LeaseKeepAliveResponse); ok && err == nil {
+		s.config.leases.keepAlive(s.ctx, s.cc, resp.GetID(), resp.GetTTL())
+	}
+	return err
+}
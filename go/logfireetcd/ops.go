@@ -0,0 +1,162 @@
+package logfireetcd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+
+	"github.com/pydantic/logfire/go/internal/rpcconv"
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// keyKey records the key of a request, or the start of its range.
+	keyKey = attribute.Key("db.etcd.key")
+	// rangeEndKey records the end of the range of a request, excluded.
+	rangeEndKey = attribute.Key("db.etcd.range_end")
+	// revisionKey records the revision of the store when a request was handled,
+	// or the revision requested.
+	revisionKey = attribute.Key("db.etcd.revision")
+	// countKey records the number of keys in the range of a GET, which can be more than those returned.
+	countKey = attribute.Key("db.etcd.count")
+	// leaseIDKey records the ID of a lease.
+	leaseIDKey = attribute.Key("db.etcd.lease_id")
+	// leaseTTLKey records the time to live of a lease in seconds.
+	leaseTTLKey = attribute.Key("db.etcd.lease.ttl")
+	// txnComparesKey records the number of comparisons of a transaction.
+	txnComparesKey = attribute.Key("db.etcd.txn.compares")
+	// txnSuccessKey records the number of operations of a transaction run if the comparisons succeed.
+	txnSuccessKey = attribute.Key("db.etcd.txn.success_ops")
+	// txnFailureKey records the number of operations of a transaction run if they fail.
+	txnFailureKey = attribute.Key("db.etcd.txn.failure_ops")
+	// txnSucceededKey records whether the comparisons of a transaction succeeded.
+	txnSucceededKey = attribute.Key("db.etcd.txn.succeeded")
+)
+
+// requestAttributes returns the span name and attributes of a request. Requests other than
+// those of the key-value store and leases are named after their method, like `etcdserverpb.Cluster/MemberList`.
+func requestAttributes(method string, req any) (string, []attribute.KeyValue) {
+	var (
+		op    string
+		attrs []attribute.KeyValue
+		msg   string
+	)
+	switch req := req.(type) {
+	case *pb.RangeRequest:
+		op = "GET"
+		msg, attrs = keyAttributes(req.GetKey(), req.GetRangeEnd())
+		if rev := req.GetRevision(); rev > 0 {
+			attrs = append(attrs, revisionKey.Int64(rev))
+		}
+	case *pb.PutRequest:
+		op = "PUT"
+		msg, attrs = keyAttributes(req.GetKey(), nil)
+		if lease := req.GetLease(); lease != 0 {
+			attrs = append(attrs, leaseIDKey.Int64(lease))
+		}
+	case *pb.DeleteRangeRequest:
+		op = "DELETE"
+		msg, attrs = keyAttributes(req.GetKey(), req.GetRangeEnd())
+	case *pb.TxnRequest:
+		op = "TXN"
+		attrs = []attribute.KeyValue{
+			txnComparesKey.Int(len(req.GetCompare())),
+			txnSuccessKey.Int(len(req.GetSuccess())),
+			txnFailureKey.Int(len(req.GetFailure())),
+		}
+		// Transactions usually compare a single key, e.g. to lock or update it.
+		if keys := compareKeys(req.GetCompare()); len(keys) == 1 {
+			msg = keys[0]
+		}
+	case *pb.CompactionRequest:
+		op = "COMPACT"
+		attrs = []attribute.KeyValue{revisionKey.Int64(req.GetRevision())}
+		msg = fmt.Sprint(req.GetRevision())
+	case *pb.LeaseGrantRequest:
+		op = "LEASE GRANT"
+		attrs = []attribute.KeyValue{leaseTTLKey.Int64(req.GetTTL())}
+	case *pb.LeaseRevokeRequest:
+		op = "LEASE REVOKE"
+		attrs = []attribute.KeyValue{leaseIDKey.Int64(req.GetID())}
+		msg = leaseMessage(req.GetID())
+	default:
+		name := rpcconv.SpanName(method)
+		return name, []attribute.KeyValue{semconv.RPCMethod(name)}
+	}
+	return op, append(attrs, operationAttributes(op, msg)...)
+}
+
+// operationAttributes returns the name of an operation and its message, e.g. `GET /config/*` for target `/config/*`.
+func operationAttributes(op, target string) []attribute.KeyValue {
+	msg := op
+	if target != "" {
+		msg += " " + target
+	}
+	return []attribute.KeyValue{logfire.MsgKey.String(msg), semconv.DBOperationName(op)}
+}
+
+// responseAttributes returns the attributes of the response of a request.
+func responseAttributes(reply any) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if r, ok := reply.(interface{ GetHeader() *pb.ResponseHeader }); ok && r.GetHeader() != nil {
+		attrs = append(attrs, revisionKey.Int64(r.GetHeader().GetRevision()))
+	}
+	switch reply := reply.(type) {
+	case *pb.RangeResponse:
+		attrs = append(attrs, semconv.DBResponseReturnedRows(len(reply.GetKvs())), countKey.Int64(reply.GetCount()))
+	case *pb.DeleteRangeResponse:
+		attrs = append(attrs, sqlconv.RowsAffectedKey.Int64(reply.GetDeleted()))
+	case *pb.TxnResponse:
+		attrs = append(attrs, txnSucceededKey.Bool(reply.GetSucceeded()))
+	case *pb.LeaseGrantResponse:
+		attrs = append(attrs, leaseIDKey.Int64(reply.GetID()), leaseTTLKey.Int64(reply.GetTTL()))
+	}
+	return attrs
+}
+
+// keyAttributes returns the message and attributes of the key or range of a request.
+// Prefixes are written like `/config/*`.
+func keyAttributes(key, rangeEnd []byte) (string, []attribute.KeyValue) {
+	k := keyString(key)
+	attrs := []attribute.KeyValue{keyKey.String(k)}
+	if len(rangeEnd) == 0 {
+		return k, attrs
+	}
+	end := keyString(rangeEnd)
+	attrs = append(attrs, rangeEndKey.String(end))
+	switch {
+	case end == clientv3.GetPrefixRangeEnd(k):
+		return k + "*", attrs
+	case end == "\x00":
+		return ">= " + k, attrs
+	}
+	return k + " .. " + end, attrs
+}
+
+// compareKeys returns the distinct keys compared by a transaction.
+func compareKeys(compares []*pb.Compare) []string {
+	var keys []string
+	for _, c := range compares {
+		k := keyString(c.GetKey())
+		if !slices.Contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// keyString converts a key to a string, as keys are usually text but may be any bytes.
+func keyString(key []byte) string {
+	return strings.ToValidUTF8(string(key), "�")
+}
+
+// leaseMessage formats the ID of a lease in hexadecimal, like etcdctl.
+func leaseMessage(id int64) string {
+	return fmt.Sprintf("%x", id)
+}
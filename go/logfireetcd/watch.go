@@ -0,0 +1,173 @@
+package logfireetcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const (
+	// watchIDKey records the ID of a watch, unique within its stream.
+	watchIDKey = attribute.Key("db.etcd.watch_id")
+	// eventsKey records the number of events of a watch notification, or of all the notifications of a watch.
+	eventsKey = attribute.Key("db.etcd.events")
+	// putsKey records the number of PUT events of a watch notification.
+	putsKey = attribute.Key("db.etcd.events.put")
+	// deletesKey records the number of DELETE events of a watch notification.
+	deletesKey = attribute.Key("db.etcd.events.delete")
+	// notificationsKey records the number of notifications of a watch.
+	notificationsKey = attribute.Key("db.etcd.watch.notifications")
+	// compactRevisionKey records the revision a watch was canceled at because it was compacted.
+	compactRevisionKey = attribute.Key("db.etcd.compact_revision")
+)
+
+// watch is the span of a watch, from its creation until it's canceled.
+type watch struct {
+	span          trace.Span
+	notifications int
+	events        int
+}
+
+func (w *watch) end(err error, attrs ...attribute.KeyValue) {
+	w.span.SetAttributes(notificationsKey.Int(w.notifications), eventsKey.Int(w.events))
+	w.span.SetAttributes(attrs...)
+	if err != nil {
+		w.span.RecordError(err)
+		w.span.SetStatus(codes.Error, err.Error())
+	}
+	w.span.End()
+}
+
+// watchStream creates a span for each watch created on a stream. The client waits for a watch
+// to be created before creating the next one, so they're matched to their IDs in order.
+type watchStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	cc     *grpc.ClientConn
+	config *config
+
+	mu      sync.Mutex
+	pending []*watch
+	watches map[int64]*watch
+}
+
+func (s *watchStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if req, ok := m.(*pb.WatchRequest); ok && err == nil && req.GetCreateRequest() != nil {
+		s.create(req.GetCreateRequest())
+	}
+	return err
+}
+
+func (s *watchStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.endAll(err)
+		return err
+	}
+	if resp, ok := m.(*pb.WatchResponse); ok {
+		s.receive(resp)
+	}
+	return nil
+}
+
+func (s *watchStream) create(req *pb.WatchCreateRequest) {
+	msg, attrs := keyAttributes(req.GetKey(), req.GetRangeEnd())
+	if rev := req.GetStartRevision(); rev > 0 {
+		attrs = append(attrs, revisionKey.Int64(rev))
+	}
+	_, span := s.config.start(s.ctx, "WATCH", s.cc, append(attrs, operationAttributes("WATCH", msg)...)...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, &watch{span: span})
+}
+
+func (s *watchStream) receive(resp *pb.WatchResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := resp.GetWatchId()
+	if resp.GetCreated() {
+		if len(s.pending) == 0 {
+			return
+		}
+		w := s.pending[0]
+		s.pending = s.pending[1:]
+		w.span.SetAttributes(watchIDKey.Int64(id))
+		if resp.GetCanceled() {
+			w.end(cancelError(resp))
+			return
+		}
+		s.watches[id] = w
+	}
+	w, ok := s.watches[id]
+	if !ok {
+		return
+	}
+	if resp.GetCanceled() {
+		delete(s.watches, id)
+		var attrs []attribute.KeyValue
+		if rev := resp.GetCompactRevision(); rev > 0 {
+			attrs = append(attrs, compactRevisionKey.Int64(rev))
+		}
+		w.end(cancelError(resp), attrs...)
+		return
+	}
+	events := resp.GetEvents()
+	if len(events) == 0 {
+		if !resp.GetCreated() {
+			w.span.AddEvent("progress notification", trace.WithAttributes(revisionKey.Int64(resp.GetHeader().GetRevision())))
+		}
+		return
+	}
+	puts := 0
+	for _, event := range events {
+		if event.GetType() == mvccpb.Event_PUT {
+			puts++
+		}
+	}
+	w.notifications++
+	w.events += len(events)
+	w.span.AddEvent("watch notification", trace.WithAttributes(
+		revisionKey.Int64(resp.GetHeader().GetRevision()),
+		eventsKey.Int(len(events)),
+		putsKey.Int(puts),
+		deletesKey.Int(len(events)-puts),
+	))
+}
+
+// endAll ends the spans of the watches of a stream when it ends. Streams end when the client
+// closes the watches by canceling their context, which isn't an error.
+func (s *watchStream) endAll(err error) {
+	if err == io.EOF || canceled(err) {
+		err = nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, w := range s.pending {
+		w.end(err)
+	}
+	for _, w := range s.watches {
+		w.end(err)
+	}
+	s.pending, s.watches = nil, map[int64]*watch{}
+}
+
+// cancelError returns the error of a watch canceled by the server, or nil if the client canceled it.
+func cancelError(resp *pb.WatchResponse) error {
+	if rev := resp.GetCompactRevision(); rev > 0 {
+		return fmt.Errorf("required revision %d has been compacted", rev)
+	}
+	if reason := resp.GetCancelReason(); reason != "" {
+		return errors.New(reason)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package logfireexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// stderrKey records the beginning of the stderr of a failed command, with [WithStderr].
+const stderrKey = attribute.Key("process.stderr")
+
+// Cmd is an [exec.Cmd] creating a span for each run.
+//
+// The span starts with Start and ends with Wait, like the process, and Run, Output and CombinedOutput
+// do both. Calling the methods of the embedded [exec.Cmd] directly doesn't create spans.
+type Cmd struct {
+	*exec.Cmd
+	ctx    context.Context
+	config *config
+	span   trace.Span
+	stderr *limitedBuffer
+}
+
+// CommandContext returns a [Cmd] to run the named program with the arguments, like [exec.CommandContext].
+func CommandContext(ctx context.Context, name string, arg ...string) *Cmd {
+	return Wrap(ctx, exec.CommandContext(ctx, name, arg...))
+}
+
+// Wrap returns a [Cmd] running cmd, whose spans are children of ctx.
+func Wrap(ctx context.Context, cmd *exec.Cmd, opts ...Option) *Cmd {
+	return &Cmd{Cmd: cmd, ctx: ctx, config: newConfig(opts)}
+}
+
+// Start starts the command and its span.
+func (c *Cmd) Start() error {
+	args := c.Args
+	if !c.config.noSanitization {
+		args = sanitizeArgs(args)
+	}
+	name := filepath.Base(c.Path)
+	if len(c.Args) > 0 {
+		name = filepath.Base(c.Args[0])
+	}
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(strings.Join(args, " ")),
+		semconv.ProcessExecutableName(name),
+		semconv.ProcessExecutablePath(c.Path),
+		semconv.ProcessCommandArgs(args...),
+	}
+	_, c.span = c.config.tracer().Start(c.ctx, "exec "+name,
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(c.config.attrs...),
+	)
+
+	if c.config.stderrSize > 0 {
+		c.stderr = &limitedBuffer{max: c.config.stderrSize}
+		switch {
+		case c.Stderr == nil:
+			c.Stderr = c.stderr
+		case interfaceEqual(c.Stderr, c.Stdout):
+			// exec copies both outputs with the same goroutine when they're the same writer,
+			// so they're serialized once they're not.
+			w := &lockedWriter{w: c.Stdout}
+			c.Stdout, c.Stderr = w, io.MultiWriter(w, c.stderr)
+		default:
+			c.Stderr = io.MultiWriter(c.Stderr, c.stderr)
+		}
+	}
+
+	if err := c.Cmd.Start(); err != nil {
+		c.end(err)
+		return err
+	}
+	c.span.SetAttributes(semconv.ProcessPID(c.Process.Pid))
+	return nil
+}
+
+// Wait waits for the command to exit and ends its span, with its exit code.
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+	if c.span != nil {
+		c.end(err)
+	}
+	return err
+}
+
+// Run starts the command and waits for it to exit.
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Output runs the command and returns its stdout, like [exec.Cmd.Output].
+func (c *Cmd) Output() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	var stderr *limitedBuffer
+	if c.Stderr == nil {
+		// Like exec, keep the beginning of stderr for the returned error.
+		stderr = &limitedBuffer{max: 32 << 10}
+		c.Stderr = stderr
+	}
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if stderr != nil && errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
+// CombinedOutput runs the command and returns its stdout and stderr, like [exec.Cmd.CombinedOutput].
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("exec: Stderr already set")
+	}
+	var b bytes.Buffer
+	c.Stdout = &b
+	c.Stderr = &b
+	err := c.Run()
+	return b.Bytes(), err
+}
+
+func (c *Cmd) end(err error) {
+	span := c.span
+	c.span = nil
+	if c.ProcessState != nil {
+		span.SetAttributes(semconv.ProcessExitCode(c.ProcessState.ExitCode()))
+	}
+	if err != nil {
+		if c.stderr != nil && c.stderr.Len() > 0 {
+			span.SetAttributes(stderrKey.String(c.stderr.String()))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// interfaceEqual compares writers like exec does, without panicking on uncomparable values.
+func interfaceEqual(a, b any) bool {
+	defer func() {
+		recover()
+	}()
+	return a == b
+}
+
+// sensitiveFlag matches the names of flags and environment variables whose values are secrets.
+var sensitiveFlag = regexp.MustCompile(`(?i)passw|pwd|secret|token|auth|credential|api[._-]?key|private[._-]?key|session|cookie|jwt`)
+
+// sanitizeArgs replaces the values of sensitive flags, like `--password=hunter2`, `--token abc`
+// or `PGPASSWORD=hunter2`, by `?`, and redacts the passwords of URLs.
+func sanitizeArgs(args []string) []string {
+	sanitized := make([]string, len(args))
+	redactNext := false
+	for i, arg := range args {
+		switch {
+		case i == 0:
+			sanitized[i] = arg
+		case redactNext && !strings.HasPrefix(arg, "-"):
+			sanitized[i] = "?"
+		default:
+			sanitized[i] = sanitizeArg(arg)
+		}
+		redactNext = i > 0 && strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") && sensitiveFlag.MatchString(arg)
+	}
+	return sanitized
+}
+
+func sanitizeArg(arg string) string {
+	if name, _, ok := strings.Cut(arg, "="); ok && sensitiveFlag.MatchString(name) {
+		return name + "=?"
+	}
+	if strings.Contains(arg, "://") {
+		if u, err := url.Parse(arg); err == nil && u.User != nil {
+			return u.Redacted()
+		}
+	}
+	return arg
+}
+
+// limitedBuffer keeps the first max bytes written to it.
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
@@ -0,0 +1,74 @@
+// Package logfireexec instruments subprocesses run with os/exec for Pydantic Logfire.
+//
+// [CommandContext] is a drop-in replacement for [exec.CommandContext] creating a span for each run
+// of the command, named like `exec git`, with its sanitized arguments and exit code:
+//
+//	out, err := logfireexec.CommandContext(ctx, "git", "rev-parse", "HEAD").Output()
+//
+// [Wrap] instruments an existing [exec.Cmd] with options, e.g. to record the stderr of failed commands:
+//
+//	cmd := logfireexec.Wrap(ctx, exec.CommandContext(ctx, "pg_dump", dsn), logfireexec.WithStderr(4096))
+//	err := cmd.Run()
+package logfireexec
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireexec"
+
+// Option configures [Wrap].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithStderr records up to maxSize bytes of the stderr of commands which fail as the
+// `process.stderr` attribute. The output is still written to the Stderr of the command if set.
+func WithStderr(maxSize int) Option {
+	return func(c *config) {
+		c.stderrSize = maxSize
+	}
+}
+
+// WithoutSanitization records arguments as is, rather than with the values of
+// flags like `--password` and the passwords of URLs replaced by `?`.
+func WithoutSanitization() Option {
+	return func(c *config) {
+		c.noSanitization = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	stderrSize     int
+	noSanitization bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
@@ -0,0 +1,201 @@
+// Package logfirefiber instruments Fiber applications for Pydantic Logfire.
+//
+// Fiber is built on fasthttp rather than net/http, so it can't use the logfirehttp handler.
+// [Middleware] creates the same server spans, named after the route template:
+//
+//	app := fiber.New()
+//	app.Use(logfirefiber.Middleware())
+//	app.Get("/users/:id", getUser) // spans named `GET /users/:id`
+//
+// Handlers should pass `c.Context()` to Logfire and other instrumented code,
+// so that their spans are children of the request span.
+package logfirefiber
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirefiber"
+
+// Option configures [Middleware].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider used to record the `http.server.request.duration`
+// and `http.server.active_requests` metrics. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to extract the trace context. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithNext skips tracing the requests for which next returns true, like the Next field of
+// Fiber's middleware configs.
+func WithNext(next func(fiber.Ctx) bool) Option {
+	return func(c *config) {
+		c.next = append(c.next, next)
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagators    propagation.TextMapPropagator
+	next           []func(fiber.Ctx) bool
+}
+
+// Middleware returns a Fiber middleware creating a server span for each request, and recording
+// the `http.server.request.duration` and `http.server.active_requests` metrics.
+//
+// Errors returned by handlers are recorded as exceptions and passed to the app's error handler
+// by the middleware, so that the span has the status code of the error response.
+// Panics are recorded before being propagated to the recover middleware, which should be registered first.
+func Middleware(opts ...Option) fiber.Handler {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func(c fiber.Ctx) error {
+		for _, next := range cfg.next {
+			if next(c) {
+				return c.Next()
+			}
+		}
+
+		parent := c.Context()
+		ctx := instrumentation.Propagator(cfg.propagators).Extract(parent, headerCarrier{&c.Request().Header})
+		method := strings.Clone(c.Method())
+		ctx, span := instrumentation.Tracer(cfg.tracerProvider, instrumentationName).Start(ctx,
+			httpconv.SpanName(method, ""),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(requestAttributes(c, method)...),
+		)
+		defer span.End(trace.WithStackTrace(true))
+		metrics := httpconv.StartServerMetrics(ctx, instrumentation.Meter(cfg.meterProvider, instrumentationName),
+			method, strings.Clone(c.Scheme()))
+
+		c.SetContext(ctx)
+		defer func() {
+			c.SetContext(parent)
+			if p := recover(); p != nil {
+				span.SetAttributes(semconv.HTTPResponseStatusCode(http.StatusInternalServerError))
+				span.SetStatus(codes.Error, fmt.Sprint(p))
+				metrics.End(ctx, matchedRoute(c), http.StatusInternalServerError)
+				panic(p)
+			}
+		}()
+
+		var handlerErr error
+		err := c.Next()
+		if err != nil {
+			span.RecordError(err)
+			// The error handler writes the response, so that its status is known.
+			handlerErr = c.App().Config().ErrorHandler(c, err)
+		}
+
+		route := matchedRoute(c)
+		if route != "" {
+			span.SetName(httpconv.SpanName(method, route))
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		status := c.Response().StatusCode()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		metrics.End(ctx, route, status)
+		if status >= http.StatusInternalServerError {
+			description := ""
+			if err != nil {
+				description = err.Error()
+			}
+			span.SetStatus(codes.Error, description)
+		}
+		return handlerErr
+	}
+}
+
+// matchedRoute returns the route of the request, which is only known once the router has matched a handler.
+func matchedRoute(c fiber.Ctx) string {
+	if !c.Matched() {
+		return ""
+	}
+	return strings.Clone(c.FullPath())
+}
+
+// requestAttributes returns the attributes of a server span known when the request starts.
+// Fiber's strings are only valid during the request, so they're copied.
+func requestAttributes(c fiber.Ctx, method string) []attribute.KeyValue {
+	path := strings.Clone(c.Path())
+	query := string(c.Request().URI().QueryString())
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(httpconv.Message(method, path, query)),
+		semconv.HTTPRequestMethodKey.String(method),
+		semconv.URLPath(path),
+		semconv.URLScheme(strings.Clone(c.Scheme())),
+		semconv.NetworkProtocolVersion(strings.TrimPrefix(strings.Clone(c.Protocol()), "HTTP/")),
+	}
+	if query != "" {
+		attrs = append(attrs, semconv.URLQuery(query))
+	}
+	if host, port := httpconv.SplitHostPort(string(c.Request().Host())); host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if port > 0 {
+			attrs = append(attrs, semconv.ServerPort(port))
+		}
+	}
+	if ip := strings.Clone(c.IP()); ip != "" {
+		attrs = append(attrs, semconv.ClientAddress(ip))
+	}
+	if ua := strings.Clone(c.UserAgent()); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
+	}
+	return attrs
+}
+
+// headerCarrier adapts fasthttp request headers for propagators.
+type headerCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c headerCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	var keys []string
+	for key := range c.header.All() {
+		keys = append(keys, string(key))
+	}
+	return keys
+}
@@ -0,0 +1,169 @@
+package logfirefiber
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/recover"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestApp(t *testing.T, opts ...Option) (*fiber.App, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	app := fiber.New()
+	app.Use(recover.New(), Middleware(append([]Option{WithTracerProvider(provider)}, opts...)...))
+	return app, recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	app, recorder := newTestApp(t, WithPropagators(propagation.TraceContext{}))
+	app.Get("/users/:id", func(c fiber.Ctx) error {
+		if !trace.SpanContextFromContext(c.Context()).IsValid() {
+			t.Error("expected the context to contain the span")
+		}
+		return c.SendStatus(http.StatusAccepted)
+	})
+
+	r := httptest.NewRequest("GET", "/users/42?page=2", nil)
+	r.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if _, err := app.Test(r); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /users/:id" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("expected the trace context to be extracted, got trace ID %s", got)
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		logfire.MsgKey:              "GET /users/42 ? page='2'",
+		"http.route":                "/users/:id",
+		"http.response.status_code": int64(http.StatusAccepted),
+		"url.path":                  "/users/42",
+		"server.address":            "example.com",
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	app, recorder := newTestApp(t)
+	app.Get("/missing", func(c fiber.Ctx) error {
+		return fiber.ErrNotFound
+	})
+	app.Get("/fail", func(c fiber.Ctx) error {
+		return errors.New("database unavailable")
+	})
+
+	for path, want := range map[string]int{"/missing": http.StatusNotFound, "/fail": http.StatusInternalServerError} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Errorf("%s: expected the error handler to respond with %d, got %d", path, want, resp.StatusCode)
+		}
+	}
+
+	for _, span := range recorder.Ended() {
+		attrs := oteltest.Attributes(span.Attributes())
+		wantCode := codes.Unset
+		if attrs["http.route"] == "/fail" {
+			wantCode = codes.Error
+		}
+		if span.Status().Code != wantCode {
+			t.Errorf("%s: status = %v, want %v", span.Name(), span.Status().Code, wantCode)
+		}
+		if len(span.Events()) != 1 {
+			t.Errorf("%s: expected the error to be recorded, got %v", span.Name(), span.Events())
+		}
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	app, recorder := newTestApp(t)
+	app.Get("/panic", func(c fiber.Ctx) error {
+		panic("boom")
+	})
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/panic", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || len(span.Events()) != 1 {
+		t.Errorf("expected the panic to be recorded, got status %v and events %v", span.Status(), span.Events())
+	}
+}
+
+func TestMiddlewareNext(t *testing.T) {
+	app, recorder := newTestApp(t, WithNext(func(c fiber.Ctx) bool { return c.Path() == "/healthz" }))
+	app.Get("/healthz", func(c fiber.Ctx) error { return c.SendStatus(http.StatusOK) })
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/healthz", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	app, _ := newTestApp(t, WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	app.Get("/users/:id", func(c fiber.Ctx) error {
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/users/42", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+	duration := metrics["http.server.request.duration"].(metricdata.Histogram[float64]).DataPoints[0]
+	want := attribute.NewSet(
+		attribute.String("http.request.method", "GET"),
+		attribute.String("url.scheme", "http"),
+		attribute.String("http.route", "/users/:id"),
+		attribute.Int("http.response.status_code", http.StatusCreated),
+	)
+	if duration.Count != 1 || !duration.Attributes.Equals(&want) {
+		t.Errorf("unexpected duration %d with attributes %v", duration.Count, duration.Attributes.ToSlice())
+	}
+	if active := metrics["http.server.active_requests"].(metricdata.Sum[int64]).DataPoints[0]; active.Value != 0 {
+		t.Errorf("expected no active requests, got %d", active.Value)
+	}
+}
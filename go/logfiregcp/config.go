@@ -0,0 +1,62 @@
+// Package logfiregcp instruments the Google Cloud client libraries, cloud.google.com/go, for Pydantic Logfire.
+//
+// The client libraries create spans for their operations with the global tracer provider, which
+// [logfire.Configure] sets, so that they're exported to Logfire with the resource of the service.
+// Their transports are instrumented with the OpenTelemetry contrib packages though, whose spans don't
+// follow the Logfire conventions. [GRPCClientOptions] replaces them with the Logfire gRPC interceptors,
+// for the clients using gRPC such as Pub/Sub, Firestore and Spanner:
+//
+//	client, err := pubsub.NewClientWithConfig(ctx, projectID,
+//		&pubsub.ClientConfig{EnableOpenTelemetryTracing: true},
+//		logfiregcp.GRPCClientOptions()...,
+//	)
+//
+// [NewHTTPClient] does the same for the clients using HTTP, such as Storage:
+//
+//	httpClient, err := logfiregcp.NewHTTPClient(ctx, []option.ClientOption{option.WithScopes(storage.ScopeReadWrite)})
+//	client, err := storage.NewClient(ctx, option.WithHTTPClient(httpClient))
+//
+// [logfire.Configure]: https://pkg.go.dev/github.com/pydantic/logfire/go/logfire#Configure
+package logfiregcp
+
+import (
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/logfiregrpc"
+	"github.com/pydantic/logfire/go/logfirehttp"
+)
+
+// Option configures [GRPCClientOptions] and [NewHTTPClient].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.grpc = append(c.grpc, logfiregrpc.WithTracerProvider(provider))
+		c.http = append(c.http, logfirehttp.WithTracerProvider(provider))
+	}
+}
+
+// WithPropagators sets the propagators used to inject the trace context in requests.
+// Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.grpc = append(c.grpc, logfiregrpc.WithPropagators(propagators))
+		c.http = append(c.http, logfirehttp.WithPropagators(propagators))
+	}
+}
+
+// config holds the options of the gRPC and HTTP integrations used by the clients.
+type config struct {
+	grpc []logfiregrpc.Option
+	http []logfirehttp.Option
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
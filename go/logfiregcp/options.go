@@ -0,0 +1,44 @@
+package logfiregcp
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+	"google.golang.org/grpc"
+
+	"github.com/pydantic/logfire/go/logfiregrpc"
+	"github.com/pydantic/logfire/go/logfirehttp"
+)
+
+// GRPCClientOptions returns the options of a Google Cloud client using gRPC creating a span for each RPC,
+// instead of the spans of the default OpenTelemetry instrumentation of the transport.
+//
+// Don't pass them to clients using HTTP, as disabling the default instrumentation would leave their requests
+// uninstrumented: use [NewHTTPClient] instead.
+func GRPCClientOptions(opts ...Option) []option.ClientOption {
+	c := newConfig(opts)
+	return []option.ClientOption{
+		option.WithTelemetryDisabled(),
+		option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(logfiregrpc.UnaryClientInterceptor(c.grpc...))),
+		option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(logfiregrpc.StreamClientInterceptor(c.grpc...))),
+	}
+}
+
+// NewHTTPClient returns an authenticated HTTP client for a Google Cloud client using HTTP, creating a span
+// for each request instead of the spans of the default OpenTelemetry instrumentation of the transport.
+// Pass it to the client with [option.WithHTTPClient].
+//
+// clientOpts configure the authentication of the HTTP client, such as its credentials and scopes,
+// as the client ignores its own when given an HTTP client.
+func NewHTTPClient(ctx context.Context, clientOpts []option.ClientOption, opts ...Option) (*http.Client, error) {
+	c := newConfig(opts)
+	clientOpts = append(clientOpts[:len(clientOpts):len(clientOpts)], option.WithTelemetryDisabled())
+	client, _, err := htransport.NewClient(ctx, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	client.Transport = logfirehttp.NewTransport(client.Transport, c.http...)
+	return client, nil
+}
@@ -0,0 +1,90 @@
+package logfiregcp
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/option"
+	grpctransport "google.golang.org/api/transport/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func newTestOptions(t *testing.T) ([]Option, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	return []Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}, recorder
+}
+
+func TestGRPCClientOptions(t *testing.T) {
+	opts, recorder := newTestOptions(t)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpctransport.Dial(t.Context(), append(GRPCClientOptions(opts...),
+		option.WithEndpoint(lis.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := healthpb.NewHealthClient(conn).Check(t.Context(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != "grpc.health.v1.Health/Check" || spans[0].SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span %q of kind %v", spans[0].Name(), spans[0].SpanKind())
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	opts, recorder := newTestOptions(t)
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClient(t.Context(), []option.ClientOption{option.WithoutAuthentication()}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL + "/storage/v1/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != "GET" || spans[0].SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span %q of kind %v", spans[0].Name(), spans[0].SpanKind())
+	}
+	if traceparent == "" {
+		t.Error("expected the trace context to be injected")
+	}
+}
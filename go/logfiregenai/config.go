@@ -0,0 +1,111 @@
+// Package logfiregenai instruments the Google Gen AI Go SDK, for Gemini and Vertex AI, for Pydantic Logfire.
+//
+// [NewTransport] creates a span for each call generating content or embeddings, named after the
+// operation and model like `generate_content gemini-2.5-flash`, with the gen_ai.* semantic convention
+// attributes: the parameters and safety settings of the request, the finish reasons and safety ratings
+// of the candidates, and the token usage. Streamed responses are recorded as they're read. Pass it
+// as the transport of the HTTP client of the SDK:
+//
+//	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+//		HTTPClient: &http.Client{Transport: logfiregenai.NewTransport(nil)},
+//	})
+//
+// The contents of requests and candidates are only recorded with [WithMessageContent].
+package logfiregenai
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiregenai"
+
+// Option configures [NewTransport].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithMessageContent records the messages of requests and responses, and the definitions of tools,
+// as the `gen_ai.input.messages`, `gen_ai.output.messages` and `gen_ai.tool.definitions` attributes.
+// They still go through the scrubbing of Logfire.
+func WithMessageContent() Option {
+	return func(c *config) {
+		c.content = true
+	}
+}
+
+// WithStreamProgress records the progress of streamed responses on their spans: the time to the first
+// chunk of the output as the `gen_ai.response.time_to_first_chunk` attribute, the number of chunks as
+// `gen_ai.response.chunk_count`, and a `gen_ai.stream.progress` event at most every interval with the
+// number of chunks so far and, with [WithMessageContent], the partial output. There are no events if
+// interval is zero.
+func WithStreamProgress(interval time.Duration) Option {
+	return func(c *config) {
+		c.progress = &genaiconv.Progress{Interval: interval}
+	}
+}
+
+// WithMeterProvider sets the meter provider used to record the `gen_ai.client.token.usage`,
+// `gen_ai.client.operation.duration` and `operation.cost` metrics. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// Price is the price of a model in USD per million tokens. The prices of cached tokens default to
+// the price of input tokens when they're zero.
+type Price = genaiconv.Price
+
+// WithPricing sets the prices of models by name, to record the cost of calls as the `operation.cost`
+// attribute and metric. The price of a model is the one of its longest prefix, so that `gemini-2.5-flash`
+// prices `gemini-2.5-flash-lite`.
+func WithPricing(prices map[string]Price) Option {
+	return func(c *config) {
+		c.pricing = prices
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	content        bool
+	progress       *genaiconv.Progress
+	meterProvider  metric.MeterProvider
+	pricing        map[string]Price
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) metrics() *genaiconv.Metrics {
+	return &genaiconv.Metrics{Meter: instrumentation.Meter(c.meterProvider, instrumentationName), Pricing: c.pricing}
+}
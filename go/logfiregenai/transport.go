@@ -0,0 +1,350 @@
+package logfiregenai
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+)
+
+const (
+	// safetySettingsKey records the safety settings of a request as JSON.
+	safetySettingsKey = attribute.Key("gcp.gen_ai.request.safety_settings")
+	// safetyRatingsKey records the safety ratings of the candidates of a response as JSON, one array per candidate.
+	safetyRatingsKey = attribute.Key("gcp.gen_ai.response.safety_ratings")
+	// blockReasonKey records why the prompt of a request was blocked.
+	blockReasonKey = attribute.Key("gcp.gen_ai.response.prompt_block_reason")
+)
+
+// NewTransport wraps base so that a span is created for each call generating content or embeddings.
+// Other calls aren't traced. If base is nil, [http.DefaultTransport] is used; for Vertex AI, wrap
+// the transport of an authenticated client.
+func NewTransport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, config: newConfig(opts)}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	config *config
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	model, method := modelMethod(r.URL.Path)
+	if r.Method != http.MethodPost || model == "" {
+		return t.base.RoundTrip(r)
+	}
+	var call genaiconv.Call
+	var parse func([]byte) genaiconv.Response
+	var stream genaiconv.Stream
+	switch method {
+	case "generateContent", "streamGenerateContent":
+		call.Operation, parse = genaiconv.OperationGenerateContent, generateResponse
+		stream = &generateStream{}
+	case "embedContent", "batchEmbedContents":
+		call.Operation, parse = genaiconv.OperationEmbeddings, func([]byte) genaiconv.Response { return genaiconv.Response{} }
+	default:
+		return t.base.RoundTrip(r)
+	}
+
+	// RoundTrippers must not modify the request, but may replace its body once read.
+	r = r.Clone(r.Context())
+	body, err := genaiconv.ReadRequestBody(r)
+	if err != nil {
+		return t.base.RoundTrip(r)
+	}
+	call.Model = model
+	call.Stream = method == "streamGenerateContent"
+	if call.Operation == genaiconv.OperationGenerateContent {
+		generateCall(&call, body, t.config.content)
+	}
+	provider := "gcp.gemini"
+	if strings.HasSuffix(r.URL.Hostname(), "aiplatform.googleapis.com") {
+		provider = "gcp.vertex_ai"
+	}
+	client := &genaiconv.Client{
+		Tracer:     t.config.tracer(),
+		Provider:   provider,
+		Content:    t.config.content,
+		Attributes: t.config.attrs,
+		Progress:   t.config.progress,
+		Metrics:    t.config.metrics(),
+	}
+	return client.Do(r, call, t.base.RoundTrip, parse, stream)
+}
+
+// modelMethod returns the model and method of paths like `/v1beta/models/gemini-2.5-flash:generateContent`
+// or `/v1/projects/p/locations/l/publishers/google/models/gemini-2.5-flash:streamGenerateContent`.
+func modelMethod(path string) (string, string) {
+	i := strings.LastIndex(path, "/models/")
+	if i < 0 {
+		return "", ""
+	}
+	model, method, ok := strings.Cut(path[i+len("/models/"):], ":")
+	if !ok {
+		return "", ""
+	}
+	return model, method
+}
+
+type part struct {
+	Text       string `json:"text"`
+	Thought    bool   `json:"thought"`
+	InlineData *struct {
+		MIMEType string `json:"mimeType"`
+	} `json:"inlineData"`
+	FileData *struct {
+		MIMEType string `json:"mimeType"`
+		FileURI  string `json:"fileUri"`
+	} `json:"fileData"`
+	FunctionCall *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Args any    `json:"args"`
+	} `json:"functionCall"`
+	FunctionResponse *struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Response any    `json:"response"`
+	} `json:"functionResponse"`
+	ExecutableCode *struct {
+		Code string `json:"code"`
+	} `json:"executableCode"`
+	CodeExecutionResult *struct {
+		Output string `json:"output"`
+	} `json:"codeExecutionResult"`
+}
+
+func (p part) part() genaiconv.Part {
+	switch {
+	case p.FunctionCall != nil:
+		return genaiconv.ToolCallPart(p.FunctionCall.ID, p.FunctionCall.Name, p.FunctionCall.Args)
+	case p.FunctionResponse != nil:
+		part := genaiconv.ToolCallResponsePart(p.FunctionResponse.ID, p.FunctionResponse.Response)
+		part.Name = p.FunctionResponse.Name
+		return part
+	case p.InlineData != nil:
+		return genaiconv.Part{Type: "blob", MIMEType: p.InlineData.MIMEType}
+	case p.FileData != nil:
+		return genaiconv.Part{Type: "uri", MIMEType: p.FileData.MIMEType, URI: p.FileData.FileURI}
+	case p.ExecutableCode != nil:
+		return genaiconv.Part{Type: "executable_code", Content: p.ExecutableCode.Code}
+	case p.CodeExecutionResult != nil:
+		return genaiconv.Part{Type: "code_execution_result", Content: p.CodeExecutionResult.Output}
+	case p.Thought:
+		return genaiconv.Part{Type: "reasoning", Content: p.Text}
+	}
+	return genaiconv.TextPart(p.Text)
+}
+
+type content struct {
+	Role  string `json:"role"`
+	Parts []part `json:"parts"`
+}
+
+func (c content) message(defaultRole string) genaiconv.Message {
+	role := c.Role
+	switch role {
+	case "":
+		role = defaultRole
+	case "model":
+		role = "assistant"
+	}
+	msg := genaiconv.Message{Role: role, Parts: []genaiconv.Part{}}
+	for _, p := range c.Parts {
+		msg.Parts = append(msg.Parts, p.part())
+	}
+	return msg
+}
+
+func generateCall(call *genaiconv.Call, body []byte, recordContent bool) {
+	var req struct {
+		Contents          []content `json:"contents"`
+		SystemInstruction *content  `json:"systemInstruction"`
+		GenerationConfig  struct {
+			Temperature      *float64 `json:"temperature"`
+			TopP             *float64 `json:"topP"`
+			TopK             *float64 `json:"topK"`
+			CandidateCount   *int64   `json:"candidateCount"`
+			MaxOutputTokens  *int64   `json:"maxOutputTokens"`
+			StopSequences    []string `json:"stopSequences"`
+			Seed             *int64   `json:"seed"`
+			PresencePenalty  *float64 `json:"presencePenalty"`
+			FrequencyPenalty *float64 `json:"frequencyPenalty"`
+		} `json:"generationConfig"`
+		SafetySettings []any `json:"safetySettings"`
+		Tools          []any `json:"tools"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return
+	}
+	gc := req.GenerationConfig
+	params := genaiconv.Params{
+		MaxTokens:        gc.MaxOutputTokens,
+		Temperature:      gc.Temperature,
+		TopP:             gc.TopP,
+		TopK:             gc.TopK,
+		FrequencyPenalty: gc.FrequencyPenalty,
+		PresencePenalty:  gc.PresencePenalty,
+		Seed:             gc.Seed,
+		ChoiceCount:      gc.CandidateCount,
+		StopSequences:    gc.StopSequences,
+	}
+	call.Attributes = append(params.Attributes(), genaiconv.RequestStreamKey.Bool(call.Stream))
+	if len(req.SafetySettings) > 0 {
+		call.Attributes = append(call.Attributes, genaiconv.JSON(safetySettingsKey, req.SafetySettings))
+	}
+	if !recordContent {
+		return
+	}
+	if req.SystemInstruction != nil {
+		call.System = req.SystemInstruction.message("system").Parts
+	}
+	for _, c := range req.Contents {
+		call.Messages = append(call.Messages, c.message("user"))
+	}
+	if len(req.Tools) > 0 {
+		call.Attributes = append(call.Attributes, genaiconv.JSON(genaiconv.ToolDefinitionsKey, req.Tools))
+	}
+}
+
+type candidate struct {
+	Index         int     `json:"index"`
+	Content       content `json:"content"`
+	FinishReason  string  `json:"finishReason"`
+	SafetyRatings []any   `json:"safetyRatings"`
+}
+
+type generateContentResponse struct {
+	ResponseID     string      `json:"responseId"`
+	ModelVersion   string      `json:"modelVersion"`
+	Candidates     []candidate `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata *struct {
+		PromptTokenCount        int64 `json:"promptTokenCount"`
+		CandidatesTokenCount    int64 `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int64 `json:"thoughtsTokenCount"`
+		CachedContentTokenCount int64 `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// response returns the response, and the attributes specific to Gemini.
+func (g generateContentResponse) response() (genaiconv.Response, []attribute.KeyValue) {
+	resp := genaiconv.Response{ID: g.ResponseID, Model: g.ModelVersion}
+	if u := g.UsageMetadata; u != nil {
+		// Thinking tokens are billed as output tokens.
+		resp.Usage = &genaiconv.Usage{
+			InputTokens:          u.PromptTokenCount,
+			OutputTokens:         u.CandidatesTokenCount + u.ThoughtsTokenCount,
+			CacheReadInputTokens: u.CachedContentTokenCount,
+		}
+	}
+	var ratings [][]any
+	for _, c := range g.Candidates {
+		msg := c.Content.message("assistant")
+		msg.FinishReason = c.FinishReason
+		resp.Output = append(resp.Output, msg)
+		if c.FinishReason != "" {
+			resp.FinishReasons = append(resp.FinishReasons, c.FinishReason)
+		}
+		if len(c.SafetyRatings) > 0 {
+			ratings = append(ratings, c.SafetyRatings)
+		}
+	}
+	var attrs []attribute.KeyValue
+	if len(ratings) > 0 {
+		attrs = append(attrs, genaiconv.JSON(safetyRatingsKey, ratings))
+	}
+	if g.PromptFeedback != nil && g.PromptFeedback.BlockReason != "" {
+		attrs = append(attrs, blockReasonKey.String(g.PromptFeedback.BlockReason))
+	}
+	return resp, attrs
+}
+
+func generateResponse(body []byte) genaiconv.Response {
+	var g generateContentResponse
+	if json.Unmarshal(body, &g) != nil {
+		return genaiconv.Response{}
+	}
+	resp, attrs := g.response()
+	resp.Attributes = attrs
+	return resp
+}
+
+// generateStream merges the chunks of a streamed response, whose candidates have parts of text
+// to concatenate, and whose last chunk has the finish reasons and usage.
+type generateStream struct {
+	genaiconv.ChunkCounter
+	merged generateContentResponse
+}
+
+func (s *generateStream) Line(line []byte) (bool, error) {
+	data, ok := genaiconv.SSEData(line)
+	if !ok {
+		return false, nil
+	}
+	var chunk generateContentResponse
+	if json.Unmarshal(data, &chunk) != nil {
+		return false, nil
+	}
+	m := &s.merged
+	if chunk.ResponseID != "" {
+		m.ResponseID = chunk.ResponseID
+	}
+	if chunk.ModelVersion != "" {
+		m.ModelVersion = chunk.ModelVersion
+	}
+	if chunk.PromptFeedback != nil {
+		m.PromptFeedback = chunk.PromptFeedback
+	}
+	if chunk.UsageMetadata != nil {
+		m.UsageMetadata = chunk.UsageMetadata
+	}
+	output := false
+	for _, c := range chunk.Candidates {
+		output = output || len(c.Content.Parts) > 0
+		for len(m.Candidates) <= c.Index {
+			m.Candidates = append(m.Candidates, candidate{Index: len(m.Candidates)})
+		}
+		merged := &m.Candidates[c.Index]
+		if c.Content.Role != "" {
+			merged.Content.Role = c.Content.Role
+		}
+		for _, p := range c.Content.Parts {
+			last := len(merged.Content.Parts) - 1
+			if isText(p) && last >= 0 && isText(merged.Content.Parts[last]) && merged.Content.Parts[last].Thought == p.Thought {
+				merged.Content.Parts[last].Text += p.Text
+				continue
+			}
+			merged.Content.Parts = append(merged.Content.Parts, p)
+		}
+		if c.FinishReason != "" {
+			merged.FinishReason = c.FinishReason
+		}
+		if len(c.SafetyRatings) > 0 {
+			merged.SafetyRatings = c.SafetyRatings
+		}
+	}
+	if output {
+		s.Add()
+	}
+	return false, nil
+}
+
+func isText(p part) bool {
+	return p.FunctionCall == nil && p.FunctionResponse == nil && p.InlineData == nil && p.FileData == nil &&
+		p.ExecutableCode == nil && p.CodeExecutionResult == nil
+}
+
+func (s *generateStream) Response() genaiconv.Response {
+	resp, attrs := s.merged.response()
+	resp.Attributes = attrs
+	return resp
+}
@@ -0,0 +1,222 @@
+package logfiregenai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/genai"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func checkJSON(t *testing.T, attrs []attribute.KeyValue, key attribute.Key, want string) {
+	t.Helper()
+	got, _ := oteltest.Attributes(attrs)[key].(string)
+	var g, w any
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Errorf("%s = %q, not JSON", key, got)
+		return
+	}
+	json.Unmarshal([]byte(want), &w)
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("%s = %s, want %s", key, gb, wb)
+	}
+}
+
+const responseJSON = `{
+	"responseId": "resp_1",
+	"modelVersion": "gemini-2.5-flash-001",
+	"candidates": [{
+		"index": 0,
+		"content": {"role": "model", "parts": [
+			{"text": "Let me check."},
+			{"functionCall": {"name": "weather", "args": {"city": "Paris"}}}
+		]},
+		"finishReason": "STOP",
+		"safetyRatings": [{"category": "HARM_CATEGORY_HARASSMENT", "probability": "NEGLIGIBLE"}]
+	}],
+	"usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 20, "thoughtsTokenCount": 5, "cachedContentTokenCount": 4}
+}`
+
+const streamSSE = `data: {"responseId":"resp_2","modelVersion":"gemini-2.5-flash-001","candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}]}
+
+data: {"responseId":"resp_2","modelVersion":"gemini-2.5-flash-001","candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2}}
+
+`
+
+func newClient(t *testing.T, handler http.HandlerFunc, opts ...Option) (*genai.Client, *tracetest.SpanRecorder) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+		HTTPClient:  &http.Client{Transport: NewTransport(nil, append(opts, WithTracerProvider(provider))...)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, recorder
+}
+
+func TestGenerateContent(t *testing.T) {
+	var requestBody map[string]any
+	client, recorder := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/models/gemini-2.5-flash:generateContent") {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, responseJSON)
+	}, WithMessageContent())
+
+	temperature := float32(0.5)
+	resp, err := client.Models.GenerateContent(context.Background(), "gemini-2.5-flash", genai.Text("Weather in Paris?"), &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText("Be brief.", genai.RoleUser),
+		Temperature:       &temperature,
+		MaxOutputTokens:   100,
+		SafetySettings: []*genai.SafetySetting{{
+			Category:  genai.HarmCategoryHarassment,
+			Threshold: genai.HarmBlockThresholdBlockOnlyHigh,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "Let me check." {
+		t.Errorf("text = %q", resp.Text())
+	}
+	if requestBody["contents"] == nil {
+		t.Errorf("request body = %v, want it sent intact", requestBody)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "generate_content gemini-2.5-flash" {
+		t.Errorf("name = %s", span.Name())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"gen_ai.provider.name":                 "gcp.gemini",
+		"gen_ai.operation.name":                "generate_content",
+		"gen_ai.request.model":                 "gemini-2.5-flash",
+		"gen_ai.request.temperature":           0.5,
+		"gen_ai.request.max_tokens":            int64(100),
+		"gen_ai.request.stream":                false,
+		"gen_ai.response.id":                   "resp_1",
+		"gen_ai.response.model":                "gemini-2.5-flash-001",
+		"gen_ai.usage.input_tokens":            int64(10),
+		"gen_ai.usage.output_tokens":           int64(25),
+		"gen_ai.usage.cache_read.input_tokens": int64(4),
+	})
+	if got := oteltest.Attributes(span.Attributes())["gen_ai.response.finish_reasons"]; len(got.([]string)) != 1 || got.([]string)[0] != "STOP" {
+		t.Errorf("finish reasons = %v", got)
+	}
+	checkJSON(t, span.Attributes(), safetySettingsKey, `[{"category":"HARM_CATEGORY_HARASSMENT","threshold":"BLOCK_ONLY_HIGH"}]`)
+	checkJSON(t, span.Attributes(), safetyRatingsKey, `[[{"category":"HARM_CATEGORY_HARASSMENT","probability":"NEGLIGIBLE"}]]`)
+	checkJSON(t, span.Attributes(), "gen_ai.system_instructions", `[{"type":"text","content":"Be brief."}]`)
+	checkJSON(t, span.Attributes(), "gen_ai.input.messages", `[{"role":"user","parts":[{"type":"text","content":"Weather in Paris?"}]}]`)
+	checkJSON(t, span.Attributes(), "gen_ai.output.messages", `[{"role":"assistant","parts":[
+		{"type":"text","content":"Let me check."},
+		{"type":"tool_call","name":"weather","arguments":{"city":"Paris"}}
+	],"finish_reason":"STOP"}]`)
+}
+
+func TestGenerateContentStream(t *testing.T) {
+	client, recorder := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("alt") != "sse" || !strings.HasSuffix(r.URL.Path, ":streamGenerateContent") {
+			t.Errorf("url = %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, streamSSE)
+	}, WithMessageContent(), WithStreamProgress(0))
+
+	var text strings.Builder
+	for resp, err := range client.Models.GenerateContentStream(context.Background(), "gemini-2.5-flash", genai.Text("Hi"), nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		text.WriteString(resp.Text())
+	}
+	if text.String() != "Hello" {
+		t.Errorf("text = %q", text.String())
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		"gen_ai.request.stream":       true,
+		"gen_ai.response.id":          "resp_2",
+		"gen_ai.usage.input_tokens":   int64(3),
+		"gen_ai.usage.output_tokens":  int64(2),
+		"gen_ai.response.chunk_count": int64(2),
+	})
+	checkJSON(t, spans[0].Attributes(), "gen_ai.output.messages",
+		`[{"role":"assistant","parts":[{"type":"text","content":"Hello"}],"finish_reason":"STOP"}]`)
+}
+
+func TestGenerateContentError(t *testing.T) {
+	client, recorder := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"error":{"code":429,"message":"Resource exhausted","status":"RESOURCE_EXHAUSTED"}}`)
+	})
+	if _, err := client.Models.GenerateContent(context.Background(), "gemini-2.5-flash", genai.Text("Hi"), nil); err == nil {
+		t.Fatal("no error")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error || !strings.HasSuffix(spans[0].Status().Description, "Resource exhausted") {
+		t.Errorf("status = %v", spans[0].Status())
+	}
+	if _, ok := oteltest.Attributes(spans[0].Attributes())["gen_ai.input.messages"]; ok {
+		t.Error("messages recorded without WithMessageContent")
+	}
+}
+
+func TestEmbedContent(t *testing.T) {
+	client, recorder := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"embeddings":[{"values":[0.1,0.2]}]}`)
+	})
+	if _, err := client.Models.EmbedContent(context.Background(), "text-embedding-004", genai.Text("Hi"), nil); err != nil {
+		t.Fatal(err)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "embeddings text-embedding-004" {
+		t.Fatalf("spans = %v", spans)
+	}
+}
+
+func TestModelMethod(t *testing.T) {
+	for path, want := range map[string][2]string{
+		"/v1beta/models/gemini-2.5-flash:generateContent":                                          {"gemini-2.5-flash", "generateContent"},
+		"/v1/projects/p/locations/l/publishers/google/models/gemini-2.5-pro:streamGenerateContent": {"gemini-2.5-pro", "streamGenerateContent"},
+		"/v1beta/models/gemini-2.5-flash":                                                          {"", ""},
+		"/v1beta/files":                                                                            {"", ""},
+	} {
+		if model, method := modelMethod(path); model != want[0] || method != want[1] {
+			t.Errorf("modelMethod(%s) = %s, %s", path, model, method)
+		}
+	}
+}
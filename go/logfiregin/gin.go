@@ -0,0 +1,133 @@
+// Package logfiregin instruments Gin applications for Pydantic Logfire.
+//
+// [Middleware] creates a server span for each request, named after the route template
+// like the spans of the Python SDK's web framework integrations:
+//
+//	r := gin.New()
+//	r.Use(logfiregin.Middleware())
+//	r.GET("/users/:id", getUser) // spans named `GET /users/:id`
+//
+// Handlers should pass `c.Request.Context()` to Logfire and other instrumented code,
+// so that their spans are children of the request span.
+package logfiregin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiregin"
+
+// handlerKey is the name of the function handling the request.
+const handlerKey = attribute.Key("gin.handler")
+
+// Option configures [Middleware].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider used to record the `http.server.request.duration`
+// and `http.server.active_requests` metrics. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to extract the trace context. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithFilter adds a filter for requests: a request is only traced if all filters return true,
+// e.g. to skip health checks.
+func WithFilter(filter func(*gin.Context) bool) Option {
+	return func(c *config) {
+		c.filters = append(c.filters, filter)
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagators    propagation.TextMapPropagator
+	filters        []func(*gin.Context) bool
+}
+
+// Middleware returns a Gin middleware creating a server span for each request, and recording
+// the `http.server.request.duration` and `http.server.active_requests` metrics.
+//
+// Errors added to the context with `c.Error` are recorded as exceptions, and panics are
+// recorded before being propagated to the recovery middleware, which should be registered first.
+func Middleware(opts ...Option) gin.HandlerFunc {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func(c *gin.Context) {
+		for _, filter := range cfg.filters {
+			if !filter(c) {
+				c.Next()
+				return
+			}
+		}
+
+		r := c.Request
+		ctx := instrumentation.Propagator(cfg.propagators).Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := c.FullPath()
+		ctx, span := instrumentation.Tracer(cfg.tracerProvider, instrumentationName).Start(ctx,
+			httpconv.SpanName(r.Method, route),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(httpconv.ServerRequestAttributes(r, route)...),
+			trace.WithAttributes(handlerKey.String(c.HandlerName())),
+		)
+		defer span.End(trace.WithStackTrace(true))
+		metrics := httpconv.StartServerMetrics(ctx, instrumentation.Meter(cfg.meterProvider, instrumentationName),
+			r.Method, httpconv.Scheme(r))
+
+		c.Request = r.WithContext(ctx)
+		defer func() {
+			c.Request = r
+			if p := recover(); p != nil {
+				span.SetAttributes(semconv.HTTPResponseStatusCode(http.StatusInternalServerError))
+				span.SetStatus(codes.Error, fmt.Sprint(p))
+				metrics.End(ctx, route, http.StatusInternalServerError)
+				panic(p)
+			}
+		}()
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		metrics.End(ctx, route, status)
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+		if status >= http.StatusInternalServerError {
+			description := ""
+			if err := c.Errors.Last(); err != nil {
+				description = err.Error()
+			}
+			span.SetStatus(codes.Error, description)
+		}
+	}
+}
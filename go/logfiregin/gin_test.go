@@ -0,0 +1,150 @@
+package logfiregin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newTestRouter(t *testing.T, opts ...Option) (*gin.Engine, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	r := gin.New()
+	r.Use(gin.Recovery(), Middleware(append([]Option{WithTracerProvider(provider)}, opts...)...))
+	return r, recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	r, recorder := newTestRouter(t)
+	r.GET("/users/:id", func(c *gin.Context) {
+		if !trace.SpanContextFromContext(c.Request.Context()).IsValid() {
+			t.Error("expected the request context to contain the span")
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42?page=2", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /users/:id" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		logfire.MsgKey:              "GET /users/42 ? page='2'",
+		"http.route":                "/users/:id",
+		"http.response.status_code": int64(http.StatusOK),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+	if attrs[handlerKey] == "" {
+		t.Error("expected the handler name to be recorded")
+	}
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	r, recorder := newTestRouter(t)
+	r.GET("/fail", func(c *gin.Context) {
+		_ = c.Error(errors.New("database unavailable"))
+		c.Status(http.StatusServiceUnavailable)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "database unavailable" {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	if len(span.Events()) != 1 || span.Events()[0].Name != "exception" {
+		t.Errorf("expected the error to be recorded, got %v", span.Events())
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	r, recorder := newTestRouter(t)
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected the recovery middleware to respond, got %d", w.Code)
+	}
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || len(span.Events()) != 1 {
+		t.Errorf("expected the panic to be recorded, got status %v and events %v", span.Status(), span.Events())
+	}
+	if got := oteltest.Attributes(span.Attributes())["http.response.status_code"]; got != int64(http.StatusInternalServerError) {
+		t.Errorf("status code = %v", got)
+	}
+}
+
+func TestMiddlewareFilter(t *testing.T) {
+	r, recorder := newTestRouter(t, WithFilter(func(c *gin.Context) bool { return c.FullPath() != "/healthz" }))
+	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	r, _ := newTestRouter(t, WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+	duration := metrics["http.server.request.duration"].(metricdata.Histogram[float64]).DataPoints[0]
+	want := attribute.NewSet(
+		attribute.String("http.request.method", "GET"),
+		attribute.String("url.scheme", "http"),
+		attribute.String("http.route", "/users/:id"),
+		attribute.Int("http.response.status_code", http.StatusCreated),
+	)
+	if duration.Count != 1 || !duration.Attributes.Equals(&want) {
+		t.Errorf("unexpected duration %d with attributes %v", duration.Count, duration.Attributes.ToSlice())
+	}
+	if active := metrics["http.server.active_requests"].(metricdata.Sum[int64]).DataPoints[0]; active.Value != 0 {
+		t.Errorf("expected no active requests, got %d", active.Value)
+	}
+}
@@ -0,0 +1,85 @@
+// Package logfiregocql instruments Cassandra sessions of gocql for Pydantic Logfire.
+//
+// [InstrumentCluster] sets the observers of a cluster, creating a span for each query and batch of
+// its sessions, with the db.* and cassandra.* semantic convention attributes, and for each connection
+// to a host. Queries are sanitized and summarized like SQL queries, e.g. `SELECT users`:
+//
+//	cluster := gocql.NewCluster("127.0.0.1")
+//	logfiregocql.InstrumentCluster(cluster)
+//	session, err := cluster.CreateSession()
+package logfiregocql
+
+import (
+	"strings"
+
+	"github.com/gocql/gocql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiregocql"
+
+// Option configures [NewObserver] and [InstrumentCluster].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithoutSanitization records statements as they are, instead of replacing their literals by `?`.
+func WithoutSanitization() Option {
+	return func(c *config) {
+		c.noSanitization = true
+	}
+}
+
+// WithoutConnectSpans disables the spans of connections to hosts.
+func WithoutConnectSpans() Option {
+	return func(c *config) {
+		c.noConnectSpans = true
+	}
+}
+
+// WithConsistency records the consistency of the session as the `cassandra.consistency.level` of queries
+// and batches. [InstrumentCluster] sets it to the consistency of the cluster. gocql doesn't report the
+// consistency of queries to observers, so queries overriding the consistency of the session are
+// recorded with the consistency of the session.
+func WithConsistency(consistency gocql.Consistency) Option {
+	return func(c *config) {
+		c.consistency = semconv.CassandraConsistencyLevelKey.String(strings.ToLower(consistency.String()))
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	noSanitization bool
+	noConnectSpans bool
+	consistency    attribute.KeyValue
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
@@ -0,0 +1,159 @@
+package logfiregocql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// attemptKey records the attempt of a query or batch, starting at 0, as each attempt is reported separately.
+const attemptKey = attribute.Key("cassandra.query.attempt")
+
+// Observer creates spans for the queries, batches and connections reported by gocql.
+// The spans are created once they're reported, with the start and end times of the operations.
+type Observer struct {
+	config *config
+}
+
+var (
+	_ gocql.QueryObserver   = (*Observer)(nil)
+	_ gocql.BatchObserver   = (*Observer)(nil)
+	_ gocql.ConnectObserver = (*Observer)(nil)
+)
+
+// NewObserver returns an observer to set as the QueryObserver, BatchObserver and ConnectObserver of a cluster.
+func NewObserver(opts ...Option) *Observer {
+	return &Observer{config: newConfig(opts)}
+}
+
+// InstrumentCluster sets the observers of a cluster, recording its consistency on queries.
+func InstrumentCluster(cluster *gocql.ClusterConfig, opts ...Option) {
+	observer := NewObserver(append([]Option{WithConsistency(cluster.Consistency)}, opts...)...)
+	cluster.QueryObserver = observer
+	cluster.BatchObserver = observer
+	cluster.ConnectObserver = observer
+}
+
+// ObserveQuery creates the span of a query, including each page of an iterator.
+func (o *Observer) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	query := sqlconv.Parse(q.Statement, !o.config.noSanitization)
+	attrs := append(query.Attributes(), semconv.DBResponseReturnedRows(q.Rows))
+	o.record(ctx, query.SpanName("cassandra"), q.Keyspace, q.Host, q.Attempt, attrs, q.Start, q.End, q.Err)
+}
+
+// ObserveBatch creates the span of a batch, named `BATCH <summary>` if all its statements have the same summary.
+func (o *Observer) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	summary := ""
+	texts := make([]string, len(b.Statements))
+	for i, statement := range b.Statements {
+		q := sqlconv.Parse(statement, !o.config.noSanitization)
+		texts[i] = q.Text
+		if i == 0 {
+			summary = q.Summary
+		} else if q.Summary != summary {
+			summary = ""
+		}
+	}
+	name, operation := "BATCH", "BATCH"
+	if summary != "" {
+		name += " " + summary
+		operation += " " + strings.SplitN(summary, " ", 2)[0]
+	}
+	noun := "statements"
+	if len(b.Statements) == 1 {
+		noun = "statement"
+	}
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(fmt.Sprintf("%s (%d %s)", name, len(b.Statements), noun)),
+		semconv.DBQueryText(strings.Join(texts, "; ")),
+		semconv.DBOperationName(operation),
+		semconv.DBOperationBatchSize(len(b.Statements)),
+	}
+	o.record(ctx, name, b.Keyspace, b.Host, b.Attempt, attrs, b.Start, b.End, b.Err)
+}
+
+func (o *Observer) record(ctx context.Context, name, keyspace string, host *gocql.HostInfo, attempt int, attrs []attribute.KeyValue, start, end time.Time, err error) {
+	attrs = append(attrs, semconv.DBSystemNameCassandra)
+	if keyspace != "" {
+		attrs = append(attrs, semconv.DBNamespace(keyspace))
+	}
+	if o.config.consistency.Valid() {
+		attrs = append(attrs, o.config.consistency)
+	}
+	if attempt > 0 {
+		attrs = append(attrs, attemptKey.Int(attempt))
+	}
+	attrs = append(attrs, hostAttributes(host)...)
+	_, span := o.config.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(o.config.attrs...),
+	)
+	recordError(span, err, end)
+	span.End(trace.WithTimestamp(end))
+}
+
+// ObserveConnect creates the span of a connection to a host, which is the root of its trace.
+func (o *Observer) ObserveConnect(c gocql.ObservedConnect) {
+	if o.config.noConnectSpans {
+		return
+	}
+	_, span := o.config.tracer().Start(context.Background(), "connect",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(c.Start),
+		trace.WithAttributes(semconv.DBSystemNameCassandra),
+		trace.WithAttributes(hostAttributes(c.Host)...),
+		trace.WithAttributes(o.config.attrs...),
+	)
+	recordError(span, c.Err, c.End)
+	span.End(trace.WithTimestamp(c.End))
+}
+
+func hostAttributes(host *gocql.HostInfo) []attribute.KeyValue {
+	if host == nil {
+		return nil
+	}
+	var attrs []attribute.KeyValue
+	// Unlike ConnectAddress, ConnectAddressAndPort doesn't panic for hosts without a valid address.
+	if addr, port := httpconv.SplitHostPort(host.ConnectAddressAndPort()); isValidIP(addr) {
+		attrs = append(attrs, semconv.ServerAddress(addr), semconv.ServerPort(port))
+	}
+	if dc := host.DataCenter(); dc != "" {
+		attrs = append(attrs, semconv.CassandraCoordinatorDC(dc))
+	}
+	if id := host.HostID(); id != "" {
+		attrs = append(attrs, semconv.CassandraCoordinatorID(id))
+	}
+	return attrs
+}
+
+func isValidIP(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && !ip.IsUnspecified()
+}
+
+func recordError(span trace.Span, err error, at time.Time) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err, trace.WithTimestamp(at))
+	span.SetStatus(codes.Error, err.Error())
+	var reqErr gocql.RequestError
+	if errors.As(err, &reqErr) {
+		span.SetAttributes(semconv.DBResponseStatusCode(fmt.Sprintf("0x%04x", reqErr.Code())))
+	}
+}
@@ -0,0 +1,158 @@
+package logfiregocql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestObserver(t *testing.T, opts ...Option) (*Observer, *sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	return NewObserver(append([]Option{WithTracerProvider(provider)}, opts...)...), provider, recorder
+}
+
+func testHost() *gocql.HostInfo {
+	host := (&gocql.HostInfo{}).SetConnectAddress(net.ParseIP("10.0.0.1"))
+	host.SetHostID("host-1")
+	return host
+}
+
+func TestObserveQuery(t *testing.T) {
+	observer, provider, recorder := newTestObserver(t, WithConsistency(gocql.LocalQuorum))
+
+	ctx, parent := provider.Tracer("test").Start(t.Context(), "parent")
+	start := time.Now().Add(-time.Second)
+	observer.ObserveQuery(ctx, gocql.ObservedQuery{
+		Keyspace:  "shop",
+		Statement: "SELECT id FROM users WHERE email = 'a@example.com'",
+		Start:     start,
+		End:       start.Add(10 * time.Millisecond),
+		Rows:      3,
+		Host:      testHost(),
+		Attempt:   1,
+	})
+	parent.End()
+
+	span := recorder.Ended()[0]
+	if span.Name() != "SELECT users" || span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("expected the span to be a child of the context of the query")
+	}
+	if !span.StartTime().Equal(start) || span.EndTime().Sub(span.StartTime()) != 10*time.Millisecond {
+		t.Errorf("unexpected times %v - %v", span.StartTime(), span.EndTime())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:                "SELECT id FROM users WHERE email = ?",
+		"db.system.name":              "cassandra",
+		"db.namespace":                "shop",
+		"db.query.text":               "SELECT id FROM users WHERE email = ?",
+		"db.response.returned_rows":   int64(3),
+		"cassandra.consistency.level": "local_quorum",
+		"cassandra.coordinator.id":    "host-1",
+		"cassandra.query.attempt":     int64(1),
+		"server.address":              "10.0.0.1",
+	})
+}
+
+func TestObserveQueryError(t *testing.T) {
+	observer, _, recorder := newTestObserver(t)
+
+	observer.ObserveQuery(t.Context(), gocql.ObservedQuery{
+		Statement: "SELECT * FROM missing",
+		Start:     time.Now(),
+		End:       time.Now(),
+		Err:       errors.New("unconfigured table missing"),
+	})
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "unconfigured table missing" {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for _, key := range []attribute.Key{"cassandra.consistency.level", "server.address", "cassandra.query.attempt"} {
+		if _, ok := attrs[key]; ok {
+			t.Errorf("unexpected attribute %s", key)
+		}
+	}
+}
+
+func TestObserveBatch(t *testing.T) {
+	observer, _, recorder := newTestObserver(t)
+
+	observer.ObserveBatch(context.Background(), gocql.ObservedBatch{
+		Keyspace:   "shop",
+		Statements: []string{"INSERT INTO users (id) VALUES (1)", "INSERT INTO users (id) VALUES (2)"},
+		Start:      time.Now(),
+		End:        time.Now(),
+	})
+	observer.ObserveBatch(context.Background(), gocql.ObservedBatch{
+		Statements: []string{"INSERT INTO users (id) VALUES (1)", "DELETE FROM orders WHERE id = 1"},
+		Start:      time.Now(),
+		End:        time.Now(),
+	})
+
+	spans := recorder.Ended()
+	if spans[0].Name() != "BATCH INSERT users" {
+		t.Errorf("unexpected span name %q", spans[0].Name())
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:            "BATCH INSERT users (2 statements)",
+		"db.query.text":           "INSERT INTO users (id) VALUES (?); INSERT INTO users (id) VALUES (?)",
+		"db.operation.name":       "BATCH INSERT",
+		"db.operation.batch.size": int64(2),
+	})
+	if spans[1].Name() != "BATCH" {
+		t.Errorf("unexpected span name %q", spans[1].Name())
+	}
+}
+
+func TestObserveConnect(t *testing.T) {
+	observer, _, recorder := newTestObserver(t)
+
+	observer.ObserveConnect(gocql.ObservedConnect{Host: testHost(), Start: time.Now(), End: time.Now(), Err: errors.New("connection refused")})
+
+	span := recorder.Ended()[0]
+	if span.Name() != "connect" || span.Status().Code != codes.Error {
+		t.Errorf("unexpected span %q with status %v", span.Name(), span.Status())
+	}
+	if span.Parent().IsValid() {
+		t.Error("expected the span to be a root span")
+	}
+
+	observer, _, recorder = newTestObserver(t, WithoutConnectSpans())
+	observer.ObserveConnect(gocql.ObservedConnect{Host: testHost()})
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
+
+func TestInstrumentCluster(t *testing.T) {
+	cluster := gocql.NewCluster("127.0.0.1")
+	cluster.Consistency = gocql.One
+	InstrumentCluster(cluster)
+
+	observer, ok := cluster.QueryObserver.(*Observer)
+	if !ok || cluster.BatchObserver != observer || cluster.ConnectObserver != observer {
+		t.Fatal("expected the observers of the cluster to be set")
+	}
+	if got := observer.config.consistency.Value.AsString(); got != "one" {
+		t.Errorf("consistency = %q, want one", got)
+	}
+}
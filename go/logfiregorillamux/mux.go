@@ -0,0 +1,147 @@
+// Package logfiregorillamux instruments gorilla/mux routers for Pydantic Logfire.
+//
+// [Middleware] creates a server span for each request, named after the route template
+// like the spans of the Python SDK's web framework integrations:
+//
+//	r := mux.NewRouter()
+//	r.Use(logfiregorillamux.Middleware())
+//	r.HandleFunc("/users/{id}", getUser) // spans named `GET /users/{id}`
+package logfiregorillamux
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiregorillamux"
+
+// varsPrefix is the prefix of the attributes recording the route variables, e.g. `mux.vars.id`.
+const varsPrefix = "mux.vars."
+
+// Option configures [Middleware].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider used to record the `http.server.request.duration`
+// and `http.server.active_requests` metrics. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to extract the trace context. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithFilter adds a filter for requests: a request is only traced if all filters return true.
+// [mux.CurrentRoute] can be used to filter by route.
+func WithFilter(filter func(*http.Request) bool) Option {
+	return func(c *config) {
+		c.filters = append(c.filters, filter)
+	}
+}
+
+// WithoutRouteVars disables recording the route variables as `mux.vars.<name>` attributes.
+func WithoutRouteVars() Option {
+	return func(c *config) {
+		c.noRouteVars = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagators    propagation.TextMapPropagator
+	filters        []func(*http.Request) bool
+	noRouteVars    bool
+}
+
+func (c *config) traced(r *http.Request) bool {
+	for _, filter := range c.filters {
+		if !filter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware returns a gorilla/mux middleware creating a server span for each request, and recording
+// the `http.server.request.duration` and `http.server.active_requests` metrics.
+//
+// The route variables are recorded as `mux.vars.<name>` attributes, which go through scrubbing
+// like any other attribute.
+func Middleware(opts ...Option) mux.MiddlewareFunc {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cfg.traced(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Middlewares run once the route has been matched.
+			route := ""
+			if current := mux.CurrentRoute(r); current != nil {
+				route, _ = current.GetPathTemplate()
+			}
+			attrs := httpconv.ServerRequestAttributes(r, route)
+			if !cfg.noRouteVars {
+				for name, value := range mux.Vars(r) {
+					attrs = append(attrs, attribute.String(varsPrefix+name, value))
+				}
+			}
+
+			ctx := instrumentation.Propagator(cfg.propagators).Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := instrumentation.Tracer(cfg.tracerProvider, instrumentationName).Start(ctx,
+				httpconv.SpanName(r.Method, route),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End(trace.WithStackTrace(true))
+			metrics := httpconv.StartServerMetrics(ctx, instrumentation.Meter(cfg.meterProvider, instrumentationName),
+				r.Method, httpconv.Scheme(r))
+
+			rw := httpconv.NewStatusRecorder(w)
+			defer func() {
+				if p := recover(); p != nil {
+					span.SetAttributes(semconv.HTTPResponseStatusCode(http.StatusInternalServerError))
+					span.SetStatus(codes.Error, fmt.Sprint(p))
+					metrics.End(ctx, route, http.StatusInternalServerError)
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rw.Status()))
+			metrics.End(ctx, route, rw.Status())
+			if rw.Status() >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, "")
+			}
+		})
+	}
+}
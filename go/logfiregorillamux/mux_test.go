@@ -0,0 +1,183 @@
+package logfiregorillamux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestRouter(t *testing.T, opts ...Option) (*mux.Router, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	r := mux.NewRouter()
+	r.Use(Middleware(append([]Option{WithTracerProvider(provider)}, opts...)...))
+	return r, recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	r, recorder := newTestRouter(t)
+	r.PathPrefix("/api").Subrouter().HandleFunc("/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Error("expected the request context to contain the span")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}).Methods("GET")
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users/42?q=1", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/users/{id:[0-9]+}" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected span kind %v", span.SpanKind())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		logfire.MsgKey:              "GET /api/users/42 ? q='1'",
+		"http.route":                "/api/users/{id:[0-9]+}",
+		"mux.vars.id":               "42",
+		"http.response.status_code": int64(http.StatusCreated),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+}
+
+func TestMiddlewareWithoutRouteVars(t *testing.T) {
+	r, recorder := newTestRouter(t, WithoutRouteVars())
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	if _, ok := oteltest.Attributes(recorder.Ended()[0].Attributes())["mux.vars.id"]; ok {
+		t.Error("expected the route variables not to be recorded")
+	}
+}
+
+func TestMiddlewareScrubsRouteVars(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	shutdown, err := logfire.Configure(t.Context(),
+		logfire.WithSendToLogfire(false),
+		logfire.WithConsole(false),
+		logfire.WithAdditionalSpanProcessors(recorder),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = shutdown(t.Context()) })
+	r := mux.NewRouter()
+	r.Use(Middleware())
+	r.HandleFunc("/reset/{password}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reset/hunter2", nil))
+
+	if got := oteltest.Attributes(recorder.Ended()[0].Attributes())["mux.vars.password"]; got != "[Scrubbed due to 'password']" {
+		t.Errorf("mux.vars.password = %v", got)
+	}
+}
+
+func TestMiddlewareServerError(t *testing.T) {
+	r, recorder := newTestRouter(t)
+	r.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+
+	if got := recorder.Ended()[0].Status().Code; got != codes.Error {
+		t.Errorf("status = %v, want %v", got, codes.Error)
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	r, recorder := newTestRouter(t)
+	r.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to be propagated")
+			}
+		}()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	}()
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "boom" {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	if got := oteltest.Attributes(span.Attributes())["http.response.status_code"]; got != int64(http.StatusInternalServerError) {
+		t.Errorf("status code = %v", got)
+	}
+	if len(span.Events()) != 1 || span.Events()[0].Name != "exception" {
+		t.Errorf("expected an exception event, got %v", span.Events())
+	}
+}
+
+func TestMiddlewareFilter(t *testing.T) {
+	r, recorder := newTestRouter(t, WithFilter(func(r *http.Request) bool {
+		template, _ := mux.CurrentRoute(r).GetPathTemplate()
+		return template != "/healthz"
+	}))
+	r.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	r, _ := newTestRouter(t, WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+	duration := metrics["http.server.request.duration"].(metricdata.Histogram[float64]).DataPoints[0]
+	want := attribute.NewSet(
+		attribute.String("http.request.method", "GET"),
+		attribute.String("url.scheme", "http"),
+		attribute.String("http.route", "/users/{id}"),
+		attribute.Int("http.response.status_code", http.StatusCreated),
+	)
+	if duration.Count != 1 || !duration.Attributes.Equals(&want) {
+		t.Errorf("unexpected duration %d with attributes %v", duration.Count, duration.Attributes.ToSlice())
+	}
+	if active := metrics["http.server.active_requests"].(metricdata.Sum[int64]).DataPoints[0]; active.Value != 0 {
+		t.Errorf("expected no active requests, got %d", active.Value)
+	}
+}
@@ -0,0 +1,77 @@
+// Package logfiregorillawebsocket instruments gorilla/websocket connections for Pydantic Logfire.
+//
+// [Upgrade] and [Dial] return connections which are a span lasting until they're closed,
+// named like `WebSocket /chat`, with an event for each message sent or received, including
+// its direction, opcode and size. The close frame ending a connection is recorded with its
+// status code, which sets the level of the span:
+//
+//	conn, err := logfiregorillawebsocket.Upgrade(&upgrader, w, r, nil)
+//	if err != nil {
+//		return
+//	}
+//	defer conn.Close()
+//	for {
+//		messageType, p, err := conn.ReadMessage()
+//		// ...
+//	}
+//
+// Dialed connections propagate the trace context in the headers of the handshake.
+package logfiregorillawebsocket
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiregorillawebsocket"
+
+// Option configures [Upgrade] and [Dial].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject and extract the trace context
+// in the headers of handshakes. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	attrs          []attribute.KeyValue
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
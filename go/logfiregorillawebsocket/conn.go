@@ -0,0 +1,276 @@
+package logfiregorillawebsocket
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/wsconv"
+)
+
+// Conn is a WebSocket connection recording its messages on its span.
+//
+// Messages written with WritePreparedMessage aren't recorded.
+type Conn struct {
+	*websocket.Conn
+	ctx  context.Context
+	conn *wsconv.Conn
+}
+
+// Upgrade upgrades an HTTP request to a WebSocket connection with upgrader, starting its span as a child
+// of the context of the request, or of the trace context in its headers if the request isn't traced.
+func Upgrade(upgrader *websocket.Upgrader, w http.ResponseWriter, r *http.Request, responseHeader http.Header, opts ...Option) (*Conn, error) {
+	c := newConfig(opts)
+	ctx := r.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = c.propagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+	}
+	ctx, conn := wsconv.Start(ctx, c.tracer(), trace.SpanKindServer, wsconv.SpanName(r.URL.Path),
+		append(wsconv.ServerAttributes(r), c.attrs...)...)
+	ws, err := upgrader.Upgrade(w, r, responseHeader)
+	if err != nil {
+		conn.Error(err)
+		conn.End()
+		return nil, err
+	}
+	return wrap(ctx, ws, conn), nil
+}
+
+// Dial dials a WebSocket server with dialer, or [websocket.DefaultDialer] if it's nil, starting the span
+// of the connection as a child of ctx. The trace context is injected in a copy of the request headers.
+func Dial(ctx context.Context, dialer *websocket.Dialer, urlStr string, requestHeader http.Header, opts ...Option) (*Conn, *http.Response, error) {
+	c := newConfig(opts)
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+	name := wsconv.SpanName("")
+	attrs := c.attrs
+	if u, err := url.Parse(urlStr); err == nil {
+		name = wsconv.SpanName(u.Host + u.Path)
+		attrs = append(wsconv.ClientAttributes(u), attrs...)
+	}
+	ctx, conn := wsconv.Start(ctx, c.tracer(), trace.SpanKindClient, name, attrs...)
+	header := make(http.Header, len(requestHeader)+1)
+	maps.Copy(header, requestHeader)
+	c.propagator().Inject(ctx, propagation.HeaderCarrier(header))
+	ws, resp, err := dialer.DialContext(ctx, urlStr, header)
+	if err != nil {
+		conn.Error(err)
+		conn.End()
+		return nil, resp, err
+	}
+	return wrap(ctx, ws, conn), resp, nil
+}
+
+func wrap(ctx context.Context, ws *websocket.Conn, conn *wsconv.Conn) *Conn {
+	if protocol := ws.Subprotocol(); protocol != "" {
+		conn.Span().SetAttributes(wsconv.SubprotocolKey.String(protocol))
+	}
+	c := &Conn{Conn: ws, ctx: ctx, conn: conn}
+	c.SetCloseHandler(ws.CloseHandler())
+	c.SetPingHandler(ws.PingHandler())
+	c.SetPongHandler(ws.PongHandler())
+	return c
+}
+
+// Context returns the context of the span of the connection, to use as the parent of the work
+// done for its messages.
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Close ends the span of the connection and closes it, without sending a close frame.
+func (c *Conn) Close() error {
+	c.conn.End()
+	return c.Conn.Close()
+}
+
+// SetCloseHandler sets the handler of the close frames received, which are recorded first.
+func (c *Conn) SetCloseHandler(h func(code int, text string) error) {
+	if h == nil {
+		c.Conn.SetCloseHandler(nil)
+		h = c.Conn.CloseHandler()
+	}
+	c.Conn.SetCloseHandler(func(code int, text string) error {
+		size := 0
+		if code != websocket.CloseNoStatusReceived {
+			size = 2 + len(text)
+		}
+		c.conn.Message(wsconv.Received, wsconv.OpClose, size)
+		c.conn.Close(wsconv.Received, code, text)
+		return h(code, text)
+	})
+}
+
+// SetPingHandler sets the handler of the pings received, which are recorded first.
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	if h == nil {
+		c.Conn.SetPingHandler(nil)
+		h = c.Conn.PingHandler()
+	}
+	c.Conn.SetPingHandler(func(appData string) error {
+		c.conn.Message(wsconv.Received, wsconv.OpPing, len(appData))
+		return h(appData)
+	})
+}
+
+// SetPongHandler sets the handler of the pongs received, which are recorded first.
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	if h == nil {
+		c.Conn.SetPongHandler(nil)
+		h = c.Conn.PongHandler()
+	}
+	c.Conn.SetPongHandler(func(appData string) error {
+		c.conn.Message(wsconv.Received, wsconv.OpPong, len(appData))
+		return h(appData)
+	})
+}
+
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	messageType, p, err := c.Conn.ReadMessage()
+	if err != nil {
+		c.readError(err)
+		return messageType, p, err
+	}
+	c.conn.Message(wsconv.Received, wsconv.Opcode(messageType), len(p))
+	return messageType, p, nil
+}
+
+// NextReader returns a reader of the next message, which is recorded once it's read until EOF.
+func (c *Conn) NextReader() (int, io.Reader, error) {
+	messageType, r, err := c.Conn.NextReader()
+	if err != nil {
+		c.readError(err)
+		return messageType, r, err
+	}
+	return messageType, &reader{Reader: r, conn: c, opcode: wsconv.Opcode(messageType)}, nil
+}
+
+// ReadJSON reads the next message and decodes it from JSON.
+func (c *Conn) ReadJSON(v any) error {
+	_, p, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(p, v)
+}
+
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	err := c.Conn.WriteMessage(messageType, data)
+	if err != nil {
+		c.conn.Error(err)
+		return err
+	}
+	c.sent(messageType, data)
+	return nil
+}
+
+// NextWriter returns a writer of the next message, which is recorded once the writer is closed.
+func (c *Conn) NextWriter(messageType int) (io.WriteCloser, error) {
+	w, err := c.Conn.NextWriter(messageType)
+	if err != nil {
+		c.conn.Error(err)
+		return nil, err
+	}
+	return &writer{WriteCloser: w, conn: c, opcode: wsconv.Opcode(messageType)}, nil
+}
+
+// WriteJSON writes a message encoded as JSON.
+func (c *Conn) WriteJSON(v any) error {
+	w, err := c.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(w).Encode(v)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WriteControl writes a control message.
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	err := c.Conn.WriteControl(messageType, data, deadline)
+	if err != nil {
+		c.conn.Error(err)
+		return err
+	}
+	c.sent(messageType, data)
+	return nil
+}
+
+// sent records a message sent. A close message is recorded as the end of the connection.
+func (c *Conn) sent(messageType int, data []byte) {
+	c.conn.Message(wsconv.Sent, wsconv.Opcode(messageType), len(data))
+	if messageType == websocket.CloseMessage {
+		code, text := websocket.CloseNoStatusReceived, ""
+		if len(data) >= 2 {
+			code, text = int(binary.BigEndian.Uint16(data)), string(data[2:])
+		}
+		c.conn.Close(wsconv.Sent, code, text)
+	}
+}
+
+// readError records the close frame ending a connection, or the error reading from it.
+func (c *Conn) readError(err error) {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		c.conn.Close(wsconv.Received, closeErr.Code, closeErr.Text)
+		return
+	}
+	c.conn.Error(err)
+}
+
+type reader struct {
+	io.Reader
+	conn   *Conn
+	opcode wsconv.Opcode
+	n      int
+	done   bool
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	switch {
+	case err == io.EOF && !r.done:
+		r.done = true
+		r.conn.conn.Message(wsconv.Received, r.opcode, r.n)
+	case err != nil && err != io.EOF:
+		r.conn.readError(err)
+	}
+	return n, err
+}
+
+type writer struct {
+	io.WriteCloser
+	conn   *Conn
+	opcode wsconv.Opcode
+	n      int
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.n += n
+	return n, err
+}
+
+func (w *writer) Close() error {
+	err := w.WriteCloser.Close()
+	if err != nil {
+		w.conn.conn.Error(err)
+		return err
+	}
+	w.conn.conn.Message(wsconv.Sent, w.opcode, w.n)
+	return nil
+}
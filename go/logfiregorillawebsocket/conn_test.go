@@ -0,0 +1,175 @@
+package logfiregorillawebsocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func waitForSpan(t *testing.T, recorder *tracetest.SpanRecorder, kind trace.SpanKind) sdktrace.ReadOnlySpan {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, span := range recorder.Ended() {
+			if span.SpanKind() == kind {
+				return span
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no %v span", kind)
+	return nil
+}
+
+// newEchoServer echoes the messages it receives until the connection is closed, or closes
+// the connection without a close frame when it receives "drop".
+func newEchoServer(t *testing.T) (string, []Option, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opts := []Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}
+	upgrader := websocket.Upgrader{Subprotocols: []string{"chat"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(&upgrader, w, r, nil, opts...)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, p, err := conn.ReadMessage()
+			if err != nil || string(p) == "drop" {
+				return
+			}
+			if err := conn.WriteMessage(messageType, p); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/chat", opts, recorder
+}
+
+func messageEvents(span sdktrace.ReadOnlySpan) []map[attribute.Key]any {
+	var events []map[attribute.Key]any
+	for _, event := range span.Events() {
+		if event.Name == "message" {
+			events = append(events, oteltest.Attributes(event.Attributes))
+		}
+	}
+	return events
+}
+
+func TestConn(t *testing.T) {
+	url, opts, recorder := newEchoServer(t)
+	dialer := websocket.Dialer{Subprotocols: []string{"chat"}}
+	conn, _, err := Dial(context.Background(), &dialer, url, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := conn.ReadMessage(); err != nil || string(p) != "hello" {
+		t.Fatalf("read %q, %v", p, err)
+	}
+	if err := conn.WriteJSON(map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]int
+	if err := conn.ReadJSON(&v); err != nil || v["n"] != 1 {
+		t.Fatalf("read %v, %v", v, err)
+	}
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye")
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("read error = %v, want the close frame", err)
+	}
+	conn.Close()
+
+	client := waitForSpan(t, recorder, trace.SpanKindClient)
+	server := waitForSpan(t, recorder, trace.SpanKindServer)
+	if server.Parent().SpanID() != client.SpanContext().SpanID() {
+		t.Error("server span isn't a child of the client span")
+	}
+	if client.Name() != "WebSocket "+strings.TrimPrefix(url, "ws://") || server.Name() != "WebSocket /chat" {
+		t.Errorf("names = %s, %s", client.Name(), server.Name())
+	}
+	oteltest.CheckAttributes(t, client.Attributes(), map[attribute.Key]any{
+		"websocket.subprotocol":       "chat",
+		"websocket.close.code":        int64(1000),
+		"websocket.close.reason":      "bye",
+		"websocket.close.direction":   "sent",
+		"websocket.messages.sent":     int64(2),
+		"websocket.messages.received": int64(2),
+		"websocket.bytes.sent":        int64(5 + len("{\"n\":1}\n")),
+		logfire.LevelNumKey:           int64(logfire.LevelInfo),
+	})
+	oteltest.CheckAttributes(t, server.Attributes(), map[attribute.Key]any{
+		"url.path":                  "/chat",
+		"websocket.close.code":      int64(1000),
+		"websocket.close.direction": "received",
+	})
+	events := messageEvents(client)
+	if len(events) != 6 {
+		t.Fatalf("events = %v, want 4 data messages and 2 close frames", events)
+	}
+	want := map[attribute.Key]any{
+		"websocket.message.direction": "sent",
+		"websocket.message.opcode":    "text",
+		"websocket.message.size":      int64(5),
+	}
+	for k, v := range want {
+		if events[0][k] != v {
+			t.Errorf("first event %s = %v, want %v", k, events[0][k], v)
+		}
+	}
+	if events[4]["websocket.message.opcode"] != "close" {
+		t.Errorf("fifth event = %v, want the close frame", events[4])
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{client, server} {
+		if span.Status().Code == codes.Error {
+			t.Errorf("%v status = %v", span.SpanKind(), span.Status())
+		}
+	}
+}
+
+func TestAbnormalClosure(t *testing.T) {
+	url, opts, recorder := newEchoServer(t)
+	conn, _, err := Dial(context.Background(), nil, url, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("drop")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseAbnormalClosure) {
+		t.Fatalf("read error = %v, want an abnormal closure", err)
+	}
+	conn.Close()
+
+	client := waitForSpan(t, recorder, trace.SpanKindClient)
+	oteltest.CheckAttributes(t, client.Attributes(), map[attribute.Key]any{
+		"websocket.close.code": int64(1006),
+		logfire.LevelNumKey:    int64(logfire.LevelError),
+	})
+	if client.Status().Code != codes.Error {
+		t.Errorf("status = %v, want an error", client.Status())
+	}
+}
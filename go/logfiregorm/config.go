@@ -0,0 +1,70 @@
+// Package logfiregorm instruments GORM for Pydantic Logfire.
+//
+// [NewPlugin] returns a plugin creating a client span for each query made through GORM,
+// named after the operation and table like `SELECT users`, with the sanitized query as message:
+//
+//	db, err := gorm.Open(postgres.Open(dsn))
+//	err = db.Use(logfiregorm.NewPlugin())
+package logfiregorm
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiregorm"
+
+// Option configures [NewPlugin].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithSystem sets the `db.system.name` attribute, e.g. "postgresql".
+// By default it's inferred from the name of the dialector.
+func WithSystem(system string) Option {
+	return func(c *config) {
+		c.system = system
+	}
+}
+
+// WithAttributes adds attributes to all spans, e.g. `db.namespace` or `server.address`.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithoutSanitization records queries as is, rather than with their literals replaced by `?`.
+// GORM passes values as arguments, so this only matters for raw queries embedding values.
+func WithoutSanitization() Option {
+	return func(c *config) {
+		c.noSanitization = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	system         string
+	attrs          []attribute.KeyValue
+	noSanitization bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
@@ -0,0 +1,129 @@
+package logfiregorm
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+)
+
+// spanKey is the key of the current call in the instance settings of a statement.
+const spanKey = "logfire:call"
+
+// NewPlugin returns a GORM plugin creating spans for queries, to register with [gorm.DB.Use].
+func NewPlugin(opts ...Option) gorm.Plugin {
+	return &plugin{config: newConfig(opts)}
+}
+
+type plugin struct {
+	config *config
+	system string
+}
+
+// call is the span of a statement, with the context to restore when it ends.
+type call struct {
+	span   trace.Span
+	parent context.Context
+}
+
+// Name implements [gorm.Plugin].
+func (p *plugin) Name() string {
+	return "logfire"
+}
+
+// Initialize registers callbacks around the processors of GORM which run queries.
+func (p *plugin) Initialize(db *gorm.DB) error {
+	p.system = p.config.system
+	if p.system == "" && db.Dialector != nil {
+		p.system = sqlconv.System(db.Dialector.Name())
+	}
+	if p.system == "" {
+		p.system = semconv.DBSystemNameOtherSQL.Value.AsString()
+	}
+
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("logfire:before_create", p.before("INSERT")),
+		cb.Create().After("gorm:create").Register("logfire:after_create", p.after(true)),
+		cb.Query().Before("gorm:query").Register("logfire:before_query", p.before("SELECT")),
+		cb.Query().After("gorm:query").Register("logfire:after_query", p.after(true)),
+		cb.Update().Before("gorm:update").Register("logfire:before_update", p.before("UPDATE")),
+		cb.Update().After("gorm:update").Register("logfire:after_update", p.after(true)),
+		cb.Delete().Before("gorm:delete").Register("logfire:before_delete", p.before("DELETE")),
+		cb.Delete().After("gorm:delete").Register("logfire:after_delete", p.after(true)),
+		cb.Row().Before("gorm:row").Register("logfire:before_row", p.before("")),
+		cb.Row().After("gorm:row").Register("logfire:after_row", p.after(false)),
+		cb.Raw().Before("gorm:raw").Register("logfire:before_raw", p.before("")),
+		cb.Raw().After("gorm:raw").Register("logfire:after_raw", p.after(true)),
+	)
+}
+
+// before returns a callback starting the span of a statement. The query is only built by GORM's
+// own callback, so the span is named after the operation until it ends.
+func (p *plugin) before(operation string) func(*gorm.DB) {
+	name := operation
+	if name == "" {
+		name = p.system
+	}
+	return func(db *gorm.DB) {
+		if db.DryRun || db.Statement.Context == nil {
+			return
+		}
+		parent := db.Statement.Context
+		ctx, span := p.config.tracer().Start(parent, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemNameKey.String(p.system)),
+			trace.WithAttributes(p.config.attrs...),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, &call{span: span, parent: parent})
+	}
+}
+
+// after returns a callback ending the span of a statement with the query GORM ran, the number of rows
+// and the error. GORM doesn't count the rows of [gorm.DB.Row] and [gorm.DB.Rows].
+func (p *plugin) after(countRows bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, _ := db.InstanceGet(spanKey)
+		c, _ := v.(*call)
+		if c == nil {
+			return
+		}
+		db.InstanceSet(spanKey, (*call)(nil))
+		db.Statement.Context = c.parent
+		span := c.span
+		defer span.End()
+		// Not finding a record is reported as an error by GORM but is usually expected.
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+
+		if query := db.Statement.SQL.String(); query != "" {
+			q := sqlconv.Parse(query, !p.config.noSanitization)
+			if q.Collection == "" {
+				q.Collection = db.Statement.Table
+			}
+			span.SetName(q.SpanName(p.system))
+			span.SetAttributes(q.Attributes()...)
+			if countRows && !failed {
+				span.SetAttributes(rowsAttribute(q.Operation, db.RowsAffected))
+			}
+		}
+		if failed {
+			span.RecordError(db.Error)
+			span.SetStatus(codes.Error, db.Error.Error())
+		}
+	}
+}
+
+// rowsAttribute returns the number of rows returned by a SELECT, or affected by other statements.
+func rowsAttribute(operation string, n int64) attribute.KeyValue {
+	if operation == "SELECT" {
+		return semconv.DBResponseReturnedRows(int(n))
+	}
+	return sqlconv.RowsAffectedKey.Int64(n)
+}
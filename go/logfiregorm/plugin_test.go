@@ -0,0 +1,265 @@
+package logfiregorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func init() {
+	sql.Register("logfiregorm", fakeDriver{})
+}
+
+// fakeDriver returns two rows for every query and affects one row for every statement.
+// Queries containing "fail" return an error.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errors.New("syntax error")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errors.New("syntax error")
+	}
+	if strings.Contains(query, "missing") {
+		return &fakeRows{}, nil
+	}
+	return &fakeRows{remaining: 2}, nil
+}
+
+type fakeRows struct {
+	remaining int
+}
+
+func (*fakeRows) Columns() []string { return []string{"id", "name"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = int64(r.remaining), "alice"
+	r.remaining--
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type User struct {
+	ID   uint
+	Name string
+}
+
+func openTestDB(t *testing.T, opts ...Option) (*gorm.DB, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	sqlDB, err := sql.Open("logfiregorm", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{
+		ConnPool:               sqlDB,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(NewPlugin(append([]Option{WithTracerProvider(provider), WithSystem("sqlite")}, opts...)...)); err != nil {
+		t.Fatal(err)
+	}
+	return db.WithContext(t.Context()), recorder
+}
+
+func onlySpan(t *testing.T, recorder *tracetest.SpanRecorder) sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	return spans[0]
+}
+
+func TestQuery(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	var users []User
+	if err := db.Where("name = ?", "alice").Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	span := onlySpan(t, recorder)
+	if span.Name() != "SELECT users" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:              "SELECT * FROM `users` WHERE name = ?",
+		"db.system.name":            "sqlite",
+		"db.query.text":             "SELECT * FROM `users` WHERE name = ?",
+		"db.operation.name":         "SELECT",
+		"db.collection.name":        "users",
+		"db.response.returned_rows": int64(2),
+	})
+}
+
+func TestRecordNotFound(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	var user User
+	err := db.Where("name = 'missing'").First(&user).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	span := onlySpan(t, recorder)
+	if span.Status().Code == codes.Error {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"db.response.returned_rows": int64(0),
+	})
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	if err := db.Model(&User{ID: 1}).Update("name", "bob").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&User{ID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for i, name := range []string{"UPDATE users", "DELETE users"} {
+		if spans[i].Name() != name {
+			t.Errorf("span %d is named %q, want %q", i, spans[i].Name(), name)
+		}
+		oteltest.CheckAttributes(t, spans[i].Attributes(), map[attribute.Key]any{
+			"db.response.affected_rows": int64(1),
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	if err := db.Create(&User{Name: "alice"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	span := onlySpan(t, recorder)
+	if span.Name() != "INSERT users" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"db.operation.name":  "INSERT",
+		"db.collection.name": "users",
+	})
+}
+
+func TestRaw(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	if err := db.Exec("UPDATE users SET name = 'bob' WHERE id = 1").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	span := onlySpan(t, recorder)
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"db.query.text":             "UPDATE users SET name = ? WHERE id = ?",
+		"db.response.affected_rows": int64(1),
+	})
+}
+
+func TestWithoutSanitization(t *testing.T) {
+	db, recorder := openTestDB(t, WithoutSanitization())
+
+	if err := db.Exec("UPDATE users SET name = 'bob'").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	oteltest.CheckAttributes(t, onlySpan(t, recorder).Attributes(), map[attribute.Key]any{
+		"db.query.text": "UPDATE users SET name = 'bob'",
+	})
+}
+
+func TestError(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	if err := db.Exec("fail").Error; err == nil {
+		t.Fatal("expected an error")
+	}
+
+	span := onlySpan(t, recorder)
+	if span.Status().Code != codes.Error {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	if _, ok := oteltest.Attributes(span.Attributes())["db.response.affected_rows"]; ok {
+		t.Error("unexpected rows affected on a failed statement")
+	}
+}
+
+func TestRow(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	var name string
+	if err := db.Raw("SELECT name FROM users WHERE id = ?", 1).Row().Scan(new(int), &name); err != nil {
+		t.Fatal(err)
+	}
+
+	span := onlySpan(t, recorder)
+	if span.Name() != "SELECT users" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if _, ok := oteltest.Attributes(span.Attributes())["db.response.returned_rows"]; ok {
+		t.Error("unexpected returned rows for Row")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	var users []User
+	db.Session(&gorm.Session{DryRun: true}).Find(&users)
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
@@ -0,0 +1,65 @@
+// Package logfiregqlgen instruments 99designs/gqlgen GraphQL servers for Pydantic Logfire.
+//
+// [Extension] returns a server extension creating a span for each GraphQL operation, named like
+// `query GetUser`, and a span for each field resolved, named like `Query.user`:
+//
+//	srv := handler.New(generated.NewExecutableSchema(generated.Config{Resolvers: resolvers}))
+//	srv.AddTransport(transport.POST{})
+//	srv.Use(logfiregqlgen.Extension())
+//
+// Errors caused by the request, such as validation errors or errors with an `extensions.code`
+// like `BAD_USER_INPUT`, are warnings, while the other errors returned by resolvers are errors.
+package logfiregqlgen
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiregqlgen"
+
+// Option configures [Extension].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithoutTrivialFieldSpans disables the spans of the fields resolved without a resolver or a method,
+// by reading a field of a struct, which are most of the fields of most operations.
+func WithoutTrivialFieldSpans() Option {
+	return func(c *config) {
+		c.noTrivialFields = true
+	}
+}
+
+type config struct {
+	tracerProvider  trace.TracerProvider
+	attrs           []attribute.KeyValue
+	noTrivialFields bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
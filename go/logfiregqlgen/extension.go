@@ -0,0 +1,210 @@
+package logfiregqlgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// complexityKey records the complexity of an operation, computed by [extension.ComplexityLimit].
+	complexityKey = attribute.Key("graphql.operation.complexity")
+	// fieldNameKey records the name of a field.
+	fieldNameKey = attribute.Key("graphql.field.name")
+	// fieldAliasKey records the alias of a field, if it has one.
+	fieldAliasKey = attribute.Key("graphql.field.alias")
+	// fieldPathKey records the path of a field in the response, e.g. `user.friends.0.name`.
+	fieldPathKey = attribute.Key("graphql.field.path")
+	// fieldTypeKey records the type of a field, e.g. `[User!]!`.
+	fieldTypeKey = attribute.Key("graphql.field.type")
+	// fieldObjectKey records the type of the object a field belongs to, e.g. `Query`.
+	fieldObjectKey = attribute.Key("graphql.field.object")
+)
+
+// clientErrorCodes are the `extensions.code` of errors caused by the request rather than by the server,
+// spelled like gqlgen and Apollo Server.
+var clientErrorCodes = map[string]bool{
+	errcode.ParseFailed:             true,
+	errcode.ValidationFailed:        true,
+	"BAD_USER_INPUT":                true,
+	"UNAUTHENTICATED":               true,
+	"FORBIDDEN":                     true,
+	"PERSISTED_QUERY_NOT_FOUND":     true,
+	"PERSISTED_QUERY_NOT_SUPPORTED": true,
+	"OPERATION_RESOLUTION_FAILURE":  true,
+}
+
+// Extension returns a gqlgen extension creating a span for each operation, and a span for each field
+// as a child of the span of its operation. The span of a subscription lasts until its last event.
+func Extension(opts ...Option) graphql.HandlerExtension {
+	return &tracer{config: newConfig(opts)}
+}
+
+type tracer struct {
+	config *config
+}
+
+var (
+	_ graphql.HandlerExtension     = (*tracer)(nil)
+	_ graphql.OperationInterceptor = (*tracer)(nil)
+	_ graphql.ResponseInterceptor  = (*tracer)(nil)
+	_ graphql.FieldInterceptor     = (*tracer)(nil)
+)
+
+func (*tracer) ExtensionName() string { return "LogfireTracing" }
+
+func (*tracer) Validate(graphql.ExecutableSchema) error { return nil }
+
+// operationKey marks the contexts of operations whose span was started by InterceptOperation.
+type operationKey struct{}
+
+func (t *tracer) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	opCtx := graphql.GetOperationContext(ctx)
+	ctx, span := t.startOperation(ctx, opCtx)
+	responses := next(context.WithValue(ctx, operationKey{}, true))
+	subscription := opCtx.Operation.Operation == ast.Subscription
+	ended := false
+	return func(ctx context.Context) *graphql.Response {
+		resp := responses(ctx)
+		if ended {
+			return resp
+		}
+		if resp != nil {
+			setErrors(span, resp.Errors)
+			// Subscriptions and deferred fields have more responses.
+			if subscription || (resp.HasNext != nil && *resp.HasNext) {
+				return resp
+			}
+		}
+		ended = true
+		span.End()
+		return resp
+	}
+}
+
+// InterceptResponse creates the span of operations which failed before being executed,
+// e.g. because they're invalid. The spans of the others are created by InterceptOperation.
+func (t *tracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if ctx.Value(operationKey{}) != nil || !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	ctx, span := t.startOperation(ctx, graphql.GetOperationContext(ctx))
+	defer span.End()
+	resp := next(ctx)
+	if resp != nil {
+		setErrors(span, resp.Errors)
+	}
+	return resp
+}
+
+func (t *tracer) startOperation(ctx context.Context, opCtx *graphql.OperationContext) (context.Context, trace.Span) {
+	name := "GraphQL Operation"
+	var attrs []attribute.KeyValue
+	if op := opCtx.Operation; op != nil {
+		name = string(op.Operation)
+		attrs = append(attrs, semconv.GraphQLOperationTypeKey.String(string(op.Operation)))
+		if op.Name != "" {
+			name += " " + op.Name
+			attrs = append(attrs, semconv.GraphQLOperationName(op.Name))
+		}
+	}
+	attrs = append(attrs, logfire.MsgKey.String(name))
+	if opCtx.RawQuery != "" {
+		attrs = append(attrs, semconv.GraphQLDocument(opCtx.RawQuery))
+	}
+	if stats := extension.GetComplexityStats(ctx); stats != nil {
+		attrs = append(attrs, complexityKey.Int(stats.Complexity))
+	}
+	startOpts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(t.config.attrs...),
+	}
+	if start := opCtx.Stats.OperationStart; !start.IsZero() {
+		startOpts = append(startOpts, trace.WithTimestamp(start))
+	}
+	return t.config.tracer().Start(ctx, name, startOpts...)
+}
+
+func (t *tracer) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || (t.config.noTrivialFields && !fc.IsResolver && !fc.IsMethod) {
+		return next(ctx)
+	}
+	name := fc.Object + "." + fc.Field.Name
+	attrs := []attribute.KeyValue{
+		logfire.MsgKey.String(name),
+		fieldNameKey.String(fc.Field.Name),
+		fieldPathKey.String(fc.Path().String()),
+		fieldObjectKey.String(fc.Object),
+	}
+	if fc.Field.Alias != "" && fc.Field.Alias != fc.Field.Name {
+		attrs = append(attrs, fieldAliasKey.String(fc.Field.Alias))
+	}
+	if def := fc.Field.Definition; def != nil && def.Type != nil {
+		attrs = append(attrs, fieldTypeKey.String(def.Type.String()))
+	}
+	ctx, span := t.config.tracer().Start(ctx, name,
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(t.config.attrs...),
+	)
+	defer span.End(trace.WithStackTrace(true))
+	defer func() {
+		if p := recover(); p != nil {
+			span.SetAttributes(logfire.LevelNumKey.Int(int(logfire.LevelError)))
+			span.SetStatus(codes.Error, fmt.Sprint(p))
+			panic(p)
+		}
+	}()
+	res, err := next(ctx)
+	if err != nil {
+		setErrors(span, gqlerror.List{gqlerror.WrapIfUnwrapped(err)})
+	}
+	return res, err
+}
+
+// setErrors records the errors of an operation or field, and sets the level of its span to the level
+// of its most severe error: warn for errors caused by the request and error for the others.
+// Only the latter give the span an error status.
+func setErrors(span trace.Span, errs gqlerror.List) {
+	if len(errs) == 0 {
+		return
+	}
+	level := logfire.LevelWarn
+	var message string
+	for _, err := range errs {
+		span.RecordError(err)
+		if errorLevel(err) == logfire.LevelError && level != logfire.LevelError {
+			level = logfire.LevelError
+			message = err.Message
+		}
+	}
+	span.SetAttributes(logfire.LevelNumKey.Int(int(level)))
+	if level == logfire.LevelError {
+		span.SetStatus(codes.Error, message)
+	}
+}
+
+func errorLevel(err *gqlerror.Error) logfire.Level {
+	if code, ok := err.Extensions["code"].(string); ok && clientErrorCodes[code] {
+		return logfire.LevelWarn
+	}
+	// Errors returned by resolvers are wrapped, and may carry their code themselves.
+	var inner *gqlerror.Error
+	if errors.As(err.Unwrap(), &inner) && inner != err {
+		return errorLevel(inner)
+	}
+	return logfire.LevelError
+}
@@ -0,0 +1,216 @@
+package logfiregqlgen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+var schema = gqlparser.MustLoadSchema(&ast.Source{Input: `
+	type Query {
+		user(id: ID!): User
+	}
+	type User {
+		name: String!
+	}
+`})
+
+func field(object, name string, isResolver bool) *graphql.FieldContext {
+	return &graphql.FieldContext{
+		Object:     object,
+		IsResolver: isResolver,
+		Field: graphql.CollectedField{Field: &ast.Field{
+			Name:       name,
+			Alias:      name,
+			Definition: schema.Types[object].Fields.ForName(name),
+		}},
+	}
+}
+
+func newServer(t *testing.T, resolve graphql.Resolver, opts ...Option) (*httptest.Server, *tracetest.SpanRecorder) {
+	t.Helper()
+	// The schema resolves `user` with resolve, then its `name` by reading a field,
+	// like the code generated by gqlgen.
+	es := &graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema { return schema },
+		ComplexityFunc: func(context.Context, string, string, int, map[string]any) (int, bool) {
+			return 2, true
+		},
+	}
+	es.ExecFunc = func(ctx context.Context) graphql.ResponseHandler {
+		ran := false
+		return func(ctx context.Context) *graphql.Response {
+			if ran {
+				return nil
+			}
+			ran = true
+			opCtx := graphql.GetOperationContext(ctx)
+			userCtx := graphql.WithFieldContext(ctx, field("Query", "user", true))
+			user, err := opCtx.ResolverMiddleware(userCtx, resolve)
+			if err != nil {
+				graphql.AddError(userCtx, err)
+				return &graphql.Response{Data: []byte(`{"user":null}`)}
+			}
+			nameCtx := graphql.WithFieldContext(userCtx, field("User", "name", false))
+			name, _ := opCtx.ResolverMiddleware(nameCtx, func(context.Context) (any, error) {
+				return user.(map[string]string)["name"], nil
+			})
+			return &graphql.Response{Data: []byte(`{"user":{"name":"` + name.(string) + `"}}`)}
+		}
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	srv := handler.New(es)
+	srv.AddTransport(transport.POST{})
+	srv.Use(extension.FixedComplexityLimit(10))
+	srv.Use(Extension(append(opts, WithTracerProvider(provider))...))
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+	return server, recorder
+}
+
+func post(t *testing.T, server *httptest.Server, body string) {
+	t.Helper()
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func spansByName(spans []sdktrace.ReadOnlySpan) map[string]sdktrace.ReadOnlySpan {
+	m := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		m[span.Name()] = span
+	}
+	return m
+}
+
+const getUser = `{"query": "query GetUser { user(id: 1) { name } }", "operationName": "GetUser"}`
+
+func TestOperation(t *testing.T) {
+	server, recorder := newServer(t, func(context.Context) (any, error) {
+		return map[string]string{"name": "Ada"}, nil
+	})
+	post(t, server, getUser)
+
+	spans := spansByName(recorder.Ended())
+	if len(spans) != 3 {
+		t.Fatalf("got spans %v, want an operation and two fields", spans)
+	}
+	op := spans["query GetUser"]
+	if op == nil {
+		t.Fatalf("no operation span in %v", spans)
+	}
+	if op.SpanKind() != trace.SpanKindServer {
+		t.Errorf("kind = %v, want server", op.SpanKind())
+	}
+	oteltest.CheckAttributes(t, op.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:           "query GetUser",
+		"graphql.operation.name": "GetUser",
+		"graphql.operation.type": "query",
+		"graphql.document":       "query GetUser { user(id: 1) { name } }",
+		complexityKey:            int64(2),
+	})
+	if op.Status().Code == codes.Error {
+		t.Errorf("status = %v", op.Status())
+	}
+
+	user := spans["Query.user"]
+	if user == nil {
+		t.Fatalf("no field span in %v", spans)
+	}
+	if user.Parent().SpanID() != op.SpanContext().SpanID() {
+		t.Error("field span isn't a child of the operation span")
+	}
+	oteltest.CheckAttributes(t, user.Attributes(), map[attribute.Key]any{
+		fieldNameKey:   "user",
+		fieldPathKey:   "user",
+		fieldTypeKey:   "User",
+		fieldObjectKey: "Query",
+	})
+	oteltest.CheckAttributes(t, spans["User.name"].Attributes(), map[attribute.Key]any{
+		fieldPathKey: "user.name",
+		fieldTypeKey: "String!",
+	})
+}
+
+func TestWithoutTrivialFieldSpans(t *testing.T) {
+	server, recorder := newServer(t, func(context.Context) (any, error) {
+		return map[string]string{"name": "Ada"}, nil
+	}, WithoutTrivialFieldSpans())
+	post(t, server, getUser)
+
+	spans := spansByName(recorder.Ended())
+	if _, ok := spans["User.name"]; ok || len(spans) != 2 {
+		t.Errorf("got spans %v, want the operation and the resolver", spans)
+	}
+}
+
+func TestResolverErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		err   error
+		level logfire.Level
+	}{
+		{"server error", errors.New("database unavailable"), logfire.LevelError},
+		{"client error", &gqlerror.Error{Message: "not logged in", Extensions: map[string]any{"code": "UNAUTHENTICATED"}}, logfire.LevelWarn},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server, recorder := newServer(t, func(context.Context) (any, error) { return nil, tt.err })
+			post(t, server, getUser)
+
+			spans := spansByName(recorder.Ended())
+			for _, name := range []string{"Query.user", "query GetUser"} {
+				span := spans[name]
+				if span == nil {
+					t.Fatalf("no %s span in %v", name, spans)
+				}
+				oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{logfire.LevelNumKey: int64(tt.level)})
+				if isError := span.Status().Code == codes.Error; isError != (tt.level == logfire.LevelError) {
+					t.Errorf("%s status = %v", name, span.Status())
+				}
+				if events := span.Events(); len(events) != 1 || events[0].Name != "exception" {
+					t.Errorf("%s events = %v, want the error", name, events)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidOperation(t *testing.T) {
+	server, recorder := newServer(t, nil)
+	post(t, server, `{"query": "query Invalid { missing }"}`)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "GraphQL Operation" {
+		t.Fatalf("got spans %v, want the span of the invalid operation", spans)
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelWarn),
+		"graphql.document":  "query Invalid { missing }",
+	})
+	if spans[0].Status().Code == codes.Error {
+		t.Errorf("status = %v, want no error for an invalid request", spans[0].Status())
+	}
+}
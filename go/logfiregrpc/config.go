@@ -0,0 +1,102 @@
+// Package logfiregrpc instruments gRPC servers and clients for Pydantic Logfire.
+//
+// The interceptors create a span for each RPC, named after the full method like
+// `helloworld.Greeter/SayHello`, with the rpc.* semantic convention attributes.
+// The gRPC status code sets the Logfire level of the span:
+//
+//	server := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(logfiregrpc.UnaryServerInterceptor()),
+//		grpc.ChainStreamInterceptor(logfiregrpc.StreamServerInterceptor()),
+//	)
+//	conn, err := grpc.NewClient(target,
+//		grpc.WithChainUnaryInterceptor(logfiregrpc.UnaryClientInterceptor()),
+//		grpc.WithChainStreamInterceptor(logfiregrpc.StreamClientInterceptor()),
+//	)
+package logfiregrpc
+
+import (
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+	"github.com/pydantic/logfire/go/internal/rpcconv"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiregrpc"
+
+// Option configures the interceptors.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to extract and inject the trace context
+// in the gRPC metadata. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithFilter adds a filter for RPCs by full method, e.g. "/grpc.health.v1.Health/Check":
+// an RPC is only traced if all filters return true.
+func WithFilter(filter func(fullMethod string) bool) Option {
+	return func(c *config) {
+		c.filters = append(c.filters, filter)
+	}
+}
+
+// WithMessageEvents records a `message` event for each message sent and received,
+// with its type, sequence number and size.
+func WithMessageEvents() Option {
+	return func(c *config) {
+		c.messages.Events = true
+	}
+}
+
+// WithMessagePayloads records the messages as JSON in the `message` events, and implies [WithMessageEvents].
+// Payloads longer than maxSize bytes are truncated, 0 means no limit.
+//
+// Payloads often contain personal data: they go through scrubbing, but only record them when needed.
+func WithMessagePayloads(maxSize int) Option {
+	return func(c *config) {
+		c.messages = rpcconv.MessageOptions{Events: true, Payloads: true, MaxPayloadSize: maxSize}
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	filters        []func(string) bool
+	messages       rpcconv.MessageOptions
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) traced(fullMethod string) bool {
+	for _, filter := range c.filters {
+		if !filter(fullMethod) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
@@ -0,0 +1,261 @@
+package logfiregrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/pydantic/logfire/go/internal/rpcconv"
+)
+
+// UnaryServerInterceptor returns an interceptor creating a server span for each unary RPC.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	cfg := newConfig(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !cfg.traced(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, span := cfg.startServerSpan(ctx, info.FullMethod)
+		defer span.End(trace.WithStackTrace(true))
+		defer rpcconv.RecordPanic(span, rpcconv.CodeInternal.String())
+
+		cfg.messages.MessageReceived(span, 1, req)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			cfg.messages.MessageSent(span, 1, resp)
+		}
+		setStatus(span, err, trace.SpanKindServer)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor creating a server span for each streaming RPC.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	cfg := newConfig(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !cfg.traced(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx, span := cfg.startServerSpan(ss.Context(), info.FullMethod)
+		defer span.End(trace.WithStackTrace(true))
+		defer rpcconv.RecordPanic(span, rpcconv.CodeInternal.String())
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx, config: cfg, span: span})
+		setStatus(span, err, trace.SpanKindServer)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor creating a client span for each unary RPC,
+// and injecting the trace context in the outgoing metadata.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	cfg := newConfig(opts)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if !cfg.traced(method) {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		ctx, span := cfg.startClientSpan(ctx, method, cc)
+		defer span.End()
+
+		cfg.messages.MessageSent(span, 1, req)
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		if err == nil {
+			cfg.messages.MessageReceived(span, 1, reply)
+		}
+		setStatus(span, err, trace.SpanKindClient)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor creating a client span for each streaming RPC,
+// and injecting the trace context in the outgoing metadata.
+//
+// The span ends when the stream does: when RecvMsg returns an error, including io.EOF,
+// when the only response of a client streaming RPC is received, or when the context is canceled.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	cfg := newConfig(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if !cfg.traced(method) {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+		ctx, span := cfg.startClientSpan(ctx, method, cc)
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			setStatus(span, err, trace.SpanKindClient)
+			span.End()
+			return cs, err
+		}
+		s := &clientStream{ClientStream: cs, desc: desc, config: cfg, span: span}
+		s.stop = context.AfterFunc(ctx, func() { s.end(ctx.Err()) })
+		return s, nil
+	}
+}
+
+func (c *config) startServerSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = c.propagator().Extract(ctx, metadataCarrier(md))
+	attrs := rpcconv.Attributes(semconv.RPCSystemNameGRPC, fullMethod)
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, peerAttributes(p.Addr)...)
+	}
+	return c.tracer().Start(ctx, rpcconv.SpanName(fullMethod),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func (c *config) startClientSpan(ctx context.Context, method string, cc *grpc.ClientConn) (context.Context, trace.Span) {
+	attrs := rpcconv.Attributes(semconv.RPCSystemNameGRPC, method)
+	attrs = append(attrs, rpcconv.ServerAddress(cc.Target())...)
+	ctx, span := c.tracer().Start(ctx, rpcconv.SpanName(method),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	// The metadata of the context must not be modified.
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	c.propagator().Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// peerAttributes returns the `client.address` and `client.port` attributes of a server span.
+func peerAttributes(addr net.Addr) []attribute.KeyValue {
+	if addr == nil {
+		return nil
+	}
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return []attribute.KeyValue{semconv.ClientAddress(tcp.IP.String()), semconv.ClientPort(tcp.Port)}
+	}
+	if s := addr.String(); s != "" {
+		return []attribute.KeyValue{semconv.ClientAddress(s)}
+	}
+	return nil
+}
+
+// setStatus records the gRPC status code of the RPC, see [rpcconv.SetStatus].
+func setStatus(span trace.Span, err error, kind trace.SpanKind) {
+	s, ok := status.FromError(err)
+	if !ok {
+		// gRPC converts context errors returned by handlers to the matching codes.
+		s = status.FromContextError(err)
+	}
+	code := rpcconv.Code(s.Code())
+	rpcconv.SetStatus(span, kind, code, code.String(), err, s.Message())
+}
+
+// metadataCarrier adapts gRPC metadata to the propagators.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// serverStream sets the context of the span in the stream, and records its messages.
+type serverStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	config   *config
+	span     trace.Span
+	sent     int
+	received int
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		s.config.messages.MessageSent(s.span, s.sent, m)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+		s.config.messages.MessageReceived(s.span, s.received, m)
+	}
+	return err
+}
+
+// clientStream records the messages of a stream and ends its span when the stream ends.
+type clientStream struct {
+	grpc.ClientStream
+	desc   *grpc.StreamDesc
+	config *config
+	span   trace.Span
+	// sent and received are only accessed by SendMsg and RecvMsg respectively,
+	// which gRPC allows to call concurrently.
+	sent     int
+	received int
+	stop     func() bool
+	once     sync.Once
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	// Errors are returned again by RecvMsg with the status of the stream.
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		s.config.messages.MessageSent(s.span, s.sent, m)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.received++
+		s.config.messages.MessageReceived(s.span, s.received, m)
+		if !s.desc.ServerStreams {
+			s.finish(nil)
+		}
+	case err == io.EOF:
+		s.finish(nil)
+	default:
+		s.finish(err)
+	}
+	return err
+}
+
+// finish ends the span when the stream ends before its context is canceled.
+func (s *clientStream) finish(err error) {
+	s.stop()
+	s.end(err)
+}
+
+func (s *clientStream) end(err error) {
+	s.once.Do(func() {
+		setStatus(s.span, err, trace.SpanKindClient)
+		s.span.End()
+	})
+}
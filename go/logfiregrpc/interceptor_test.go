@@ -0,0 +1,271 @@
+package logfiregrpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/internal/rpcconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// newTestClient starts a health server and returns a client, both instrumented with opts.
+// Extra server interceptors run inside the instrumented ones.
+func newTestClient(t *testing.T, opts []Option, interceptors ...grpc.UnaryServerInterceptor) (healthpb.HealthClient, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	opts = append([]Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}, opts...)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{UnaryServerInterceptor(opts...)}, interceptors...)...),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(opts...)),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(opts...)),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor(opts...)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn), recorder
+}
+
+// spansByKind returns the server and client spans, which end in an unspecified order.
+func spansByKind(t *testing.T, recorder *tracetest.SpanRecorder) (server, client sdktrace.ReadOnlySpan) {
+	t.Helper()
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for _, span := range spans {
+		switch span.SpanKind() {
+		case trace.SpanKindServer:
+			server = span
+		case trace.SpanKindClient:
+			client = span
+		}
+	}
+	if server == nil || client == nil {
+		t.Fatal("expected a server and a client span")
+	}
+	return server, client
+}
+
+func TestUnary(t *testing.T) {
+	client, recorder := newTestClient(t, nil)
+
+	if _, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	server, clientSpan := spansByKind(t, recorder)
+	if server.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Error("expected the server span to be a child of the client span")
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{server, clientSpan} {
+		if span.Name() != "grpc.health.v1.Health/Check" {
+			t.Errorf("unexpected span name %q", span.Name())
+		}
+		attrs := oteltest.Attributes(span.Attributes())
+		for key, want := range map[attribute.Key]any{
+			"rpc.system.name":          "grpc",
+			"rpc.method":               "grpc.health.v1.Health/Check",
+			"rpc.response.status_code": "OK",
+			logfire.LevelNumKey:        int64(logfire.LevelInfo),
+		} {
+			if attrs[key] != want {
+				t.Errorf("%v span attribute %s = %v, want %v", span.SpanKind(), key, attrs[key], want)
+			}
+		}
+		if span.Status().Code != otelcodes.Unset {
+			t.Errorf("unexpected status %v", span.Status())
+		}
+	}
+	if got := oteltest.Attributes(clientSpan.Attributes())["server.address"]; got != "bufnet" {
+		t.Errorf("server.address = %v", got)
+	}
+}
+
+func TestUnaryClientError(t *testing.T) {
+	client, recorder := newTestClient(t, nil)
+
+	_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	server, clientSpan := spansByKind(t, recorder)
+	for _, span := range []sdktrace.ReadOnlySpan{server, clientSpan} {
+		attrs := oteltest.Attributes(span.Attributes())
+		if attrs["rpc.response.status_code"] != "NOT_FOUND" || attrs[logfire.LevelNumKey] != int64(logfire.LevelWarn) {
+			t.Errorf("unexpected %v span attributes %v", span.SpanKind(), attrs)
+		}
+	}
+	if server.Status().Code != otelcodes.Unset {
+		t.Errorf("expected no error status for the server span, got %v", server.Status())
+	}
+	if clientSpan.Status().Code != otelcodes.Error {
+		t.Errorf("expected an error status for the client span, got %v", clientSpan.Status())
+	}
+}
+
+func TestUnaryServerError(t *testing.T) {
+	failing := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return nil, status.Error(codes.Internal, "database is down")
+	}
+	client, recorder := newTestClient(t, nil, failing)
+
+	if _, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{}); status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal, got %v", err)
+	}
+
+	server, _ := spansByKind(t, recorder)
+	if server.Status().Code != otelcodes.Error || server.Status().Description != "database is down" {
+		t.Errorf("unexpected status %v", server.Status())
+	}
+	if got := oteltest.Attributes(server.Attributes())[logfire.LevelNumKey]; got != int64(logfire.LevelError) {
+		t.Errorf("level = %v", got)
+	}
+	if len(server.Events()) != 1 || server.Events()[0].Name != "exception" {
+		t.Errorf("expected an exception event, got %v", server.Events())
+	}
+}
+
+func TestUnaryServerPanic(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	interceptor := UnaryServerInterceptor(WithTracerProvider(provider))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to be propagated")
+			}
+		}()
+		_, _ = interceptor(t.Context(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}, func(ctx context.Context, req any) (any, error) {
+			panic("boom")
+		})
+	}()
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != otelcodes.Error || span.Status().Description != "boom" {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	if got := oteltest.Attributes(span.Attributes())["rpc.response.status_code"]; got != "INTERNAL" {
+		t.Errorf("rpc.response.status_code = %v", got)
+	}
+	if len(span.Events()) != 1 || span.Events()[0].Name != "exception" {
+		t.Errorf("expected an exception event, got %v", span.Events())
+	}
+}
+
+func TestStream(t *testing.T) {
+	client, recorder := newTestClient(t, []Option{WithMessagePayloads(0)})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("unexpected status %v", resp.Status)
+	}
+	// Watch streams until it's canceled.
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatalf("expected Canceled, got %v", err)
+	}
+	server := waitForServerSpan(t, recorder)
+
+	var clientSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanKind() == trace.SpanKindClient {
+			clientSpan = span
+		}
+	}
+	if clientSpan == nil {
+		t.Fatal("expected the client span to end")
+	}
+	if got := oteltest.Attributes(clientSpan.Attributes())["rpc.response.status_code"]; got != "CANCELLED" {
+		t.Errorf("client rpc.response.status_code = %v", got)
+	}
+	if server.Name() != "grpc.health.v1.Health/Watch" {
+		t.Errorf("unexpected span name %q", server.Name())
+	}
+
+	var messages []map[attribute.Key]any
+	for _, event := range clientSpan.Events() {
+		if event.Name == "message" {
+			messages = append(messages, oteltest.Attributes(event.Attributes))
+		}
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 message events, got %v", messages)
+	}
+	if messages[0][rpcconv.MessageTypeKey] != "SENT" || messages[1][rpcconv.MessageTypeKey] != "RECEIVED" || messages[1][rpcconv.MessageIDKey] != int64(1) {
+		t.Errorf("unexpected message events %v", messages)
+	}
+	if payload, _ := messages[1][rpcconv.MessagePayloadKey].(string); !strings.Contains(payload, "SERVING") {
+		t.Errorf("unexpected payload %q", payload)
+	}
+}
+
+// waitForServerSpan waits for the server span, which ends after the client sees the cancellation.
+func waitForServerSpan(t *testing.T, recorder *tracetest.SpanRecorder) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for range 1000 {
+		for _, span := range recorder.Ended() {
+			if span.SpanKind() == trace.SpanKindServer {
+				return span
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("expected the server span to end")
+	return nil
+}
+
+func TestFilter(t *testing.T) {
+	client, recorder := newTestClient(t, []Option{WithFilter(func(fullMethod string) bool {
+		return !strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/")
+	})})
+
+	if _, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
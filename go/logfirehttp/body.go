@@ -0,0 +1,159 @@
+package logfirehttp
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// defaultMaxBodySize is the default maximum number of bytes of a body that are recorded.
+const defaultMaxBodySize = 8 << 10
+
+// defaultBodyContentTypes are the content types of the bodies recorded by default.
+var defaultBodyContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/xml",
+	"application/x-www-form-urlencoded",
+	"+json",
+	"+xml",
+}
+
+const (
+	requestBodyKey  = attribute.Key("http.request.body.text")
+	responseBodyKey = attribute.Key("http.response.body.text")
+)
+
+// BodyCaptureOptions configures the recording of request and response bodies,
+// see [WithCapturedRequestBody] and [WithCapturedResponseBody].
+type BodyCaptureOptions struct {
+	// MaxSize is the maximum number of bytes recorded, the rest of the body is ignored. Defaults to 8 KiB.
+	MaxSize int
+	// ContentTypes are the media types of the bodies which are recorded. Entries ending in `/` match
+	// any subtype, e.g. `text/`, and entries starting with `+` match a suffix, e.g. `+json`.
+	// Defaults to text, JSON, XML and form bodies.
+	ContentTypes []string
+}
+
+// WithCapturedRequestBody records the start of request bodies as the `http.request.body.text` attribute.
+//
+// Only the part of the body read by the server handler, or sent by the client transport, is recorded.
+// Bodies go through scrubbing like any other attribute: sensitive fields of JSON bodies are redacted.
+func WithCapturedRequestBody(opts BodyCaptureOptions) Option {
+	return func(c *config) {
+		c.requestBody = normalizeBodyCaptureOptions(opts)
+	}
+}
+
+// WithCapturedResponseBody records the start of response bodies as the `http.response.body.text` attribute.
+//
+// For clients, the span then ends when the response body is read to the end or closed,
+// rather than when the response headers are received.
+func WithCapturedResponseBody(opts BodyCaptureOptions) Option {
+	return func(c *config) {
+		c.responseBody = normalizeBodyCaptureOptions(opts)
+	}
+}
+
+func normalizeBodyCaptureOptions(opts BodyCaptureOptions) *BodyCaptureOptions {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultMaxBodySize
+	}
+	if len(opts.ContentTypes) == 0 {
+		opts.ContentTypes = defaultBodyContentTypes
+	}
+	return &opts
+}
+
+// captures reports whether bodies with the given Content-Type header are recorded.
+func (o *BodyCaptureOptions) captures(contentType string) bool {
+	if o == nil || contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range o.ContentTypes {
+		pattern = strings.ToLower(pattern)
+		switch {
+		case strings.HasPrefix(pattern, "+"):
+			if strings.HasSuffix(mediaType, pattern) {
+				return true
+			}
+		case strings.HasSuffix(pattern, "/"):
+			if strings.HasPrefix(mediaType, pattern) {
+				return true
+			}
+		case mediaType == pattern:
+			return true
+		}
+	}
+	return false
+}
+
+// bodyBuffer keeps the start of a body. It may be written and read from different goroutines,
+// e.g. when a transport is still sending the request body after receiving the response.
+type bodyBuffer struct {
+	mu   sync.Mutex
+	data []byte
+	max  int
+}
+
+func newBodyBuffer(max int) *bodyBuffer {
+	return &bodyBuffer{max: max}
+}
+
+func (b *bodyBuffer) write(p []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.max - len(b.data); room > 0 {
+		b.data = append(b.data, p[:min(room, len(p))]...)
+	}
+}
+
+// attribute returns the recorded body, with any invalid UTF-8 such as a truncated character replaced.
+func (b *bodyBuffer) attribute(key attribute.Key) attribute.KeyValue {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return key.String(strings.ToValidUTF8(string(b.data), "�"))
+}
+
+// capturingBody records a body as it's read, calling done once when it's read to the end or closed.
+type capturingBody struct {
+	io.ReadCloser
+	buf  *bodyBuffer
+	once sync.Once
+	done func()
+}
+
+func (b *capturingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.write(p[:n])
+	if err == io.EOF && b.done != nil {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *capturingBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.done != nil {
+		b.once.Do(b.done)
+	}
+	return err
+}
+
+// captureRequestBody starts recording the body of r if it should be, returning the buffer it's recorded in.
+func (c *config) captureRequestBody(r *http.Request) *bodyBuffer {
+	if r.Body == nil || r.Body == http.NoBody || !c.requestBody.captures(r.Header.Get("Content-Type")) {
+		return nil
+	}
+	buf := newBodyBuffer(c.requestBody.MaxSize)
+	r.Body = &capturingBody{ReadCloser: r.Body, buf: buf}
+	return buf
+}
@@ -0,0 +1,113 @@
+package logfirehttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func TestHandlerCapturesBodies(t *testing.T) {
+	provider, recorder := newTestTracerProvider(t)
+	handler := NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = io.WriteString(w, `{"id": 1, "name": "widget"}`)
+	}),
+		WithTracerProvider(provider),
+		WithCapturedRequestBody(BodyCaptureOptions{}),
+		WithCapturedResponseBody(BodyCaptureOptions{MaxSize: 8}),
+	)
+
+	r := httptest.NewRequest("POST", "/items", strings.NewReader(`{"name": "widget"}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	attrs := oteltest.Attributes(recorder.Ended()[0].Attributes())
+	if got := attrs[requestBodyKey]; got != `{"name": "widget"}` {
+		t.Errorf("request body = %q", got)
+	}
+	if got := attrs[responseBodyKey]; got != `{"id": 1` {
+		t.Errorf("expected the response body to be truncated, got %q", got)
+	}
+}
+
+func TestHandlerSkipsBinaryBodies(t *testing.T) {
+	provider, recorder := newTestTracerProvider(t)
+	handler := NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'})
+	}),
+		WithTracerProvider(provider),
+		WithCapturedRequestBody(BodyCaptureOptions{}),
+		WithCapturedResponseBody(BodyCaptureOptions{}),
+	)
+
+	r := httptest.NewRequest("POST", "/upload", strings.NewReader("data"))
+	r.Header.Set("Content-Type", "application/octet-stream")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	attrs := oteltest.Attributes(recorder.Ended()[0].Attributes())
+	if _, ok := attrs[requestBodyKey]; ok {
+		t.Error("expected the binary request body not to be recorded")
+	}
+	if _, ok := attrs[responseBodyKey]; ok {
+		t.Error("expected the image response body not to be recorded")
+	}
+}
+
+func TestTransportCapturesBodies(t *testing.T) {
+	provider, recorder := newTestTracerProvider(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write(append([]byte("echo: "), body...))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewTransport(nil,
+		WithTracerProvider(provider),
+		WithCapturedRequestBody(BodyCaptureOptions{ContentTypes: []string{"text/plain"}}),
+		WithCapturedResponseBody(BodyCaptureOptions{}),
+	)}
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Fatal("expected the span to end once the response body is read")
+	}
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	attrs := oteltest.Attributes(spans[0].Attributes())
+	if attrs[requestBodyKey] != "hello" || attrs[responseBodyKey] != "echo: hello" {
+		t.Errorf("unexpected bodies %q and %q", attrs[requestBodyKey], attrs[responseBodyKey])
+	}
+}
+
+func TestBodyCaptureContentTypes(t *testing.T) {
+	opts := normalizeBodyCaptureOptions(BodyCaptureOptions{})
+	for contentType, want := range map[string]bool{
+		"text/html; charset=utf-8":          true,
+		"application/json":                  true,
+		"application/vnd.api+json":          true,
+		"application/x-www-form-urlencoded": true,
+		"application/octet-stream":          false,
+		"image/png":                         false,
+		"":                                  false,
+	} {
+		if got := opts.captures(contentType); got != want {
+			t.Errorf("captures(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package logfirehttp instruments net/http servers and clients for Pydantic Logfire.
+//
+// [NewHandler] wraps a server handler and [NewTransport] wraps a client transport,
+// creating spans whose names and messages follow the conventions of the Python SDK,
+// e.g. a span named `GET /users/{id}` with the message `GET /users/42`:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("GET /users/{id}", getUser)
+//	log.Fatal(http.ListenAndServe(":8080", logfirehttp.NewHandler(mux)))
+//
+// Spans are sent to the global tracer provider, which is installed by [logfire.Configure]. The handler also
+// records the `http.server.request.duration` and `http.server.active_requests` metrics, so that the rate and
+// latency of requests are known even when traces are sampled.
+package logfirehttp
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirehttp"
+
+// Option configures [NewHandler] and [NewTransport].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider used by [NewHandler] to record the `http.server.request.duration`
+// and `http.server.active_requests` metrics. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to extract and inject the trace context.
+// Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithFilter adds a filter for requests: a request is only traced if all filters return true,
+// e.g. to skip health checks.
+func WithFilter(filter func(*http.Request) bool) Option {
+	return func(c *config) {
+		c.filters = append(c.filters, filter)
+	}
+}
+
+type config struct {
+	tracerProvider  trace.TracerProvider
+	meterProvider   metric.MeterProvider
+	propagators     propagation.TextMapPropagator
+	filters         []func(*http.Request) bool
+	requestHeaders  []string
+	responseHeaders []string
+	requestBody     *BodyCaptureOptions
+	responseBody    *BodyCaptureOptions
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) meter() metric.Meter {
+	return instrumentation.Meter(c.meterProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
+
+func (c *config) traced(r *http.Request) bool {
+	for _, filter := range c.filters {
+		if !filter(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package logfirehttp
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+)
+
+// NewHandler wraps next so that a server span is created for each request.
+//
+// The trace context is extracted from the request headers, so spans continue the traces of
+// instrumented clients. When next is an [http.ServeMux], the matched pattern is recorded
+// as `http.route` and used in the span name.
+func NewHandler(next http.Handler, opts ...Option) http.Handler {
+	return &handler{next: next, config: newConfig(opts)}
+}
+
+type handler struct {
+	next   http.Handler
+	config *config
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.config.traced(r) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	ctx := h.config.propagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	route := httpconv.RouteFromPattern(r.Pattern)
+	ctx, span := h.config.tracer().Start(ctx, httpconv.SpanName(r.Method, route),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(httpconv.ServerRequestAttributes(r, route)...),
+		trace.WithAttributes(h.config.requestHeaderAttributes(r.Header)...),
+	)
+	defer span.End(trace.WithStackTrace(true))
+	metrics := httpconv.StartServerMetrics(ctx, h.config.meter(), r.Method, httpconv.Scheme(r))
+
+	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK, bodyOptions: h.config.responseBody}
+	r = r.WithContext(ctx)
+	requestBody := h.config.captureRequestBody(r)
+	defer func() {
+		if p := recover(); p != nil {
+			span.SetStatus(codes.Error, fmt.Sprint(p))
+			metrics.End(ctx, httpconv.RouteFromPattern(r.Pattern), http.StatusInternalServerError)
+			panic(p)
+		}
+	}()
+	h.next.ServeHTTP(rw, r)
+
+	// The request was copied by WithContext, so this is the pattern set by a ServeMux in next.
+	if pattern := httpconv.RouteFromPattern(r.Pattern); pattern != route {
+		route = pattern
+		span.SetName(httpconv.SpanName(r.Method, route))
+		span.SetAttributes(semconv.HTTPRoute(route))
+	}
+	metrics.End(ctx, route, rw.status)
+	span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+	span.SetAttributes(h.config.responseHeaderAttributes(w.Header())...)
+	if requestBody != nil {
+		span.SetAttributes(requestBody.attribute(requestBodyKey))
+	}
+	if rw.body != nil {
+		span.SetAttributes(rw.body.attribute(responseBodyKey))
+	}
+	if rw.status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, "")
+	}
+}
+
+// responseWriter records the status code and optionally the body written by the handler.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	bodyOptions *BodyCaptureOptions
+	// body is set on the first write if the body is recorded.
+	body      *bodyBuffer
+	wroteBody bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// Informational responses are followed by the final status.
+	if !w.wroteHeader && status >= http.StatusOK {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if !w.wroteBody {
+		w.wroteBody = true
+		contentType := w.Header().Get("Content-Type")
+		if contentType == "" {
+			// The same detection as net/http when the handler doesn't set the header.
+			contentType = http.DetectContentType(b)
+		}
+		if w.bodyOptions.captures(contentType) {
+			w.body = newBodyBuffer(w.bodyOptions.MaxSize)
+		}
+	}
+	n, err := w.ResponseWriter.Write(b)
+	if w.body != nil {
+		w.body.write(b[:n])
+	}
+	return n, err
+}
+
+// Flush implements [http.Flusher] for streaming handlers.
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets [http.ResponseController] access the underlying response writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
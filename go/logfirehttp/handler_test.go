@@ -0,0 +1,202 @@
+package logfirehttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestTracerProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	return provider, recorder
+}
+
+func TestHandler(t *testing.T) {
+	provider, recorder := newTestTracerProvider(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Error("expected the request context to contain the span")
+		}
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := NewHandler(mux, WithTracerProvider(provider))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42?page=2&q=hello%20world", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /users/{id}" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected span kind %v", span.SpanKind())
+	}
+	if span.Status().Code != codes.Unset {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		logfire.MsgKey:              "GET /users/42 ? page='2' & q='hello world'",
+		"http.request.method":       "GET",
+		"http.route":                "/users/{id}",
+		"http.response.status_code": int64(http.StatusTeapot),
+		"url.path":                  "/users/42",
+		"url.query":                 "page=2&q=hello%20world",
+		"server.address":            "example.com",
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+}
+
+func TestHandlerMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	provider, _ := newTestTracerProvider(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	handler := NewHandler(mux, WithTracerProvider(provider), WithMeterProvider(meterProvider))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+	duration := metrics["http.server.request.duration"].(metricdata.Histogram[float64]).DataPoints[0]
+	if duration.Count != 1 {
+		t.Errorf("expected 1 request, got %d", duration.Count)
+	}
+	want := attribute.NewSet(
+		attribute.String("http.request.method", "GET"),
+		attribute.String("url.scheme", "http"),
+		attribute.String("http.route", "/users/{id}"),
+		attribute.Int("http.response.status_code", 503),
+		attribute.String("error.type", "503"),
+	)
+	if !duration.Attributes.Equals(&want) {
+		t.Errorf("unexpected duration attributes %v", duration.Attributes.ToSlice())
+	}
+	active := metrics["http.server.active_requests"].(metricdata.Sum[int64]).DataPoints[0]
+	if active.Value != 0 {
+		t.Errorf("expected no active requests, got %d", active.Value)
+	}
+}
+
+func TestHandlerServerError(t *testing.T) {
+	provider, recorder := newTestTracerProvider(t)
+	handler := NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}), WithTracerProvider(provider))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/items", nil))
+
+	span := recorder.Ended()[0]
+	if span.Name() != "POST" || span.Status().Code != codes.Error {
+		t.Errorf("unexpected span %q with status %v", span.Name(), span.Status())
+	}
+	if msg := oteltest.Attributes(span.Attributes())[logfire.MsgKey]; msg != "POST /items" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestHandlerExtractsTraceContext(t *testing.T) {
+	provider, recorder := newTestTracerProvider(t)
+	handler := NewHandler(http.NotFoundHandler(),
+		WithTracerProvider(provider),
+		WithPropagators(propagation.TraceContext{}),
+	)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	span := recorder.Ended()[0]
+	if got := span.SpanContext().TraceID().String(); got != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("unexpected trace ID %s", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "b7ad6b7169203331" {
+		t.Errorf("unexpected parent span ID %s", got)
+	}
+}
+
+func TestHandlerFilter(t *testing.T) {
+	provider, recorder := newTestTracerProvider(t)
+	handler := NewHandler(http.NotFoundHandler(),
+		WithTracerProvider(provider),
+		WithFilter(func(r *http.Request) bool { return r.URL.Path != "/healthz" }),
+	)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
+
+func TestHandlerPanic(t *testing.T) {
+	provider, recorder := newTestTracerProvider(t)
+	handler := NewHandler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}), WithTracerProvider(provider))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to be propagated")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || len(span.Events()) != 1 {
+		t.Errorf("expected the panic to be recorded, got status %v and events %v", span.Status(), span.Events())
+	}
+}
+
+func TestHandlerCapturesHeaders(t *testing.T) {
+	provider, recorder := newTestTracerProvider(t)
+	handler := NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+	}), WithTracerProvider(provider), WithCapturedRequestHeaders("Accept"), WithCapturedResponseHeaders("Content-Type"))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Add("Accept", "text/html")
+	r.Header.Add("Accept", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	attrs := oteltest.Attributes(recorder.Ended()[0].Attributes())
+	if got := attrs["http.request.header.accept"]; !equalStrings(got, "text/html", "application/json") {
+		t.Errorf("request header = %v", got)
+	}
+	if got := attrs["http.response.header.content-type"]; !equalStrings(got, "application/json") {
+		t.Errorf("response header = %v", got)
+	}
+}
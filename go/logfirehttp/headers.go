@@ -0,0 +1,46 @@
+package logfirehttp
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// WithCapturedRequestHeaders records the values of the given request headers as
+// `http.request.header.<name>` attributes, e.g. `http.request.header.content-type`.
+//
+// Sensitive headers such as `Authorization` and `Cookie` are redacted by the Logfire scrubbing
+// unless it's disabled.
+func WithCapturedRequestHeaders(names ...string) Option {
+	return func(c *config) {
+		c.requestHeaders = append(c.requestHeaders, names...)
+	}
+}
+
+// WithCapturedResponseHeaders records the values of the given response headers as
+// `http.response.header.<name>` attributes, e.g. `http.response.header.content-type`.
+func WithCapturedResponseHeaders(names ...string) Option {
+	return func(c *config) {
+		c.responseHeaders = append(c.responseHeaders, names...)
+	}
+}
+
+func (c *config) requestHeaderAttributes(h http.Header) []attribute.KeyValue {
+	return headerAttributes(c.requestHeaders, h, semconv.HTTPRequestHeader)
+}
+
+func (c *config) responseHeaderAttributes(h http.Header) []attribute.KeyValue {
+	return headerAttributes(c.responseHeaders, h, semconv.HTTPResponseHeader)
+}
+
+func headerAttributes(names []string, h http.Header, attr func(string, ...string) attribute.KeyValue) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, name := range names {
+		if values := h.Values(name); len(values) > 0 {
+			attrs = append(attrs, attr(strings.ToLower(name), values...))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,77 @@
+package logfirehttp
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+)
+
+// NewTransport wraps base so that a client span is created for each request,
+// and the trace context is injected into the request headers.
+// If base is nil, [http.DefaultTransport] is used.
+//
+//	client := &http.Client{Transport: logfirehttp.NewTransport(nil)}
+func NewTransport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, config: newConfig(opts)}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	config *config
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !t.config.traced(r) {
+		return t.base.RoundTrip(r)
+	}
+
+	ctx, span := t.config.tracer().Start(r.Context(), r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(httpconv.ClientRequestAttributes(r)...),
+		trace.WithAttributes(t.config.requestHeaderAttributes(r.Header)...),
+	)
+
+	// RoundTrippers must not modify the request.
+	r = r.Clone(ctx)
+	t.config.propagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+	requestBody := t.config.captureRequestBody(r)
+
+	resp, err := t.base.RoundTrip(r)
+	if requestBody != nil {
+		span.SetAttributes(requestBody.attribute(requestBodyKey))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return resp, err
+	}
+	span.SetAttributes(
+		semconv.HTTPResponseStatusCode(resp.StatusCode),
+		semconv.NetworkProtocolVersion(strconv.Itoa(resp.ProtoMajor)+"."+strconv.Itoa(resp.ProtoMinor)),
+	)
+	span.SetAttributes(t.config.responseHeaderAttributes(resp.Header)...)
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, "")
+	}
+
+	if resp.Body != nil && resp.Body != http.NoBody && t.config.responseBody.captures(resp.Header.Get("Content-Type")) {
+		buf := newBodyBuffer(t.config.responseBody.MaxSize)
+		resp.Body = &capturingBody{ReadCloser: resp.Body, buf: buf, done: func() {
+			span.SetAttributes(buf.attribute(responseBodyKey))
+			span.End()
+		}}
+		return resp, nil
+	}
+	span.End()
+	return resp, nil
+}
@@ -0,0 +1,46 @@
+package logfirekgo
+
+import (
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// RecordCarrier carries the trace context in the headers of a record.
+type RecordCarrier struct {
+	record *kgo.Record
+}
+
+var _ propagation.TextMapCarrier = RecordCarrier{}
+
+// NewRecordCarrier returns a carrier for the headers of a record.
+func NewRecordCarrier(record *kgo.Record) RecordCarrier {
+	return RecordCarrier{record: record}
+}
+
+func (c RecordCarrier) Get(key string) string {
+	for _, h := range c.record.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces the header with the key, so that injecting twice doesn't duplicate headers.
+func (c RecordCarrier) Set(key, value string) {
+	for i, h := range c.record.Headers {
+		if h.Key == key {
+			c.record.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.record.Headers = append(c.record.Headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+}
+
+func (c RecordCarrier) Keys() []string {
+	keys := make([]string, len(c.record.Headers))
+	for i, h := range c.record.Headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
@@ -0,0 +1,85 @@
+// Package logfirekgo instruments franz-go Kafka clients for Pydantic Logfire.
+//
+// [NewHooks] returns hooks creating a producer span for each record produced, named like `send orders`,
+// and a consumer span for each record fetched, named like `receive orders`, with the trace context
+// propagated in the headers of records:
+//
+//	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.WithHooks(logfirekgo.NewHooks()))
+//
+// The context of the span of a fetched record is set as its Context, so that the work done for it
+// continues the trace of the producer. [Hooks.StartProcessSpan] creates a span for that work.
+package logfirekgo
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirekgo"
+
+// Option configures [NewHooks].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject and extract the trace context
+// in the headers of records. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithConsumerGroup records the consumer group of the client on the spans of fetched records.
+func WithConsumerGroup(group string) Option {
+	return func(c *config) {
+		c.consumerGroup = group
+	}
+}
+
+// WithoutConnectSpans disables the spans of connections to brokers.
+func WithoutConnectSpans() Option {
+	return func(c *config) {
+		c.noConnectSpans = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	attrs          []attribute.KeyValue
+	consumerGroup  string
+	noConnectSpans bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
@@ -0,0 +1,175 @@
+package logfirekgo
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/msgconv"
+)
+
+// Hooks creates spans for the records produced and fetched by a client, and its connections to brokers.
+type Hooks struct {
+	config *config
+}
+
+var (
+	_ kgo.HookProduceRecordBuffered   = (*Hooks)(nil)
+	_ kgo.HookProduceRecordUnbuffered = (*Hooks)(nil)
+	_ kgo.HookFetchRecordBuffered     = (*Hooks)(nil)
+	_ kgo.HookFetchRecordUnbuffered   = (*Hooks)(nil)
+	_ kgo.HookBrokerConnect           = (*Hooks)(nil)
+)
+
+// NewHooks returns the hooks of a client, to register with [kgo.WithHooks].
+func NewHooks(opts ...Option) *Hooks {
+	return &Hooks{config: newConfig(opts)}
+}
+
+// spanKey is the context key of the span of a record, so that it's only ended by the hook which started it.
+type spanKey struct{}
+
+// OnProduceRecordBuffered starts the span of a record to produce, as a child of the context passed
+// to Produce, and injects its context in the headers of the record.
+func (h *Hooks) OnProduceRecordBuffered(r *kgo.Record) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attrs := msgconv.Attributes(semconv.MessagingSystemKafka, semconv.MessagingOperationTypeSend, "send", r.Topic)
+	attrs = append(attrs, recordAttributes(r)...)
+	ctx, span := h.config.tracer().Start(ctx, msgconv.SpanName("send", r.Topic),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(h.config.attrs...),
+	)
+	h.config.propagator().Inject(ctx, NewRecordCarrier(r))
+	r.Context = context.WithValue(ctx, spanKey{}, span)
+}
+
+// OnProduceRecordUnbuffered ends the span of a produced record, with the partition and offset it was written to.
+func (h *Hooks) OnProduceRecordUnbuffered(r *kgo.Record, err error) {
+	span := recordSpan(r)
+	if span == nil {
+		return
+	}
+	defer span.End()
+	if err != nil {
+		msgconv.RecordError(span, err)
+		return
+	}
+	span.SetAttributes(
+		semconv.MessagingDestinationPartitionID(strconv.Itoa(int(r.Partition))),
+		semconv.MessagingKafkaOffset(int(r.Offset)),
+	)
+}
+
+// OnFetchRecordBuffered starts the span of a fetched record, continuing the trace of the producer,
+// and sets its context as the Context of the record.
+func (h *Hooks) OnFetchRecordBuffered(r *kgo.Record) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = h.config.propagator().Extract(ctx, NewRecordCarrier(r))
+	attrs := msgconv.Attributes(semconv.MessagingSystemKafka, semconv.MessagingOperationTypeReceive, "receive", r.Topic)
+	attrs = append(attrs, h.consumerAttributes(r)...)
+	ctx, span := h.config.tracer().Start(ctx, msgconv.SpanName("receive", r.Topic),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(h.config.attrs...),
+	)
+	r.Context = context.WithValue(ctx, spanKey{}, span)
+}
+
+// OnFetchRecordUnbuffered ends the span of a fetched record once it's polled, or discarded
+// e.g. when its partition is revoked.
+func (h *Hooks) OnFetchRecordUnbuffered(r *kgo.Record, polled bool) {
+	span := recordSpan(r)
+	if span == nil {
+		return
+	}
+	if !polled {
+		span.SetAttributes(discardedKey.Bool(true))
+	}
+	span.End()
+}
+
+// StartProcessSpan starts the span of the work done for a fetched record, named like `process orders`,
+// as a child of the span of the record. End the span once the record is processed.
+func (h *Hooks) StartProcessSpan(r *kgo.Record) (context.Context, trace.Span) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = h.config.propagator().Extract(context.Background(), NewRecordCarrier(r))
+	}
+	attrs := msgconv.Attributes(semconv.MessagingSystemKafka, semconv.MessagingOperationTypeProcess, "process", r.Topic)
+	attrs = append(attrs, h.consumerAttributes(r)...)
+	return h.config.tracer().Start(ctx, msgconv.SpanName("process", r.Topic),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(h.config.attrs...),
+	)
+}
+
+// OnBrokerConnect creates the span of a connection to a broker, which happens in the background
+// so the span is the root of its trace.
+func (h *Hooks) OnBrokerConnect(meta kgo.BrokerMetadata, initDur time.Duration, _ net.Conn, err error) {
+	if h.config.noConnectSpans {
+		return
+	}
+	end := time.Now()
+	_, span := h.config.tracer().Start(context.Background(), "connect",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(end.Add(-initDur)),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.ServerAddress(meta.Host),
+			semconv.ServerPort(int(meta.Port)),
+			brokerNodeIDKey.Int(int(meta.NodeID)),
+		),
+		trace.WithAttributes(h.config.attrs...),
+	)
+	msgconv.RecordError(span, err)
+	span.End(trace.WithTimestamp(end))
+}
+
+const (
+	// discardedKey records that a fetched record was discarded rather than polled.
+	discardedKey = attribute.Key("messaging.kafka.record.discarded")
+	// brokerNodeIDKey records the node ID of a broker, which is negative for seed brokers.
+	brokerNodeIDKey = attribute.Key("messaging.kafka.broker.node_id")
+)
+
+func (h *Hooks) consumerAttributes(r *kgo.Record) []attribute.KeyValue {
+	attrs := append(recordAttributes(r),
+		semconv.MessagingDestinationPartitionID(strconv.Itoa(int(r.Partition))),
+		semconv.MessagingKafkaOffset(int(r.Offset)),
+	)
+	if h.config.consumerGroup != "" {
+		attrs = append(attrs, semconv.MessagingConsumerGroupName(h.config.consumerGroup))
+	}
+	return attrs
+}
+
+func recordAttributes(r *kgo.Record) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.MessagingMessageBodySize(len(r.Value))}
+	if r.Value == nil && r.Key != nil {
+		attrs = append(attrs, semconv.MessagingKafkaMessageTombstone(true))
+	}
+	return attrs
+}
+
+// recordSpan returns the span started by the hooks for a record, if any.
+func recordSpan(r *kgo.Record) trace.Span {
+	if r.Context == nil {
+		return nil
+	}
+	span, _ := r.Context.Value(spanKey{}).(trace.Span)
+	return span
+}
@@ -0,0 +1,183 @@
+package logfirekgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestHooks(t *testing.T, opts ...Option) (*Hooks, *sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	opts = append([]Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}, opts...)
+	return NewHooks(opts...), provider, recorder
+}
+
+func TestProduce(t *testing.T) {
+	hooks, provider, recorder := newTestHooks(t)
+
+	parent, parentSpan := provider.Tracer("test").Start(t.Context(), "parent")
+	r := &kgo.Record{Topic: "orders", Value: []byte("hello"), Context: parent}
+	hooks.OnProduceRecordBuffered(r)
+	if len(r.Headers) != 1 || r.Headers[0].Key != "traceparent" {
+		t.Fatalf("expected the trace context in the headers, got %v", r.Headers)
+	}
+	r.Partition, r.Offset = 3, 42
+	hooks.OnProduceRecordUnbuffered(r, nil)
+	parentSpan.End()
+
+	span := recorder.Ended()[0]
+	if span.Name() != "send orders" || span.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	if span.Parent().SpanID() != parentSpan.SpanContext().SpanID() {
+		t.Error("expected the span to be a child of the context passed to Produce")
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:                       "send orders",
+		"messaging.system":                   "kafka",
+		"messaging.operation.type":           "send",
+		"messaging.destination.name":         "orders",
+		"messaging.destination.partition.id": "3",
+		"messaging.message.body.size":        int64(5),
+		"messaging.kafka.offset":             int64(42),
+	})
+
+	// Retrying a record replaces its trace context rather than adding another header.
+	hooks.OnProduceRecordBuffered(r)
+	if len(r.Headers) != 1 {
+		t.Errorf("expected 1 header, got %v", r.Headers)
+	}
+}
+
+func TestProduceError(t *testing.T) {
+	hooks, _, recorder := newTestHooks(t)
+
+	r := &kgo.Record{Topic: "orders", Key: []byte("k")}
+	hooks.OnProduceRecordBuffered(r)
+	hooks.OnProduceRecordUnbuffered(r, errors.New("broker unavailable"))
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "broker unavailable" {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"messaging.kafka.message.tombstone": true,
+	})
+	if _, ok := oteltest.Attributes(span.Attributes())["messaging.kafka.offset"]; ok {
+		t.Error("expected no offset for a failed record")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	hooks, provider, recorder := newTestHooks(t, WithConsumerGroup("billing"))
+
+	producer, producerSpan := provider.Tracer("test").Start(t.Context(), "producer")
+	producerSpan.End()
+	r := &kgo.Record{Topic: "orders", Partition: 1, Offset: 7, Value: []byte("hi"), Context: context.Background()}
+	propagation.TraceContext{}.Inject(producer, NewRecordCarrier(r))
+
+	hooks.OnFetchRecordBuffered(r)
+	hooks.OnFetchRecordUnbuffered(r, true)
+	_, processSpan := hooks.StartProcessSpan(r)
+	processSpan.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	receive, process := spans[1], spans[2]
+	if receive.Name() != "receive orders" || receive.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("unexpected span %q of kind %v", receive.Name(), receive.SpanKind())
+	}
+	if receive.Parent().SpanID() != producerSpan.SpanContext().SpanID() {
+		t.Error("expected the span to continue the trace of the producer")
+	}
+	oteltest.CheckAttributes(t, receive.Attributes(), map[attribute.Key]any{
+		"messaging.operation.type":           "receive",
+		"messaging.destination.partition.id": "1",
+		"messaging.kafka.offset":             int64(7),
+		"messaging.consumer.group.name":      "billing",
+	})
+	if process.Name() != "process orders" || process.Parent().SpanID() != receive.SpanContext().SpanID() {
+		t.Errorf("unexpected process span %q", process.Name())
+	}
+	oteltest.CheckAttributes(t, process.Attributes(), map[attribute.Key]any{
+		"messaging.operation.type": "process",
+	})
+}
+
+func TestFetchDiscarded(t *testing.T) {
+	hooks, _, recorder := newTestHooks(t)
+
+	r := &kgo.Record{Topic: "orders"}
+	hooks.OnFetchRecordBuffered(r)
+	hooks.OnFetchRecordUnbuffered(r, false)
+
+	oteltest.CheckAttributes(t, recorder.Ended()[0].Attributes(), map[attribute.Key]any{
+		"messaging.kafka.record.discarded": true,
+	})
+}
+
+func TestUnbufferedWithoutSpan(t *testing.T) {
+	hooks, _, recorder := newTestHooks(t)
+
+	hooks.OnProduceRecordUnbuffered(&kgo.Record{Context: t.Context()}, nil)
+	hooks.OnFetchRecordUnbuffered(&kgo.Record{}, true)
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
+
+func TestBrokerConnect(t *testing.T) {
+	hooks, _, recorder := newTestHooks(t)
+
+	meta := kgo.BrokerMetadata{NodeID: 2, Host: "kafka-2", Port: 9092}
+	hooks.OnBrokerConnect(meta, 50*time.Millisecond, nil, nil)
+	hooks.OnBrokerConnect(meta, time.Millisecond, nil, errors.New("connection refused"))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "connect" || span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	if d := span.EndTime().Sub(span.StartTime()); d != 50*time.Millisecond {
+		t.Errorf("duration = %v, want 50ms", d)
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"server.address":                 "kafka-2",
+		"server.port":                    int64(9092),
+		"messaging.kafka.broker.node_id": int64(2),
+	})
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("unexpected status %v", spans[1].Status())
+	}
+}
+
+func TestWithoutConnectSpans(t *testing.T) {
+	hooks, _, recorder := newTestHooks(t, WithoutConnectSpans())
+
+	hooks.OnBrokerConnect(kgo.BrokerMetadata{Host: "kafka"}, 0, nil, nil)
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
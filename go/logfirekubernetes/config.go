@@ -0,0 +1,83 @@
+// Package logfirekubernetes instruments Kubernetes client-go for Pydantic Logfire.
+//
+// [WrapTransport] creates a span for each call to the API server, named after its verb and
+// resource like `list pods`, with the namespace, name and API group of the object and the
+// status code of the response. Wrap the transport of a rest.Config before creating clients:
+//
+//	config, err := rest.InClusterConfig()
+//	if err != nil {
+//		return err
+//	}
+//	config.Wrap(logfirekubernetes.WrapTransport())
+//	clientset, err := kubernetes.NewForConfig(config)
+//
+// [EventHandler] optionally traces the notifications of informers, including their resyncs:
+//
+//	informer.AddEventHandler(logfirekubernetes.EventHandler("pods", handler))
+package logfirekubernetes
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirekubernetes"
+
+// Option configures [WrapTransport] and [EventHandler].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject the trace context in requests.
+// Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithoutWatchSpans skips the watch requests, which informers and reflectors renew every few minutes.
+func WithoutWatchSpans() Option {
+	return func(c *config) {
+		c.noWatch = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	attrs          []attribute.KeyValue
+	noWatch        bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
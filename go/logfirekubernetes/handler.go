@@ -0,0 +1,97 @@
+package logfirekubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// eventKey records the kind of an informer notification: `add`, `update`, `resync` or `delete`.
+	eventKey = attribute.Key("k8s.informer.event")
+	// initialListKey records whether an added object comes from the initial list of the informer.
+	initialListKey = attribute.Key("k8s.informer.initial_list")
+	// resourceVersionKey records the resource version of the object of a notification.
+	resourceVersionKey = attribute.Key("k8s.object.resource_version")
+)
+
+// EventHandler wraps the handler of the notifications of an informer of resource, e.g. `pods`,
+// so that a span is created for each notification, named like `informer update pods`.
+//
+// Updates where the object keeps its resource version are the periodic resyncs of the informer,
+// named like `informer resync pods`. Handlers aren't given a context, so the spans they
+// create aren't children of the span of the notification.
+func EventHandler(resource string, handler cache.ResourceEventHandler, opts ...Option) cache.ResourceEventHandler {
+	return &eventHandler{resource: resource, handler: handler, config: newConfig(opts)}
+}
+
+type eventHandler struct {
+	resource string
+	handler  cache.ResourceEventHandler
+	config   *config
+}
+
+func (h *eventHandler) OnAdd(obj any, isInInitialList bool) {
+	h.notify("add", obj, func() { h.handler.OnAdd(obj, isInInitialList) }, initialListKey.Bool(isInInitialList))
+}
+
+func (h *eventHandler) OnUpdate(oldObj, newObj any) {
+	event := "update"
+	if resourceVersion(oldObj) == resourceVersion(newObj) {
+		event = "resync"
+	}
+	h.notify(event, newObj, func() { h.handler.OnUpdate(oldObj, newObj) })
+}
+
+func (h *eventHandler) OnDelete(obj any) {
+	h.notify("delete", obj, func() { h.handler.OnDelete(obj) })
+}
+
+func (h *eventHandler) notify(event string, obj any, handle func(), attrs ...attribute.KeyValue) {
+	name := "informer " + event + " " + h.resource
+	attrs = append(attrs,
+		logfire.MsgKey.String(name),
+		eventKey.String(event),
+		resourceKey.String(h.resource),
+	)
+	if deleted, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = deleted.Obj
+	}
+	if object, err := meta.Accessor(obj); err == nil {
+		if namespace := object.GetNamespace(); namespace != "" {
+			attrs = append(attrs, semconv.K8SNamespaceName(namespace))
+		}
+		attrs = append(attrs,
+			objectNameKey.String(object.GetName()),
+			resourceVersionKey.String(object.GetResourceVersion()),
+		)
+	}
+	_, span := h.config.tracer().Start(context.Background(), name,
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(h.config.attrs...),
+	)
+	defer span.End(trace.WithStackTrace(true))
+	defer func() {
+		if p := recover(); p != nil {
+			span.SetStatus(codes.Error, fmt.Sprint(p))
+			panic(p)
+		}
+	}()
+	handle()
+}
+
+func resourceVersion(obj any) string {
+	object, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return object.GetResourceVersion()
+}
@@ -0,0 +1,63 @@
+package logfirekubernetes
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func pod(resourceVersion string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", ResourceVersion: resourceVersion}}
+}
+
+func TestEventHandler(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	var notifications []string
+	handler := EventHandler("pods", cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { notifications = append(notifications, "add") },
+		UpdateFunc: func(any, any) { notifications = append(notifications, "update") },
+		DeleteFunc: func(any) { notifications = append(notifications, "delete") },
+	}, WithTracerProvider(provider))
+
+	handler.OnAdd(pod("1"), true)
+	handler.OnUpdate(pod("1"), pod("2"))
+	handler.OnUpdate(pod("2"), pod("2"))
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/nginx", Obj: pod("2")})
+
+	if len(notifications) != 4 {
+		t.Errorf("notifications = %v, want all of them handled", notifications)
+	}
+	spans := recorder.Ended()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	want := []string{"informer add pods", "informer update pods", "informer resync pods", "informer delete pods"}
+	if len(names) != len(want) {
+		t.Fatalf("names = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("names = %v, want %v", names, want)
+		}
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		"k8s.informer.event":          "add",
+		"k8s.informer.initial_list":   true,
+		"k8s.namespace.name":          "default",
+		"k8s.object.name":             "nginx",
+		"k8s.object.resource_version": "1",
+	})
+	oteltest.CheckAttributes(t, spans[3].Attributes(), map[attribute.Key]any{
+		"k8s.object.name":             "nginx",
+		"k8s.object.resource_version": "2",
+	})
+}
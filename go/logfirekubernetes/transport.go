@@ -0,0 +1,199 @@
+package logfirekubernetes
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// verbKey records the Kubernetes verb of a call, e.g. `list` or `patch`.
+	verbKey = attribute.Key("k8s.api.verb")
+	// groupKey records the API group of the resource, which is empty for the core group.
+	groupKey = attribute.Key("k8s.api.group")
+	// versionKey records the API version of the resource, e.g. `v1`.
+	versionKey = attribute.Key("k8s.api.version")
+	// resourceKey records the resource of a call, e.g. `pods`.
+	resourceKey = attribute.Key("k8s.api.resource")
+	// subresourceKey records the subresource of a call, e.g. `status` or `log`.
+	subresourceKey = attribute.Key("k8s.api.subresource")
+	// objectNameKey records the name of the object of a call.
+	objectNameKey = attribute.Key("k8s.object.name")
+)
+
+// WrapTransport returns a wrapper of the transport of a rest.Config creating a span for each call
+// to the API server, to pass to its Wrap method.
+//
+// Watch spans end once the server accepts the watch, rather than when it's closed.
+func WrapTransport(opts ...Option) func(http.RoundTripper) http.RoundTripper {
+	c := newConfig(opts)
+	return func(base http.RoundTripper) http.RoundTripper {
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		return &transport{base: base, config: c}
+	}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	config *config
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	info := parseRequest(r)
+	if info.verb == "watch" && t.config.noWatch {
+		return t.base.RoundTrip(r)
+	}
+
+	name := info.spanName()
+	attrs := httpconv.ClientRequestAttributes(r)
+	for i, kv := range attrs {
+		if kv.Key == logfire.MsgKey {
+			attrs[i] = logfire.MsgKey.String(info.message())
+		}
+	}
+	attrs = append(attrs, info.attributes()...)
+	ctx, span := t.config.tracer().Start(r.Context(), name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(t.config.attrs...),
+	)
+	defer span.End()
+
+	// RoundTrippers must not modify the request.
+	r = r.Clone(ctx)
+	t.config.propagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	// Controllers routinely get not found and conflict responses, which are handled by retrying.
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		span.SetAttributes(logfire.LevelNumKey.Int(int(logfire.LevelError)))
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	case resp.StatusCode >= http.StatusBadRequest:
+		span.SetAttributes(logfire.LevelNumKey.Int(int(logfire.LevelWarn)))
+	}
+	return resp, nil
+}
+
+// requestInfo is what an API server call is about, parsed like the API server does from its path.
+type requestInfo struct {
+	verb        string
+	path        string
+	group       string
+	version     string
+	namespace   string
+	resource    string
+	subresource string
+	name        string
+}
+
+// parseRequest parses the paths of resources, like `/api/v1/namespaces/default/pods/nginx/log`
+// or `/apis/apps/v1/deployments`. Other paths, like `/version`, only have a verb and a path.
+func parseRequest(r *http.Request) requestInfo {
+	info := requestInfo{verb: strings.ToLower(r.Method), path: r.URL.Path}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		info.version, parts = parts[1], parts[2:]
+	case len(parts) >= 4 && parts[0] == "apis":
+		info.group, info.version, parts = parts[1], parts[2], parts[3:]
+	default:
+		return info
+	}
+	if parts[0] == "namespaces" && len(parts) >= 3 {
+		info.namespace, parts = parts[1], parts[2:]
+	}
+	info.resource = parts[0]
+	if len(parts) >= 2 {
+		info.name = parts[1]
+	}
+	if len(parts) >= 3 {
+		info.subresource = strings.Join(parts[2:], "/")
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		switch {
+		case r.URL.Query().Get("watch") == "true" || r.URL.Query().Get("watch") == "1":
+			info.verb = "watch"
+		case info.name == "":
+			info.verb = "list"
+		default:
+			info.verb = "get"
+		}
+	case http.MethodPost:
+		info.verb = "create"
+	case http.MethodPut:
+		info.verb = "update"
+	case http.MethodPatch:
+		info.verb = "patch"
+	case http.MethodDelete:
+		if info.name == "" {
+			info.verb = "deletecollection"
+		}
+	}
+	return info
+}
+
+// spanName returns the low-cardinality name of the span of a call, e.g. `get pods/log`.
+func (info requestInfo) spanName() string {
+	if info.resource == "" {
+		return info.verb + " " + info.path
+	}
+	if info.subresource != "" {
+		return info.verb + " " + info.resource + "/" + info.subresource
+	}
+	return info.verb + " " + info.resource
+}
+
+// message returns the message of the span of a call, e.g. `get pods default/nginx`.
+func (info requestInfo) message() string {
+	msg := info.spanName()
+	switch {
+	case info.namespace != "" && info.name != "":
+		msg += " " + info.namespace + "/" + info.name
+	case info.name != "":
+		msg += " " + info.name
+	case info.namespace != "":
+		msg += " in " + info.namespace
+	}
+	return msg
+}
+
+func (info requestInfo) attributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{verbKey.String(info.verb)}
+	if info.resource == "" {
+		return attrs
+	}
+	attrs = append(attrs,
+		groupKey.String(info.group),
+		versionKey.String(info.version),
+		resourceKey.String(info.resource),
+	)
+	if info.subresource != "" {
+		attrs = append(attrs, subresourceKey.String(info.subresource))
+	}
+	if info.namespace != "" {
+		attrs = append(attrs, semconv.K8SNamespaceName(info.namespace))
+	}
+	if info.name != "" {
+		attrs = append(attrs, objectNameKey.String(info.name))
+	}
+	return attrs
+}
@@ -0,0 +1,126 @@
+package logfirekubernetes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newClientset(t *testing.T, handler http.HandlerFunc, opts ...Option) (*kubernetes.Clientset, *tracetest.SpanRecorder) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	config := &rest.Config{Host: server.URL}
+	config.Wrap(WrapTransport(append(opts, WithTracerProvider(provider), WithPropagators(propagation.TraceContext{}))...))
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientset, recorder
+}
+
+func TestWrapTransport(t *testing.T) {
+	clientset, recorder := newClientset(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Traceparent") == "" {
+			t.Error("no trace context in the request")
+		}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/default/pods/nginx":
+			w.Write([]byte(`{"kind": "Pod", "apiVersion": "v1", "metadata": {"name": "nginx", "namespace": "default"}}`))
+		case "/apis/apps/v1/namespaces/default/deployments":
+			w.Write([]byte(`{"kind": "DeploymentList", "apiVersion": "apps/v1", "items": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404}`))
+		}
+	})
+	ctx := context.Background()
+	if _, err := clientset.CoreV1().Pods("default").Get(ctx, "nginx", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clientset.AppsV1().Deployments("default").List(ctx, metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := clientset.CoreV1().Nodes().Delete(ctx, "missing", metav1.DeleteOptions{}); err == nil {
+		t.Fatal("deleted a missing node")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	get, list, del := spans[0], spans[1], spans[2]
+	if get.Name() != "get pods" || list.Name() != "list deployments" || del.Name() != "delete nodes" {
+		t.Errorf("names = %s, %s, %s", get.Name(), list.Name(), del.Name())
+	}
+	if get.SpanKind() != trace.SpanKindClient {
+		t.Errorf("kind = %v, want client", get.SpanKind())
+	}
+	oteltest.CheckAttributes(t, get.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:              "get pods default/nginx",
+		"k8s.api.verb":              "get",
+		"k8s.api.group":             "",
+		"k8s.api.version":           "v1",
+		"k8s.api.resource":          "pods",
+		"k8s.namespace.name":        "default",
+		"k8s.object.name":           "nginx",
+		"http.request.method":       "GET",
+		"http.response.status_code": int64(200),
+	})
+	oteltest.CheckAttributes(t, list.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:     "list deployments in default",
+		"k8s.api.group":    "apps",
+		"k8s.api.resource": "deployments",
+	})
+	oteltest.CheckAttributes(t, del.Attributes(), map[attribute.Key]any{
+		"k8s.namespace.name":        nil,
+		"k8s.object.name":           "missing",
+		"http.response.status_code": int64(404),
+		logfire.LevelNumKey:         int64(logfire.LevelWarn),
+	})
+	if del.Status().Code == codes.Error {
+		t.Errorf("status = %v, want no error for a not found response", del.Status())
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	for _, tt := range []struct {
+		method, url string
+		want        requestInfo
+		name        string
+	}{
+		{"GET", "/api/v1/namespaces/default/pods/nginx/log", requestInfo{verb: "get", version: "v1", namespace: "default", resource: "pods", name: "nginx", subresource: "log"}, "get pods/log"},
+		{"GET", "/api/v1/pods?watch=true", requestInfo{verb: "watch", version: "v1", resource: "pods"}, "watch pods"},
+		{"GET", "/api/v1/namespaces/kube-system", requestInfo{verb: "get", version: "v1", resource: "namespaces", name: "kube-system"}, "get namespaces"},
+		{"PUT", "/apis/apps/v1/namespaces/default/deployments/web/status", requestInfo{verb: "update", group: "apps", version: "v1", namespace: "default", resource: "deployments", name: "web", subresource: "status"}, "update deployments/status"},
+		{"DELETE", "/apis/batch/v1/namespaces/default/jobs", requestInfo{verb: "deletecollection", group: "batch", version: "v1", namespace: "default", resource: "jobs"}, "deletecollection jobs"},
+		{"GET", "/version", requestInfo{verb: "get"}, "get /version"},
+	} {
+		r := httptest.NewRequest(tt.method, tt.url, nil)
+		got := parseRequest(r)
+		tt.want.path = r.URL.Path
+		if got != tt.want {
+			t.Errorf("parseRequest(%s %s) = %+v, want %+v", tt.method, tt.url, got, tt.want)
+		}
+		if got.spanName() != tt.name {
+			t.Errorf("spanName(%s %s) = %s, want %s", tt.method, tt.url, got.spanName(), tt.name)
+		}
+	}
+}
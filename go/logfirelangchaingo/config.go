@@ -0,0 +1,113 @@
+// Package logfirelangchaingo instruments LangChainGo applications for Pydantic Logfire.
+//
+// [NewHandler] returns a callbacks handler converting the events of chains, LLMs, tools and
+// retrievers into nested spans: a chain is the parent of the LLM calls and tool executions it
+// makes, LLM calls have the gen_ai.* semantic convention attributes, and the actions of agents
+// name the tools they execute. Set it as the callbacks handler of the LLMs, chains, agents and
+// tools to trace:
+//
+//	handler := logfirelangchaingo.NewHandler()
+//	llm, err := openai.New(openai.WithCallback(handler))
+//	chain := chains.NewLLMChain(llm, prompt)
+//	chain.CallbacksHandler = handler
+//
+// The inputs and outputs of chains, tools and retrievers, and the messages of LLM calls, are
+// only recorded with [WithMessageContent].
+package logfirelangchaingo
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirelangchaingo"
+
+// Option configures [NewHandler].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithMessageContent records the messages of LLM calls as the `gen_ai.input.messages` and
+// `gen_ai.output.messages` attributes, and the inputs and outputs of chains, tools and retrievers.
+// They still go through the scrubbing of Logfire.
+func WithMessageContent() Option {
+	return func(c *config) {
+		c.content = true
+	}
+}
+
+// WithStreamProgress records the progress of streamed responses on their spans: the time to the first
+// chunk of the output as the `gen_ai.response.time_to_first_chunk` attribute, the number of chunks as
+// `gen_ai.response.chunk_count`, and a `gen_ai.stream.progress` event at most every interval with the
+// number of chunks so far and, with [WithMessageContent], the partial output. There are no events if
+// interval is zero.
+func WithStreamProgress(interval time.Duration) Option {
+	return func(c *config) {
+		c.progress = &genaiconv.Progress{Interval: interval}
+	}
+}
+
+// WithMeterProvider sets the meter provider used to record the `gen_ai.client.token.usage`,
+// `gen_ai.client.operation.duration` and `operation.cost` metrics. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// Price is the price of a model in USD per million tokens. The prices of cached tokens default to
+// the price of input tokens when they're zero.
+type Price = genaiconv.Price
+
+// WithPricing sets the prices of models by name, to record the cost of calls as the `operation.cost`
+// attribute and metric. The price of a model is the one of its longest prefix, so that `gpt-4o`
+// prices `gpt-4o-2024-08-06`.
+func WithPricing(prices map[string]Price) Option {
+	return func(c *config) {
+		c.pricing = prices
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	content        bool
+	progress       *genaiconv.Progress
+	meterProvider  metric.MeterProvider
+	pricing        map[string]Price
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) metrics() *genaiconv.Metrics {
+	return &genaiconv.Metrics{Meter: instrumentation.Meter(c.meterProvider, instrumentationName), Pricing: c.pricing}
+}
@@ -0,0 +1,464 @@
+package logfirelangchaingo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// chainInputsKey records the inputs of a chain as JSON, when content is recorded.
+	chainInputsKey = attribute.Key("langchaingo.chain.inputs")
+	// chainOutputsKey records the outputs of a chain as JSON, when content is recorded.
+	chainOutputsKey = attribute.Key("langchaingo.chain.outputs")
+	// retrieverQueryKey records the query of a retriever, when content is recorded.
+	retrieverQueryKey = attribute.Key("langchaingo.retriever.query")
+	// retrieverDocumentsKey records the documents retrieved as JSON, when content is recorded.
+	retrieverDocumentsKey = attribute.Key("langchaingo.retriever.documents")
+	// retrieverDocumentCountKey records the number of documents retrieved.
+	retrieverDocumentCountKey = attribute.Key("langchaingo.retriever.document_count")
+	// agentToolKey records the tool an agent decided to execute.
+	agentToolKey = attribute.Key("langchaingo.agent.tool")
+	// agentToolInputKey records the input of the tool an agent decided to execute, when content is recorded.
+	agentToolInputKey = attribute.Key("langchaingo.agent.tool_input")
+	// agentReturnValuesKey records the values an agent returned as JSON, when content is recorded.
+	agentReturnValuesKey = attribute.Key("langchaingo.agent.return_values")
+)
+
+// kind is the kind of event span.
+type kind int
+
+const (
+	chainKind kind = iota
+	llmKind
+	toolKind
+	retrieverKind
+)
+
+// Handler is a callbacks handler creating spans for the events of chains, LLMs, tools and retrievers.
+//
+// The callbacks of LangChainGo don't return contexts, so each span is the child of the last span
+// started with the same context and not ended yet, or of the span of the context. Ending a span ends
+// the spans started after it with the same context, which some components don't end on errors.
+type Handler struct {
+	config *config
+
+	mu     sync.Mutex
+	stacks map[any]*stack
+}
+
+var _ callbacks.Handler = (*Handler)(nil)
+
+// stack holds the spans started with a context and not ended yet.
+type stack struct {
+	entries []*entry
+	// action is the last action of an agent, which names the tool executed next.
+	action *schema.AgentAction
+}
+
+type entry struct {
+	kind kind
+	ctx  context.Context
+	span trace.Span
+	// llm is the span of an LLM call.
+	llm *genaiconv.Span
+	// streamed is the text streamed by an LLM call so far, when its progress is recorded.
+	streamed *strings.Builder
+}
+
+// end ends the span of the entry, with err if it's not nil.
+func (e *entry) end(err error) {
+	if e.llm != nil {
+		if err != nil {
+			e.llm.Fail(err, genaiconv.Response{})
+			return
+		}
+		e.llm.End(genaiconv.Response{})
+		return
+	}
+	if err != nil {
+		e.span.RecordError(err)
+		e.span.SetStatus(codes.Error, err.Error())
+	}
+	e.span.End()
+}
+
+// NewHandler returns a callbacks handler creating spans.
+func NewHandler(opts ...Option) *Handler {
+	return &Handler{config: newConfig(opts), stacks: map[any]*stack{}}
+}
+
+// key returns the key of the stack of a context. Contexts which can't be map keys share a stack.
+func key(ctx context.Context) any {
+	if ctx == nil || !reflect.TypeOf(ctx).Comparable() {
+		return nil
+	}
+	return ctx
+}
+
+// start starts a span as a child of the last span started with ctx, or of ctx.
+func (h *Handler) start(ctx context.Context, kind kind, name string, attrs ...attribute.KeyValue) *entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.stack(ctx)
+	parent := ctx
+	if len(s.entries) > 0 {
+		parent = s.entries[len(s.entries)-1].ctx
+	}
+	e := &entry{kind: kind}
+	if kind == llmKind {
+		e.ctx, e.llm = genaiconv.Start(parent, h.config.tracer(), "", genaiconv.OperationChat, "", h.config.content,
+			append(attrs, h.config.attrs...)...)
+		e.llm.Metrics(h.config.metrics())
+		e.span = e.llm.Span()
+	} else {
+		attrs = append([]attribute.KeyValue{logfire.MsgKey.String(name)}, attrs...)
+		e.ctx, e.span = h.config.tracer().Start(parent, name, trace.WithAttributes(attrs...), trace.WithAttributes(h.config.attrs...))
+	}
+	s.entries = append(s.entries, e)
+	return e
+}
+
+func (h *Handler) stack(ctx context.Context) *stack {
+	s, ok := h.stacks[key(ctx)]
+	if !ok {
+		s = &stack{}
+		h.stacks[key(ctx)] = s
+	}
+	return s
+}
+
+// pop removes the last span of the kind started with ctx, ending the spans started after it.
+// It returns nil if there's none.
+func (h *Handler) pop(ctx context.Context, kind kind) *entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.stacks[key(ctx)]
+	if !ok {
+		return nil
+	}
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if e.kind != kind {
+			continue
+		}
+		for _, child := range s.entries[i+1:] {
+			child.end(nil)
+		}
+		s.entries = s.entries[:i]
+		if len(s.entries) == 0 && s.action == nil {
+			delete(h.stacks, key(ctx))
+		}
+		return e
+	}
+	return nil
+}
+
+// top returns the last span started with ctx, or the span of ctx.
+func (h *Handler) top(ctx context.Context) trace.Span {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.stacks[key(ctx)]; ok && len(s.entries) > 0 {
+		return s.entries[len(s.entries)-1].span
+	}
+	return trace.SpanFromContext(ctx)
+}
+
+// HandleText does nothing, as no component of LangChainGo calls it.
+func (h *Handler) HandleText(context.Context, string) {}
+
+// HandleLLMStart does nothing, as LLMs call HandleLLMGenerateContentStart.
+func (h *Handler) HandleLLMStart(context.Context, []string) {}
+
+// HandleLLMGenerateContentStart starts the span of an LLM call.
+func (h *Handler) HandleLLMGenerateContentStart(ctx context.Context, ms []llms.MessageContent) {
+	e := h.start(ctx, llmKind, "")
+	var messages []genaiconv.Message
+	for _, m := range ms {
+		messages = append(messages, message(m))
+	}
+	e.llm.Input(nil, messages)
+}
+
+// HandleLLMGenerateContentEnd ends the span of an LLM call with its response.
+func (h *Handler) HandleLLMGenerateContentEnd(ctx context.Context, res *llms.ContentResponse) {
+	if e := h.pop(ctx, llmKind); e != nil {
+		e.llm.End(response(res))
+	}
+}
+
+// HandleLLMError ends the span of an LLM call with its error.
+func (h *Handler) HandleLLMError(ctx context.Context, err error) {
+	if e := h.pop(ctx, llmKind); e != nil {
+		e.end(err)
+	}
+}
+
+// HandleChainStart starts the span of a chain.
+func (h *Handler) HandleChainStart(ctx context.Context, inputs map[string]any) {
+	var attrs []attribute.KeyValue
+	if h.config.content {
+		attrs = append(attrs, genaiconv.JSON(chainInputsKey, inputs))
+	}
+	h.start(ctx, chainKind, "chain", attrs...)
+}
+
+// HandleChainEnd ends the span of a chain.
+func (h *Handler) HandleChainEnd(ctx context.Context, outputs map[string]any) {
+	if e := h.pop(ctx, chainKind); e != nil {
+		if h.config.content {
+			e.span.SetAttributes(genaiconv.JSON(chainOutputsKey, outputs))
+		}
+		e.end(nil)
+	}
+}
+
+// HandleChainError ends the span of a chain with its error.
+func (h *Handler) HandleChainError(ctx context.Context, err error) {
+	if e := h.pop(ctx, chainKind); e != nil {
+		e.end(err)
+	}
+}
+
+// HandleToolStart starts the span of a tool execution, named after the tool of the last
+// action of an agent if any.
+func (h *Handler) HandleToolStart(ctx context.Context, input string) {
+	h.mu.Lock()
+	var action *schema.AgentAction
+	if s, ok := h.stacks[key(ctx)]; ok {
+		action, s.action = s.action, nil
+	}
+	h.mu.Unlock()
+	attrs := []attribute.KeyValue{genaiconv.OperationNameKey.String(genaiconv.OperationExecuteTool)}
+	name := genaiconv.OperationExecuteTool
+	if action != nil {
+		name = genaiconv.SpanName(name, action.Tool)
+		attrs = append(attrs, genaiconv.ToolNameKey.String(action.Tool))
+		if action.ToolID != "" {
+			attrs = append(attrs, genaiconv.ToolCallIDKey.String(action.ToolID))
+		}
+	}
+	if h.config.content {
+		attrs = append(attrs, genaiconv.ToolCallArgumentsKey.String(input))
+	}
+	h.start(ctx, toolKind, name, attrs...)
+}
+
+// HandleToolEnd ends the span of a tool execution.
+func (h *Handler) HandleToolEnd(ctx context.Context, output string) {
+	if e := h.pop(ctx, toolKind); e != nil {
+		if h.config.content {
+			e.span.SetAttributes(genaiconv.ToolCallResultKey.String(output))
+		}
+		e.end(nil)
+	}
+}
+
+// HandleToolError ends the span of a tool execution with its error.
+func (h *Handler) HandleToolError(ctx context.Context, err error) {
+	if e := h.pop(ctx, toolKind); e != nil {
+		e.end(err)
+	}
+}
+
+// HandleAgentAction records the action of an agent as an event of the current span.
+func (h *Handler) HandleAgentAction(ctx context.Context, action schema.AgentAction) {
+	h.mu.Lock()
+	h.stack(ctx).action = &action
+	h.mu.Unlock()
+	attrs := []attribute.KeyValue{agentToolKey.String(action.Tool)}
+	if h.config.content {
+		attrs = append(attrs, agentToolInputKey.String(action.ToolInput))
+	}
+	h.top(ctx).AddEvent("agent action", trace.WithAttributes(attrs...))
+}
+
+// HandleAgentFinish records the end of the run of an agent as an event of the current span.
+func (h *Handler) HandleAgentFinish(ctx context.Context, finish schema.AgentFinish) {
+	var attrs []attribute.KeyValue
+	if h.config.content {
+		attrs = append(attrs, genaiconv.JSON(agentReturnValuesKey, finish.ReturnValues))
+	}
+	h.top(ctx).AddEvent("agent finish", trace.WithAttributes(attrs...))
+}
+
+// HandleRetrieverStart starts the span of a retrieval.
+func (h *Handler) HandleRetrieverStart(ctx context.Context, query string) {
+	var attrs []attribute.KeyValue
+	if h.config.content {
+		attrs = append(attrs, retrieverQueryKey.String(query))
+	}
+	h.start(ctx, retrieverKind, "retriever", attrs...)
+}
+
+type document struct {
+	PageContent string         `json:"page_content"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	Score       float32        `json:"score,omitempty"`
+}
+
+// HandleRetrieverEnd ends the span of a retrieval with the documents retrieved.
+func (h *Handler) HandleRetrieverEnd(ctx context.Context, _ string, documents []schema.Document) {
+	e := h.pop(ctx, retrieverKind)
+	if e == nil {
+		return
+	}
+	e.span.SetAttributes(retrieverDocumentCountKey.Int(len(documents)))
+	if h.config.content {
+		docs := make([]document, 0, len(documents))
+		for _, d := range documents {
+			docs = append(docs, document{PageContent: d.PageContent, Metadata: d.Metadata, Score: d.Score})
+		}
+		e.span.SetAttributes(genaiconv.JSON(retrieverDocumentsKey, docs))
+	}
+	e.end(nil)
+}
+
+// HandleStreamingFunc records the progress of the streamed response of the current LLM call, with
+// [WithStreamProgress]. Its response is recorded once it ends.
+func (h *Handler) HandleStreamingFunc(ctx context.Context, chunk []byte) {
+	if h.config.progress == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.stacks[key(ctx)]
+	if !ok {
+		return
+	}
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if e.kind != llmKind {
+			continue
+		}
+		if e.streamed == nil {
+			e.streamed = &strings.Builder{}
+			e.llm.StreamProgress(h.config.progress, func() genaiconv.Response {
+				msg := genaiconv.Message{Role: "assistant", Parts: []genaiconv.Part{genaiconv.TextPart(e.streamed.String())}}
+				return genaiconv.Response{Output: []genaiconv.Message{msg}}
+			})
+		}
+		e.streamed.Write(chunk)
+		e.llm.Chunk()
+		return
+	}
+}
+
+// roles maps the types of LangChainGo messages to the roles of the semantic conventions.
+var roles = map[llms.ChatMessageType]string{
+	llms.ChatMessageTypeHuman:  "user",
+	llms.ChatMessageTypeAI:     "assistant",
+	llms.ChatMessageTypeSystem: "system",
+	llms.ChatMessageTypeTool:   "tool",
+}
+
+func message(m llms.MessageContent) genaiconv.Message {
+	role, ok := roles[m.Role]
+	if !ok {
+		role = string(m.Role)
+	}
+	msg := genaiconv.Message{Role: role, Parts: []genaiconv.Part{}}
+	for _, p := range m.Parts {
+		msg.Parts = append(msg.Parts, part(p))
+	}
+	return msg
+}
+
+func part(p llms.ContentPart) genaiconv.Part {
+	switch p := p.(type) {
+	case llms.TextContent:
+		return genaiconv.TextPart(p.Text)
+	case llms.ImageURLContent:
+		return genaiconv.Part{Type: "uri", URI: p.URL}
+	case llms.BinaryContent:
+		return genaiconv.Part{Type: "blob", MIMEType: p.MIMEType}
+	case llms.ToolCall:
+		if p.FunctionCall == nil {
+			return genaiconv.ToolCallPart(p.ID, "", nil)
+		}
+		return genaiconv.ToolCallPart(p.ID, p.FunctionCall.Name, p.FunctionCall.Arguments)
+	case llms.ToolCallResponse:
+		part := genaiconv.ToolCallResponsePart(p.ToolCallID, p.Content)
+		part.Name = p.Name
+		return part
+	}
+	return genaiconv.Part{Type: fmt.Sprintf("%T", p)}
+}
+
+// count returns a token count of the generation info of a choice, set by LLMs as various integer types.
+func count(info map[string]any, keys ...string) int64 {
+	for _, key := range keys {
+		switch v := info[key].(type) {
+		case int:
+			return int64(v)
+		case int32:
+			return int64(v)
+		case int64:
+			return v
+		case float64:
+			return int64(v)
+		}
+	}
+	return 0
+}
+
+func response(res *llms.ContentResponse) genaiconv.Response {
+	var resp genaiconv.Response
+	if res == nil {
+		return resp
+	}
+	for _, choice := range res.Choices {
+		if resp.Usage == nil && choice.GenerationInfo != nil {
+			info := choice.GenerationInfo
+			// The input tokens of Anthropic don't include the cached tokens, while the prompt tokens of OpenAI do.
+			cacheCreation := count(info, "CacheCreationInputTokens")
+			cacheRead := count(info, "CacheReadInputTokens", "PromptCachedTokens")
+			usage := &genaiconv.Usage{
+				InputTokens:              count(info, "PromptTokens"),
+				OutputTokens:             count(info, "CompletionTokens", "OutputTokens"),
+				CacheCreationInputTokens: cacheCreation,
+				CacheReadInputTokens:     cacheRead,
+			}
+			if input := count(info, "InputTokens"); input > 0 && usage.InputTokens == 0 {
+				usage.InputTokens = input + cacheCreation + cacheRead
+			}
+			if usage.InputTokens > 0 || usage.OutputTokens > 0 {
+				resp.Usage = usage
+			}
+		}
+		msg := genaiconv.Message{Role: "assistant", Parts: []genaiconv.Part{}, FinishReason: choice.StopReason}
+		reasoning := choice.ReasoningContent
+		if thinking, ok := choice.GenerationInfo["ThinkingContent"].(string); ok && reasoning == "" {
+			reasoning = thinking
+		}
+		if reasoning != "" {
+			msg.Parts = append(msg.Parts, genaiconv.Part{Type: "reasoning", Content: reasoning})
+		}
+		if choice.Content != "" {
+			msg.Parts = append(msg.Parts, genaiconv.TextPart(choice.Content))
+		}
+		for _, call := range choice.ToolCalls {
+			msg.Parts = append(msg.Parts, part(call))
+		}
+		if len(choice.ToolCalls) == 0 && choice.FuncCall != nil {
+			msg.Parts = append(msg.Parts, genaiconv.ToolCallPart("", choice.FuncCall.Name, choice.FuncCall.Arguments))
+		}
+		if choice.StopReason != "" {
+			resp.FinishReasons = append(resp.FinishReasons, choice.StopReason)
+		}
+		resp.Output = append(resp.Output, msg)
+	}
+	return resp
+}
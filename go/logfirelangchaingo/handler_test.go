@@ -0,0 +1,269 @@
+package logfirelangchaingo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/chains"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/prompts"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/tools"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func checkJSON(t *testing.T, attrs []attribute.KeyValue, key attribute.Key, want string) {
+	t.Helper()
+	got, _ := oteltest.Attributes(attrs)[key].(string)
+	var g, w any
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Errorf("%s = %q, not JSON", key, got)
+		return
+	}
+	json.Unmarshal([]byte(want), &w)
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("%s = %s, want %s", key, gb, wb)
+	}
+}
+
+func newHandler(opts ...Option) (*Handler, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return NewHandler(append([]Option{WithTracerProvider(provider)}, opts...)...), recorder
+}
+
+// model is an LLM calling the callbacks handler like the LLMs of LangChainGo.
+type model struct {
+	handler *Handler
+	resp    *llms.ContentResponse
+	err     error
+}
+
+func (m *model) GenerateContent(ctx context.Context, messages []llms.MessageContent, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.handler.HandleLLMGenerateContentStart(ctx, messages)
+	if m.err != nil {
+		m.handler.HandleLLMError(ctx, m.err)
+		return nil, m.err
+	}
+	m.handler.HandleLLMGenerateContentEnd(ctx, m.resp)
+	return m.resp, nil
+}
+
+func (m *model) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestChain(t *testing.T) {
+	h, recorder := newHandler(WithMessageContent())
+	llm := &model{handler: h, resp: &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content:        "Hello!",
+		StopReason:     "stop",
+		GenerationInfo: map[string]any{"PromptTokens": 10, "CompletionTokens": 3, "PromptCachedTokens": 4},
+	}}}}
+	chain := chains.NewLLMChain(llm, prompts.NewPromptTemplate("Say {{.word}}", []string{"word"}))
+	chain.CallbacksHandler = h
+	if _, err := chains.Call(context.Background(), chain, map[string]any{"word": "hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	chat, span := spans[0], spans[1]
+	if span.Name() != "chain" || chat.Name() != "chat" {
+		t.Fatalf("names = %q, %q", span.Name(), chat.Name())
+	}
+	if chat.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Error("chat span isn't a child of the chain span")
+	}
+	checkJSON(t, span.Attributes(), chainInputsKey, `{"word": "hello"}`)
+	checkJSON(t, span.Attributes(), chainOutputsKey, `{"text": "Hello!"}`)
+	oteltest.CheckAttributes(t, chat.Attributes(), map[attribute.Key]any{
+		"gen_ai.operation.name":                "chat",
+		"gen_ai.usage.input_tokens":            int64(10),
+		"gen_ai.usage.output_tokens":           int64(3),
+		"gen_ai.usage.cache_read.input_tokens": int64(4),
+	})
+	if _, ok := oteltest.Attributes(chat.Attributes())["gen_ai.provider.name"]; ok {
+		t.Error("gen_ai.provider.name is set")
+	}
+	checkJSON(t, chat.Attributes(), "gen_ai.input.messages",
+		`[{"role": "user", "parts": [{"type": "text", "content": "Say hello"}]}]`)
+	checkJSON(t, chat.Attributes(), "gen_ai.output.messages",
+		`[{"role": "assistant", "parts": [{"type": "text", "content": "Hello!"}], "finish_reason": "stop"}]`)
+}
+
+func TestChainWithoutContent(t *testing.T) {
+	h, recorder := newHandler()
+	llm := &model{handler: h, resp: &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "Hello!"}}}}
+	chain := chains.NewLLMChain(llm, prompts.NewPromptTemplate("Say {{.word}}", []string{"word"}))
+	chain.CallbacksHandler = h
+	if _, err := chains.Call(context.Background(), chain, map[string]any{"word": "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, span := range recorder.Ended() {
+		m := oteltest.Attributes(span.Attributes())
+		for _, key := range []attribute.Key{chainInputsKey, chainOutputsKey, "gen_ai.input.messages", "gen_ai.output.messages"} {
+			if _, ok := m[key]; ok {
+				t.Errorf("%s: %s is set", span.Name(), key)
+			}
+		}
+	}
+}
+
+func TestLLMError(t *testing.T) {
+	h, recorder := newHandler()
+	llm := &model{handler: h, err: errors.New("rate limited")}
+	chain := chains.NewLLMChain(llm, prompts.NewPromptTemplate("Say {{.word}}", []string{"word"}))
+	chain.CallbacksHandler = h
+	if _, err := chains.Call(context.Background(), chain, map[string]any{"word": "hello"}); err == nil {
+		t.Fatal("no error")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	for _, span := range spans {
+		if span.Status().Code != codes.Error || span.Status().Description != "rate limited" {
+			t.Errorf("%s: status = %v", span.Name(), span.Status())
+		}
+	}
+}
+
+func TestStreamProgress(t *testing.T) {
+	h, recorder := newHandler(WithMessageContent(), WithStreamProgress(time.Nanosecond))
+	ctx := context.Background()
+	h.HandleLLMGenerateContentStart(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Hi")})
+	h.HandleStreamingFunc(ctx, []byte("Hel"))
+	h.HandleStreamingFunc(ctx, []byte("lo"))
+	h.HandleLLMGenerateContentEnd(ctx, &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "Hello"}}})
+
+	span := recorder.Ended()[0]
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{"gen_ai.response.chunk_count": int64(2)})
+	events := span.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	checkJSON(t, events[1].Attributes, "gen_ai.output.messages",
+		`[{"role": "assistant", "parts": [{"type": "text", "content": "Hello"}]}]`)
+}
+
+func TestResponse(t *testing.T) {
+	resp := response(&llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		StopReason: "tool_use",
+		GenerationInfo: map[string]any{
+			"InputTokens":              int64(5),
+			"OutputTokens":             int64(7),
+			"CacheCreationInputTokens": int64(2),
+			"CacheReadInputTokens":     int64(3),
+			"ThinkingContent":          "Let me think.",
+		},
+		ToolCalls: []llms.ToolCall{{
+			ID:           "call_1",
+			Type:         "function",
+			FunctionCall: &llms.FunctionCall{Name: "calculator", Arguments: `{"expression": "1+1"}`},
+		}},
+	}}})
+	if resp.Usage == nil || resp.Usage.InputTokens != 10 || resp.Usage.OutputTokens != 7 ||
+		resp.Usage.CacheCreationInputTokens != 2 || resp.Usage.CacheReadInputTokens != 3 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+	got, _ := json.Marshal(resp.Output)
+	want := `[{"role":"assistant","parts":[{"type":"reasoning","content":"Let me think."},` +
+		`{"type":"tool_call","id":"call_1","name":"calculator","arguments":{"expression":"1+1"}}],"finish_reason":"tool_use"}]`
+	if string(got) != want {
+		t.Errorf("output = %s, want %s", got, want)
+	}
+}
+
+func TestTool(t *testing.T) {
+	h, recorder := newHandler(WithMessageContent())
+	ctx := context.Background()
+	h.HandleChainStart(ctx, map[string]any{"input": "What's 1+1?"})
+	h.HandleAgentAction(ctx, schema.AgentAction{Tool: "calculator", ToolInput: "1+1", ToolID: "call_1"})
+	calculator := tools.Calculator{CallbacksHandler: h}
+	if _, err := calculator.Call(ctx, "1+1"); err != nil {
+		t.Fatal(err)
+	}
+	h.HandleAgentFinish(ctx, schema.AgentFinish{ReturnValues: map[string]any{"output": "2"}})
+	h.HandleChainEnd(ctx, map[string]any{"output": "2"})
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	tool, chain := spans[0], spans[1]
+	if tool.Name() != "execute_tool calculator" {
+		t.Errorf("name = %q", tool.Name())
+	}
+	if tool.Parent().SpanID() != chain.SpanContext().SpanID() {
+		t.Error("tool span isn't a child of the chain span")
+	}
+	oteltest.CheckAttributes(t, tool.Attributes(), map[attribute.Key]any{
+		"gen_ai.operation.name":      "execute_tool",
+		"gen_ai.tool.name":           "calculator",
+		"gen_ai.tool.call.id":        "call_1",
+		"gen_ai.tool.call.arguments": "1+1",
+		"gen_ai.tool.call.result":    "2",
+	})
+	events := chain.Events()
+	if len(events) != 2 || events[0].Name != "agent action" || events[1].Name != "agent finish" {
+		t.Fatalf("events = %v", events)
+	}
+	oteltest.CheckAttributes(t, events[0].Attributes, map[attribute.Key]any{agentToolKey: "calculator", agentToolInputKey: "1+1"})
+	checkJSON(t, events[1].Attributes, agentReturnValuesKey, `{"output": "2"}`)
+	if len(h.stacks) != 0 {
+		t.Errorf("%d stacks left", len(h.stacks))
+	}
+}
+
+func TestRetriever(t *testing.T) {
+	h, recorder := newHandler(WithMessageContent())
+	ctx := context.Background()
+	h.HandleRetrieverStart(ctx, "logfire")
+	h.HandleRetrieverEnd(ctx, "logfire", []schema.Document{{PageContent: "Logfire is an observability platform.", Score: 0.5}})
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		retrieverQueryKey:         "logfire",
+		retrieverDocumentCountKey: int64(1),
+	})
+	checkJSON(t, spans[0].Attributes(), retrieverDocumentsKey,
+		`[{"page_content": "Logfire is an observability platform.", "score": 0.5}]`)
+}
+
+func TestUnendedChildren(t *testing.T) {
+	h, recorder := newHandler()
+	ctx := context.Background()
+	h.HandleChainStart(ctx, nil)
+	h.HandleToolStart(ctx, "1+")
+	h.HandleChainError(ctx, errors.New("invalid expression"))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Name() != "execute_tool" || spans[0].Status().Code == codes.Error {
+		t.Errorf("tool span = %q, %v", spans[0].Name(), spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("chain status = %v", spans[1].Status())
+	}
+	if len(h.stacks) != 0 {
+		t.Errorf("%d stacks left", len(h.stacks))
+	}
+}
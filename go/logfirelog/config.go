@@ -0,0 +1,65 @@
+// Package logfirelog sends the output of log.Logger, or of any writer of log lines, to Pydantic Logfire.
+//
+// [NewWriter] returns a writer emitting each line written to it as a Logfire log. The date, time and
+// file prefixes of [log.Logger] are parsed, and the level is detected from the start of the message,
+// e.g. `ERROR: ` or `[warn] `:
+//
+//	log.SetOutput(io.MultiWriter(os.Stderr, logfirelog.NewWriter()))
+//
+// [NewLogger] returns a logger writing to it, e.g. for the ErrorLog of an [net/http.Server]:
+//
+//	srv := &http.Server{ErrorLog: logfirelog.NewLogger(logfirelog.WithDefaultLevel(logfire.LevelError))}
+package logfirelog
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// instrumentationName is the instrumentation scope of the logs emitted by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirelog"
+
+// Option configures [NewWriter] and [NewLogger].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to emit logs. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all logs.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithDefaultLevel sets the level of the lines without a level. Defaults to [logfire.LevelInfo].
+func WithDefaultLevel(level logfire.Level) Option {
+	return func(c *config) {
+		c.defaultLevel = level
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	defaultLevel   logfire.Level
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{defaultLevel: logfire.LevelInfo}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
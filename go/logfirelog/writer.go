@@ -0,0 +1,84 @@
+package logfirelog
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/logconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+var (
+	// timeRe matches the date and time written by [log.Logger] with [log.LstdFlags] or [log.Lmicroseconds].
+	timeRe = regexp.MustCompile(`^(\d{4}/\d\d/\d\d )?(\d\d:\d\d:\d\d(\.\d{6})? )?`)
+	// fileRe matches the file and line written by [log.Logger] with [log.Lshortfile] or [log.Llongfile].
+	fileRe = regexp.MustCompile(`^(\S+\.go):(\d+): `)
+	// levelRe matches the words which may be levels starting log messages, e.g. `[warn] `, `Error: `,
+	// `DEBUG - ` or `INFO `. Words followed by a space only are levels if uppercase, unlike `Error connecting`.
+	levelRe = regexp.MustCompile(`^(?:\[(\w+)\]\s*|(\w+)(?::\s*| - )|([A-Z]+)\s+)`)
+)
+
+// levels are the Logfire levels of the names matched by levelRe.
+var levels = map[string]logfire.Level{
+	"trace":    logfire.LevelTrace,
+	"debug":    logfire.LevelDebug,
+	"info":     logfire.LevelInfo,
+	"notice":   logfire.LevelNotice,
+	"warn":     logfire.LevelWarn,
+	"warning":  logfire.LevelWarn,
+	"error":    logfire.LevelError,
+	"err":      logfire.LevelError,
+	"fatal":    logfire.LevelFatal,
+	"panic":    logfire.LevelFatal,
+	"critical": logfire.LevelFatal,
+}
+
+// Writer emits the lines written to it as Logfire logs.
+type Writer struct {
+	config *config
+	tracer trace.Tracer
+}
+
+// NewWriter returns a writer emitting lines as Logfire logs.
+func NewWriter(opts ...Option) *Writer {
+	c := newConfig(opts)
+	return &Writer{config: c, tracer: c.tracer()}
+}
+
+// NewLogger returns a logger without flags writing to a [Writer],
+// since logs have their own time and the file would have to be parsed.
+func NewLogger(opts ...Option) *log.Logger {
+	return log.New(NewWriter(opts...), "", 0)
+}
+
+// Write emits p as a log, without its trailing newline. [log.Logger] writes each message at once,
+// so messages spanning several lines, like stack traces, are a single log.
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\r\n")
+	if msg == "" {
+		return len(p), nil
+	}
+	rec := logconv.Record{Level: w.config.defaultLevel, Attrs: w.config.attrs}
+	if loc := timeRe.FindStringIndex(msg); loc != nil {
+		msg = msg[loc[1]:]
+	}
+	if m := fileRe.FindStringSubmatch(msg); m != nil {
+		rec.File = m[1]
+		rec.Line, _ = strconv.Atoi(m[2])
+		msg = msg[len(m[0]):]
+	}
+	if m := levelRe.FindStringSubmatch(msg); m != nil && len(m[0]) < len(msg) {
+		if level, ok := levels[strings.ToLower(m[1]+m[2]+m[3])]; ok {
+			rec.Level = level
+			msg = msg[len(m[0]):]
+		}
+	}
+	rec.Message = msg
+	logconv.Emit(context.Background(), w.tracer, rec, false)
+	return len(p), nil
+}
@@ -0,0 +1,92 @@
+package logfirelog
+
+import (
+	"log"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newWriter(opts ...Option) (*Writer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return NewWriter(append(opts, WithTracerProvider(provider))...), recorder
+}
+
+func TestLogger(t *testing.T) {
+	w, recorder := newWriter(WithAttributes(attribute.String("service", "orders")))
+	logger := log.New(w, "", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
+	logger.Print("ERROR: payment failed")
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "payment failed" || span.StartTime() != span.EndTime() {
+		t.Errorf("span = %s, from %v to %v", span.Name(), span.StartTime(), span.EndTime())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v", span.Status())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for k, want := range map[attribute.Key]any{
+		logfire.SpanTypeKey: "log",
+		logfire.LevelNumKey: int64(logfire.LevelError),
+		logfire.MsgKey:      "payment failed",
+		"code.filepath":     "writer_test.go",
+		"service":           "orders",
+	} {
+		if attrs[k] != want {
+			t.Errorf("%s = %v, want %v", k, attrs[k], want)
+		}
+	}
+	if line, _ := attrs["code.lineno"].(int64); line == 0 {
+		t.Errorf("code.lineno = %v", attrs["code.lineno"])
+	}
+}
+
+func TestLevels(t *testing.T) {
+	w, recorder := newWriter(WithDefaultLevel(logfire.LevelNotice))
+	tests := []struct {
+		line  string
+		level logfire.Level
+		msg   string
+	}{
+		{"[warn] disk almost full", logfire.LevelWarn, "disk almost full"},
+		{"Debug - cache miss", logfire.LevelDebug, "cache miss"},
+		{"INFO server started", logfire.LevelInfo, "server started"},
+		{"fatal: out of memory", logfire.LevelFatal, "out of memory"},
+		{"panic: nil map\n\ngoroutine 1", logfire.LevelFatal, "nil map\n\ngoroutine 1"},
+		{"Error connecting to database", logfire.LevelNotice, "Error connecting to database"},
+		{"note: not a level", logfire.LevelNotice, "note: not a level"},
+		{"WARNING", logfire.LevelNotice, "WARNING"},
+	}
+	for _, tt := range tests {
+		w.Write([]byte(tt.line + "\n"))
+	}
+	w.Write([]byte("\n"))
+
+	spans := recorder.Ended()
+	if len(spans) != len(tests) {
+		t.Fatalf("spans = %d, want %d", len(spans), len(tests))
+	}
+	for i, tt := range tests {
+		attrs := oteltest.Attributes(spans[i].Attributes())
+		if attrs[logfire.MsgKey] != tt.msg || attrs[logfire.LevelNumKey] != int64(tt.level) {
+			t.Errorf("%q: message = %q, level = %v, want %q, %v", tt.line, attrs[logfire.MsgKey], attrs[logfire.LevelNumKey], tt.msg, tt.level)
+		}
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	if flags := NewLogger().Flags(); flags != 0 {
+		t.Errorf("flags = %d, want 0", flags)
+	}
+}
@@ -0,0 +1,72 @@
+// Package logfirelogrus sends the entries of logrus loggers to Pydantic Logfire.
+//
+// [NewHook] returns a [logrus.Hook] emitting each entry as a Logfire log with its level, fields and
+// caller. Errors in fields are recorded as exceptions, and entries with a context holding a span
+// are children of it:
+//
+//	logrus.AddHook(logfirelogrus.NewHook())
+//	logrus.WithContext(ctx).WithField("order_id", id).Info("order placed")
+package logfirelogrus
+
+import (
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the logs emitted by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirelogrus"
+
+// Option configures [NewHook].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to emit logs. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all logs.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithLevel sets the least severe level of the entries to emit. Defaults to [logrus.InfoLevel].
+func WithLevel(level logrus.Level) Option {
+	return func(c *config) {
+		c.level = level
+	}
+}
+
+// WithSpanEvents adds logs as events to the recording span of their context rather than emitting
+// separate logs, to keep them with the span and reduce the number of records. Logs without a span are
+// still emitted.
+func WithSpanEvents() Option {
+	return func(c *config) {
+		c.spanEvents = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	level          logrus.Level
+	spanEvents     bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{level: logrus.InfoLevel}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
@@ -0,0 +1,86 @@
+package logfirelogrus
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/logconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// Hook is a [logrus.Hook] emitting entries as Logfire logs.
+type Hook struct {
+	config *config
+	tracer trace.Tracer
+}
+
+var _ logrus.Hook = (*Hook)(nil)
+
+// NewHook returns a hook emitting entries as Logfire logs.
+func NewHook(opts ...Option) *Hook {
+	c := newConfig(opts)
+	return &Hook{config: c, tracer: c.tracer()}
+}
+
+// Levels returns the levels from [logrus.PanicLevel] to the level set with [WithLevel].
+func (h *Hook) Levels() []logrus.Level {
+	var levels []logrus.Level
+	for _, l := range logrus.AllLevels {
+		if l <= h.config.level {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+// Fire emits entry as a Logfire log, a child of the span in the context of the entry if any.
+// The error of the [logrus.ErrorKey] field, or else the first error in fields, is recorded as an exception.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rec := logconv.Record{
+		Time:    entry.Time,
+		Level:   Level(entry.Level),
+		Message: entry.Message,
+		Attrs:   slices.Grow(slices.Clip(h.config.attrs), len(entry.Data)),
+	}
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		rec.Err = err
+	}
+	for _, k := range slices.Sorted(maps.Keys(entry.Data)) {
+		v := entry.Data[k]
+		if err, ok := v.(error); ok && rec.Err == nil {
+			rec.Err = err
+		}
+		rec.Attrs = append(rec.Attrs, logconv.Value(k, v))
+	}
+	if entry.Caller != nil {
+		rec.File, rec.Line, rec.Function = entry.Caller.File, entry.Caller.Line, entry.Caller.Function
+	}
+	logconv.Emit(ctx, h.tracer, rec, h.config.spanEvents)
+	return nil
+}
+
+// Level converts a logrus level to a Logfire level.
+func Level(level logrus.Level) logfire.Level {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return logfire.LevelFatal
+	case logrus.ErrorLevel:
+		return logfire.LevelError
+	case logrus.WarnLevel:
+		return logfire.LevelWarn
+	case logrus.InfoLevel:
+		return logfire.LevelInfo
+	case logrus.DebugLevel:
+		return logfire.LevelDebug
+	default:
+		return logfire.LevelTrace
+	}
+}
@@ -0,0 +1,131 @@
+package logfirelogrus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newLogger(opts ...Option) (*logrus.Logger, *tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.TraceLevel)
+	logger.AddHook(NewHook(append(opts, WithTracerProvider(provider))...))
+	return logger, recorder, provider
+}
+
+func TestHook(t *testing.T) {
+	logger, recorder, provider := newLogger(WithAttributes(attribute.String("service", "orders")))
+	logger.SetReportCaller(true)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	logger.WithContext(ctx).WithFields(logrus.Fields{
+		"user_id": 42,
+		"method":  "GET",
+		"payload": map[string]int{"items": 2},
+	}).Warn("slow request")
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "slow request" || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("span = %s, parent %v", span.Name(), span.Parent())
+	}
+	if span.StartTime() != span.EndTime() {
+		t.Errorf("duration = %v, want 0", span.EndTime().Sub(span.StartTime()))
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.SpanTypeKey: "log",
+		logfire.LevelNumKey: int64(logfire.LevelWarn),
+		logfire.MsgKey:      "slow request",
+		"service":           "orders",
+		"user_id":           int64(42),
+		"method":            "GET",
+		"payload":           `{"items":2}`,
+		"code.function":     "github.com/pydantic/logfire/go/logfirelogrus.TestHook",
+	})
+	if file, _ := oteltest.Attributes(span.Attributes())["code.filepath"].(string); !strings.HasSuffix(file, "hook_test.go") {
+		t.Errorf("code.filepath = %v", file)
+	}
+}
+
+func TestHookError(t *testing.T) {
+	logger, recorder, _ := newLogger()
+	logger.WithError(errors.New("card declined")).WithField("cause", errors.New("insufficient funds")).Error("payment failed")
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelError),
+		"error":             "card declined",
+		"cause":             "insufficient funds",
+	})
+	events := span.Events()
+	if len(events) != 1 || events[0].Name != "exception" {
+		t.Fatalf("events = %v", events)
+	}
+	oteltest.CheckAttributes(t, events[0].Attributes, map[attribute.Key]any{"exception.message": "card declined"})
+}
+
+func TestHookLevel(t *testing.T) {
+	logger, recorder, _ := newLogger(WithLevel(logrus.DebugLevel))
+	logger.Trace("trace")
+	logger.Debug("debug")
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Name() != "debug" {
+		t.Errorf("spans = %v, want the debug entry", spans)
+	}
+
+	for level, want := range map[logrus.Level]logfire.Level{
+		logrus.TraceLevel: logfire.LevelTrace,
+		logrus.DebugLevel: logfire.LevelDebug,
+		logrus.InfoLevel:  logfire.LevelInfo,
+		logrus.WarnLevel:  logfire.LevelWarn,
+		logrus.ErrorLevel: logfire.LevelError,
+		logrus.FatalLevel: logfire.LevelFatal,
+		logrus.PanicLevel: logfire.LevelFatal,
+	} {
+		if got := Level(level); got != want {
+			t.Errorf("Level(%v) = %v, want %v", level, got, want)
+		}
+	}
+}
+
+func TestHookSpanEvents(t *testing.T) {
+	logger, recorder, provider := newLogger(WithSpanEvents())
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	logger.WithContext(ctx).WithError(errors.New("card declined")).Error("payment failed")
+	span.End()
+	logger.Info("no span")
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "request" || spans[1].Name() != "no span" {
+		t.Fatalf("spans = %v, want the request and the log without span", spans)
+	}
+	events := spans[0].Events()
+	if len(events) != 1 || events[0].Name != "payment failed" {
+		t.Fatalf("events = %v", events)
+	}
+	oteltest.CheckAttributes(t, events[0].Attributes, map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelError),
+		"error":             "card declined",
+		"exception.message": "card declined",
+	})
+}
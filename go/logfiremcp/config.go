@@ -0,0 +1,86 @@
+// Package logfiremcp instruments the clients and servers of mark3labs/mcp-go, the Model Context
+// Protocol library, for Pydantic Logfire.
+//
+// [NewTransport] wraps the transport of a client, creating a client span for each tool call,
+// resource read and prompt request, named like `tools/call get_weather`, and propagating the
+// trace context in the `_meta` of the request, and in its headers over the HTTP transports:
+//
+//	base, err := transport.NewStreamableHTTP(url)
+//	c := client.NewClient(logfiremcp.NewTransport(base))
+//
+// [ServerOptions] returns the options of a server creating a server span for each tool call,
+// resource read and prompt request it handles, as a child of the trace context propagated by
+// the client, so that the traces of agents calling MCP servers stitch together:
+//
+//	s := server.NewMCPServer("weather", "1.0.0", logfiremcp.ServerOptions()...)
+//
+// The arguments and results of tool calls are only recorded with [WithMessageContent].
+package logfiremcp
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiremcp"
+
+// Option configures [NewTransport] and [ServerOptions].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject and extract the trace context of requests.
+// Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithMessageContent records the arguments and results of tool calls as the
+// `gen_ai.tool.call.arguments` and `gen_ai.tool.call.result` attributes. They still go through
+// the scrubbing of Logfire.
+func WithMessageContent() Option {
+	return func(c *config) {
+		c.content = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	attrs          []attribute.KeyValue
+	content        bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
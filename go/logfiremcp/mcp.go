@@ -0,0 +1,119 @@
+package logfiremcp
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// methodNameKey records the method of a request.
+	methodNameKey = attribute.Key("mcp.method.name")
+	// sessionIDKey records the ID of the session of a request.
+	sessionIDKey = attribute.Key("mcp.session.id")
+	// resourceURIKey records the URI of the resource read.
+	resourceURIKey = attribute.Key("mcp.resource.uri")
+	// promptNameKey records the name of the prompt requested.
+	promptNameKey = attribute.Key("gen_ai.prompt.name")
+)
+
+// toolError is the `error.type` of tool calls whose result is an error.
+const toolError = "tool_error"
+
+// request is a traced request.
+type request struct {
+	method string
+	// target is the tool or prompt of the request.
+	target string
+	attrs  []attribute.KeyValue
+}
+
+// toolCall returns the request of a tool call.
+func toolCall(name string, arguments any, content bool) request {
+	r := request{method: string(mcp.MethodToolsCall), target: name, attrs: []attribute.KeyValue{
+		genaiconv.OperationNameKey.String(genaiconv.OperationExecuteTool),
+		genaiconv.ToolNameKey.String(name),
+	}}
+	if content && arguments != nil {
+		r.attrs = append(r.attrs, genaiconv.JSON(genaiconv.ToolCallArgumentsKey, arguments))
+	}
+	return r
+}
+
+// resourceRead returns the request of a resource read.
+func resourceRead(uri string) request {
+	return request{method: string(mcp.MethodResourcesRead), attrs: []attribute.KeyValue{resourceURIKey.String(uri)}}
+}
+
+// promptGet returns the request of a prompt.
+func promptGet(name string) request {
+	return request{method: string(mcp.MethodPromptsGet), target: name, attrs: []attribute.KeyValue{promptNameKey.String(name)}}
+}
+
+// start starts the span of a request, named like `tools/call get_weather`.
+func (c *config) start(ctx context.Context, kind trace.SpanKind, r request, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	name := r.method
+	if r.target != "" {
+		name += " " + r.target
+	}
+	attrs = append(append([]attribute.KeyValue{
+		logfire.MsgKey.String(name),
+		methodNameKey.String(r.method),
+	}, r.attrs...), attrs...)
+	return c.tracer().Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...), trace.WithAttributes(c.attrs...))
+}
+
+// fail records the error of a request on its span.
+func fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.SetAttributes(semconv.ErrorType(err))
+}
+
+// endTool records the result of a tool call on its span, whose status is an error if the result is.
+func endTool(span trace.Span, result *mcp.CallToolResult, content bool) {
+	if result == nil {
+		return
+	}
+	if content {
+		span.SetAttributes(genaiconv.JSON(genaiconv.ToolCallResultKey, result.Content))
+	}
+	if result.IsError {
+		msg := toolError
+		for _, c := range result.Content {
+			if text, ok := c.(mcp.TextContent); ok {
+				msg = text.Text
+				break
+			}
+		}
+		span.SetStatus(codes.Error, msg)
+		span.SetAttributes(semconv.ErrorTypeKey.String(toolError))
+	}
+}
+
+// metaCarrier carries the trace context in the `_meta` of a request.
+type metaCarrier map[string]any
+
+func (m metaCarrier) Get(key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func (m metaCarrier) Set(key, value string) {
+	m[key] = value
+}
+
+func (m metaCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
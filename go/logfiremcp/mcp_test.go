@@ -0,0 +1,257 @@
+package logfiremcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func checkJSON(t *testing.T, attrs []attribute.KeyValue, key attribute.Key, want string) {
+	t.Helper()
+	got, _ := oteltest.Attributes(attrs)[key].(string)
+	var g, w any
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Errorf("%s = %q, not JSON", key, got)
+		return
+	}
+	json.Unmarshal([]byte(want), &w)
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("%s = %s, want %s", key, gb, wb)
+	}
+}
+
+func newOptions(recorder *tracetest.SpanRecorder, opts ...Option) []Option {
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return append([]Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}, opts...)
+}
+
+func newServer(opts ...Option) *server.MCPServer {
+	s := server.NewMCPServer("weather", "1.0.0", ServerOptions(opts...)...)
+	s.AddTool(mcp.NewTool("get_weather", mcp.WithString("city", mcp.Required())),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			city, err := req.RequireString("city")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText("Sunny in " + city), nil
+		})
+	s.AddTool(mcp.NewTool("fail"), func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return nil, errors.New("broken")
+	})
+	s.AddResource(mcp.NewResource("weather://cities", "cities"),
+		func(context.Context, mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: "weather://cities", Text: "Paris"}}, nil
+		})
+	s.AddPrompt(mcp.NewPrompt("forecast"), func(context.Context, mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return mcp.NewGetPromptResult("Forecast", []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("What's the forecast?")),
+		}), nil
+	})
+	return s
+}
+
+func newClient(t *testing.T, base transport.Interface, opts ...Option) *client.Client {
+	t.Helper()
+	c := client.NewClient(NewTransport(base, opts...))
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if _, err := c.Initialize(context.Background(), mcp.InitializeRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func callTool(name string, args map[string]any) mcp.CallToolRequest {
+	var req mcp.CallToolRequest
+	req.Params.Name = name
+	req.Params.Arguments = args
+	return req
+}
+
+// checkPair checks that the spans are a client span and its child server span with the same name.
+func checkPair(t *testing.T, spans []sdktrace.ReadOnlySpan, name string) (clientSpan, serverSpan sdktrace.ReadOnlySpan) {
+	t.Helper()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	serverSpan, clientSpan = spans[0], spans[1]
+	if clientSpan.SpanKind() != trace.SpanKindClient || serverSpan.SpanKind() != trace.SpanKindServer {
+		t.Errorf("kinds = %v, %v", clientSpan.SpanKind(), serverSpan.SpanKind())
+	}
+	if clientSpan.Name() != name || serverSpan.Name() != name {
+		t.Errorf("names = %q, %q, want %q", clientSpan.Name(), serverSpan.Name(), name)
+	}
+	if serverSpan.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Error("server span isn't a child of the client span")
+	}
+	return clientSpan, serverSpan
+}
+
+func TestToolCall(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	opts := newOptions(recorder, WithMessageContent())
+	c := newClient(t, transport.NewInProcessTransport(newServer(opts...)), opts...)
+	if _, err := c.CallTool(context.Background(), callTool("get_weather", map[string]any{"city": "Paris"})); err != nil {
+		t.Fatal(err)
+	}
+
+	clientSpan, serverSpan := checkPair(t, recorder.Ended(), "tools/call get_weather")
+	for _, span := range []sdktrace.ReadOnlySpan{clientSpan, serverSpan} {
+		oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+			"logfire.msg":           "tools/call get_weather",
+			"mcp.method.name":       "tools/call",
+			"gen_ai.operation.name": "execute_tool",
+			"gen_ai.tool.name":      "get_weather",
+		})
+		checkJSON(t, span.Attributes(), "gen_ai.tool.call.arguments", `{"city": "Paris"}`)
+		checkJSON(t, span.Attributes(), "gen_ai.tool.call.result", `[{"type": "text", "text": "Sunny in Paris"}]`)
+	}
+	oteltest.CheckAttributes(t, clientSpan.Attributes(), map[attribute.Key]any{"jsonrpc.request.id": "2"})
+}
+
+func TestToolCallWithoutContent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	opts := newOptions(recorder)
+	c := newClient(t, transport.NewInProcessTransport(newServer(opts...)), opts...)
+	if _, err := c.CallTool(context.Background(), callTool("get_weather", map[string]any{"city": "Paris"})); err != nil {
+		t.Fatal(err)
+	}
+	for _, span := range recorder.Ended() {
+		m := oteltest.Attributes(span.Attributes())
+		for _, key := range []attribute.Key{"gen_ai.tool.call.arguments", "gen_ai.tool.call.result"} {
+			if _, ok := m[key]; ok {
+				t.Errorf("%s is set", key)
+			}
+		}
+	}
+}
+
+func TestToolErrors(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	opts := newOptions(recorder)
+	c := newClient(t, transport.NewInProcessTransport(newServer(opts...)), opts...)
+
+	result, err := c.CallTool(context.Background(), callTool("get_weather", nil))
+	if err != nil || !result.IsError {
+		t.Fatalf("result = %v, %v", result, err)
+	}
+	clientSpan, serverSpan := checkPair(t, recorder.Ended(), "tools/call get_weather")
+	for _, span := range []sdktrace.ReadOnlySpan{clientSpan, serverSpan} {
+		if span.Status().Code != codes.Error {
+			t.Errorf("status = %v", span.Status())
+		}
+		oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{"error.type": "tool_error"})
+	}
+
+	recorder.Reset()
+	if _, err := c.CallTool(context.Background(), callTool("fail", nil)); err == nil {
+		t.Fatal("no error")
+	}
+	clientSpan, serverSpan = checkPair(t, recorder.Ended(), "tools/call fail")
+	if serverSpan.Status().Description != "broken" {
+		t.Errorf("server status = %v", serverSpan.Status())
+	}
+	if clientSpan.Status().Code != codes.Error {
+		t.Errorf("client status = %v", clientSpan.Status())
+	}
+	oteltest.CheckAttributes(t, clientSpan.Attributes(), map[attribute.Key]any{
+		"rpc.response.status_code": "-32603",
+		"error.type":               "-32603",
+	})
+}
+
+func TestPrompt(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	opts := newOptions(recorder)
+	c := newClient(t, transport.NewInProcessTransport(newServer(opts...)), opts...)
+	var req mcp.GetPromptRequest
+	req.Params.Name = "forecast"
+	if _, err := c.GetPrompt(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	clientSpan, serverSpan := checkPair(t, recorder.Ended(), "prompts/get forecast")
+	for _, span := range []sdktrace.ReadOnlySpan{clientSpan, serverSpan} {
+		oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{"gen_ai.prompt.name": "forecast"})
+	}
+}
+
+func TestUntracedRequests(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	opts := newOptions(recorder)
+	c := newClient(t, transport.NewInProcessTransport(newServer(opts...)), opts...)
+	if _, err := c.ListTools(context.Background(), mcp.ListToolsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("got %d spans, want 0", len(spans))
+	}
+}
+
+func TestResourceOverHTTP(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	opts := newOptions(recorder)
+	srv := httptest.NewServer(server.NewStreamableHTTPServer(newServer(opts...)))
+	t.Cleanup(srv.Close)
+	base, err := transport.NewStreamableHTTP(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t, base, opts...)
+	var req mcp.ReadResourceRequest
+	req.Params.URI = "weather://cities"
+	if _, err := c.ReadResource(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	clientSpan, serverSpan := checkPair(t, recorder.Ended(), "resources/read")
+	for _, span := range []sdktrace.ReadOnlySpan{clientSpan, serverSpan} {
+		oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{"mcp.resource.uri": "weather://cities"})
+		if _, ok := oteltest.Attributes(span.Attributes())["mcp.session.id"]; !ok {
+			t.Errorf("%v: mcp.session.id isn't set", span.SpanKind())
+		}
+	}
+}
+
+func TestMetaPropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	s := newServer(newOptions(recorder)...)
+	message, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params": map[string]any{
+			"name":      "get_weather",
+			"arguments": map[string]any{"city": "Paris"},
+			"_meta":     map[string]any{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		},
+	})
+	s.HandleMessage(context.Background(), message)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if got := spans[0].Parent(); got.TraceID().String() != "0af7651916cd43dd8448eb211c80319c" ||
+		got.SpanID().String() != "b7ad6b7169203331" || !got.IsRemote() {
+		t.Errorf("parent = %v", got)
+	}
+}
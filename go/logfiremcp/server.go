@@ -0,0 +1,81 @@
+package logfiremcp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServerOptions returns the options of a server adding middlewares which create a span for each
+// tool call, resource read and prompt request it handles.
+//
+// The span is a child of the context of the request, or of the trace context propagated by the
+// client if the request isn't traced: in the `_meta` of tool calls, or in the headers of the HTTP
+// transports, as mcp-go doesn't decode the `_meta` of resource reads and prompt requests.
+func ServerOptions(opts ...Option) []server.ServerOption {
+	c := newConfig(opts)
+	return []server.ServerOption{
+		server.WithToolHandlerMiddleware(func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+			return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				var meta map[string]any
+				if req.Params.Meta != nil {
+					meta = req.Params.Meta.AdditionalFields
+				}
+				ctx, span := c.startServer(ctx, toolCall(req.Params.Name, req.Params.Arguments, c.content), meta, req.Header)
+				defer span.End()
+				result, err := next(ctx, req)
+				if err != nil {
+					fail(span, err)
+					return result, err
+				}
+				endTool(span, result, c.content)
+				return result, nil
+			}
+		}),
+		server.WithResourceHandlerMiddleware(func(next server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+			return func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+				ctx, span := c.startServer(ctx, resourceRead(req.Params.URI), nil, req.Header)
+				defer span.End()
+				contents, err := next(ctx, req)
+				if err != nil {
+					fail(span, err)
+				}
+				return contents, err
+			}
+		}),
+		server.WithPromptHandlerMiddleware(func(next server.PromptHandlerFunc) server.PromptHandlerFunc {
+			return func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+				ctx, span := c.startServer(ctx, promptGet(req.Params.Name), nil, req.Header)
+				defer span.End()
+				result, err := next(ctx, req)
+				if err != nil {
+					fail(span, err)
+				}
+				return result, err
+			}
+		}),
+	}
+}
+
+// startServer starts the span of a request handled by a server, extracting the trace context from
+// the `_meta` of the request or from its headers if ctx isn't traced.
+func (c *config) startServer(ctx context.Context, r request, meta map[string]any, header http.Header) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if len(meta) > 0 {
+			ctx = c.propagator().Extract(ctx, metaCarrier(meta))
+		}
+		if !trace.SpanContextFromContext(ctx).IsValid() && header != nil {
+			ctx = c.propagator().Extract(ctx, propagation.HeaderCarrier(header))
+		}
+	}
+	var attrs []attribute.KeyValue
+	if session := server.ClientSessionFromContext(ctx); session != nil && session.SessionID() != "" {
+		attrs = append(attrs, sessionIDKey.String(session.SessionID()))
+	}
+	return c.start(ctx, trace.SpanKindServer, r, attrs...)
+}
@@ -0,0 +1,128 @@
+package logfiremcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTransport wraps the transport of a client, creating a span for each tool call, resource read
+// and prompt request, and propagating the trace context in the `_meta` of their parameters and in
+// their headers. Other requests aren't traced.
+func NewTransport(base transport.Interface, opts ...Option) transport.Interface {
+	return &clientTransport{Interface: base, config: newConfig(opts)}
+}
+
+type clientTransport struct {
+	transport.Interface
+	config *config
+}
+
+var (
+	_ transport.BidirectionalInterface = (*clientTransport)(nil)
+	_ transport.HTTPConnection         = (*clientTransport)(nil)
+)
+
+// params are the parameters of the traced requests.
+type params struct {
+	Name      string          `json:"name"`
+	URI       string          `json:"uri"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+func (t *clientTransport) SendRequest(ctx context.Context, req transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	var r request
+	switch mcp.MCPMethod(req.Method) {
+	case mcp.MethodToolsCall, mcp.MethodResourcesRead, mcp.MethodPromptsGet:
+	default:
+		return t.Interface.SendRequest(ctx, req)
+	}
+	raw, err := json.Marshal(req.Params)
+	if err != nil {
+		return t.Interface.SendRequest(ctx, req)
+	}
+	fields := map[string]json.RawMessage{}
+	var p params
+	if json.Unmarshal(raw, &fields) != nil || json.Unmarshal(raw, &p) != nil {
+		return t.Interface.SendRequest(ctx, req)
+	}
+	switch mcp.MCPMethod(req.Method) {
+	case mcp.MethodToolsCall:
+		var arguments any
+		if len(p.Arguments) > 0 {
+			arguments = p.Arguments
+		}
+		r = toolCall(p.Name, arguments, t.config.content)
+	case mcp.MethodResourcesRead:
+		r = resourceRead(p.URI)
+	case mcp.MethodPromptsGet:
+		r = promptGet(p.Name)
+	}
+	attrs := []attribute.KeyValue{semconv.JSONRPCRequestID(fmt.Sprint(req.ID.Value()))}
+	if id := t.GetSessionId(); id != "" {
+		attrs = append(attrs, sessionIDKey.String(id))
+	}
+	ctx, span := t.config.start(ctx, trace.SpanKindClient, r, attrs...)
+	defer span.End()
+
+	meta := metaCarrier{}
+	json.Unmarshal(fields["_meta"], &meta)
+	t.config.propagator().Inject(ctx, meta)
+	fields["_meta"], _ = json.Marshal(meta)
+	req.Params = fields
+	header := req.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	t.config.propagator().Inject(ctx, propagation.HeaderCarrier(header))
+	req.Header = header
+
+	resp, err := t.Interface.SendRequest(ctx, req)
+	if err != nil {
+		fail(span, err)
+		return resp, err
+	}
+	if resp.Error != nil {
+		code := strconv.Itoa(resp.Error.Code)
+		span.RecordError(errors.New(resp.Error.Message))
+		span.SetStatus(codes.Error, resp.Error.Message)
+		span.SetAttributes(semconv.RPCResponseStatusCode(code), semconv.ErrorTypeKey.String(code))
+		return resp, nil
+	}
+	if r.method == string(mcp.MethodToolsCall) {
+		if result, err := mcp.ParseCallToolResult(&resp.Result); err == nil {
+			endTool(span, result, t.config.content)
+		}
+	}
+	return resp, nil
+}
+
+func (t *clientTransport) SetRequestHandler(handler transport.RequestHandler) {
+	if b, ok := t.Interface.(transport.BidirectionalInterface); ok {
+		b.SetRequestHandler(handler)
+	}
+}
+
+func (t *clientTransport) SetProtocolVersion(version string) {
+	if c, ok := t.Interface.(transport.HTTPConnection); ok {
+		c.SetProtocolVersion(version)
+	}
+}
+
+// SetConnectionLostHandler sets the handler of the HTTP transports called when the connection is lost.
+func (t *clientTransport) SetConnectionLostHandler(handler func(error)) {
+	if s, ok := t.Interface.(interface{ SetConnectionLostHandler(func(error)) }); ok {
+		s.SetConnectionLostHandler(handler)
+	}
+}
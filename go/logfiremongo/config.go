@@ -0,0 +1,74 @@
+// Package logfiremongo instruments the MongoDB Go driver v2 for Pydantic Logfire.
+//
+// A [Monitor] creates a client span for each command, named after the operation and collection
+// like `find users`, with the command as `db.query.text` and its values replaced by `?`:
+//
+//	m := logfiremongo.NewMonitor()
+//	client, err := mongo.Connect(options.Client().ApplyURI(uri).
+//		SetMonitor(m.CommandMonitor()).
+//		SetPoolMonitor(m.PoolMonitor()))
+//
+// Pool events, which the driver reports without a context, are recorded as events of the span
+// of the next command sent to the same server.
+package logfiremongo
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiremongo"
+
+// Option configures [NewMonitor].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithoutSanitization records commands as is, rather than with their values replaced by `?`.
+// Only use it if documents never contain sensitive values.
+func WithoutSanitization() Option {
+	return func(c *config) {
+		c.noSanitization = true
+	}
+}
+
+// WithoutPoolEvents disables recording pool events on the spans of commands.
+func WithoutPoolEvents() Option {
+	return func(c *config) {
+		c.noPoolEvents = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	noSanitization bool
+	noPoolEvents   bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
@@ -0,0 +1,214 @@
+package logfiremongo
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// maxPendingPoolEvents is the number of pool events kept per server until the next command.
+	maxPendingPoolEvents = 32
+
+	// poolConnectionIDKey, poolDurationKey and poolReasonKey are the attributes of pool events.
+	poolConnectionIDKey = attribute.Key("db.mongodb.connection_id")
+	poolDurationKey     = attribute.Key("db.mongodb.duration_ms")
+	poolReasonKey       = attribute.Key("db.mongodb.reason")
+)
+
+// sensitiveCommands are redacted by the driver, so their text isn't recorded.
+var sensitiveCommands = map[string]bool{
+	"authenticate": true, "saslStart": true, "saslContinue": true, "getnonce": true,
+	"createUser": true, "updateUser": true, "copydbgetnonce": true, "copydbsaslstart": true, "copydb": true,
+}
+
+// Monitor creates spans from the events of the driver.
+type Monitor struct {
+	config *config
+
+	mu      sync.Mutex
+	spans   map[spanKey]trace.Span
+	pending map[string][]poolEvent
+}
+
+// spanKey identifies a command in flight.
+type spanKey struct {
+	connectionID string
+	requestID    int64
+}
+
+// poolEvent is an event of a pool waiting to be recorded on the span of the next command.
+type poolEvent struct {
+	name  string
+	time  time.Time
+	attrs []attribute.KeyValue
+}
+
+// NewMonitor returns a monitor, whose [Monitor.CommandMonitor] and [Monitor.PoolMonitor]
+// are set in the options of a client.
+func NewMonitor(opts ...Option) *Monitor {
+	return &Monitor{
+		config:  newConfig(opts),
+		spans:   map[spanKey]trace.Span{},
+		pending: map[string][]poolEvent{},
+	}
+}
+
+// CommandMonitor returns the command monitor of the client, creating the spans of commands.
+func (m *Monitor) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started:   m.started,
+		Succeeded: m.succeeded,
+		Failed:    m.failed,
+	}
+}
+
+// PoolMonitor returns the pool monitor of the client, recording pool events on the spans of commands.
+func (m *Monitor) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: m.poolEvent}
+}
+
+func (m *Monitor) started(ctx context.Context, e *event.CommandStartedEvent) {
+	operation := e.CommandName
+	collection := commandCollection(e.CommandName, e.Command)
+	name := operation
+	if collection != "" {
+		name += " " + collection
+	}
+	msg := name
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemNameMongoDB,
+		semconv.DBOperationName(operation),
+		semconv.DBNamespace(e.DatabaseName),
+	}
+	if collection != "" {
+		attrs = append(attrs, semconv.DBCollectionName(collection))
+	}
+	if !sensitiveCommands[e.CommandName] && len(e.Command) > 0 {
+		if text := commandText(e.Command, !m.config.noSanitization); text != "" {
+			attrs = append(attrs, semconv.DBQueryText(text))
+		}
+		if filter, err := e.Command.LookupErr("filter"); err == nil && filter.Type == bson.TypeEmbeddedDocument {
+			msg += " " + sqlconv.Message(documentText(filter, !m.config.noSanitization))
+		}
+	}
+	address := serverAddress(e.ConnectionID)
+	if host, port := httpconv.SplitHostPort(address); host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if port > 0 {
+			attrs = append(attrs, semconv.ServerPort(port))
+		}
+	}
+	attrs = append(attrs, logfire.MsgKey.String(msg))
+
+	_, span := m.config.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(m.config.attrs...),
+	)
+	m.mu.Lock()
+	m.spans[spanKey{e.ConnectionID, e.RequestID}] = span
+	pending := m.pending[address]
+	delete(m.pending, address)
+	m.mu.Unlock()
+	for _, pe := range pending {
+		span.AddEvent(pe.name, trace.WithTimestamp(pe.time), trace.WithAttributes(pe.attrs...))
+	}
+}
+
+func (m *Monitor) succeeded(_ context.Context, e *event.CommandSucceededEvent) {
+	if span := m.end(e.CommandFinishedEvent); span != nil {
+		span.End()
+	}
+}
+
+func (m *Monitor) failed(_ context.Context, e *event.CommandFailedEvent) {
+	span := m.end(e.CommandFinishedEvent)
+	if span == nil {
+		return
+	}
+	if e.Failure != nil {
+		span.RecordError(e.Failure)
+		span.SetStatus(codes.Error, e.Failure.Error())
+	}
+	span.End()
+}
+
+// end returns the span of a command that finished, if it was started by this monitor.
+func (m *Monitor) end(e event.CommandFinishedEvent) trace.Span {
+	key := spanKey{e.ConnectionID, e.RequestID}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	span := m.spans[key]
+	delete(m.spans, key)
+	return span
+}
+
+// poolEvent keeps the events of a pool until the next command sent to its server.
+// Check-ins and closed connections happen after the commands using them, so they aren't recorded.
+func (m *Monitor) poolEvent(e *event.PoolEvent) {
+	if m.config.noPoolEvents {
+		return
+	}
+	switch e.Type {
+	case event.ConnectionPoolClosed:
+		m.mu.Lock()
+		delete(m.pending, e.Address)
+		m.mu.Unlock()
+		return
+	case event.ConnectionCheckedIn, event.ConnectionClosed, event.ConnectionCheckOutStarted:
+		return
+	}
+	var attrs []attribute.KeyValue
+	if e.ConnectionID != 0 {
+		attrs = append(attrs, poolConnectionIDKey.Int64(e.ConnectionID))
+	}
+	if e.Duration > 0 {
+		attrs = append(attrs, poolDurationKey.Float64(float64(e.Duration)/float64(time.Millisecond)))
+	}
+	if e.Reason != "" {
+		attrs = append(attrs, poolReasonKey.String(e.Reason))
+	}
+	if e.Error != nil {
+		attrs = append(attrs, semconv.ExceptionMessage(e.Error.Error()))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pending := m.pending[e.Address]
+	if len(pending) >= maxPendingPoolEvents {
+		pending = pending[1:]
+	}
+	m.pending[e.Address] = append(pending, poolEvent{name: e.Type, time: time.Now(), attrs: attrs})
+}
+
+// commandCollection returns the collection a command operates on, the value of its first field
+// except for getMore.
+func commandCollection(name string, command bson.Raw) string {
+	if len(command) == 0 {
+		return ""
+	}
+	value := command.Index(0).Value()
+	if name == "getMore" {
+		value = command.Lookup("collection")
+	}
+	collection, _ := value.StringValueOK()
+	return collection
+}
+
+// serverAddress returns the address of the server from the ID of a connection, e.g. `localhost:27017[-3]`.
+func serverAddress(connectionID string) string {
+	address, _, _ := strings.Cut(connectionID, "[-")
+	return address
+}
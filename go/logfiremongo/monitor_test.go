@@ -0,0 +1,182 @@
+package logfiremongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// The tests send events like the driver does, since they can't connect to MongoDB.
+
+const connectionID = "db.example.com:27017[-3]"
+
+func newTestMonitor(t *testing.T, opts ...Option) (*Monitor, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	return NewMonitor(append([]Option{WithTracerProvider(provider)}, opts...)...), recorder
+}
+
+func marshal(t *testing.T, d bson.D) bson.Raw {
+	t.Helper()
+	raw, err := bson.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func runCommand(t *testing.T, m *Monitor, name string, command bson.D, failure error) {
+	t.Helper()
+	ctx := context.Background()
+	cm := m.CommandMonitor()
+	cm.Started(ctx, &event.CommandStartedEvent{
+		Command:      marshal(t, command),
+		DatabaseName: "app",
+		CommandName:  name,
+		RequestID:    1,
+		ConnectionID: connectionID,
+	})
+	finished := event.CommandFinishedEvent{CommandName: name, DatabaseName: "app", RequestID: 1, ConnectionID: connectionID}
+	if failure != nil {
+		cm.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: finished, Failure: failure})
+	} else {
+		cm.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished})
+	}
+}
+
+func TestFind(t *testing.T) {
+	m, recorder := newTestMonitor(t)
+
+	runCommand(t, m, "find", bson.D{
+		{Key: "find", Value: "users"},
+		{Key: "filter", Value: bson.D{{Key: "email", Value: "a@example.com"}, {Key: "age", Value: bson.D{{Key: "$gt", Value: 18}}}}},
+		{Key: "limit", Value: 10},
+		{Key: "lsid", Value: bson.D{{Key: "id", Value: "session"}}},
+		{Key: "$db", Value: "app"},
+	}, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "find users" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		logfire.MsgKey:       `find users {"email":"?","age":{"$gt":"?"}}`,
+		"db.system.name":     "mongodb",
+		"db.namespace":       "app",
+		"db.operation.name":  "find",
+		"db.collection.name": "users",
+		"db.query.text":      `{"find":"users","filter":{"email":"?","age":{"$gt":"?"}},"limit":"?"}`,
+		"server.address":     "db.example.com",
+		"server.port":        int64(27017),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+}
+
+func TestWithoutSanitization(t *testing.T) {
+	m, recorder := newTestMonitor(t, WithoutSanitization())
+
+	runCommand(t, m, "find", bson.D{
+		{Key: "find", Value: "users"},
+		{Key: "filter", Value: bson.D{{Key: "name", Value: "alice"}}},
+	}, nil)
+
+	attrs := oteltest.Attributes(recorder.Ended()[0].Attributes())
+	if got := attrs["db.query.text"]; got != `{"find":"users","filter":{"name":"alice"}}` {
+		t.Errorf("unexpected query text %v", got)
+	}
+}
+
+func TestInsertArrays(t *testing.T) {
+	m, recorder := newTestMonitor(t)
+
+	runCommand(t, m, "insert", bson.D{
+		{Key: "insert", Value: "users"},
+		{Key: "documents", Value: bson.A{
+			bson.D{{Key: "name", Value: "alice"}, {Key: "tags", Value: bson.A{"a", "b"}}},
+			bson.D{{Key: "name", Value: "bob"}},
+		}},
+	}, nil)
+
+	attrs := oteltest.Attributes(recorder.Ended()[0].Attributes())
+	want := `{"insert":"users","documents":[{"name":"?","tags":"?"},{"name":"?"}]}`
+	if got := attrs["db.query.text"]; got != want {
+		t.Errorf("db.query.text = %v, want %v", got, want)
+	}
+}
+
+func TestFailure(t *testing.T) {
+	m, recorder := newTestMonitor(t)
+
+	runCommand(t, m, "delete", bson.D{{Key: "delete", Value: "users"}}, errors.New("not authorized"))
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "not authorized" {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+}
+
+func TestSensitiveCommand(t *testing.T) {
+	m, recorder := newTestMonitor(t)
+
+	runCommand(t, m, "saslStart", bson.D{{Key: "saslStart", Value: 1}, {Key: "payload", Value: "secret"}}, nil)
+
+	span := recorder.Ended()[0]
+	if span.Name() != "saslStart" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if _, ok := oteltest.Attributes(span.Attributes())["db.query.text"]; ok {
+		t.Error("expected the text of sensitive commands not to be recorded")
+	}
+}
+
+func TestPoolEvents(t *testing.T) {
+	m, recorder := newTestMonitor(t)
+	pm := m.PoolMonitor()
+
+	pm.Event(&event.PoolEvent{Type: event.ConnectionCheckOutStarted, Address: "db.example.com:27017"})
+	pm.Event(&event.PoolEvent{Type: event.ConnectionCreated, Address: "db.example.com:27017", ConnectionID: 3})
+	pm.Event(&event.PoolEvent{Type: event.ConnectionCheckedOut, Address: "db.example.com:27017", ConnectionID: 3, Duration: 5 * time.Millisecond})
+	pm.Event(&event.PoolEvent{Type: event.ConnectionCreated, Address: "other.example.com:27017", ConnectionID: 1})
+	runCommand(t, m, "ping", bson.D{{Key: "ping", Value: 1}}, nil)
+	runCommand(t, m, "ping", bson.D{{Key: "ping", Value: 1}}, nil)
+
+	spans := recorder.Ended()
+	events := spans[0].Events()
+	if len(events) != 2 || events[0].Name != event.ConnectionCreated || events[1].Name != event.ConnectionCheckedOut {
+		t.Fatalf("unexpected events %v", events)
+	}
+	attrs := oteltest.Attributes(events[1].Attributes)
+	if attrs["db.mongodb.connection_id"] != int64(3) || attrs["db.mongodb.duration_ms"] != 5.0 {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+	if len(spans[1].Events()) != 0 {
+		t.Errorf("expected the events to be recorded once, got %v", spans[1].Events())
+	}
+}
+
+func TestGetMore(t *testing.T) {
+	if got := commandCollection("getMore", marshal(t, bson.D{{Key: "getMore", Value: int64(42)}, {Key: "collection", Value: "users"}})); got != "users" {
+		t.Errorf("commandCollection = %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package logfiremongo
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// maxArrayElements is the number of elements of arrays of documents kept in commands,
+// e.g. of the documents of an insert.
+const maxArrayElements = 10
+
+// driverFields are added to commands by the driver and aren't useful in spans.
+var driverFields = map[string]bool{
+	"lsid":                 true,
+	"$clusterTime":         true,
+	"$db":                  true,
+	"$readPreference":      true,
+	"txnNumber":            true,
+	"autocommit":           true,
+	"startTransaction":     true,
+	"apiVersion":           true,
+	"apiStrict":            true,
+	"apiDeprecationErrors": true,
+}
+
+// commandText returns a command as relaxed extended JSON without the fields added by the driver.
+// When sanitizing, values are replaced by `?` except for the name of the collection, which is the value
+// of the first field, so that only the shape of filters and documents is recorded.
+func commandText(command bson.Raw, sanitize bool) string {
+	elems, err := command.Elements()
+	if err != nil {
+		return ""
+	}
+	d := make(bson.D, 0, len(elems))
+	for i, elem := range elems {
+		key := elem.Key()
+		if driverFields[key] {
+			continue
+		}
+		value := any(elem.Value())
+		if sanitize && i > 0 {
+			value = sanitizeValue(elem.Value())
+		}
+		d = append(d, bson.E{Key: key, Value: value})
+	}
+	text, err := bson.MarshalExtJSON(d, false, false)
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// documentText returns an embedded document such as a filter as relaxed extended JSON.
+func documentText(v bson.RawValue, sanitize bool) string {
+	var doc any = v.Document()
+	if sanitize {
+		doc = sanitizeValue(v)
+	}
+	text, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// sanitizeValue replaces the values of a document or array by `?`, keeping its keys and operators.
+func sanitizeValue(v bson.RawValue) any {
+	switch v.Type {
+	case bson.TypeEmbeddedDocument:
+		elems, err := v.Document().Elements()
+		if err != nil {
+			return "?"
+		}
+		d := make(bson.D, len(elems))
+		for i, elem := range elems {
+			d[i] = bson.E{Key: elem.Key(), Value: sanitizeValue(elem.Value())}
+		}
+		return d
+	case bson.TypeArray:
+		values, err := v.Array().Values()
+		if err != nil {
+			return "?"
+		}
+		// Lists of values, such as the operand of $in, are collapsed into a single `?`.
+		a := bson.A{}
+		for _, value := range values[:min(len(values), maxArrayElements)] {
+			if value.Type != bson.TypeEmbeddedDocument && value.Type != bson.TypeArray {
+				return "?"
+			}
+			a = append(a, sanitizeValue(value))
+		}
+		if len(values) > maxArrayElements {
+			a = append(a, "…")
+		}
+		return a
+	}
+	return "?"
+}
@@ -0,0 +1,39 @@
+package logfirenats
+
+import (
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// MsgCarrier carries the trace context in the headers of a message.
+//
+// Unlike [propagation.HeaderCarrier], keys aren't canonicalized, as NATS headers are case-sensitive.
+type MsgCarrier struct {
+	msg *nats.Msg
+}
+
+var _ propagation.TextMapCarrier = MsgCarrier{}
+
+// NewMsgCarrier returns a carrier for the headers of a message.
+func NewMsgCarrier(msg *nats.Msg) MsgCarrier {
+	return MsgCarrier{msg: msg}
+}
+
+func (c MsgCarrier) Get(key string) string {
+	return c.msg.Header.Get(key)
+}
+
+func (c MsgCarrier) Set(key, value string) {
+	if c.msg.Header == nil {
+		c.msg.Header = nats.Header{}
+	}
+	c.msg.Header.Set(key, value)
+}
+
+func (c MsgCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.msg.Header))
+	for key := range c.msg.Header {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,77 @@
+// Package logfirenats instruments NATS clients for Pydantic Logfire, for both core NATS and JetStream.
+//
+// [Wrap] returns a connection creating a producer span for each message published, named like
+// `send orders.created`, and a consumer span for each message handled by its subscriptions,
+// named like `process orders.*`, with the trace context propagated in the headers of messages:
+//
+//	conn := logfirenats.Wrap(nc)
+//	err := conn.Publish(ctx, "orders.created", data)
+//	sub, err := conn.Subscribe("orders.*", func(ctx context.Context, msg *nats.Msg) {
+//		// ...
+//	})
+//
+// [WrapJetStream] and [WrapJetStreamHandler] do the same for JetStream, also creating a span for
+// each acknowledgement of a message, named like `ack orders.created`.
+package logfirenats
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirenats"
+
+// system is the `messaging.system` of NATS, which has no constant in the semantic conventions.
+var system = semconv.MessagingSystemKey.String("nats")
+
+// Option configures [Wrap], [WrapJetStream] and [WrapJetStreamHandler].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject and extract the trace context
+// in the headers of messages. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	attrs          []attribute.KeyValue
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
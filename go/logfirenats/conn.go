@@ -0,0 +1,134 @@
+package logfirenats
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/msgconv"
+)
+
+// Conn is a NATS connection creating spans for the messages it publishes and handles.
+// Methods which don't take a context aren't instrumented, use the embedded [nats.Conn] for them.
+type Conn struct {
+	*nats.Conn
+	config *config
+}
+
+// Wrap instruments a NATS connection.
+func Wrap(nc *nats.Conn, opts ...Option) *Conn {
+	return &Conn{Conn: nc, config: newConfig(opts)}
+}
+
+// MsgHandler handles a message, with the context of the span of the message.
+type MsgHandler func(ctx context.Context, msg *nats.Msg)
+
+// Publish publishes data to a subject, as a child of ctx.
+func (c *Conn) Publish(ctx context.Context, subj string, data []byte) error {
+	return c.PublishMsg(ctx, &nats.Msg{Subject: subj, Data: data})
+}
+
+// PublishMsg publishes a message, as a child of ctx.
+func (c *Conn) PublishMsg(ctx context.Context, msg *nats.Msg) error {
+	ctx, span := startSend(ctx, c.config, c.Conn, msg, "send", trace.SpanKindProducer)
+	defer span.End()
+	err := c.Conn.PublishMsg(msg)
+	msgconv.RecordError(span, err)
+	return err
+}
+
+// RequestWithContext sends a request to a subject and waits for the reply.
+func (c *Conn) RequestWithContext(ctx context.Context, subj string, data []byte) (*nats.Msg, error) {
+	return c.RequestMsgWithContext(ctx, &nats.Msg{Subject: subj, Data: data})
+}
+
+// RequestMsgWithContext sends a request message and waits for the reply.
+func (c *Conn) RequestMsgWithContext(ctx context.Context, msg *nats.Msg) (*nats.Msg, error) {
+	ctx, span := startSend(ctx, c.config, c.Conn, msg, "request", trace.SpanKindClient)
+	defer span.End()
+	reply, err := c.Conn.RequestMsgWithContext(ctx, msg)
+	msgconv.RecordError(span, err)
+	return reply, err
+}
+
+// Subscribe subscribes to a subject, creating a span for each message handled.
+func (c *Conn) Subscribe(subj string, handler MsgHandler) (*nats.Subscription, error) {
+	return c.Conn.Subscribe(subj, c.handler(handler))
+}
+
+// QueueSubscribe subscribes to a subject as a member of a queue group, creating a span for each message handled.
+func (c *Conn) QueueSubscribe(subj, queue string, handler MsgHandler) (*nats.Subscription, error) {
+	return c.Conn.QueueSubscribe(subj, queue, c.handler(handler))
+}
+
+func (c *Conn) handler(handler MsgHandler) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		ctx, span := c.startProcess(msg)
+		defer span.End()
+		handler(ctx, msg)
+	}
+}
+
+// startProcess starts the span of a message handled by a subscription, named after the subject
+// of the subscription, which may contain wildcards, rather than of the message.
+func (c *Conn) startProcess(msg *nats.Msg) (context.Context, trace.Span) {
+	ctx := c.config.propagator().Extract(context.Background(), NewMsgCarrier(msg))
+	name := msgconv.SpanName("process", msg.Subject)
+	attrs := msgconv.Attributes(system, semconv.MessagingOperationTypeProcess, "process", msg.Subject)
+	attrs = append(attrs, messageAttributes(msg)...)
+	if msg.Sub != nil {
+		name = msgconv.SpanName("process", msg.Sub.Subject)
+		if msg.Sub.Subject != msg.Subject {
+			attrs = append(attrs, semconv.MessagingDestinationTemplate(msg.Sub.Subject))
+		}
+		if msg.Sub.Queue != "" {
+			attrs = append(attrs, semconv.MessagingConsumerGroupName(msg.Sub.Queue))
+		}
+	}
+	return c.config.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(c.config.attrs...),
+	)
+}
+
+// startSend starts the span of a message to send and injects its context in the headers of the message.
+func startSend(ctx context.Context, c *config, nc *nats.Conn, msg *nats.Msg, operation string, kind trace.SpanKind) (context.Context, trace.Span) {
+	attrs := msgconv.Attributes(system, semconv.MessagingOperationTypeSend, operation, msg.Subject)
+	attrs = append(attrs, messageAttributes(msg)...)
+	attrs = append(attrs, serverAttributes(nc)...)
+	ctx, span := c.tracer().Start(ctx, msgconv.SpanName(operation, msg.Subject),
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(c.attrs...),
+	)
+	c.propagator().Inject(ctx, NewMsgCarrier(msg))
+	return ctx, span
+}
+
+func messageAttributes(msg *nats.Msg) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.MessagingMessageBodySize(len(msg.Data))}
+	if id := msg.Header.Get(nats.MsgIdHdr); id != "" {
+		attrs = append(attrs, semconv.MessagingMessageID(id))
+	}
+	return attrs
+}
+
+func serverAttributes(nc *nats.Conn) []attribute.KeyValue {
+	if nc == nil {
+		return nil
+	}
+	host, port := httpconv.SplitHostPort(nc.ConnectedAddr())
+	if host == "" {
+		return nil
+	}
+	attrs := []attribute.KeyValue{semconv.ServerAddress(host)}
+	if port > 0 {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+	return attrs
+}
@@ -0,0 +1,164 @@
+package logfirenats
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/msgconv"
+)
+
+// JetStream is a JetStream context creating spans for the messages it publishes.
+// Asynchronous publishing isn't instrumented.
+type JetStream struct {
+	jetstream.JetStream
+	config *config
+}
+
+// WrapJetStream instruments a JetStream context.
+func WrapJetStream(js jetstream.JetStream, opts ...Option) *JetStream {
+	return &JetStream{JetStream: js, config: newConfig(opts)}
+}
+
+// Publish publishes data to a stream, as a child of ctx.
+func (js *JetStream) Publish(ctx context.Context, subj string, data []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	return js.PublishMsg(ctx, &nats.Msg{Subject: subj, Data: data}, opts...)
+}
+
+// PublishMsg publishes a message to a stream, as a child of ctx, recording the sequence of the message in the stream.
+func (js *JetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	ctx, span := startSend(ctx, js.config, js.Conn(), msg, "send", trace.SpanKindProducer)
+	defer span.End()
+	ack, err := js.JetStream.PublishMsg(ctx, msg, opts...)
+	if err != nil {
+		msgconv.RecordError(span, err)
+		return ack, err
+	}
+	span.SetAttributes(
+		streamKey.String(ack.Stream),
+		streamSequenceKey.Int64(int64(ack.Sequence)),
+	)
+	if ack.Duplicate {
+		span.SetAttributes(duplicateKey.Bool(true))
+	}
+	return ack, nil
+}
+
+const (
+	// streamKey records the stream of a message.
+	streamKey = attribute.Key("messaging.nats.stream")
+	// streamSequenceKey records the sequence of a message in its stream.
+	streamSequenceKey = attribute.Key("messaging.nats.stream_sequence")
+	// duplicateKey records that a published message was a duplicate of one already in the stream.
+	duplicateKey = attribute.Key("messaging.nats.duplicate")
+	// deliveryCountKey records how many times a message was delivered to the consumer.
+	deliveryCountKey = attribute.Key("messaging.nats.delivery_count")
+	// ackKey records how the handler of a message acknowledged it, e.g. `ack` or `nak`.
+	ackKey = attribute.Key("messaging.nats.ack")
+)
+
+// JetStreamHandler handles a JetStream message, with the context of the span of the message.
+type JetStreamHandler func(ctx context.Context, msg jetstream.Msg)
+
+// WrapJetStreamHandler returns a handler for [jetstream.Consumer.Consume] creating a span for each
+// message, named like `process orders.created`, which ends when the handler returns.
+//
+// Acknowledging a message creates a span named after the acknowledgement, e.g. `ack orders.created`
+// or `nak orders.created`, and records it on the span of the message. The handler can also be called
+// directly with the messages returned by [jetstream.Consumer.Fetch] or [jetstream.Consumer.Next].
+func WrapJetStreamHandler(handler JetStreamHandler, opts ...Option) jetstream.MessageHandler {
+	c := newConfig(opts)
+	return func(msg jetstream.Msg) {
+		ctx := c.propagator().Extract(context.Background(), NewMsgCarrier(&nats.Msg{Header: msg.Headers()}))
+		attrs := msgconv.Attributes(system, semconv.MessagingOperationTypeProcess, "process", msg.Subject())
+		attrs = append(attrs, jetStreamAttributes(msg)...)
+		ctx, span := c.tracer().Start(ctx, msgconv.SpanName("process", msg.Subject()),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attrs...),
+			trace.WithAttributes(c.attrs...),
+		)
+		defer span.End()
+		handler(ctx, &ackMsg{Msg: msg, ctx: ctx, config: c, span: span})
+	}
+}
+
+func jetStreamAttributes(msg jetstream.Msg) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.MessagingMessageBodySize(len(msg.Data()))}
+	if id := msg.Headers().Get(nats.MsgIdHdr); id != "" {
+		attrs = append(attrs, semconv.MessagingMessageID(id))
+	}
+	meta, err := msg.Metadata()
+	if err != nil {
+		return attrs
+	}
+	return append(attrs,
+		streamKey.String(meta.Stream),
+		streamSequenceKey.Int64(int64(meta.Sequence.Stream)),
+		semconv.MessagingConsumerGroupName(meta.Consumer),
+		deliveryCountKey.Int64(int64(meta.NumDelivered)),
+	)
+}
+
+// ackMsg is a JetStream message recording its acknowledgements.
+type ackMsg struct {
+	jetstream.Msg
+	ctx    context.Context
+	config *config
+	span   trace.Span
+}
+
+func (m *ackMsg) Ack() error {
+	return m.settle("ack", m.Msg.Ack)
+}
+
+func (m *ackMsg) DoubleAck(ctx context.Context) error {
+	return m.settle("ack", func() error { return m.Msg.DoubleAck(ctx) })
+}
+
+func (m *ackMsg) Nak() error {
+	return m.settle("nak", m.Msg.Nak)
+}
+
+func (m *ackMsg) NakWithDelay(delay time.Duration) error {
+	return m.settle("nak", func() error { return m.Msg.NakWithDelay(delay) })
+}
+
+func (m *ackMsg) Term() error {
+	return m.settle("term", m.Msg.Term)
+}
+
+func (m *ackMsg) TermWithReason(reason string) error {
+	return m.settle("term", func() error { return m.Msg.TermWithReason(reason) })
+}
+
+// InProgress only adds an event, as it doesn't settle the message.
+func (m *ackMsg) InProgress() error {
+	err := m.Msg.InProgress()
+	if err == nil {
+		m.span.AddEvent("in_progress")
+	}
+	return err
+}
+
+// settle creates the span of an acknowledgement and records it on the span of the message,
+// unless it already ended because the message is acknowledged after the handler returns.
+func (m *ackMsg) settle(operation string, ack func() error) error {
+	attrs := msgconv.Attributes(system, semconv.MessagingOperationTypeSettle, operation, m.Msg.Subject())
+	_, span := m.config.tracer().Start(m.ctx, msgconv.SpanName(operation, m.Msg.Subject()),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(m.config.attrs...),
+	)
+	defer span.End()
+	err := ack()
+	msgconv.RecordError(span, err)
+	if err == nil {
+		m.span.SetAttributes(ackKey.String(operation))
+	}
+	return err
+}
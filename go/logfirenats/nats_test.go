@@ -0,0 +1,247 @@
+package logfirenats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// connect starts an embedded NATS server with JetStream enabled and connects to it.
+func connect(t *testing.T) *nats.Conn {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	t.Cleanup(s.Shutdown)
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("the server isn't ready")
+	}
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func newTestProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	return provider, recorder
+}
+
+func testOptions(provider trace.TracerProvider) []Option {
+	return []Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}
+}
+
+// waitForSpans waits for the spans ended by subscriptions, which handle messages in the background.
+func waitForSpans(t *testing.T, recorder *tracetest.SpanRecorder, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recorder.Ended()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d spans, got %d", n, len(recorder.Ended()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func spanNamed(t *testing.T, spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("no span named %q", name)
+	return nil
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	provider, recorder := newTestProvider(t)
+	conn := Wrap(connect(t), testOptions(provider)...)
+
+	sub, err := conn.QueueSubscribe("orders.*", "billing", func(ctx context.Context, msg *nats.Msg) {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			t.Error("expected the context to contain the span")
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	if err := conn.Publish(t.Context(), "orders.created", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	waitForSpans(t, recorder, 2)
+
+	spans := recorder.Ended()
+	send := spanNamed(t, spans, "send orders.created")
+	if send.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("unexpected span kind %v", send.SpanKind())
+	}
+	oteltest.CheckAttributes(t, send.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:                "send orders.created",
+		"messaging.system":            "nats",
+		"messaging.operation.type":    "send",
+		"messaging.destination.name":  "orders.created",
+		"messaging.message.body.size": int64(5),
+		"server.address":              "127.0.0.1",
+	})
+	if _, ok := oteltest.Attributes(send.Attributes())["server.port"]; !ok {
+		t.Error("expected the server port")
+	}
+
+	process := spanNamed(t, spans, "process orders.*")
+	if process.Parent().SpanID() != send.SpanContext().SpanID() {
+		t.Error("expected the process span to continue the trace of the publisher")
+	}
+	oteltest.CheckAttributes(t, process.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:                   "process orders.created",
+		"messaging.operation.type":       "process",
+		"messaging.destination.name":     "orders.created",
+		"messaging.destination.template": "orders.*",
+		"messaging.consumer.group.name":  "billing",
+	})
+}
+
+func TestRequest(t *testing.T) {
+	provider, recorder := newTestProvider(t)
+	conn := Wrap(connect(t), testOptions(provider)...)
+
+	sub, err := conn.Subscribe("users.get", func(ctx context.Context, msg *nats.Msg) {
+		_ = msg.Respond([]byte("alice"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	reply, err := conn.RequestWithContext(t.Context(), "users.get", []byte("42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reply.Data) != "alice" {
+		t.Errorf("unexpected reply %q", reply.Data)
+	}
+
+	span := spanNamed(t, recorder.Ended(), "request users.get")
+	if span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span kind %v", span.SpanKind())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"messaging.operation.name": "request",
+		"messaging.operation.type": "send",
+	})
+}
+
+func TestPublishError(t *testing.T) {
+	provider, recorder := newTestProvider(t)
+	nc := connect(t)
+	nc.Close()
+	conn := Wrap(nc, testOptions(provider)...)
+
+	if err := conn.Publish(t.Context(), "orders.created", nil); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	span := recorder.Ended()[0]
+	if span.Status().Description != nats.ErrConnectionClosed.Error() {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+}
+
+func TestJetStream(t *testing.T) {
+	provider, recorder := newTestProvider(t)
+	nc := connect(t)
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := js.CreateStream(t.Context(), jetstream.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumer, err := stream.CreateConsumer(t.Context(), jetstream.ConsumerConfig{Durable: "billing", AckPolicy: jetstream.AckExplicitPolicy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracedJS := WrapJetStream(js, testOptions(provider)...)
+
+	if _, err := tracedJS.Publish(t.Context(), "orders.created", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracedJS.PublishMsg(t.Context(), &nats.Msg{Subject: "orders.failed", Data: []byte("b")}); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := WrapJetStreamHandler(func(ctx context.Context, msg jetstream.Msg) {
+		if msg.Subject() == "orders.failed" {
+			_ = msg.Nak()
+			return
+		}
+		_ = msg.InProgress()
+		_ = msg.Ack()
+	}, testOptions(provider)...)
+	batch, err := consumer.Fetch(2, jetstream.FetchMaxWait(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for msg := range batch.Messages() {
+		handler(msg)
+	}
+	if err := batch.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	send := spanNamed(t, spans, "send orders.created")
+	oteltest.CheckAttributes(t, send.Attributes(), map[attribute.Key]any{
+		"messaging.nats.stream":          "ORDERS",
+		"messaging.nats.stream_sequence": int64(1),
+	})
+
+	process := spanNamed(t, spans, "process orders.created")
+	if process.Parent().SpanID() != send.SpanContext().SpanID() {
+		t.Error("expected the process span to continue the trace of the publisher")
+	}
+	oteltest.CheckAttributes(t, process.Attributes(), map[attribute.Key]any{
+		"messaging.nats.stream":          "ORDERS",
+		"messaging.nats.stream_sequence": int64(1),
+		"messaging.consumer.group.name":  "billing",
+		"messaging.nats.delivery_count":  int64(1),
+		"messaging.nats.ack":             "ack",
+	})
+	if len(process.Events()) != 1 || process.Events()[0].Name != "in_progress" {
+		t.Errorf("expected an in_progress event, got %v", process.Events())
+	}
+	ack := spanNamed(t, spans, "ack orders.created")
+	if ack.Parent().SpanID() != process.SpanContext().SpanID() {
+		t.Error("expected the ack span to be a child of the process span")
+	}
+	oteltest.CheckAttributes(t, ack.Attributes(), map[attribute.Key]any{
+		"messaging.operation.type": "settle",
+	})
+
+	oteltest.CheckAttributes(t, spanNamed(t, spans, "process orders.failed").Attributes(), map[attribute.Key]any{
+		"messaging.nats.ack": "nak",
+	})
+	spanNamed(t, spans, "nak orders.failed")
+}
@@ -0,0 +1,440 @@
+package logfireollama
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+	"github.com/pydantic/logfire/go/internal/httpconv"
+)
+
+// provider is the `gen_ai.provider.name` of the spans.
+const provider = "ollama"
+
+const (
+	// numCtxKey records the size of the context window requested, in tokens.
+	numCtxKey = attribute.Key("ollama.request.num_ctx")
+	// keepAliveKey records how long the model stays loaded after the call, e.g. `5m`.
+	keepAliveKey = attribute.Key("ollama.request.keep_alive")
+	// totalDurationKey records the time Ollama spent on the call, in milliseconds.
+	totalDurationKey = attribute.Key("ollama.total_duration_ms")
+	// loadDurationKey records the time spent loading the model, in milliseconds.
+	loadDurationKey = attribute.Key("ollama.load_duration_ms")
+	// promptEvalDurationKey records the time spent evaluating the prompt, in milliseconds.
+	promptEvalDurationKey = attribute.Key("ollama.prompt_eval_duration_ms")
+	// evalDurationKey records the time spent generating tokens, in milliseconds.
+	evalDurationKey = attribute.Key("ollama.eval_duration_ms")
+	// evalRateKey records the number of tokens generated per second.
+	evalRateKey = attribute.Key("ollama.eval_rate")
+)
+
+// NewTransport wraps base so that a span is created for each call of the generate, chat and
+// embeddings endpoints. Other calls, like pulling models, aren't traced. If base is nil,
+// [http.DefaultTransport] is used.
+func NewTransport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c := newConfig(opts)
+	return &transport{
+		base: base,
+		client: &genaiconv.Client{
+			Tracer:     c.tracer(),
+			Provider:   provider,
+			Content:    c.content,
+			Attributes: c.attrs,
+			Progress:   c.progress,
+			Metrics:    c.metrics(),
+		},
+	}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	client *genaiconv.Client
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodPost {
+		return t.base.RoundTrip(r)
+	}
+	var endpoint *endpoint
+	for _, e := range endpoints {
+		if strings.HasSuffix(r.URL.Path, e.path) {
+			endpoint = &e
+			break
+		}
+	}
+	if endpoint == nil {
+		return t.base.RoundTrip(r)
+	}
+	// RoundTrippers must not modify the request, but may replace its body once read.
+	r = r.Clone(r.Context())
+	body, err := genaiconv.ReadRequestBody(r)
+	if err != nil {
+		return t.base.RoundTrip(r)
+	}
+	call, err := endpoint.call(body, t.client.Content)
+	if err != nil {
+		return t.base.RoundTrip(r)
+	}
+	if host, port := httpconv.SplitHostPort(r.URL.Host); host != "" {
+		call.Attributes = append(call.Attributes, semconv.ServerAddress(host))
+		if port > 0 {
+			call.Attributes = append(call.Attributes, semconv.ServerPort(port))
+		}
+	}
+	var stream genaiconv.Stream
+	if endpoint.stream != nil {
+		stream = endpoint.stream()
+	}
+	return t.client.Do(r, call, t.base.RoundTrip, endpoint.response, stream)
+}
+
+// endpoint parses the requests and responses of an endpoint of the API.
+type endpoint struct {
+	path     string
+	call     func(body []byte, content bool) (genaiconv.Call, error)
+	response func(body []byte) genaiconv.Response
+	stream   func() genaiconv.Stream
+}
+
+var endpoints = []endpoint{
+	{"/api/chat", chatCall, chatResponse, func() genaiconv.Stream { return &chatStream{} }},
+	{"/api/generate", generateCall, generateResponse, func() genaiconv.Stream { return &generateStream{} }},
+	{"/api/embed", embedCall, embedResponse, nil},
+	{"/api/embeddings", embedCall, embedResponse, nil},
+}
+
+// params are the parameters shared by the endpoints. Responses are streamed unless
+// stream is false.
+type params struct {
+	Model     string          `json:"model"`
+	Stream    *bool           `json:"stream"`
+	KeepAlive json.RawMessage `json:"keep_alive"`
+	Options   struct {
+		NumPredict       *int64   `json:"num_predict"`
+		NumCtx           *int64   `json:"num_ctx"`
+		Temperature      *float64 `json:"temperature"`
+		TopP             *float64 `json:"top_p"`
+		TopK             *float64 `json:"top_k"`
+		Seed             *int64   `json:"seed"`
+		Stop             []string `json:"stop"`
+		PresencePenalty  *float64 `json:"presence_penalty"`
+		FrequencyPenalty *float64 `json:"frequency_penalty"`
+	} `json:"options"`
+}
+
+func (p params) call(operation string, streams bool) genaiconv.Call {
+	o := p.Options
+	gp := genaiconv.Params{
+		MaxTokens:        o.NumPredict,
+		Temperature:      o.Temperature,
+		TopP:             o.TopP,
+		TopK:             o.TopK,
+		FrequencyPenalty: o.FrequencyPenalty,
+		PresencePenalty:  o.PresencePenalty,
+		Seed:             o.Seed,
+		StopSequences:    o.Stop,
+	}
+	call := genaiconv.Call{Operation: operation, Model: p.Model, Attributes: gp.Attributes()}
+	if streams {
+		call.Stream = p.Stream == nil || *p.Stream
+		call.Attributes = append(call.Attributes, genaiconv.RequestStreamKey.Bool(call.Stream))
+	}
+	if o.NumCtx != nil {
+		call.Attributes = append(call.Attributes, numCtxKey.Int64(*o.NumCtx))
+	}
+	if len(p.KeepAlive) > 0 && string(p.KeepAlive) != "null" {
+		// The duration is either a string like `5m` or a number of seconds.
+		keepAlive := string(p.KeepAlive)
+		json.Unmarshal(p.KeepAlive, &keepAlive)
+		call.Attributes = append(call.Attributes, keepAliveKey.String(keepAlive))
+	}
+	return call
+}
+
+// metrics are the token counts and durations, in nanoseconds, of the last message of a response.
+type metrics struct {
+	TotalDuration      int64 `json:"total_duration"`
+	LoadDuration       int64 `json:"load_duration"`
+	PromptEvalCount    int64 `json:"prompt_eval_count"`
+	PromptEvalDuration int64 `json:"prompt_eval_duration"`
+	EvalCount          int64 `json:"eval_count"`
+	EvalDuration       int64 `json:"eval_duration"`
+}
+
+func milliseconds(ns int64) float64 {
+	return float64(ns) / 1e6
+}
+
+// record records the usage and durations in resp.
+func (m metrics) record(resp *genaiconv.Response) {
+	resp.Usage = &genaiconv.Usage{InputTokens: m.PromptEvalCount, OutputTokens: m.EvalCount}
+	for _, d := range []struct {
+		key attribute.Key
+		ns  int64
+	}{
+		{totalDurationKey, m.TotalDuration},
+		{loadDurationKey, m.LoadDuration},
+		{promptEvalDurationKey, m.PromptEvalDuration},
+		{evalDurationKey, m.EvalDuration},
+	} {
+		if d.ns > 0 {
+			resp.Attributes = append(resp.Attributes, d.key.Float64(milliseconds(d.ns)))
+		}
+	}
+	if m.EvalCount > 0 && m.EvalDuration > 0 {
+		resp.Attributes = append(resp.Attributes, evalRateKey.Float64(float64(m.EvalCount)/(float64(m.EvalDuration)/1e9)))
+	}
+}
+
+type toolCall struct {
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments any    `json:"arguments"`
+	} `json:"function"`
+}
+
+type message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	Thinking   string     `json:"thinking"`
+	Images     []string   `json:"images"`
+	ToolCalls  []toolCall `json:"tool_calls"`
+	ToolName   string     `json:"tool_name"`
+	ToolCallID string     `json:"tool_call_id"`
+}
+
+func (m message) message() genaiconv.Message {
+	role := strings.ToLower(m.Role)
+	if role == "tool" {
+		part := genaiconv.ToolCallResponsePart(m.ToolCallID, m.Content)
+		part.Name = m.ToolName
+		return genaiconv.Message{Role: role, Parts: []genaiconv.Part{part}}
+	}
+	msg := genaiconv.Message{Role: role, Parts: []genaiconv.Part{}}
+	if m.Thinking != "" {
+		msg.Parts = append(msg.Parts, genaiconv.Part{Type: "reasoning", Content: m.Thinking})
+	}
+	if m.Content != "" {
+		msg.Parts = append(msg.Parts, genaiconv.TextPart(m.Content))
+	}
+	for range m.Images {
+		msg.Parts = append(msg.Parts, genaiconv.Part{Type: "blob"})
+	}
+	for _, call := range m.ToolCalls {
+		msg.Parts = append(msg.Parts, genaiconv.ToolCallPart(call.ID, call.Function.Name, call.Function.Arguments))
+	}
+	return msg
+}
+
+func chatCall(body []byte, content bool) (genaiconv.Call, error) {
+	var req struct {
+		params
+		Messages []message `json:"messages"`
+		Tools    []any     `json:"tools"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return genaiconv.Call{}, err
+	}
+	call := req.call(genaiconv.OperationChat, true)
+	if content {
+		for _, m := range req.Messages {
+			call.Messages = append(call.Messages, m.message())
+		}
+		if len(req.Tools) > 0 {
+			call.Attributes = append(call.Attributes, genaiconv.JSON(genaiconv.ToolDefinitionsKey, req.Tools))
+		}
+	}
+	return call, nil
+}
+
+type chat struct {
+	metrics
+	Model      string  `json:"model"`
+	Message    message `json:"message"`
+	Done       bool    `json:"done"`
+	DoneReason string  `json:"done_reason"`
+	Error      string  `json:"error"`
+}
+
+func (c chat) response() genaiconv.Response {
+	resp := genaiconv.Response{Model: c.Model}
+	if c.Done {
+		c.metrics.record(&resp)
+	}
+	msg := c.Message.message()
+	if msg.Role == "" {
+		msg.Role = "assistant"
+	}
+	msg.FinishReason = c.DoneReason
+	resp.Output = []genaiconv.Message{msg}
+	if c.DoneReason != "" {
+		resp.FinishReasons = []string{c.DoneReason}
+	}
+	return resp
+}
+
+func chatResponse(body []byte) genaiconv.Response {
+	var c chat
+	if json.Unmarshal(body, &c) != nil {
+		return genaiconv.Response{}
+	}
+	return c.response()
+}
+
+// chatStream accumulates the messages of a streamed chat, whose content and thinking come in
+// deltas, and whose last message has the metrics.
+type chatStream struct {
+	genaiconv.ChunkCounter
+	merged chat
+}
+
+func (s *chatStream) Line(line []byte) (bool, error) {
+	var chunk chat
+	if json.Unmarshal(line, &chunk) != nil {
+		return false, nil
+	}
+	if chunk.Error != "" {
+		return true, errors.New(chunk.Error)
+	}
+	m := &s.merged
+	m.Model = chunk.Model
+	if chunk.Message.Role != "" {
+		m.Message.Role = chunk.Message.Role
+	}
+	m.Message.Content += chunk.Message.Content
+	m.Message.Thinking += chunk.Message.Thinking
+	m.Message.ToolCalls = append(m.Message.ToolCalls, chunk.Message.ToolCalls...)
+	if chunk.Message.Content != "" || chunk.Message.Thinking != "" || len(chunk.Message.ToolCalls) > 0 {
+		s.Add()
+	}
+	if chunk.Done {
+		m.Done, m.DoneReason, m.metrics = true, chunk.DoneReason, chunk.metrics
+	}
+	return chunk.Done, nil
+}
+
+func (s *chatStream) Response() genaiconv.Response {
+	return s.merged.response()
+}
+
+func generateCall(body []byte, content bool) (genaiconv.Call, error) {
+	var req struct {
+		params
+		Prompt string   `json:"prompt"`
+		System string   `json:"system"`
+		Images []string `json:"images"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return genaiconv.Call{}, err
+	}
+	call := req.call(genaiconv.OperationTextCompletion, true)
+	if content {
+		if req.System != "" {
+			call.System = []genaiconv.Part{genaiconv.TextPart(req.System)}
+		}
+		msg := message{Role: "user", Content: req.Prompt, Images: req.Images}.message()
+		if len(msg.Parts) > 0 {
+			call.Messages = []genaiconv.Message{msg}
+		}
+	}
+	return call, nil
+}
+
+type generate struct {
+	metrics
+	Model      string `json:"model"`
+	Response   string `json:"response"`
+	Thinking   string `json:"thinking"`
+	Done       bool   `json:"done"`
+	DoneReason string `json:"done_reason"`
+	Error      string `json:"error"`
+}
+
+func (g generate) response() genaiconv.Response {
+	return chat{
+		metrics:    g.metrics,
+		Model:      g.Model,
+		Message:    message{Role: "assistant", Content: g.Response, Thinking: g.Thinking},
+		Done:       g.Done,
+		DoneReason: g.DoneReason,
+	}.response()
+}
+
+func generateResponse(body []byte) genaiconv.Response {
+	var g generate
+	if json.Unmarshal(body, &g) != nil {
+		return genaiconv.Response{}
+	}
+	return g.response()
+}
+
+// generateStream accumulates the text of a streamed generation.
+type generateStream struct {
+	genaiconv.ChunkCounter
+	merged generate
+}
+
+func (s *generateStream) Line(line []byte) (bool, error) {
+	var chunk generate
+	if json.Unmarshal(line, &chunk) != nil {
+		return false, nil
+	}
+	if chunk.Error != "" {
+		return true, errors.New(chunk.Error)
+	}
+	m := &s.merged
+	m.Model = chunk.Model
+	m.Response += chunk.Response
+	m.Thinking += chunk.Thinking
+	if chunk.Response != "" || chunk.Thinking != "" {
+		s.Add()
+	}
+	if chunk.Done {
+		m.Done, m.DoneReason, m.metrics = true, chunk.DoneReason, chunk.metrics
+	}
+	return chunk.Done, nil
+}
+
+func (s *generateStream) Response() genaiconv.Response {
+	return s.merged.response()
+}
+
+func embedCall(body []byte, _ bool) (genaiconv.Call, error) {
+	var req struct {
+		params
+		Dimensions *int64 `json:"dimensions"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return genaiconv.Call{}, err
+	}
+	call := req.call(genaiconv.OperationEmbeddings, false)
+	if req.Dimensions != nil {
+		call.Attributes = append(call.Attributes, genaiconv.EmbeddingsDimensionCountKey.Int64(*req.Dimensions))
+	}
+	return call, nil
+}
+
+func embedResponse(body []byte) genaiconv.Response {
+	var e struct {
+		metrics
+		Model string `json:"model"`
+	}
+	if json.Unmarshal(body, &e) != nil {
+		return genaiconv.Response{}
+	}
+	resp := genaiconv.Response{Model: e.Model}
+	// The legacy embeddings endpoint doesn't report metrics.
+	if e.PromptEvalCount > 0 || e.TotalDuration > 0 {
+		e.metrics.record(&resp)
+	}
+	return resp
+}
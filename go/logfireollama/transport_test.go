@@ -0,0 +1,198 @@
+package logfireollama
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func checkJSON(t *testing.T, attrs []attribute.KeyValue, key attribute.Key, want string) {
+	t.Helper()
+	got, _ := oteltest.Attributes(attrs)[key].(string)
+	var g, w any
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Errorf("%s = %q, not JSON", key, got)
+		return
+	}
+	json.Unmarshal([]byte(want), &w)
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("%s = %s, want %s", key, gb, wb)
+	}
+}
+
+// chatNDJSON is a streamed chat, whose last message has the metrics.
+const chatNDJSON = `{"model":"llama3.2","message":{"role":"assistant","content":"Hel","thinking":"Greet."},"done":false}
+{"model":"llama3.2","message":{"role":"assistant","content":"lo"},"done":false}
+{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","total_duration":3000000000,"load_duration":2000000000,"prompt_eval_count":12,"prompt_eval_duration":500000000,"eval_count":20,"eval_duration":400000000}
+`
+
+func newClient(t *testing.T, handler http.HandlerFunc, opts ...Option) (*api.Client, *tracetest.SpanRecorder, *url.URL) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	base, _ := url.Parse(server.URL)
+	client := api.NewClient(base, &http.Client{Transport: NewTransport(nil, append(opts, WithTracerProvider(provider))...)})
+	return client, recorder, base
+}
+
+func TestChatStream(t *testing.T) {
+	client, recorder, base := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, chatNDJSON)
+	}, WithMessageContent(), WithStreamProgress(0))
+
+	var text strings.Builder
+	err := client.Chat(context.Background(), &api.ChatRequest{
+		Model:     "llama3.2",
+		Messages:  []api.Message{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "Hi"}},
+		KeepAlive: &api.Duration{Duration: 5 * time.Minute},
+		Options:   map[string]any{"temperature": 0.2, "num_ctx": 8192},
+	}, func(resp api.ChatResponse) error {
+		text.WriteString(resp.Message.Content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "Hello" {
+		t.Errorf("text = %q", text.String())
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "chat llama3.2" {
+		t.Errorf("name = %s", span.Name())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"gen_ai.provider.name":        "ollama",
+		"gen_ai.request.model":        "llama3.2",
+		"gen_ai.request.temperature":  0.2,
+		"gen_ai.request.stream":       true,
+		"gen_ai.response.model":       "llama3.2",
+		"gen_ai.usage.input_tokens":   int64(12),
+		"gen_ai.usage.output_tokens":  int64(20),
+		"gen_ai.response.chunk_count": int64(2),
+		"server.address":              base.Hostname(),
+		numCtxKey:                     int64(8192),
+		keepAliveKey:                  "5m0s",
+		totalDurationKey:              3000.0,
+		loadDurationKey:               2000.0,
+		promptEvalDurationKey:         500.0,
+		evalDurationKey:               400.0,
+		evalRateKey:                   50.0,
+	})
+	checkJSON(t, span.Attributes(), "gen_ai.input.messages", `[
+		{"role":"system","parts":[{"type":"text","content":"Be brief."}]},
+		{"role":"user","parts":[{"type":"text","content":"Hi"}]}
+	]`)
+	checkJSON(t, span.Attributes(), "gen_ai.output.messages", `[{"role":"assistant","parts":[
+		{"type":"reasoning","content":"Greet."},
+		{"type":"text","content":"Hello"}
+	],"finish_reason":"stop"}]`)
+}
+
+func TestChatTools(t *testing.T) {
+	client, recorder, _ := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"model":"llama3.2","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"weather","arguments":{"city":"Paris"}}}]},"done":true,"done_reason":"stop","prompt_eval_count":30,"eval_count":10}`+"\n")
+	}, WithMessageContent())
+
+	stream := false
+	err := client.Chat(context.Background(), &api.ChatRequest{
+		Model:  "llama3.2",
+		Stream: &stream,
+		Messages: []api.Message{
+			{Role: "user", Content: "Weather in Paris?"},
+			{Role: "tool", Content: `{"temperature":20}`, ToolName: "weather"},
+		},
+	}, func(api.ChatResponse) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := recorder.Ended()[0]
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"gen_ai.request.stream":      false,
+		"gen_ai.usage.output_tokens": int64(10),
+	})
+	checkJSON(t, span.Attributes(), "gen_ai.input.messages", `[
+		{"role":"user","parts":[{"type":"text","content":"Weather in Paris?"}]},
+		{"role":"tool","parts":[{"type":"tool_call_response","name":"weather","response":{"temperature":20}}]}
+	]`)
+	checkJSON(t, span.Attributes(), "gen_ai.output.messages", `[{"role":"assistant","parts":[
+		{"type":"tool_call","name":"weather","arguments":{"city":"Paris"}}
+	],"finish_reason":"stop"}]`)
+}
+
+func TestGenerateStreamError(t *testing.T) {
+	client, recorder, _ := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"model":"llama3.2","response":"Par","done":false}`+"\n"+`{"error":"model runner has unexpectedly stopped"}`+"\n")
+	}, WithMessageContent())
+
+	err := client.Generate(context.Background(), &api.GenerateRequest{Model: "llama3.2", Prompt: "Capital of France?", System: "Be brief."},
+		func(api.GenerateResponse) error { return nil })
+	if err == nil {
+		t.Fatal("no error")
+	}
+	span := recorder.Ended()[0]
+	if span.Name() != "text_completion llama3.2" {
+		t.Errorf("name = %s", span.Name())
+	}
+	if span.Status().Code != codes.Error || span.Status().Description != "model runner has unexpectedly stopped" {
+		t.Errorf("status = %v", span.Status())
+	}
+	checkJSON(t, span.Attributes(), "gen_ai.system_instructions", `[{"type":"text","content":"Be brief."}]`)
+	checkJSON(t, span.Attributes(), "gen_ai.output.messages", `[{"role":"assistant","parts":[{"type":"text","content":"Par"}]}]`)
+}
+
+func TestEmbed(t *testing.T) {
+	client, recorder, _ := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"model":"nomic-embed-text","embeddings":[[0.1,0.2]],"total_duration":14000000,"load_duration":1000000,"prompt_eval_count":2}`)
+	})
+	if _, err := client.Embed(context.Background(), &api.EmbedRequest{Model: "nomic-embed-text", Input: "Hi", Dimensions: 2}); err != nil {
+		t.Fatal(err)
+	}
+	span := recorder.Ended()[0]
+	if span.Name() != "embeddings nomic-embed-text" {
+		t.Errorf("name = %s", span.Name())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"gen_ai.usage.input_tokens":         int64(2),
+		"gen_ai.embeddings.dimension.count": int64(2),
+		totalDurationKey:                    14.0,
+	})
+	if _, ok := oteltest.Attributes(span.Attributes())["gen_ai.request.stream"]; ok {
+		t.Error("stream recorded for embeddings")
+	}
+}
+
+func TestNotTraced(t *testing.T) {
+	client, recorder, _ := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"models":[]}`)
+	})
+	if _, err := client.List(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("spans = %d, want 0", len(spans))
+	}
+}
@@ -0,0 +1,254 @@
+package logfireopenai
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+)
+
+type chatMessage struct {
+	Role       string          `json:"role"`
+	Name       string          `json:"name"`
+	Content    json.RawMessage `json:"content"`
+	Refusal    string          `json:"refusal"`
+	ToolCalls  []toolCall      `json:"tool_calls"`
+	ToolCallID string          `json:"tool_call_id"`
+}
+
+type toolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// contentParts converts the content of a message, which is a string or an array of parts.
+func contentParts(raw json.RawMessage) []genaiconv.Part {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		if text == "" {
+			return nil
+		}
+		return []genaiconv.Part{genaiconv.TextPart(text)}
+	}
+	var parts []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Refusal  string `json:"refusal"`
+		ImageURL struct {
+			URL string `json:"url"`
+		} `json:"image_url"`
+		InputAudio struct {
+			Format string `json:"format"`
+		} `json:"input_audio"`
+		File struct {
+			FileID   string `json:"file_id"`
+			Filename string `json:"filename"`
+		} `json:"file"`
+	}
+	json.Unmarshal(raw, &parts)
+	var converted []genaiconv.Part
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			converted = append(converted, genaiconv.TextPart(p.Text))
+		case "refusal":
+			converted = append(converted, genaiconv.Part{Type: "refusal", Content: p.Refusal})
+		case "image_url":
+			converted = append(converted, mediaPart(p.ImageURL.URL))
+		case "input_audio":
+			converted = append(converted, genaiconv.Part{Type: "blob", MIMEType: "audio/" + p.InputAudio.Format})
+		case "file":
+			converted = append(converted, genaiconv.Part{Type: "file", ID: p.File.FileID, Name: p.File.Filename})
+		default:
+			converted = append(converted, genaiconv.Part{Type: p.Type})
+		}
+	}
+	return converted
+}
+
+// mediaPart returns the part of an image URL, without the content of data URLs, which are large.
+func mediaPart(url string) genaiconv.Part {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		mimeType, _, _ := strings.Cut(rest, ";")
+		return genaiconv.Part{Type: "blob", MIMEType: mimeType}
+	}
+	return genaiconv.Part{Type: "uri", URI: url}
+}
+
+func (m chatMessage) message() genaiconv.Message {
+	msg := genaiconv.Message{Role: m.Role, Name: m.Name}
+	if m.Role == "tool" {
+		var text strings.Builder
+		for _, part := range contentParts(m.Content) {
+			text.WriteString(part.Content)
+		}
+		msg.Parts = []genaiconv.Part{genaiconv.ToolCallResponsePart(m.ToolCallID, text.String())}
+		return msg
+	}
+	msg.Parts = contentParts(m.Content)
+	if m.Refusal != "" {
+		msg.Parts = append(msg.Parts, genaiconv.Part{Type: "refusal", Content: m.Refusal})
+	}
+	for _, call := range m.ToolCalls {
+		msg.Parts = append(msg.Parts, genaiconv.ToolCallPart(call.ID, call.Function.Name, call.Function.Arguments))
+	}
+	if msg.Parts == nil {
+		msg.Parts = []genaiconv.Part{}
+	}
+	return msg
+}
+
+func chatCall(body []byte, content bool) (genaiconv.Call, error) {
+	var req struct {
+		params
+		Messages []chatMessage `json:"messages"`
+		Tools    []any         `json:"tools"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return genaiconv.Call{}, err
+	}
+	call := req.call(genaiconv.OperationChat)
+	if content {
+		for _, m := range req.Messages {
+			call.Messages = append(call.Messages, m.message())
+		}
+		if len(req.Tools) > 0 {
+			call.Attributes = append(call.Attributes, genaiconv.JSON(genaiconv.ToolDefinitionsKey, req.Tools))
+		}
+	}
+	return call, nil
+}
+
+type chatChoice struct {
+	Index        int         `json:"index"`
+	Message      chatMessage `json:"message"`
+	Delta        chatMessage `json:"delta"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *usage       `json:"usage"`
+}
+
+func chatResponse(body []byte) genaiconv.Response {
+	var c chatCompletion
+	if json.Unmarshal(body, &c) != nil {
+		return genaiconv.Response{}
+	}
+	resp := genaiconv.Response{ID: c.ID, Model: c.Model, Usage: c.Usage.usage()}
+	for _, choice := range c.Choices {
+		resp.FinishReasons = append(resp.FinishReasons, choice.FinishReason)
+		msg := choice.Message.message()
+		msg.FinishReason = choice.FinishReason
+		resp.Output = append(resp.Output, msg)
+	}
+	return resp
+}
+
+// chatStream accumulates the deltas of the choices of a streamed chat completion.
+type chatStream struct {
+	genaiconv.ChunkCounter
+	resp    genaiconv.Response
+	choices map[int]*streamedChoice
+	indices []int
+}
+
+type streamedChoice struct {
+	role         string
+	text         strings.Builder
+	refusal      strings.Builder
+	toolCalls    map[int]*toolCall
+	toolIndices  []int
+	finishReason string
+}
+
+func (s *chatStream) Line(line []byte) (bool, error) {
+	data, ok := genaiconv.SSEData(line)
+	if !ok {
+		return false, nil
+	}
+	if string(data) == "[DONE]" {
+		return true, nil
+	}
+	var chunk chatCompletion
+	if json.Unmarshal(data, &chunk) != nil {
+		return false, nil
+	}
+	if s.choices == nil {
+		s.choices = map[int]*streamedChoice{}
+	}
+	s.resp.ID, s.resp.Model = chunk.ID, chunk.Model
+	if chunk.Usage != nil {
+		s.resp.Usage = chunk.Usage.usage()
+	}
+	output := false
+	for _, c := range chunk.Choices {
+		choice, ok := s.choices[c.Index]
+		if !ok {
+			choice = &streamedChoice{role: "assistant", toolCalls: map[int]*toolCall{}}
+			s.choices[c.Index] = choice
+			s.indices = append(s.indices, c.Index)
+		}
+		if c.Delta.Role != "" {
+			choice.role = c.Delta.Role
+		}
+		var text string
+		if json.Unmarshal(c.Delta.Content, &text) == nil {
+			choice.text.WriteString(text)
+		}
+		choice.refusal.WriteString(c.Delta.Refusal)
+		output = output || text != "" || c.Delta.Refusal != "" || len(c.Delta.ToolCalls) > 0
+		for _, delta := range c.Delta.ToolCalls {
+			call, ok := choice.toolCalls[delta.Index]
+			if !ok {
+				call = &toolCall{}
+				choice.toolCalls[delta.Index] = call
+				choice.toolIndices = append(choice.toolIndices, delta.Index)
+			}
+			if delta.ID != "" {
+				call.ID = delta.ID
+			}
+			if delta.Function.Name != "" {
+				call.Function.Name = delta.Function.Name
+			}
+			call.Function.Arguments += delta.Function.Arguments
+		}
+		if c.FinishReason != "" {
+			choice.finishReason = c.FinishReason
+		}
+	}
+	if output {
+		s.Add()
+	}
+	return false, nil
+}
+
+func (s *chatStream) Response() genaiconv.Response {
+	resp := s.resp
+	for _, i := range s.indices {
+		choice := s.choices[i]
+		msg := genaiconv.Message{Role: choice.role, Parts: []genaiconv.Part{}, FinishReason: choice.finishReason}
+		if choice.text.Len() > 0 {
+			msg.Parts = append(msg.Parts, genaiconv.TextPart(choice.text.String()))
+		}
+		if choice.refusal.Len() > 0 {
+			msg.Parts = append(msg.Parts, genaiconv.Part{Type: "refusal", Content: choice.refusal.String()})
+		}
+		for _, j := range choice.toolIndices {
+			call := choice.toolCalls[j]
+			msg.Parts = append(msg.Parts, genaiconv.ToolCallPart(call.ID, call.Function.Name, call.Function.Arguments))
+		}
+		if choice.finishReason != "" {
+			resp.FinishReasons = append(resp.FinishReasons, choice.finishReason)
+		}
+		resp.Output = append(resp.Output, msg)
+	}
+	return resp
+}
@@ -0,0 +1,268 @@
+package logfireopenai
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go/v3/option"
+
+	"github.com/pydantic/logfire/go/internal/genaiconv"
+)
+
+// provider is the `gen_ai.provider.name` of the spans.
+const provider = "openai"
+
+// Middleware returns a middleware of the OpenAI client creating a span for each call of the
+// chat completions, completions and embeddings endpoints. Other calls aren't traced.
+func Middleware(opts ...Option) option.Middleware {
+	c := newConfig(opts)
+	client := &genaiconv.Client{
+		Tracer:     c.tracer(),
+		Provider:   provider,
+		Content:    c.content,
+		Attributes: c.attrs,
+		Progress:   c.progress,
+		Metrics:    c.metrics(),
+	}
+	return func(r *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if r.Method != http.MethodPost {
+			return next(r)
+		}
+		var endpoint *endpoint
+		for _, e := range endpoints {
+			if strings.HasSuffix(r.URL.Path, e.path) {
+				endpoint = &e
+				break
+			}
+		}
+		if endpoint == nil {
+			return next(r)
+		}
+		body, err := genaiconv.ReadRequestBody(r)
+		if err != nil {
+			return next(r)
+		}
+		call, err := endpoint.call(body, c.content)
+		if err != nil {
+			return next(r)
+		}
+		var stream genaiconv.Stream
+		if endpoint.stream != nil {
+			stream = endpoint.stream()
+		}
+		return client.Do(r, call, next, endpoint.response, stream)
+	}
+}
+
+// endpoint parses the requests and responses of an endpoint of the API.
+type endpoint struct {
+	path     string
+	call     func(body []byte, content bool) (genaiconv.Call, error)
+	response func(body []byte) genaiconv.Response
+	stream   func() genaiconv.Stream
+}
+
+var endpoints = []endpoint{
+	{"/chat/completions", chatCall, chatResponse, func() genaiconv.Stream { return &chatStream{} }},
+	{"/completions", completionCall, completionResponse, func() genaiconv.Stream { return &completionStream{} }},
+	{"/embeddings", embeddingsCall, embeddingsResponse, nil},
+}
+
+type usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+}
+
+func (u *usage) usage() *genaiconv.Usage {
+	if u == nil {
+		return nil
+	}
+	return &genaiconv.Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
+}
+
+// params are the parameters shared by chat completions and completions.
+type params struct {
+	Model               string          `json:"model"`
+	Stream              bool            `json:"stream"`
+	MaxTokens           *int64          `json:"max_tokens"`
+	MaxCompletionTokens *int64          `json:"max_completion_tokens"`
+	Temperature         *float64        `json:"temperature"`
+	TopP                *float64        `json:"top_p"`
+	FrequencyPenalty    *float64        `json:"frequency_penalty"`
+	PresencePenalty     *float64        `json:"presence_penalty"`
+	Seed                *int64          `json:"seed"`
+	N                   *int64          `json:"n"`
+	Stop                json.RawMessage `json:"stop"`
+}
+
+func (p params) call(operation string) genaiconv.Call {
+	maxTokens := p.MaxTokens
+	if p.MaxCompletionTokens != nil {
+		maxTokens = p.MaxCompletionTokens
+	}
+	gp := genaiconv.Params{
+		MaxTokens:        maxTokens,
+		Temperature:      p.Temperature,
+		TopP:             p.TopP,
+		FrequencyPenalty: p.FrequencyPenalty,
+		PresencePenalty:  p.PresencePenalty,
+		Seed:             p.Seed,
+		ChoiceCount:      p.N,
+		StopSequences:    stringOrStrings(p.Stop),
+	}
+	attrs := append(gp.Attributes(), genaiconv.RequestStreamKey.Bool(p.Stream))
+	return genaiconv.Call{Operation: operation, Model: p.Model, Stream: p.Stream, Attributes: attrs}
+}
+
+// stringOrStrings decodes a field which is either a string or an array of strings.
+func stringOrStrings(raw json.RawMessage) []string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if s == "" {
+			return nil
+		}
+		return []string{s}
+	}
+	var ss []string
+	json.Unmarshal(raw, &ss)
+	return ss
+}
+
+func completionCall(body []byte, content bool) (genaiconv.Call, error) {
+	var req struct {
+		params
+		Prompt json.RawMessage `json:"prompt"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return genaiconv.Call{}, err
+	}
+	call := req.call(genaiconv.OperationTextCompletion)
+	if content {
+		var parts []genaiconv.Part
+		for _, prompt := range stringOrStrings(req.Prompt) {
+			parts = append(parts, genaiconv.TextPart(prompt))
+		}
+		if len(parts) > 0 {
+			call.Messages = []genaiconv.Message{{Role: "user", Parts: parts}}
+		}
+	}
+	return call, nil
+}
+
+type completion struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index        int    `json:"index"`
+		Text         string `json:"text"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *usage `json:"usage"`
+}
+
+func completionResponse(body []byte) genaiconv.Response {
+	var c completion
+	if json.Unmarshal(body, &c) != nil {
+		return genaiconv.Response{}
+	}
+	resp := genaiconv.Response{ID: c.ID, Model: c.Model, Usage: c.Usage.usage()}
+	for _, choice := range c.Choices {
+		resp.FinishReasons = append(resp.FinishReasons, choice.FinishReason)
+		resp.Output = append(resp.Output, genaiconv.Message{
+			Role:         "assistant",
+			Parts:        []genaiconv.Part{genaiconv.TextPart(choice.Text)},
+			FinishReason: choice.FinishReason,
+		})
+	}
+	return resp
+}
+
+// completionStream accumulates the text of the choices of a streamed completion.
+type completionStream struct {
+	genaiconv.ChunkCounter
+	resp    genaiconv.Response
+	texts   map[int]*strings.Builder
+	reasons map[int]string
+	indices []int
+}
+
+func (s *completionStream) Line(line []byte) (bool, error) {
+	data, ok := genaiconv.SSEData(line)
+	if !ok {
+		return false, nil
+	}
+	if string(data) == "[DONE]" {
+		return true, nil
+	}
+	var chunk completion
+	if json.Unmarshal(data, &chunk) != nil {
+		return false, nil
+	}
+	if s.texts == nil {
+		s.texts, s.reasons = map[int]*strings.Builder{}, map[int]string{}
+	}
+	s.resp.ID, s.resp.Model = chunk.ID, chunk.Model
+	if chunk.Usage != nil {
+		s.resp.Usage = chunk.Usage.usage()
+	}
+	output := false
+	for _, choice := range chunk.Choices {
+		text, ok := s.texts[choice.Index]
+		if !ok {
+			text = &strings.Builder{}
+			s.texts[choice.Index] = text
+			s.indices = append(s.indices, choice.Index)
+		}
+		text.WriteString(choice.Text)
+		output = output || choice.Text != ""
+		if choice.FinishReason != "" {
+			s.reasons[choice.Index] = choice.FinishReason
+		}
+	}
+	if output {
+		s.Add()
+	}
+	return false, nil
+}
+
+func (s *completionStream) Response() genaiconv.Response {
+	resp := s.resp
+	for _, i := range s.indices {
+		if s.reasons[i] != "" {
+			resp.FinishReasons = append(resp.FinishReasons, s.reasons[i])
+		}
+		resp.Output = append(resp.Output, genaiconv.Message{
+			Role:         "assistant",
+			Parts:        []genaiconv.Part{genaiconv.TextPart(s.texts[i].String())},
+			FinishReason: s.reasons[i],
+		})
+	}
+	return resp
+}
+
+func embeddingsCall(body []byte, content bool) (genaiconv.Call, error) {
+	var req struct {
+		Model      string `json:"model"`
+		Dimensions *int64 `json:"dimensions"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return genaiconv.Call{}, err
+	}
+	call := genaiconv.Call{Operation: genaiconv.OperationEmbeddings, Model: req.Model}
+	if req.Dimensions != nil {
+		call.Attributes = append(call.Attributes, genaiconv.EmbeddingsDimensionCountKey.Int64(*req.Dimensions))
+	}
+	return call, nil
+}
+
+func embeddingsResponse(body []byte) genaiconv.Response {
+	var e struct {
+		Model string `json:"model"`
+		Usage *usage `json:"usage"`
+	}
+	if json.Unmarshal(body, &e) != nil {
+		return genaiconv.Response{}
+	}
+	return genaiconv.Response{Model: e.Model, Usage: e.Usage.usage()}
+}
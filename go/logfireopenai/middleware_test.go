@@ -0,0 +1,316 @@
+package logfireopenai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func checkJSON(t *testing.T, attrs []attribute.KeyValue, key attribute.Key, want string) {
+	t.Helper()
+	got, _ := oteltest.Attributes(attrs)[key].(string)
+	var g, w any
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Errorf("%s = %q, not JSON", key, got)
+		return
+	}
+	json.Unmarshal([]byte(want), &w)
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("%s = %s, want %s", key, gb, wb)
+	}
+}
+
+const chatCompletionJSON = `{
+	"id": "chatcmpl-1",
+	"object": "chat.completion",
+	"model": "gpt-4o-2024-08-06",
+	"choices": [{
+		"index": 0,
+		"finish_reason": "tool_calls",
+		"message": {
+			"role": "assistant",
+			"content": null,
+			"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\":\"Paris\"}"}}]
+		}
+	}],
+	"usage": {"prompt_tokens": 12, "completion_tokens": 7, "total_tokens": 19}
+}`
+
+const chatStreamSSE = `data: {"id":"chatcmpl-2","object":"chat.completion.chunk","model":"gpt-4o-2024-08-06","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}
+
+data: {"id":"chatcmpl-2","object":"chat.completion.chunk","model":"gpt-4o-2024-08-06","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}
+
+data: {"id":"chatcmpl-2","object":"chat.completion.chunk","model":"gpt-4o-2024-08-06","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}
+
+data: [DONE]
+
+`
+
+func newClient(t *testing.T, opts ...Option) (openai.Client, *tracetest.SpanRecorder) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Stream bool   `json:"stream"`
+			Model  string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case req.Model == "missing":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"message": "The model does not exist", "type": "invalid_request_error"}}`))
+		case strings.HasSuffix(r.URL.Path, "/chat/completions") && req.Stream:
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(chatStreamSSE))
+		case strings.HasSuffix(r.URL.Path, "/chat/completions"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(chatCompletionJSON))
+		case strings.HasSuffix(r.URL.Path, "/embeddings"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"object": "list", "model": "text-embedding-3-small", "data": [{"object": "embedding", "index": 0, "embedding": [0.1, 0.2]}], "usage": {"prompt_tokens": 3, "total_tokens": 3}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := openai.NewClient(
+		option.WithBaseURL(server.URL),
+		option.WithAPIKey("test"),
+		option.WithMaxRetries(0),
+		option.WithMiddleware(Middleware(append(opts, WithTracerProvider(provider))...)),
+	)
+	return client, recorder
+}
+
+func TestChatCompletion(t *testing.T) {
+	client, recorder := newClient(t, WithMessageContent())
+	_, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model: openai.ChatModelGPT4o,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("Be brief."),
+			openai.UserMessage("What's the weather in Paris?"),
+		},
+		Temperature:         openai.Float(0.5),
+		MaxCompletionTokens: openai.Int(100),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "chat gpt-4o" || span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span %s of kind %v", span.Name(), span.SpanKind())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:               "chat gpt-4o",
+		"gen_ai.provider.name":       "openai",
+		"gen_ai.system":              "openai",
+		"gen_ai.operation.name":      "chat",
+		"gen_ai.request.model":       "gpt-4o",
+		"gen_ai.request.temperature": 0.5,
+		"gen_ai.request.max_tokens":  int64(100),
+		"gen_ai.request.stream":      false,
+		"gen_ai.response.id":         "chatcmpl-1",
+		"gen_ai.response.model":      "gpt-4o-2024-08-06",
+		"gen_ai.usage.input_tokens":  int64(12),
+		"gen_ai.usage.output_tokens": int64(7),
+		"http.response.status_code":  int64(200),
+	})
+	if reasons := oteltest.Attributes(span.Attributes())["gen_ai.response.finish_reasons"]; len(reasons.([]string)) != 1 {
+		t.Errorf("finish reasons = %v", reasons)
+	}
+	checkJSON(t, span.Attributes(), "gen_ai.input.messages", `[
+		{"role": "system", "parts": [{"type": "text", "content": "Be brief."}]},
+		{"role": "user", "parts": [{"type": "text", "content": "What's the weather in Paris?"}]}
+	]`)
+	checkJSON(t, span.Attributes(), "gen_ai.output.messages", `[{
+		"role": "assistant",
+		"finish_reason": "tool_calls",
+		"parts": [{"type": "tool_call", "id": "call_1", "name": "weather", "arguments": {"city": "Paris"}}]
+	}]`)
+}
+
+func TestChatCompletionStreaming(t *testing.T) {
+	client, recorder := newClient(t, WithMessageContent())
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{
+		Model:    openai.ChatModelGPT4o,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hi")},
+	})
+	var text strings.Builder
+	for stream.Next() {
+		if choices := stream.Current().Choices; len(choices) > 0 {
+			text.WriteString(choices[0].Delta.Content)
+		}
+	}
+	if err := stream.Err(); err != nil || text.String() != "Hello" {
+		t.Fatalf("streamed %q, %v", text.String(), err)
+	}
+	stream.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{
+		"gen_ai.request.stream":      true,
+		"gen_ai.response.id":         "chatcmpl-2",
+		"gen_ai.usage.input_tokens":  int64(5),
+		"gen_ai.usage.output_tokens": int64(2),
+	})
+	checkJSON(t, spans[0].Attributes(), "gen_ai.output.messages",
+		`[{"role": "assistant", "finish_reason": "stop", "parts": [{"type": "text", "content": "Hello"}]}]`)
+}
+
+func TestChatCompletionStreamProgress(t *testing.T) {
+	client, recorder := newClient(t, WithMessageContent(), WithStreamProgress(time.Nanosecond))
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{
+		Model:    openai.ChatModelGPT4o,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hi")},
+	})
+	for stream.Next() {
+	}
+	stream.Close()
+
+	span := recorder.Ended()[0]
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{"gen_ai.response.chunk_count": int64(2)})
+	if ttfc, _ := oteltest.Attributes(span.Attributes())["gen_ai.response.time_to_first_chunk"].(float64); ttfc <= 0 {
+		t.Errorf("gen_ai.response.time_to_first_chunk = %v", ttfc)
+	}
+	events := span.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	for i, output := range []string{
+		`[{"role": "assistant", "parts": [{"type": "text", "content": "Hel"}]}]`,
+		`[{"role": "assistant", "finish_reason": "stop", "parts": [{"type": "text", "content": "Hello"}]}]`,
+	} {
+		if events[i].Name != "gen_ai.stream.progress" {
+			t.Errorf("event %d is %q", i, events[i].Name)
+		}
+		oteltest.CheckAttributes(t, events[i].Attributes, map[attribute.Key]any{"gen_ai.response.chunk_count": int64(i + 1)})
+		checkJSON(t, events[i].Attributes, "gen_ai.output.messages", output)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	client, recorder := newClient(t, WithMeterProvider(meterProvider), WithPricing(map[string]Price{
+		"gpt-4":  {Input: 30, Output: 60},
+		"gpt-4o": {Input: 2.5, Output: 10},
+	}))
+	_, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model:    openai.ChatModelGPT4o,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hi")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The cost of 12 input tokens and 7 output tokens of gpt-4o-2024-08-06.
+	const cost = (12*2.5 + 7*10) / 1e6
+	if got := oteltest.Attributes(recorder.Ended()[0].Attributes())["operation.cost"]; got != cost {
+		t.Errorf("operation.cost = %v, want %v", got, cost)
+	}
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	usage, ok := metrics["gen_ai.client.token.usage"].(metricdata.Histogram[int64])
+	if !ok || len(usage.DataPoints) != 2 {
+		t.Fatalf("gen_ai.client.token.usage = %v", metrics["gen_ai.client.token.usage"])
+	}
+	for _, dp := range usage.DataPoints {
+		tokenType, _ := dp.Attributes.Value("gen_ai.token.type")
+		model, _ := dp.Attributes.Value("gen_ai.response.model")
+		if want := map[string]int64{"input": 12, "output": 7}[tokenType.AsString()]; dp.Sum != want || model.AsString() != "gpt-4o-2024-08-06" {
+			t.Errorf("%s tokens = %d of %s", tokenType.AsString(), dp.Sum, model.AsString())
+		}
+	}
+	if duration, ok := metrics["gen_ai.client.operation.duration"].(metricdata.Histogram[float64]); !ok || len(duration.DataPoints) != 1 {
+		t.Errorf("gen_ai.client.operation.duration = %v", metrics["gen_ai.client.operation.duration"])
+	}
+	if sum, ok := metrics["operation.cost"].(metricdata.Sum[float64]); !ok || len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != cost {
+		t.Errorf("operation.cost = %v", metrics["operation.cost"])
+	}
+}
+
+func TestWithoutMessageContent(t *testing.T) {
+	client, recorder := newClient(t)
+	_, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model:    openai.ChatModelGPT4o,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("secret")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oteltest.CheckAttributes(t, recorder.Ended()[0].Attributes(), map[attribute.Key]any{
+		"gen_ai.input.messages":     nil,
+		"gen_ai.output.messages":    nil,
+		"gen_ai.usage.input_tokens": int64(12),
+	})
+}
+
+func TestEmbeddings(t *testing.T) {
+	client, recorder := newClient(t)
+	_, err := client.Embeddings.New(context.Background(), openai.EmbeddingNewParams{
+		Model: openai.EmbeddingModelTextEmbedding3Small,
+		Input: openai.EmbeddingNewParamsInputUnion{OfString: openai.String("hello")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := recorder.Ended()[0]
+	if span.Name() != "embeddings text-embedding-3-small" {
+		t.Errorf("name = %s", span.Name())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		"gen_ai.operation.name":      "embeddings",
+		"gen_ai.usage.input_tokens":  int64(3),
+		"gen_ai.usage.output_tokens": nil,
+	})
+}
+
+func TestError(t *testing.T) {
+	client, recorder := newClient(t)
+	_, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model:    "missing",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hi")},
+	})
+	if err == nil {
+		t.Fatal("no error for a missing model")
+	}
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "404 Not Found: The model does not exist" {
+		t.Errorf("status = %v", span.Status())
+	}
+}
@@ -0,0 +1,87 @@
+// Package logfirepgx instruments pgx v5 connections and pools for Pydantic Logfire.
+//
+// [NewTracer] returns a tracer creating a client span for each query, named after the operation
+// and table like `SELECT users`, with the sanitized query as message:
+//
+//	config, err := pgxpool.ParseConfig(dsn)
+//	config.ConnConfig.Tracer = logfirepgx.NewTracer()
+//	pool, err := pgxpool.NewWithConfig(ctx, config)
+//
+// Batches, copies, prepared statements, new connections and pool acquires get spans too.
+// [RegisterPoolMetrics] exports the statistics of a pool as metrics.
+package logfirepgx
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirepgx"
+
+// Option configures [NewTracer] and [RegisterPoolMetrics].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider used by [RegisterPoolMetrics]. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithoutSanitization records queries as is, rather than with their literals replaced by `?`.
+// Only use it if queries never embed sensitive values, e.g. when all values are passed as arguments.
+func WithoutSanitization() Option {
+	return func(c *config) {
+		c.noSanitization = true
+	}
+}
+
+// WithoutAcquireSpans disables the spans of acquiring a connection from a pool,
+// which are usually short and numerous.
+func WithoutAcquireSpans() Option {
+	return func(c *config) {
+		c.noAcquireSpans = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	attrs          []attribute.KeyValue
+	noSanitization bool
+	noAcquireSpans bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) meter() metric.Meter {
+	return instrumentation.Meter(c.meterProvider, instrumentationName)
+}
@@ -0,0 +1,29 @@
+package logfirepgx
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+)
+
+// RegisterPoolMetrics exports the statistics of pool, named name in the `db.client.connection.pool.name`
+// attribute: the `db.client.connection.count` of idle and used connections, `db.client.connection.max`,
+// and the cumulative `db.client.connection.wait_count` and `db.client.connection.wait_duration` of
+// acquires which waited for a connection.
+//
+// It should be called after [logfire.Configure] when using the global meter provider, and the registration
+// unregistered once the pool is closed.
+func RegisterPoolMetrics(pool *pgxpool.Pool, name string, opts ...Option) (metric.Registration, error) {
+	return sqlconv.RegisterPoolMetrics(newConfig(opts).meter(), name, func() sqlconv.PoolStats {
+		s := pool.Stat()
+		return sqlconv.PoolStats{
+			Idle: int64(s.IdleConns()),
+			// Connections being established count as used, like those being opened by database/sql.
+			Used:         int64(s.AcquiredConns() + s.ConstructingConns()),
+			Max:          int64(s.MaxConns()),
+			WaitCount:    s.EmptyAcquireCount(),
+			WaitDuration: s.EmptyAcquireWaitTime(),
+		}
+	})
+}
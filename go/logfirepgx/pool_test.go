@@ -0,0 +1,51 @@
+package logfirepgx
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRegisterPoolMetrics(t *testing.T) {
+	// The pool only connects when a connection is acquired.
+	pool, err := pgxpool.New(t.Context(), "postgres://localhost:5432/app?pool_max_conns=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	reader := sdkmetric.NewManualReader()
+	registration, err := RegisterPoolMetrics(pool, "app",
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registration.Unregister()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		names[m.Name] = true
+		if m.Name != "db.client.connection.max" {
+			continue
+		}
+		point := m.Data.(metricdata.Sum[int64]).DataPoints[0]
+		if point.Value != 7 {
+			t.Errorf("max connections = %d, want 7", point.Value)
+		}
+		if name, _ := point.Attributes.Value("db.client.connection.pool.name"); name.AsString() != "app" {
+			t.Errorf("unexpected pool name %v", name)
+		}
+	}
+	for _, name := range []string{"db.client.connection.count", "db.client.connection.max",
+		"db.client.connection.wait_count", "db.client.connection.wait_duration"} {
+		if !names[name] {
+			t.Errorf("missing %s", name)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package logfirepgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// system is the `db.system.name` of all spans.
+const system = "postgresql"
+
+// Tracer creates spans for pgx. Set it as the Tracer of a [pgx.ConnConfig];
+// pools created from that config also use it for the spans of acquiring connections.
+type Tracer struct {
+	config *config
+}
+
+var (
+	_ pgx.QueryTracer       = (*Tracer)(nil)
+	_ pgx.BatchTracer       = (*Tracer)(nil)
+	_ pgx.CopyFromTracer    = (*Tracer)(nil)
+	_ pgx.PrepareTracer     = (*Tracer)(nil)
+	_ pgx.ConnectTracer     = (*Tracer)(nil)
+	_ pgxpool.AcquireTracer = (*Tracer)(nil)
+)
+
+// NewTracer returns a tracer for pgx connections and pools.
+func NewTracer(opts ...Option) *Tracer {
+	return &Tracer{config: newConfig(opts)}
+}
+
+// TraceQueryStart starts the span of a query.
+func (t *Tracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = t.startQuery(ctx, connConfig(conn), "", data.SQL)
+	return ctx
+}
+
+// TraceQueryEnd ends the span of a query, recording the number of rows it returned or affected.
+func (t *Tracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	if data.Err != nil {
+		recordError(span, data.Err)
+		return
+	}
+	span.SetAttributes(rowsAttribute(data.CommandTag))
+}
+
+// TraceBatchStart starts the span of a batch, named `BATCH <summary>` if all its queries have the same summary.
+func (t *Tracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	var queries []*pgx.QueuedQuery
+	if data.Batch != nil {
+		queries = data.Batch.QueuedQueries
+	}
+	summary := ""
+	for i, qq := range queries {
+		q := sqlconv.Parse(qq.SQL, false)
+		if i > 0 && q.Summary != summary {
+			summary = ""
+			break
+		}
+		summary = q.Summary
+	}
+	name, operation := "BATCH", "BATCH"
+	if summary != "" {
+		name += " " + summary
+		operation += " " + strings.SplitN(summary, " ", 2)[0]
+	}
+	noun := "queries"
+	if len(queries) == 1 {
+		noun = "query"
+	}
+	ctx, _ = t.start(ctx, connConfig(conn), name,
+		logfire.MsgKey.String(fmt.Sprintf("%s (%d %s)", name, len(queries), noun)),
+		semconv.DBOperationName(operation),
+		semconv.DBOperationBatchSize(len(queries)),
+	)
+	return ctx
+}
+
+// TraceBatchQuery records a query of a batch as an event of its span, since pgx only reports it once it's done.
+func (t *Tracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	span := trace.SpanFromContext(ctx)
+	q := sqlconv.Parse(data.SQL, !t.config.noSanitization)
+	attrs := q.Attributes()
+	if data.Err != nil {
+		attrs = append(attrs, semconv.ExceptionMessage(data.Err.Error()))
+		attrs = append(attrs, errorAttributes(data.Err)...)
+	} else {
+		attrs = append(attrs, rowsAttribute(data.CommandTag))
+	}
+	span.AddEvent("query", trace.WithAttributes(attrs...))
+}
+
+// TraceBatchEnd ends the span of a batch.
+func (t *Tracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	if data.Err != nil {
+		recordError(span, data.Err)
+	}
+}
+
+// TraceCopyFromStart starts the span of a copy, named `COPY <table>`.
+func (t *Tracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	table := strings.Join(data.TableName, ".")
+	name := "COPY " + table
+	msg := name
+	if len(data.ColumnNames) > 0 {
+		msg += " (" + strings.Join(data.ColumnNames, ", ") + ")"
+	}
+	ctx, _ = t.start(ctx, connConfig(conn), name,
+		logfire.MsgKey.String(msg),
+		semconv.DBOperationName("COPY"),
+		semconv.DBCollectionName(table),
+		semconv.DBQuerySummary(name),
+	)
+	return ctx
+}
+
+// TraceCopyFromEnd ends the span of a copy, recording the number of rows copied.
+func (t *Tracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	if data.Err != nil {
+		recordError(span, data.Err)
+		return
+	}
+	span.SetAttributes(sqlconv.RowsAffectedKey.Int64(data.CommandTag.RowsAffected()))
+}
+
+// TracePrepareStart starts the span of preparing a statement, named like `prepare SELECT users`.
+func (t *Tracer) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	ctx, span := t.startQuery(ctx, connConfig(conn), "prepare", data.SQL)
+	if data.Name != "" {
+		span.SetAttributes(preparedStatementKey.String(data.Name))
+	}
+	return ctx
+}
+
+// TracePrepareEnd ends the span of preparing a statement.
+func (t *Tracer) TracePrepareEnd(ctx context.Context, _ *pgx.Conn, data pgx.TracePrepareEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	if data.Err != nil {
+		recordError(span, data.Err)
+		return
+	}
+	span.SetAttributes(alreadyPreparedKey.Bool(data.AlreadyPrepared))
+}
+
+// TraceConnectStart starts the span of a new connection.
+func (t *Tracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	ctx, _ = t.start(ctx, data.ConnConfig, "connect")
+	return ctx
+}
+
+// TraceConnectEnd ends the span of a new connection.
+func (t *Tracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	if data.Err != nil {
+		recordError(span, data.Err)
+	}
+}
+
+// TraceAcquireStart starts the span of acquiring a connection from a pool, unless [WithoutAcquireSpans] is used.
+func (t *Tracer) TraceAcquireStart(ctx context.Context, pool *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	if t.config.noAcquireSpans {
+		return ctx
+	}
+	var cc *pgx.ConnConfig
+	if pool != nil {
+		cc = pool.Config().ConnConfig
+	}
+	ctx, span := t.start(ctx, cc, "acquire", logfire.MsgKey.String("acquire connection"))
+	return context.WithValue(ctx, acquireKey{}, span)
+}
+
+// TraceAcquireEnd ends the span of acquiring a connection from a pool.
+func (t *Tracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	// The acquire span is stored separately so that it's never confused with the span of the caller.
+	span, ok := ctx.Value(acquireKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+	if data.Err != nil {
+		recordError(span, data.Err)
+	}
+}
+
+// acquireKey is the context key of the span of acquiring a connection.
+type acquireKey struct{}
+
+const (
+	// preparedStatementKey records the name of a prepared statement.
+	preparedStatementKey = attribute.Key("db.pgx.prepared_statement_name")
+	// alreadyPreparedKey records whether a statement was already prepared on the connection.
+	alreadyPreparedKey = attribute.Key("db.pgx.already_prepared")
+)
+
+// start creates the span of an operation which isn't a query, e.g. "connect".
+func (t *Tracer) start(ctx context.Context, cc *pgx.ConnConfig, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.config.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		trace.WithAttributes(serverAttributes(cc)...),
+		trace.WithAttributes(t.config.attrs...),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// startQuery creates the span of a query. prefix is prepended to the span name and message
+// of operations such as preparing a statement.
+func (t *Tracer) startQuery(ctx context.Context, cc *pgx.ConnConfig, prefix, query string) (context.Context, trace.Span) {
+	q := sqlconv.Parse(query, !t.config.noSanitization)
+	q.Prefix = prefix
+	return t.start(ctx, cc, q.SpanName(system), q.Attributes()...)
+}
+
+// connConfig returns the config of a connection, which is nil in tests calling the tracer directly.
+func connConfig(conn *pgx.Conn) *pgx.ConnConfig {
+	if conn == nil {
+		return nil
+	}
+	return conn.Config()
+}
+
+// serverAttributes returns the attributes of the server a connection is made to.
+func serverAttributes(cc *pgx.ConnConfig) []attribute.KeyValue {
+	if cc == nil {
+		return nil
+	}
+	var attrs []attribute.KeyValue
+	if cc.Host != "" {
+		attrs = append(attrs, semconv.ServerAddress(cc.Host))
+	}
+	if cc.Port != 0 {
+		attrs = append(attrs, semconv.ServerPort(int(cc.Port)))
+	}
+	if cc.Database != "" {
+		attrs = append(attrs, semconv.DBNamespace(cc.Database))
+	}
+	return attrs
+}
+
+// rowsAttribute returns the number of rows returned by a SELECT, or affected by other statements.
+func rowsAttribute(tag pgconn.CommandTag) attribute.KeyValue {
+	if tag.Select() {
+		return semconv.DBResponseReturnedRows(int(tag.RowsAffected()))
+	}
+	return sqlconv.RowsAffectedKey.Int64(tag.RowsAffected())
+}
+
+// errorAttributes returns the SQLSTATE code of PostgreSQL errors.
+func errorAttributes(err error) []attribute.KeyValue {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return []attribute.KeyValue{semconv.DBResponseStatusCode(pgErr.Code)}
+	}
+	return nil
+}
+
+func recordError(span trace.Span, err error) {
+	span.SetAttributes(errorAttributes(err)...)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,72 @@
+// Package logfireredis instruments go-redis v9 clients for Pydantic Logfire.
+//
+// [InstrumentClient] adds a hook creating a client span for each command and pipeline,
+// named after the command like `GET`:
+//
+//	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	logfireredis.InstrumentClient(rdb)
+//
+// Keys can contain personal data, so only their number is recorded unless [WithKeys] is used.
+// Values are never recorded.
+package logfireredis
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireredis"
+
+// Option configures [NewHook] and [InstrumentClient].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all spans, e.g. `server.address` for hooks created with [NewHook].
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithKeys records the keys of commands in the `db.redis.keys` attribute and in the message of their spans.
+// Only use it if keys never contain personal data or secrets.
+func WithKeys() Option {
+	return func(c *config) {
+		c.keys = true
+	}
+}
+
+// WithoutDialSpans disables the spans of new connections.
+func WithoutDialSpans() Option {
+	return func(c *config) {
+		c.noDialSpans = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	keys           bool
+	noDialSpans    bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
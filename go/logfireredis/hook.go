@@ -0,0 +1,157 @@
+package logfireredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+const (
+	// keyCountKey records the number of keys of a command or pipeline.
+	keyCountKey = attribute.Key("db.redis.key_count")
+	// keysKey records the keys of a command when [WithKeys] is used.
+	keysKey = attribute.Key("db.redis.keys")
+)
+
+// maxMessageKeys is the number of keys shown in the message of a span.
+const maxMessageKeys = 5
+
+// InstrumentClient adds a hook to a client, recording the address and database of the server
+// for clients created by [redis.NewClient].
+func InstrumentClient(rdb redis.UniversalClient, opts ...Option) {
+	if client, ok := rdb.(*redis.Client); ok {
+		options := client.Options()
+		var attrs []attribute.KeyValue
+		if host, port := httpconv.SplitHostPort(options.Addr); host != "" && options.Network != "unix" {
+			attrs = append(attrs, semconv.ServerAddress(host))
+			if port > 0 {
+				attrs = append(attrs, semconv.ServerPort(port))
+			}
+		}
+		attrs = append(attrs, semconv.DBNamespace(fmt.Sprint(options.DB)))
+		opts = append([]Option{WithAttributes(attrs...)}, opts...)
+	}
+	rdb.AddHook(NewHook(opts...))
+}
+
+// NewHook returns a hook creating spans for commands, pipelines and new connections,
+// to add with [redis.Client.AddHook].
+func NewHook(opts ...Option) redis.Hook {
+	return &hook{config: newConfig(opts)}
+}
+
+type hook struct {
+	config *config
+}
+
+func (h *hook) DialHook(next redis.DialHook) redis.DialHook {
+	if h.config.noDialSpans {
+		return next
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		attrs := []attribute.KeyValue{semconv.NetworkTransportKey.String(network)}
+		if host, port := httpconv.SplitHostPort(addr); host != "" {
+			attrs = append(attrs, semconv.ServerAddress(host))
+			if port > 0 {
+				attrs = append(attrs, semconv.ServerPort(port))
+			}
+		}
+		ctx, span := h.start(ctx, "connect", attrs...)
+		defer span.End()
+		conn, err := next(ctx, network, addr)
+		recordError(span, err)
+		return conn, err
+	}
+}
+
+func (h *hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		name := strings.ToUpper(cmd.FullName())
+		keys := commandKeys(cmd.Name(), cmd.Args())
+		msg := name
+		attrs := []attribute.KeyValue{
+			semconv.DBOperationName(name),
+			keyCountKey.Int(len(keys)),
+		}
+		if h.config.keys && len(keys) > 0 {
+			attrs = append(attrs, keysKey.StringSlice(keys))
+			msg += " " + strings.Join(keys[:min(len(keys), maxMessageKeys)], " ")
+			if len(keys) > maxMessageKeys {
+				msg += " …"
+			}
+		}
+		ctx, span := h.start(ctx, name, append(attrs, logfire.MsgKey.String(msg))...)
+		defer span.End()
+		err := next(ctx, cmd)
+		recordError(span, err)
+		return err
+	}
+}
+
+// ProcessPipelineHook creates a span for a pipeline, named `PIPELINE <command>` if all its commands
+// are the same. The MULTI and EXEC commands of transactions are left out.
+func (h *hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		command, keyCount, size := "", 0, 0
+		for _, cmd := range cmds {
+			name := strings.ToUpper(cmd.FullName())
+			if name == "MULTI" || name == "EXEC" {
+				continue
+			}
+			if size == 0 {
+				command = name
+			} else if name != command {
+				command = ""
+			}
+			size++
+			keyCount += len(commandKeys(cmd.Name(), cmd.Args()))
+		}
+		name := "PIPELINE"
+		if command != "" {
+			name += " " + command
+		}
+		noun := "commands"
+		if size == 1 {
+			noun = "command"
+		}
+		ctx, span := h.start(ctx, name,
+			logfire.MsgKey.String(fmt.Sprintf("%s (%d %s)", name, size, noun)),
+			semconv.DBOperationName(name),
+			semconv.DBOperationBatchSize(size),
+			keyCountKey.Int(keyCount),
+		)
+		defer span.End()
+		err := next(ctx, cmds)
+		recordError(span, err)
+		return err
+	}
+}
+
+func (h *hook) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return h.config.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNameRedis),
+		trace.WithAttributes(h.config.attrs...),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// recordError records errors other than [redis.Nil], which means that a key doesn't exist.
+func recordError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,191 @@
+package logfireredis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestHook(t *testing.T, opts ...Option) (redis.Hook, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	return NewHook(append([]Option{WithTracerProvider(provider)}, opts...)...), recorder
+}
+
+func onlySpan(t *testing.T, recorder *tracetest.SpanRecorder) sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	return spans[0]
+}
+
+func process(t *testing.T, hook redis.Hook, cmd redis.Cmder, err error) {
+	t.Helper()
+	got := hook.ProcessHook(func(context.Context, redis.Cmder) error { return err })(t.Context(), cmd)
+	if got != err {
+		t.Fatalf("unexpected error %v", got)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	hook, recorder := newTestHook(t)
+
+	process(t, hook, redis.NewStringCmd(t.Context(), "get", "user:42"), nil)
+
+	span := onlySpan(t, recorder)
+	if span.Name() != "GET" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		logfire.MsgKey:       "GET",
+		"db.system.name":     "redis",
+		"db.operation.name":  "GET",
+		"db.redis.key_count": int64(1),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+	if _, ok := attrs["db.redis.keys"]; ok {
+		t.Error("expected keys not to be recorded by default")
+	}
+}
+
+func TestWithKeys(t *testing.T) {
+	hook, recorder := newTestHook(t, WithKeys())
+
+	process(t, hook, redis.NewStatusCmd(t.Context(), "mset", "a", "1", "b", "2"), nil)
+
+	attrs := oteltest.Attributes(onlySpan(t, recorder).Attributes())
+	if attrs[logfire.MsgKey] != "MSET a b" {
+		t.Errorf("unexpected message %q", attrs[logfire.MsgKey])
+	}
+	if got := attrs["db.redis.keys"]; !slices.Equal(got.([]string), []string{"a", "b"}) {
+		t.Errorf("unexpected keys %v", got)
+	}
+}
+
+func TestNilIsNotAnError(t *testing.T) {
+	hook, recorder := newTestHook(t)
+
+	process(t, hook, redis.NewStringCmd(t.Context(), "get", "missing"), redis.Nil)
+
+	if span := onlySpan(t, recorder); span.Status().Code == codes.Error {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+}
+
+func TestError(t *testing.T) {
+	hook, recorder := newTestHook(t)
+
+	process(t, hook, redis.NewStringCmd(t.Context(), "get", "k"), errors.New("WRONGTYPE"))
+
+	if span := onlySpan(t, recorder); span.Status().Code != codes.Error {
+		t.Errorf("unexpected status %v", span.Status())
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	hook, recorder := newTestHook(t)
+	ctx := t.Context()
+	cmds := []redis.Cmder{
+		redis.NewStatusCmd(ctx, "multi"),
+		redis.NewIntCmd(ctx, "incr", "a"),
+		redis.NewIntCmd(ctx, "incr", "b"),
+		redis.NewSliceCmd(ctx, "exec"),
+	}
+
+	err := hook.ProcessPipelineHook(func(context.Context, []redis.Cmder) error { return nil })(ctx, cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	span := onlySpan(t, recorder)
+	if span.Name() != "PIPELINE INCR" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		logfire.MsgKey:            "PIPELINE INCR (2 commands)",
+		"db.operation.batch.size": int64(2),
+		"db.redis.key_count":      int64(2),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+}
+
+func TestInstrumentClient(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	rdb := redis.NewClient(&redis.Options{
+		Addr:       "cache.example.com:6380",
+		DB:         2,
+		MaxRetries: -1,
+		Dialer: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		},
+	})
+	t.Cleanup(func() { _ = rdb.Close() })
+	InstrumentClient(rdb, WithTracerProvider(provider))
+
+	if err := rdb.Get(t.Context(), "k").Err(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// The pool retries dialing, with a span for each attempt.
+	spans := recorder.Ended()
+	if len(spans) < 2 || spans[0].Name() != "connect" || spans[len(spans)-1].Name() != "GET" {
+		t.Fatalf("unexpected number of spans %d", len(spans))
+	}
+	for _, span := range spans {
+		if span.Status().Code != codes.Error {
+			t.Errorf("unexpected status %v of %s", span.Status(), span.Name())
+		}
+		attrs := oteltest.Attributes(span.Attributes())
+		if attrs["server.address"] != "cache.example.com" || attrs["server.port"] != int64(6380) {
+			t.Errorf("unexpected server attributes of %s: %v", span.Name(), attrs)
+		}
+	}
+	if got := oteltest.Attributes(spans[len(spans)-1].Attributes())["db.namespace"]; got != "2" {
+		t.Errorf("db.namespace = %v", got)
+	}
+}
+
+func TestCommandKeys(t *testing.T) {
+	for _, test := range []struct {
+		args []any
+		want []string
+	}{
+		{[]any{"ping"}, nil},
+		{[]any{"auth", "user", "secret"}, nil},
+		{[]any{"set", "k", "v", "ex", 10}, []string{"k"}},
+		{[]any{"del", "a", "b"}, []string{"a", "b"}},
+		{[]any{"blpop", "a", "b", 1}, []string{"a", "b"}},
+		{[]any{"rename", "a", "b"}, []string{"a", "b"}},
+		{[]any{"eval", "return 1", 2, "a", "b", "arg"}, []string{"a", "b"}},
+		{[]any{"zunionstore", "dest", 2, "a", "b", "weights", 1, 2}, []string{"dest", "a", "b"}},
+	} {
+		if got := commandKeys(test.args[0].(string), test.args); !slices.Equal(got, test.want) {
+			t.Errorf("commandKeys(%v) = %v, want %v", test.args, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package logfireredis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// keySpec says which arguments of a command are keys, like the key specs of the COMMAND command.
+type keySpec int
+
+const (
+	// firstKey commands, the default, have a single key as their first argument.
+	firstKey keySpec = iota
+	// noKeys commands don't operate on keys.
+	noKeys
+	// allKeys commands only have keys as arguments.
+	allKeys
+	// twoKeys commands have a source and a destination key followed by other arguments.
+	twoKeys
+	// keyValuePairs commands alternate keys and values.
+	keyValuePairs
+	// allButLastKeys commands have keys followed by a timeout.
+	allButLastKeys
+	// numKeysFirst commands have the number of keys as their first argument, followed by the keys.
+	numKeysFirst
+	// numKeysSecond commands have the number of keys as their second argument, followed by the keys.
+	numKeysSecond
+	// destinationAndNumKeys commands have a destination key and the number of source keys, followed by them.
+	destinationAndNumKeys
+)
+
+var keySpecs = map[string]keySpec{
+	"acl": noKeys, "auth": noKeys, "bgrewriteaof": noKeys, "bgsave": noKeys, "client": noKeys,
+	"cluster": noKeys, "command": noKeys, "config": noKeys, "dbsize": noKeys, "debug": noKeys,
+	"discard": noKeys, "echo": noKeys, "exec": noKeys, "flushall": noKeys, "flushdb": noKeys,
+	"function": noKeys, "hello": noKeys, "info": noKeys, "keys": noKeys, "lastsave": noKeys,
+	"memory": noKeys, "module": noKeys, "monitor": noKeys, "multi": noKeys, "ping": noKeys,
+	"psubscribe": noKeys, "publish": noKeys, "pubsub": noKeys, "punsubscribe": noKeys, "quit": noKeys,
+	"randomkey": noKeys, "readonly": noKeys, "readwrite": noKeys, "role": noKeys, "save": noKeys,
+	"scan": noKeys, "script": noKeys, "select": noKeys, "shutdown": noKeys, "slowlog": noKeys,
+	"spublish": noKeys, "ssubscribe": noKeys, "subscribe": noKeys, "sunsubscribe": noKeys,
+	"swapdb": noKeys, "time": noKeys, "unsubscribe": noKeys, "unwatch": noKeys, "wait": noKeys,
+
+	"del": allKeys, "exists": allKeys, "mget": allKeys, "pfcount": allKeys, "pfmerge": allKeys,
+	"sdiff": allKeys, "sdiffstore": allKeys, "sinter": allKeys, "sinterstore": allKeys,
+	"sunion": allKeys, "sunionstore": allKeys, "touch": allKeys, "unlink": allKeys, "watch": allKeys,
+
+	"blmove": twoKeys, "brpoplpush": twoKeys, "copy": twoKeys, "lmove": twoKeys, "rename": twoKeys,
+	"renamenx": twoKeys, "rpoplpush": twoKeys, "smove": twoKeys,
+
+	"mset": keyValuePairs, "msetnx": keyValuePairs,
+
+	"blpop": allButLastKeys, "brpop": allButLastKeys, "bzpopmax": allButLastKeys, "bzpopmin": allButLastKeys,
+
+	"lmpop": numKeysFirst, "sintercard": numKeysFirst, "zdiff": numKeysFirst, "zinter": numKeysFirst,
+	"zintercard": numKeysFirst, "zmpop": numKeysFirst, "zunion": numKeysFirst,
+
+	"blmpop": numKeysSecond, "bzmpop": numKeysSecond, "eval": numKeysSecond, "eval_ro": numKeysSecond,
+	"evalsha": numKeysSecond, "evalsha_ro": numKeysSecond, "fcall": numKeysSecond, "fcall_ro": numKeysSecond,
+
+	"zdiffstore": destinationAndNumKeys, "zinterstore": destinationAndNumKeys, "zunionstore": destinationAndNumKeys,
+}
+
+// commandKeys returns the keys of a command, given its name and all its arguments including the name.
+func commandKeys(name string, args []any) []string {
+	if len(args) < 2 {
+		return nil
+	}
+	args = args[1:]
+	var keys []any
+	switch keySpecs[strings.ToLower(name)] {
+	case noKeys:
+	case firstKey:
+		keys = args[:1]
+	case allKeys:
+		keys = args
+	case twoKeys:
+		keys = args[:min(2, len(args))]
+	case keyValuePairs:
+		for i := 0; i < len(args); i += 2 {
+			keys = append(keys, args[i])
+		}
+	case allButLastKeys:
+		keys = args[:len(args)-1]
+	case numKeysFirst:
+		keys = numberedKeys(args, 0)
+	case numKeysSecond:
+		keys = numberedKeys(args, 1)
+	case destinationAndNumKeys:
+		keys = append(args[:1:1], numberedKeys(args, 1)...)
+	}
+	strs := make([]string, len(keys))
+	for i, key := range keys {
+		strs[i] = fmt.Sprint(key)
+	}
+	return strs
+}
+
+// numberedKeys returns the keys following their number, at position i of args.
+func numberedKeys(args []any, i int) []any {
+	if i >= len(args) {
+		return nil
+	}
+	n, err := strconv.Atoi(fmt.Sprint(args[i]))
+	if err != nil || n < 0 {
+		return nil
+	}
+	return args[i+1 : min(i+1+n, len(args))]
+}
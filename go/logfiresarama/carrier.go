@@ -0,0 +1,97 @@
+package logfiresarama
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// ProducerMessageCarrier carries the trace context in the headers of a message to send.
+type ProducerMessageCarrier struct {
+	msg *sarama.ProducerMessage
+}
+
+var _ propagation.TextMapCarrier = ProducerMessageCarrier{}
+
+// NewProducerMessageCarrier returns a carrier for the headers of a message to send.
+func NewProducerMessageCarrier(msg *sarama.ProducerMessage) ProducerMessageCarrier {
+	return ProducerMessageCarrier{msg: msg}
+}
+
+func (c ProducerMessageCarrier) Get(key string) string {
+	for _, h := range c.msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces the header with the key, so that injecting twice doesn't duplicate headers.
+func (c ProducerMessageCarrier) Set(key, value string) {
+	for i, h := range c.msg.Headers {
+		if string(h.Key) == key {
+			c.msg.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.msg.Headers = append(c.msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (c ProducerMessageCarrier) Keys() []string {
+	keys := make([]string, len(c.msg.Headers))
+	for i, h := range c.msg.Headers {
+		keys[i] = string(h.Key)
+	}
+	return keys
+}
+
+// ConsumerMessageCarrier carries the trace context in the headers of a consumed message.
+type ConsumerMessageCarrier struct {
+	msg *sarama.ConsumerMessage
+}
+
+var _ propagation.TextMapCarrier = ConsumerMessageCarrier{}
+
+// NewConsumerMessageCarrier returns a carrier for the headers of a consumed message.
+func NewConsumerMessageCarrier(msg *sarama.ConsumerMessage) ConsumerMessageCarrier {
+	return ConsumerMessageCarrier{msg: msg}
+}
+
+func (c ConsumerMessageCarrier) Get(key string) string {
+	for _, h := range c.msg.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c ConsumerMessageCarrier) Set(key, value string) {
+	for _, h := range c.msg.Headers {
+		if h != nil && string(h.Key) == key {
+			h.Value = []byte(value)
+			return
+		}
+	}
+	c.msg.Headers = append(c.msg.Headers, &sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (c ConsumerMessageCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.msg.Headers))
+	for _, h := range c.msg.Headers {
+		if h != nil {
+			keys = append(keys, string(h.Key))
+		}
+	}
+	return keys
+}
+
+// InjectContext injects the trace context of ctx in the headers of a message with the global propagators,
+// so that the span created when sending it is a child of the current span.
+func InjectContext(ctx context.Context, msg *sarama.ProducerMessage) {
+	instrumentation.Propagator(nil).Inject(ctx, NewProducerMessageCarrier(msg))
+}
@@ -0,0 +1,75 @@
+// Package logfiresarama instruments sarama Kafka producers and consumer groups for Pydantic Logfire.
+//
+// The wrapped producers create a producer span for each message, named like `send orders`,
+// and inject its trace context in the headers of the message. Sarama doesn't take contexts,
+// so set the parent of the span with [InjectContext] before sending:
+//
+//	producer = logfiresarama.WrapSyncProducer(producer)
+//	msg := &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder(body)}
+//	logfiresarama.InjectContext(ctx, msg)
+//	partition, offset, err := producer.SendMessage(msg)
+//
+// The wrapped consumer group handlers create a consumer span for each message, named like
+// `process orders`, continuing the trace of the producer. Use [MessageContext] as the parent
+// of the work done for the message:
+//
+//	err := group.Consume(ctx, topics, logfiresarama.WrapConsumerGroupHandler(handler))
+package logfiresarama
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiresarama"
+
+// Option configures the wrappers.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject and extract the trace context
+// in the headers of messages. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithAttributes adds attributes to all spans, e.g. `messaging.consumer.group.name`.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	attrs          []attribute.KeyValue
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
@@ -0,0 +1,174 @@
+package logfiresarama
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+	"github.com/pydantic/logfire/go/internal/msgconv"
+)
+
+// lagKey records the number of messages of the partition after the consumed message.
+const lagKey = attribute.Key("messaging.kafka.consumer.lag")
+
+// processing holds the messages being processed by wrapped handlers, for [MessageContext].
+var processing sync.Map // map[*sarama.ConsumerMessage]*delivery
+
+// delivery is a message handed to a handler. Its span starts once the handler reads it,
+// which is signaled by closing started.
+type delivery struct {
+	msg     *sarama.ConsumerMessage
+	started chan struct{}
+	ctx     context.Context
+	span    trace.Span
+}
+
+// MessageContext returns the context of the span processing a message consumed by a wrapped handler,
+// or the context propagated by the producer if the message isn't being processed.
+func MessageContext(msg *sarama.ConsumerMessage) context.Context {
+	if v, ok := processing.Load(msg); ok {
+		d := v.(*delivery)
+		<-d.started
+		return d.ctx
+	}
+	return instrumentation.Propagator(nil).Extract(context.Background(), NewConsumerMessageCarrier(msg))
+}
+
+// WrapConsumerGroupHandler instruments a consumer group handler, creating a span for each message
+// it processes. A span ends when the handler reads the next message from the claim, marks the message
+// or returns from ConsumeClaim.
+func WrapConsumerGroupHandler(handler sarama.ConsumerGroupHandler, opts ...Option) sarama.ConsumerGroupHandler {
+	return &consumerGroupHandler{ConsumerGroupHandler: handler, config: newConfig(opts)}
+}
+
+type consumerGroupHandler struct {
+	sarama.ConsumerGroupHandler
+	config *config
+}
+
+func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	c := &consumerGroupClaim{
+		ConsumerGroupClaim: claim,
+		config:             h.config,
+		messages:           make(chan *sarama.ConsumerMessage),
+		done:               make(chan struct{}),
+	}
+	go c.deliver()
+	defer c.stop()
+	return h.ConsumerGroupHandler.ConsumeClaim(&consumerGroupSession{ConsumerGroupSession: session, claim: c}, c)
+}
+
+type consumerGroupClaim struct {
+	sarama.ConsumerGroupClaim
+	config   *config
+	messages chan *sarama.ConsumerMessage
+	done     chan struct{}
+
+	mu      sync.Mutex
+	current *delivery
+	stopped bool
+}
+
+func (c *consumerGroupClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+// deliver hands the messages of the claim to the handler, starting the span of each message
+// once the handler has read it.
+func (c *consumerGroupClaim) deliver() {
+	defer close(c.messages)
+	for {
+		var msg *sarama.ConsumerMessage
+		select {
+		case m, ok := <-c.ConsumerGroupClaim.Messages():
+			if !ok {
+				return
+			}
+			msg = m
+		case <-c.done:
+			return
+		}
+		d := &delivery{msg: msg, started: make(chan struct{})}
+		processing.Store(msg, d)
+		select {
+		case c.messages <- msg:
+		case <-c.done:
+			processing.Delete(msg)
+			d.ctx = context.Background()
+			close(d.started)
+			return
+		}
+		c.mu.Lock()
+		c.finish(nil)
+		if c.stopped {
+			// The handler returned right after reading the message.
+			processing.Delete(msg)
+			d.ctx = context.Background()
+			close(d.started)
+			c.mu.Unlock()
+			return
+		}
+		d.ctx, d.span = c.start(msg)
+		close(d.started)
+		c.current = d
+		c.mu.Unlock()
+	}
+}
+
+func (c *consumerGroupClaim) start(msg *sarama.ConsumerMessage) (context.Context, trace.Span) {
+	ctx := c.config.propagator().Extract(context.Background(), NewConsumerMessageCarrier(msg))
+	attrs := msgconv.Attributes(semconv.MessagingSystemKafka, semconv.MessagingOperationTypeProcess, "process", msg.Topic)
+	attrs = append(attrs,
+		semconv.MessagingDestinationPartitionID(strconv.Itoa(int(msg.Partition))),
+		semconv.MessagingKafkaOffset(int(msg.Offset)),
+		semconv.MessagingMessageBodySize(len(msg.Value)),
+	)
+	if hwm := c.HighWaterMarkOffset(); hwm > 0 {
+		attrs = append(attrs, lagKey.Int64(max(hwm-msg.Offset-1, 0)))
+	}
+	if msg.Value == nil {
+		attrs = append(attrs, semconv.MessagingKafkaMessageTombstone(true))
+	}
+	return c.config.tracer().Start(ctx, msgconv.SpanName("process", msg.Topic),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(c.config.attrs...),
+	)
+}
+
+// finish ends the span of the current message, if it's msg or msg is nil. c.mu must be held.
+func (c *consumerGroupClaim) finish(msg *sarama.ConsumerMessage) {
+	if c.current == nil || (msg != nil && c.current.msg != msg) {
+		return
+	}
+	processing.Delete(c.current.msg)
+	c.current.span.End()
+	c.current = nil
+}
+
+func (c *consumerGroupClaim) stop() {
+	close(c.done)
+	c.mu.Lock()
+	c.stopped = true
+	c.finish(nil)
+	c.mu.Unlock()
+}
+
+// consumerGroupSession ends the span of a message when it's marked.
+type consumerGroupSession struct {
+	sarama.ConsumerGroupSession
+	claim *consumerGroupClaim
+}
+
+func (s *consumerGroupSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.ConsumerGroupSession.MarkMessage(msg, metadata)
+	s.claim.mu.Lock()
+	s.claim.finish(msg)
+	s.claim.mu.Unlock()
+}
@@ -0,0 +1,199 @@
+package logfiresarama
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/IBM/sarama"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/msgconv"
+)
+
+// startProducer starts the span of a message to send, as a child of the context injected in its headers,
+// and injects the context of the span in its place.
+func (c *config) startProducer(msg *sarama.ProducerMessage) trace.Span {
+	carrier := NewProducerMessageCarrier(msg)
+	ctx := c.propagator().Extract(context.Background(), carrier)
+	attrs := msgconv.Attributes(semconv.MessagingSystemKafka, semconv.MessagingOperationTypeSend, "send", msg.Topic)
+	if msg.Value != nil {
+		attrs = append(attrs, semconv.MessagingMessageBodySize(msg.Value.Length()))
+	}
+	if msg.Value == nil && msg.Key != nil {
+		attrs = append(attrs, semconv.MessagingKafkaMessageTombstone(true))
+	}
+	ctx, span := c.tracer().Start(ctx, msgconv.SpanName("send", msg.Topic),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(c.attrs...),
+	)
+	c.propagator().Inject(ctx, carrier)
+	return span
+}
+
+// endProducer ends the span of a message, with the partition and offset it was written to.
+func endProducer(span trace.Span, msg *sarama.ProducerMessage, err error) {
+	if err != nil {
+		msgconv.RecordError(span, err)
+	} else {
+		span.SetAttributes(
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(int(msg.Partition))),
+			semconv.MessagingKafkaOffset(int(msg.Offset)),
+		)
+	}
+	span.End()
+}
+
+// WrapSyncProducer instruments a sync producer, creating a span for each message.
+func WrapSyncProducer(producer sarama.SyncProducer, opts ...Option) sarama.SyncProducer {
+	return &syncProducer{SyncProducer: producer, config: newConfig(opts)}
+}
+
+type syncProducer struct {
+	sarama.SyncProducer
+	config *config
+}
+
+func (p *syncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	span := p.config.startProducer(msg)
+	partition, offset, err := p.SyncProducer.SendMessage(msg)
+	endProducer(span, msg, err)
+	return partition, offset, err
+}
+
+// SendMessages creates a span for each message, failed if the message is in the returned [sarama.ProducerErrors].
+func (p *syncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	spans := make([]trace.Span, len(msgs))
+	for i, msg := range msgs {
+		spans[i] = p.config.startProducer(msg)
+	}
+	err := p.SyncProducer.SendMessages(msgs)
+	failures := map[*sarama.ProducerMessage]error{}
+	if errs, ok := err.(sarama.ProducerErrors); ok {
+		for _, e := range errs {
+			failures[e.Msg] = e.Err
+		}
+	} else if err != nil {
+		for _, msg := range msgs {
+			failures[msg] = err
+		}
+	}
+	for i, msg := range msgs {
+		endProducer(spans[i], msg, failures[msg])
+	}
+	return err
+}
+
+// WrapAsyncProducer instruments an async producer, creating a span for each message.
+//
+// Spans end when the result of the message is returned if the config of the producer returns both
+// successes and errors. Otherwise results can't be tracked and spans end when messages are queued.
+// The successes and errors must then be read from the wrapper, as usual.
+func WrapAsyncProducer(saramaConfig *sarama.Config, producer sarama.AsyncProducer, opts ...Option) sarama.AsyncProducer {
+	p := &asyncProducer{
+		AsyncProducer: producer,
+		config:        newConfig(opts),
+		track:         saramaConfig.Producer.Return.Successes && saramaConfig.Producer.Return.Errors,
+		input:         make(chan *sarama.ProducerMessage),
+		successes:     make(chan *sarama.ProducerMessage),
+		errors:        make(chan *sarama.ProducerError),
+		spans:         map[*sarama.ProducerMessage]trace.Span{},
+	}
+	go p.processInput()
+	go p.processSuccesses()
+	go p.processErrors()
+	return p
+}
+
+type asyncProducer struct {
+	sarama.AsyncProducer
+	config *config
+	// track is whether spans end with the result of messages.
+	track bool
+
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+	closeOnce sync.Once
+
+	mu    sync.Mutex
+	spans map[*sarama.ProducerMessage]trace.Span
+}
+
+func (p *asyncProducer) Input() chan<- *sarama.ProducerMessage {
+	return p.input
+}
+
+func (p *asyncProducer) Successes() <-chan *sarama.ProducerMessage {
+	return p.successes
+}
+
+func (p *asyncProducer) Errors() <-chan *sarama.ProducerError {
+	return p.errors
+}
+
+// AsyncClose closes the producer once the messages already sent to the input are queued.
+func (p *asyncProducer) AsyncClose() {
+	p.closeOnce.Do(func() { close(p.input) })
+}
+
+// Close closes the producer like [sarama.AsyncProducer.Close], reading the results from the wrapper.
+func (p *asyncProducer) Close() error {
+	p.AsyncClose()
+	go func() {
+		for range p.successes {
+		}
+	}()
+	var errs sarama.ProducerErrors
+	for err := range p.errors {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *asyncProducer) processInput() {
+	for msg := range p.input {
+		span := p.config.startProducer(msg)
+		if p.track {
+			p.mu.Lock()
+			p.spans[msg] = span
+			p.mu.Unlock()
+		}
+		p.AsyncProducer.Input() <- msg
+		if !p.track {
+			span.End()
+		}
+	}
+	p.AsyncProducer.AsyncClose()
+}
+
+func (p *asyncProducer) processSuccesses() {
+	defer close(p.successes)
+	for msg := range p.AsyncProducer.Successes() {
+		p.end(msg, nil)
+		p.successes <- msg
+	}
+}
+
+func (p *asyncProducer) processErrors() {
+	defer close(p.errors)
+	for err := range p.AsyncProducer.Errors() {
+		p.end(err.Msg, err.Err)
+		p.errors <- err
+	}
+}
+
+func (p *asyncProducer) end(msg *sarama.ProducerMessage, err error) {
+	p.mu.Lock()
+	span, ok := p.spans[msg]
+	delete(p.spans, msg)
+	p.mu.Unlock()
+	if ok {
+		endProducer(span, msg, err)
+	}
+}
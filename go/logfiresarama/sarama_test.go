@@ -0,0 +1,219 @@
+package logfiresarama
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newTestProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	return provider, recorder
+}
+
+func testOptions(provider trace.TracerProvider) []Option {
+	return []Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}
+}
+
+func TestSyncProducer(t *testing.T) {
+	provider, recorder := newTestProvider(t)
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	mock := mocks.NewSyncProducer(t, config)
+	mock.ExpectSendMessageAndSucceed()
+	producer := WrapSyncProducer(mock, testOptions(provider)...)
+
+	parent, parentSpan := provider.Tracer("test").Start(t.Context(), "parent")
+	msg := &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder("hello")}
+	propagation.TraceContext{}.Inject(parent, NewProducerMessageCarrier(msg))
+	if _, _, err := producer.SendMessage(msg); err != nil {
+		t.Fatal(err)
+	}
+	parentSpan.End()
+
+	span := recorder.Ended()[0]
+	if span.Name() != "send orders" || span.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	if span.Parent().SpanID() != parentSpan.SpanContext().SpanID() {
+		t.Error("expected the span to be a child of the injected context")
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.MsgKey:                "send orders",
+		"messaging.system":            "kafka",
+		"messaging.operation.type":    "send",
+		"messaging.destination.name":  "orders",
+		"messaging.message.body.size": int64(5),
+		"messaging.kafka.offset":      int64(1),
+	})
+	if _, ok := oteltest.Attributes(span.Attributes())["messaging.destination.partition.id"]; !ok {
+		t.Error("expected the partition to be recorded")
+	}
+	// The headers now carry the context of the producer span.
+	ctx := propagation.TraceContext{}.Extract(context.Background(), NewProducerMessageCarrier(msg))
+	if trace.SpanContextFromContext(ctx).SpanID() != span.SpanContext().SpanID() {
+		t.Error("expected the context of the producer span in the headers")
+	}
+	if len(msg.Headers) != 1 {
+		t.Errorf("expected a single traceparent header, got %d headers", len(msg.Headers))
+	}
+}
+
+func TestSendMessagesError(t *testing.T) {
+	provider, recorder := newTestProvider(t)
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	mock := mocks.NewSyncProducer(t, config)
+	mock.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+	mock.ExpectSendMessageAndSucceed()
+	producer := WrapSyncProducer(mock, testOptions(provider)...)
+
+	err := producer.SendMessages([]*sarama.ProducerMessage{
+		{Topic: "orders", Value: sarama.StringEncoder("a")},
+		{Topic: "orders", Value: sarama.StringEncoder("b")},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	// Errors other than sarama.ProducerErrors fail all the messages.
+	for _, span := range spans {
+		if span.Status().Code != codes.Error {
+			t.Errorf("unexpected status %v", span.Status())
+		}
+	}
+}
+
+func TestAsyncProducer(t *testing.T) {
+	provider, recorder := newTestProvider(t)
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	mock := mocks.NewAsyncProducer(t, config)
+	mock.ExpectInputAndSucceed()
+	mock.ExpectInputAndFail(sarama.ErrMessageSizeTooLarge)
+	producer := WrapAsyncProducer(config, mock, testOptions(provider)...)
+
+	producer.Input() <- &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder("a")}
+	<-producer.Successes()
+	producer.Input() <- &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder("b")}
+	if err := <-producer.Errors(); !errors.Is(err.Err, sarama.ErrMessageSizeTooLarge) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := producer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	oteltest.CheckAttributes(t, spans[0].Attributes(), map[attribute.Key]any{"messaging.kafka.offset": int64(1)})
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("unexpected status %v", spans[1].Status())
+	}
+}
+
+// fakeSession and fakeClaim stand in for a consumer group, which needs a broker.
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	marked []*sarama.ConsumerMessage
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg)
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return 10 }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+type handlerFunc func(sarama.ConsumerGroupSession, sarama.ConsumerGroupClaim) error
+
+func (handlerFunc) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (handlerFunc) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (f handlerFunc) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	return f(session, claim)
+}
+
+func TestConsumerGroupHandler(t *testing.T) {
+	provider, recorder := newTestProvider(t)
+	tracer := provider.Tracer("test")
+
+	producerCtx, producerSpan := tracer.Start(t.Context(), "send orders")
+	producerSpan.End()
+	first := &sarama.ConsumerMessage{Topic: "orders", Partition: 2, Offset: 7, Value: []byte("a")}
+	propagation.TraceContext{}.Inject(producerCtx, NewConsumerMessageCarrier(first))
+	second := &sarama.ConsumerMessage{Topic: "orders", Partition: 2, Offset: 8, Value: []byte("b")}
+	claim := &fakeClaim{messages: make(chan *sarama.ConsumerMessage, 2)}
+	claim.messages <- first
+	claim.messages <- second
+	close(claim.messages)
+
+	var children []trace.SpanContext
+	handler := WrapConsumerGroupHandler(handlerFunc(func(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+		for msg := range claim.Messages() {
+			_, child := tracer.Start(MessageContext(msg), "handle")
+			child.End()
+			children = append(children, child.SpanContext())
+			if msg == first {
+				session.MarkMessage(msg, "")
+			}
+		}
+		return nil
+	}), testOptions(provider)...)
+	session := &fakeSession{}
+	if err := handler.ConsumeClaim(session, claim); err != nil {
+		t.Fatal(err)
+	}
+
+	var process []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "process orders" {
+			process = append(process, span)
+		}
+	}
+	if len(process) != 2 {
+		t.Fatalf("expected 2 process spans, got %d", len(process))
+	}
+	if process[0].SpanKind() != trace.SpanKindConsumer || process[0].Parent().SpanID() != producerSpan.SpanContext().SpanID() {
+		t.Error("expected a consumer span continuing the trace of the producer")
+	}
+	oteltest.CheckAttributes(t, process[0].Attributes(), map[attribute.Key]any{
+		"messaging.operation.type":           "process",
+		"messaging.destination.partition.id": "2",
+		"messaging.kafka.offset":             int64(7),
+		"messaging.kafka.consumer.lag":       int64(2),
+	})
+	for i, span := range process {
+		if children[i].TraceID() != span.SpanContext().TraceID() {
+			t.Errorf("expected the work on message %d to be in the trace of its span", i)
+		}
+	}
+	if len(session.marked) != 1 {
+		t.Errorf("expected the message to be marked on the session, got %d", len(session.marked))
+	}
+}
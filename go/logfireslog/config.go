@@ -0,0 +1,75 @@
+// Package logfireslog sends the records of log/slog to Pydantic Logfire.
+//
+// [NewHandler] returns a [slog.Handler] emitting each record as a Logfire log with its level,
+// attributes and source location. Records logged with a context holding a span are children of it,
+// so they are shown in the trace of the span:
+//
+//	logger := slog.New(logfireslog.NewHandler())
+//	logger.InfoContext(ctx, "order placed", "order_id", id)
+//
+// Attributes in groups are recorded with the group names as prefix, e.g. `request.method`.
+package logfireslog
+
+import (
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the logs emitted by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfireslog"
+
+// Option configures [NewHandler].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to emit logs. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all logs.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithLevel sets the minimum level of the records to emit. Defaults to [slog.LevelInfo].
+func WithLevel(level slog.Leveler) Option {
+	return func(c *config) {
+		c.level = level
+	}
+}
+
+// WithSpanEvents adds logs as events to the recording span of their context rather than emitting
+// separate logs, to keep them with the span and reduce the number of records. Logs without a span are
+// still emitted.
+func WithSpanEvents() Option {
+	return func(c *config) {
+		c.spanEvents = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	level          slog.Leveler
+	spanEvents     bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{level: slog.LevelInfo}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
@@ -0,0 +1,126 @@
+package logfireslog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/logconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// Handler is a [slog.Handler] emitting records as Logfire logs.
+type Handler struct {
+	config *config
+	tracer trace.Tracer
+	// attrs are the attributes added by [Handler.WithAttrs], already prefixed by their groups.
+	attrs []attribute.KeyValue
+	// prefix is the prefix of the keys of attributes, made of the names of the open groups.
+	prefix string
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// NewHandler returns a handler emitting records as Logfire logs.
+func NewHandler(opts ...Option) *Handler {
+	c := newConfig(opts)
+	return &Handler{config: c, tracer: c.tracer(), attrs: c.attrs}
+}
+
+// Enabled reports whether level is at least the level set with [WithLevel].
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.config.level.Level()
+}
+
+// Handle emits r as a Logfire log, a child of the span in ctx if any.
+// The first attribute whose value is an error is also recorded as an exception.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rec := logconv.Record{
+		Time:    r.Time,
+		Level:   Level(r.Level),
+		Message: r.Message,
+		Attrs:   slices.Grow(slices.Clip(h.attrs), r.NumAttrs()),
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.Attrs = appendAttr(rec.Attrs, h.prefix, a, &rec.Err)
+		return true
+	})
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		rec.File, rec.Line, rec.Function = frame.File, frame.Line, frame.Function
+	}
+	logconv.Emit(ctx, h.tracer, rec, h.config.spanEvents)
+	return nil
+}
+
+// WithAttrs returns a handler adding attrs to all records, in the open groups.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = slices.Clip(h.attrs)
+	for _, a := range attrs {
+		h2.attrs = appendAttr(h2.attrs, h.prefix, a, nil)
+	}
+	return &h2
+}
+
+// WithGroup returns a handler adding the attributes which follow to the group name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// Level converts a slog level to a Logfire level, e.g. [slog.LevelWarn] to [logfire.LevelWarn].
+// Levels between the predefined ones are kept between them, since both are 4 apart.
+func Level(level slog.Level) logfire.Level {
+	return logfire.Level(min(max(int(level-slog.LevelInfo)+int(logfire.LevelInfo), 1), 24))
+}
+
+// appendAttr appends a, flattening groups into keys prefixed with their name.
+// If err isn't nil and is unset, it is set to the value of a if it's an error.
+func appendAttr(attrs []attribute.KeyValue, prefix string, a slog.Attr, err *error) []attribute.KeyValue {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+	key := prefix + a.Key
+	switch v := a.Value; v.Kind() {
+	case slog.KindGroup:
+		// Attributes of groups without a key are inlined, as for the slog handlers.
+		if a.Key != "" {
+			prefix = key + "."
+		}
+		for _, ga := range v.Group() {
+			attrs = appendAttr(attrs, prefix, ga, err)
+		}
+		return attrs
+	case slog.KindString:
+		return append(attrs, attribute.String(key, v.String()))
+	case slog.KindInt64:
+		return append(attrs, attribute.Int64(key, v.Int64()))
+	case slog.KindUint64:
+		return append(attrs, logconv.Uint64(key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(attrs, attribute.Float64(key, v.Float64()))
+	case slog.KindBool:
+		return append(attrs, attribute.Bool(key, v.Bool()))
+	default:
+		if e, ok := v.Any().(error); ok && err != nil && *err == nil {
+			*err = e
+		}
+		return append(attrs, logconv.Value(key, v.Any()))
+	}
+}
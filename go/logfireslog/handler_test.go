@@ -0,0 +1,153 @@
+package logfireslog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newLogger(opts ...Option) (*slog.Logger, *tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return slog.New(NewHandler(append(opts, WithTracerProvider(provider))...)), recorder, provider
+}
+
+func TestHandle(t *testing.T) {
+	logger, recorder, provider := newLogger(WithAttributes(attribute.String("service", "orders")))
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	logger = logger.With("user_id", 42).WithGroup("request")
+	logger.WarnContext(ctx, "slow request",
+		"method", "GET",
+		slog.Duration("duration", 1500*time.Millisecond),
+		slog.Group("client", "ip", "10.0.0.1"),
+		slog.Group("", "inlined", true),
+		slog.Group("empty"),
+		"payload", map[string]int{"items": 2},
+	)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "slow request" {
+		t.Errorf("name = %s", span.Name())
+	}
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() || span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Errorf("parent = %v, want %v", span.Parent(), parent.SpanContext())
+	}
+	if span.StartTime() != span.EndTime() {
+		t.Errorf("duration = %v, want 0", span.EndTime().Sub(span.StartTime()))
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.SpanTypeKey:    "log",
+		logfire.LevelNumKey:    int64(logfire.LevelWarn),
+		logfire.MsgTemplateKey: "slow request",
+		logfire.MsgKey:         "slow request",
+		"service":              "orders",
+		"user_id":              int64(42),
+		"request.method":       "GET",
+		"request.duration":     "1.5s",
+		"request.client.ip":    "10.0.0.1",
+		"request.inlined":      true,
+		"request.payload":      `{"items":2}`,
+		"code.function":        "github.com/pydantic/logfire/go/logfireslog.TestHandle",
+	})
+	attrs := oteltest.Attributes(span.Attributes())
+	if file, _ := attrs["code.filepath"].(string); !strings.HasSuffix(file, "handler_test.go") {
+		t.Errorf("code.filepath = %v", attrs["code.filepath"])
+	}
+	if _, ok := attrs["request.empty"]; ok {
+		t.Error("empty group recorded")
+	}
+	if span.Status().Code != codes.Unset {
+		t.Errorf("status = %v", span.Status())
+	}
+}
+
+func TestHandleError(t *testing.T) {
+	logger, recorder, _ := newLogger()
+	logger.Error("payment failed", "err", errors.New("card declined"))
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelError),
+		"err":               "card declined",
+	})
+	events := span.Events()
+	if len(events) != 1 || events[0].Name != "exception" {
+		t.Fatalf("events = %v", events)
+	}
+	oteltest.CheckAttributes(t, events[0].Attributes, map[attribute.Key]any{"exception.message": "card declined"})
+}
+
+func TestLevel(t *testing.T) {
+	logger, recorder, _ := newLogger(WithLevel(slog.LevelDebug))
+	logger.Debug("debug")
+	logger.Log(context.Background(), slog.LevelDebug-8, "too low")
+	if spans := recorder.Ended(); len(spans) != 1 {
+		t.Errorf("spans = %d, want 1", len(spans))
+	}
+
+	for level, want := range map[slog.Level]logfire.Level{
+		slog.LevelDebug:     logfire.LevelDebug,
+		slog.LevelInfo:      logfire.LevelInfo,
+		slog.LevelInfo + 1:  logfire.LevelNotice,
+		slog.LevelWarn:      logfire.LevelWarn,
+		slog.LevelError:     logfire.LevelError,
+		slog.LevelError + 4: logfire.LevelFatal,
+		-100:                1,
+		100:                 24,
+	} {
+		if got := Level(level); got != want {
+			t.Errorf("Level(%v) = %v, want %v", level, got, want)
+		}
+	}
+}
+
+func TestSpanEvents(t *testing.T) {
+	logger, recorder, provider := newLogger(WithSpanEvents())
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	logger.WithGroup("request").InfoContext(ctx, "handling", "method", "GET")
+	logger.ErrorContext(ctx, "payment failed", "err", errors.New("card declined"))
+	span.End()
+	logger.Info("no span")
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "request" || spans[1].Name() != "no span" {
+		t.Fatalf("spans = %v, want the request and the log without span", spans)
+	}
+	if spans[0].Status().Code != codes.Unset {
+		t.Errorf("status = %v, want the span status left alone", spans[0].Status())
+	}
+	events := spans[0].Events()
+	if len(events) != 2 || events[0].Name != "handling" || events[1].Name != "payment failed" {
+		t.Fatalf("events = %v", events)
+	}
+	oteltest.CheckAttributes(t, events[0].Attributes, map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelInfo),
+		"request.method":    "GET",
+		"code.function":     "github.com/pydantic/logfire/go/logfireslog.TestSpanEvents",
+	})
+	oteltest.CheckAttributes(t, events[1].Attributes, map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelError),
+		"err":               "card declined",
+		"exception.type":    "*errors.errorString",
+		"exception.message": "card declined",
+	})
+}
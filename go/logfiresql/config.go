@@ -0,0 +1,94 @@
+// Package logfiresql instruments database/sql drivers for Pydantic Logfire.
+//
+// [Open] is a drop-in replacement for [sql.Open] creating a client span for each query,
+// named after the operation and table like `SELECT users`, with the sanitized query as message:
+//
+//	db, err := logfiresql.Open("pgx", dsn)
+//
+// Prepared statements, transactions, pings and new connections get spans too.
+// [RegisterPoolMetrics] exports the statistics of the connection pool of a [sql.DB] as metrics.
+package logfiresql
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiresql"
+
+// Option configures [Open] and the wrappers.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider used by [RegisterPoolMetrics]. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithSystem sets the `db.system.name` attribute, e.g. "postgresql".
+// [Open] infers it from the name of common drivers.
+func WithSystem(system string) Option {
+	return func(c *config) {
+		c.system = system
+	}
+}
+
+// WithAttributes adds attributes to all spans, e.g. `db.namespace` or `server.address`.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithoutSanitization records queries as is, rather than with their literals replaced by `?`.
+// Only use it if queries never embed sensitive values, e.g. when all values are passed as arguments.
+func WithoutSanitization() Option {
+	return func(c *config) {
+		c.noSanitization = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	system         string
+	attrs          []attribute.KeyValue
+	noSanitization bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.system == "" {
+		c.system = semconv.DBSystemNameOtherSQL.Value.AsString()
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) meter() metric.Meter {
+	return instrumentation.Meter(c.meterProvider, instrumentationName)
+}
+
+// attributes returns the attributes of all spans.
+func (c *config) attributes() []attribute.KeyValue {
+	return append([]attribute.KeyValue{semconv.DBSystemNameKey.String(c.system)}, c.attrs...)
+}
@@ -0,0 +1,242 @@
+package logfiresql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+)
+
+// conn implements all the optional interfaces of connections, falling back to what database/sql
+// does when the wrapped connection doesn't implement them.
+type conn struct {
+	driver.Conn
+	config *config
+}
+
+var (
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	ctx, span := c.config.startQuery(ctx, "prepare", query)
+	defer span.End()
+
+	var s driver.Stmt
+	var err error
+	if prep, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = prep.PrepareContext(ctx, query)
+	} else if s, err = c.Conn.Prepare(query); err == nil && ctx.Err() != nil {
+		s.Close()
+		s, err = nil, ctx.Err()
+	}
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+	return &stmt{Stmt: s, conn: c, query: query}, nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	ctx, span := c.config.start(ctx, "BEGIN", semconv.DBOperationName("BEGIN"))
+	defer span.End()
+
+	var t driver.Tx
+	var err error
+	if begin, ok := c.Conn.(driver.ConnBeginTx); ok {
+		t, err = begin.BeginTx(ctx, opts)
+	} else {
+		t, err = c.beginLegacy(ctx, opts)
+	}
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+	return &tx{Tx: t, ctx: ctx, config: c.config}, nil
+}
+
+// beginLegacy starts a transaction with the deprecated [driver.Conn.Begin], with the same checks as database/sql.
+func (c *conn) beginLegacy(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.Isolation != driver.IsolationLevel(0) {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Conn.Begin()
+}
+
+// ExecContext falls back to the deprecated [driver.Execer] like database/sql.
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var exec func(context.Context, string, []driver.NamedValue) (driver.Result, error)
+	switch execer := c.Conn.(type) {
+	case driver.ExecerContext:
+		exec = execer.ExecContext
+	case driver.Execer:
+		exec = func(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+			values, err := namedValuesToValues(args)
+			if err != nil {
+				return nil, err
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return execer.Exec(query, values)
+		}
+	default:
+		// database/sql prepares a statement instead.
+		return nil, driver.ErrSkip
+	}
+
+	// The span is only created once the driver ran the query, as database/sql prepares a statement,
+	// with its own spans, when the driver returns driver.ErrSkip.
+	start := time.Now()
+	result, err := exec(ctx, query, args)
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	_, span := c.config.startQuery(ctx, "", query, trace.WithTimestamp(start))
+	defer span.End()
+	endExec(span, result, err)
+	return result, err
+}
+
+// QueryContext falls back to the deprecated [driver.Queryer] like database/sql.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var run func(context.Context, string, []driver.NamedValue) (driver.Rows, error)
+	switch queryer := c.Conn.(type) {
+	case driver.QueryerContext:
+		run = queryer.QueryContext
+	case driver.Queryer:
+		run = func(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+			values, err := namedValuesToValues(args)
+			if err != nil {
+				return nil, err
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return queryer.Query(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+
+	// Like in ExecContext, driver.ErrSkip doesn't create a span.
+	start := time.Now()
+	r, err := run(ctx, query, args)
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	_, span := c.config.startQuery(ctx, "", query, trace.WithTimestamp(start))
+	return wrapRows(span, r, err)
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	pinger, ok := c.Conn.(driver.Pinger)
+	if !ok {
+		return nil
+	}
+	ctx, span := c.config.start(ctx, "ping")
+	defer span.End()
+	err := pinger.Ping(ctx)
+	if err != nil {
+		recordError(span, err)
+	}
+	return err
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue defers to the driver, or to the default conversion of database/sql.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// tx creates spans for the end of transactions, children of the span of the context
+// in which they began since [driver.Tx] methods don't have a context.
+type tx struct {
+	driver.Tx
+	ctx    context.Context
+	config *config
+}
+
+func (t *tx) Commit() error {
+	return t.end("COMMIT", t.Tx.Commit)
+}
+
+func (t *tx) Rollback() error {
+	return t.end("ROLLBACK", t.Tx.Rollback)
+}
+
+func (t *tx) end(operation string, f func() error) error {
+	// The transaction may end after its context is canceled, which doesn't matter for the span.
+	_, span := t.config.start(context.WithoutCancel(t.ctx), operation, semconv.DBOperationName(operation))
+	defer span.End()
+	err := f()
+	if err != nil {
+		recordError(span, err)
+	}
+	return err
+}
+
+// endExec records the result of a statement.
+func endExec(span trace.Span, result driver.Result, err error) {
+	if err != nil {
+		recordError(span, err)
+		return
+	}
+	if n, err := result.RowsAffected(); err == nil {
+		span.SetAttributes(sqlconv.RowsAffectedKey.Int64(n))
+	}
+}
+
+// namedValuesToValues converts arguments for the deprecated driver interfaces, like database/sql.
+func namedValuesToValues(named []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(named))
+	for i, nv := range named {
+		if nv.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = nv.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,24 @@
+package logfiresql
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// attributesKey is the context key of the attributes added by [ContextWithAttributes].
+type attributesKey struct{}
+
+// ContextWithAttributes returns a context adding attributes to the spans of the queries made with it,
+// e.g. for libraries built on database/sql to record what the driver can't see.
+func ContextWithAttributes(ctx context.Context, attrs ...attribute.KeyValue) context.Context {
+	existing := contextAttributes(ctx)
+	merged := make([]attribute.KeyValue, 0, len(existing)+len(attrs))
+	merged = append(append(merged, existing...), attrs...)
+	return context.WithValue(ctx, attributesKey{}, merged)
+}
+
+func contextAttributes(ctx context.Context) []attribute.KeyValue {
+	attrs, _ := ctx.Value(attributesKey{}).([]attribute.KeyValue)
+	return attrs
+}
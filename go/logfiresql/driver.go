@@ -0,0 +1,152 @@
+package logfiresql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+)
+
+// Open opens a database like [sql.Open], with the driver instrumented.
+// The `db.system.name` attribute is inferred from the driver name unless [WithSystem] is used.
+func Open(driverName, dsn string, opts ...Option) (*sql.DB, error) {
+	// sql.Open doesn't connect, and is the only way to get a registered driver.
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	if system := sqlconv.System(driverName); system != "" {
+		opts = append([]Option{WithSystem(system)}, opts...)
+	}
+	connector, err := WrapDriver(d, opts...).(driver.DriverContext).OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// WrapDriver instruments a driver, e.g. to register it under another name with [sql.Register].
+func WrapDriver(d driver.Driver, opts ...Option) driver.Driver {
+	return &wrappedDriver{Driver: d, config: newConfig(opts)}
+}
+
+// WrapConnector instruments a connector, for use with [sql.OpenDB].
+func WrapConnector(c driver.Connector, opts ...Option) driver.Connector {
+	d := &wrappedDriver{Driver: c.Driver(), config: newConfig(opts)}
+	return &connector{Connector: c, driver: d}
+}
+
+type wrappedDriver struct {
+	driver.Driver
+	config *config
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return d.wrapConn(c), nil
+}
+
+// OpenConnector implements [driver.DriverContext] for all drivers, so that connections
+// are created with a context and their spans have a parent.
+func (d *wrappedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.Driver.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &connector{Connector: c, driver: d}, nil
+	}
+	return &connector{Connector: dsnConnector{dsn: name, driver: d.Driver}, driver: d}, nil
+}
+
+type connector struct {
+	driver.Connector
+	driver *wrappedDriver
+}
+
+// Connect creates the `connect` span of a new connection. Reusing a connection of the pool
+// doesn't create a span.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	ctx, span := c.driver.config.start(ctx, "connect")
+	defer span.End()
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+	return c.driver.wrapConn(conn), nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close closes the connector if it's an [io.Closer], like [sql.DB.Close] does.
+func (c *connector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (d *wrappedDriver) wrapConn(c driver.Conn) driver.Conn {
+	return &conn{Conn: c, config: d.config}
+}
+
+// dsnConnector is the connector of drivers which don't implement [driver.DriverContext],
+// like the one database/sql uses.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// start creates the span of an operation which isn't a query, e.g. "connect".
+func (c *config) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return c.startSpan(ctx, name, trace.WithAttributes(attrs...))
+}
+
+func (c *config) startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return c.tracer().Start(ctx, name, append([]trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(c.attributes()...),
+	}, opts...)...)
+}
+
+// startQuery creates the span of a query. prefix is prepended to the span name and message
+// of operations such as preparing a statement.
+func (c *config) startQuery(ctx context.Context, prefix, query string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	q := sqlconv.Parse(query, !c.noSanitization)
+	q.Prefix = prefix
+	opts = append(opts, trace.WithAttributes(append(q.Attributes(), contextAttributes(ctx)...)...))
+	return c.startSpan(ctx, q.SpanName(c.system), opts...)
+}
+
+// recordError records errors other than [driver.ErrSkip], which asks database/sql to retry differently.
+func recordError(span trace.Span, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,322 @@
+package logfiresql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func init() {
+	sql.Register("sqlite", fakeDriver{})
+	sql.Register("logfiresql-minimal", fakeDriver{minimal: true})
+}
+
+// fakeDriver returns two rows for every query and affects one row for every statement.
+// Queries containing "fail" return an error, and the connection skips those containing "skip" to
+// prepare statements for them. Minimal connections only implement [driver.Conn].
+type fakeDriver struct {
+	minimal bool
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	if d.minimal {
+		return minimalConn{}, nil
+	}
+	return fakeConn{}, nil
+}
+
+type minimalConn struct{}
+
+func (minimalConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (minimalConn) Close() error                              { return nil }
+func (minimalConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeConn struct {
+	minimalConn
+}
+
+func (fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "skip") {
+		return nil, driver.ErrSkip
+	}
+	return fakeStmt{query: query}.Exec(nil)
+}
+
+func (fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "skip") {
+		return nil, driver.ErrSkip
+	}
+	return fakeStmt{query: query}.Query(nil)
+}
+
+func (fakeConn) Ping(context.Context) error { return nil }
+
+type fakeStmt struct {
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errors.New("syntax error")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errors.New("syntax error")
+	}
+	return &fakeRows{remaining: 2}, nil
+}
+
+type fakeRows struct {
+	remaining int
+}
+
+func (*fakeRows) Columns() []string { return []string{"id"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+	r.remaining--
+	dest[0] = int64(r.remaining)
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func openTestDB(t *testing.T, driverName string, opts ...Option) (*sql.DB, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	db, err := Open(driverName, "", append([]Option{WithTracerProvider(provider)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	// Connect before the tests so that the connect span isn't mixed with theirs.
+	if err := db.PingContext(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	recorder.Reset()
+	return db, recorder
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name()
+	}
+	return names
+}
+
+func TestQuery(t *testing.T) {
+	db, recorder := openTestDB(t, "sqlite")
+
+	rows, err := db.QueryContext(t.Context(), "SELECT id FROM users WHERE email = 'a@example.com' AND id > ?", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %v", spanNames(spans))
+	}
+	span := spans[0]
+	if span.Name() != "SELECT users" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		logfire.MsgKey:              "SELECT id FROM users WHERE email = ? AND id > ?",
+		"db.system.name":            "sqlite",
+		"db.query.text":             "SELECT id FROM users WHERE email = ? AND id > ?",
+		"db.operation.name":         "SELECT",
+		"db.collection.name":        "users",
+		"db.query.summary":          "SELECT users",
+		"db.response.returned_rows": int64(2),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+}
+
+func TestExec(t *testing.T) {
+	db, recorder := openTestDB(t, "sqlite", WithoutSanitization(), WithAttributes(attribute.String("db.namespace", "main")))
+
+	if _, err := db.ExecContext(t.Context(), "UPDATE users SET name = 'x'"); err != nil {
+		t.Fatal(err)
+	}
+
+	span := recorder.Ended()[0]
+	attrs := oteltest.Attributes(span.Attributes())
+	for key, want := range map[attribute.Key]any{
+		"db.query.text":             "UPDATE users SET name = 'x'",
+		"db.namespace":              "main",
+		"db.response.affected_rows": int64(1),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+}
+
+func TestContextWithAttributes(t *testing.T) {
+	db, recorder := openTestDB(t, "sqlite")
+
+	ctx := ContextWithAttributes(t.Context(), attribute.String("app.a", "1"))
+	ctx = ContextWithAttributes(ctx, attribute.String("app.b", "2"))
+	if _, err := db.ExecContext(ctx, "DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := oteltest.Attributes(recorder.Ended()[0].Attributes())
+	if attrs["app.a"] != "1" || attrs["app.b"] != "2" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
+
+func TestError(t *testing.T) {
+	db, recorder := openTestDB(t, "sqlite")
+
+	if _, err := db.ExecContext(t.Context(), "fail"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := db.QueryContext(t.Context(), "SELECT fail"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %v", spanNames(spans))
+	}
+	for _, span := range spans {
+		if span.Status().Code != codes.Error || span.Status().Description != "syntax error" {
+			t.Errorf("unexpected status %v", span.Status())
+		}
+		if len(span.Events()) != 1 || span.Events()[0].Name != "exception" {
+			t.Errorf("expected an exception event, got %v", span.Events())
+		}
+	}
+}
+
+func TestPreparedStatement(t *testing.T) {
+	// Without ExecContext, database/sql prepares a statement for each query.
+	db, recorder := openTestDB(t, "logfiresql-minimal", WithSystem("postgresql"))
+
+	stmt, err := db.PrepareContext(t.Context(), "INSERT INTO orders (id) VALUES (?)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := stmt.ExecContext(t.Context(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stmt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(t.Context(), "DELETE FROM orders"); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	want := []string{"prepare INSERT orders", "INSERT orders", "INSERT orders", "prepare DELETE orders", "DELETE orders"}
+	if strings.Join(spanNames(spans), ",") != strings.Join(want, ",") {
+		t.Fatalf("spans = %v, want %v", spanNames(spans), want)
+	}
+	if got := oteltest.Attributes(spans[0].Attributes())[logfire.MsgKey]; got != "prepare INSERT INTO orders (id) VALUES (?)" {
+		t.Errorf("unexpected message %v", got)
+	}
+	if got := oteltest.Attributes(spans[1].Attributes())["db.system.name"]; got != "postgresql" {
+		t.Errorf("db.system.name = %v", got)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	db, recorder := openTestDB(t, "sqlite")
+
+	if _, err := db.ExecContext(t.Context(), "DELETE FROM skipped"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.QueryContext(t.Context(), "SELECT id FROM skipped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"prepare DELETE skipped", "DELETE skipped", "prepare SELECT skipped", "SELECT skipped"}
+	if got := spanNames(recorder.Ended()); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("spans = %v, want the prepared statements only %v", got, want)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	db, recorder := openTestDB(t, "sqlite")
+
+	tx, err := db.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(t.Context(), "DELETE FROM sessions"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if got := strings.Join(spanNames(spans), ","); got != "BEGIN,DELETE sessions,COMMIT" {
+		t.Fatalf("unexpected spans %s", got)
+	}
+	if got := oteltest.Attributes(spans[2].Attributes())["db.operation.name"]; got != "COMMIT" {
+		t.Errorf("db.operation.name = %v", got)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	db, err := Open("sqlite", "", WithTracerProvider(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(spanNames(recorder.Ended()), ","); got != "connect,ping" {
+		t.Errorf("unexpected spans %s", got)
+	}
+}
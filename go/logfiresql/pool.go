@@ -0,0 +1,29 @@
+package logfiresql
+
+import (
+	"database/sql"
+
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/pydantic/logfire/go/internal/sqlconv"
+)
+
+// RegisterPoolMetrics exports the statistics of the connection pool of db, named name in the
+// `db.client.connection.pool.name` attribute: the `db.client.connection.count` of idle and used connections,
+// `db.client.connection.max`, and the cumulative `db.client.connection.wait_count` and
+// `db.client.connection.wait_duration` of waits for a connection.
+//
+// It should be called after [logfire.Configure] when using the global meter provider, and the registration
+// unregistered once db is closed.
+func RegisterPoolMetrics(db *sql.DB, name string, opts ...Option) (metric.Registration, error) {
+	return sqlconv.RegisterPoolMetrics(newConfig(opts).meter(), name, func() sqlconv.PoolStats {
+		s := db.Stats()
+		return sqlconv.PoolStats{
+			Idle:         int64(s.Idle),
+			Used:         int64(s.InUse),
+			Max:          int64(s.MaxOpenConnections),
+			WaitCount:    s.WaitCount,
+			WaitDuration: s.WaitDuration,
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package logfiresql
+
+import (
+	"database/sql"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRegisterPoolMetrics(t *testing.T) {
+	db, err := sql.Open("sqlite", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(5)
+	conn, err := db.Conn(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	reader := sdkmetric.NewManualReader()
+	registration, err := RegisterPoolMetrics(db, "main",
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registration.Unregister()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]int64{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		sum, ok := m.Data.(metricdata.Sum[int64])
+		if !ok {
+			continue
+		}
+		for _, point := range sum.DataPoints {
+			if name, _ := point.Attributes.Value("db.client.connection.pool.name"); name != attribute.StringValue("main") {
+				t.Errorf("unexpected pool name %v", name)
+			}
+			state, _ := point.Attributes.Value("db.client.connection.state")
+			values[m.Name+" "+state.AsString()] = point.Value
+		}
+	}
+	for key, want := range map[string]int64{
+		"db.client.connection.count used":  1,
+		"db.client.connection.count idle":  0,
+		"db.client.connection.max ":        5,
+		"db.client.connection.wait_count ": 0,
+	} {
+		if got, ok := values[key]; !ok || got != want {
+			t.Errorf("%s = %d, want %d", key, got, want)
+		}
+	}
+}
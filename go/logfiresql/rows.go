@@ -0,0 +1,109 @@
+package logfiresql
+
+import (
+	"database/sql/driver"
+	"io"
+	"reflect"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// wrapRows returns rows ending the span of their query when they're closed,
+// so that the span includes fetching the rows.
+func wrapRows(span trace.Span, r driver.Rows, err error) (driver.Rows, error) {
+	if err != nil {
+		recordError(span, err)
+		span.End()
+		return nil, err
+	}
+	return &rows{Rows: r, span: span}, nil
+}
+
+// rows implements all the optional interfaces of rows, with the defaults of database/sql
+// when the wrapped rows don't implement them.
+type rows struct {
+	driver.Rows
+	span  trace.Span
+	count int64
+}
+
+var (
+	_ driver.RowsNextResultSet              = (*rows)(nil)
+	_ driver.RowsColumnTypeScanType         = (*rows)(nil)
+	_ driver.RowsColumnTypeDatabaseTypeName = (*rows)(nil)
+	_ driver.RowsColumnTypeLength           = (*rows)(nil)
+	_ driver.RowsColumnTypeNullable         = (*rows)(nil)
+	_ driver.RowsColumnTypePrecisionScale   = (*rows)(nil)
+)
+
+func (r *rows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch err {
+	case nil:
+		r.count++
+	case io.EOF:
+	default:
+		recordError(r.span, err)
+	}
+	return err
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	if err != nil {
+		recordError(r.span, err)
+	}
+	r.span.SetAttributes(semconv.DBResponseReturnedRows(int(r.count)))
+	r.span.End()
+	return err
+}
+
+func (r *rows) HasNextResultSet() bool {
+	if next, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return next.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *rows) NextResultSet() error {
+	if next, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return next.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return reflect.TypeFor[any]()
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *rows) ColumnTypeLength(index int) (int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *rows) ColumnTypeNullable(index int) (bool, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *rows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,96 @@
+package logfiresql
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// stmt creates a span for each execution of a prepared statement.
+//
+// Statements implementing the deprecated [driver.ColumnConverter] are converted
+// with [driver.NamedValueChecker] or the default conversion instead.
+type stmt struct {
+	driver.Stmt
+	conn  *conn
+	query string
+}
+
+var (
+	_ driver.StmtExecContext   = (*stmt)(nil)
+	_ driver.StmtQueryContext  = (*stmt)(nil)
+	_ driver.NamedValueChecker = (*stmt)(nil)
+)
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := s.conn.config.startQuery(ctx, "", s.query)
+	defer span.End()
+
+	var result driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		result, err = s.execLegacy(ctx, args)
+	}
+	endExec(span, result, err)
+	return result, err
+}
+
+func (s *stmt) execLegacy(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	values, err := namedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values)
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := s.conn.config.startQuery(ctx, "", s.query)
+
+	var r driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		r, err = queryer.QueryContext(ctx, args)
+	} else {
+		r, err = s.queryLegacy(ctx, args)
+	}
+	return wrapRows(span, r, err)
+}
+
+func (s *stmt) queryLegacy(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	values, err := namedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(values)
+}
+
+// CheckNamedValue defers to the statement, then to the connection like database/sql.
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+func valuesToNamedValues(values []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(values))
+	for i, v := range values {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
@@ -0,0 +1,123 @@
+package logfiresqlx
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/pydantic/logfire/go/logfiresql"
+)
+
+const (
+	// parameterKeyPrefix is the prefix of the attributes of named parameters, from the semantic conventions.
+	parameterKeyPrefix = "db.query.parameter."
+	// destinationTypeKey records the Go type a query is scanned into, e.g. `[]main.User`.
+	destinationTypeKey = attribute.Key("db.sqlx.destination_type")
+)
+
+// binder binds the named parameters of a query, like [sqlx.DB] and [sqlx.Tx] do.
+type binder interface {
+	BindNamed(query string, arg any) (string, []any, error)
+}
+
+// namedContext returns a context adding the named parameters of a query to its span.
+func namedContext(ctx context.Context, b binder, query string, arg any) context.Context {
+	names := parameterNames(query)
+	if len(names) == 0 {
+		return ctx
+	}
+	_, args, err := b.BindNamed(query, arg)
+	// Slices of arguments for batch inserts repeat the parameters, which aren't recorded.
+	if err != nil || len(args) != len(names) {
+		return ctx
+	}
+	attrs := make([]attribute.KeyValue, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if v, ok := parameterValue(args[i]); ok {
+			attrs = append(attrs, attribute.KeyValue{Key: attribute.Key(parameterKeyPrefix + name), Value: v})
+		}
+	}
+	return logfiresql.ContextWithAttributes(ctx, attrs...)
+}
+
+// destContext returns a context adding the type a query is scanned into to its span.
+func destContext(ctx context.Context, dest any) context.Context {
+	t := reflect.TypeOf(dest)
+	if t == nil {
+		return ctx
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return logfiresql.ContextWithAttributes(ctx, destinationTypeKey.String(t.String()))
+}
+
+// parameterValue converts a parameter like database/sql does, so that values such as pointers and
+// [driver.Valuer] implementations are recorded as what's sent to the database. NULLs aren't recorded.
+func parameterValue(arg any) (attribute.Value, bool) {
+	v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+	if err != nil {
+		return attribute.StringValue(fmt.Sprint(arg)), true
+	}
+	switch v := v.(type) {
+	case nil:
+		return attribute.Value{}, false
+	case int64:
+		return attribute.Int64Value(v), true
+	case float64:
+		return attribute.Float64Value(v), true
+	case bool:
+		return attribute.BoolValue(v), true
+	case string:
+		return attribute.StringValue(v), true
+	case []byte:
+		if !utf8.Valid(v) {
+			return attribute.StringValue(fmt.Sprintf("<%d bytes>", len(v))), true
+		}
+		return attribute.StringValue(string(v)), true
+	case time.Time:
+		return attribute.StringValue(v.Format(time.RFC3339Nano)), true
+	}
+	return attribute.StringValue(fmt.Sprint(v)), true
+}
+
+// parameterNames returns the names of the parameters of a named query in order,
+// parsing it like sqlx: `::` is an escaped colon and `:=` an assignment.
+func parameterNames(query string) []string {
+	var names []string
+	for i := 0; i < len(query); i++ {
+		if query[i] != ':' {
+			continue
+		}
+		if i+1 < len(query) && (query[i+1] == ':' || query[i+1] == '=') {
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(query) && isNameByte(query[end]) {
+			end++
+		}
+		if end > i+1 {
+			names = append(names, query[i+1:end])
+		}
+		i = end - 1
+	}
+	return names
+}
+
+// isNameByte reports whether sqlx allows a byte in parameter names. Like sqlx, it checks bytes rather than runes.
+func isNameByte(b byte) bool {
+	r := rune(b)
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || b == '_' || b == '.'
+}
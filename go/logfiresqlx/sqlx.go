@@ -0,0 +1,122 @@
+// Package logfiresqlx instruments sqlx for Pydantic Logfire.
+//
+// [Open] opens a [sqlx.DB] instrumented with [logfiresql], whose named queries also record their
+// parameters as `db.query.parameter.<name>` attributes, and whose Get and Select methods record
+// the type they scan into as `db.sqlx.destination_type`:
+//
+//	db, err := logfiresqlx.Open("pgx", dsn)
+//	_, err = db.NamedExecContext(ctx, "UPDATE users SET email = :email WHERE id = :id", user)
+//
+// Parameters go through the scrubbing of the SDK like all attributes, so that values of parameters
+// named like `password` are redacted.
+package logfiresqlx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/pydantic/logfire/go/logfiresql"
+)
+
+// DB is a [sqlx.DB] recording the parameters of named queries and the destination of scans.
+type DB struct {
+	*sqlx.DB
+}
+
+// Open opens a database like [sqlx.Open], with the driver instrumented by [logfiresql.Open].
+func Open(driverName, dsn string, opts ...logfiresql.Option) (*DB, error) {
+	db, err := logfiresql.Open(driverName, dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{DB: sqlx.NewDb(db, driverName)}, nil
+}
+
+// Wrap wraps a database whose driver is already instrumented, e.g. with [logfiresql.WrapConnector].
+func Wrap(db *sqlx.DB) *DB {
+	return &DB{DB: db}
+}
+
+func (db *DB) NamedExec(query string, arg any) (sql.Result, error) {
+	return db.NamedExecContext(context.Background(), query, arg)
+}
+
+func (db *DB) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	return db.DB.NamedExecContext(namedContext(ctx, db.DB, query, arg), query, arg)
+}
+
+func (db *DB) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
+	return db.NamedQueryContext(context.Background(), query, arg)
+}
+
+func (db *DB) NamedQueryContext(ctx context.Context, query string, arg any) (*sqlx.Rows, error) {
+	return db.DB.NamedQueryContext(namedContext(ctx, db.DB, query, arg), query, arg)
+}
+
+func (db *DB) Get(dest any, query string, args ...any) error {
+	return db.GetContext(context.Background(), dest, query, args...)
+}
+
+func (db *DB) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return db.DB.GetContext(destContext(ctx, dest), dest, query, args...)
+}
+
+func (db *DB) Select(dest any, query string, args ...any) error {
+	return db.SelectContext(context.Background(), dest, query, args...)
+}
+
+func (db *DB) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return db.DB.SelectContext(destContext(ctx, dest), dest, query, args...)
+}
+
+func (db *DB) Beginx() (*Tx, error) {
+	return db.BeginTxx(context.Background(), nil)
+}
+
+func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx}, nil
+}
+
+// Tx is a [sqlx.Tx] recording the parameters of named queries and the destination of scans.
+type Tx struct {
+	*sqlx.Tx
+}
+
+func (tx *Tx) NamedExec(query string, arg any) (sql.Result, error) {
+	return tx.NamedExecContext(context.Background(), query, arg)
+}
+
+func (tx *Tx) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	return tx.Tx.NamedExecContext(namedContext(ctx, tx.Tx, query, arg), query, arg)
+}
+
+func (tx *Tx) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
+	return tx.NamedQueryContext(context.Background(), query, arg)
+}
+
+// NamedQueryContext runs a named query in the transaction, which [sqlx.Tx] only supports without a context.
+func (tx *Tx) NamedQueryContext(ctx context.Context, query string, arg any) (*sqlx.Rows, error) {
+	return sqlx.NamedQueryContext(namedContext(ctx, tx.Tx, query, arg), tx.Tx, query, arg)
+}
+
+func (tx *Tx) Get(dest any, query string, args ...any) error {
+	return tx.GetContext(context.Background(), dest, query, args...)
+}
+
+func (tx *Tx) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return tx.Tx.GetContext(destContext(ctx, dest), dest, query, args...)
+}
+
+func (tx *Tx) Select(dest any, query string, args ...any) error {
+	return tx.SelectContext(context.Background(), dest, query, args...)
+}
+
+func (tx *Tx) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return tx.Tx.SelectContext(destContext(ctx, dest), dest, query, args...)
+}
@@ -0,0 +1,206 @@
+package logfiresqlx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+	"github.com/pydantic/logfire/go/logfiresql"
+)
+
+func init() {
+	sql.Register("logfiresqlx", fakeDriver{})
+}
+
+// fakeDriver returns a single user for every query and affects one row for every statement.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{remaining: 1}, nil
+}
+
+type fakeRows struct {
+	remaining int
+}
+
+func (*fakeRows) Columns() []string { return []string{"id", "name"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = int64(1), "alice"
+	r.remaining--
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type user struct {
+	ID       int     `db:"id"`
+	Name     string  `db:"name"`
+	Nickname *string `db:"nickname"`
+}
+
+func openTestDB(t *testing.T) (*DB, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	db, err := Open("logfiresqlx", "", logfiresql.WithTracerProvider(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := db.PingContext(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	recorder.Reset()
+	return db, recorder
+}
+
+func TestNamedExec(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	_, err := db.NamedExecContext(t.Context(), "UPDATE users SET name = :name, nickname = :nickname WHERE id = :id",
+		user{ID: 42, Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	attrs := oteltest.Attributes(spans[0].Attributes())
+	for key, want := range map[attribute.Key]any{
+		"db.query.text":           "UPDATE users SET name = ?, nickname = ? WHERE id = ?",
+		"db.query.parameter.name": "alice",
+		"db.query.parameter.id":   int64(42),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key], want)
+		}
+	}
+	if _, ok := attrs["db.query.parameter.nickname"]; ok {
+		t.Error("expected NULL parameters not to be recorded")
+	}
+}
+
+func TestNamedQueryInTransaction(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	tx, err := db.BeginTxx(t.Context(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := tx.NamedQueryContext(t.Context(), "SELECT * FROM users WHERE name = :name", map[string]any{"name": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	idx := slices.IndexFunc(recorder.Ended(), func(s sdktrace.ReadOnlySpan) bool { return s.Name() == "SELECT users" })
+	if idx < 0 {
+		t.Fatal("expected a span for the query")
+	}
+	if got := oteltest.Attributes(recorder.Ended()[idx].Attributes())["db.query.parameter.name"]; got != "alice" {
+		t.Errorf("db.query.parameter.name = %v", got)
+	}
+}
+
+func TestDestinationType(t *testing.T) {
+	db, recorder := openTestDB(t)
+
+	var u user
+	if err := db.GetContext(t.Context(), &u, "SELECT id, name FROM users WHERE id = ?", 1); err != nil {
+		t.Fatal(err)
+	}
+	var users []user
+	if err := db.Select(&users, "SELECT id, name FROM users"); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for i, want := range []string{"logfiresqlx.user", "[]logfiresqlx.user"} {
+		if got := oteltest.Attributes(spans[i].Attributes())["db.sqlx.destination_type"]; got != want {
+			t.Errorf("db.sqlx.destination_type = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestParametersAreScrubbed(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	shutdown, err := logfire.Configure(t.Context(),
+		logfire.WithSendToLogfire(false),
+		logfire.WithConsole(false),
+		logfire.WithAdditionalSpanProcessors(recorder),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = shutdown(t.Context()) })
+	db, err := Open("logfiresqlx", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.NamedExec("UPDATE users SET password = :password", map[string]any{"password": "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	got := oteltest.Attributes(spans[len(spans)-1].Attributes())["db.query.parameter.password"]
+	if got != "[Scrubbed due to 'password']" {
+		t.Errorf("db.query.parameter.password = %v", got)
+	}
+}
+
+func TestParameterNames(t *testing.T) {
+	for query, want := range map[string][]string{
+		"SELECT * FROM users WHERE id = :id":            {"id"},
+		"INSERT INTO t (a, b) VALUES (:a, :user.b)":     {"a", "user.b"},
+		"SELECT :a::text, x := 1 FROM t WHERE y = :y_2": {"a", "y_2"},
+		"SELECT * FROM users":                           nil,
+		"UPDATE t SET a = :a, b = :b WHERE a = :a":      {"a", "b", "a"},
+	} {
+		if got := parameterNames(query); !slices.Equal(got, want) {
+			t.Errorf("parameterNames(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
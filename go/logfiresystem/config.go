@@ -0,0 +1,68 @@
+// Package logfiresystem exports metrics of the host for Pydantic Logfire, with the names and attributes
+// of the Python SDK's `instrument_system_metrics`, so that the same dashboards work for services in both languages.
+//
+// [Instrument] should be called once, after [logfire.Configure]:
+//
+//	if err := logfiresystem.Instrument(); err != nil {
+//		return err
+//	}
+//
+// By default, only the CPU, memory and swap utilization are exported, like the `basic` metrics of the
+// Python SDK. [WithFullMetrics] also exports the CPU time and utilization of each CPU, the memory and
+// swap usage, and the disk and network I/O. [WithProcessMetrics] adds metrics of the current process.
+package logfiresystem
+
+import (
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the metrics exported by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiresystem"
+
+// Option configures [Instrument].
+type Option func(*config)
+
+// WithMeterProvider sets the meter provider used to export the metrics. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithFullMetrics exports all metrics, like the `full` metrics of the Python SDK, instead of only the
+// utilization of the CPU, memory and swap. Metrics of disks and network interfaces have an attribute
+// for each device, so they can have many series.
+func WithFullMetrics() Option {
+	return func(c *config) {
+		c.full = true
+	}
+}
+
+// WithProcessMetrics also exports metrics of the current process: its resident memory as `process.memory.usage`,
+// `process.open_file_descriptor.count`, `process.thread.count`, `process.cpu.time` in user and system mode,
+// and `process.uptime`.
+func WithProcessMetrics() Option {
+	return func(c *config) {
+		c.process = true
+	}
+}
+
+type config struct {
+	meterProvider metric.MeterProvider
+	full          bool
+	process       bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) meter() metric.Meter {
+	return instrumentation.Meter(c.meterProvider, instrumentationName)
+}
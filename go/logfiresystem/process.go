@@ -0,0 +1,76 @@
+package logfiresystem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// processMetrics observes the metrics of the current process.
+type processMetrics struct {
+	process *process.Process
+	start   time.Time
+
+	memoryUsage     metric.Int64ObservableUpDownCounter
+	fileDescriptors metric.Int64ObservableUpDownCounter
+	threads         metric.Int64ObservableUpDownCounter
+	cpuTime         metric.Float64ObservableCounter
+	uptime          metric.Float64ObservableGauge
+}
+
+// instrumentProcess exports the metrics of the current process, see [WithProcessMetrics].
+func instrumentProcess(meter metric.Meter) error {
+	p, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return err
+	}
+	m := &processMetrics{process: p, start: time.Now()}
+	if created, err := p.CreateTime(); err == nil {
+		m.start = time.UnixMilli(created)
+	}
+
+	var errs []error
+	upDown := func(name, unit, description string) metric.Int64ObservableUpDownCounter {
+		c, err := meter.Int64ObservableUpDownCounter(name, metric.WithUnit(unit), metric.WithDescription(description))
+		errs = append(errs, err)
+		return c
+	}
+	m.memoryUsage = upDown("process.memory.usage", "By", "Resident memory of the process")
+	m.fileDescriptors = upDown("process.open_file_descriptor.count", "{file_descriptor}",
+		"File descriptors open by the process")
+	m.threads = upDown("process.thread.count", "{thread}", "Threads of the process")
+	m.cpuTime, err = meter.Float64ObservableCounter("process.cpu.time", metric.WithUnit("s"),
+		metric.WithDescription("CPU time of the process in user and system mode"))
+	errs = append(errs, err)
+	m.uptime, err = meter.Float64ObservableGauge("process.uptime", metric.WithUnit("s"),
+		metric.WithDescription("Time since the process started"))
+	errs = append(errs, err)
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(m.observe, m.memoryUsage, m.fileDescriptors, m.threads, m.cpuTime, m.uptime)
+	return err
+}
+
+func (m *processMetrics) observe(ctx context.Context, o metric.Observer) error {
+	// Like for the metrics of the host, those the platform doesn't provide are skipped.
+	if memory, err := m.process.MemoryInfoWithContext(ctx); err == nil {
+		o.ObserveInt64(m.memoryUsage, int64(memory.RSS))
+	}
+	if fds, err := m.process.NumFDsWithContext(ctx); err == nil {
+		o.ObserveInt64(m.fileDescriptors, int64(fds))
+	}
+	if threads, err := m.process.NumThreadsWithContext(ctx); err == nil {
+		o.ObserveInt64(m.threads, int64(threads))
+	}
+	if times, err := m.process.TimesWithContext(ctx); err == nil {
+		o.ObserveFloat64(m.cpuTime, times.User, metric.WithAttributes(typeKey.String("user")))
+		o.ObserveFloat64(m.cpuTime, times.System, metric.WithAttributes(typeKey.String("system")))
+	}
+	o.ObserveFloat64(m.uptime, time.Since(m.start).Seconds())
+	return nil
+}
@@ -0,0 +1,253 @@
+package logfiresystem
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/net"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Attributes of the metrics, those of the Python SDK.
+const (
+	cpuKey       = attribute.Key("cpu")
+	stateKey     = attribute.Key("state")
+	deviceKey    = attribute.Key("device")
+	directionKey = attribute.Key("direction")
+	typeKey      = attribute.Key("type")
+)
+
+// cpuStates are the values of the `state` attribute of the CPU metrics, in the order of [cpuTimes].
+var cpuStates = [...]string{"user", "system", "idle", "nice", "iowait", "irq", "softirq", "steal"}
+
+// Indexes of the states in which a CPU isn't busy.
+const (
+	cpuIdle   = 2
+	cpuIOWait = 4
+)
+
+// Instrument exports the metrics of the host with the meter provider, from then on.
+// It should be called once, after [logfire.Configure] when using the global meter provider.
+//
+// Metrics which can't be read on the platform aren't exported.
+// The metrics of the current process are only exported with [WithProcessMetrics].
+func Instrument(opts ...Option) error {
+	c := newConfig(opts)
+	meter := c.meter()
+	s := &system{full: c.full}
+	var errs []error
+	gauge := func(name, unit, description string) metric.Float64ObservableGauge {
+		g, err := meter.Float64ObservableGauge(name, metric.WithUnit(unit), metric.WithDescription(description))
+		errs = append(errs, err)
+		return g
+	}
+	counter := func(name, unit, description string) metric.Int64ObservableCounter {
+		c, err := meter.Int64ObservableCounter(name, metric.WithUnit(unit), metric.WithDescription(description))
+		errs = append(errs, err)
+		return c
+	}
+	usage := func(name, description string) metric.Int64ObservableUpDownCounter {
+		c, err := meter.Int64ObservableUpDownCounter(name, metric.WithUnit("By"), metric.WithDescription(description))
+		errs = append(errs, err)
+		return c
+	}
+
+	s.cpuSimpleUtilization = gauge("system.cpu.simple_utilization", "1",
+		"Fraction of the time the CPUs were busy since the previous collection")
+	s.memoryUtilization = gauge("system.memory.utilization", "1", "Fraction of the memory by state")
+	s.swapUtilization = gauge("system.swap.utilization", "1", "Fraction of the swap by state")
+	instruments := []metric.Observable{s.cpuSimpleUtilization, s.memoryUtilization, s.swapUtilization}
+	if c.full {
+		var err error
+		s.cpuTime, err = meter.Float64ObservableCounter("system.cpu.time", metric.WithUnit("s"),
+			metric.WithDescription("Time spent by each CPU in each state"))
+		errs = append(errs, err)
+		s.cpuUtilization = gauge("system.cpu.utilization", "1",
+			"Fraction of the time spent by each CPU in each state since the previous collection")
+		s.memoryUsage = usage("system.memory.usage", "Memory by state")
+		s.swapUsage = usage("system.swap.usage", "Swap by state")
+		s.diskIO = counter("system.disk.io", "By", "Bytes read and written by each disk")
+		s.diskOperations = counter("system.disk.operations", "{operation}", "Reads and writes of each disk")
+		s.diskTime, err = meter.Float64ObservableCounter("system.disk.time", metric.WithUnit("s"),
+			metric.WithDescription("Time spent reading and writing by each disk"))
+		errs = append(errs, err)
+		s.networkIO = counter("system.network.io", "By", "Bytes transmitted and received by each network interface")
+		s.networkPackets = counter("system.network.packets", "{packet}",
+			"Packets transmitted and received by each network interface")
+		s.networkErrors = counter("system.network.errors", "{error}",
+			"Errors transmitting and receiving by each network interface")
+		s.networkDropped = counter("system.network.dropped.packets", "{packet}",
+			"Packets dropped by each network interface")
+		instruments = append(instruments, s.cpuTime, s.cpuUtilization, s.memoryUsage, s.swapUsage, s.diskIO,
+			s.diskOperations, s.diskTime, s.networkIO, s.networkPackets, s.networkErrors, s.networkDropped)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	// The utilization of the first collection is the one since Instrument.
+	s.cpuTimes, _ = cpu.Times(true)
+	if _, err := meter.RegisterCallback(s.observe, instruments...); err != nil {
+		return err
+	}
+	if c.process {
+		return instrumentProcess(meter)
+	}
+	return nil
+}
+
+// system observes the metrics of the host.
+type system struct {
+	full bool
+
+	cpuSimpleUtilization metric.Float64ObservableGauge
+	memoryUtilization    metric.Float64ObservableGauge
+	swapUtilization      metric.Float64ObservableGauge
+
+	cpuTime        metric.Float64ObservableCounter
+	cpuUtilization metric.Float64ObservableGauge
+	memoryUsage    metric.Int64ObservableUpDownCounter
+	swapUsage      metric.Int64ObservableUpDownCounter
+	diskIO         metric.Int64ObservableCounter
+	diskOperations metric.Int64ObservableCounter
+	diskTime       metric.Float64ObservableCounter
+	networkIO      metric.Int64ObservableCounter
+	networkPackets metric.Int64ObservableCounter
+	networkErrors  metric.Int64ObservableCounter
+	networkDropped metric.Int64ObservableCounter
+
+	// mu guards cpuTimes, the times of the CPUs at the previous collection.
+	mu       sync.Mutex
+	cpuTimes []cpu.TimesStat
+}
+
+func (s *system) observe(ctx context.Context, o metric.Observer) error {
+	// Errors only mean that the platform doesn't provide the metrics, so they're not reported at every collection.
+	s.observeCPU(ctx, o)
+	s.observeMemory(ctx, o)
+	if s.full {
+		s.observeDisks(ctx, o)
+		s.observeNetwork(ctx, o)
+	}
+	return nil
+}
+
+// cpuTimes returns the times of a CPU in each of [cpuStates].
+func cpuTimes(t cpu.TimesStat) [len(cpuStates)]float64 {
+	return [...]float64{t.User, t.System, t.Idle, t.Nice, t.Iowait, t.Irq, t.Softirq, t.Steal}
+}
+
+func (s *system) observeCPU(ctx context.Context, o metric.Observer) {
+	times, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	previous := s.cpuTimes
+	s.cpuTimes = times
+	s.mu.Unlock()
+
+	var busy, total float64
+	for i, t := range times {
+		current := cpuTimes(t)
+		var deltas [len(cpuStates)]float64
+		var cpuTotal float64
+		for j, seconds := range current {
+			deltas[j] = seconds
+			if i < len(previous) {
+				deltas[j] -= cpuTimes(previous[i])[j]
+			}
+			cpuTotal += deltas[j]
+		}
+		total += cpuTotal
+		busy += cpuTotal - deltas[cpuIdle] - deltas[cpuIOWait]
+		if !s.full {
+			continue
+		}
+		for j, state := range cpuStates {
+			attrs := metric.WithAttributes(cpuKey.Int(i), stateKey.String(state))
+			o.ObserveFloat64(s.cpuTime, current[j], attrs)
+			if cpuTotal > 0 {
+				o.ObserveFloat64(s.cpuUtilization, deltas[j]/cpuTotal, attrs)
+			}
+		}
+	}
+	if total > 0 {
+		o.ObserveFloat64(s.cpuSimpleUtilization, busy/total)
+	}
+}
+
+// memoryState is the memory in the state of the `state` attribute.
+type memoryState struct {
+	name  string
+	bytes uint64
+}
+
+func (s *system) observeMemory(ctx context.Context, o metric.Observer) {
+	if vm, err := mem.VirtualMemoryWithContext(ctx); err == nil && vm.Total > 0 {
+		states := []memoryState{{"available", vm.Available}}
+		if s.full {
+			states = append(states, memoryState{"used", vm.Used}, memoryState{"free", vm.Free},
+				memoryState{"cached", vm.Cached}, memoryState{"buffers", vm.Buffers})
+		}
+		for _, state := range states {
+			attrs := metric.WithAttributes(stateKey.String(state.name))
+			o.ObserveFloat64(s.memoryUtilization, float64(state.bytes)/float64(vm.Total), attrs)
+			if s.full {
+				o.ObserveInt64(s.memoryUsage, int64(state.bytes), attrs)
+			}
+		}
+	}
+	if swap, err := mem.SwapMemoryWithContext(ctx); err == nil {
+		used := metric.WithAttributes(stateKey.String("used"))
+		if swap.Total > 0 {
+			o.ObserveFloat64(s.swapUtilization, float64(swap.Used)/float64(swap.Total), used)
+		}
+		if s.full {
+			o.ObserveInt64(s.swapUsage, int64(swap.Used), used)
+			o.ObserveInt64(s.swapUsage, int64(swap.Free), metric.WithAttributes(stateKey.String("free")))
+		}
+	}
+}
+
+func (s *system) observeDisks(ctx context.Context, o metric.Observer) {
+	disks, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return
+	}
+	for name, d := range disks {
+		read := metric.WithAttributes(deviceKey.String(name), directionKey.String("read"))
+		write := metric.WithAttributes(deviceKey.String(name), directionKey.String("write"))
+		o.ObserveInt64(s.diskIO, int64(d.ReadBytes), read)
+		o.ObserveInt64(s.diskIO, int64(d.WriteBytes), write)
+		o.ObserveInt64(s.diskOperations, int64(d.ReadCount), read)
+		o.ObserveInt64(s.diskOperations, int64(d.WriteCount), write)
+		// The times are in milliseconds.
+		o.ObserveFloat64(s.diskTime, float64(d.ReadTime)/1000, read)
+		o.ObserveFloat64(s.diskTime, float64(d.WriteTime)/1000, write)
+	}
+}
+
+func (s *system) observeNetwork(ctx context.Context, o metric.Observer) {
+	interfaces, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return
+	}
+	for _, n := range interfaces {
+		transmit := metric.WithAttributes(deviceKey.String(n.Name), directionKey.String("transmit"))
+		receive := metric.WithAttributes(deviceKey.String(n.Name), directionKey.String("receive"))
+		o.ObserveInt64(s.networkIO, int64(n.BytesSent), transmit)
+		o.ObserveInt64(s.networkIO, int64(n.BytesRecv), receive)
+		o.ObserveInt64(s.networkPackets, int64(n.PacketsSent), transmit)
+		o.ObserveInt64(s.networkPackets, int64(n.PacketsRecv), receive)
+		o.ObserveInt64(s.networkErrors, int64(n.Errout), transmit)
+		o.ObserveInt64(s.networkErrors, int64(n.Errin), receive)
+		o.ObserveInt64(s.networkDropped, int64(n.Dropout), transmit)
+		o.ObserveInt64(s.networkDropped, int64(n.Dropin), receive)
+	}
+}
@@ -0,0 +1,102 @@
+package logfiresystem
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// collect instruments a meter provider and returns the metrics of its collection by name.
+func collect(t *testing.T, opts ...Option) map[string]metricdata.Metrics {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	if err := Instrument(append([]Option{WithMeterProvider(provider)}, opts...)...); err != nil {
+		t.Fatal(err)
+	}
+	// The CPU times only change every clock tick.
+	time.Sleep(50 * time.Millisecond)
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Metrics{}
+	for _, sm := range rm.ScopeMetrics {
+		if sm.Scope.Name != instrumentationName {
+			t.Errorf("unexpected scope %q", sm.Scope.Name)
+		}
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+	return metrics
+}
+
+func TestBasicMetrics(t *testing.T) {
+	metrics := collect(t)
+
+	memory, ok := metrics["system.memory.utilization"].Data.(metricdata.Gauge[float64])
+	if !ok || len(memory.DataPoints) != 1 {
+		t.Fatalf("unexpected memory utilization %+v", metrics["system.memory.utilization"])
+	}
+	point := memory.DataPoints[0]
+	if state, _ := point.Attributes.Value("state"); state != attribute.StringValue("available") {
+		t.Errorf("unexpected state %v", state)
+	}
+	if point.Value <= 0 || point.Value > 1 {
+		t.Errorf("unexpected available memory fraction %v", point.Value)
+	}
+	if _, ok := metrics["system.cpu.simple_utilization"]; !ok {
+		t.Error("missing system.cpu.simple_utilization")
+	}
+	if _, ok := metrics["system.cpu.time"]; ok {
+		t.Error("unexpected full metric system.cpu.time")
+	}
+}
+
+func TestFullMetrics(t *testing.T) {
+	metrics := collect(t, WithFullMetrics())
+
+	for _, name := range []string{"system.cpu.simple_utilization", "system.cpu.time", "system.cpu.utilization",
+		"system.memory.usage", "system.memory.utilization", "system.swap.usage", "system.network.io"} {
+		if _, ok := metrics[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+	cpuTime := metrics["system.cpu.time"].Data.(metricdata.Sum[float64])
+	if !cpuTime.IsMonotonic || len(cpuTime.DataPoints)%len(cpuStates) != 0 {
+		t.Errorf("unexpected CPU time %+v", cpuTime)
+	}
+	for _, point := range cpuTime.DataPoints {
+		if _, ok := point.Attributes.Value("cpu"); !ok {
+			t.Errorf("CPU time without cpu attribute: %v", point.Attributes)
+		}
+	}
+}
+
+func TestProcessMetrics(t *testing.T) {
+	metrics := collect(t, WithProcessMetrics())
+
+	for _, name := range []string{"process.memory.usage", "process.open_file_descriptor.count",
+		"process.thread.count", "process.cpu.time", "process.uptime"} {
+		if _, ok := metrics[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+	rss := metrics["process.memory.usage"].Data.(metricdata.Sum[int64])
+	if len(rss.DataPoints) != 1 || rss.DataPoints[0].Value <= 0 {
+		t.Errorf("unexpected resident memory %+v", rss)
+	}
+	cpuTime := metrics["process.cpu.time"].Data.(metricdata.Sum[float64])
+	if len(cpuTime.DataPoints) != 2 {
+		t.Errorf("expected user and system CPU time, got %+v", cpuTime)
+	}
+	uptime := metrics["process.uptime"].Data.(metricdata.Gauge[float64])
+	if len(uptime.DataPoints) != 1 || uptime.DataPoints[0].Value <= 0 {
+		t.Errorf("unexpected uptime %+v", uptime)
+	}
+}
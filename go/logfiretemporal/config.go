@@ -0,0 +1,79 @@
+// Package logfiretemporal instruments Temporal clients and workers for Pydantic Logfire.
+//
+// [NewInterceptor] returns an interceptor creating spans for starting, signaling and querying workflows,
+// for running workflows and activities, and for starting activities and child workflows from workflows,
+// named like `RunWorkflow:OrderWorkflow`. The trace context is propagated through the Temporal headers,
+// so a workflow and everything it starts are part of the trace that started it:
+//
+//	c, err := client.Dial(client.Options{
+//		Interceptors: []interceptor.ClientInterceptor{logfiretemporal.NewInterceptor()},
+//	})
+//
+// Workers created from the client use the interceptor too.
+package logfiretemporal
+
+import (
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiretemporal"
+
+// Option configures [NewInterceptor] and [NewTracer].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to extract and inject the trace context
+// in the Temporal headers. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithoutSignalSpans disables the spans of sending and handling signals.
+func WithoutSignalSpans() Option {
+	return func(c *config) {
+		c.noSignals = true
+	}
+}
+
+// WithoutQuerySpans disables the spans of sending and handling queries, which are often
+// numerous when workflows are polled for their state.
+func WithoutQuerySpans() Option {
+	return func(c *config) {
+		c.noQueries = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	noSignals      bool
+	noQueries      bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
@@ -0,0 +1,151 @@
+package logfiretemporal
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// headerKey is the Temporal header holding the trace context, which is the one used by the
+// OpenTelemetry interceptors of the other Temporal SDKs, so that traces continue across languages.
+const headerKey = "_tracer-data"
+
+// NewInterceptor returns an interceptor for Temporal clients and workers, tracing with [NewTracer].
+func NewInterceptor(opts ...Option) interceptor.Interceptor {
+	return interceptor.NewTracingInterceptor(NewTracer(opts...))
+}
+
+// NewTracer returns the tracer used by [NewInterceptor], for use with [interceptor.NewTracingInterceptor].
+//
+// The tags of the spans, such as `temporalWorkflowID`, are recorded as attributes with the same names
+// as the other Temporal SDKs. The operations of a workflow, such as starting an activity, aren't traced
+// again when the workflow is replayed.
+func NewTracer(opts ...Option) interceptor.Tracer {
+	return &tracer{config: newConfig(opts)}
+}
+
+type tracer struct {
+	interceptor.BaseTracer
+	config *config
+}
+
+// spanContextKey is the key of the span in workflow contexts.
+type spanContextKey struct{}
+
+func (t *tracer) Options() interceptor.TracerOptions {
+	return interceptor.TracerOptions{
+		SpanContextKey:       spanContextKey{},
+		HeaderKey:            headerKey,
+		DisableSignalTracing: t.config.noSignals,
+		DisableQueryTracing:  t.config.noQueries,
+	}
+}
+
+func (t *tracer) UnmarshalSpan(m map[string]string) (interceptor.TracerSpanRef, error) {
+	ctx := t.config.propagator().Extract(context.Background(), propagation.MapCarrier(m))
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil, errors.New("no trace context in the Temporal header")
+	}
+	return spanRef{sc}, nil
+}
+
+func (t *tracer) MarshalSpan(span interceptor.TracerSpan) (map[string]string, error) {
+	m := map[string]string{}
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext(span))
+	t.config.propagator().Inject(ctx, propagation.MapCarrier(m))
+	return m, nil
+}
+
+func (t *tracer) SpanFromContext(ctx context.Context) interceptor.TracerSpan {
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return nil
+	}
+	return &tracerSpan{Span: span}
+}
+
+func (t *tracer) ContextWithSpan(ctx context.Context, span interceptor.TracerSpan) context.Context {
+	if s, ok := span.(*tracerSpan); ok {
+		return trace.ContextWithSpan(ctx, s.Span)
+	}
+	return trace.ContextWithSpanContext(ctx, spanContext(span))
+}
+
+func (t *tracer) StartSpan(opts *interceptor.TracerStartSpanOptions) (interceptor.TracerSpan, error) {
+	ctx := context.Background()
+	switch parent := opts.Parent.(type) {
+	case *tracerSpan:
+		ctx = trace.ContextWithSpan(ctx, parent.Span)
+	case spanRef:
+		ctx = trace.ContextWithRemoteSpanContext(ctx, parent.SpanContext)
+	}
+	attrs := make([]attribute.KeyValue, 0, len(opts.Tags))
+	for key, value := range opts.Tags {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	startOpts := []trace.SpanStartOption{
+		trace.WithSpanKind(spanKind(opts.Operation)),
+		trace.WithAttributes(attrs...),
+	}
+	if !opts.Time.IsZero() {
+		startOpts = append(startOpts, trace.WithTimestamp(opts.Time))
+	}
+	_, span := t.config.tracer().Start(ctx, t.SpanName(opts), startOpts...)
+	return &tracerSpan{Span: span}, nil
+}
+
+// spanKind returns server for the operations run by workers, and client for those calling them.
+func spanKind(operation string) trace.SpanKind {
+	for _, prefix := range []string{"Run", "Handle", "Validate"} {
+		if strings.HasPrefix(operation, prefix) {
+			return trace.SpanKindServer
+		}
+	}
+	return trace.SpanKindClient
+}
+
+// spanRef is the span context extracted from a Temporal header.
+type spanRef struct {
+	trace.SpanContext
+}
+
+type tracerSpan struct {
+	trace.Span
+}
+
+func (s *tracerSpan) Finish(opts *interceptor.TracerFinishSpanOptions) {
+	if err := opts.Error; err != nil && !benign(err) {
+		s.RecordError(err)
+		s.SetStatus(codes.Error, err.Error())
+	}
+	s.End()
+}
+
+// benign is whether an error is part of the normal operation of a workflow: continuing as new,
+// or an application error marked as benign.
+func benign(err error) bool {
+	if workflow.IsContinueAsNewError(err) {
+		return true
+	}
+	var appErr *temporal.ApplicationError
+	return errors.As(err, &appErr) && appErr.Category() == temporal.ApplicationErrorCategoryBenign
+}
+
+func spanContext(ref interceptor.TracerSpanRef) trace.SpanContext {
+	switch ref := ref.(type) {
+	case *tracerSpan:
+		return ref.SpanContext()
+	case spanRef:
+		return ref.SpanContext
+	}
+	return trace.SpanContext{}
+}
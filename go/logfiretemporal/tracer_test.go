@@ -0,0 +1,192 @@
+package logfiretemporal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+)
+
+func ChargeActivity(_ context.Context, amount int) (int, error) {
+	if amount < 0 {
+		return 0, errors.New("negative amount")
+	}
+	return amount, nil
+}
+
+func ShipWorkflow(ctx workflow.Context) (string, error) {
+	return "shipped", nil
+}
+
+func OrderWorkflow(ctx workflow.Context, amount int) (string, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+	})
+	if err := workflow.ExecuteActivity(ctx, ChargeActivity, amount).Get(ctx, nil); err != nil {
+		return "", err
+	}
+	var shipped string
+	err := workflow.ExecuteChildWorkflow(ctx, ShipWorkflow).Get(ctx, &shipped)
+	return shipped, err
+}
+
+func newTestEnv(t *testing.T) (*testsuite.TestWorkflowEnvironment, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	var suite testsuite.WorkflowTestSuite
+	suite.SetLogger(log.NewStructuredLogger(slog.New(slog.DiscardHandler)))
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{
+			NewInterceptor(WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})),
+		},
+	})
+	env.RegisterWorkflow(OrderWorkflow)
+	env.RegisterWorkflow(ShipWorkflow)
+	env.RegisterActivity(ChargeActivity)
+	return env, recorder
+}
+
+func spansByName(spans []sdktrace.ReadOnlySpan) map[string]sdktrace.ReadOnlySpan {
+	m := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		m[span.Name()] = span
+	}
+	return m
+}
+
+func TestWorkflow(t *testing.T) {
+	env, recorder := newTestEnv(t)
+	env.ExecuteWorkflow(OrderWorkflow, 42)
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := spansByName(recorder.Ended())
+	run, ok := spans["RunWorkflow:OrderWorkflow"]
+	if !ok {
+		t.Fatalf("no workflow span in %v", spans)
+	}
+	if run.SpanKind() != trace.SpanKindServer {
+		t.Errorf("kind = %v, want server", run.SpanKind())
+	}
+	if attrs := oteltest.Attributes(run.Attributes()); attrs["temporalWorkflowID"] == nil || attrs["temporalRunID"] == nil {
+		t.Errorf("attributes = %v, want the workflow and run IDs", attrs)
+	}
+
+	// Each span is the child of the previous one, in the trace of the workflow.
+	parent := run
+	for _, name := range []string{"StartActivity:ChargeActivity", "RunActivity:ChargeActivity"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("no %s span in %v", name, spans)
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s parent = %v, want %s", name, span.Parent().SpanID(), parent.Name())
+		}
+		parent = span
+	}
+	parent = run
+	for _, name := range []string{"StartChildWorkflow:ShipWorkflow", "RunWorkflow:ShipWorkflow"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("no %s span in %v", name, spans)
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s parent = %v, want %s", name, span.Parent().SpanID(), parent.Name())
+		}
+		parent = span
+	}
+	if kind := spans["StartActivity:ChargeActivity"].SpanKind(); kind != trace.SpanKindClient {
+		t.Errorf("kind = %v, want client", kind)
+	}
+	if id := oteltest.Attributes(spans["RunActivity:ChargeActivity"].Attributes())["temporalActivityID"]; id == nil {
+		t.Error("no activity ID")
+	}
+}
+
+func TestActivityError(t *testing.T) {
+	env, recorder := newTestEnv(t)
+	env.ExecuteWorkflow(OrderWorkflow, -1)
+	if env.GetWorkflowError() == nil {
+		t.Fatal("workflow succeeded")
+	}
+
+	spans := spansByName(recorder.Ended())
+	for _, name := range []string{"RunActivity:ChargeActivity", "RunWorkflow:OrderWorkflow"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("no %s span in %v", name, spans)
+		}
+		if span.Status().Code != codes.Error {
+			t.Errorf("%s status = %v, want error", name, span.Status())
+		}
+	}
+	if _, ok := spans["StartChildWorkflow:ShipWorkflow"]; ok {
+		t.Error("child workflow started")
+	}
+}
+
+func TestBenignError(t *testing.T) {
+	if !benign(temporal.NewApplicationErrorWithOptions("done", "", temporal.ApplicationErrorOptions{
+		Category: temporal.ApplicationErrorCategoryBenign,
+	})) {
+		t.Error("benign application error recorded")
+	}
+	if benign(temporal.NewApplicationError("failed", "")) {
+		t.Error("application error not recorded")
+	}
+}
+
+func TestMarshalSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := NewTracer(WithTracerProvider(provider), WithPropagators(propagation.TraceContext{}))
+
+	span, err := tracer.StartSpan(&interceptor.TracerStartSpanOptions{Operation: "StartWorkflow", Name: "OrderWorkflow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	span.Finish(&interceptor.TracerFinishSpanOptions{})
+	m, err := tracer.MarshalSpan(span)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["traceparent"] == "" {
+		t.Fatalf("header = %v, want a traceparent", m)
+	}
+	ref, err := tracer.UnmarshalSpan(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := tracer.StartSpan(&interceptor.TracerStartSpanOptions{Parent: ref, Operation: "RunWorkflow", Name: "OrderWorkflow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child.Finish(&interceptor.TracerFinishSpanOptions{})
+
+	spans := recorder.Ended()
+	if spans[1].Parent().SpanID() != spans[0].SpanContext().SpanID() || !spans[1].Parent().IsRemote() {
+		t.Errorf("parent = %v, want the remote span %v", spans[1].Parent(), spans[0].SpanContext())
+	}
+	if _, err := tracer.UnmarshalSpan(map[string]string{}); err == nil {
+		t.Error("empty header unmarshaled")
+	}
+}
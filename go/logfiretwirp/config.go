@@ -0,0 +1,80 @@
+// Package logfiretwirp instruments Twirp servers and clients for Pydantic Logfire.
+//
+// [NewServerHooks] and [NewClientHooks] create a span for each RPC, named after the method
+// like `twitch.twirp.example.Haberdasher/MakeHat`, with the rpc.* semantic convention attributes.
+// The Twirp error code sets the status and Logfire level of the span:
+//
+//	server := example.NewHaberdasherServer(&haberdasher{}, twirp.WithServerHooks(logfiretwirp.NewServerHooks()))
+//	http.ListenAndServe(":8080", logfiretwirp.NewHandler(server))
+//
+//	client := example.NewHaberdasherProtobufClient(url, http.DefaultClient,
+//		twirp.WithClientHooks(logfiretwirp.NewClientHooks()))
+package logfiretwirp
+
+import (
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the spans created by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfiretwirp"
+
+// Option configures the hooks and [NewHandler].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to extract and inject the trace context
+// in the request headers. Defaults to the global propagators.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithFilter adds a filter for RPCs by service, e.g. "twitch.twirp.example.Haberdasher", and method:
+// an RPC is only traced if all filters return true. The method is empty for server requests
+// which couldn't be routed.
+func WithFilter(filter func(service, method string) bool) Option {
+	return func(c *config) {
+		c.filters = append(c.filters, filter)
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	filters        []func(service, method string) bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) traced(service, method string) bool {
+	for _, filter := range c.filters {
+		if !filter(service, method) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
+
+func (c *config) propagator() propagation.TextMapPropagator {
+	return instrumentation.Propagator(c.propagators)
+}
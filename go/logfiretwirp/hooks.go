@@ -0,0 +1,187 @@
+package logfiretwirp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/twitchtv/twirp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/httpconv"
+	"github.com/pydantic/logfire/go/internal/rpcconv"
+)
+
+// rpcSystem is the `rpc.system.name` of Twirp, which the semantic conventions don't define.
+var rpcSystem = semconv.RPCSystemNameKey.String("twirp")
+
+// errorCodes maps the Twirp error codes to the gRPC codes they are modeled after,
+// which set the level of the spans.
+var errorCodes = map[twirp.ErrorCode]rpcconv.Code{
+	twirp.Canceled:           1,
+	twirp.Unknown:            2,
+	twirp.InvalidArgument:    3,
+	twirp.Malformed:          3,
+	twirp.DeadlineExceeded:   4,
+	twirp.NotFound:           5,
+	twirp.BadRoute:           5,
+	twirp.AlreadyExists:      6,
+	twirp.PermissionDenied:   7,
+	twirp.ResourceExhausted:  8,
+	twirp.FailedPrecondition: 9,
+	twirp.Aborted:            10,
+	twirp.OutOfRange:         11,
+	twirp.Unimplemented:      12,
+	twirp.Internal:           13,
+	twirp.Unavailable:        14,
+	twirp.DataLoss:           15,
+	twirp.Unauthenticated:    16,
+}
+
+// call is the state of a traced RPC, stored in the context passed between hooks.
+type call struct {
+	span   trace.Span
+	failed bool
+}
+
+type callKey struct{}
+
+func callFromContext(ctx context.Context) *call {
+	c, _ := ctx.Value(callKey{}).(*call)
+	return c
+}
+
+// NewServerHooks returns server hooks creating a server span for each RPC.
+//
+// Hooks don't have access to the request headers: wrap the server with [NewHandler]
+// so that the spans continue the traces of the clients.
+func NewServerHooks(opts ...Option) *twirp.ServerHooks {
+	cfg := newConfig(opts)
+	return &twirp.ServerHooks{
+		RequestRouted: func(ctx context.Context) (context.Context, error) {
+			return cfg.start(ctx, trace.SpanKindServer, nil), nil
+		},
+		Error: func(ctx context.Context, err twirp.Error) context.Context {
+			c := callFromContext(ctx)
+			if c == nil {
+				// The request couldn't be routed.
+				ctx = cfg.start(ctx, trace.SpanKindServer, nil)
+				if c = callFromContext(ctx); c == nil {
+					return ctx
+				}
+			}
+			c.setError(trace.SpanKindServer, err)
+			return ctx
+		},
+		ResponseSent: func(ctx context.Context) {
+			c := callFromContext(ctx)
+			if c == nil {
+				return
+			}
+			if status, ok := twirp.StatusCode(ctx); ok {
+				if code, err := strconv.Atoi(status); err == nil {
+					c.span.SetAttributes(semconv.HTTPResponseStatusCode(code))
+				}
+			}
+			c.end(trace.SpanKindServer)
+		},
+	}
+}
+
+// NewClientHooks returns client hooks creating a client span for each RPC,
+// and injecting the trace context in the request headers.
+func NewClientHooks(opts ...Option) *twirp.ClientHooks {
+	cfg := newConfig(opts)
+	return &twirp.ClientHooks{
+		RequestPrepared: func(ctx context.Context, r *http.Request) (context.Context, error) {
+			ctx = cfg.start(ctx, trace.SpanKindClient, r)
+			if callFromContext(ctx) != nil {
+				cfg.propagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+				// The request is sent after this hook returns, so that the spans of an instrumented
+				// HTTP client become children of the RPC span.
+				*r = *r.WithContext(ctx)
+			}
+			return ctx, nil
+		},
+		ResponseReceived: func(ctx context.Context) {
+			if c := callFromContext(ctx); c != nil {
+				c.end(trace.SpanKindClient)
+			}
+		},
+		Error: func(ctx context.Context, err twirp.Error) {
+			if c := callFromContext(ctx); c != nil {
+				c.setError(trace.SpanKindClient, err)
+				c.end(trace.SpanKindClient)
+			}
+		},
+	}
+}
+
+// NewHandler wraps a Twirp server so that the trace context of incoming requests is extracted,
+// and the spans of [NewServerHooks] continue the traces of the clients.
+func NewHandler(h http.Handler, opts ...Option) http.Handler {
+	cfg := newConfig(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := cfg.propagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// start creates the span of the RPC described by the context, unless it's filtered out.
+// r is the request of client RPCs.
+func (c *config) start(ctx context.Context, kind trace.SpanKind, r *http.Request) context.Context {
+	pkg, _ := twirp.PackageName(ctx)
+	service, _ := twirp.ServiceName(ctx)
+	method, _ := twirp.MethodName(ctx)
+	if pkg != "" {
+		service = pkg + "." + service
+	}
+	if !c.traced(service, method) {
+		return ctx
+	}
+
+	procedure := service
+	if method != "" {
+		procedure += "/" + method
+	}
+	attrs := rpcconv.Attributes(rpcSystem, procedure)
+	if r != nil {
+		attrs = append(attrs, serverAddress(r)...)
+	}
+	ctx, span := c.tracer().Start(ctx, rpcconv.SpanName(procedure),
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(attrs...),
+	)
+	return context.WithValue(ctx, callKey{}, &call{span: span})
+}
+
+// serverAddress returns the `server.address` and `server.port` attributes of a client request.
+func serverAddress(r *http.Request) []attribute.KeyValue {
+	host, port := httpconv.SplitHostPort(r.URL.Host)
+	attrs := []attribute.KeyValue{semconv.ServerAddress(host)}
+	if port != 0 {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+	return attrs
+}
+
+// setError records the Twirp error code of the RPC, see [rpcconv.SetStatus].
+func (c *call) setError(kind trace.SpanKind, err twirp.Error) {
+	c.failed = true
+	code, ok := errorCodes[err.Code()]
+	if !ok {
+		code = errorCodes[twirp.Unknown]
+	}
+	rpcconv.SetStatus(c.span, kind, code, string(err.Code()), err, err.Msg())
+}
+
+func (c *call) end(kind trace.SpanKind) {
+	if !c.failed {
+		// Twirp has no error code for successful RPCs.
+		rpcconv.SetStatus(c.span, kind, rpcconv.CodeOK, "", nil, "")
+	}
+	c.span.End()
+}
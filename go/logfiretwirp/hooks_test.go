@@ -0,0 +1,208 @@
+package logfiretwirp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/example"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+type haberdasher struct{}
+
+func (haberdasher) MakeHat(ctx context.Context, size *example.Size) (*example.Hat, error) {
+	switch {
+	case size.Inches <= 0:
+		return nil, twirp.InvalidArgumentError("inches", "must be positive")
+	case size.Inches > 100:
+		return nil, twirp.InternalError("out of felt")
+	case size.Inches == 42:
+		panic("boom")
+	}
+	return &example.Hat{Size: size.Inches, Color: "blue", Name: "bowler"}, nil
+}
+
+// newTestClient starts an instrumented Haberdasher server and returns an instrumented client.
+func newTestClient(t *testing.T, opts ...Option) (example.Haberdasher, *httptest.Server, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	opts = append([]Option{WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})}, opts...)
+
+	server := example.NewHaberdasherServer(haberdasher{}, twirp.WithServerHooks(NewServerHooks(opts...)))
+	srv := httptest.NewServer(NewHandler(server, opts...))
+	t.Cleanup(srv.Close)
+	client := example.NewHaberdasherProtobufClient(srv.URL, srv.Client(), twirp.WithClientHooks(NewClientHooks(opts...)))
+	return client, srv, recorder
+}
+
+// spansByKind returns the server and client spans.
+func spansByKind(t *testing.T, recorder *tracetest.SpanRecorder) (server, client sdktrace.ReadOnlySpan) {
+	t.Helper()
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for _, span := range spans {
+		switch span.SpanKind() {
+		case trace.SpanKindServer:
+			server = span
+		case trace.SpanKindClient:
+			client = span
+		}
+	}
+	if server == nil || client == nil {
+		t.Fatal("expected a server and a client span")
+	}
+	return server, client
+}
+
+func TestHooks(t *testing.T) {
+	client, _, recorder := newTestClient(t)
+
+	if _, err := client.MakeHat(t.Context(), &example.Size{Inches: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	server, clientSpan := spansByKind(t, recorder)
+	if server.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Error("expected the server span to be a child of the client span")
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{server, clientSpan} {
+		if span.Name() != "twitch.twirp.example.Haberdasher/MakeHat" {
+			t.Errorf("unexpected span name %q", span.Name())
+		}
+		attrs := oteltest.Attributes(span.Attributes())
+		for key, want := range map[attribute.Key]any{
+			"rpc.system.name":   "twirp",
+			"rpc.method":        "twitch.twirp.example.Haberdasher/MakeHat",
+			logfire.LevelNumKey: int64(logfire.LevelInfo),
+		} {
+			if attrs[key] != want {
+				t.Errorf("%v span attribute %s = %v, want %v", span.SpanKind(), key, attrs[key], want)
+			}
+		}
+		if span.Status().Code != codes.Unset {
+			t.Errorf("unexpected status %v", span.Status())
+		}
+	}
+	if got := oteltest.Attributes(server.Attributes())["http.response.status_code"]; got != int64(http.StatusOK) {
+		t.Errorf("http.response.status_code = %v", got)
+	}
+	if got := oteltest.Attributes(clientSpan.Attributes())["server.address"]; got != "127.0.0.1" {
+		t.Errorf("server.address = %v", got)
+	}
+}
+
+func TestHooksClientError(t *testing.T) {
+	client, _, recorder := newTestClient(t)
+
+	if _, err := client.MakeHat(t.Context(), &example.Size{Inches: -1}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	server, clientSpan := spansByKind(t, recorder)
+	for _, span := range []sdktrace.ReadOnlySpan{server, clientSpan} {
+		attrs := oteltest.Attributes(span.Attributes())
+		if attrs["rpc.response.status_code"] != "invalid_argument" || attrs[logfire.LevelNumKey] != int64(logfire.LevelWarn) {
+			t.Errorf("unexpected %v span attributes %v", span.SpanKind(), attrs)
+		}
+	}
+	if server.Status().Code != codes.Unset {
+		t.Errorf("expected no error status for the server span, got %v", server.Status())
+	}
+	if oteltest.Attributes(server.Attributes())["http.response.status_code"] != int64(http.StatusBadRequest) {
+		t.Errorf("unexpected server span attributes %v", oteltest.Attributes(server.Attributes()))
+	}
+	if clientSpan.Status().Code != codes.Error || clientSpan.Status().Description != "inches must be positive" {
+		t.Errorf("unexpected client status %v", clientSpan.Status())
+	}
+}
+
+func TestHooksServerError(t *testing.T) {
+	client, _, recorder := newTestClient(t)
+
+	if _, err := client.MakeHat(t.Context(), &example.Size{Inches: 200}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	server, _ := spansByKind(t, recorder)
+	if server.Status().Code != codes.Error || server.Status().Description != "out of felt" {
+		t.Errorf("unexpected status %v", server.Status())
+	}
+	if got := oteltest.Attributes(server.Attributes())[logfire.LevelNumKey]; got != int64(logfire.LevelError) {
+		t.Errorf("level = %v", got)
+	}
+	if len(server.Events()) != 1 || server.Events()[0].Name != "exception" {
+		t.Errorf("expected an exception event, got %v", server.Events())
+	}
+}
+
+func TestHooksPanic(t *testing.T) {
+	client, _, recorder := newTestClient(t)
+
+	// net/http recovers the panic and closes the connection.
+	_, _ = client.MakeHat(t.Context(), &example.Size{Inches: 42})
+
+	var server sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanKind() == trace.SpanKindServer {
+			server = span
+		}
+	}
+	if server == nil {
+		t.Fatal("expected a server span")
+	}
+	if server.Status().Code != codes.Error || oteltest.Attributes(server.Attributes())["rpc.response.status_code"] != "internal" {
+		t.Errorf("unexpected span status %v and attributes %v", server.Status(), oteltest.Attributes(server.Attributes()))
+	}
+}
+
+func TestHooksBadRoute(t *testing.T) {
+	_, srv, recorder := newTestClient(t)
+
+	resp, err := srv.Client().Post(srv.URL+example.HaberdasherPathPrefix+"MakeScarf", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "twitch.twirp.example.Haberdasher" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	attrs := oteltest.Attributes(span.Attributes())
+	if attrs["rpc.response.status_code"] != "bad_route" || attrs[logfire.LevelNumKey] != int64(logfire.LevelWarn) {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
+
+func TestHooksFilter(t *testing.T) {
+	client, _, recorder := newTestClient(t, WithFilter(func(service, method string) bool {
+		return method != "MakeHat"
+	}))
+
+	if _, err := client.MakeHat(t.Context(), &example.Size{Inches: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
@@ -0,0 +1,75 @@
+// Package logfirezap sends the entries of zap loggers to Pydantic Logfire.
+//
+// [Core] returns a [zapcore.Core] emitting each entry as a Logfire log with its level, fields,
+// caller and stack trace. It is usually combined with the existing core of a logger:
+//
+//	logger := zap.New(zapcore.NewTee(existingCore, logfirezap.Core()), zap.AddCaller())
+//
+// zap has no context, so the [Context] field sets the span the log is a child of:
+//
+//	logger.Info("order placed", logfirezap.Context(ctx), zap.Int("order_id", id))
+package logfirezap
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the logs emitted by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirezap"
+
+// Option configures [Core].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to emit logs. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all logs.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithLevel sets the levels of the entries to emit, e.g. [zapcore.DebugLevel] or a [zap.AtomicLevel].
+// Defaults to [zapcore.InfoLevel] and above.
+func WithLevel(level zapcore.LevelEnabler) Option {
+	return func(c *config) {
+		c.level = level
+	}
+}
+
+// WithSpanEvents adds logs as events to the recording span of their context rather than emitting
+// separate logs, to keep them with the span and reduce the number of records. Logs without a span are
+// still emitted.
+func WithSpanEvents() Option {
+	return func(c *config) {
+		c.spanEvents = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	level          zapcore.LevelEnabler
+	spanEvents     bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{level: zapcore.InfoLevel}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
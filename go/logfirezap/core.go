@@ -0,0 +1,120 @@
+package logfirezap
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/pydantic/logfire/go/internal/logconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// loggerNameKey is the name of the logger of an entry, set with [zap.Logger.Named].
+const loggerNameKey = attribute.Key("logger.name")
+
+// contextKey is the key of the fields made by [Context].
+const contextKey = "logfire.context"
+
+// Context returns a field setting the context of a log, whose span is the parent of the log.
+// Other cores skip the field.
+func Context(ctx context.Context) zap.Field {
+	return zap.Field{Key: contextKey, Type: zapcore.SkipType, Interface: ctx}
+}
+
+type core struct {
+	zapcore.LevelEnabler
+	config *config
+	tracer trace.Tracer
+	// fields are the fields added by [zapcore.Core.With].
+	fields []zapcore.Field
+}
+
+// Core returns a core emitting entries as Logfire logs.
+//
+// Sampling applies to the core as to any other, e.g. when wrapped with [zapcore.NewSamplerWithOptions]
+// or built by [zap.Config] with sampling enabled.
+func Core(opts ...Option) zapcore.Core {
+	c := newConfig(opts)
+	return &core{LevelEnabler: c.level, config: c, tracer: c.tracer()}
+}
+
+func (c *core) Level() zapcore.Level {
+	return zapcore.LevelOf(c.LevelEnabler)
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	c2 := *c
+	c2.fields = append(slices.Clip(c.fields), fields...)
+	return &c2
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ctx := context.Background()
+	rec := logconv.Record{
+		Time:    ent.Time,
+		Level:   Level(ent.Level),
+		Message: ent.Message,
+		Stack:   ent.Stack,
+		Attrs:   slices.Clip(c.config.attrs),
+	}
+	if ent.LoggerName != "" {
+		rec.Attrs = append(rec.Attrs, loggerNameKey.String(ent.LoggerName))
+	}
+	if ent.Caller.Defined {
+		rec.File, rec.Line, rec.Function = ent.Caller.File, ent.Caller.Line, ent.Caller.Function
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range slices.Concat(c.fields, fields) {
+		switch {
+		case f.Type == zapcore.SkipType && f.Key == contextKey:
+			if fctx, ok := f.Interface.(context.Context); ok && fctx != nil {
+				ctx = fctx
+			}
+			continue
+		case f.Type == zapcore.ErrorType && rec.Err == nil:
+			rec.Err, _ = f.Interface.(error)
+		}
+		f.AddTo(enc)
+	}
+	for _, k := range slices.Sorted(maps.Keys(enc.Fields)) {
+		rec.Attrs = append(rec.Attrs, logconv.Value(k, enc.Fields[k]))
+	}
+	logconv.Emit(ctx, c.tracer, rec, c.config.spanEvents)
+	return nil
+}
+
+func (c *core) Sync() error {
+	return nil
+}
+
+// Level converts a zap level to a Logfire level. [zapcore.DPanicLevel] is an error,
+// and [zapcore.PanicLevel] and [zapcore.FatalLevel] are fatal.
+func Level(level zapcore.Level) logfire.Level {
+	switch {
+	case level < zapcore.DebugLevel:
+		return logfire.LevelTrace
+	case level == zapcore.DebugLevel:
+		return logfire.LevelDebug
+	case level == zapcore.InfoLevel:
+		return logfire.LevelInfo
+	case level == zapcore.WarnLevel:
+		return logfire.LevelWarn
+	case level <= zapcore.DPanicLevel:
+		return logfire.LevelError
+	default:
+		return logfire.LevelFatal
+	}
+}
@@ -0,0 +1,146 @@
+package logfirezap
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newCore(opts ...Option) (zapcore.Core, *tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return Core(append(opts, WithTracerProvider(provider))...), recorder, provider
+}
+
+func TestCore(t *testing.T) {
+	core, recorder, provider := newCore(WithAttributes(attribute.String("service", "orders")))
+	observed, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(zapcore.NewTee(observed, core), zap.AddCaller()).Named("orders").With(zap.Int("user_id", 42))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	logger.Warn("slow request",
+		Context(ctx),
+		zap.String("method", "GET"),
+		zap.Duration("duration", 1500*time.Millisecond),
+		zap.Any("payload", map[string]int{"items": 2}),
+	)
+	parent.End()
+
+	if entries := logs.All(); len(entries) != 1 || len(entries[0].Context) != 5 {
+		t.Errorf("entries = %v, want the entry logged by the other core", entries)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "slow request" || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("span = %s, parent %v", span.Name(), span.Parent())
+	}
+	if span.StartTime() != span.EndTime() {
+		t.Errorf("duration = %v, want 0", span.EndTime().Sub(span.StartTime()))
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.SpanTypeKey: "log",
+		logfire.LevelNumKey: int64(logfire.LevelWarn),
+		logfire.MsgKey:      "slow request",
+		"service":           "orders",
+		"logger.name":       "orders",
+		"user_id":           int64(42),
+		"method":            "GET",
+		"duration":          "1.5s",
+		"payload":           `{"items":2}`,
+		"code.function":     "github.com/pydantic/logfire/go/logfirezap.TestCore",
+	})
+	attrs := oteltest.Attributes(span.Attributes())
+	if file, _ := attrs["code.filepath"].(string); !strings.HasSuffix(file, "core_test.go") {
+		t.Errorf("code.filepath = %v", attrs["code.filepath"])
+	}
+	if _, ok := attrs[contextKey]; ok {
+		t.Error("context recorded")
+	}
+}
+
+func TestCoreError(t *testing.T) {
+	core, recorder, _ := newCore()
+	logger := zap.New(core, zap.AddStacktrace(zapcore.ErrorLevel))
+	logger.Error("payment failed", zap.Error(errors.New("card declined")))
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelError),
+		"error":             "card declined",
+	})
+	events := span.Events()
+	if len(events) != 1 || events[0].Name != "exception" {
+		t.Fatalf("events = %v", events)
+	}
+	oteltest.CheckAttributes(t, events[0].Attributes, map[attribute.Key]any{"exception.message": "card declined"})
+	if stack, _ := oteltest.Attributes(events[0].Attributes)["exception.stacktrace"].(string); !strings.Contains(stack, "TestCoreError") {
+		t.Errorf("exception.stacktrace = %q", stack)
+	}
+}
+
+func TestCoreLevel(t *testing.T) {
+	core, recorder, _ := newCore()
+	logger := zap.New(zapcore.NewSamplerWithOptions(core, time.Minute, 2, 0))
+	logger.Debug("debug")
+	for range 3 {
+		logger.Info("sampled")
+	}
+	if spans := recorder.Ended(); len(spans) != 2 {
+		t.Errorf("spans = %d, want the 2 first info entries", len(spans))
+	}
+
+	for level, want := range map[zapcore.Level]logfire.Level{
+		zapcore.DebugLevel:  logfire.LevelDebug,
+		zapcore.InfoLevel:   logfire.LevelInfo,
+		zapcore.WarnLevel:   logfire.LevelWarn,
+		zapcore.ErrorLevel:  logfire.LevelError,
+		zapcore.DPanicLevel: logfire.LevelError,
+		zapcore.PanicLevel:  logfire.LevelFatal,
+		zapcore.FatalLevel:  logfire.LevelFatal,
+	} {
+		if got := Level(level); got != want {
+			t.Errorf("Level(%v) = %v, want %v", level, got, want)
+		}
+	}
+}
+
+func TestCoreSpanEvents(t *testing.T) {
+	core, recorder, provider := newCore(WithSpanEvents())
+	logger := zap.New(core)
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	logger.With(Context(ctx)).Info("handling", zap.String("method", "GET"))
+	span.End()
+	logger.Info("no span")
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "request" || spans[1].Name() != "no span" {
+		t.Fatalf("spans = %v, want the request and the log without span", spans)
+	}
+	events := spans[0].Events()
+	if len(events) != 1 || events[0].Name != "handling" {
+		t.Fatalf("events = %v", events)
+	}
+	oteltest.CheckAttributes(t, events[0].Attributes, map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelInfo),
+		"method":            "GET",
+	})
+}
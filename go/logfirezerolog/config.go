@@ -0,0 +1,80 @@
+// Package logfirezerolog sends the events of zerolog loggers to Pydantic Logfire.
+//
+// [NewWriter] returns a writer parsing the JSON events of zerolog, emitting each as a Logfire log with
+// its level, fields, caller and error. [Hook] adds the trace and span IDs of the context of events to
+// them, so that their logs are children of the span of the context:
+//
+//	logger := zerolog.New(zerolog.MultiLevelWriter(os.Stderr, logfirezerolog.NewWriter())).
+//		Hook(logfirezerolog.Hook{}).
+//		With().Timestamp().Caller().Logger()
+//	logger.Info().Ctx(ctx).Int("order_id", id).Msg("order placed")
+//
+// With [WithSpanEvents], logs are added as events to the spans of their context, which are passed by
+// the hook of the writer:
+//
+//	w := logfirezerolog.NewWriter(logfirezerolog.WithSpanEvents())
+//	logger := zerolog.New(w).Hook(w.Hook())
+package logfirezerolog
+
+import (
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/instrumentation"
+)
+
+// instrumentationName is the instrumentation scope of the logs emitted by this package.
+const instrumentationName = "github.com/pydantic/logfire/go/logfirezerolog"
+
+// Option configures [NewWriter].
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to emit logs. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithAttributes adds attributes to all logs.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// WithLevel sets the minimum level of the events to emit. Defaults to [zerolog.InfoLevel].
+func WithLevel(level zerolog.Level) Option {
+	return func(c *config) {
+		c.level = level
+	}
+}
+
+// WithSpanEvents adds logs as events to the recording span of their context rather than emitting
+// separate logs, to keep them with the span and reduce the number of records. Logs without a span are
+// still emitted. The spans are passed to the writer by the hook returned by [Writer.Hook], rather than [Hook].
+func WithSpanEvents() Option {
+	return func(c *config) {
+		c.spanEvents = true
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	attrs          []attribute.KeyValue
+	level          zerolog.Level
+	spanEvents     bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{level: zerolog.InfoLevel}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return instrumentation.Tracer(c.tracerProvider, instrumentationName)
+}
@@ -0,0 +1,269 @@
+package logfirezerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pydantic/logfire/go/internal/logconv"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+// Fields added by [Hook], following the names of the OpenTelemetry log data model.
+const (
+	traceIDField    = "trace_id"
+	spanIDField     = "span_id"
+	traceFlagsField = "trace_flags"
+)
+
+// Hook adds the trace and span IDs of the span in the context of events, set with [zerolog.Event.Ctx],
+// to them. The [Writer] makes the logs of these events children of the span.
+type Hook struct{}
+
+// Run adds the trace context fields to e.
+func (Hook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	sc := trace.SpanContextFromContext(e.GetCtx())
+	if !sc.IsValid() {
+		return
+	}
+	e.Str(traceIDField, sc.TraceID().String()).
+		Str(spanIDField, sc.SpanID().String()).
+		Str(traceFlagsField, sc.TraceFlags().String())
+}
+
+// Hook returns a hook like [Hook] which also passes the spans of events to w,
+// so that their logs can be added to them as events with [WithSpanEvents].
+func (w *Writer) Hook() zerolog.Hook {
+	return writerHook{w}
+}
+
+type writerHook struct {
+	w *Writer
+}
+
+func (h writerHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	Hook{}.Run(e, level, msg)
+	if span := trace.SpanFromContext(e.GetCtx()); h.w.config.spanEvents && span.IsRecording() {
+		h.w.mu.Lock()
+		defer h.w.mu.Unlock()
+		p := h.w.spans[span.SpanContext().SpanID()]
+		if p == nil {
+			h.w.sweep()
+			p = &pendingSpan{span: span}
+			h.w.spans[span.SpanContext().SpanID()] = p
+		}
+		p.events++
+	}
+}
+
+// minSweep is the number of pending spans from which [Writer.sweep] removes the ended ones.
+const minSweep = 1024
+
+// sweep removes the pending spans which ended, e.g. because their events were filtered out before reaching
+// the writer, so that they don't accumulate. Events can't be added to them anymore, so their logs are only
+// children of them. Sweeps wait for the number of the remaining ones to double, amortizing their cost.
+func (w *Writer) sweep() {
+	if len(w.spans) < w.nextSweep {
+		return
+	}
+	for id, p := range w.spans {
+		if !p.span.IsRecording() {
+			delete(w.spans, id)
+		}
+	}
+	w.nextSweep = max(minSweep, 2*len(w.spans))
+}
+
+// pendingSpan is a span passed by [Writer.Hook], with the number of its events yet to be written.
+type pendingSpan struct {
+	span   trace.Span
+	events int
+}
+
+// Writer is a [zerolog.LevelWriter] emitting the JSON events written to it as Logfire logs.
+type Writer struct {
+	config *config
+	tracer trace.Tracer
+
+	mu        sync.Mutex
+	spans     map[trace.SpanID]*pendingSpan
+	nextSweep int
+}
+
+var _ zerolog.LevelWriter = (*Writer)(nil)
+
+// NewWriter returns a writer emitting events as Logfire logs.
+func NewWriter(opts ...Option) *Writer {
+	c := newConfig(opts)
+	return &Writer{config: c, tracer: c.tracer(), spans: make(map[trace.SpanID]*pendingSpan), nextSweep: minSweep}
+}
+
+// Write emits the events in p, taking their level from the level field.
+// Writes which aren't JSON are emitted as is, with the info level.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel emits the events in p with the given level, or the level of their level field
+// if level is [zerolog.NoLevel].
+func (w *Writer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	for n := 0; ; n++ {
+		var fields map[string]any
+		if err := dec.Decode(&fields); err != nil {
+			if msg := strings.TrimSpace(string(p)); n == 0 && msg != "" && !errors.Is(err, io.EOF) && w.config.level <= zerolog.InfoLevel {
+				logconv.Emit(context.Background(), w.tracer, logconv.Record{Level: logfire.LevelInfo, Message: msg, Attrs: w.config.attrs}, false)
+			}
+			return len(p), nil
+		}
+		w.emit(level, fields)
+	}
+}
+
+func (w *Writer) emit(level zerolog.Level, fields map[string]any) {
+	ctx := w.parent(fields)
+	if level == zerolog.NoLevel {
+		if s, ok := fields[zerolog.LevelFieldName].(string); ok {
+			level, _ = zerolog.ParseLevel(s)
+		}
+	}
+	if level != zerolog.NoLevel && level < w.config.level || level == zerolog.Disabled {
+		return
+	}
+	delete(fields, zerolog.LevelFieldName)
+
+	rec := logconv.Record{
+		Level: Level(level),
+		Time:  eventTime(fields[zerolog.TimestampFieldName]),
+		Attrs: slices.Clip(w.config.attrs),
+	}
+	delete(fields, zerolog.TimestampFieldName)
+	rec.Message, _ = fields[zerolog.MessageFieldName].(string)
+	delete(fields, zerolog.MessageFieldName)
+	if caller, ok := fields[zerolog.CallerFieldName].(string); ok {
+		if i := strings.LastIndexByte(caller, ':'); i > 0 {
+			rec.File = caller[:i]
+			rec.Line, _ = strconv.Atoi(caller[i+1:])
+			delete(fields, zerolog.CallerFieldName)
+		}
+	}
+	if msg, ok := fields[zerolog.ErrorFieldName].(string); ok {
+		rec.Err = errors.New(msg)
+	}
+	if stack, ok := fields[zerolog.ErrorStackFieldName]; ok {
+		if s, ok := stack.(string); ok {
+			rec.Stack = s
+		} else {
+			b, _ := json.Marshal(stack)
+			rec.Stack = string(b)
+		}
+		delete(fields, zerolog.ErrorStackFieldName)
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		rec.Attrs = append(rec.Attrs, value(k, fields[k]))
+	}
+	logconv.Emit(ctx, w.tracer, rec, w.config.spanEvents)
+}
+
+// parent returns a context with the span whose IDs were added by [Hook], removing them from fields.
+// The span is the one passed by [Writer.Hook] if any, so that events can be added to it.
+func (w *Writer) parent(fields map[string]any) context.Context {
+	ctx := context.Background()
+	traceID, _ := fields[traceIDField].(string)
+	spanID, _ := fields[spanIDField].(string)
+	flags, _ := fields[traceFlagsField].(string)
+	tid, err1 := trace.TraceIDFromHex(traceID)
+	sid, err2 := trace.SpanIDFromHex(spanID)
+	if err1 != nil || err2 != nil {
+		return ctx
+	}
+	delete(fields, traceIDField)
+	delete(fields, spanIDField)
+	delete(fields, traceFlagsField)
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: sid})
+	if f, err := strconv.ParseUint(flags, 16, 8); err == nil {
+		sc = sc.WithTraceFlags(trace.TraceFlags(f))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if p := w.spans[sid]; p != nil {
+		if p.events--; p.events == 0 {
+			delete(w.spans, sid)
+		}
+		return trace.ContextWithSpan(ctx, p.span)
+	}
+	return trace.ContextWithSpanContext(ctx, sc)
+}
+
+// eventTime parses the timestamp of an event, formatted with [zerolog.TimeFieldFormat].
+// It returns the zero time, i.e. now, if there's none.
+func eventTime(v any) time.Time {
+	switch v := v.(type) {
+	case string:
+		t, _ := time.Parse(zerolog.TimeFieldFormat, v)
+		return t
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return time.Time{}
+		}
+		switch zerolog.TimeFieldFormat {
+		case zerolog.TimeFormatUnix:
+			return time.Unix(n, 0)
+		case zerolog.TimeFormatUnixMs:
+			return time.UnixMilli(n)
+		case zerolog.TimeFormatUnixMicro:
+			return time.UnixMicro(n)
+		case zerolog.TimeFormatUnixNano:
+			return time.Unix(0, n)
+		}
+	}
+	return time.Time{}
+}
+
+// value converts a JSON field to an attribute, keeping integers as integers.
+func value(key string, v any) attribute.KeyValue {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return attribute.Int64(key, i)
+		}
+		f, _ := n.Float64()
+		return attribute.Float64(key, f)
+	}
+	return logconv.Value(key, v)
+}
+
+// Level converts a zerolog level to a Logfire level. Events without a level are infos.
+func Level(level zerolog.Level) logfire.Level {
+	switch level {
+	case zerolog.TraceLevel:
+		return logfire.LevelTrace
+	case zerolog.DebugLevel:
+		return logfire.LevelDebug
+	case zerolog.WarnLevel:
+		return logfire.LevelWarn
+	case zerolog.ErrorLevel:
+		return logfire.LevelError
+	case zerolog.FatalLevel, zerolog.PanicLevel:
+		return logfire.LevelFatal
+	}
+	if level < zerolog.TraceLevel {
+		return logfire.LevelTrace
+	}
+	return logfire.LevelInfo
+}
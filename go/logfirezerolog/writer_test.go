@@ -0,0 +1,171 @@
+package logfirezerolog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/pydantic/logfire/go/internal/oteltest"
+	"github.com/pydantic/logfire/go/logfire"
+)
+
+func newWriter(opts ...Option) (*Writer, *tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return NewWriter(append(opts, WithTracerProvider(provider))...), recorder, provider
+}
+
+func TestWriter(t *testing.T) {
+	w, recorder, provider := newWriter(WithAttributes(attribute.String("service", "orders")))
+	var out bytes.Buffer
+	logger := zerolog.New(zerolog.MultiLevelWriter(&out, w)).Hook(Hook{}).
+		With().Timestamp().Caller().Int("user_id", 42).Logger()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	logger.Warn().Ctx(ctx).
+		Str("method", "GET").
+		Float64("ratio", 0.5).
+		Dict("client", zerolog.Dict().Str("ip", "10.0.0.1")).
+		Msg("slow request")
+	parent.End()
+
+	if !strings.Contains(out.String(), `"trace_id":"`+parent.SpanContext().TraceID().String()+`"`) {
+		t.Errorf("output = %s, want the trace ID", out.String())
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "slow request" || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("span = %s, parent %v", span.Name(), span.Parent())
+	}
+	if span.StartTime() != span.EndTime() || span.StartTime().IsZero() {
+		t.Errorf("start = %v, end = %v", span.StartTime(), span.EndTime())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.SpanTypeKey: "log",
+		logfire.LevelNumKey: int64(logfire.LevelWarn),
+		logfire.MsgKey:      "slow request",
+		"service":           "orders",
+		"user_id":           int64(42),
+		"method":            "GET",
+		"ratio":             0.5,
+		"client":            `{"ip":"10.0.0.1"}`,
+	})
+	attrs := oteltest.Attributes(span.Attributes())
+	if file, _ := attrs["code.filepath"].(string); !strings.HasSuffix(file, "writer_test.go") {
+		t.Errorf("code.filepath = %v", attrs["code.filepath"])
+	}
+	for _, k := range []attribute.Key{"level", "message", "time", "caller", "trace_id", "span_id", "trace_flags"} {
+		if _, ok := attrs[k]; ok {
+			t.Errorf("%s recorded", k)
+		}
+	}
+}
+
+func TestWriterError(t *testing.T) {
+	w, recorder, _ := newWriter()
+	logger := zerolog.New(w)
+	logger.Error().Err(errors.New("card declined")).Msg("payment failed")
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v", span.Status())
+	}
+	oteltest.CheckAttributes(t, span.Attributes(), map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelError),
+		"error":             "card declined",
+	})
+	events := span.Events()
+	if len(events) != 1 || events[0].Name != "exception" {
+		t.Fatalf("events = %v", events)
+	}
+	oteltest.CheckAttributes(t, events[0].Attributes, map[attribute.Key]any{"exception.message": "card declined"})
+}
+
+func TestWriterLevel(t *testing.T) {
+	w, recorder, _ := newWriter()
+	logger := zerolog.New(w)
+	logger.Debug().Msg("debug")
+	logger.Log().Msg("no level")
+	w.Write([]byte(`{"level":"debug","message":"written"}`))
+	w.Write([]byte("not JSON\n"))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "no level" || spans[1].Name() != "not JSON" {
+		t.Fatalf("spans = %v", spans)
+	}
+	oteltest.CheckAttributes(t, spans[1].Attributes(), map[attribute.Key]any{logfire.LevelNumKey: int64(logfire.LevelInfo)})
+
+	for level, want := range map[zerolog.Level]logfire.Level{
+		zerolog.TraceLevel: logfire.LevelTrace,
+		zerolog.DebugLevel: logfire.LevelDebug,
+		zerolog.InfoLevel:  logfire.LevelInfo,
+		zerolog.WarnLevel:  logfire.LevelWarn,
+		zerolog.ErrorLevel: logfire.LevelError,
+		zerolog.FatalLevel: logfire.LevelFatal,
+		zerolog.PanicLevel: logfire.LevelFatal,
+		zerolog.NoLevel:    logfire.LevelInfo,
+	} {
+		if got := Level(level); got != want {
+			t.Errorf("Level(%v) = %v, want %v", level, got, want)
+		}
+	}
+}
+
+func TestWriterSpanEvents(t *testing.T) {
+	w, recorder, provider := newWriter(WithSpanEvents())
+	logger := zerolog.New(w).Hook(w.Hook())
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	logger.Info().Ctx(ctx).Str("method", "GET").Msg("handling")
+	logger.Error().Ctx(ctx).Err(errors.New("card declined")).Msg("payment failed")
+	span.End()
+	logger.Info().Msg("no span")
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "request" || spans[1].Name() != "no span" {
+		t.Fatalf("spans = %v, want the request and the log without span", spans)
+	}
+	events := spans[0].Events()
+	if len(events) != 2 || events[0].Name != "handling" || events[1].Name != "payment failed" {
+		t.Fatalf("events = %v", events)
+	}
+	oteltest.CheckAttributes(t, events[0].Attributes, map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelInfo),
+		"method":            "GET",
+	})
+	oteltest.CheckAttributes(t, events[1].Attributes, map[attribute.Key]any{
+		logfire.LevelNumKey: int64(logfire.LevelError),
+		"exception.message": "card declined",
+	})
+	if len(w.spans) != 0 {
+		t.Errorf("pending spans = %d, want 0", len(w.spans))
+	}
+}
+
+func TestWriterSweepsEndedSpans(t *testing.T) {
+	w, _, provider := newWriter(WithSpanEvents())
+	// The events discarded by a later hook never reach the writer.
+	logger := zerolog.New(w).Hook(w.Hook(), zerolog.HookFunc(func(e *zerolog.Event, _ zerolog.Level, _ string) {
+		e.Discard()
+	}))
+
+	for range 3 * minSweep {
+		ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+		logger.Info().Ctx(ctx).Msg("dropped")
+		span.End()
+	}
+	if len(w.spans) > minSweep {
+		t.Errorf("pending spans = %d, want at most %d", len(w.spans), minSweep)
+	}
+}